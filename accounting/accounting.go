@@ -222,12 +222,15 @@ const (
 	REQUEST_TIMER = "request_timer"
 
 	PREPARED = "prepared"
+
+	RESULT_CACHE_HITS   = "result_cache_hits"
+	RESULT_CACHE_MISSES = "result_cache_misses"
 )
 
 var metricNames = []string{REQUESTS, CANCELLED, SELECTS, UPDATES, INSERTS, DELETES, ACTIVE_REQUESTS, QUEUED_REQUESTS, INVALID_REQUESTS,
 	UNBOUNDED, AT_PLUS, SCAN_PLUS,
 	REQUEST_TIME, SERVICE_TIME, RESULT_COUNT, RESULT_SIZE, ERRORS, REQUESTS_250MS, REQUESTS_500MS, REQUESTS_1000MS,
-	REQUESTS_5000MS, WARNINGS, MUTATIONS}
+	REQUESTS_5000MS, WARNINGS, MUTATIONS, RESULT_CACHE_HITS, RESULT_CACHE_MISSES}
 
 // Map each duration to its metrics
 var slowMetricsMap = map[time.Duration][]string{
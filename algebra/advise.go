@@ -0,0 +1,163 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the ADVISE statement, which recommends indexes for a
+SELECT, UPDATE or DELETE statement, or for the statements logged in
+a keyspace of completed requests.
+*/
+type Advise struct {
+	statementBase
+
+	stmt     Statement    `json:"stmt"`
+	keyspace *KeyspaceRef `json:"keyspace"`
+}
+
+/*
+The function NewAdvise returns a pointer to the Advise struct for
+the input statement, or for the input keyspace of completed requests
+if the statement is nil.
+*/
+func NewAdvise(stmt Statement, keyspace *KeyspaceRef) *Advise {
+	rv := &Advise{
+		stmt:     stmt,
+		keyspace: keyspace,
+	}
+
+	rv.statementBase.stmt = rv
+	return rv
+}
+
+/*
+It calls the VisitAdvise method by passing in the receiver and
+returns the interface. It is a visitor pattern.
+*/
+func (this *Advise) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitAdvise(this)
+}
+
+/*
+Returns the shape of the results.
+*/
+func (this *Advise) Signature() value.Value {
+	if this.stmt == nil {
+		return value.NewValue(map[string]interface{}{
+			"statement":          value.STRING.String(),
+			"kind":               value.STRING.String(),
+			"keyspace":           value.STRING.String(),
+			"count":              value.NUMBER.String(),
+			"total_elapsed_time": value.STRING.String(),
+			"queries":            value.ARRAY.String(),
+		})
+	}
+
+	return value.NewValue(map[string]interface{}{
+		"keyspace":        value.STRING.String(),
+		"alias":           value.STRING.String(),
+		"current_scan":    value.STRING.String(),
+		"indexes":         value.ARRAY.String(),
+		"recommendations": value.ARRAY.String(),
+	})
+}
+
+/*
+Call Formalize for the input statement.
+*/
+func (this *Advise) Formalize() error {
+	if this.stmt == nil {
+		return nil
+	}
+
+	return this.stmt.Formalize()
+}
+
+/*
+Map statement expressions by calling MapExpressions.
+*/
+func (this *Advise) MapExpressions(mapper expression.Mapper) error {
+	if this.stmt == nil {
+		return nil
+	}
+
+	return this.stmt.MapExpressions(mapper)
+}
+
+/*
+Return all contained Expressions.
+*/
+func (this *Advise) Expressions() expression.Expressions {
+	if this.stmt == nil {
+		return nil
+	}
+
+	return this.stmt.Expressions()
+}
+
+/*
+Returns all required privileges. Advising a statement only reads the
+keyspaces of the statement.
+*/
+func (this *Advise) Privileges() (datastore.Privileges, errors.Error) {
+	if this.stmt == nil {
+		return datastore.Privileges{
+			this.keyspace.Namespace() + ":" + this.keyspace.Keyspace(): datastore.PRIV_READ,
+		}, nil
+	}
+
+	privs, err := this.stmt.Privileges()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := datastore.NewPrivileges()
+	for keyspace, _ := range privs {
+		rv[keyspace] = datastore.PRIV_READ
+	}
+
+	return rv, nil
+}
+
+/*
+Return the statement being advised, or nil.
+*/
+func (this *Advise) Statement() Statement {
+	return this.stmt
+}
+
+/*
+Return the keyspace of completed requests being advised, or nil.
+*/
+func (this *Advise) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+/*
+Marshals input receiver into byte array.
+*/
+func (this *Advise) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "advise"}
+	if this.stmt != nil {
+		r["stmt"] = this.stmt
+	} else {
+		r["keyspaceRef"] = this.keyspace
+	}
+
+	return json.Marshal(r)
+}
@@ -0,0 +1,140 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"sort"
+
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function MODE(expr). It returns
+the most frequent non-NULL, non-MISSING value in the group. Ties
+are broken by returning the lowest value in collation order. Type Mode is a struct
+that inherits from AggregateBase.
+*/
+type Mode struct {
+	AggregateBase
+}
+
+/*
+The function NewMode calls NewAggregateBase to
+create an aggregate function named MODE with
+one expression as input.
+*/
+func NewMode(operand expression.Expression) Aggregate {
+	rv := &Mode{
+		*NewAggregateBase("mode", operand),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *Mode) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type JSON.
+*/
+func (this *Mode) Type() value.Type { return value.JSON }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *Mode) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+The constructor returns a NewMode with the input operand
+cast to a Function as the FunctionConstructor.
+*/
+func (this *Mode) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewMode(operands[0])
+	}
+}
+
+/*
+If no input to the MODE function, then the default value
+returned is a null.
+*/
+func (this *Mode) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. For null and
+missing values, return the input value itself. Call
+cumulateValues to collect the value and return it.
+*/
+func (this *Mode) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := this.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() <= value.NULL || item.Type() == value.BINARY {
+		return cumulative, nil
+	}
+
+	return cumulateValues("MODE", value.NewValue([]interface{}{item}), cumulative)
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *Mode) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateValues("MODE", part, cumulative)
+}
+
+/*
+Compute the Final. Sort the values, so that equal values are
+adjacent, and return the value of the longest run.
+*/
+func (this *Mode) ComputeFinal(cumulative value.Value, context Context) (value.Value, error) {
+	if cumulative == value.NULL_VALUE {
+		return cumulative, nil
+	}
+
+	sort.Sort(value.NewSorter(cumulative))
+
+	var mode, prev value.Value
+	modeCount, count := 0, 0
+	for i := 0; ; i++ {
+		v, ok := cumulative.Index(i)
+		if !ok {
+			break
+		}
+
+		if prev != nil && v.Equals(prev).Truth() {
+			count++
+		} else {
+			prev, count = v, 1
+		}
+
+		if count > modeCount {
+			mode, modeCount = prev, count
+		}
+	}
+
+	if mode == nil {
+		return value.NULL_VALUE, nil
+	}
+
+	return mode, nil
+}
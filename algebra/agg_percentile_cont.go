@@ -0,0 +1,135 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function PERCENTILE_CONT(expr,
+fraction). It returns the percentile of the number values in the
+group for the given fraction, between 0 and 1, interpolating
+between the nearest numbers. The fraction must not depend on the
+grouped documents. Type PercentileCont is a struct
+that inherits from AggregateBase.
+*/
+type PercentileCont struct {
+	AggregateBase
+}
+
+/*
+The function NewPercentileCont calls NewAggregateBase to
+create an aggregate function named PERCENTILE_CONT with
+two expressions as input.
+*/
+func NewPercentileCont(first, second expression.Expression) Aggregate {
+	rv := &PercentileCont{
+		*NewAggregateBase("percentile_cont", first, second),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *PercentileCont) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type NUMBER.
+*/
+func (this *PercentileCont) Type() value.Type { return value.NUMBER }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *PercentileCont) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+Minimum input arguments required is 2.
+*/
+func (this *PercentileCont) MinArgs() int { return 2 }
+
+/*
+Maximum number of input arguments allowed is 2.
+*/
+func (this *PercentileCont) MaxArgs() int { return 2 }
+
+/*
+The constructor returns a NewPercentileCont with the input operands
+cast to a Function as the FunctionConstructor.
+*/
+func (this *PercentileCont) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewPercentileCont(operands[0], operands[1])
+	}
+}
+
+/*
+If no input to the PERCENTILE_CONT function, then the default value
+returned is a null.
+*/
+func (this *PercentileCont) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. For all
+values other than Number, return the input value itself.
+Call cumulateValues to collect the number and return it.
+*/
+func (this *PercentileCont) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := this.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() != value.NUMBER {
+		return cumulative, nil
+	}
+
+	return cumulateValues("PERCENTILE_CONT", value.NewValue([]interface{}{item}), cumulative)
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *PercentileCont) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateValues("PERCENTILE_CONT", part, cumulative)
+}
+
+/*
+Compute the Final. Evaluate the fraction, sort the numbers and
+interpolate the percentile.
+*/
+func (this *PercentileCont) ComputeFinal(cumulative value.Value, context Context) (value.Value, error) {
+	fraction, e := percentileFraction("PERCENTILE_CONT", this.Operands()[1], context)
+	if e != nil {
+		return nil, e
+	}
+
+	if cumulative == value.NULL_VALUE {
+		return cumulative, nil
+	}
+
+	numbers, e := sortedNumbers("PERCENTILE_CONT", cumulative)
+	if e != nil || len(numbers) == 0 {
+		return value.NULL_VALUE, e
+	}
+
+	return value.NewValue(continuousPercentile(numbers, fraction)), nil
+}
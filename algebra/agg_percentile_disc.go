@@ -0,0 +1,142 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"math"
+
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function PERCENTILE_DISC(expr,
+fraction). It returns the smallest number value in the group
+whose cumulative distribution is at least the given fraction,
+between 0 and 1. The fraction must not depend on the grouped
+documents. Type PercentileDisc is a struct
+that inherits from AggregateBase.
+*/
+type PercentileDisc struct {
+	AggregateBase
+}
+
+/*
+The function NewPercentileDisc calls NewAggregateBase to
+create an aggregate function named PERCENTILE_DISC with
+two expressions as input.
+*/
+func NewPercentileDisc(first, second expression.Expression) Aggregate {
+	rv := &PercentileDisc{
+		*NewAggregateBase("percentile_disc", first, second),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *PercentileDisc) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type NUMBER.
+*/
+func (this *PercentileDisc) Type() value.Type { return value.NUMBER }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *PercentileDisc) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+Minimum input arguments required is 2.
+*/
+func (this *PercentileDisc) MinArgs() int { return 2 }
+
+/*
+Maximum number of input arguments allowed is 2.
+*/
+func (this *PercentileDisc) MaxArgs() int { return 2 }
+
+/*
+The constructor returns a NewPercentileDisc with the input operands
+cast to a Function as the FunctionConstructor.
+*/
+func (this *PercentileDisc) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewPercentileDisc(operands[0], operands[1])
+	}
+}
+
+/*
+If no input to the PERCENTILE_DISC function, then the default value
+returned is a null.
+*/
+func (this *PercentileDisc) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. For all
+values other than Number, return the input value itself.
+Call cumulateValues to collect the number and return it.
+*/
+func (this *PercentileDisc) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := this.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() != value.NUMBER {
+		return cumulative, nil
+	}
+
+	return cumulateValues("PERCENTILE_DISC", value.NewValue([]interface{}{item}), cumulative)
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *PercentileDisc) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateValues("PERCENTILE_DISC", part, cumulative)
+}
+
+/*
+Compute the Final. Evaluate the fraction, sort the numbers and
+return the first one at or above the fraction.
+*/
+func (this *PercentileDisc) ComputeFinal(cumulative value.Value, context Context) (value.Value, error) {
+	fraction, e := percentileFraction("PERCENTILE_DISC", this.Operands()[1], context)
+	if e != nil {
+		return nil, e
+	}
+
+	if cumulative == value.NULL_VALUE {
+		return cumulative, nil
+	}
+
+	numbers, e := sortedNumbers("PERCENTILE_DISC", cumulative)
+	if e != nil || len(numbers) == 0 {
+		return value.NULL_VALUE, e
+	}
+
+	i := int(math.Ceil(fraction*float64(len(numbers)))) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return value.NewValue(numbers[i]), nil
+}
@@ -34,7 +34,7 @@ func GetAggregate(name string, distinct bool) (Aggregate, bool) {
 /*
 Aggregate functions with a DISTINCT specified. The variable
 represents a map from string to Aggregate Function. The
-aggregate functions ARRAY_AGG, AVG, COUNT and SUM  are
+aggregate functions ARRAY_AGG, AVG, COUNT, COUNTN and SUM  are
 defined by _DISTINCT_AGGREGATES. They map to the corresponding
 distinct methods.
 */
@@ -42,19 +42,37 @@ var _DISTINCT_AGGREGATES = map[string]Aggregate{
 	"array_agg": &ArrayAggDistinct{},
 	"avg":       &AvgDistinct{},
 	"count":     &CountDistinct{},
+	"countn":    &CountNDistinct{},
 	"sum":       &SumDistinct{},
 }
 
 /*
 Non Distinct Aggregate functions. The variable represents a
 map from string to Aggregate Function. Contains aggregate
-functions ARRAY_AGG, AVG, COUNT, MAX, MIN and SUM.
+functions ARRAY_AGG, AVG, COUNT, MAX, MIN and SUM, the
+statistical aggregates and their synonyms, OBJECT_AGG and
+APPROX_COUNT_DISTINCT.
 */
 var _OTHER_AGGREGATES = map[string]Aggregate{
-	"array_agg": &ArrayAgg{},
-	"avg":       &Avg{},
-	"count":     &Count{},
-	"max":       &Max{},
-	"min":       &Min{},
-	"sum":       &Sum{},
+	"approx_count_distinct": &ApproxCountDistinct{},
+	"array_agg":             &ArrayAgg{},
+	"avg":                   &Avg{},
+	"count":                 &Count{},
+	"countn":                &CountN{},
+	"max":                   &Max{},
+	"median":                &Median{},
+	"min":                   &Min{},
+	"mode":                  &Mode{},
+	"object_agg":            &ObjectAgg{},
+	"percentile_cont":       &PercentileCont{},
+	"percentile_disc":       &PercentileDisc{},
+	"stddev":                &Stddev{},
+	"stddev_pop":            &StddevPop{},
+	"stddev_samp":           &Stddev{},
+	"sum":                   &Sum{},
+	"var_pop":               &VariancePop{},
+	"var_samp":              &Variance{},
+	"variance":              &Variance{},
+	"variance_pop":          &VariancePop{},
+	"variance_samp":         &Variance{},
 }
@@ -0,0 +1,118 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"math"
+
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function STDDEV_POP(expr).
+It returns the population standard deviation of all the number
+values in the group. Type StddevPop is a struct that
+inherits from AggregateBase.
+*/
+type StddevPop struct {
+	AggregateBase
+}
+
+/*
+The function NewStddevPop calls NewAggregateBase to
+create an aggregate function named STDDEV_POP with
+one expression as input.
+*/
+func NewStddevPop(operand expression.Expression) Aggregate {
+	rv := &StddevPop{
+		*NewAggregateBase("stddev_pop", operand),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *StddevPop) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type NUMBER.
+*/
+func (this *StddevPop) Type() value.Type { return value.NUMBER }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *StddevPop) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+The constructor returns a NewStddevPop with the input operand
+cast to a Function as the FunctionConstructor.
+*/
+func (this *StddevPop) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewStddevPop(operands[0])
+	}
+}
+
+/*
+If no input to the STDDEV_POP function, then the default value
+returned is a null.
+*/
+func (this *StddevPop) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. For all
+values other than Number, return the input value itself.
+Call cumulateMoments to compute the intermediate aggregate
+value and return it.
+*/
+func (this *StddevPop) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := this.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() != value.NUMBER {
+		return cumulative, nil
+	}
+
+	return cumulateMoments("STDDEV_POP", momentsPart(item), cumulative)
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *StddevPop) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateMoments("STDDEV_POP", part, cumulative)
+}
+
+/*
+Compute the Final. Compute the variance from the count and
+the sum of squared differences from the mean, and return its
+square root.
+*/
+func (this *StddevPop) ComputeFinal(cumulative value.Value, context Context) (value.Value, error) {
+	variance, e := computeVariance("STDDEV_POP", cumulative, false)
+	if e != nil || variance == value.NULL_VALUE {
+		return variance, e
+	}
+
+	return value.NewValue(math.Sqrt(variance.Actual().(float64))), nil
+}
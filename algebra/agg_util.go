@@ -11,7 +11,10 @@ package algebra
 
 import (
 	"fmt"
+	"math"
+	"sort"
 
+	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/value"
 )
 
@@ -99,3 +102,159 @@ func getSet(item value.Value) (*value.Set, error) {
 		return nil, fmt.Errorf("Invalid DISTINCT %v of type %T.", item, item)
 	}
 }
+
+/*
+Partial moments of a single number, for the variance aggregates.
+The intermediate value holds the count, the mean and the sum of
+squared differences from the mean (m2) of the numbers seen so far.
+*/
+func momentsPart(item value.Value) value.Value {
+	return value.NewValue(map[string]interface{}{
+		"count": 1.0,
+		"mean":  item.Actual(),
+		"m2":    0.0,
+	})
+}
+
+/*
+Combine partial moments, using the parallel algorithm of Chan et
+al. It is numerically stable and does not depend on the order in
+which the parts are combined.
+*/
+func cumulateMoments(name string, part, cumulative value.Value) (value.Value, error) {
+	if part == value.NULL_VALUE {
+		return cumulative, nil
+	} else if cumulative == value.NULL_VALUE {
+		return part, nil
+	}
+
+	pcount, pmean, pm2, pok := getMoments(part)
+	ccount, cmean, cm2, cok := getMoments(cumulative)
+	if !pok || !cok {
+		return nil, fmt.Errorf("Missing or invalid partial moments in %s: %v, %v.",
+			name, part.Actual(), cumulative.Actual())
+	}
+
+	count := pcount + ccount
+	delta := pmean - cmean
+	return value.NewValue(map[string]interface{}{
+		"count": count,
+		"mean":  cmean + delta*pcount/count,
+		"m2":    cm2 + pm2 + delta*delta*pcount*ccount/count,
+	}), nil
+}
+
+/*
+Compute the variance from the final moments. The sample variance
+of less than two numbers, and the variance of no numbers, are NULL.
+*/
+func computeVariance(name string, cumulative value.Value, sample bool) (value.Value, error) {
+	if cumulative == value.NULL_VALUE {
+		return cumulative, nil
+	}
+
+	count, _, m2, ok := getMoments(cumulative)
+	if !ok {
+		return nil, fmt.Errorf("Missing or invalid moments in %s: %v.", name, cumulative.Actual())
+	}
+
+	if sample {
+		count--
+	}
+
+	if count <= 0.0 {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(m2 / count), nil
+}
+
+func getMoments(moments value.Value) (count, mean, m2 float64, ok bool) {
+	c, _ := moments.Field("count")
+	m, _ := moments.Field("mean")
+	s, _ := moments.Field("m2")
+
+	if c.Type() != value.NUMBER || m.Type() != value.NUMBER || s.Type() != value.NUMBER {
+		return
+	}
+
+	return c.Actual().(float64), m.Actual().(float64), s.Actual().(float64), true
+}
+
+/*
+Append partial arrays of values, for the aggregates that need all
+the values of the group, such as MEDIAN and MODE.
+*/
+func cumulateValues(name string, part, cumulative value.Value) (value.Value, error) {
+	if part == value.NULL_VALUE {
+		return cumulative, nil
+	} else if cumulative == value.NULL_VALUE {
+		return part, nil
+	}
+
+	actual, ok := part.Actual().([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Invalid partial %s %v of type %T.", name, part.Actual(), part.Actual())
+	}
+
+	array, ok := cumulative.Actual().([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Invalid %s %v of type %T.", name, cumulative.Actual(), cumulative.Actual())
+	}
+
+	return value.NewValue(append(array, actual...)), nil
+}
+
+/*
+Return the final numbers collected by cumulateValues, sorted.
+*/
+func sortedNumbers(name string, cumulative value.Value) ([]float64, error) {
+	array, ok := cumulative.Actual().([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Invalid %s %v of type %T.", name, cumulative.Actual(), cumulative.Actual())
+	}
+
+	rv := make([]float64, 0, len(array))
+	for _, a := range array {
+		switch a := value.NewValue(a).Actual().(type) {
+		case float64:
+			rv = append(rv, a)
+		default:
+			return nil, fmt.Errorf("Invalid %s number %v of type %T.", name, a, a)
+		}
+	}
+
+	sort.Float64s(rv)
+	return rv, nil
+}
+
+/*
+Evaluate the fraction of a percentile aggregate, which must not
+depend on the grouped documents.
+*/
+func percentileFraction(name string, fraction expression.Expression, context Context) (float64, error) {
+	f, e := fraction.Evaluate(value.NULL_VALUE, context)
+	if e != nil {
+		return 0.0, e
+	}
+
+	if f.Type() == value.NUMBER {
+		rv := f.Actual().(float64)
+		if rv >= 0.0 && rv <= 1.0 {
+			return rv, nil
+		}
+	}
+
+	return 0.0, fmt.Errorf("%s fraction must be a number between 0 and 1: %v.", name, f.Actual())
+}
+
+/*
+Return the continuous percentile of sorted numbers, interpolating
+linearly between the two nearest numbers.
+*/
+func continuousPercentile(numbers []float64, fraction float64) float64 {
+	pos := fraction * float64(len(numbers)-1)
+	lo := math.Floor(pos)
+	hi := math.Ceil(pos)
+	return numbers[int(lo)] + (pos-lo)*(numbers[int(hi)]-numbers[int(lo)])
+}
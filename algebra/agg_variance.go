@@ -0,0 +1,110 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function VARIANCE(expr).
+It returns the sample variance of all the number values in the
+group. VAR_SAMP and VARIANCE_SAMP are synonyms. Type Variance is a struct that
+inherits from AggregateBase.
+*/
+type Variance struct {
+	AggregateBase
+}
+
+/*
+The function NewVariance calls NewAggregateBase to
+create an aggregate function named VARIANCE with
+one expression as input.
+*/
+func NewVariance(operand expression.Expression) Aggregate {
+	rv := &Variance{
+		*NewAggregateBase("variance", operand),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *Variance) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type NUMBER.
+*/
+func (this *Variance) Type() value.Type { return value.NUMBER }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *Variance) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+The constructor returns a NewVariance with the input operand
+cast to a Function as the FunctionConstructor.
+*/
+func (this *Variance) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewVariance(operands[0])
+	}
+}
+
+/*
+If no input to the VARIANCE function, then the default value
+returned is a null.
+*/
+func (this *Variance) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. For all
+values other than Number, return the input value itself.
+Call cumulateMoments to compute the intermediate aggregate
+value and return it.
+*/
+func (this *Variance) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := this.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() != value.NUMBER {
+		return cumulative, nil
+	}
+
+	return cumulateMoments("VARIANCE", momentsPart(item), cumulative)
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *Variance) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateMoments("VARIANCE", part, cumulative)
+}
+
+/*
+Compute the Final. Compute the variance from the count and
+the sum of squared differences from the mean.
+*/
+func (this *Variance) ComputeFinal(cumulative value.Value, context Context) (value.Value, error) {
+	return computeVariance("VARIANCE", cumulative, true)
+}
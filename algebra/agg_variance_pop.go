@@ -0,0 +1,110 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function VARIANCE_POP(expr).
+It returns the population variance of all the number values in the
+group. VAR_POP is a synonym. Type VariancePop is a struct that
+inherits from AggregateBase.
+*/
+type VariancePop struct {
+	AggregateBase
+}
+
+/*
+The function NewVariancePop calls NewAggregateBase to
+create an aggregate function named VARIANCE_POP with
+one expression as input.
+*/
+func NewVariancePop(operand expression.Expression) Aggregate {
+	rv := &VariancePop{
+		*NewAggregateBase("variance_pop", operand),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *VariancePop) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type NUMBER.
+*/
+func (this *VariancePop) Type() value.Type { return value.NUMBER }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *VariancePop) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+The constructor returns a NewVariancePop with the input operand
+cast to a Function as the FunctionConstructor.
+*/
+func (this *VariancePop) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewVariancePop(operands[0])
+	}
+}
+
+/*
+If no input to the VARIANCE_POP function, then the default value
+returned is a null.
+*/
+func (this *VariancePop) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. For all
+values other than Number, return the input value itself.
+Call cumulateMoments to compute the intermediate aggregate
+value and return it.
+*/
+func (this *VariancePop) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := this.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() != value.NUMBER {
+		return cumulative, nil
+	}
+
+	return cumulateMoments("VARIANCE_POP", momentsPart(item), cumulative)
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *VariancePop) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateMoments("VARIANCE_POP", part, cumulative)
+}
+
+/*
+Compute the Final. Compute the variance from the count and
+the sum of squared differences from the mean.
+*/
+func (this *VariancePop) ComputeFinal(cumulative value.Value, context Context) (value.Value, error) {
+	return computeVariance("VARIANCE_POP", cumulative, false)
+}
@@ -67,26 +67,45 @@ type Aggregate interface {
 
 /*
 Base class for Aggregate functions. It inherits from
-expressions UnaryFunctionBase, and has field text
-which represents the function name.
+expressions FunctionBase, and has field text which
+represents the function name. The first operand is the
+aggregated expression; aggregates such as PERCENTILE_CONT
+and OBJECT_AGG take a second operand.
 */
 type AggregateBase struct {
-	expression.UnaryFunctionBase
+	expression.FunctionBase
 	text string
 }
 
 /*
-This method creates a new Unary function using the
-input expression name and operands, and returns it
-as a pointer to an AggregateBase struct.
+This method creates a new function using the input
+expression name and operands, and returns it as a
+pointer to an AggregateBase struct.
 */
-func NewAggregateBase(name string, operand expression.Expression) *AggregateBase {
+func NewAggregateBase(name string, operands ...expression.Expression) *AggregateBase {
 	return &AggregateBase{
-		*expression.NewUnaryFunctionBase(name, operand),
+		*expression.NewFunctionBase(name, operands...),
 		"",
 	}
 }
 
+/*
+Minimum input arguments required is 1.
+*/
+func (this *AggregateBase) MinArgs() int { return 1 }
+
+/*
+Maximum number of input arguments allowed is 1.
+*/
+func (this *AggregateBase) MaxArgs() int { return 1 }
+
+/*
+Return the aggregated operand.
+*/
+func (this *AggregateBase) Operand() expression.Expression {
+	return this.Operands()[0]
+}
+
 /*
 This method evaluates the input aggregate, by retrieving the
 aggregates map from the attachments and performing a lookup
@@ -55,6 +55,16 @@ type Statement interface {
 		Returns all required privileges.
 	*/
 	Privileges() (datastore.Privileges, errors.Error)
+
+	/*
+		Returns the optimizer hints given with this statement.
+	*/
+	OptimHints() OptimHints
+
+	/*
+		Attach optimizer hints to this statement.
+	*/
+	SetOptimHints(hints OptimHints)
 }
 
 /*
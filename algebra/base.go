@@ -16,7 +16,19 @@ import (
 )
 
 type statementBase struct {
-	stmt Statement
+	stmt       Statement
+	optimHints OptimHints
+}
+
+/*
+Returns the optimizer hints given with this statement.
+*/
+func (this *statementBase) OptimHints() OptimHints {
+	return this.optimHints
+}
+
+func (this *statementBase) SetOptimHints(hints OptimHints) {
+	this.optimHints = hints
 }
 
 /*
@@ -0,0 +1,121 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the CREATE PLAN BASELINE statement, which pins the
+current plan of a statement.
+*/
+type CreatePlanBaseline struct {
+	statementBase
+
+	statement Statement `json:"statement"`
+	text      string    `json:"text"`
+}
+
+/*
+The function NewCreatePlanBaseline returns a pointer to the
+CreatePlanBaseline struct with the input argument values as fields.
+*/
+func NewCreatePlanBaseline(statement Statement, text string) *CreatePlanBaseline {
+	rv := &CreatePlanBaseline{
+		statement: statement,
+		text:      text,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+/*
+It calls the VisitCreatePlanBaseline method by passing in the
+receiver and returns the interface. It is a visitor pattern.
+*/
+func (this *CreatePlanBaseline) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreatePlanBaseline(this)
+}
+
+/*
+Returns nil.
+*/
+func (this *CreatePlanBaseline) Signature() value.Value {
+	return nil
+}
+
+/*
+Call Formalize for the statement.
+*/
+func (this *CreatePlanBaseline) Formalize() error {
+	return this.statement.Formalize()
+}
+
+/*
+Map statement expressions by calling MapExpressions.
+*/
+func (this *CreatePlanBaseline) MapExpressions(mapper expression.Mapper) error {
+	return this.statement.MapExpressions(mapper)
+}
+
+/*
+Returns all contained Expressions.
+*/
+func (this *CreatePlanBaseline) Expressions() expression.Expressions {
+	return this.statement.Expressions()
+}
+
+/*
+Returns all required privileges, which are DDL privileges on the
+keyspaces of the statement.
+*/
+func (this *CreatePlanBaseline) Privileges() (datastore.Privileges, errors.Error) {
+	privs, err := this.statement.Privileges()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := datastore.NewPrivileges()
+	for keyspace := range privs {
+		rv[keyspace] = datastore.PRIV_DDL
+	}
+
+	return rv, nil
+}
+
+/*
+Returns the statement whose plan is pinned.
+*/
+func (this *CreatePlanBaseline) Statement() Statement {
+	return this.statement
+}
+
+/*
+Returns the text of the statement.
+*/
+func (this *CreatePlanBaseline) Text() string {
+	return this.text
+}
+
+/*
+Marshals input receiver into byte array.
+*/
+func (this *CreatePlanBaseline) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "createPlanBaseline"}
+	r["text"] = this.text
+	return json.Marshal(r)
+}
@@ -18,6 +18,8 @@ import (
 type Context interface {
 	expression.Context
 	Datastore() datastore.Datastore
+	Namespace() string
+	Readonly() bool
 	NamedArg(name string) (value.Value, bool)
 	PositionalArg(position int) (value.Value, bool)
 	EvaluateSubquery(query *Select, parent value.Value) (value.Value, error)
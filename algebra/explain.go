@@ -16,6 +16,16 @@ import (
 	"github.com/couchbase/query/value"
 )
 
+/*
+Formats of EXPLAIN output. Without a format, EXPLAIN returns the plan
+as JSON.
+*/
+const (
+	EXPLAIN_FORMAT_JSON = "json"
+	EXPLAIN_FORMAT_TEXT = "text"
+	EXPLAIN_FORMAT_DOT  = "dot"
+)
+
 /*
 Represents the explain text for a query. Type Explain is
 a struct that represents the explain json statement.
@@ -23,18 +33,20 @@ a struct that represents the explain json statement.
 type Explain struct {
 	statementBase
 
-	stmt Statement `json:"stmt"`
-	text string    `json:"text"`
+	stmt   Statement `json:"stmt"`
+	text   string    `json:"text"`
+	format string    `json:"format"`
 }
 
 /*
 The function NewExplain returns a pointer to the Explain
 struct that has its field stmt set to the input Statement.
 */
-func NewExplain(stmt Statement, text string) *Explain {
+func NewExplain(stmt Statement, text, format string) *Explain {
 	rv := &Explain{
-		stmt: stmt,
-		text: text,
+		stmt:   stmt,
+		text:   text,
+		format: format,
 	}
 
 	rv.statementBase.stmt = rv
@@ -98,3 +110,10 @@ Return the text of the statement being explained
 func (this *Explain) Text() string {
 	return this.text
 }
+
+/*
+Return the output format, or the empty string for the plan JSON.
+*/
+func (this *Explain) Format() string {
+	return this.format
+}
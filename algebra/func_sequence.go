@@ -0,0 +1,311 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/value"
+)
+
+/*
+The function GetFunction returns the function that needs the
+statement context, such as access to the datastore, if it exists.
+While looking into the map, convert the string name to lowercase.
+*/
+func GetFunction(name string) (expression.Function, bool) {
+	rv, ok := _FUNCTIONS[strings.ToLower(name)]
+	return rv, ok
+}
+
+var _FUNCTIONS = map[string]expression.Function{
+	"auto":    &Auto{},
+	"nextval": &NextVal{},
+}
+
+///////////////////////////////////////////////////
+//
+// NextVal
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the function NEXTVAL(sequence). It returns the next
+value of the sequence, named either as namespace:sequence or as
+sequence in the namespace of the statement.
+*/
+type NextVal struct {
+	expression.UnaryFunctionBase
+}
+
+func NewNextVal(operand expression.Expression) expression.Function {
+	rv := &NextVal{
+		*expression.NewUnaryFunctionBase("nextval", operand),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *NextVal) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *NextVal) Type() value.Type { return value.NUMBER }
+
+func (this *NextVal) Evaluate(item value.Value, context expression.Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+/*
+Returns nil. Every evaluation consumes a value of the sequence.
+*/
+func (this *NextVal) Value() value.Value {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *NextVal) Static() expression.Expression {
+	return nil
+}
+
+/*
+Returns true. Every evaluation returns a new value.
+*/
+func (this *NextVal) Volatile() bool {
+	return true
+}
+
+/*
+Returns false. Not indexable.
+*/
+func (this *NextVal) Indexable() bool {
+	return false
+}
+
+/*
+Returns false. No two evaluations are equivalent.
+*/
+func (this *NextVal) EquivalentTo(other expression.Expression) bool {
+	return false
+}
+
+func (this *NextVal) Apply(context expression.Context, arg value.Value) (value.Value, error) {
+	if arg.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if arg.Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	next, err := nextSequenceValue(context, arg.Actual().(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return value.NewValue(next), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *NextVal) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewNextVal(operands[0])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// Auto
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the function AUTO([pattern]), which generates
+document keys. Without a pattern it returns a UUID. Otherwise each
+placeholder of the pattern is replaced: {uuid} by a UUID, and
+{sequence} by the next value of the sequence. INSERT accepts a bare
+AUTO as the key, for AUTO().
+*/
+type Auto struct {
+	expression.FunctionBase
+}
+
+func NewAuto(operands ...expression.Expression) expression.Function {
+	rv := &Auto{
+		*expression.NewFunctionBase("auto", operands...),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Auto) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Auto) Type() value.Type { return value.STRING }
+
+func (this *Auto) Evaluate(item value.Value, context expression.Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+/*
+Returns nil. Every evaluation generates a new key.
+*/
+func (this *Auto) Value() value.Value {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *Auto) Static() expression.Expression {
+	return nil
+}
+
+/*
+Returns false. Not indexable.
+*/
+func (this *Auto) Indexable() bool {
+	return false
+}
+
+/*
+Returns false. No two evaluations are equivalent.
+*/
+func (this *Auto) EquivalentTo(other expression.Expression) bool {
+	return false
+}
+
+func (this *Auto) Apply(context expression.Context, args ...value.Value) (value.Value, error) {
+	if len(args) == 0 {
+		u, err := util.UUID()
+		if err != nil {
+			return nil, err
+		}
+		return value.NewValue(u), nil
+	}
+
+	if args[0].Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if args[0].Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	pattern := args[0].Actual().(string)
+	if !_AUTO_PLACEHOLDER.MatchString(pattern) {
+		return nil, fmt.Errorf("AUTO pattern %s has no placeholder.", pattern)
+	}
+
+	var err error
+	rv := _AUTO_PLACEHOLDER.ReplaceAllStringFunc(pattern, func(placeholder string) string {
+		if err != nil {
+			return ""
+		}
+
+		name := placeholder[1 : len(placeholder)-1]
+		if strings.ToLower(name) == "uuid" {
+			var u string
+			u, err = util.UUID()
+			return u
+		}
+
+		var next int64
+		next, err = nextSequenceValue(context, name)
+		return strconv.FormatInt(next, 10)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value.NewValue(rv), nil
+}
+
+/*
+Minimum input arguments required is 0.
+*/
+func (this *Auto) MinArgs() int { return 0 }
+
+/*
+Maximum number of input arguments allowed is 1.
+*/
+func (this *Auto) MaxArgs() int { return 1 }
+
+/*
+Factory method pattern.
+*/
+func (this *Auto) Constructor() expression.FunctionConstructor {
+	return NewAuto
+}
+
+var _AUTO_PLACEHOLDER = regexp.MustCompile(`\{[^{}]+\}`)
+
+/*
+Replace a bare AUTO key of INSERT VALUES by the function AUTO(). Only
+the VALUES form has no source item whose field could be named auto.
+*/
+func autoKey(key expression.Expression) expression.Expression {
+	ident, ok := key.(*expression.Identifier)
+	if ok && strings.ToLower(ident.Identifier()) == "auto" {
+		return NewAuto()
+	}
+
+	return key
+}
+
+func nextSequenceValue(context expression.Context, name string) (int64, error) {
+	ctx, ok := context.(Context)
+	if !ok {
+		return 0, fmt.Errorf("Sequence %s is not available in this context.", name)
+	}
+
+	// Advancing a sequence persists it
+	if ctx.Readonly() {
+		return 0, errors.NewSequenceReadonlyError(name)
+	}
+
+	namespace := ctx.Namespace()
+	if i := strings.Index(name, ":"); i >= 0 {
+		namespace, name = name[:i], name[i+1:]
+	}
+
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	ns, err := ctx.Datastore().NamespaceByName(namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	sns, ok := ns.(datastore.SequenceNamespace)
+	if !ok {
+		return 0, errors.NewSequencesNotSupportedError(namespace)
+	}
+
+	return sns.NextSequenceValue(name)
+}
@@ -0,0 +1,105 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+/*
+Precision of HyperLogLog sketches: the sketch has 2^p registers,
+and a standard error of 1.04 / sqrt(2^p), about 0.8%.
+*/
+const _HLL_PRECISION = 14
+
+const _HLL_REGISTERS = 1 << _HLL_PRECISION
+
+/*
+hyperLogLog estimates the number of distinct values added to it,
+in constant space. Sketches are merged by taking the maximum of
+each register, so that they can be computed in parallel.
+*/
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{
+		registers: make([]uint8, _HLL_REGISTERS),
+	}
+}
+
+/*
+Add the canonical JSON encoding of a value.
+*/
+func (this *hyperLogLog) add(bytes []byte) {
+	h := fnv.New64a()
+	h.Write(bytes)
+	x := mix64(h.Sum64())
+
+	i := x >> (64 - _HLL_PRECISION)
+	w := x<<_HLL_PRECISION | 1<<(_HLL_PRECISION-1)
+
+	// Position of the leftmost 1 bit
+	rank := uint8(1)
+	for w&(1<<63) == 0 {
+		rank++
+		w <<= 1
+	}
+
+	if rank > this.registers[i] {
+		this.registers[i] = rank
+	}
+}
+
+func (this *hyperLogLog) merge(other *hyperLogLog) {
+	for i, r := range other.registers {
+		if r > this.registers[i] {
+			this.registers[i] = r
+		}
+	}
+}
+
+/*
+Return the estimated count, using linear counting for small
+cardinalities, where it is more accurate.
+*/
+func (this *hyperLogLog) count() int64 {
+	m := float64(_HLL_REGISTERS)
+	sum := 0.0
+	zeros := 0
+	for _, r := range this.registers {
+		sum += math.Ldexp(1.0, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1.0 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return int64(estimate + 0.5)
+}
+
+/*
+Finalizer of MurmurHash3, to spread the bits of the FNV hash over
+the register index and rank.
+*/
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
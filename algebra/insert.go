@@ -42,9 +42,14 @@ type Insert struct {
 The function NewInsertValues returns a pointer to the Insert
 struct by assigning the input attributes to the fields of the
 struct, and setting key, value and query to nil. This
-represents the insert values clause.
+represents the insert values clause. A bare AUTO key is replaced
+by the function AUTO().
 */
 func NewInsertValues(keyspace *KeyspaceRef, values Pairs, returning *Projection) *Insert {
+	for _, pair := range values {
+		pair.Key = autoKey(pair.Key)
+	}
+
 	rv := &Insert{
 		keyspace:  keyspace,
 		key:       nil,
@@ -62,7 +67,8 @@ func NewInsertValues(keyspace *KeyspaceRef, values Pairs, returning *Projection)
 The function NewInsertSelect returns a pointer to the Insert
 struct by assigning the input attributes to the fields of the
 struct, and setting values to nil. This represents the insert
-select clause.
+select clause. Here AUTO names a field of the query results, and
+generated keys require the function AUTO().
 */
 func NewInsertSelect(keyspace *KeyspaceRef, key, value expression.Expression,
 	query *Select, returning *Projection) *Insert {
@@ -0,0 +1,111 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the Alter keyspace ddl statement, which sets or unsets the
+JSON schema of a keyspace.
+*/
+type AlterKeyspace struct {
+	statementBase
+
+	keyspace *KeyspaceRef `json:"keyspace"`
+	schema   value.Value  `json:"schema"`
+}
+
+/*
+The function NewAlterKeyspace returns a pointer to the AlterKeyspace
+struct with the input argument values as fields. A nil schema unsets
+the schema.
+*/
+func NewAlterKeyspace(keyspace *KeyspaceRef, schema value.Value) *AlterKeyspace {
+	rv := &AlterKeyspace{
+		keyspace: keyspace,
+		schema:   schema,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+/*
+It calls the VisitAlterKeyspace method by passing in the receiver
+and returns the interface. It is a visitor pattern.
+*/
+func (this *AlterKeyspace) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitAlterKeyspace(this)
+}
+
+/*
+Returns nil.
+*/
+func (this *AlterKeyspace) Signature() value.Value {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *AlterKeyspace) Formalize() error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *AlterKeyspace) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *AlterKeyspace) Expressions() expression.Expressions {
+	return nil
+}
+
+/*
+Returns all required privileges.
+*/
+func (this *AlterKeyspace) Privileges() (datastore.Privileges, errors.Error) {
+	return datastore.Privileges{
+		this.keyspace.Namespace() + ":" + this.keyspace.Keyspace(): datastore.PRIV_DDL,
+	}, nil
+}
+
+func (this *AlterKeyspace) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+/*
+Returns the schema, or nil to unset the schema.
+*/
+func (this *AlterKeyspace) Schema() value.Value {
+	return this.schema
+}
+
+/*
+Marshals input receiver into byte array.
+*/
+func (this *AlterKeyspace) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "alterKeyspace"}
+	r["keyspaceRef"] = this.keyspace
+	r["schema"] = this.schema
+	return json.Marshal(r)
+}
@@ -24,7 +24,9 @@ struct that contains fields mapping to each clause in
 the statement.  Keyspace is the keyspace-ref for
 the merge stmt. Merge source represents the path or a
 select statement with an alias, the key expression
-represents the keys clause. Merge actions can have
+represents the ON KEY clause, and the on expression the
+ON clause with an arbitrary condition; only one of them
+is set. Merge actions can have
 three possible statements, the merge update, merge
 delete or the merge insert statement. Limit represents
 the limit clause and Returning represents the returning
@@ -36,6 +38,7 @@ type Merge struct {
 	keyspace  *KeyspaceRef          `json:"keyspace"`
 	source    *MergeSource          `json:"source"`
 	key       expression.Expression `json:"key"`
+	on        expression.Expression `json:"on"`
 	actions   *MergeActions         `json:"actions"`
 	limit     expression.Expression `json:"limit"`
 	returning *Projection           `json:"returning"`
@@ -46,12 +49,13 @@ The function NewMerge returns a pointer to the Merge
 struct by assigning the input attributes to the fields
 of the struct.
 */
-func NewMerge(keyspace *KeyspaceRef, source *MergeSource, key expression.Expression,
+func NewMerge(keyspace *KeyspaceRef, source *MergeSource, key, on expression.Expression,
 	actions *MergeActions, limit expression.Expression, returning *Projection) *Merge {
 	rv := &Merge{
 		keyspace:  keyspace,
 		source:    source,
 		key:       key,
+		on:        on,
 		actions:   actions,
 		limit:     limit,
 		returning: returning,
@@ -90,9 +94,18 @@ func (this *Merge) MapExpressions(mapper expression.Mapper) (err error) {
 		return
 	}
 
-	this.key, err = mapper.Map(this.key)
-	if err != nil {
-		return
+	if this.key != nil {
+		this.key, err = mapper.Map(this.key)
+		if err != nil {
+			return
+		}
+	}
+
+	if this.on != nil {
+		this.on, err = mapper.Map(this.on)
+		if err != nil {
+			return
+		}
 	}
 
 	err = this.actions.MapExpressions(mapper)
@@ -121,7 +134,14 @@ func (this *Merge) Expressions() expression.Expressions {
 	exprs := make(expression.Expressions, 0, 64)
 
 	exprs = append(exprs, this.source.Expressions()...)
-	exprs = append(exprs, this.key)
+	if this.key != nil {
+		exprs = append(exprs, this.key)
+	}
+
+	if this.on != nil {
+		exprs = append(exprs, this.on)
+	}
+
 	exprs = append(exprs, this.actions.Expressions()...)
 
 	if this.limit != nil {
@@ -173,9 +193,13 @@ func (this *Merge) Formalize() (err error) {
 		return err
 	}
 
-	this.key, err = sf.Map(this.key)
-	if err != nil {
-		return err
+	if this.key != nil {
+		this.key, err = sf.Map(this.key)
+		if err != nil {
+			return err
+		}
+	} else if this.actions.Insert() != nil && this.actions.Insert().Key() == nil {
+		return fmt.Errorf("MERGE with ON condition requires INSERT with KEY.")
 	}
 
 	if kf.Keyspace() != "" &&
@@ -193,6 +217,13 @@ func (this *Merge) Formalize() (err error) {
 		f.Allowed().SetField(sf.Keyspace(), sf.Keyspace())
 	}
 
+	if this.on != nil {
+		this.on, err = f.Map(this.on)
+		if err != nil {
+			return
+		}
+	}
+
 	err = this.actions.MapExpressions(f)
 	if err != nil {
 		return
@@ -227,13 +258,21 @@ func (this *Merge) Source() *MergeSource {
 }
 
 /*
-Returns the key expression for the keys clause in
-the merge statement.
+Returns the key expression for the ON KEY clause in
+the merge statement, or nil.
 */
 func (this *Merge) Key() expression.Expression {
 	return this.key
 }
 
+/*
+Returns the condition of the ON clause in the merge
+statement, or nil if it has an ON KEY clause.
+*/
+func (this *Merge) On() expression.Expression {
+	return this.on
+}
+
 /*
 Returns the merge actions for the merge statement.
 */
@@ -625,10 +664,11 @@ func (this *MergeDelete) Where() expression.Expression {
 
 /*
 Represents the merge insert merge actions statement.
-Type MergeInsert is a struct that contains the value
-and where condition expressions.
+Type MergeInsert is a struct that contains the key,
+value and where condition expressions.
 */
 type MergeInsert struct {
+	key   expression.Expression `json:"key"`
 	value expression.Expression `json:"value"`
 	where expression.Expression `json:"where"`
 }
@@ -638,14 +678,21 @@ The function NewMergeInsert returns a pointer to the MergeInsert
 struct by assigning the input attributes to the fields of the
 struct.
 */
-func NewMergeInsert(value, where expression.Expression) *MergeInsert {
-	return &MergeInsert{value, where}
+func NewMergeInsert(key, value, where expression.Expression) *MergeInsert {
+	return &MergeInsert{key, value, where}
 }
 
 /*
 Apply mapper to value and where expressions.
 */
 func (this *MergeInsert) MapExpressions(mapper expression.Mapper) (err error) {
+	if this.key != nil {
+		this.key, err = mapper.Map(this.key)
+		if err != nil {
+			return
+		}
+	}
+
 	if this.value != nil {
 		this.value, err = mapper.Map(this.value)
 		if err != nil {
@@ -664,7 +711,11 @@ func (this *MergeInsert) MapExpressions(mapper expression.Mapper) (err error) {
 Returns all contained Expressions.
 */
 func (this *MergeInsert) Expressions() expression.Expressions {
-	exprs := make(expression.Expressions, 0, 2)
+	exprs := make(expression.Expressions, 0, 3)
+
+	if this.key != nil {
+		exprs = append(exprs, this.key)
+	}
 
 	if this.value != nil {
 		exprs = append(exprs, this.value)
@@ -677,6 +728,14 @@ func (this *MergeInsert) Expressions() expression.Expressions {
 	return exprs
 }
 
+/*
+Return the merge insert key expression, or nil if the
+key is that of the ON KEY clause.
+*/
+func (this *MergeInsert) Key() expression.Expression {
+	return this.key
+}
+
 /*
 Return the merge insert value expression.
 */
@@ -0,0 +1,124 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the Create view ddl statement. The view publishes query
+under a name, for use in the FROM clause of other statements; the
+query is expanded when they are planned.
+*/
+type CreateView struct {
+	statementBase
+
+	keyspace *KeyspaceRef `json:"keyspace"`
+	query    *Select      `json:"query"`
+	text     string       `json:"text"`
+}
+
+/*
+The function NewCreateView returns a pointer to the CreateView
+struct with the input argument values as fields.
+*/
+func NewCreateView(keyspace *KeyspaceRef, query *Select, text string) *CreateView {
+	rv := &CreateView{
+		keyspace: keyspace,
+		query:    query,
+		text:     text,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+/*
+It calls the VisitCreateView method by passing in the receiver and
+returns the interface. It is a visitor pattern.
+*/
+func (this *CreateView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateView(this)
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateView) Signature() value.Value {
+	return nil
+}
+
+/*
+Formalize the view query.
+*/
+func (this *CreateView) Formalize() error {
+	return this.query.Formalize()
+}
+
+/*
+Returns nil. The view query is not evaluated by this statement.
+*/
+func (this *CreateView) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateView) Expressions() expression.Expressions {
+	return nil
+}
+
+/*
+Returns all required privileges. Reading the keyspaces of the view
+query is checked when the view is used.
+*/
+func (this *CreateView) Privileges() (datastore.Privileges, errors.Error) {
+	return datastore.Privileges{
+		this.keyspace.Namespace() + ":" + this.keyspace.Keyspace(): datastore.PRIV_DDL,
+	}, nil
+}
+
+/*
+Returns the name of the view, as a keyspace.
+*/
+func (this *CreateView) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+/*
+Returns the view query.
+*/
+func (this *CreateView) Query() *Select {
+	return this.query
+}
+
+/*
+Returns the text of the view query.
+*/
+func (this *CreateView) Text() string {
+	return this.text
+}
+
+/*
+Marshals input receiver into byte array.
+*/
+func (this *CreateView) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "createView"}
+	r["keyspaceRef"] = this.keyspace
+	r["statement"] = this.text
+	return json.Marshal(r)
+}
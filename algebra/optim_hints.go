@@ -0,0 +1,154 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+Optimizer hints, given in a comment of the form /*+ hint ... that
+immediately follows the SELECT, UPDATE, DELETE or MERGE keyword.
+*/
+const (
+	OPTIM_INDEX    = "INDEX"    // INDEX(alias index ...)
+	OPTIM_NO_INDEX = "NO_INDEX" // NO_INDEX(alias [index ...])
+	OPTIM_USE_NL   = "USE_NL"   // USE_NL(alias)
+	OPTIM_USE_HASH = "USE_HASH" // USE_HASH(alias)
+	OPTIM_ORDERED  = "ORDERED"  // ORDERED
+	OPTIM_PARALLEL = "PARALLEL" // PARALLEL(n)
+	OPTIM_NO_COVER = "NO_COVER" // NO_COVER[(alias)]
+)
+
+type OptimHints []*OptimHint
+
+/*
+OptimHint is a single optimizer hint. A hint that is malformed or
+unknown is kept, with the reason it is invalid, so that the planner
+can report it instead of failing the statement.
+*/
+type OptimHint struct {
+	name     string
+	args     []string
+	parallel int
+	invalid  string
+}
+
+/*
+NewOptimHint validates the arguments of a hint. The name is not
+case-sensitive; the aliases and index names are.
+*/
+func NewOptimHint(name string, args []string) *OptimHint {
+	rv := &OptimHint{
+		name: strings.ToUpper(name),
+		args: args,
+	}
+
+	switch rv.name {
+	case OPTIM_INDEX:
+		if len(args) < 2 {
+			rv.invalid = "an alias and at least one index are required"
+		}
+	case OPTIM_NO_INDEX:
+		if len(args) < 1 {
+			rv.invalid = "an alias is required"
+		}
+	case OPTIM_USE_NL, OPTIM_USE_HASH:
+		if len(args) != 1 {
+			rv.invalid = "exactly one alias is required"
+		}
+	case OPTIM_ORDERED:
+		if len(args) != 0 {
+			rv.invalid = "no arguments are allowed"
+		}
+	case OPTIM_PARALLEL:
+		if len(args) == 1 {
+			rv.parallel, _ = strconv.Atoi(args[0])
+		}
+
+		if rv.parallel <= 0 {
+			rv.invalid = "a positive degree of parallelism is required"
+		}
+	case OPTIM_NO_COVER:
+		if len(args) > 1 {
+			rv.invalid = "at most one alias is allowed"
+		}
+	default:
+		rv.invalid = "unknown hint"
+	}
+
+	return rv
+}
+
+/*
+NewInvalidOptimHint returns a hint that could not be parsed.
+*/
+func NewInvalidOptimHint(text, reason string) *OptimHint {
+	return &OptimHint{
+		name:    text,
+		invalid: reason,
+	}
+}
+
+func (this *OptimHint) Name() string {
+	return this.name
+}
+
+/*
+Alias returns the keyspace alias the hint applies to, or the empty
+string for statement-wide hints.
+*/
+func (this *OptimHint) Alias() string {
+	switch this.name {
+	case OPTIM_INDEX, OPTIM_NO_INDEX, OPTIM_USE_NL, OPTIM_USE_HASH, OPTIM_NO_COVER:
+		if len(this.args) > 0 {
+			return this.args[0]
+		}
+	}
+
+	return ""
+}
+
+/*
+Indexes returns the index names of INDEX and NO_INDEX hints.
+*/
+func (this *OptimHint) Indexes() []string {
+	switch this.name {
+	case OPTIM_INDEX, OPTIM_NO_INDEX:
+		if len(this.args) > 1 {
+			return this.args[1:]
+		}
+	}
+
+	return nil
+}
+
+func (this *OptimHint) Parallel() int {
+	return this.parallel
+}
+
+/*
+Invalid returns the reason the hint is invalid, or the empty string.
+*/
+func (this *OptimHint) Invalid() string {
+	return this.invalid
+}
+
+/*
+String returns the hint in its canonical form, e.g. INDEX(o idx1).
+*/
+func (this *OptimHint) String() string {
+	if len(this.args) == 0 {
+		return this.name
+	}
+
+	return this.name + "(" + strings.Join(this.args, " ") + ")"
+}
@@ -30,6 +30,7 @@ type Subselect struct {
 	group      *Group                `json:"group"`
 	projection *Projection           `json:"projection"`
 	correlated bool                  `json:"correlated"`
+	optimHints OptimHints            `json:"optimizer_hints"`
 }
 
 /*
@@ -37,7 +38,7 @@ Constructor.
 */
 func NewSubselect(from FromTerm, let expression.Bindings, where expression.Expression,
 	group *Group, projection *Projection) *Subselect {
-	return &Subselect{from, let, where, group, projection, false}
+	return &Subselect{from, let, where, group, projection, false, nil}
 }
 
 /*
@@ -291,6 +292,18 @@ func (this *Subselect) Projection() *Projection {
 	return this.projection
 }
 
+/*
+Returns the optimizer hints that follow the SELECT keyword of the
+subselect.
+*/
+func (this *Subselect) OptimHints() OptimHints {
+	return this.optimHints
+}
+
+func (this *Subselect) SetOptimHints(hints OptimHints) {
+	this.optimHints = hints
+}
+
 /*
    Representation as a N1QL string.
 */
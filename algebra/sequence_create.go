@@ -0,0 +1,190 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the Create sequence ddl statement. The options are START
+WITH, INCREMENT BY or DECREMENT BY, and CACHE.
+*/
+type CreateSequence struct {
+	statementBase
+
+	keyspace *KeyspaceRef `json:"keyspace"`
+	options  value.Value  `json:"options"`
+}
+
+/*
+The function NewCreateSequence returns a pointer to the
+CreateSequence struct with the input argument values as fields.
+*/
+func NewCreateSequence(keyspace *KeyspaceRef, options value.Value) *CreateSequence {
+	rv := &CreateSequence{
+		keyspace: keyspace,
+		options:  options,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+/*
+It calls the VisitCreateSequence method by passing in the receiver
+and returns the interface. It is a visitor pattern.
+*/
+func (this *CreateSequence) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateSequence(this)
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateSequence) Signature() value.Value {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateSequence) Formalize() error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateSequence) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateSequence) Expressions() expression.Expressions {
+	return nil
+}
+
+/*
+Returns all required privileges.
+*/
+func (this *CreateSequence) Privileges() (datastore.Privileges, errors.Error) {
+	return datastore.Privileges{
+		this.keyspace.Namespace() + ":" + this.keyspace.Keyspace(): datastore.PRIV_DDL,
+	}, nil
+}
+
+/*
+Returns the name of the sequence, as a keyspace.
+*/
+func (this *CreateSequence) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+/*
+Returns the options.
+*/
+func (this *CreateSequence) Options() value.Value {
+	return this.options
+}
+
+/*
+Returns the sequence definition, after checking the options.
+*/
+func (this *CreateSequence) Sequence() (*datastore.Sequence, errors.Error) {
+	rv := &datastore.Sequence{
+		Name:      this.keyspace.Keyspace(),
+		Start:     datastore.SEQUENCE_START,
+		Increment: datastore.SEQUENCE_INCREMENT,
+		Cache:     datastore.SEQUENCE_CACHE,
+	}
+
+	if this.options == nil {
+		return rv, nil
+	}
+
+	_, start := this.options.Field("start")
+	_, increment := this.options.Field("increment")
+	_, decrement := this.options.Field("decrement")
+	if increment && decrement {
+		return nil, errors.NewSequenceOptionError("decrement")
+	}
+
+	for name, field := range this.options.Fields() {
+		n, ok := sequenceOption(field)
+		if !ok {
+			return nil, errors.NewSequenceOptionError(name)
+		}
+
+		switch name {
+		case "start":
+			rv.Start = n
+		case "increment":
+			rv.Increment = n
+		case "decrement":
+			rv.Increment = -n
+		case "cache":
+			rv.Cache = n
+		default:
+			return nil, errors.NewSequenceOptionError(name)
+		}
+	}
+
+	if rv.Increment == 0 {
+		return nil, errors.NewSequenceOptionError("increment")
+	}
+
+	if rv.Cache < 1 {
+		return nil, errors.NewSequenceOptionError("cache")
+	}
+
+	// Descending sequences start at -1 by default
+	if !start && rv.Increment < 0 {
+		rv.Start = -datastore.SEQUENCE_START
+	}
+
+	return rv, nil
+}
+
+/*
+Returns the option as an integer.
+*/
+func sequenceOption(option interface{}) (int64, bool) {
+	switch n := value.NewValue(option).Actual().(type) {
+	case int64:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < math.MaxInt64 {
+			return int64(n), true
+		}
+	}
+
+	return 0, false
+}
+
+/*
+Marshals input receiver into byte array.
+*/
+func (this *CreateSequence) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "createSequence"}
+	r["keyspaceRef"] = this.keyspace
+	if this.options != nil {
+		r["options"] = this.options
+	}
+	return json.Marshal(r)
+}
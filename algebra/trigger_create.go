@@ -0,0 +1,251 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the Create trigger ddl statement. The trigger runs stmt
+after each of the events on the keyspace. The WITH clause sets the
+options async (boolean) and on_error (fail, warn or ignore).
+*/
+type CreateTrigger struct {
+	statementBase
+
+	name     string       `json:"name"`
+	keyspace *KeyspaceRef `json:"keyspace"`
+	events   []string     `json:"events"`
+	with     value.Value  `json:"with"`
+	stmt     Statement    `json:"stmt"`
+	text     string       `json:"text"`
+}
+
+/*
+The function NewCreateTrigger returns a pointer to the CreateTrigger
+struct with the input argument values as fields.
+*/
+func NewCreateTrigger(name string, keyspace *KeyspaceRef, events []string, with value.Value,
+	stmt Statement, text string) *CreateTrigger {
+	rv := &CreateTrigger{
+		name:     name,
+		keyspace: keyspace,
+		events:   events,
+		with:     with,
+		stmt:     stmt,
+		text:     text,
+	}
+
+	rv.statementBase.stmt = rv
+	return rv
+}
+
+/*
+It calls the VisitCreateTrigger method by passing in the receiver
+and returns the interface. It is a visitor pattern.
+*/
+func (this *CreateTrigger) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateTrigger(this)
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateTrigger) Signature() value.Value {
+	return nil
+}
+
+/*
+Formalize the trigger statement, with NEW and OLD bound.
+*/
+func (this *CreateTrigger) Formalize() error {
+	err := BindTriggerParameters(this.stmt)
+	if err != nil {
+		return err
+	}
+
+	return this.stmt.Formalize()
+}
+
+/*
+Returns nil. The trigger statement is not evaluated by this
+statement.
+*/
+func (this *CreateTrigger) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateTrigger) Expressions() expression.Expressions {
+	return nil
+}
+
+/*
+Returns all required privileges: DDL on the keyspace, and those of
+the trigger statement.
+*/
+func (this *CreateTrigger) Privileges() (datastore.Privileges, errors.Error) {
+	privs, err := this.stmt.Privileges()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := datastore.NewPrivileges()
+	rv.Add(privs)
+	rv.Add(datastore.Privileges{
+		this.keyspace.Namespace() + ":" + this.keyspace.Keyspace(): datastore.PRIV_DDL,
+	})
+	return rv, nil
+}
+
+/*
+Returns the name of the trigger.
+*/
+func (this *CreateTrigger) Name() string {
+	return this.name
+}
+
+/*
+Returns the keyspace that the trigger is created on.
+*/
+func (this *CreateTrigger) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+/*
+Returns the events that run the trigger.
+*/
+func (this *CreateTrigger) Events() []string {
+	return this.events
+}
+
+/*
+Returns the WITH options.
+*/
+func (this *CreateTrigger) With() value.Value {
+	return this.with
+}
+
+/*
+Returns the trigger statement.
+*/
+func (this *CreateTrigger) Statement() Statement {
+	return this.stmt
+}
+
+/*
+Returns the text of the trigger statement.
+*/
+func (this *CreateTrigger) Text() string {
+	return this.text
+}
+
+/*
+Returns the trigger definition, after checking the WITH options.
+*/
+func (this *CreateTrigger) Trigger() (*datastore.Trigger, errors.Error) {
+	rv := &datastore.Trigger{
+		Name:      this.name,
+		Events:    this.events,
+		Statement: this.text,
+		OnError:   datastore.TRIGGER_ON_ERROR_FAIL,
+	}
+
+	if this.with == nil {
+		return rv, nil
+	}
+
+	if this.with.Type() != value.OBJECT {
+		return nil, errors.NewTriggerOptionError(this.with.String())
+	}
+
+	for name, field := range this.with.Fields() {
+		option := value.NewValue(field).Actual()
+		switch name {
+		case "async":
+			async, ok := option.(bool)
+			if !ok {
+				return nil, errors.NewTriggerOptionError(name)
+			}
+			rv.Async = async
+		case "on_error":
+			switch option {
+			case datastore.TRIGGER_ON_ERROR_FAIL, datastore.TRIGGER_ON_ERROR_WARN,
+				datastore.TRIGGER_ON_ERROR_IGNORE:
+				rv.OnError = option.(string)
+			default:
+				return nil, errors.NewTriggerOptionError(name)
+			}
+		default:
+			return nil, errors.NewTriggerOptionError(name)
+		}
+	}
+
+	return rv, nil
+}
+
+/*
+Marshals input receiver into byte array.
+*/
+func (this *CreateTrigger) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "createTrigger"}
+	r["keyspaceRef"] = this.keyspace
+	r["name"] = this.name
+	r["events"] = this.events
+	r["statement"] = this.text
+	if this.with != nil {
+		r["with"] = this.with
+	}
+
+	return json.Marshal(r)
+}
+
+const (
+	TRIGGER_NEW = "NEW"
+	TRIGGER_OLD = "OLD"
+)
+
+/*
+BindTriggerParameters maps the identifiers NEW and OLD in stmt, in any
+case, to the named parameters $NEW and $OLD, which hold the documents
+after and before a mutation when a trigger runs. It must be called
+before stmt is formalized.
+*/
+func BindTriggerParameters(stmt Statement) error {
+	return stmt.MapExpressions(newTriggerBinder())
+}
+
+type triggerBinder struct {
+	expression.MapperBase
+}
+
+func newTriggerBinder() *triggerBinder {
+	rv := &triggerBinder{}
+	rv.SetMapper(rv)
+	return rv
+}
+
+func (this *triggerBinder) VisitIdentifier(expr *expression.Identifier) (interface{}, error) {
+	switch name := strings.ToUpper(expr.Identifier()); name {
+	case TRIGGER_NEW, TRIGGER_OLD:
+		return NewNamedParameter(name), nil
+	default:
+		return expr, nil
+	}
+}
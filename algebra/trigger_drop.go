@@ -0,0 +1,113 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the Drop trigger ddl statement, with the keyspace and the
+trigger name.
+*/
+type DropTrigger struct {
+	statementBase
+
+	keyspace *KeyspaceRef `json:"keyspace"`
+	name     string       `json:"name"`
+}
+
+/*
+The function NewDropTrigger returns a pointer to the DropTrigger
+struct with the input argument values as fields.
+*/
+func NewDropTrigger(keyspace *KeyspaceRef, name string) *DropTrigger {
+	rv := &DropTrigger{
+		keyspace: keyspace,
+		name:     name,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+/*
+It calls the VisitDropTrigger method by passing in the receiver and
+returns the interface. It is a visitor pattern.
+*/
+func (this *DropTrigger) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDropTrigger(this)
+}
+
+/*
+Returns nil.
+*/
+func (this *DropTrigger) Signature() value.Value {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *DropTrigger) Formalize() error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *DropTrigger) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *DropTrigger) Expressions() expression.Expressions {
+	return nil
+}
+
+/*
+Returns all required privileges.
+*/
+func (this *DropTrigger) Privileges() (datastore.Privileges, errors.Error) {
+	return datastore.Privileges{
+		this.keyspace.Namespace() + ":" + this.keyspace.Keyspace(): datastore.PRIV_DDL,
+	}, nil
+}
+
+/*
+Return the keyspace.
+*/
+func (this *DropTrigger) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+/*
+Return the name of the trigger to be dropped.
+*/
+func (this *DropTrigger) Name() string {
+	return this.name
+}
+
+/*
+Marshals input receiver into byte array.
+*/
+func (this *DropTrigger) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "dropTrigger"}
+	r["keyspaceRef"] = this.keyspace
+	r["name"] = this.name
+	return json.Marshal(r)
+}
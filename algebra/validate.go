@@ -0,0 +1,103 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the VALIDATE statement, which returns the documents of a
+keyspace that do not match its schema, with their violations.
+*/
+type Validate struct {
+	statementBase
+
+	keyspace *KeyspaceRef `json:"keyspace"`
+}
+
+/*
+The function NewValidate returns a pointer to the Validate struct
+with the input argument values as fields.
+*/
+func NewValidate(keyspace *KeyspaceRef) *Validate {
+	rv := &Validate{
+		keyspace: keyspace,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+/*
+It calls the VisitValidate method by passing in the receiver and
+returns the interface. It is a visitor pattern.
+*/
+func (this *Validate) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitValidate(this)
+}
+
+/*
+Returns the shape of the results.
+*/
+func (this *Validate) Signature() value.Value {
+	return value.NewValue(map[string]interface{}{
+		"id":         value.STRING.String(),
+		"violations": value.ARRAY.String(),
+	})
+}
+
+/*
+Returns nil.
+*/
+func (this *Validate) Formalize() error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *Validate) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *Validate) Expressions() expression.Expressions {
+	return nil
+}
+
+/*
+Returns all required privileges.
+*/
+func (this *Validate) Privileges() (datastore.Privileges, errors.Error) {
+	return datastore.Privileges{
+		this.keyspace.Namespace() + ":" + this.keyspace.Keyspace(): datastore.PRIV_READ,
+	}, nil
+}
+
+func (this *Validate) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+/*
+Marshals input receiver into byte array.
+*/
+func (this *Validate) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "validate"}
+	r["keyspaceRef"] = this.keyspace
+	return json.Marshal(r)
+}
@@ -0,0 +1,232 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the Create materialized view ddl statement. The view
+stores the results of query in a read-only keyspace. The WITH clause
+sets the option refresh (incremental or manual).
+*/
+type CreateMaterializedView struct {
+	statementBase
+
+	keyspace *KeyspaceRef `json:"keyspace"`
+	with     value.Value  `json:"with"`
+	query    *Select      `json:"query"`
+	text     string       `json:"text"`
+}
+
+/*
+The function NewCreateMaterializedView returns a pointer to the
+CreateMaterializedView struct with the input argument values as
+fields.
+*/
+func NewCreateMaterializedView(keyspace *KeyspaceRef, with value.Value, query *Select,
+	text string) *CreateMaterializedView {
+	rv := &CreateMaterializedView{
+		keyspace: keyspace,
+		with:     with,
+		query:    query,
+		text:     text,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+/*
+It calls the VisitCreateMaterializedView method by passing in the
+receiver and returns the interface. It is a visitor pattern.
+*/
+func (this *CreateMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateMaterializedView(this)
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateMaterializedView) Signature() value.Value {
+	return nil
+}
+
+/*
+Formalize the view query.
+*/
+func (this *CreateMaterializedView) Formalize() error {
+	return this.query.Formalize()
+}
+
+/*
+Returns nil. The view query is not evaluated by this statement.
+*/
+func (this *CreateMaterializedView) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateMaterializedView) Expressions() expression.Expressions {
+	return nil
+}
+
+/*
+Returns all required privileges: DDL on the view, and those of the
+view query.
+*/
+func (this *CreateMaterializedView) Privileges() (datastore.Privileges, errors.Error) {
+	privs, err := this.query.Privileges()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := datastore.NewPrivileges()
+	rv.Add(privs)
+	rv.Add(datastore.Privileges{
+		this.keyspace.Namespace() + ":" + this.keyspace.Keyspace(): datastore.PRIV_DDL,
+	})
+	return rv, nil
+}
+
+/*
+Returns the name of the view, as a keyspace.
+*/
+func (this *CreateMaterializedView) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+/*
+Returns the WITH options.
+*/
+func (this *CreateMaterializedView) With() value.Value {
+	return this.with
+}
+
+/*
+Returns the view query.
+*/
+func (this *CreateMaterializedView) Query() *Select {
+	return this.query
+}
+
+/*
+Returns the text of the view query.
+*/
+func (this *CreateMaterializedView) Text() string {
+	return this.text
+}
+
+/*
+Returns the view definition, after checking the WITH options. Views
+are incremental by default when their query allows it.
+*/
+func (this *CreateMaterializedView) View() (*datastore.MaterializedView, errors.Error) {
+	rv := &datastore.MaterializedView{
+		Name:      this.keyspace.Keyspace(),
+		Statement: this.text,
+	}
+
+	if this.with != nil {
+		if this.with.Type() != value.OBJECT {
+			return nil, errors.NewViewOptionError(this.with.String())
+		}
+
+		for name, field := range this.with.Fields() {
+			option := value.NewValue(field).Actual()
+			switch name {
+			case "refresh":
+				switch option {
+				case datastore.VIEW_REFRESH_INCREMENTAL, datastore.VIEW_REFRESH_MANUAL:
+					rv.Refresh = option.(string)
+				default:
+					return nil, errors.NewViewOptionError(name)
+				}
+			default:
+				return nil, errors.NewViewOptionError(name)
+			}
+		}
+	}
+
+	_, _, err := IncrementalView(this.query)
+	switch {
+	case err == nil && rv.Refresh == "":
+		rv.Refresh = datastore.VIEW_REFRESH_INCREMENTAL
+	case err != nil && rv.Refresh == datastore.VIEW_REFRESH_INCREMENTAL:
+		return nil, err
+	case rv.Refresh == "":
+		rv.Refresh = datastore.VIEW_REFRESH_MANUAL
+	}
+
+	return rv, nil
+}
+
+/*
+Marshals input receiver into byte array.
+*/
+func (this *CreateMaterializedView) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "createMaterializedView"}
+	r["keyspaceRef"] = this.keyspace
+	r["statement"] = this.text
+	if this.with != nil {
+		r["with"] = this.with
+	}
+
+	return json.Marshal(r)
+}
+
+/*
+IncrementalView returns the subselect and keyspace term of a view
+query that can be maintained from the mutations of its keyspace: a
+single keyspace, with optional WHERE, GROUP BY and HAVING, and no
+subqueries. Otherwise it returns the reason why not.
+*/
+func IncrementalView(query *Select) (*Subselect, *KeyspaceTerm, errors.Error) {
+	if query.Order() != nil || query.Limit() != nil || query.Offset() != nil {
+		return nil, nil, errors.NewViewNotIncrementalError("ORDER BY, LIMIT and OFFSET are not supported.")
+	}
+
+	sub, ok := query.Subresult().(*Subselect)
+	if !ok {
+		return nil, nil, errors.NewViewNotIncrementalError("set operations are not supported.")
+	}
+
+	term, ok := sub.From().(*KeyspaceTerm)
+	if !ok {
+		return nil, nil, errors.NewViewNotIncrementalError("the query must select from a single keyspace.")
+	}
+
+	if term.Keys() != nil {
+		return nil, nil, errors.NewViewNotIncrementalError("USE KEYS is not supported.")
+	}
+
+	if sub.Let() != nil || (sub.Group() != nil && sub.Group().Letting() != nil) {
+		return nil, nil, errors.NewViewNotIncrementalError("LET and LETTING are not supported.")
+	}
+
+	if sub.Projection().Distinct() {
+		return nil, nil, errors.NewViewNotIncrementalError("DISTINCT is not supported.")
+	}
+
+	subqueries, err := expression.ListSubqueries(sub.Expressions(), false)
+	if err != nil || len(subqueries) > 0 {
+		return nil, nil, errors.NewViewNotIncrementalError("subqueries are not supported.")
+	}
+
+	return sub, term, nil
+}
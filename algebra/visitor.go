@@ -37,6 +37,50 @@ type Visitor interface {
 	VisitAlterIndex(stmt *AlterIndex) (interface{}, error)
 	VisitBuildIndexes(stmt *BuildIndexes) (interface{}, error)
 
+	/*
+	   Visitor for trigger statements CREATE TRIGGER and DROP
+	   TRIGGER.
+	*/
+	VisitCreateTrigger(stmt *CreateTrigger) (interface{}, error)
+	VisitDropTrigger(stmt *DropTrigger) (interface{}, error)
+
+	/*
+	   Visitor for materialized view statements CREATE, REFRESH and
+	   DROP MATERIALIZED VIEW.
+	*/
+	VisitCreateMaterializedView(stmt *CreateMaterializedView) (interface{}, error)
+	VisitRefreshMaterializedView(stmt *RefreshMaterializedView) (interface{}, error)
+	VisitDropMaterializedView(stmt *DropMaterializedView) (interface{}, error)
+
+	/*
+	   Visitor for CREATE VIEW and DROP VIEW.
+	*/
+	VisitCreateView(stmt *CreateView) (interface{}, error)
+	VisitDropView(stmt *DropView) (interface{}, error)
+
+	/*
+	   Visitor for CREATE SEQUENCE and DROP SEQUENCE.
+	*/
+	VisitCreateSequence(stmt *CreateSequence) (interface{}, error)
+	VisitDropSequence(stmt *DropSequence) (interface{}, error)
+
+	/*
+	   Visitor for CREATE PLAN BASELINE and DROP PLAN BASELINE.
+	*/
+	VisitCreatePlanBaseline(stmt *CreatePlanBaseline) (interface{}, error)
+	VisitDropPlanBaseline(stmt *DropPlanBaseline) (interface{}, error)
+
+	/*
+	   Visitor for ALTER KEYSPACE and VALIDATE.
+	*/
+	VisitAlterKeyspace(stmt *AlterKeyspace) (interface{}, error)
+	VisitValidate(stmt *Validate) (interface{}, error)
+
+	/*
+	   Visitor for ADVISE statements.
+	*/
+	VisitAdvise(stmt *Advise) (interface{}, error)
+
 	/*
 	   Visitor for EXPLAIN statements.
 	*/
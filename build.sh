@@ -29,6 +29,16 @@ cd shell/cbq
 ./build.sh $*
 cd ../..
 
+echo cd shell/cbq-import
+cd shell/cbq-import
+./build.sh $*
+cd ../..
+
+echo cd shell/cbq-export
+cd shell/cbq-export
+./build.sh $*
+cd ../..
+
 echo cd tutorial
 cd tutorial
 ./build.sh $*
@@ -0,0 +1,411 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+
+Package changes publishes the mutations made through the query engine
+to subscribers, so that clients can follow a keyspace instead of
+polling it.
+
+Every mutation is numbered with a sequence number per keyspace and
+kept in a bounded log. A subscriber may resume from the positions of
+the last events it has seen, as long as the log still holds the
+events that follow them.
+
+*/
+package changes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+const (
+	INSERT = "insert"
+	UPDATE = "update"
+	UPSERT = "upsert"
+	DELETE = "delete"
+)
+
+const (
+	_LOG_SIZE   = 10000
+	_QUEUE_SIZE = 10000
+)
+
+/*
+Event is a single mutation. For deletes, Doc is the document before
+it was deleted, if known; it is used for filtering but not sent to
+subscribers.
+*/
+type Event struct {
+	Keyspace string
+	Seqno    uint64
+	Op       string
+	Key      string
+	Doc      value.Value
+}
+
+func (this *Event) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{
+		"keyspace": this.Keyspace,
+		"seqno":    this.Seqno,
+		"op":       this.Op,
+		"key":      this.Key,
+	}
+
+	if this.Op != DELETE && this.Doc != nil {
+		r["doc"] = this.Doc
+	}
+
+	return json.Marshal(r)
+}
+
+/*
+Source is implemented by keyspaces that publish their own changes, so
+that the execution operators do not publish them again.
+*/
+type Source interface {
+	PublishesChanges() bool
+}
+
+func Publishes(keyspace datastore.Keyspace) bool {
+	source, ok := keyspace.(Source)
+	return ok && source.PublishesChanges()
+}
+
+func KeyspaceName(namespace, keyspace string) string {
+	return namespace + ":" + keyspace
+}
+
+/*
+Bus numbers the published events, keeps the most recent of them and
+hands them to the subscriptions.
+*/
+type Bus struct {
+	sync.Mutex
+	seqnos  map[string]uint64
+	evicted map[string]uint64
+	log     []*Event
+	next    int
+	full    bool
+	subs    map[*Subscription]bool
+	queue   int
+}
+
+func NewBus(logSize, queueSize int) *Bus {
+	return &Bus{
+		seqnos:  make(map[string]uint64),
+		evicted: make(map[string]uint64),
+		log:     make([]*Event, logSize),
+		subs:    make(map[*Subscription]bool),
+		queue:   queueSize,
+	}
+}
+
+var bus = NewBus(_LOG_SIZE, _QUEUE_SIZE)
+
+func DefaultBus() *Bus {
+	return bus
+}
+
+func Publish(namespace, keyspace, op, key string, doc value.Value) {
+	bus.Publish(namespace, keyspace, op, key, doc)
+}
+
+/*
+Publish records a mutation and queues it for the subscriptions to its
+keyspace. It never blocks on a subscriber. The document must not be
+modified afterwards.
+*/
+func (this *Bus) Publish(namespace, keyspace, op, key string, doc value.Value) {
+	name := KeyspaceName(namespace, keyspace)
+
+	// Annotations are not part of the document
+	for {
+		av, ok := doc.(value.AnnotatedValue)
+		if !ok {
+			break
+		}
+		doc = av.GetValue()
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.seqnos[name]++
+	event := &Event{
+		Keyspace: name,
+		Seqno:    this.seqnos[name],
+		Op:       op,
+		Key:      key,
+		Doc:      doc,
+	}
+
+	if len(this.log) > 0 {
+		if old := this.log[this.next]; old != nil {
+			this.evicted[old.Keyspace] = old.Seqno
+		}
+
+		this.log[this.next] = event
+		this.next++
+		if this.next == len(this.log) {
+			this.next = 0
+			this.full = true
+		}
+	}
+
+	for sub, _ := range this.subs {
+		if sub.keyspaces[name] && !sub.offer(event, this.queue) {
+			delete(this.subs, sub)
+		}
+	}
+}
+
+/*
+Seqno returns the sequence number of the last event of keyspace.
+*/
+func (this *Bus) Seqno(keyspace string) uint64 {
+	this.Lock()
+	defer this.Unlock()
+	return this.seqnos[keyspace]
+}
+
+/*
+Subscribe follows keyspaces, given as namespace:keyspace. Only events
+for which filter is true are returned; a nil filter returns them all.
+For a keyspace in since, the subscription starts after that sequence
+number, otherwise with the next event.
+*/
+func (this *Bus) Subscribe(keyspaces []string, filter expression.Expression,
+	since map[string]uint64) (*Subscription, errors.Error) {
+	sub := &Subscription{
+		bus:       this,
+		keyspaces: make(map[string]bool, len(keyspaces)),
+		filter:    filter,
+		context:   expression.NewIndexContext(),
+		notify:    make(chan bool, 1),
+		positions: make(map[string]uint64, len(keyspaces)),
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	for _, name := range keyspaces {
+		sub.keyspaces[name] = true
+
+		seqno, ok := since[name]
+		if !ok {
+			sub.positions[name] = this.seqnos[name]
+			continue
+		}
+
+		if seqno < this.evicted[name] || seqno > this.seqnos[name] {
+			return nil, errors.NewServiceErrorChangesPosition(name, seqno)
+		}
+
+		sub.positions[name] = seqno
+	}
+
+	// Replay the logged events after the positions
+	start := 0
+	if this.full {
+		start = this.next
+	}
+
+	for i := 0; i < len(this.log); i++ {
+		event := this.log[(start+i)%len(this.log)]
+		if event == nil {
+			break
+		}
+
+		if sub.keyspaces[event.Keyspace] && event.Seqno > sub.positions[event.Keyspace] {
+			sub.queue = append(sub.queue, event)
+		}
+	}
+
+	if len(sub.queue) > 0 {
+		sub.notify <- true
+	}
+
+	this.subs[sub] = true
+	return sub, nil
+}
+
+/*
+Subscription is the consumer side of Subscribe.
+*/
+type Subscription struct {
+	sync.Mutex
+	bus       *Bus
+	keyspaces map[string]bool
+	filter    expression.Expression
+	context   expression.Context
+	notify    chan bool
+	queue     []*Event
+	positions map[string]uint64
+	err       errors.Error
+	closed    bool
+}
+
+func (this *Subscription) offer(event *Event, limit int) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.closed {
+		return false
+	}
+
+	if limit > 0 && len(this.queue) >= limit {
+		this.err = errors.NewServiceErrorChangesOverflow()
+		this.closed = true
+	} else {
+		this.queue = append(this.queue, event)
+	}
+
+	select {
+	case this.notify <- true:
+	default:
+	}
+
+	return !this.closed
+}
+
+/*
+Ready receives a value when there may be events to Read.
+*/
+func (this *Subscription) Ready() <-chan bool {
+	return this.notify
+}
+
+/*
+Read returns the queued events that pass the filter. It returns an
+error once the subscription has fallen too far behind; the positions
+then tell where to resume.
+*/
+func (this *Subscription) Read() ([]*Event, errors.Error) {
+	this.Lock()
+	queue := this.queue
+	this.queue = nil
+	err := this.err
+	this.Unlock()
+
+	events := make([]*Event, 0, len(queue))
+	for _, event := range queue {
+		if this.matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	this.Lock()
+	for _, event := range queue {
+		this.positions[event.Keyspace] = event.Seqno
+	}
+	this.Unlock()
+
+	if len(events) == 0 && err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (this *Subscription) matches(event *Event) bool {
+	if this.filter == nil {
+		return true
+	}
+
+	var doc value.AnnotatedValue
+	if event.Doc != nil {
+		doc = value.NewAnnotatedValue(event.Doc)
+	} else {
+		// The document of a delete may be unknown
+		doc = value.NewAnnotatedValue(map[string]interface{}{})
+	}
+	doc.SetAttachment("meta", map[string]interface{}{"id": event.Key})
+
+	result, err := this.filter.Evaluate(doc, this.context)
+	return err == nil && result.Truth()
+}
+
+/*
+Positions returns the sequence number of the last event read, per
+keyspace.
+*/
+func (this *Subscription) Positions() map[string]uint64 {
+	this.Lock()
+	defer this.Unlock()
+
+	positions := make(map[string]uint64, len(this.positions))
+	for name, seqno := range this.positions {
+		positions[name] = seqno
+	}
+
+	return positions
+}
+
+func (this *Subscription) Close() {
+	this.bus.Lock()
+	delete(this.bus.subs, this)
+	this.bus.Unlock()
+
+	this.Lock()
+	this.closed = true
+	this.Unlock()
+}
+
+/*
+FormatPositions renders positions as ns:ks=seqno pairs separated by
+commas, the form accepted by ParsePositions.
+*/
+func FormatPositions(positions map[string]uint64) string {
+	names := make([]string, 0, len(positions))
+	for name, _ := range positions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.FormatUint(positions[name], 10)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func ParsePositions(s string) (map[string]uint64, error) {
+	positions := make(map[string]uint64)
+	if s == "" {
+		return positions, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.LastIndex(pair, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("Invalid position %s", pair)
+		}
+
+		seqno, err := strconv.ParseUint(pair[i+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid position %s", pair)
+		}
+
+		positions[strings.TrimSpace(pair[:i])] = seqno
+	}
+
+	return positions, nil
+}
@@ -0,0 +1,152 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package changes
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/couchbase/query/expression/parser"
+	"github.com/couchbase/query/value"
+)
+
+func doc(s string) value.Value {
+	return value.NewValue([]byte(s))
+}
+
+func read(t *testing.T, sub *Subscription) []*Event {
+	events, err := sub.Read()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	return events
+}
+
+func TestPublish(t *testing.T) {
+	bus := NewBus(100, 100)
+	sub, err := bus.Subscribe([]string{"default:orders"}, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer sub.Close()
+
+	bus.Publish("default", "orders", INSERT, "o1", doc(`{"total": 10}`))
+	bus.Publish("default", "items", INSERT, "i1", doc(`{}`))
+	bus.Publish("default", "orders", DELETE, "o1", doc(`{"total": 10}`))
+
+	select {
+	case <-sub.Ready():
+	default:
+		t.Fatalf("Expected subscription to be ready")
+	}
+
+	events := read(t, sub)
+	if len(events) != 2 || events[0].Seqno != 1 || events[1].Seqno != 2 || events[1].Op != DELETE {
+		t.Fatalf("Unexpected events %v", events)
+	}
+
+	buf, _ := json.Marshal(events[1])
+	if string(buf) != `{"key":"o1","keyspace":"default:orders","op":"delete","seqno":2}` {
+		t.Errorf("Unexpected delete event %s", buf)
+	}
+
+	if p := FormatPositions(sub.Positions()); p != "default:orders=2" {
+		t.Errorf("Unexpected positions %s", p)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	bus := NewBus(100, 100)
+	filter, _ := parser.Parse(`total > 5 OR META().id = "o3"`)
+	sub, _ := bus.Subscribe([]string{"default:orders"}, filter, nil)
+	defer sub.Close()
+
+	bus.Publish("default", "orders", INSERT, "o1", doc(`{"total": 10}`))
+	bus.Publish("default", "orders", INSERT, "o2", doc(`{"total": 1}`))
+	bus.Publish("default", "orders", DELETE, "o3", nil)
+
+	events := read(t, sub)
+	if len(events) != 2 || events[0].Key != "o1" || events[1].Key != "o3" {
+		t.Fatalf("Unexpected events %v", events)
+	}
+}
+
+func TestResume(t *testing.T) {
+	bus := NewBus(4, 100)
+	for i := 0; i < 3; i++ {
+		bus.Publish("default", "orders", UPSERT, "o1", doc(`{}`))
+	}
+
+	sub, err := bus.Subscribe([]string{"default:orders"}, nil, map[string]uint64{"default:orders": 1})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	events := read(t, sub)
+	if len(events) != 2 || events[0].Seqno != 2 || events[1].Seqno != 3 {
+		t.Errorf("Unexpected events %v", events)
+	}
+	sub.Close()
+
+	// Seqnos 1 and 2 are evicted
+	for i := 0; i < 3; i++ {
+		bus.Publish("default", "orders", UPSERT, "o1", doc(`{}`))
+	}
+
+	_, err = bus.Subscribe([]string{"default:orders"}, nil, map[string]uint64{"default:orders": 1})
+	if err == nil {
+		t.Errorf("Expected error resuming from an evicted position")
+	}
+
+	_, err = bus.Subscribe([]string{"default:orders"}, nil, map[string]uint64{"default:orders": 7})
+	if err == nil {
+		t.Errorf("Expected error resuming from a future position")
+	}
+
+	positions, e := ParsePositions("default:orders=2")
+	if e != nil {
+		t.Fatalf("Unexpected error %v", e)
+	}
+
+	sub, err = bus.Subscribe([]string{"default:orders"}, nil, positions)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer sub.Close()
+
+	events = read(t, sub)
+	if len(events) != 4 || events[0].Seqno != 3 {
+		t.Errorf("Unexpected events %v", events)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	bus := NewBus(10, 2)
+	sub, _ := bus.Subscribe([]string{"default:orders"}, nil, nil)
+	defer sub.Close()
+
+	for i := 0; i < 3; i++ {
+		bus.Publish("default", "orders", UPSERT, "o1", doc(`{}`))
+	}
+
+	events := read(t, sub)
+	if len(events) != 2 {
+		t.Errorf("Expected the queued events, got %v", events)
+	}
+
+	_, err := sub.Read()
+	if err == nil {
+		t.Errorf("Expected overflow error")
+	}
+
+	if p := FormatPositions(sub.Positions()); p != "default:orders=2" {
+		t.Errorf("Unexpected positions %s", p)
+	}
+}
@@ -0,0 +1,39 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package datastore
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/couchbase/query/errors"
+)
+
+/*
+PlanBaseline is the persisted form of a plan baseline: the normalized
+text of a statement, and its prepared plan encoded as JSON.
+*/
+type PlanBaseline struct {
+	Statement string          `json:"statement"`
+	Plan      json.RawMessage `json:"plan"`
+	Created   time.Time       `json:"created"`
+}
+
+/*
+BaselineNamespace is implemented by namespaces that can store plan
+baselines, so that they survive a restart of the query service.
+*/
+type BaselineNamespace interface {
+	Namespace
+
+	PlanBaselines() ([]*PlanBaseline, errors.Error)      // Sorted by statement
+	SetPlanBaseline(baseline *PlanBaseline) errors.Error // Replaces any baseline of the statement
+	DropPlanBaseline(statement string) errors.Error      // Fails if the statement has no baseline
+}
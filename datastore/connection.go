@@ -22,6 +22,77 @@ type Context interface {
 	Warning(errors.Error)
 }
 
+/*
+QueryContext lets keyspace operations, scans and inference be
+cancelled. Done is closed when the request is stopped, by a timeout or
+by the client going away. Err returns nil until then, and afterwards
+the error that an operation returns along with its partial results.
+*/
+type QueryContext interface {
+	Done() <-chan bool
+	Err() errors.Error
+}
+
+// NULL_QUERY_CONTEXT is never stopped. Used outside of requests.
+var NULL_QUERY_CONTEXT QueryContext = nullQueryContext{}
+
+type nullQueryContext struct{}
+
+func (this nullQueryContext) Done() <-chan bool {
+	return nil
+}
+
+func (this nullQueryContext) Err() errors.Error {
+	return nil
+}
+
+/*
+StoppableQueryContext is a QueryContext that is stopped by calling
+Stop. Used by tests to stop operations as a request would.
+*/
+type StoppableQueryContext struct {
+	done chan bool
+}
+
+func NewStoppableQueryContext() *StoppableQueryContext {
+	return &StoppableQueryContext{done: make(chan bool)}
+}
+
+func (this *StoppableQueryContext) Stop() {
+	close(this.done)
+}
+
+func (this *StoppableQueryContext) Done() <-chan bool {
+	return this.done
+}
+
+func (this *StoppableQueryContext) Err() errors.Error {
+	select {
+	case <-this.done:
+		return errors.NewExecutionStoppedError()
+	default:
+		return nil
+	}
+}
+
+/*
+queryDone and queryErr give the cancellation of the request that
+opened a connection, if its context is a QueryContext.
+*/
+func queryDone(context Context) <-chan bool {
+	if qc, ok := context.(QueryContext); ok {
+		return qc.Done()
+	}
+	return nil
+}
+
+func queryErr(context Context) errors.Error {
+	if qc, ok := context.(QueryContext); ok {
+		return qc.Err()
+	}
+	return nil
+}
+
 type ValueConnection struct {
 	valueChannel value.ValueChannel // Closed by the generator when the scan is completed or aborted.
 	stopChannel  StopChannel        // Notifies generator  to stop generating. Never closed, just garbage-collected.
@@ -51,6 +122,29 @@ func (this *ValueConnection) StopChannel() StopChannel {
 	return this.stopChannel
 }
 
+/*
+Send sends val to the consumer. It returns false if the generator is
+stopped, by the consumer or by its request, and should end.
+*/
+func (this *ValueConnection) Send(val value.Value) bool {
+	select {
+	case this.valueChannel <- val:
+		return true
+	case <-this.stopChannel:
+		return false
+	case <-this.Done():
+		return false
+	}
+}
+
+func (this *ValueConnection) Done() <-chan bool {
+	return queryDone(this.context)
+}
+
+func (this *ValueConnection) Err() errors.Error {
+	return queryErr(this.context)
+}
+
 func (this *ValueConnection) Fatal(err errors.Error) {
 	this.context.Fatal(err)
 }
@@ -0,0 +1,156 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package datastore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/util"
+)
+
+// Scans wait this long for consistency unless the request sets scan_wait
+const DEFAULT_SCAN_WAIT = time.Minute
+
+/*
+Sequenced is implemented by keyspaces that number their mutations.
+MutationToken returns the position, guard and sequence number of the
+last indexed mutation; clients pass it back in scan vectors.
+*/
+type Sequenced interface {
+	MutationToken() timestamp.Entry
+}
+
+/*
+ScanWaiter is implemented by the contexts of requests that bound the
+time scans may wait for consistency.
+*/
+type ScanWaiter interface {
+	ScanWait() time.Duration
+}
+
+/*
+Seqnos numbers the mutations of a keyspace that is a single partition,
+at position 0, and lets scans wait until the index reflects them. The
+guard identifies this instance of the keyspace, so that tokens from an
+earlier instance, whose mutations are all indexed, are recognized.
+*/
+type Seqnos struct {
+	sync.Mutex
+	guard    string
+	assigned uint64
+	indexed  uint64
+	changed  chan bool // Closed when indexed advances
+}
+
+func NewSeqnos() *Seqnos {
+	guard, _ := util.UUID()
+	return &Seqnos{
+		guard:   guard,
+		changed: make(chan bool),
+	}
+}
+
+// Assign numbers a mutation before it is indexed.
+func (this *Seqnos) Assign() uint64 {
+	this.Lock()
+	defer this.Unlock()
+	this.assigned++
+	return this.assigned
+}
+
+// Index records that the mutations up to seqno are indexed.
+func (this *Seqnos) Index(seqno uint64) {
+	this.Lock()
+	defer this.Unlock()
+	if seqno <= this.indexed {
+		return
+	}
+
+	this.indexed = seqno
+	close(this.changed)
+	this.changed = make(chan bool)
+}
+
+func (this *Seqnos) Token() timestamp.Entry {
+	this.Lock()
+	defer this.Unlock()
+	return &seqnoEntry{guard: this.guard, seqno: this.indexed}
+}
+
+/*
+Wait waits until the index reflects the mutations a scan must see: all
+those assigned when the scan starts for SCAN_PLUS, or those up to the
+vector entry at position 0 for AT_PLUS. It returns false if the scan
+or its request is stopped, or after reporting an error if the wait
+times out.
+*/
+func (this *Seqnos) Wait(cons ScanConsistency, vector timestamp.Vector, conn *IndexConnection) bool {
+	var target uint64
+	this.Lock()
+	switch cons {
+	case SCAN_PLUS:
+		target = this.assigned
+	case AT_PLUS:
+		if vector != nil {
+			for _, entry := range vector.Entries() {
+				if entry.Position() == 0 && entry.Guard() == this.guard {
+					target = entry.Value()
+				}
+			}
+		}
+	}
+	this.Unlock()
+
+	wait := conn.ScanWait()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		this.Lock()
+		indexed, changed := this.indexed, this.changed
+		this.Unlock()
+
+		if indexed >= target {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			conn.Error(errors.NewOtherScanWaitTimeoutError(target, wait))
+			return false
+		case <-conn.StopChannel():
+			return false
+		case <-conn.Done():
+			return false
+		}
+	}
+}
+
+// seqnoEntry implements timestamp.Entry
+type seqnoEntry struct {
+	guard string
+	seqno uint64
+}
+
+func (this *seqnoEntry) Position() uint32 {
+	return 0
+}
+
+func (this *seqnoEntry) Guard() string {
+	return this.guard
+}
+
+func (this *seqnoEntry) Value() uint64 {
+	return this.seqno
+}
@@ -703,12 +703,17 @@ func (b *keyspace) Indexers() ([]datastore.Indexer, errors.Error) {
 	return indexers, nil
 }
 
-func (b *keyspace) Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) {
+func (b *keyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
 
 	if len(keys) == 0 {
 		return nil, nil
 	}
 
+	// A bulk get cannot be interrupted
+	if err := context.Err(); err != nil {
+		return nil, []errors.Error{err}
+	}
+
 	bulkResponse, keyCount, err := b.cbbucket.GetBulk(keys)
 	defer b.cbbucket.ReleaseGetBulkPools(keyCount, bulkResponse)
 
@@ -806,7 +811,7 @@ func getMeta(key string, meta map[string]interface{}) (cas uint64, flags uint32,
 
 }
 
-func (b *keyspace) performOp(op int, inserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *keyspace) performOp(op int, inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 
 	if len(inserts) == 0 {
 		return nil, nil
@@ -816,6 +821,10 @@ func (b *keyspace) performOp(op int, inserts []value.Pair) ([]value.Pair, errors
 	var err error
 
 	for _, kv := range inserts {
+		if stopped := context.Err(); stopped != nil {
+			return insertedKeys, stopped
+		}
+
 		key := kv.Name
 		val := kv.Value.Actual()
 
@@ -879,25 +888,29 @@ func (b *keyspace) performOp(op int, inserts []value.Pair) ([]value.Pair, errors
 
 }
 
-func (b *keyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
-	return b.performOp(INSERT, inserts)
+func (b *keyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return b.performOp(INSERT, inserts, context)
 
 }
 
-func (b *keyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
-	return b.performOp(UPDATE, updates)
+func (b *keyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return b.performOp(UPDATE, updates, context)
 }
 
-func (b *keyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
-	return b.performOp(UPSERT, upserts)
+func (b *keyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return b.performOp(UPSERT, upserts, context)
 }
 
-func (b *keyspace) Delete(deletes []string) ([]string, errors.Error) {
+func (b *keyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
 
 	failedDeletes := make([]string, 0)
 	actualDeletes := make([]string, 0)
 	var err error
 	for _, key := range deletes {
+		if stopped := context.Err(); stopped != nil {
+			return actualDeletes, stopped
+		}
+
 		if err = b.cbbucket.Delete(key); err != nil {
 			if !isNotFoundError(err) {
 				logging.Infof("Failed to delete key %s Error %s", key, err)
@@ -86,7 +86,7 @@ func TestServer(t *testing.T) {
 		fmt.Printf("primary index created %v", index)
 	}
 
-	pair, errs := ks.Fetch([]string{"357", "aass_brewery"})
+	pair, errs := ks.Fetch([]string{"357", "aass_brewery"}, datastore.NULL_QUERY_CONTEXT)
 	if errs != nil {
 		t.Fatalf(" Cannot fetch keys errors %v", errs)
 
@@ -95,12 +95,12 @@ func TestServer(t *testing.T) {
 	fmt.Printf("Keys fetched %v", pair)
 	insertKey := value.Pair{Name: "testBeerKey", Value: value.NewValue(("This is a random test key-value"))}
 
-	_, err = ks.Insert([]value.Pair{insertKey})
+	_, err = ks.Insert([]value.Pair{insertKey}, datastore.NULL_QUERY_CONTEXT)
 	if err != nil {
 		t.Fatalf("Cannot insert key %v", insertKey)
 	}
 
-	deleted, err := ks.Delete([]string{insertKey.Name})
+	deleted, err := ks.Delete([]string{insertKey.Name}, datastore.NULL_QUERY_CONTEXT)
 	if err != nil || (len(deleted) != 1 && deleted[0] != insertKey.Name) {
 		t.Fatalf("Failed to delete %v", err)
 	}
@@ -437,6 +437,9 @@ func (vi *viewIndex) Scan(requestId string, span *datastore.Span, distinct bool,
 				case <-conn.StopChannel():
 					logging.Debugf(" Asked to stop after sending %v rows", numRows)
 					ok = false
+				case <-conn.Done():
+					logging.Debugf(" Request stopped after sending %v rows", numRows)
+					ok = false
 				}
 			}
 		case err, ok = <-viewErrChannel:
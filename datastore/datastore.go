@@ -71,15 +71,16 @@ type Keyspace interface {
 	Indexers() ([]Indexer, errors.Error)            // List of index providers
 
 	// Used by both SELECT and DML statements
-	Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) // Bulk key-value fetch from this keyspace
+	// When context is stopped, operations return their partial results and context.Err()
+	Fetch(keys []string, context QueryContext) ([]value.AnnotatedPair, []errors.Error) // Bulk key-value fetch from this keyspace
 	//Fetch(keys []string, projection, filter expression.Expression) ([]value.AnnotatedPair, errors.Error) // Bulk key-value fetch from this keyspace
 
 	// Used by DML statements
 	// For insert and upsert, nil input keys are replaced with auto-generated keys
-	Insert(inserts []value.Pair) ([]value.Pair, errors.Error) // Bulk key-value insert into this keyspace
-	Update(updates []value.Pair) ([]value.Pair, errors.Error) // Bulk key-value updates into this keyspace
-	Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) // Bulk key-value upserts into this keyspace
-	Delete(deletes []string) ([]string, errors.Error)         // Bulk key-value deletes from this keyspace
+	Insert(inserts []value.Pair, context QueryContext) ([]value.Pair, errors.Error) // Bulk key-value insert into this keyspace
+	Update(updates []value.Pair, context QueryContext) ([]value.Pair, errors.Error) // Bulk key-value updates into this keyspace
+	Upsert(upserts []value.Pair, context QueryContext) ([]value.Pair, errors.Error) // Bulk key-value upserts into this keyspace
+	Delete(deletes []string, context QueryContext) ([]string, errors.Error)         // Bulk key-value deletes from this keyspace
 
 	Release() // Release any resources held by this object
 }
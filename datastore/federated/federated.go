@@ -0,0 +1,282 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+
+Package federated provides a datastore that combines several
+datastores, each under its own namespace, so that a single query can
+join or union keyspaces of different stores.
+
+Each namespace of a federated datastore is the namespace of the same
+name of its store, or the default namespace of its store if it has
+none. Authorization, indexers and inferencers are delegated to the
+store that owns the keyspace.
+
+*/
+package federated
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/value"
+)
+
+type store struct {
+	id     string
+	stores map[string]datastore.Datastore // Store of each namespace
+	names  []string                       // Sorted namespace names
+}
+
+/*
+NewDatastore returns a datastore with a namespace for each entry of
+stores. Several namespaces can share a store.
+*/
+func NewDatastore(stores map[string]datastore.Datastore) (datastore.Datastore, errors.Error) {
+	if len(stores) == 0 {
+		return nil, errors.NewOtherDatastoreError(nil, "Federated datastore requires at least one namespace.")
+	}
+
+	s := &store{
+		stores: make(map[string]datastore.Datastore, len(stores)),
+		names:  make([]string, 0, len(stores)),
+	}
+
+	for name, ds := range stores {
+		if name == "" || name == "#system" || ds == nil {
+			return nil, errors.NewOtherDatastoreError(nil,
+				fmt.Sprintf("Invalid federated namespace %s.", name))
+		}
+
+		s.stores[name] = ds
+		s.names = append(s.names, name)
+	}
+
+	sort.Strings(s.names)
+	urls := make([]string, len(s.names))
+	for i, name := range s.names {
+		urls[i] = name + "=" + s.stores[name].URL()
+	}
+	s.id = "federated:" + strings.Join(urls, ",")
+
+	return s, nil
+}
+
+func (s *store) Id() string {
+	return s.id
+}
+
+func (s *store) URL() string {
+	return s.id
+}
+
+func (s *store) NamespaceIds() ([]string, errors.Error) {
+	return s.NamespaceNames()
+}
+
+func (s *store) NamespaceNames() ([]string, errors.Error) {
+	rv := make([]string, len(s.names))
+	copy(rv, s.names)
+	return rv, nil
+}
+
+func (s *store) NamespaceById(id string) (datastore.Namespace, errors.Error) {
+	return s.NamespaceByName(id)
+}
+
+func (s *store) NamespaceByName(name string) (datastore.Namespace, errors.Error) {
+	ds, ok := s.stores[name]
+	if !ok {
+		return nil, errors.NewOtherNamespaceNotFoundError(nil, name+" for federated datastore")
+	}
+
+	ns, err := ds.NamespaceByName(name)
+	if err != nil && name != "default" {
+		ns, err = ds.NamespaceByName("default")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &namespace{store: s, name: name, datastore: ds, namespace: ns}, nil
+}
+
+/*
+Authorize authorizes the privileges of each namespace with its store.
+Privileges on other namespaces, such as #system, are not checked.
+*/
+func (s *store) Authorize(privileges datastore.Privileges, credentials datastore.Credentials) errors.Error {
+	byStore := make(map[datastore.Datastore]datastore.Privileges, len(s.stores))
+	for key, privilege := range privileges {
+		name, keyspace := "default", key
+		if i := strings.Index(key, ":"); i >= 0 {
+			name, keyspace = key[:i], key[i+1:]
+		}
+
+		ns, err := s.NamespaceByName(name)
+		if err != nil {
+			continue
+		}
+
+		n := ns.(*namespace)
+		privs, ok := byStore[n.datastore]
+		if !ok {
+			privs = datastore.NewPrivileges()
+			byStore[n.datastore] = privs
+		}
+		privs.Add(datastore.Privileges{n.namespace.Name() + ":" + keyspace: privilege})
+	}
+
+	for ds, privs := range byStore {
+		err := ds.Authorize(privs, credentials)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *store) SetLogLevel(level logging.Level) {
+	for _, ds := range s.distinct() {
+		ds.SetLogLevel(level)
+	}
+}
+
+func (s *store) Inferencer(name datastore.InferenceType) (datastore.Inferencer, errors.Error) {
+	if name == "" {
+		name = datastore.INF_DEFAULT
+	}
+	return &inferencer{name: name}, nil
+}
+
+func (s *store) Inferencers() ([]datastore.Inferencer, errors.Error) {
+	seen := make(map[datastore.InferenceType]bool)
+	rv := make([]datastore.Inferencer, 0, 1)
+	for _, ds := range s.distinct() {
+		infs, err := ds.Inferencers()
+		if err != nil {
+			continue
+		}
+
+		for _, inf := range infs {
+			if inf != nil && !seen[inf.Name()] {
+				seen[inf.Name()] = true
+				rv = append(rv, &inferencer{name: inf.Name()})
+			}
+		}
+	}
+	return rv, nil
+}
+
+// distinct returns the stores of the namespaces, without duplicates.
+func (s *store) distinct() []datastore.Datastore {
+	seen := make(map[datastore.Datastore]bool, len(s.stores))
+	rv := make([]datastore.Datastore, 0, len(s.stores))
+	for _, name := range s.names {
+		ds := s.stores[name]
+		if !seen[ds] {
+			seen[ds] = true
+			rv = append(rv, ds)
+		}
+	}
+	return rv
+}
+
+// namespace is a namespace of a store, under its federated name.
+type namespace struct {
+	store     *store
+	name      string
+	datastore datastore.Datastore
+	namespace datastore.Namespace
+}
+
+func (p *namespace) DatastoreId() string {
+	return p.store.Id()
+}
+
+func (p *namespace) Id() string {
+	return p.name
+}
+
+func (p *namespace) Name() string {
+	return p.name
+}
+
+func (p *namespace) KeyspaceIds() ([]string, errors.Error) {
+	return p.namespace.KeyspaceIds()
+}
+
+func (p *namespace) KeyspaceNames() ([]string, errors.Error) {
+	return p.namespace.KeyspaceNames()
+}
+
+func (p *namespace) KeyspaceById(id string) (datastore.Keyspace, errors.Error) {
+	ks, err := p.namespace.KeyspaceById(id)
+	if err != nil {
+		return nil, err
+	}
+	return &keyspace{Keyspace: ks, namespace: p}, nil
+}
+
+func (p *namespace) KeyspaceByName(name string) (datastore.Keyspace, errors.Error) {
+	ks, err := p.namespace.KeyspaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return &keyspace{Keyspace: ks, namespace: p}, nil
+}
+
+/*
+keyspace is a keyspace of a store, in its federated namespace. Plans
+refer to keyspaces by namespace, so that the keyspace must report the
+federated namespace.
+*/
+type keyspace struct {
+	datastore.Keyspace
+	namespace *namespace
+}
+
+func (b *keyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+/*
+inferencer delegates schema inference to the inferencer of the store
+of the keyspace.
+*/
+type inferencer struct {
+	name datastore.InferenceType
+}
+
+func (this *inferencer) Name() datastore.InferenceType {
+	return this.name
+}
+
+func (this *inferencer) InferKeyspace(ks datastore.Keyspace, with value.Value, conn *datastore.ValueConnection) {
+	b, ok := ks.(*keyspace)
+	if !ok {
+		conn.Error(errors.NewInferencerNotFoundError(nil, string(this.name)))
+		close(conn.ValueChannel())
+		return
+	}
+
+	inf, err := b.namespace.datastore.Inferencer(this.name)
+	if err != nil || inf == nil {
+		conn.Error(errors.NewInferencerNotFoundError(err, string(this.name)))
+		close(conn.ValueChannel())
+		return
+	}
+
+	inf.InferKeyspace(b.Keyspace, with, conn)
+}
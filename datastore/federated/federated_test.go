@@ -0,0 +1,108 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package federated
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mem"
+	"github.com/couchbase/query/errors"
+)
+
+// authStore records the privileges it authorizes.
+type authStore struct {
+	*mem.Store
+	privileges datastore.Privileges
+}
+
+func (s *authStore) Authorize(privileges datastore.Privileges, credentials datastore.Credentials) errors.Error {
+	s.privileges = privileges
+	return nil
+}
+
+func newMemStore(t *testing.T, uri string) *authStore {
+	s, err := mem.NewStore(uri)
+	if err != nil {
+		t.Fatalf("failed to create store %s: %v", uri, err)
+	}
+	return &authStore{Store: s}
+}
+
+func TestFederated(t *testing.T) {
+	orders := newMemStore(t, "mem:orders")
+	fixtures := newMemStore(t, "mem:customers,fixtures:products")
+
+	s, err := NewDatastore(map[string]datastore.Datastore{
+		"prod":     orders,
+		"default":  fixtures,
+		"fixtures": fixtures,
+	})
+	if err != nil {
+		t.Fatalf("failed to create federated store: %v", err)
+	}
+
+	names, _ := s.NamespaceNames()
+	if len(names) != 3 || names[0] != "default" || names[1] != "fixtures" || names[2] != "prod" {
+		t.Errorf("unexpected namespaces %v", names)
+	}
+
+	p, err := s.NamespaceByName("prod")
+	if err != nil {
+		t.Fatalf("expected namespace prod: %v", err)
+	}
+
+	if p.DatastoreId() != s.Id() {
+		t.Errorf("expected namespace of federated store, got %s", p.DatastoreId())
+	}
+
+	b, err := p.KeyspaceByName("orders")
+	if err != nil {
+		t.Fatalf("expected keyspace prod:orders: %v", err)
+	}
+
+	if b.NamespaceId() != "prod" {
+		t.Errorf("expected keyspace in namespace prod, got %s", b.NamespaceId())
+	}
+
+	p, _ = s.NamespaceByName("fixtures")
+	_, err = p.KeyspaceByName("products")
+	if err != nil {
+		t.Errorf("expected keyspace products in namespace fixtures of store: %v", err)
+	}
+
+	_, err = p.KeyspaceByName("customers")
+	if err == nil {
+		t.Errorf("expected keyspace customers not to be in namespace fixtures")
+	}
+
+	_, err = s.NamespaceByName("test")
+	if err == nil {
+		t.Errorf("expected namespace test not to exist")
+	}
+
+	privs := datastore.Privileges{
+		"prod:orders":     datastore.PRIV_WRITE,
+		"default:orders":  datastore.PRIV_READ,
+		"#system:indexes": datastore.PRIV_READ,
+	}
+	err = s.Authorize(privs, nil)
+	if err != nil {
+		t.Fatalf("unexpected authorization error: %v", err)
+	}
+
+	if len(orders.privileges) != 1 || orders.privileges["default:orders"] != datastore.PRIV_WRITE {
+		t.Errorf("unexpected privileges of prod store %v", orders.privileges)
+	}
+
+	if len(fixtures.privileges) != 1 || fixtures.privileges["default:orders"] != datastore.PRIV_READ {
+		t.Errorf("unexpected privileges of fixtures store %v", fixtures.privileges)
+	}
+}
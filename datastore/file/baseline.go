@@ -0,0 +1,120 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+/*
+Plan baselines are persisted as files in the directory of their
+namespace. Statements are not valid file names, so the files are
+named by the hash of the statement.
+*/
+const _BASELINE_EXT = ".baseline"
+
+func (p *namespace) loadPlanBaselines() errors.Error {
+	p.baselines = make(map[string]*datastore.PlanBaseline)
+
+	dirEntries, er := ioutil.ReadDir(p.path())
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || filepath.Ext(dirEntry.Name()) != _BASELINE_EXT {
+			continue
+		}
+
+		baseline := &datastore.PlanBaseline{}
+		e := readIndexFile(filepath.Join(p.path(), dirEntry.Name()), baseline)
+		if e != nil {
+			return e
+		}
+
+		p.baselines[baseline.Statement] = baseline
+	}
+
+	return nil
+}
+
+func (p *namespace) PlanBaselines() ([]*datastore.PlanBaseline, errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	rv := make(planBaselines, 0, len(p.baselines))
+	for _, baseline := range p.baselines {
+		rv = append(rv, baseline)
+	}
+
+	sort.Sort(rv)
+	return rv, nil
+}
+
+func (p *namespace) SetPlanBaseline(baseline *datastore.PlanBaseline) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	bytes, er := json.Marshal(baseline)
+	if er == nil {
+		er = ioutil.WriteFile(p.baselinePath(baseline.Statement), bytes, 0666)
+	}
+
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	p.baselines[baseline.Statement] = baseline
+	return nil
+}
+
+func (p *namespace) DropPlanBaseline(statement string) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.baselines[statement]; !ok {
+		return errors.NewPlanBaselineNotFoundError(statement)
+	}
+
+	er := os.Remove(p.baselinePath(statement))
+	if er != nil && !os.IsNotExist(er) {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	delete(p.baselines, statement)
+	return nil
+}
+
+func (p *namespace) baselinePath(statement string) string {
+	return filepath.Join(p.path(), fmt.Sprintf("%x", sha1.Sum([]byte(statement)))+_BASELINE_EXT)
+}
+
+type planBaselines []*datastore.PlanBaseline
+
+func (this planBaselines) Len() int {
+	return len(this)
+}
+
+func (this planBaselines) Less(i, j int) bool {
+	return this[i].Statement < this[j].Statement
+}
+
+func (this planBaselines) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+}
@@ -21,9 +21,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
+	"github.com/couchbase/query/changes"
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
@@ -134,10 +136,14 @@ func (s *store) loadNamespaces() (e errors.Error) {
 
 // namespace represents a file-based Namespace.
 type namespace struct {
+	sync.RWMutex
 	store         *store
 	name          string
 	keyspaces     map[string]*keyspace
 	keyspaceNames []string
+	views         map[string]*datastore.NamedView
+	sequences     map[string]*datastore.SequenceCounter
+	baselines     map[string]*datastore.PlanBaseline
 }
 
 func (p *namespace) DatastoreId() string {
@@ -157,6 +163,8 @@ func (p *namespace) KeyspaceIds() ([]string, errors.Error) {
 }
 
 func (p *namespace) KeyspaceNames() ([]string, errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
 	return p.keyspaceNames, nil
 }
 
@@ -165,6 +173,9 @@ func (p *namespace) KeyspaceById(id string) (b datastore.Keyspace, e errors.Erro
 }
 
 func (p *namespace) KeyspaceByName(name string) (b datastore.Keyspace, e errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
+
 	b, ok := p.keyspaces[strings.ToUpper(name)]
 	if !ok {
 		e = errors.NewFileKeyspaceNotFoundError(nil, name)
@@ -184,6 +195,15 @@ func newNamespace(s *store, dir string) (p *namespace, e errors.Error) {
 	p.name = dir
 
 	e = p.loadKeyspaces()
+	if e == nil {
+		e = p.loadNamedViews()
+	}
+	if e == nil {
+		e = p.loadSequences()
+	}
+	if e == nil {
+		e = p.loadPlanBaselines()
+	}
 	return
 }
 
@@ -222,6 +242,12 @@ type keyspace struct {
 	namespace *namespace
 	name      string
 	fi        datastore.Indexer
+	fts       *ftsIndexer
+	geo       *geoIndexer
+	triggers  triggers
+	view      *datastore.MaterializedView
+	schema    keyspaceSchema
+	seqnos    *datastore.Seqnos
 	fileLock  sync.Mutex
 }
 
@@ -242,21 +268,39 @@ func (b *keyspace) Count() (int64, errors.Error) {
 	if er != nil {
 		return 0, errors.NewFileDatastoreError(er, "")
 	}
-	return int64(len(dirEntries)), nil
+
+	var n int64
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			n++
+		}
+	}
+	return n, nil
 }
 
 func (b *keyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	switch name {
+	case datastore.FTS:
+		return b.fts, nil
+	case datastore.GEO:
+		return b.geo, nil
+	}
 	return b.fi, nil
 }
 
 func (b *keyspace) Indexers() ([]datastore.Indexer, errors.Error) {
-	return []datastore.Indexer{b.fi}, nil
+	return []datastore.Indexer{b.fi, b.fts, b.geo}, nil
 }
 
-func (b *keyspace) Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) {
+func (b *keyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
 	var errs []errors.Error
 	rv := make([]value.AnnotatedPair, 0, len(keys))
 	for _, k := range keys {
+		if err := context.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
 		item, e := b.fetchOne(k)
 
 		if e != nil {
@@ -316,7 +360,7 @@ func opToString(op int) string {
 	return "unknown operation"
 }
 
-func (b *keyspace) performOp(op int, kvPairs []value.Pair) ([]value.Pair, errors.Error) {
+func (b *keyspace) performOp(op int, kvPairs []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 
 	if len(kvPairs) == 0 {
 		return nil, errors.NewFileNoKeysInsertError(nil, "keyspace "+b.Name())
@@ -324,6 +368,7 @@ func (b *keyspace) performOp(op int, kvPairs []value.Pair) ([]value.Pair, errors
 
 	insertedKeys := make([]value.Pair, 0)
 	var returnErr errors.Error
+	var seqno uint64
 
 	// this lock can be mode more granular FIXME
 	b.fileLock.Lock()
@@ -333,6 +378,12 @@ func (b *keyspace) performOp(op int, kvPairs []value.Pair) ([]value.Pair, errors
 		var file *os.File
 		var err error
 
+		// Index and publish the mutations done so far
+		if stopped := context.Err(); stopped != nil {
+			returnErr = stopped
+			break
+		}
+
 		key := kv.Name
 		value, _ := json.Marshal(kv.Value.Actual())
 		filename := filepath.Join(b.path(), key+".json")
@@ -372,40 +423,100 @@ func (b *keyspace) performOp(op int, kvPairs []value.Pair) ([]value.Pair, errors
 			returnErr = errors.NewFileDMLError(returnErr, opToString(op)+" Failed "+err.Error())
 		} else {
 			insertedKeys = append(insertedKeys, kv)
+			seqno = b.seqnos.Assign()
 		}
 	}
 
+	if err := b.fts.update(insertedKeys); err != nil {
+		returnErr = errors.NewFileDMLError(returnErr, opToString(op)+" Failed "+err.Error())
+	}
+
+	if err := b.geo.update(insertedKeys); err != nil {
+		returnErr = errors.NewFileDMLError(returnErr, opToString(op)+" Failed "+err.Error())
+	}
+
+	// The indexes now reflect the mutations
+	b.seqnos.Index(seqno)
+
+	for _, kv := range insertedKeys {
+		changes.Publish(b.NamespaceId(), b.Name(), opToString(op), kv.Name, kv.Value)
+	}
+
 	return insertedKeys, returnErr
 
 }
 
-func (b *keyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
-	return b.performOp(INSERT, inserts)
+func (b *keyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	if b.view != nil {
+		return nil, errors.NewViewReadOnlyError(b.name)
+	}
+	return b.performOp(INSERT, inserts, context)
 }
 
-func (b *keyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
-	return b.performOp(UPDATE, updates)
+func (b *keyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	if b.view != nil {
+		return nil, errors.NewViewReadOnlyError(b.name)
+	}
+	return b.performOp(UPDATE, updates, context)
 }
 
-func (b *keyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
-	return b.performOp(UPSERT, upserts)
+func (b *keyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	if b.view != nil {
+		return nil, errors.NewViewReadOnlyError(b.name)
+	}
+	return b.performOp(UPSERT, upserts, context)
 }
 
-func (b *keyspace) Delete(deletes []string) ([]string, errors.Error) {
+func (b *keyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	if b.view != nil {
+		return nil, errors.NewViewReadOnlyError(b.name)
+	}
+	return b.delete(deletes, context)
+}
 
+func (b *keyspace) delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
 	var fileError []string
 	var deleted []string
+	var seqno uint64
+	var stopped errors.Error
 	for _, key := range deletes {
+		if stopped = context.Err(); stopped != nil {
+			break
+		}
+
 		filename := filepath.Join(b.path(), key+".json")
+
+		// Keep the document for change subscribers
+		var doc value.Value
+		if bytes, err := ioutil.ReadFile(filename); err == nil {
+			doc = value.NewValue(bytes)
+		}
+
 		if err := os.Remove(filename); err != nil {
 			if !os.IsNotExist(err) {
 				fileError = append(fileError, err.Error())
 			}
 		} else {
 			deleted = append(deleted, key)
+			seqno = b.seqnos.Assign()
+			changes.Publish(b.NamespaceId(), b.Name(), changes.DELETE, key, doc)
 		}
 	}
 
+	if err := b.fts.remove(deleted); err != nil {
+		fileError = append(fileError, err.Error())
+	}
+
+	if err := b.geo.remove(deleted); err != nil {
+		fileError = append(fileError, err.Error())
+	}
+
+	b.seqnos.Index(seqno)
+
+	if stopped != nil {
+		return deleted, stopped
+	}
+
 	if len(fileError) > 0 {
 		errLine := fmt.Sprintf("Delete failed on some keys %v", fileError)
 		return deleted, errors.NewFileDatastoreError(nil, errLine)
@@ -417,6 +528,14 @@ func (b *keyspace) Delete(deletes []string) ([]string, errors.Error) {
 func (b *keyspace) Release() {
 }
 
+func (b *keyspace) PublishesChanges() bool {
+	return true
+}
+
+func (b *keyspace) MutationToken() timestamp.Entry {
+	return b.seqnos.Token()
+}
+
 func (b *keyspace) path() string {
 	return filepath.Join(b.namespace.path(), b.name)
 }
@@ -426,6 +545,7 @@ func newKeyspace(p *namespace, dir string) (b *keyspace, e errors.Error) {
 	b = new(keyspace)
 	b.namespace = p
 	b.name = dir
+	b.seqnos = datastore.NewSeqnos()
 
 	fi, er := os.Stat(b.path())
 	if er != nil {
@@ -439,6 +559,31 @@ func newKeyspace(p *namespace, dir string) (b *keyspace, e errors.Error) {
 	b.fi = newFileIndexer(b)
 	b.fi.CreatePrimaryIndex("", "#primary", nil)
 
+	b.fts, e = newFtsIndexer(b)
+	if e != nil {
+		return nil, e
+	}
+
+	b.geo, e = newGeoIndexer(b)
+	if e != nil {
+		return nil, e
+	}
+
+	e = b.loadTriggers()
+	if e != nil {
+		return nil, e
+	}
+
+	e = b.loadSchema()
+	if e != nil {
+		return nil, e
+	}
+
+	e = b.loadView()
+	if e != nil {
+		return nil, e
+	}
+
 	return
 }
 
@@ -575,7 +720,12 @@ func (pi *primaryIndex) State() (state datastore.IndexState, msg string, err err
 
 func (pi *primaryIndex) Statistics(requestId string, span *datastore.Span) (
 	datastore.Statistics, errors.Error) {
-	return nil, nil
+	ids, err := pi.ids(span)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStatistics(ids), nil
 }
 
 func (pi *primaryIndex) Drop(requestId string) errors.Error {
@@ -586,19 +736,53 @@ func (pi *primaryIndex) Scan(requestId string, span *datastore.Span, distinct bo
 	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
 	defer close(conn.EntryChannel())
 
+	if !pi.keyspace.seqnos.Wait(cons, vector, conn) {
+		return
+	}
+
+	ids, err := pi.ids(span)
+	if err != nil {
+		conn.Error(err)
+		return
+	}
+
+	for i, id := range ids {
+		if limit > 0 && int64(i) >= limit {
+			break
+		}
+
+		entry := datastore.IndexEntry{PrimaryKey: id}
+		if !conn.Send(&entry) {
+			return
+		}
+	}
+}
+
+func (pi *primaryIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	pi.Scan(requestId, &datastore.Span{}, false, limit, cons, vector, conn)
+}
+
+/*
+ids returns the sorted document keys in the range of span. Ranged
+scans of disjoint spans may run concurrently.
+*/
+func (pi *primaryIndex) ids(span *datastore.Span) ([]string, errors.Error) {
 	// For primary indexes, bounds must always be strings, so we
 	// can just enforce that directly
 	low, high := "", ""
+	hasLow, hasHigh := false, false
 
 	// Ensure that lower bound is a string, if any
 	if len(span.Range.Low) > 0 {
 		a := span.Range.Low[0].Actual()
 		switch a := a.(type) {
 		case string:
-			low = a
+			low, hasLow = a, true
+		case nil:
+			// Covering scans start at null, before all the keys
 		default:
-			conn.Error(errors.NewFileDatastoreError(nil, fmt.Sprintf("Invalid lower bound %v of type %T.", a, a)))
-			return
+			return nil, errors.NewFileDatastoreError(nil, fmt.Sprintf("Invalid lower bound %v of type %T.", a, a))
 		}
 	}
 
@@ -607,70 +791,43 @@ func (pi *primaryIndex) Scan(requestId string, span *datastore.Span, distinct bo
 		a := span.Range.High[0].Actual()
 		switch a := a.(type) {
 		case string:
-			high = a
+			high, hasHigh = a, true
 		default:
-			conn.Error(errors.NewFileDatastoreError(nil, fmt.Sprintf("Invalid upper bound %v of type %T.", a, a)))
-			return
+			return nil, errors.NewFileDatastoreError(nil, fmt.Sprintf("Invalid upper bound %v of type %T.", a, a))
 		}
 	}
 
 	dirEntries, er := ioutil.ReadDir(pi.keyspace.path())
 	if er != nil {
-		conn.Error(errors.NewFileDatastoreError(er, ""))
-		return
+		return nil, errors.NewFileDatastoreError(er, "")
 	}
 
-	var n int64 = 0
+	ids := make([]string, 0, len(dirEntries))
 	for _, dirEntry := range dirEntries {
-
-		logging.Debugf("Dir entry being scanned %v \n", dirEntry.Name())
-		if limit > 0 && n > limit {
-			break
+		if dirEntry.IsDir() {
+			continue
 		}
 
 		id := documentPathToId(dirEntry.Name())
 
-		if low != "" &&
+		if hasLow &&
 			(id < low ||
 				(id == low && (span.Range.Inclusion&datastore.LOW == 0))) {
 			continue
 		}
 
-		low = ""
-
-		if high != "" &&
+		if hasHigh &&
 			(id > high ||
 				(id == high && (span.Range.Inclusion&datastore.HIGH == 0))) {
-			break
-		}
-
-		if !dirEntry.IsDir() {
-			entry := datastore.IndexEntry{PrimaryKey: id}
-			conn.EntryChannel() <- &entry
-			n++
+			continue
 		}
-	}
-}
 
-func (pi *primaryIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
-	vector timestamp.Vector, conn *datastore.IndexConnection) {
-	defer close(conn.EntryChannel())
-
-	dirEntries, er := ioutil.ReadDir(pi.keyspace.path())
-	if er != nil {
-		conn.Error(errors.NewFileDatastoreError(er, ""))
-		return
+		ids = append(ids, id)
 	}
 
-	for i, dirEntry := range dirEntries {
-		if limit > 0 && int64(i) > limit {
-			break
-		}
-		if !dirEntry.IsDir() {
-			entry := datastore.IndexEntry{PrimaryKey: documentPathToId(dirEntry.Name())}
-			conn.EntryChannel() <- &entry
-		}
-	}
+	// File names sort differently from keys when extensions differ
+	sort.Strings(ids)
+	return ids, nil
 }
 
 func fetch(path string) (item value.AnnotatedValue, e errors.Error) {
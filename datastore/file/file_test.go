@@ -11,11 +11,16 @@ package file
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/timestamp"
 	"github.com/couchbase/query/value"
 )
 
@@ -117,7 +122,7 @@ func TestFile(t *testing.T) {
 		}
 	}
 
-	freds, errs := keyspace.Fetch([]string{"fred"})
+	freds, errs := keyspace.Fetch([]string{"fred"}, datastore.NULL_QUERY_CONTEXT)
 	if errs != nil || len(freds) == 0 {
 		t.Errorf("failed to fetch fred: %v", errs)
 	}
@@ -129,58 +134,484 @@ func TestFile(t *testing.T) {
 	dmlKey.Name = "fred2"
 	dmlKey.Value = fred
 
-	_, err = keyspace.Insert([]value.Pair{dmlKey})
+	_, err = keyspace.Insert([]value.Pair{dmlKey}, datastore.NULL_QUERY_CONTEXT)
 	if err != nil {
 		t.Errorf("failed to insert fred2: %v", err)
 	}
 
-	_, err = keyspace.Update([]value.Pair{dmlKey})
+	_, err = keyspace.Update([]value.Pair{dmlKey}, datastore.NULL_QUERY_CONTEXT)
 	if err != nil {
 		t.Errorf("failed to insert fred2: %v", err)
 	}
 
-	_, err = keyspace.Upsert([]value.Pair{dmlKey})
+	_, err = keyspace.Upsert([]value.Pair{dmlKey}, datastore.NULL_QUERY_CONTEXT)
 	if err != nil {
 		t.Errorf("failed to insert fred2: %v", err)
 	}
 
 	dmlKey.Name = "fred3"
-	_, err = keyspace.Upsert([]value.Pair{dmlKey})
+	_, err = keyspace.Upsert([]value.Pair{dmlKey}, datastore.NULL_QUERY_CONTEXT)
 	if err != nil {
 		t.Errorf("failed to insert fred2: %v", err)
 	}
 
 	// negative cases
-	_, err = keyspace.Insert([]value.Pair{dmlKey})
+	_, err = keyspace.Insert([]value.Pair{dmlKey}, datastore.NULL_QUERY_CONTEXT)
 	if err == nil {
 		t.Errorf("Insert should not have succeeded for fred2")
 	}
 
 	// delete all the freds
-	deleted, err := keyspace.Delete([]string{"fred2", "fred3"})
+	deleted, err := keyspace.Delete([]string{"fred2", "fred3"}, datastore.NULL_QUERY_CONTEXT)
 	if err != nil && len(deleted) != 2 {
 		fmt.Printf("Warning: Failed to delete. Error %v", err)
 	}
 
-	_, err = keyspace.Update([]value.Pair{dmlKey})
+	_, err = keyspace.Update([]value.Pair{dmlKey}, datastore.NULL_QUERY_CONTEXT)
 	if err == nil {
 		t.Errorf("Update should have failed. Key fred3 doesn't exist")
 	}
 
 	// finally upsert the key. this should work
-	_, err = keyspace.Upsert([]value.Pair{dmlKey})
+	_, err = keyspace.Upsert([]value.Pair{dmlKey}, datastore.NULL_QUERY_CONTEXT)
 	if err != nil {
 		t.Errorf("failed to insert fred2: %v", err)
 	}
 
 	// some deletes should fail
-	deleted, err = keyspace.Delete([]string{"fred2", "fred3"})
+	deleted, err = keyspace.Delete([]string{"fred2", "fred3"}, datastore.NULL_QUERY_CONTEXT)
 	if len(deleted) != 1 && deleted[0] != "fred2" {
 		t.Errorf("failed to delete fred2: %v, #deleted=%d", deleted, len(deleted))
 	}
 
 }
 
+func TestRangedScan(t *testing.T) {
+	store, err := NewDatastore("../../test/filestore/json")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	namespace, err := store.NamespaceByName("default")
+	if err != nil {
+		t.Fatalf("failed to get namespace: %v", err)
+	}
+
+	keyspace, err := namespace.KeyspaceByName("contacts")
+	if err != nil {
+		t.Fatalf("failed to get keyspace by name: contacts")
+	}
+
+	indexers, _ := keyspace.Indexers()
+	pindexes, _ := indexers[0].PrimaryIndexes()
+	index := pindexes[0]
+
+	stats, err := index.Statistics("", &datastore.Span{})
+	if err != nil {
+		t.Fatalf("failed to get statistics: %v", err)
+	}
+
+	count, _ := stats.Count()
+	bins, _ := stats.Bins()
+	if count != 6 || len(bins) != 6 {
+		t.Errorf("expected 6 keys in 6 bins, got %d in %d", count, len(bins))
+	}
+
+	// Partitions at ian scan all the keys, once
+	spans := []*datastore.Span{
+		&datastore.Span{Range: datastore.Range{
+			High: value.Values{value.NewValue("ian")}, Inclusion: datastore.NEITHER}},
+		&datastore.Span{Range: datastore.Range{
+			Low: value.Values{value.NewValue("ian")}, Inclusion: datastore.LOW}},
+	}
+
+	context := &testingContext{t}
+	conns := make([]*datastore.IndexConnection, len(spans))
+	for i, span := range spans {
+		conns[i] = datastore.NewIndexConnection(context)
+		go index.Scan("", span, false, math.MaxInt64, datastore.UNBOUNDED, nil, conns[i])
+	}
+
+	var scanned []string
+	for _, conn := range conns {
+		for entry := range conn.EntryChannel() {
+			scanned = append(scanned, entry.PrimaryKey)
+		}
+	}
+
+	if fmt.Sprint(scanned) != "[dave earl fred harry ian jane]" {
+		t.Errorf("unexpected ranged scans %v", scanned)
+	}
+}
+
+func TestScanConsistency(t *testing.T) {
+	store, err := NewDatastore("../../test/filestore/json")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	namespace, err := store.NamespaceByName("default")
+	if err != nil {
+		t.Fatalf("failed to get namespace: %v", err)
+	}
+
+	keyspace, err := namespace.KeyspaceByName("contacts")
+	if err != nil {
+		t.Fatalf("failed to get keyspace by name: contacts")
+	}
+
+	indexers, _ := keyspace.Indexers()
+	pindexes, _ := indexers[0].PrimaryIndexes()
+	index := pindexes[0]
+	sequenced := keyspace.(datastore.Sequenced)
+
+	before := sequenced.MutationToken()
+	pair := value.Pair{Name: "fred4", Value: value.NewValue(map[string]interface{}{"name": "fred4"})}
+	_, err = keyspace.Upsert([]value.Pair{pair}, datastore.NULL_QUERY_CONTEXT)
+	if err != nil {
+		t.Fatalf("failed to upsert fred4: %v", err)
+	}
+	defer keyspace.Delete([]string{"fred4"}, datastore.NULL_QUERY_CONTEXT)
+
+	token := sequenced.MutationToken()
+	if token.Value() != before.Value()+1 || token.Guard() != before.Guard() {
+		t.Errorf("expected the next sequence number, got %v after %v", token.Value(), before.Value())
+	}
+
+	context := &waitingContext{testingContext{t}, 50 * time.Millisecond, nil}
+	scan := func(cons datastore.ScanConsistency, vector timestamp.Vector) []string {
+		conn := datastore.NewIndexConnection(context)
+		go index.ScanEntries("", math.MaxInt64, cons, vector, conn)
+
+		var scanned []string
+		for entry := range conn.EntryChannel() {
+			scanned = append(scanned, entry.PrimaryKey)
+		}
+		return scanned
+	}
+
+	// The index reflects completed mutations
+	if n := len(scan(datastore.SCAN_PLUS, nil)); n != 7 {
+		t.Errorf("expected 7 keys with scan_plus, got %d", n)
+	}
+
+	if n := len(scan(datastore.AT_PLUS, vector(0, token.Value(), token.Guard()))); n != 7 {
+		t.Errorf("expected 7 keys at the token, got %d", n)
+	}
+
+	// Tokens of other keyspace instances are indexed already
+	if n := len(scan(datastore.AT_PLUS, vector(0, token.Value()+100, "stale"))); n != 7 {
+		t.Errorf("expected 7 keys with a stale token, got %d", n)
+	}
+
+	// Scans time out waiting for mutations that do not happen
+	if n := len(scan(datastore.AT_PLUS, vector(0, token.Value()+1, token.Guard()))); n != 0 ||
+		len(context.errs) != 1 || context.errs[0].Code() != 16009 {
+		t.Errorf("expected a scan wait timeout, got %d keys and errors %v", n, context.errs)
+	}
+
+	// Scans wait for the mutations they must see
+	context.wait = time.Minute
+	done := make(chan []string)
+	go func() {
+		done <- scan(datastore.AT_PLUS, vector(0, token.Value()+1, token.Guard()))
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	_, err = keyspace.Delete([]string{"fred4"}, datastore.NULL_QUERY_CONTEXT)
+	if err != nil {
+		t.Fatalf("failed to delete fred4: %v", err)
+	}
+
+	if n := len(<-done); n != 6 {
+		t.Errorf("expected 6 keys after the delete, got %d", n)
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	store, err := NewDatastore("../../test/filestore/json")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	namespace, err := store.NamespaceByName("default")
+	if err != nil {
+		t.Fatalf("failed to get namespace: %v", err)
+	}
+
+	keyspace, err := namespace.KeyspaceByName("contacts")
+	if err != nil {
+		t.Fatalf("failed to get keyspace by name: contacts")
+	}
+
+	indexers, _ := keyspace.Indexers()
+	pindexes, _ := indexers[0].PrimaryIndexes()
+	index := pindexes[0]
+	token := keyspace.(datastore.Sequenced).MutationToken()
+
+	// Scans blocked on a slow consumer, or waiting for consistency,
+	// end when their request is stopped
+	scans := []func(conn *datastore.IndexConnection){
+		func(conn *datastore.IndexConnection) {
+			index.ScanEntries("", math.MaxInt64, datastore.UNBOUNDED, nil, conn)
+		},
+		func(conn *datastore.IndexConnection) {
+			index.ScanEntries("", math.MaxInt64, datastore.AT_PLUS,
+				vector(0, token.Value()+1, token.Guard()), conn)
+		},
+	}
+
+	for i, scan := range scans {
+		context := newStoppableContext(t)
+		conn, _ := datastore.NewSizedIndexConnection(1, context)
+		done := make(chan bool)
+		go func() {
+			scan(conn)
+			close(done)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		context.Stop()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("scan %d did not end when its request was stopped", i)
+		}
+
+		n := 0
+		for range conn.EntryChannel() {
+			n++
+		}
+		if n > 1 || len(context.errs) != 0 {
+			t.Errorf("scan %d: expected at most 1 entry and no errors, got %d and %v", i, n, context.errs)
+		}
+	}
+
+	// Stopped fetches and mutations do nothing, and return the error of the context
+	context := newStoppableContext(t)
+	context.Stop()
+
+	pairs, errs := keyspace.Fetch([]string{"fred"}, context)
+	if len(pairs) != 0 || len(errs) != 1 || errs[0].Code() != 5260 {
+		t.Errorf("expected a stopped fetch, got %v and %v", pairs, errs)
+	}
+
+	pair := value.Pair{Name: "fred5", Value: value.NewValue(map[string]interface{}{"name": "fred5"})}
+	written, err := keyspace.Upsert([]value.Pair{pair}, context)
+	if len(written) != 0 || err == nil || err.Code() != 5260 {
+		t.Errorf("expected a stopped upsert, got %v and %v", written, err)
+	}
+
+	deleted, err := keyspace.Delete([]string{"fred"}, context)
+	if len(deleted) != 0 || err == nil || err.Code() != 5260 {
+		t.Errorf("expected a stopped delete, got %v and %v", deleted, err)
+	}
+
+	pairs, errs = keyspace.Fetch([]string{"fred", "fred5"}, datastore.NULL_QUERY_CONTEXT)
+	if len(pairs) != 1 || errs != nil {
+		t.Errorf("expected only fred after stopped mutations, got %v and %v", pairs, errs)
+	}
+}
+
+func TestSequences(t *testing.T) {
+	dir, er := ioutil.TempDir("", "sequences")
+	if er != nil {
+		t.Fatalf("failed to create directory: %v", er)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "default"), 0755)
+
+	openNamespace := func() datastore.SequenceNamespace {
+		store, err := NewDatastore(dir)
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		namespace, err := store.NamespaceByName("default")
+		if err != nil {
+			t.Fatalf("failed to get namespace: %v", err)
+		}
+		return namespace.(datastore.SequenceNamespace)
+	}
+
+	namespace := openNamespace()
+	err := namespace.CreateSequence(&datastore.Sequence{Name: "orders", Start: 10, Increment: 5, Cache: 3})
+	if err != nil {
+		t.Fatalf("failed to create sequence: %v", err)
+	}
+
+	err = namespace.CreateSequence(&datastore.Sequence{Name: "ORDERS", Start: 1, Increment: 1})
+	if err == nil || err.Code() != 4440 {
+		t.Errorf("expected the sequence to exist, got %v", err)
+	}
+
+	seen := make(map[int64]bool)
+	next := func(namespace datastore.SequenceNamespace) int64 {
+		val, err := namespace.NextSequenceValue("orders")
+		if err != nil {
+			t.Fatalf("failed to get the next value: %v", err)
+		}
+		if seen[val] {
+			t.Errorf("value %d was handed out twice", val)
+		}
+		seen[val] = true
+		return val
+	}
+
+	for i, expected := range []int64{10, 15, 20, 25} {
+		if val := next(namespace); val != expected {
+			t.Errorf("value %d: expected %d, got %d", i, expected, val)
+		}
+	}
+
+	// Only the reservation is replaced; no temporary file remains
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "default"))
+	if len(files) != 1 || files[0].Name() != "orders.sequence" {
+		t.Errorf("expected only the sequence file, got %v", files)
+	}
+
+	// A reopened store resumes after the reserved batch
+	namespace = openNamespace()
+	if val := next(namespace); val != 40 {
+		t.Errorf("expected 40 after reopening, got %d", val)
+	}
+
+	sequences, _ := namespace.Sequences()
+	if len(sequences) != 1 || sequences[0].Name != "orders" || sequences[0].Increment != 5 {
+		t.Errorf("expected the reloaded sequence orders, got %v", sequences)
+	}
+
+	err = namespace.DropSequence("orders")
+	if err != nil {
+		t.Fatalf("failed to drop sequence: %v", err)
+	}
+
+	_, err = namespace.NextSequenceValue("orders")
+	if err == nil || err.Code() != 4450 {
+		t.Errorf("expected the dropped sequence not to exist, got %v", err)
+	}
+
+	namespace = openNamespace()
+	sequences, _ = namespace.Sequences()
+	if len(sequences) != 0 {
+		t.Errorf("expected no sequences after the drop, got %v", sequences)
+	}
+}
+
+func TestPlanBaselines(t *testing.T) {
+	dir, er := ioutil.TempDir("", "baselines")
+	if er != nil {
+		t.Fatalf("failed to create directory: %v", er)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "default"), 0755)
+
+	openNamespace := func() datastore.BaselineNamespace {
+		store, err := NewDatastore(dir)
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		namespace, err := store.NamespaceByName("default")
+		if err != nil {
+			t.Fatalf("failed to get namespace: %v", err)
+		}
+		return namespace.(datastore.BaselineNamespace)
+	}
+
+	namespace := openNamespace()
+	statements := []string{"SELECT b FROM orders", "SELECT a FROM orders WHERE a = \"x/y\""}
+	for _, statement := range statements {
+		err := namespace.SetPlanBaseline(&datastore.PlanBaseline{
+			Statement: statement,
+			Plan:      []byte(`{"operator": {"#operator": "Sequence"}}`),
+			Created:   time.Unix(1, 0).UTC(),
+		})
+		if err != nil {
+			t.Fatalf("failed to set baseline: %v", err)
+		}
+	}
+
+	// Setting a baseline again replaces it
+	err := namespace.SetPlanBaseline(&datastore.PlanBaseline{
+		Statement: statements[0],
+		Plan:      []byte(`{"operator": {"#operator": "Stream"}}`),
+		Created:   time.Unix(2, 0).UTC(),
+	})
+	if err != nil {
+		t.Fatalf("failed to replace baseline: %v", err)
+	}
+
+	// A reopened store reloads the baselines, sorted by statement
+	namespace = openNamespace()
+	baselines, _ := namespace.PlanBaselines()
+	if len(baselines) != 2 || baselines[0].Statement != statements[1] || baselines[1].Statement != statements[0] {
+		t.Fatalf("expected the reloaded baselines %v, got %v", statements, baselines)
+	}
+	if string(baselines[1].Plan) != `{"operator":{"#operator":"Stream"}}` || !baselines[1].Created.Equal(time.Unix(2, 0)) {
+		t.Errorf("expected the replaced baseline, got %s created %v", baselines[1].Plan, baselines[1].Created)
+	}
+
+	err = namespace.DropPlanBaseline(statements[0])
+	if err != nil {
+		t.Fatalf("failed to drop baseline: %v", err)
+	}
+
+	err = namespace.DropPlanBaseline(statements[0])
+	if err == nil || err.Code() != 4550 {
+		t.Errorf("expected the dropped baseline not to exist, got %v", err)
+	}
+
+	namespace = openNamespace()
+	baselines, _ = namespace.PlanBaselines()
+	if len(baselines) != 1 || baselines[0].Statement != statements[1] {
+		t.Errorf("expected only %s after the drop, got %v", statements[1], baselines)
+	}
+}
+
+/*
+stoppableContext is the context of a request that the test stops.
+*/
+type stoppableContext struct {
+	waitingContext
+	*datastore.StoppableQueryContext
+}
+
+func newStoppableContext(t *testing.T) *stoppableContext {
+	return &stoppableContext{waitingContext{testingContext{t}, time.Minute, nil}, datastore.NewStoppableQueryContext()}
+}
+
+type waitingContext struct {
+	testingContext
+	wait time.Duration
+	errs []errors.Error
+}
+
+func (this *waitingContext) Error(err errors.Error) {
+	this.errs = append(this.errs, err)
+}
+
+func (this *waitingContext) ScanWait() time.Duration {
+	return this.wait
+}
+
+type testEntry struct {
+	position uint32
+	seqno    uint64
+	guard    string
+}
+
+func (this *testEntry) Position() uint32 { return this.position }
+func (this *testEntry) Guard() string    { return this.guard }
+func (this *testEntry) Value() uint64    { return this.seqno }
+
+type testVector []timestamp.Entry
+
+func (this testVector) Entries() []timestamp.Entry { return this }
+
+func vector(position uint32, seqno uint64, guard string) timestamp.Vector {
+	return testVector{&testEntry{position, seqno, guard}}
+}
+
 type testingContext struct {
 	t *testing.T
 }
@@ -0,0 +1,462 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/search"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+// Extension of the files of full-text indexes
+const _FTS_EXT = ".fts"
+
+// ftsIndexer manages the full-text indexes of a file-based keyspace.
+type ftsIndexer struct {
+	sync.RWMutex
+	keyspace *keyspace
+	indexes  map[string]*ftsIndex
+}
+
+func newFtsIndexer(keyspace *keyspace) (*ftsIndexer, errors.Error) {
+	fi := &ftsIndexer{
+		keyspace: keyspace,
+		indexes:  make(map[string]*ftsIndex),
+	}
+
+	e := keyspace.loadIndexFiles(_FTS_EXT, func(path string) errors.Error {
+		index, e := loadFtsIndex(fi, path)
+		if e == nil {
+			fi.indexes[index.name] = index
+		}
+		return e
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	return fi, nil
+}
+
+func (fi *ftsIndexer) KeyspaceId() string {
+	return fi.keyspace.Id()
+}
+
+func (fi *ftsIndexer) Name() datastore.IndexType {
+	return datastore.FTS
+}
+
+func (fi *ftsIndexer) IndexIds() ([]string, errors.Error) {
+	return fi.IndexNames()
+}
+
+func (fi *ftsIndexer) IndexNames() ([]string, errors.Error) {
+	fi.RLock()
+	defer fi.RUnlock()
+
+	rv := make([]string, 0, len(fi.indexes))
+	for name, _ := range fi.indexes {
+		rv = append(rv, name)
+	}
+	return rv, nil
+}
+
+func (fi *ftsIndexer) IndexById(id string) (datastore.Index, errors.Error) {
+	return fi.IndexByName(id)
+}
+
+func (fi *ftsIndexer) IndexByName(name string) (datastore.Index, errors.Error) {
+	fi.RLock()
+	defer fi.RUnlock()
+
+	index, ok := fi.indexes[name]
+	if !ok {
+		return nil, errors.NewFileIdxNotFound(nil, name)
+	}
+	return index, nil
+}
+
+func (fi *ftsIndexer) PrimaryIndexes() ([]datastore.PrimaryIndex, errors.Error) {
+	return nil, nil
+}
+
+func (fi *ftsIndexer) Indexes() ([]datastore.Index, errors.Error) {
+	fi.RLock()
+	defer fi.RUnlock()
+
+	rv := make([]datastore.Index, 0, len(fi.indexes))
+	for _, index := range fi.indexes {
+		rv = append(rv, index)
+	}
+	return rv, nil
+}
+
+func (fi *ftsIndexer) CreatePrimaryIndex(requestId, name string, with value.Value) (
+	datastore.PrimaryIndex, errors.Error) {
+	return nil, errors.NewFileNotSupported(nil, "CREATE PRIMARY INDEX is not supported for full-text indexes.")
+}
+
+func (fi *ftsIndexer) CreateIndex(requestId, name string, seekKey, rangeKey expression.Expressions,
+	where expression.Expression, with value.Value) (datastore.Index, errors.Error) {
+
+	analyzerName := search.DEFAULT_ANALYZER
+	if with != nil {
+		if a, ok := with.Field("analyzer"); ok {
+			s, ok := a.Actual().(string)
+			if !ok {
+				return nil, errors.NewFileDatastoreError(nil, "Analyzer of full-text index must be a string.")
+			}
+			analyzerName = s
+		}
+	}
+
+	analyzer, ok := search.GetAnalyzer(analyzerName)
+	if !ok {
+		return nil, errors.NewFileDatastoreError(nil, "Unknown search analyzer "+analyzerName)
+	}
+
+	fi.Lock()
+	defer fi.Unlock()
+
+	if _, ok := fi.indexes[name]; ok {
+		return nil, errors.NewIndexAlreadyExistsError(name)
+	}
+
+	index := newFtsIndex(fi, name, rangeKey, where, search.NewIndex(analyzer))
+
+	e := index.build()
+	if e != nil {
+		return nil, e
+	}
+
+	e = index.save()
+	if e != nil {
+		return nil, e
+	}
+
+	fi.indexes[name] = index
+	return index, nil
+}
+
+func (fi *ftsIndexer) BuildIndexes(requestId string, names ...string) errors.Error {
+	return errors.NewFileNotSupported(nil, "BUILD INDEXES is not supported for file-based datastore.")
+}
+
+func (fi *ftsIndexer) Refresh() errors.Error {
+	return nil
+}
+
+func (fi *ftsIndexer) SetLogLevel(level logging.Level) {
+	// No-op, uses query engine logger
+}
+
+/*
+update indexes the documents written by a mutation, and persists
+the affected indexes.
+*/
+func (fi *ftsIndexer) update(pairs []value.Pair) errors.Error {
+	fi.RLock()
+	defer fi.RUnlock()
+
+	for _, index := range fi.indexes {
+		for _, pair := range pairs {
+			index.add(pair.Name, pair.Value)
+		}
+
+		if len(pairs) > 0 {
+			if e := index.save(); e != nil {
+				return e
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+remove drops deleted documents from the indexes, and persists the
+affected indexes.
+*/
+func (fi *ftsIndexer) remove(keys []string) errors.Error {
+	fi.RLock()
+	defer fi.RUnlock()
+
+	for _, index := range fi.indexes {
+		for _, key := range keys {
+			index.index.Remove(key)
+		}
+
+		if len(keys) > 0 {
+			if e := index.save(); e != nil {
+				return e
+			}
+		}
+	}
+
+	return nil
+}
+
+func (fi *ftsIndexer) drop(index *ftsIndex) errors.Error {
+	fi.Lock()
+	defer fi.Unlock()
+
+	e := fi.keyspace.removeIndexFile(index.fileName())
+	if e != nil {
+		return e
+	}
+
+	delete(fi.indexes, index.name)
+	return nil
+}
+
+// ftsIndex is a full-text index on the fields named by its keys.
+type ftsIndex struct {
+	name     string
+	indexer  *ftsIndexer
+	keys     expression.Expressions
+	where    expression.Expression
+	fields   []string
+	index    *search.Index
+	saveLock sync.Mutex
+}
+
+func newFtsIndex(indexer *ftsIndexer, name string, keys expression.Expressions,
+	where expression.Expression, index *search.Index) *ftsIndex {
+	fields := make([]string, len(keys))
+	for i, key := range keys {
+		fields[i] = fieldPath(key)
+	}
+
+	return &ftsIndex{
+		name:    name,
+		indexer: indexer,
+		keys:    keys,
+		where:   where,
+		fields:  fields,
+		index:   index,
+	}
+}
+
+/*
+fieldPath returns the document path of an index key, e.g.
+"address.city"; other expressions are named by their text.
+*/
+func fieldPath(expr expression.Expression) string {
+	switch expr := expr.(type) {
+	case *expression.Identifier:
+		return expr.Identifier()
+	case *expression.Field:
+		first := fieldPath(expr.First())
+		if _, ok := expr.Second().(*expression.FieldName); ok {
+			return first + "." + expr.Second().Alias()
+		}
+	case *expression.Self:
+		return ""
+	}
+
+	return expr.String()
+}
+
+func (fti *ftsIndex) KeyspaceId() string {
+	return fti.indexer.KeyspaceId()
+}
+
+func (fti *ftsIndex) Id() string {
+	return fti.Name()
+}
+
+func (fti *ftsIndex) Name() string {
+	return fti.name
+}
+
+func (fti *ftsIndex) Type() datastore.IndexType {
+	return datastore.FTS
+}
+
+func (fti *ftsIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (fti *ftsIndex) RangeKey() expression.Expressions {
+	return fti.keys
+}
+
+func (fti *ftsIndex) Condition() expression.Expression {
+	return fti.where
+}
+
+func (fti *ftsIndex) IsPrimary() bool {
+	return false
+}
+
+func (fti *ftsIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (fti *ftsIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (fti *ftsIndex) Drop(requestId string) errors.Error {
+	return fti.indexer.drop(fti)
+}
+
+func (fti *ftsIndex) Analyzer() string {
+	return fti.index.Analyzer().Name()
+}
+
+func (fti *ftsIndex) Fields() []string {
+	return fti.fields
+}
+
+func (fti *ftsIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer close(conn.EntryChannel())
+
+	if !fti.indexer.keyspace.seqnos.Wait(cons, vector, conn) {
+		return
+	}
+
+	if len(span.Seek) != 1 {
+		conn.Error(errors.NewFileDatastoreError(nil, "Full-text index scan requires a single query string."))
+		return
+	}
+
+	text, ok := span.Seek[0].Actual().(string)
+	if !ok {
+		// Matches nothing, as SEARCH() would return NULL
+		return
+	}
+
+	query, er := search.ParseQuery(text)
+	if er != nil {
+		conn.Error(errors.NewFileDatastoreError(er, ""))
+		return
+	}
+
+	for i, hit := range fti.index.Search(query) {
+		if limit > 0 && int64(i) >= limit {
+			break
+		}
+
+		entry := datastore.IndexEntry{
+			EntryKey:   value.Values{value.NewValue(hit.Score)},
+			PrimaryKey: hit.Id,
+		}
+		if !conn.Send(&entry) {
+			return
+		}
+	}
+}
+
+/*
+add indexes the fields of a document, or removes the document if it
+does not satisfy the index condition.
+*/
+func (fti *ftsIndex) add(key string, doc value.Value) {
+	context := expression.NewIndexContext()
+	av, ok := indexItem(key, doc, fti.where, context)
+	if !ok {
+		fti.index.Remove(key)
+		return
+	}
+
+	fields := make(search.Fields, len(fti.keys))
+	for i, expr := range fti.keys {
+		v, vs, err := expr.EvaluateForIndex(av, context)
+		if err != nil {
+			logging.Debugf("Error indexing %s in full-text index %s: %v", key, fti.name, err)
+			continue
+		}
+
+		if vs != nil {
+			for _, v := range vs {
+				fields.Add(fti.fields[i], v.Actual())
+			}
+		} else if v != nil {
+			fields.Add(fti.fields[i], v.Actual())
+		}
+	}
+
+	fti.index.Add(key, fields)
+}
+
+// build indexes the documents already in the keyspace.
+func (fti *ftsIndex) build() errors.Error {
+	return fti.indexer.keyspace.scanDocuments(fti.add)
+}
+
+func (fti *ftsIndex) fileName() string {
+	return fti.name + _FTS_EXT
+}
+
+// ftsIndexFile is the persisted form of a full-text index.
+type ftsIndexFile struct {
+	Name  string        `json:"name"`
+	Keys  []string      `json:"keys"`
+	Where string        `json:"where,omitempty"`
+	Index *search.Index `json:"index"`
+}
+
+func (fti *ftsIndex) save() errors.Error {
+	fti.saveLock.Lock()
+	defer fti.saveLock.Unlock()
+
+	file := ftsIndexFile{
+		Name:  fti.name,
+		Keys:  make([]string, len(fti.keys)),
+		Index: fti.index,
+	}
+
+	for i, key := range fti.keys {
+		file.Keys[i] = key.String()
+	}
+
+	if fti.where != nil {
+		file.Where = fti.where.String()
+	}
+
+	return fti.indexer.keyspace.writeIndexFile(fti.fileName(), &file)
+}
+
+func loadFtsIndex(indexer *ftsIndexer, path string) (*ftsIndex, errors.Error) {
+	var file ftsIndexFile
+	e := readIndexFile(path, &file)
+	if e != nil {
+		return nil, e
+	}
+
+	if file.Index == nil {
+		return nil, errors.NewFileDatastoreError(nil, fmt.Sprintf("Missing contents of full-text index %s.", path))
+	}
+
+	keys := make(expression.Expressions, len(file.Keys))
+	for i, key := range file.Keys {
+		keys[i], e = parseIndexExpression(key, path)
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	where, e := parseIndexExpression(file.Where, path)
+	if e != nil {
+		return nil, e
+	}
+
+	return newFtsIndex(indexer, file.Name, keys, where, file.Index), nil
+}
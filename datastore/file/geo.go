@@ -0,0 +1,545 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/geo"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+// Extension of the files of geospatial indexes
+const _GEO_EXT = ".geo"
+
+// Default geohash precision of index cells, about 38m x 19m
+const _GEO_PRECISION = 8
+
+// Maximum number of cells covering a document, or a scanned region
+const (
+	_GEO_DOC_CELLS  = 8
+	_GEO_SCAN_CELLS = 64
+)
+
+// geoIndexer manages the geospatial indexes of a file-based keyspace.
+type geoIndexer struct {
+	sync.RWMutex
+	keyspace *keyspace
+	indexes  map[string]*geoIndex
+}
+
+func newGeoIndexer(keyspace *keyspace) (*geoIndexer, errors.Error) {
+	gi := &geoIndexer{
+		keyspace: keyspace,
+		indexes:  make(map[string]*geoIndex),
+	}
+
+	e := keyspace.loadIndexFiles(_GEO_EXT, func(path string) errors.Error {
+		index, e := loadGeoIndex(gi, path)
+		if e == nil {
+			gi.indexes[index.name] = index
+		}
+		return e
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	return gi, nil
+}
+
+func (gi *geoIndexer) KeyspaceId() string {
+	return gi.keyspace.Id()
+}
+
+func (gi *geoIndexer) Name() datastore.IndexType {
+	return datastore.GEO
+}
+
+func (gi *geoIndexer) IndexIds() ([]string, errors.Error) {
+	return gi.IndexNames()
+}
+
+func (gi *geoIndexer) IndexNames() ([]string, errors.Error) {
+	gi.RLock()
+	defer gi.RUnlock()
+
+	rv := make([]string, 0, len(gi.indexes))
+	for name, _ := range gi.indexes {
+		rv = append(rv, name)
+	}
+	return rv, nil
+}
+
+func (gi *geoIndexer) IndexById(id string) (datastore.Index, errors.Error) {
+	return gi.IndexByName(id)
+}
+
+func (gi *geoIndexer) IndexByName(name string) (datastore.Index, errors.Error) {
+	gi.RLock()
+	defer gi.RUnlock()
+
+	index, ok := gi.indexes[name]
+	if !ok {
+		return nil, errors.NewFileIdxNotFound(nil, name)
+	}
+	return index, nil
+}
+
+func (gi *geoIndexer) PrimaryIndexes() ([]datastore.PrimaryIndex, errors.Error) {
+	return nil, nil
+}
+
+func (gi *geoIndexer) Indexes() ([]datastore.Index, errors.Error) {
+	gi.RLock()
+	defer gi.RUnlock()
+
+	rv := make([]datastore.Index, 0, len(gi.indexes))
+	for _, index := range gi.indexes {
+		rv = append(rv, index)
+	}
+	return rv, nil
+}
+
+func (gi *geoIndexer) CreatePrimaryIndex(requestId, name string, with value.Value) (
+	datastore.PrimaryIndex, errors.Error) {
+	return nil, errors.NewFileNotSupported(nil, "CREATE PRIMARY INDEX is not supported for geospatial indexes.")
+}
+
+func (gi *geoIndexer) CreateIndex(requestId, name string, seekKey, rangeKey expression.Expressions,
+	where expression.Expression, with value.Value) (datastore.Index, errors.Error) {
+	if len(rangeKey) != 1 {
+		return nil, errors.NewFileNotSupported(nil, "Geospatial indexes must have a single key.")
+	}
+
+	precision := _GEO_PRECISION
+	if with != nil {
+		if p, ok := with.Field("precision"); ok {
+			n, ok := p.Actual().(float64)
+			if !ok || n < 1 || n > geo.MAX_PRECISION || n != float64(int(n)) {
+				return nil, errors.NewFileDatastoreError(nil, fmt.Sprintf(
+					"Precision of geospatial index must be an integer from 1 to %d.", geo.MAX_PRECISION))
+			}
+			precision = int(n)
+		}
+	}
+
+	gi.Lock()
+	defer gi.Unlock()
+
+	if _, ok := gi.indexes[name]; ok {
+		return nil, errors.NewIndexAlreadyExistsError(name)
+	}
+
+	index := newGeoIndex(gi, name, rangeKey[0], where, precision)
+
+	e := gi.keyspace.scanDocuments(index.add)
+	if e != nil {
+		return nil, e
+	}
+
+	e = index.save()
+	if e != nil {
+		return nil, e
+	}
+
+	gi.indexes[name] = index
+	return index, nil
+}
+
+func (gi *geoIndexer) BuildIndexes(requestId string, names ...string) errors.Error {
+	return errors.NewFileNotSupported(nil, "BUILD INDEXES is not supported for file-based datastore.")
+}
+
+func (gi *geoIndexer) Refresh() errors.Error {
+	return nil
+}
+
+func (gi *geoIndexer) SetLogLevel(level logging.Level) {
+	// No-op, uses query engine logger
+}
+
+/*
+update indexes the documents written by a mutation, and persists
+the affected indexes.
+*/
+func (gi *geoIndexer) update(pairs []value.Pair) errors.Error {
+	gi.RLock()
+	defer gi.RUnlock()
+
+	for _, index := range gi.indexes {
+		for _, pair := range pairs {
+			index.add(pair.Name, pair.Value)
+		}
+
+		if len(pairs) > 0 {
+			if e := index.save(); e != nil {
+				return e
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+remove drops deleted documents from the indexes, and persists the
+affected indexes.
+*/
+func (gi *geoIndexer) remove(keys []string) errors.Error {
+	gi.RLock()
+	defer gi.RUnlock()
+
+	for _, index := range gi.indexes {
+		for _, key := range keys {
+			index.remove(key)
+		}
+
+		if len(keys) > 0 {
+			if e := index.save(); e != nil {
+				return e
+			}
+		}
+	}
+
+	return nil
+}
+
+func (gi *geoIndexer) drop(index *geoIndex) errors.Error {
+	gi.Lock()
+	defer gi.Unlock()
+
+	e := gi.keyspace.removeIndexFile(index.fileName())
+	if e != nil {
+		return e
+	}
+
+	delete(gi.indexes, index.name)
+	return nil
+}
+
+/*
+geoIndex is a geohash index. Each document is indexed under the
+geohash cells that cover the bounding box of its geometry; a scan
+looks up the cells that cover the scanned region, then checks the
+bounding boxes of the candidates.
+*/
+type geoIndex struct {
+	sync.RWMutex
+	name      string
+	indexer   *geoIndexer
+	key       expression.Expression
+	where     expression.Expression
+	precision int
+	bounds    map[string]geo.Bounds      // doc -> bounding box
+	cells     map[string]map[string]bool // cell -> docs
+	sorted    []string                   // sorted cells; nil when stale
+	saveLock  sync.Mutex
+}
+
+func newGeoIndex(indexer *geoIndexer, name string, key, where expression.Expression,
+	precision int) *geoIndex {
+	return &geoIndex{
+		name:      name,
+		indexer:   indexer,
+		key:       key,
+		where:     where,
+		precision: precision,
+		bounds:    make(map[string]geo.Bounds),
+		cells:     make(map[string]map[string]bool),
+	}
+}
+
+func (gix *geoIndex) KeyspaceId() string {
+	return gix.indexer.KeyspaceId()
+}
+
+func (gix *geoIndex) Id() string {
+	return gix.Name()
+}
+
+func (gix *geoIndex) Name() string {
+	return gix.name
+}
+
+func (gix *geoIndex) Type() datastore.IndexType {
+	return datastore.GEO
+}
+
+func (gix *geoIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (gix *geoIndex) RangeKey() expression.Expressions {
+	return expression.Expressions{gix.key}
+}
+
+func (gix *geoIndex) Condition() expression.Expression {
+	return gix.where
+}
+
+func (gix *geoIndex) IsPrimary() bool {
+	return false
+}
+
+func (gix *geoIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (gix *geoIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (gix *geoIndex) Drop(requestId string) errors.Error {
+	return gix.indexer.drop(gix)
+}
+
+func (gix *geoIndex) Precision() int {
+	return gix.precision
+}
+
+func (gix *geoIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer close(conn.EntryChannel())
+
+	if !gix.indexer.keyspace.seqnos.Wait(cons, vector, conn) {
+		return
+	}
+
+	if len(span.Seek) != 1 {
+		conn.Error(errors.NewFileDatastoreError(nil, "Geospatial index scan requires a single bounding box."))
+		return
+	}
+
+	// A NULL or MISSING region matches nothing
+	if span.Seek[0].Type() != value.ARRAY {
+		return
+	}
+
+	region, er := geo.ParseBounds(span.Seek[0].Actual())
+	if er != nil {
+		conn.Error(errors.NewFileDatastoreError(er, ""))
+		return
+	}
+
+	for i, key := range gix.lookup(region) {
+		if limit > 0 && int64(i) >= limit {
+			break
+		}
+
+		entry := datastore.IndexEntry{PrimaryKey: key}
+		if !conn.Send(&entry) {
+			return
+		}
+	}
+}
+
+/*
+lookup returns the sorted keys of the documents whose bounding box
+intersects region.
+*/
+func (gix *geoIndex) lookup(region geo.Bounds) []string {
+	gix.Lock()
+	if gix.sorted == nil {
+		gix.sorted = make([]string, 0, len(gix.cells))
+		for cell, _ := range gix.cells {
+			gix.sorted = append(gix.sorted, cell)
+		}
+		sort.Strings(gix.sorted)
+	}
+	gix.Unlock()
+
+	gix.RLock()
+	defer gix.RUnlock()
+
+	found := make(map[string]bool)
+	check := func(cell string) {
+		for key, _ := range gix.cells[cell] {
+			if !found[key] && gix.bounds[key].Intersects(region) {
+				found[key] = true
+			}
+		}
+	}
+
+	for _, qc := range geo.Cover(region, gix.precision, _GEO_SCAN_CELLS) {
+		// Larger cells containing qc
+		for i := 0; i < len(qc); i++ {
+			check(qc[0:i])
+		}
+
+		// qc and the cells within it
+		for i := sort.SearchStrings(gix.sorted, qc); i < len(gix.sorted) &&
+			strings.HasPrefix(gix.sorted[i], qc); i++ {
+			check(gix.sorted[i])
+		}
+	}
+
+	rv := make([]string, 0, len(found))
+	for key, _ := range found {
+		rv = append(rv, key)
+	}
+
+	sort.Strings(rv)
+	return rv
+}
+
+/*
+add indexes the geometry of a document, or removes the document if
+it has no valid geometry or does not satisfy the index condition.
+*/
+func (gix *geoIndex) add(key string, doc value.Value) {
+	context := expression.NewIndexContext()
+	av, ok := indexItem(key, doc, gix.where, context)
+	if !ok {
+		gix.remove(key)
+		return
+	}
+
+	v, err := gix.key.Evaluate(av, context)
+	if err != nil {
+		logging.Debugf("Error indexing %s in geospatial index %s: %v", key, gix.name, err)
+		gix.remove(key)
+		return
+	}
+
+	g, er := geo.Parse(v.Actual())
+	if er != nil || g.Empty() {
+		gix.remove(key)
+		return
+	}
+
+	gix.Lock()
+	defer gix.Unlock()
+
+	gix.removeLocked(key)
+	gix.addLocked(key, g.Bounds())
+}
+
+func (gix *geoIndex) addLocked(key string, bounds geo.Bounds) {
+	gix.bounds[key] = bounds
+	for _, cell := range geo.Cover(bounds, gix.precision, _GEO_DOC_CELLS) {
+		docs, ok := gix.cells[cell]
+		if !ok {
+			docs = make(map[string]bool)
+			gix.cells[cell] = docs
+			gix.sorted = nil
+		}
+
+		docs[key] = true
+	}
+}
+
+func (gix *geoIndex) remove(key string) {
+	gix.Lock()
+	defer gix.Unlock()
+
+	gix.removeLocked(key)
+}
+
+func (gix *geoIndex) removeLocked(key string) {
+	bounds, ok := gix.bounds[key]
+	if !ok {
+		return
+	}
+
+	for _, cell := range geo.Cover(bounds, gix.precision, _GEO_DOC_CELLS) {
+		docs := gix.cells[cell]
+		delete(docs, key)
+		if len(docs) == 0 {
+			delete(gix.cells, cell)
+			gix.sorted = nil
+		}
+	}
+
+	delete(gix.bounds, key)
+}
+
+func (gix *geoIndex) fileName() string {
+	return gix.name + _GEO_EXT
+}
+
+/*
+geoIndexFile is the persisted form of a geospatial index. Cells are
+recomputed from the bounding boxes when the index is loaded.
+*/
+type geoIndexFile struct {
+	Name      string                   `json:"name"`
+	Key       string                   `json:"key"`
+	Where     string                   `json:"where,omitempty"`
+	Precision int                      `json:"precision"`
+	Bounds    map[string][]interface{} `json:"bounds"`
+}
+
+func (gix *geoIndex) save() errors.Error {
+	gix.saveLock.Lock()
+	defer gix.saveLock.Unlock()
+
+	gix.RLock()
+	file := geoIndexFile{
+		Name:      gix.name,
+		Key:       gix.key.String(),
+		Precision: gix.precision,
+		Bounds:    make(map[string][]interface{}, len(gix.bounds)),
+	}
+
+	for key, bounds := range gix.bounds {
+		file.Bounds[key] = bounds.Array()
+	}
+	gix.RUnlock()
+
+	if gix.where != nil {
+		file.Where = gix.where.String()
+	}
+
+	return gix.indexer.keyspace.writeIndexFile(gix.fileName(), &file)
+}
+
+func loadGeoIndex(indexer *geoIndexer, path string) (*geoIndex, errors.Error) {
+	var file geoIndexFile
+	e := readIndexFile(path, &file)
+	if e != nil {
+		return nil, e
+	}
+
+	key, e := parseIndexExpression(file.Key, path)
+	if e != nil {
+		return nil, e
+	}
+
+	if key == nil || file.Precision < 1 || file.Precision > geo.MAX_PRECISION {
+		return nil, errors.NewFileDatastoreError(nil, fmt.Sprintf("Invalid geospatial index %s.", path))
+	}
+
+	where, e := parseIndexExpression(file.Where, path)
+	if e != nil {
+		return nil, e
+	}
+
+	index := newGeoIndex(indexer, file.Name, key, where, file.Precision)
+	for k, b := range file.Bounds {
+		bounds, er := geo.ParseBounds(b)
+		if er != nil {
+			return nil, errors.NewFileDatastoreError(er, "index "+path)
+		}
+
+		index.addLocked(k, bounds)
+	}
+
+	return index, nil
+}
@@ -0,0 +1,169 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/expression/parser"
+	"github.com/couchbase/query/value"
+)
+
+// Secondary indexes are persisted in this directory of the keyspace.
+const _INDEX_DIR = ".indexes"
+
+func (b *keyspace) indexPath() string {
+	return filepath.Join(b.path(), _INDEX_DIR)
+}
+
+/*
+loadIndexFiles calls load for each index file of the keyspace with
+the given extension.
+*/
+func (b *keyspace) loadIndexFiles(ext string, load func(path string) errors.Error) errors.Error {
+	dirEntries, er := ioutil.ReadDir(b.indexPath())
+	if er != nil {
+		if os.IsNotExist(er) {
+			return nil
+		}
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || filepath.Ext(dirEntry.Name()) != ext {
+			continue
+		}
+
+		e := load(filepath.Join(b.indexPath(), dirEntry.Name()))
+		if e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func (b *keyspace) writeIndexFile(name string, v interface{}) errors.Error {
+	bytes, er := json.Marshal(v)
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	er = os.MkdirAll(b.indexPath(), 0755)
+	if er == nil {
+		er = ioutil.WriteFile(filepath.Join(b.indexPath(), name), bytes, 0666)
+	}
+
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	return nil
+}
+
+func readIndexFile(path string, v interface{}) errors.Error {
+	bytes, er := ioutil.ReadFile(path)
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	er = json.Unmarshal(bytes, v)
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "index "+path)
+	}
+
+	return nil
+}
+
+/*
+removeIndexFile removes the file of a dropped index, and the index
+directory once it is empty, to leave the keyspace as we found it.
+*/
+func (b *keyspace) removeIndexFile(name string) errors.Error {
+	er := os.Remove(filepath.Join(b.indexPath(), name))
+	if er != nil && !os.IsNotExist(er) {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	dirEntries, er := ioutil.ReadDir(b.indexPath())
+	if er == nil && len(dirEntries) == 0 {
+		os.Remove(b.indexPath())
+	}
+
+	return nil
+}
+
+/*
+parseIndexExpression parses an index key or condition persisted as
+text. The empty string is parsed as nil.
+*/
+func parseIndexExpression(s, path string) (expression.Expression, errors.Error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	expr, er := parser.Parse(s)
+	if er != nil {
+		return nil, errors.NewFileDatastoreError(er, "index "+path)
+	}
+
+	return expr, nil
+}
+
+/*
+scanDocuments calls fn for each document in the keyspace, to build
+a new index.
+*/
+func (b *keyspace) scanDocuments(fn func(key string, doc value.Value)) errors.Error {
+	dirEntries, er := ioutil.ReadDir(b.path())
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+
+		doc, e := fetch(filepath.Join(b.path(), dirEntry.Name()))
+		if e != nil {
+			return e
+		}
+
+		fn(documentPathToId(dirEntry.Name()), doc)
+	}
+
+	return nil
+}
+
+/*
+indexItem prepares a document for the evaluation of index keys. It
+returns false if the document does not satisfy the index condition.
+*/
+func indexItem(key string, doc value.Value, where expression.Expression,
+	context expression.Context) (value.AnnotatedValue, bool) {
+	av := value.NewAnnotatedValue(doc)
+	av.SetAttachment("meta", map[string]interface{}{"id": key})
+
+	if where != nil {
+		w, err := where.Evaluate(av, context)
+		if err != nil || !w.Truth() {
+			return nil, false
+		}
+	}
+
+	return av, true
+}
@@ -0,0 +1,70 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/schema"
+	"github.com/couchbase/query/value"
+)
+
+// The schema is persisted with the indexes of the keyspace.
+const _SCHEMA_EXT = ".schema"
+
+type keyspaceSchema struct {
+	sync.RWMutex
+	schema *schema.Schema
+}
+
+func (b *keyspace) loadSchema() errors.Error {
+	return b.loadIndexFiles(_SCHEMA_EXT, func(path string) errors.Error {
+		var source json.RawMessage
+		e := readIndexFile(path, &source)
+		if e != nil {
+			return e
+		}
+
+		sch, err := schema.Compile(value.NewValue([]byte(source)))
+		if err != nil {
+			return errors.NewSchemaError(err, b.Name())
+		}
+
+		b.schema.schema = sch
+		return nil
+	})
+}
+
+func (b *keyspace) Schema() *schema.Schema {
+	b.schema.RLock()
+	defer b.schema.RUnlock()
+	return b.schema.schema
+}
+
+func (b *keyspace) SetSchema(sch *schema.Schema) errors.Error {
+	b.schema.Lock()
+	defer b.schema.Unlock()
+
+	var e errors.Error
+	if sch == nil {
+		e = b.removeIndexFile(b.Name() + _SCHEMA_EXT)
+	} else {
+		e = b.writeIndexFile(b.Name()+_SCHEMA_EXT, sch.Source())
+	}
+
+	if e != nil {
+		return e
+	}
+
+	b.schema.schema = sch
+	return nil
+}
@@ -0,0 +1,186 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+/*
+Sequences are persisted in the namespace directory, with the value
+that follows the last reserved batch. The file is replaced
+atomically, so that a crash leaves either the old or the new
+reservation.
+*/
+const _SEQUENCE_EXT = ".sequence"
+
+type sequenceFile struct {
+	datastore.Sequence
+	Reserved int64 `json:"reserved"`
+}
+
+func (p *namespace) loadSequences() errors.Error {
+	p.sequences = make(map[string]*datastore.SequenceCounter)
+
+	dirEntries, er := ioutil.ReadDir(p.path())
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || filepath.Ext(dirEntry.Name()) != _SEQUENCE_EXT {
+			continue
+		}
+
+		sf := &sequenceFile{}
+		e := readIndexFile(filepath.Join(p.path(), dirEntry.Name()), sf)
+		if e != nil {
+			return e
+		}
+
+		sequence := sf.Sequence
+		p.sequences[strings.ToUpper(sequence.Name)] = datastore.NewSequenceCounter(&sequence, sf.Reserved)
+	}
+
+	return nil
+}
+
+func (p *namespace) Sequences() ([]*datastore.Sequence, errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	rv := make(sequences, 0, len(p.sequences))
+	for _, counter := range p.sequences {
+		rv = append(rv, counter.Sequence())
+	}
+
+	sort.Sort(rv)
+	return rv, nil
+}
+
+func (p *namespace) CreateSequence(sequence *datastore.Sequence) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	nameu := strings.ToUpper(sequence.Name)
+	if _, ok := p.sequences[nameu]; ok {
+		return errors.NewSequenceAlreadyExistsError(sequence.Name)
+	}
+
+	e := p.writeSequenceFile(sequence, sequence.Start)
+	if e != nil {
+		return e
+	}
+
+	p.sequences[nameu] = datastore.NewSequenceCounter(sequence, sequence.Start)
+	return nil
+}
+
+func (p *namespace) DropSequence(name string) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	nameu := strings.ToUpper(name)
+	counter, ok := p.sequences[nameu]
+	if !ok {
+		return errors.NewSequenceNotFoundError(name)
+	}
+
+	er := os.Remove(filepath.Join(p.path(), counter.Sequence().Name+_SEQUENCE_EXT))
+	if er != nil && !os.IsNotExist(er) {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	delete(p.sequences, nameu)
+	return nil
+}
+
+func (p *namespace) NextSequenceValue(name string) (int64, errors.Error) {
+	p.RLock()
+	counter, ok := p.sequences[strings.ToUpper(name)]
+	p.RUnlock()
+
+	if !ok {
+		return 0, errors.NewSequenceNotFoundError(name)
+	}
+
+	return counter.Next(func(reserved int64) errors.Error {
+		return p.writeSequenceFile(counter.Sequence(), reserved)
+	})
+}
+
+func (p *namespace) SequencePosition(name string) (int64, errors.Error) {
+	p.RLock()
+	counter, ok := p.sequences[strings.ToUpper(name)]
+	p.RUnlock()
+
+	if !ok {
+		return 0, errors.NewSequenceNotFoundError(name)
+	}
+
+	return counter.Position(), nil
+}
+
+/*
+writeSequenceFile writes to a temporary file, syncs it, and renames
+it over the sequence file.
+*/
+func (p *namespace) writeSequenceFile(sequence *datastore.Sequence, reserved int64) errors.Error {
+	bytes, er := json.Marshal(&sequenceFile{*sequence, reserved})
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	path := filepath.Join(p.path(), sequence.Name+_SEQUENCE_EXT)
+	f, er := os.Create(path + ".tmp")
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	_, er = f.Write(bytes)
+	if er == nil {
+		er = f.Sync()
+	}
+	if cer := f.Close(); er == nil {
+		er = cer
+	}
+	if er == nil {
+		er = os.Rename(path+".tmp", path)
+	}
+
+	if er != nil {
+		os.Remove(path + ".tmp")
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	return nil
+}
+
+type sequences []*datastore.Sequence
+
+func (this sequences) Len() int {
+	return len(this)
+}
+
+func (this sequences) Less(i, j int) bool {
+	return this[i].Name < this[j].Name
+}
+
+func (this sequences) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+}
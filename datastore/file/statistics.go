@@ -0,0 +1,73 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+// Maximum number of bins in primary index statistics.
+const _STATISTICS_BINS = 64
+
+// statistics of the sorted document keys of a span. Keys are unique,
+// so the distinct count is the count.
+type statistics struct {
+	ids  []string
+	bins []datastore.Statistics
+}
+
+/*
+newStatistics samples the keys into equal-depth bins, which the
+planner uses to partition primary scans.
+*/
+func newStatistics(ids []string) *statistics {
+	stats := &statistics{ids: ids}
+
+	size := (len(ids) + _STATISTICS_BINS - 1) / _STATISTICS_BINS
+	for i := 0; i < len(ids) && size > 0; i += size {
+		end := i + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		stats.bins = append(stats.bins, &statistics{ids: ids[i:end]})
+	}
+
+	return stats
+}
+
+func (s *statistics) Count() (int64, errors.Error) {
+	return int64(len(s.ids)), nil
+}
+
+func (s *statistics) Min() (value.Values, errors.Error) {
+	if len(s.ids) == 0 {
+		return nil, nil
+	}
+
+	return value.Values{value.NewValue(s.ids[0])}, nil
+}
+
+func (s *statistics) Max() (value.Values, errors.Error) {
+	if len(s.ids) == 0 {
+		return nil, nil
+	}
+
+	return value.Values{value.NewValue(s.ids[len(s.ids)-1])}, nil
+}
+
+func (s *statistics) DistinctCount() (int64, errors.Error) {
+	return int64(len(s.ids)), nil
+}
+
+func (s *statistics) Bins() ([]datastore.Statistics, errors.Error) {
+	return s.bins, nil
+}
@@ -0,0 +1,93 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+// Triggers are persisted with the indexes of the keyspace.
+const _TRIGGER_EXT = ".trigger"
+
+type triggers struct {
+	sync.RWMutex
+	byName map[string]*datastore.Trigger
+}
+
+func (b *keyspace) loadTriggers() errors.Error {
+	b.triggers.byName = make(map[string]*datastore.Trigger)
+
+	return b.loadIndexFiles(_TRIGGER_EXT, func(path string) errors.Error {
+		trigger := &datastore.Trigger{}
+		e := readIndexFile(path, trigger)
+		if e != nil {
+			return e
+		}
+
+		b.triggers.byName[trigger.Name] = trigger
+		return nil
+	})
+}
+
+func (b *keyspace) Triggers() ([]*datastore.Trigger, errors.Error) {
+	b.triggers.RLock()
+	defer b.triggers.RUnlock()
+
+	names := make([]string, 0, len(b.triggers.byName))
+	for name, _ := range b.triggers.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rv := make([]*datastore.Trigger, len(names))
+	for i, name := range names {
+		rv[i] = b.triggers.byName[name]
+	}
+
+	return rv, nil
+}
+
+func (b *keyspace) CreateTrigger(trigger *datastore.Trigger) errors.Error {
+	b.triggers.Lock()
+	defer b.triggers.Unlock()
+
+	if _, ok := b.triggers.byName[trigger.Name]; ok {
+		return errors.NewTriggerAlreadyExistsError(trigger.Name)
+	}
+
+	e := b.writeIndexFile(trigger.Name+_TRIGGER_EXT, trigger)
+	if e != nil {
+		return e
+	}
+
+	b.triggers.byName[trigger.Name] = trigger
+	return nil
+}
+
+func (b *keyspace) DropTrigger(name string) errors.Error {
+	b.triggers.Lock()
+	defer b.triggers.Unlock()
+
+	if _, ok := b.triggers.byName[name]; !ok {
+		return errors.NewTriggerNotFoundError(name)
+	}
+
+	e := b.removeIndexFile(name + _TRIGGER_EXT)
+	if e != nil {
+		return e
+	}
+
+	delete(b.triggers.byName, name)
+	return nil
+}
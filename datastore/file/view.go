@@ -0,0 +1,258 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+/*
+The definition of a materialized view is persisted with the indexes
+of its keyspace; the rows of the view are its documents.
+*/
+const _VIEW_EXT = ".view"
+
+func (b *keyspace) loadView() errors.Error {
+	return b.loadIndexFiles(_VIEW_EXT, func(path string) errors.Error {
+		view := &datastore.MaterializedView{}
+		e := readIndexFile(path, view)
+		if e != nil {
+			return e
+		}
+
+		b.view = view
+		return nil
+	})
+}
+
+func (p *namespace) CreateView(view *datastore.MaterializedView) (datastore.ViewKeyspace, errors.Error) {
+	p.Lock()
+	defer p.Unlock()
+
+	nameu := strings.ToUpper(view.Name)
+	if p.nameTaken(nameu) {
+		return nil, errors.NewViewAlreadyExistsError(view.Name)
+	}
+
+	er := os.Mkdir(filepath.Join(p.path(), view.Name), 0755)
+	if er != nil {
+		return nil, errors.NewFileDatastoreError(er, "")
+	}
+
+	b, e := newKeyspace(p, view.Name)
+	if e == nil {
+		e = b.writeIndexFile(view.Name+_VIEW_EXT, view)
+	}
+
+	if e != nil {
+		os.RemoveAll(filepath.Join(p.path(), view.Name))
+		return nil, e
+	}
+
+	b.view = view
+	p.keyspaces[nameu] = b
+	p.keyspaceNames = append(append([]string(nil), p.keyspaceNames...), b.Name())
+	sort.Strings(p.keyspaceNames)
+	return b, nil
+}
+
+func (p *namespace) DropView(name string) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	nameu := strings.ToUpper(name)
+	b, ok := p.keyspaces[nameu]
+	if !ok || b.view == nil {
+		return errors.NewViewNotFoundError(name)
+	}
+
+	er := os.RemoveAll(b.path())
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	delete(p.keyspaces, nameu)
+	names := make([]string, 0, len(p.keyspaceNames))
+	for _, n := range p.keyspaceNames {
+		if n != b.Name() {
+			names = append(names, n)
+		}
+	}
+	p.keyspaceNames = names
+	return nil
+}
+
+func (b *keyspace) View() *datastore.MaterializedView {
+	return b.view
+}
+
+/*
+ReplaceRows removes the rows that are not in the new result of the
+view, and writes the others.
+*/
+func (b *keyspace) ReplaceRows(rows []value.Pair) errors.Error {
+	keep := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		keep[row.Name] = true
+	}
+
+	var deletes []string
+	e := b.scanDocuments(func(key string, doc value.Value) {
+		if !keep[key] {
+			deletes = append(deletes, key)
+		}
+	})
+	if e != nil {
+		return e
+	}
+
+	return b.ApplyRows(rows, deletes)
+}
+
+func (b *keyspace) ApplyRows(upserts []value.Pair, deletes []string) errors.Error {
+	if len(deletes) > 0 {
+		_, e := b.delete(deletes, datastore.NULL_QUERY_CONTEXT)
+		if e != nil {
+			return e
+		}
+	}
+
+	if len(upserts) > 0 {
+		_, e := b.performOp(UPSERT, upserts, datastore.NULL_QUERY_CONTEXT)
+		if e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+/*
+Named views are persisted as files in the directory of their
+namespace, next to the keyspace directories.
+*/
+const _NAMED_VIEW_EXT = ".view"
+
+func (p *namespace) loadNamedViews() errors.Error {
+	p.views = make(map[string]*datastore.NamedView)
+
+	dirEntries, er := ioutil.ReadDir(p.path())
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || filepath.Ext(dirEntry.Name()) != _NAMED_VIEW_EXT {
+			continue
+		}
+
+		view := &datastore.NamedView{}
+		e := readIndexFile(filepath.Join(p.path(), dirEntry.Name()), view)
+		if e != nil {
+			return e
+		}
+
+		p.views[strings.ToUpper(view.Name)] = view
+	}
+
+	return nil
+}
+
+func (p *namespace) NamedViews() ([]*datastore.NamedView, errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	rv := make([]*datastore.NamedView, 0, len(p.views))
+	for _, view := range p.views {
+		rv = append(rv, view)
+	}
+	sort.Sort(namedViews(rv))
+	return rv, nil
+}
+
+func (p *namespace) NamedViewByName(name string) (*datastore.NamedView, errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.views[strings.ToUpper(name)], nil
+}
+
+func (p *namespace) CreateNamedView(view *datastore.NamedView) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	nameu := strings.ToUpper(view.Name)
+	if p.nameTaken(nameu) {
+		return errors.NewNamedViewAlreadyExistsError(view.Name)
+	}
+
+	bytes, er := json.Marshal(view)
+	if er == nil {
+		er = ioutil.WriteFile(filepath.Join(p.path(), view.Name+_NAMED_VIEW_EXT), bytes, 0666)
+	}
+
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	p.views[nameu] = view
+	return nil
+}
+
+func (p *namespace) DropNamedView(name string) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	nameu := strings.ToUpper(name)
+	view, ok := p.views[nameu]
+	if !ok {
+		return errors.NewNamedViewNotFoundError(name)
+	}
+
+	er := os.Remove(filepath.Join(p.path(), view.Name+_NAMED_VIEW_EXT))
+	if er != nil && !os.IsNotExist(er) {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	delete(p.views, nameu)
+	return nil
+}
+
+/*
+nameTaken returns true if nameu is the upper case name of a keyspace
+or named view. The namespace must be locked.
+*/
+func (p *namespace) nameTaken(nameu string) bool {
+	_, keyspace := p.keyspaces[nameu]
+	_, view := p.views[nameu]
+	return keyspace || view
+}
+
+type namedViews []*datastore.NamedView
+
+func (this namedViews) Len() int {
+	return len(this)
+}
+
+func (this namedViews) Less(i, j int) bool {
+	return this[i].Name < this[j].Name
+}
+
+func (this namedViews) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+}
@@ -10,6 +10,8 @@
 package datastore
 
 import (
+	"time"
+
 	atomic "github.com/couchbase/go-couchbase/platform"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
@@ -24,6 +26,8 @@ const (
 	DEFAULT IndexType = "default" // default may vary per backend
 	VIEW    IndexType = "view"    // view index
 	GSI     IndexType = "gsi"     // global secondary index
+	FTS     IndexType = "fts"     // full-text search index
+	GEO     IndexType = "geo"     // geospatial index
 )
 
 type Indexer interface {
@@ -100,6 +104,28 @@ type PrimaryIndex interface {
 		vector timestamp.Vector, conn *IndexConnection) // Perform a scan of all the entries in this index
 }
 
+/*
+SearchIndex represents full-text indexes. Scan expects the query string
+as the single Seek value of the span, and returns entries in descending
+order of relevance, with the score as the single EntryKey.
+*/
+type SearchIndex interface {
+	Index
+	Analyzer() string // Name of the text analyzer used by this index
+	Fields() []string // Document paths indexed by this index; "" is the whole document
+}
+
+/*
+SpatialIndex represents geospatial indexes on a single key. Scan
+expects a bounding box [min_lon, min_lat, max_lon, max_lat] as the
+single Seek value of the span, and returns the entries whose geometry
+may intersect it.
+*/
+type SpatialIndex interface {
+	Index
+	Precision() int // Geohash precision of the index cells
+}
+
 type SizedIndex interface {
 	Index
 	SizeFromStatistics(requestId string) (int64, errors.Error)
@@ -201,6 +227,29 @@ func (this *IndexConnection) StopChannel() StopChannel {
 	return this.stopChannel
 }
 
+func (this *IndexConnection) Done() <-chan bool {
+	return queryDone(this.context)
+}
+
+func (this *IndexConnection) Err() errors.Error {
+	return queryErr(this.context)
+}
+
+/*
+Send sends entry to the consumer of the scan. It returns false if the
+scan is stopped, by the consumer or by its request, and should end.
+*/
+func (this *IndexConnection) Send(entry *IndexEntry) bool {
+	select {
+	case this.entryChannel <- entry:
+		return true
+	case <-this.stopChannel:
+		return false
+	case <-this.Done():
+		return false
+	}
+}
+
 func (this *IndexConnection) Fatal(err errors.Error) {
 	this.context.Fatal(err)
 }
@@ -224,3 +273,13 @@ func (this *IndexConnection) SetPrimary() {
 func (this *IndexConnection) Timeout() bool {
 	return this.timeout
 }
+
+/*
+ScanWait returns how long the scan may wait for consistency.
+*/
+func (this *IndexConnection) ScanWait() time.Duration {
+	if waiter, ok := this.context.(ScanWaiter); ok && waiter.ScanWait() > 0 {
+		return waiter.ScanWait()
+	}
+	return DEFAULT_SCAN_WAIT
+}
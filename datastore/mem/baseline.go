@@ -0,0 +1,55 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package mem
+
+import (
+	"sort"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+func (p *namespace) PlanBaselines() ([]*datastore.PlanBaseline, errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	statements := make([]string, 0, len(p.baselines))
+	for statement, _ := range p.baselines {
+		statements = append(statements, statement)
+	}
+	sort.Strings(statements)
+
+	rv := make([]*datastore.PlanBaseline, len(statements))
+	for i, statement := range statements {
+		rv[i] = p.baselines[statement]
+	}
+
+	return rv, nil
+}
+
+func (p *namespace) SetPlanBaseline(baseline *datastore.PlanBaseline) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	p.baselines[baseline.Statement] = baseline
+	return nil
+}
+
+func (p *namespace) DropPlanBaseline(statement string) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.baselines[statement]; !ok {
+		return errors.NewPlanBaselineNotFoundError(statement)
+	}
+
+	delete(p.baselines, statement)
+	return nil
+}
@@ -0,0 +1,556 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package mem
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+/*
+indexer manages the indexes of a keyspace. Its indexes are protected
+by the lock of the keyspace.
+*/
+type indexer struct {
+	keyspace *keyspace
+	indexes  map[string]*index
+	primary  *index
+}
+
+func newIndexer(keyspace *keyspace) *indexer {
+	return &indexer{
+		keyspace: keyspace,
+		indexes:  make(map[string]*index),
+	}
+}
+
+func (mi *indexer) KeyspaceId() string {
+	return mi.keyspace.Id()
+}
+
+func (mi *indexer) Name() datastore.IndexType {
+	return datastore.GSI
+}
+
+func (mi *indexer) IndexIds() ([]string, errors.Error) {
+	return mi.IndexNames()
+}
+
+func (mi *indexer) IndexNames() ([]string, errors.Error) {
+	mi.keyspace.RLock()
+	defer mi.keyspace.RUnlock()
+
+	rv := make([]string, 0, len(mi.indexes))
+	for name, _ := range mi.indexes {
+		rv = append(rv, name)
+	}
+	sort.Strings(rv)
+	return rv, nil
+}
+
+func (mi *indexer) IndexById(id string) (datastore.Index, errors.Error) {
+	return mi.IndexByName(id)
+}
+
+func (mi *indexer) IndexByName(name string) (datastore.Index, errors.Error) {
+	mi.keyspace.RLock()
+	defer mi.keyspace.RUnlock()
+
+	index, ok := mi.indexes[name]
+	if !ok {
+		return nil, errors.NewOtherIdxNotFoundError(nil, name+" for mem datastore")
+	}
+	return index, nil
+}
+
+func (mi *indexer) PrimaryIndexes() ([]datastore.PrimaryIndex, errors.Error) {
+	mi.keyspace.RLock()
+	defer mi.keyspace.RUnlock()
+
+	if mi.primary == nil {
+		return nil, nil
+	}
+	return []datastore.PrimaryIndex{mi.primary}, nil
+}
+
+func (mi *indexer) Indexes() ([]datastore.Index, errors.Error) {
+	mi.keyspace.RLock()
+	defer mi.keyspace.RUnlock()
+
+	names := make([]string, 0, len(mi.indexes))
+	for name, _ := range mi.indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rv := make([]datastore.Index, len(names))
+	for i, name := range names {
+		rv[i] = mi.indexes[name]
+	}
+	return rv, nil
+}
+
+func (mi *indexer) CreatePrimaryIndex(requestId, name string, with value.Value) (
+	datastore.PrimaryIndex, errors.Error) {
+	mi.keyspace.Lock()
+	defer mi.keyspace.Unlock()
+
+	if mi.primary != nil {
+		if mi.primary.name == name {
+			return mi.primary, nil
+		}
+		return nil, errors.NewOtherDatastoreError(nil, fmt.Sprintf(
+			"Keyspace %s already has primary index %s.", mi.keyspace.Name(), mi.primary.name))
+	}
+
+	index, e := mi.create(name, nil, nil, with)
+	if e != nil {
+		return nil, e
+	}
+
+	mi.primary = index
+	return index, nil
+}
+
+func (mi *indexer) CreateIndex(requestId, name string, seekKey, rangeKey expression.Expressions,
+	where expression.Expression, with value.Value) (datastore.Index, errors.Error) {
+	if len(rangeKey) == 0 {
+		return nil, errors.NewOtherDatastoreError(nil, "Secondary index requires at least one key.")
+	}
+
+	mi.keyspace.Lock()
+	defer mi.keyspace.Unlock()
+
+	return mi.create(name, rangeKey, where, with)
+}
+
+/*
+create creates and builds an index, unless its build is deferred.
+The keyspace must be locked.
+*/
+func (mi *indexer) create(name string, keys expression.Expressions, where expression.Expression,
+	with value.Value) (*index, errors.Error) {
+	if _, ok := mi.indexes[name]; ok {
+		return nil, errors.NewIndexAlreadyExistsError(name)
+	}
+
+	index := &index{
+		indexer: mi,
+		name:    name,
+		primary: keys == nil,
+		keys:    keys,
+		where:   where,
+		state:   datastore.DEFERRED,
+	}
+
+	deferred := false
+	if with != nil {
+		if d, ok := with.Field("defer_build"); ok {
+			deferred = d.Truth()
+		}
+	}
+
+	if !deferred {
+		mi.build(index)
+	}
+
+	mi.indexes[name] = index
+	return index, nil
+}
+
+/*
+build indexes the documents of the keyspace, after indexing pending
+mutations in the other indexes. The keyspace must be locked.
+*/
+func (mi *indexer) build(index *index) {
+	mi.keyspace.catchUp(mi.keyspace.seqno)
+
+	index.root = nil
+	index.entries = make(map[string][]*indexEntry, len(mi.keyspace.docs))
+	for key, doc := range mi.keyspace.docs {
+		index.add(key, doc)
+	}
+	index.state = datastore.ONLINE
+}
+
+func (mi *indexer) BuildIndexes(requestId string, names ...string) errors.Error {
+	mi.keyspace.Lock()
+	defer mi.keyspace.Unlock()
+
+	for _, name := range names {
+		index, ok := mi.indexes[name]
+		if !ok {
+			return errors.NewOtherIdxNotFoundError(nil, name+" for mem datastore")
+		}
+
+		if index.state == datastore.DEFERRED {
+			mi.build(index)
+		}
+	}
+
+	return nil
+}
+
+func (mi *indexer) Refresh() errors.Error {
+	return nil
+}
+
+func (mi *indexer) SetLogLevel(level logging.Level) {
+	// No-op, uses query engine logger
+}
+
+/*
+update indexes a document written or deleted by a mutation; doc is
+nil for deletions. The keyspace must be locked.
+*/
+func (mi *indexer) update(key string, doc []byte) {
+	for _, index := range mi.indexes {
+		if index.state != datastore.ONLINE {
+			continue
+		}
+
+		index.remove(key)
+		if doc != nil {
+			index.add(key, doc)
+		}
+	}
+}
+
+func (mi *indexer) drop(index *index) errors.Error {
+	mi.keyspace.Lock()
+	defer mi.keyspace.Unlock()
+
+	if mi.indexes[index.name] != index {
+		return errors.NewOtherIdxNotFoundError(nil, index.name+" for mem datastore")
+	}
+
+	delete(mi.indexes, index.name)
+	if mi.primary == index {
+		mi.primary = nil
+	}
+	return nil
+}
+
+/*
+index is a primary or secondary index, stored as a tree of entries.
+Primary index entries have the document key as their single key.
+Secondary index entries have the values of the index keys; an array
+index key is expanded to an entry per element. Documents whose
+leading key is MISSING are not indexed.
+*/
+type index struct {
+	indexer *indexer
+	name    string
+	primary bool
+	keys    expression.Expressions
+	where   expression.Expression
+	state   datastore.IndexState
+	root    *treeNode
+	entries map[string][]*indexEntry // Entries of each document
+}
+
+func (idx *index) KeyspaceId() string {
+	return idx.indexer.KeyspaceId()
+}
+
+func (idx *index) Id() string {
+	return idx.Name()
+}
+
+func (idx *index) Name() string {
+	return idx.name
+}
+
+func (idx *index) Type() datastore.IndexType {
+	return datastore.GSI
+}
+
+func (idx *index) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (idx *index) RangeKey() expression.Expressions {
+	return idx.keys
+}
+
+func (idx *index) Condition() expression.Expression {
+	return idx.where
+}
+
+func (idx *index) IsPrimary() bool {
+	return idx.primary
+}
+
+func (idx *index) State() (state datastore.IndexState, msg string, err errors.Error) {
+	idx.indexer.keyspace.RLock()
+	defer idx.indexer.keyspace.RUnlock()
+	return idx.state, "", nil
+}
+
+func (idx *index) Drop(requestId string) errors.Error {
+	return idx.indexer.drop(idx)
+}
+
+func (idx *index) add(key string, doc []byte) {
+	entries := idx.evaluate(key, doc)
+	if len(entries) == 0 {
+		return
+	}
+
+	idx.entries[key] = entries
+	for _, entry := range entries {
+		idx.root = treeInsert(idx.root, entry)
+	}
+}
+
+func (idx *index) remove(key string) {
+	for _, entry := range idx.entries[key] {
+		idx.root = treeRemove(idx.root, entry)
+	}
+	delete(idx.entries, key)
+}
+
+/*
+evaluate returns the index entries of a document.
+*/
+func (idx *index) evaluate(key string, doc []byte) []*indexEntry {
+	if idx.primary {
+		return []*indexEntry{&indexEntry{key: value.Values{value.NewValue(key)}, pk: key}}
+	}
+
+	context := expression.NewIndexContext()
+	av := value.NewAnnotatedValue(value.NewValue(doc))
+	av.SetAttachment("meta", map[string]interface{}{"id": key})
+
+	if idx.where != nil {
+		w, err := idx.where.Evaluate(av, context)
+		if err != nil || !w.Truth() {
+			return nil
+		}
+	}
+
+	keys := []value.Values{value.Values{}}
+	for i, expr := range idx.keys {
+		val, vals, err := expr.EvaluateForIndex(av, context)
+		if err != nil {
+			logging.Debugf("Error indexing %s in mem index %s: %v", key, idx.name, err)
+			return nil
+		}
+
+		if vals == nil {
+			if val == nil || (i == 0 && val.Type() == value.MISSING) {
+				return nil
+			}
+			vals = value.Values{val}
+		}
+
+		if len(vals) == 0 {
+			return nil
+		}
+
+		expanded := make([]value.Values, 0, len(keys)*len(vals))
+		for _, k := range keys {
+			for _, v := range vals {
+				ek := make(value.Values, len(k), len(k)+1)
+				copy(ek, k)
+				expanded = append(expanded, append(ek, v))
+			}
+		}
+		keys = expanded
+	}
+
+	rv := make([]*indexEntry, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		entry := &indexEntry{key: k, pk: key}
+		s := keyText(k)
+		if !seen[s] {
+			seen[s] = true
+			rv = append(rv, entry)
+		}
+	}
+	return rv
+}
+
+/*
+scan calls fn for the entries in span, in order, until fn returns
+false. It returns an error if the index is not online.
+*/
+func (idx *index) scan(span *datastore.Span, cons datastore.ScanConsistency,
+	vector timestamp.Vector, fn func(*indexEntry) bool) errors.Error {
+	state, _, _ := idx.State()
+	if state != datastore.ONLINE {
+		return errors.NewOtherDatastoreError(nil, fmt.Sprintf("Index %s is %s.", idx.name, state))
+	}
+
+	root := idx.indexer.keyspace.scanRoot(idx, cons, vector)
+
+	low, high, inclusion := span.Range.Low, span.Range.High, span.Range.Inclusion
+	if len(span.Seek) > 0 {
+		low, high, inclusion = span.Seek, span.Seek, datastore.BOTH
+	}
+
+	start := func(entry *indexEntry) bool {
+		return compareKeys(entry.key, low) >= 0
+	}
+
+	treeAscend(root, start, func(entry *indexEntry) bool {
+		if len(low) > 0 && inclusion&datastore.LOW == 0 && compareKeys(entry.key, low) == 0 {
+			return true
+		}
+
+		if len(high) > 0 {
+			c := compareKeys(entry.key, high)
+			if c > 0 || (c == 0 && inclusion&datastore.HIGH == 0) {
+				return false
+			}
+		}
+
+		return fn(entry)
+	})
+
+	return nil
+}
+
+func (idx *index) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer close(conn.EntryChannel())
+
+	var n int64
+	err := idx.scan(span, cons, vector, func(entry *indexEntry) bool {
+		if limit > 0 && n >= limit {
+			return false
+		}
+		n++
+
+		ie := &datastore.IndexEntry{PrimaryKey: entry.pk}
+		if !idx.primary {
+			ie.EntryKey = entry.key
+		}
+		return conn.Send(ie)
+	})
+
+	if err != nil {
+		conn.Error(err)
+	}
+}
+
+func (idx *index) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	idx.Scan(requestId, &datastore.Span{}, false, limit, cons, vector, conn)
+}
+
+/*
+Count counts the entries in span, for COUNT pushdown.
+*/
+func (idx *index) Count(span *datastore.Span, cons datastore.ScanConsistency,
+	vector timestamp.Vector) (int64, errors.Error) {
+	var n int64
+	err := idx.scan(span, cons, vector, func(entry *indexEntry) bool {
+		n++
+		return true
+	})
+	return n, err
+}
+
+func (idx *index) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	var entries []value.Values
+	err := idx.scan(span, datastore.UNBOUNDED, nil, func(entry *indexEntry) bool {
+		entries = append(entries, entry.key)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := newStatistics(entries)
+
+	// Equal-depth bins, for the planner to partition scans
+	size := (len(entries) + _STATISTICS_BINS - 1) / _STATISTICS_BINS
+	for i := 0; i < len(entries) && size > 0; i += size {
+		end := i + size
+		if end > len(entries) {
+			end = len(entries)
+		}
+		stats.bins = append(stats.bins, newStatistics(entries[i:end]))
+	}
+
+	return stats, nil
+}
+
+// Maximum number of bins in index statistics.
+const _STATISTICS_BINS = 64
+
+/*
+keyText returns the text of an index entry key, to find duplicates.
+*/
+func keyText(key value.Values) string {
+	var buf bytes.Buffer
+	for _, v := range key {
+		buf.WriteString(v.String())
+		buf.WriteByte(0)
+	}
+	return buf.String()
+}
+
+// statistics of a span, computed by a scan.
+type statistics struct {
+	count    int64
+	distinct int64
+	min      value.Values
+	max      value.Values
+	bins     []datastore.Statistics
+}
+
+func newStatistics(entries []value.Values) *statistics {
+	stats := &statistics{count: int64(len(entries))}
+	if len(entries) == 0 {
+		return stats
+	}
+
+	distinct := make(map[string]bool, len(entries))
+	for _, key := range entries {
+		distinct[keyText(key)] = true
+	}
+
+	stats.distinct = int64(len(distinct))
+	stats.min = entries[0]
+	stats.max = entries[len(entries)-1]
+	return stats
+}
+
+func (s *statistics) Count() (int64, errors.Error) {
+	return s.count, nil
+}
+
+func (s *statistics) Min() (value.Values, errors.Error) {
+	return s.min, nil
+}
+
+func (s *statistics) Max() (value.Values, errors.Error) {
+	return s.max, nil
+}
+
+func (s *statistics) DistinctCount() (int64, errors.Error) {
+	return s.distinct, nil
+}
+
+func (s *statistics) Bins() ([]datastore.Statistics, errors.Error) {
+	return s.bins, nil
+}
@@ -0,0 +1,559 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+
+Package mem provides an in-memory implementation of the datastore
+package, for tests. Unlike the mock datastore it stores documents,
+supports DML and primary and secondary indexes, and can be
+snapshotted and restored between tests. Each DML operation is applied
+under the lock of its keyspace, so that fetches and scans see all or
+none of its documents.
+
+The URI of a mem datastore is mem: followed by an optional
+comma-separated list of keyspaces to create, qualified by their
+namespace if it is not default, and options:
+
+	mem:orders,customers,index_delay=100ms
+
+Indexes are normally updated with each mutation. With the index_delay
+option, mutations are indexed after the given delay, the way a
+secondary index service lags behind the data; scans with request_plus
+or at_plus consistency include all prior mutations.
+
+*/
+package mem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/schema"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+const DEFAULT_NAMESPACE = "default"
+
+/*
+Store is a mem datastore. In addition to the datastore interface it
+provides the creation of keyspaces, and snapshots.
+*/
+type Store struct {
+	sync.RWMutex
+	path       string
+	indexDelay time.Duration
+	namespaces map[string]*namespace
+}
+
+/*
+NewDatastore creates a mem datastore from its URI. See the package
+documentation.
+*/
+func NewDatastore(uri string) (datastore.Datastore, errors.Error) {
+	return NewStore(uri)
+}
+
+func NewStore(uri string) (*Store, errors.Error) {
+	path := strings.TrimPrefix(uri, "mem:")
+	s := &Store{
+		path:       path,
+		namespaces: make(map[string]*namespace),
+	}
+
+	s.namespaces[DEFAULT_NAMESPACE] = newNamespace(s, DEFAULT_NAMESPACE)
+
+	for _, option := range strings.Split(path, ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+
+		if strings.HasPrefix(option, "index_delay=") {
+			delay, er := time.ParseDuration(option[len("index_delay="):])
+			if er != nil || delay < 0 {
+				return nil, errors.NewOtherDatastoreError(er,
+					fmt.Sprintf("Invalid index_delay in mem datastore URI: %s", option))
+			}
+			s.indexDelay = delay
+			continue
+		}
+
+		namespace, keyspace := DEFAULT_NAMESPACE, option
+		if i := strings.IndexByte(option, ':'); i >= 0 {
+			namespace, keyspace = option[:i], option[i+1:]
+		}
+
+		e := s.CreateKeyspace(namespace, keyspace)
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Store) Id() string {
+	return s.URL()
+}
+
+func (s *Store) URL() string {
+	return "mem:" + s.path
+}
+
+func (s *Store) NamespaceIds() ([]string, errors.Error) {
+	return s.NamespaceNames()
+}
+
+func (s *Store) NamespaceNames() ([]string, errors.Error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	rv := make([]string, 0, len(s.namespaces))
+	for name, _ := range s.namespaces {
+		rv = append(rv, name)
+	}
+	sort.Strings(rv)
+	return rv, nil
+}
+
+func (s *Store) NamespaceById(id string) (datastore.Namespace, errors.Error) {
+	return s.NamespaceByName(id)
+}
+
+func (s *Store) NamespaceByName(name string) (datastore.Namespace, errors.Error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	p, ok := s.namespaces[name]
+	if !ok {
+		return nil, errors.NewOtherNamespaceNotFoundError(nil, name+" for mem datastore")
+	}
+	return p, nil
+}
+
+func (s *Store) Authorize(datastore.Privileges, datastore.Credentials) errors.Error {
+	return nil
+}
+
+func (s *Store) SetLogLevel(level logging.Level) {
+	// No-op. Uses query engine logger.
+}
+
+func (s *Store) Inferencer(name datastore.InferenceType) (datastore.Inferencer, errors.Error) {
+	return nil, errors.NewOtherNotImplementedError(nil, "INFER")
+}
+
+func (s *Store) Inferencers() ([]datastore.Inferencer, errors.Error) {
+	return nil, errors.NewOtherNotImplementedError(nil, "INFER")
+}
+
+/*
+CreateKeyspace creates an empty keyspace, and its namespace if
+needed.
+*/
+func (s *Store) CreateKeyspace(namespace, name string) errors.Error {
+	if namespace == "" || name == "" {
+		return errors.NewOtherDatastoreError(nil,
+			fmt.Sprintf("Invalid keyspace name %s:%s.", namespace, name))
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	p, ok := s.namespaces[namespace]
+	if !ok {
+		p = newNamespace(s, namespace)
+		s.namespaces[namespace] = p
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if p.nameTaken(name) {
+		return errors.NewOtherDatastoreError(nil,
+			fmt.Sprintf("Keyspace %s:%s already exists.", namespace, name))
+	}
+
+	p.keyspaces[name] = newKeyspace(p, name)
+	return nil
+}
+
+/*
+DropKeyspace drops a keyspace and its indexes.
+*/
+func (s *Store) DropKeyspace(namespace, name string) errors.Error {
+	s.RLock()
+	defer s.RUnlock()
+
+	p, ok := s.namespaces[namespace]
+	if !ok {
+		return errors.NewOtherNamespaceNotFoundError(nil, namespace+" for mem datastore")
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.keyspaces[name]; !ok {
+		return errors.NewOtherKeyspaceNotFoundError(nil, name+" for mem datastore")
+	}
+
+	delete(p.keyspaces, name)
+	return nil
+}
+
+// namespace is a mem namespace.
+type namespace struct {
+	sync.RWMutex
+	store     *Store
+	name      string
+	keyspaces map[string]*keyspace
+	views     map[string]*datastore.NamedView
+	sequences map[string]*datastore.SequenceCounter
+	baselines map[string]*datastore.PlanBaseline
+}
+
+func newNamespace(s *Store, name string) *namespace {
+	return &namespace{
+		store:     s,
+		name:      name,
+		keyspaces: make(map[string]*keyspace),
+		views:     make(map[string]*datastore.NamedView),
+		sequences: make(map[string]*datastore.SequenceCounter),
+		baselines: make(map[string]*datastore.PlanBaseline),
+	}
+}
+
+func (p *namespace) DatastoreId() string {
+	return p.store.Id()
+}
+
+func (p *namespace) Id() string {
+	return p.Name()
+}
+
+func (p *namespace) Name() string {
+	return p.name
+}
+
+func (p *namespace) KeyspaceIds() ([]string, errors.Error) {
+	return p.KeyspaceNames()
+}
+
+func (p *namespace) KeyspaceNames() ([]string, errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	rv := make([]string, 0, len(p.keyspaces))
+	for name, _ := range p.keyspaces {
+		rv = append(rv, name)
+	}
+	sort.Strings(rv)
+	return rv, nil
+}
+
+func (p *namespace) KeyspaceById(id string) (datastore.Keyspace, errors.Error) {
+	return p.KeyspaceByName(id)
+}
+
+func (p *namespace) KeyspaceByName(name string) (datastore.Keyspace, errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	b, ok := p.keyspaces[name]
+	if !ok {
+		return nil, errors.NewOtherKeyspaceNotFoundError(nil, name+" for mem datastore")
+	}
+	return b, nil
+}
+
+/*
+keyspace is a mem keyspace. Documents are stored as JSON, so that
+they are isolated from the values of the query engine. The lock of
+the keyspace protects its documents and mutation log, and the roots
+of its index trees.
+*/
+type keyspace struct {
+	sync.RWMutex
+	namespace *namespace
+	name      string
+	docs      map[string][]byte
+	indexer   *indexer
+	triggers  map[string]*datastore.Trigger
+	view      *datastore.MaterializedView
+	schema    *schema.Schema
+	seqno     uint64     // Sequence number of the last mutation
+	indexed   uint64     // Sequence number of the last indexed mutation
+	pending   []mutation // Mutations not yet indexed
+}
+
+/*
+mutation is an entry of the mutation log of a keyspace, indexed
+after the index_delay of the store. doc is nil for deletions.
+*/
+type mutation struct {
+	seqno uint64
+	time  time.Time
+	key   string
+	doc   []byte
+}
+
+func newKeyspace(p *namespace, name string) *keyspace {
+	b := &keyspace{
+		namespace: p,
+		name:      name,
+		docs:      make(map[string][]byte),
+		triggers:  make(map[string]*datastore.Trigger),
+	}
+	b.indexer = newIndexer(b)
+	return b
+}
+
+func (b *keyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *keyspace) Id() string {
+	return b.Name()
+}
+
+func (b *keyspace) Name() string {
+	return b.name
+}
+
+func (b *keyspace) Count() (int64, errors.Error) {
+	b.RLock()
+	defer b.RUnlock()
+	return int64(len(b.docs)), nil
+}
+
+func (b *keyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	switch name {
+	case datastore.FTS, datastore.GEO:
+		return nil, errors.NewOtherNotSupportedError(nil,
+			fmt.Sprintf("%s indexes are not supported for mem datastore.", name))
+	}
+	return b.indexer, nil
+}
+
+func (b *keyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *keyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
+	b.RLock()
+	defer b.RUnlock()
+
+	rv := make([]value.AnnotatedPair, 0, len(keys))
+	for _, k := range keys {
+		if err := context.Err(); err != nil {
+			return rv, []errors.Error{err}
+		}
+
+		bytes, ok := b.docs[k]
+		if !ok {
+			// Missing keys are ignored, as in the other datastores
+			continue
+		}
+
+		item := value.NewAnnotatedValue(value.NewValue(bytes))
+		item.SetAttachment("meta", map[string]interface{}{
+			"id": k,
+		})
+
+		rv = append(rv, value.AnnotatedPair{
+			Name:  k,
+			Value: item,
+		})
+	}
+
+	return rv, nil
+}
+
+const (
+	_INSERT = iota
+	_UPDATE
+	_UPSERT
+)
+
+/*
+performOp writes documents. Each document is written or fails on its
+own; the pairs that were written are returned with the first error.
+If context is stopped, the remaining documents are not written.
+*/
+func (b *keyspace) performOp(op int, pairs []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	b.Lock()
+	defer b.Unlock()
+
+	var err errors.Error
+	rv := make([]value.Pair, 0, len(pairs))
+	for _, pair := range pairs {
+		if stopped := context.Err(); stopped != nil {
+			return rv, stopped
+		}
+
+		_, exists := b.docs[pair.Name]
+		switch {
+		case op == _INSERT && exists:
+			if err == nil {
+				err = errors.NewOtherKeyExistsError(nil, pair.Name)
+			}
+			continue
+		case op == _UPDATE && !exists:
+			if err == nil {
+				err = errors.NewOtherKeyNotFoundError(nil, pair.Name)
+			}
+			continue
+		}
+
+		bytes, er := pair.Value.MarshalJSON()
+		if er != nil {
+			if err == nil {
+				err = errors.NewOtherDatastoreError(er, "")
+			}
+			continue
+		}
+
+		b.docs[pair.Name] = bytes
+		b.mutate(pair.Name, bytes)
+		rv = append(rv, pair)
+	}
+
+	return rv, err
+}
+
+func (b *keyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	if b.view != nil {
+		return nil, errors.NewViewReadOnlyError(b.name)
+	}
+	return b.performOp(_INSERT, inserts, context)
+}
+
+func (b *keyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	if b.view != nil {
+		return nil, errors.NewViewReadOnlyError(b.name)
+	}
+	return b.performOp(_UPDATE, updates, context)
+}
+
+func (b *keyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	if b.view != nil {
+		return nil, errors.NewViewReadOnlyError(b.name)
+	}
+	return b.performOp(_UPSERT, upserts, context)
+}
+
+func (b *keyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	if b.view != nil {
+		return nil, errors.NewViewReadOnlyError(b.name)
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	return b.delete(deletes, context)
+}
+
+// delete removes documents. The keyspace must be locked.
+func (b *keyspace) delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	rv := make([]string, 0, len(deletes))
+	for _, key := range deletes {
+		if err := context.Err(); err != nil {
+			return rv, err
+		}
+
+		if _, ok := b.docs[key]; ok {
+			delete(b.docs, key)
+			b.mutate(key, nil)
+			rv = append(rv, key)
+		}
+	}
+
+	return rv, nil
+}
+
+func (b *keyspace) Release() {
+}
+
+/*
+mutate records a mutation, and indexes it unless the store has an
+index delay. The keyspace must be locked.
+*/
+func (b *keyspace) mutate(key string, doc []byte) {
+	b.seqno++
+	if b.namespace.store.indexDelay == 0 && len(b.pending) == 0 {
+		b.indexer.update(key, doc)
+		b.indexed = b.seqno
+		return
+	}
+
+	b.pending = append(b.pending, mutation{
+		seqno: b.seqno,
+		time:  time.Now(),
+		key:   key,
+		doc:   doc,
+	})
+}
+
+/*
+catchUp indexes the pending mutations up to seqno, and those older
+than the index delay. The keyspace must be locked.
+*/
+func (b *keyspace) catchUp(seqno uint64) {
+	cutoff := time.Now().Add(-b.namespace.store.indexDelay)
+
+	n := 0
+	for _, m := range b.pending {
+		if m.seqno > seqno && m.time.After(cutoff) {
+			break
+		}
+
+		b.indexer.update(m.key, m.doc)
+		b.indexed = m.seqno
+		n++
+	}
+
+	if n > 0 {
+		b.pending = append(b.pending[:0], b.pending[n:]...)
+	}
+}
+
+/*
+scanRoot returns the root of an index tree for a scan with the given
+consistency, after indexing the mutations the scan must include.
+*/
+func (b *keyspace) scanRoot(index *index, cons datastore.ScanConsistency,
+	vector timestamp.Vector) *treeNode {
+	b.Lock()
+	defer b.Unlock()
+
+	seqno := b.indexed
+	switch cons {
+	case datastore.SCAN_PLUS:
+		seqno = b.seqno
+	case datastore.AT_PLUS:
+		seqno = b.seqno
+		if vector != nil {
+			seqno = 0
+			for _, entry := range vector.Entries() {
+				if entry.Value() > seqno {
+					seqno = entry.Value()
+				}
+			}
+		}
+	}
+
+	b.catchUp(seqno)
+	return index.root
+}
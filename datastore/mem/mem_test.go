@@ -0,0 +1,653 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package mem
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/expression/parser"
+	"github.com/couchbase/query/schema"
+	"github.com/couchbase/query/value"
+)
+
+func newTestKeyspace(t *testing.T, uri string) (*Store, *keyspace) {
+	s, err := NewStore(uri)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	b, err := s.namespaces["default"].KeyspaceByName("orders")
+	if err != nil {
+		t.Fatalf("expected keyspace orders: %v", err)
+	}
+
+	_, err = b.Insert([]value.Pair{
+		value.Pair{Name: "o1", Value: value.NewValue(map[string]interface{}{"total": 10, "items": []interface{}{"a", "b"}})},
+		value.Pair{Name: "o2", Value: value.NewValue(map[string]interface{}{"total": 20, "items": []interface{}{"b"}})},
+		value.Pair{Name: "o3", Value: value.NewValue(map[string]interface{}{"total": 30})},
+		value.Pair{Name: "o4", Value: value.NewValue(map[string]interface{}{"status": "new"})},
+	}, datastore.NULL_QUERY_CONTEXT)
+	if err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+
+	return s, b.(*keyspace)
+}
+
+func createIndex(t *testing.T, b *keyspace, name string, key string) datastore.Index {
+	expr, e := parser.Parse(key)
+	if e != nil {
+		t.Fatalf("invalid index key %s: %v", key, e)
+	}
+
+	index, err := b.indexer.CreateIndex("", name, nil, expression.Expressions{expr}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create index %s: %v", name, err)
+	}
+	return index
+}
+
+func TestDML(t *testing.T) {
+	_, b := newTestKeyspace(t, "mem:orders")
+
+	c, _ := b.Count()
+	if c != 4 {
+		t.Fatalf("expected 4 documents, got %d", c)
+	}
+
+	_, err := b.Insert([]value.Pair{value.Pair{Name: "o1", Value: value.NewValue(1)}}, datastore.NULL_QUERY_CONTEXT)
+	if err == nil || err.Code() != errors.NewOtherKeyExistsError(nil, "").Code() {
+		t.Fatalf("expected duplicate key error, got %v", err)
+	}
+
+	_, err = b.Update([]value.Pair{value.Pair{Name: "o9", Value: value.NewValue(1)}}, datastore.NULL_QUERY_CONTEXT)
+	if err == nil {
+		t.Fatalf("expected missing key error")
+	}
+
+	_, err = b.Upsert([]value.Pair{value.Pair{Name: "o1", Value: value.NewValue(map[string]interface{}{"total": 15})}}, datastore.NULL_QUERY_CONTEXT)
+	if err != nil {
+		t.Fatalf("failed to upsert: %v", err)
+	}
+
+	pairs, errs := b.Fetch([]string{"o1", "o9"}, datastore.NULL_QUERY_CONTEXT)
+	if len(errs) != 0 || len(pairs) != 1 {
+		t.Fatalf("expected to fetch o1 only, got %v %v", pairs, errs)
+	}
+
+	total, _ := pairs[0].Value.Field("total")
+	if total.Actual() != float64(15) {
+		t.Errorf("expected upserted total 15, got %v", total)
+	}
+
+	id, _ := pairs[0].Value.GetAttachment("meta").(map[string]interface{})["id"]
+	if id != "o1" {
+		t.Errorf("expected meta id o1, got %v", id)
+	}
+
+	deleted, err := b.Delete([]string{"o1", "o9"}, datastore.NULL_QUERY_CONTEXT)
+	if err != nil || len(deleted) != 1 {
+		t.Fatalf("expected to delete o1 only, got %v %v", deleted, err)
+	}
+}
+
+func TestPrimaryScan(t *testing.T) {
+	_, b := newTestKeyspace(t, "mem:orders")
+
+	_, err := b.indexer.CreatePrimaryIndex("", "#primary", nil)
+	if err != nil {
+		t.Fatalf("failed to create primary index: %v", err)
+	}
+
+	primary := b.indexer.primary
+	entries := doScan(t, primary, &datastore.Span{}, datastore.UNBOUNDED)
+	if keys(entries) != "o1 o2 o3 o4" {
+		t.Errorf("unexpected primary scan %s", keys(entries))
+	}
+
+	span := &datastore.Span{Range: datastore.Range{
+		Low:       value.Values{value.NewValue("o2")},
+		High:      value.Values{value.NewValue("o4")},
+		Inclusion: datastore.LOW,
+	}}
+	entries = doScan(t, primary, span, datastore.UNBOUNDED)
+	if keys(entries) != "o2 o3" {
+		t.Errorf("unexpected primary range scan %s", keys(entries))
+	}
+
+	b.Delete([]string{"o2"}, datastore.NULL_QUERY_CONTEXT)
+	entries = doScan(t, primary, span, datastore.UNBOUNDED)
+	if keys(entries) != "o3" {
+		t.Errorf("unexpected primary range scan after delete %s", keys(entries))
+	}
+}
+
+func TestSecondaryScan(t *testing.T) {
+	_, b := newTestKeyspace(t, "mem:orders")
+	index := createIndex(t, b, "ix_total", "total")
+
+	entries := doScan(t, index, &datastore.Span{}, datastore.UNBOUNDED)
+	if keys(entries) != "o1 o2 o3" {
+		t.Errorf("expected documents without total not to be indexed, got %s", keys(entries))
+	}
+
+	if len(entries[0].EntryKey) != 1 || entries[0].EntryKey[0].Actual() != float64(10) {
+		t.Errorf("unexpected entry key %v", entries[0].EntryKey)
+	}
+
+	span := &datastore.Span{Seek: value.Values{value.NewValue(20)}}
+	entries = doScan(t, index, span, datastore.UNBOUNDED)
+	if keys(entries) != "o2" {
+		t.Errorf("unexpected seek %s", keys(entries))
+	}
+
+	span = &datastore.Span{Range: datastore.Range{
+		Low:       value.Values{value.NewValue(10)},
+		Inclusion: datastore.NEITHER,
+	}}
+	entries = doScan(t, index, span, datastore.UNBOUNDED)
+	if keys(entries) != "o2 o3" {
+		t.Errorf("unexpected range scan %s", keys(entries))
+	}
+
+	b.Update([]value.Pair{value.Pair{Name: "o3", Value: value.NewValue(map[string]interface{}{"total": 5})}}, datastore.NULL_QUERY_CONTEXT)
+	entries = doScan(t, index, &datastore.Span{}, datastore.UNBOUNDED)
+	if keys(entries) != "o3 o1 o2" {
+		t.Errorf("unexpected scan after update %s", keys(entries))
+	}
+
+	count, err := index.(datastore.CountIndex).Count(span, datastore.UNBOUNDED, nil)
+	if err != nil || count != 1 {
+		t.Errorf("expected count 1, got %d %v", count, err)
+	}
+}
+
+func TestArrayIndex(t *testing.T) {
+	_, b := newTestKeyspace(t, "mem:orders")
+	index := createIndex(t, b, "ix_items", "DISTINCT ARRAY i FOR i IN items END")
+
+	span := &datastore.Span{Seek: value.Values{value.NewValue("b")}}
+	entries := doScan(t, index, span, datastore.UNBOUNDED)
+	if keys(entries) != "o1 o2" {
+		t.Errorf("unexpected array index seek %s", keys(entries))
+	}
+
+	stats, err := index.Statistics("", &datastore.Span{})
+	if err != nil {
+		t.Fatalf("failed to get statistics: %v", err)
+	}
+
+	count, _ := stats.Count()
+	distinct, _ := stats.DistinctCount()
+	if count != 3 || distinct != 2 {
+		t.Errorf("expected 3 entries and 2 distinct keys, got %d and %d", count, distinct)
+	}
+}
+
+func TestStatisticsBins(t *testing.T) {
+	_, b := newTestKeyspace(t, "mem:orders")
+	index := createIndex(t, b, "ix_total", "total")
+
+	pairs := make([]value.Pair, 0, 200)
+	for i := 0; i < 200; i++ {
+		pairs = append(pairs, value.Pair{
+			Name:  fmt.Sprintf("x%03d", i),
+			Value: value.NewValue(map[string]interface{}{"total": 1000 + i}),
+		})
+	}
+	b.Insert(pairs, datastore.NULL_QUERY_CONTEXT)
+
+	stats, err := index.Statistics("", &datastore.Span{})
+	if err != nil {
+		t.Fatalf("failed to get statistics: %v", err)
+	}
+
+	bins, _ := stats.Bins()
+	if len(bins) == 0 || len(bins) > _STATISTICS_BINS {
+		t.Fatalf("unexpected number of bins %d", len(bins))
+	}
+
+	var total int64
+	var prev value.Values
+	for _, bin := range bins {
+		count, _ := bin.Count()
+		total += count
+
+		min, _ := bin.Min()
+		if prev != nil && compareKeys(min, prev) <= 0 {
+			t.Errorf("bin minimum %v is not above previous bin maximum %v", min, prev)
+		}
+		prev, _ = bin.Max()
+	}
+
+	count, _ := stats.Count()
+	if total != count {
+		t.Errorf("expected bins to hold %d entries, got %d", count, total)
+	}
+}
+
+func TestIndexDelay(t *testing.T) {
+	_, b := newTestKeyspace(t, "mem:orders,index_delay=1h")
+	index := createIndex(t, b, "ix_total", "total")
+
+	b.Insert([]value.Pair{value.Pair{Name: "o5", Value: value.NewValue(map[string]interface{}{"total": 50})}}, datastore.NULL_QUERY_CONTEXT)
+
+	entries := doScan(t, index, &datastore.Span{}, datastore.UNBOUNDED)
+	if keys(entries) != "o1 o2 o3" {
+		t.Errorf("expected unbounded scan not to include pending mutation, got %s", keys(entries))
+	}
+
+	entries = doScan(t, index, &datastore.Span{}, datastore.SCAN_PLUS)
+	if keys(entries) != "o1 o2 o3 o5" {
+		t.Errorf("expected request_plus scan to include pending mutation, got %s", keys(entries))
+	}
+
+	s, _ := NewStore("mem:orders,index_delay=1ms")
+	o, _ := s.namespaces["default"].KeyspaceByName("orders")
+	b = o.(*keyspace)
+	index = createIndex(t, b, "ix_total", "total")
+	b.Insert([]value.Pair{value.Pair{Name: "o1", Value: value.NewValue(map[string]interface{}{"total": 1})}}, datastore.NULL_QUERY_CONTEXT)
+	time.Sleep(10 * time.Millisecond)
+
+	entries = doScan(t, index, &datastore.Span{}, datastore.UNBOUNDED)
+	if keys(entries) != "o1" {
+		t.Errorf("expected mutation to be indexed after delay, got %s", keys(entries))
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s, b := newTestKeyspace(t, "mem:orders")
+	index := createIndex(t, b, "ix_items", "DISTINCT ARRAY i FOR i IN items END")
+	b.indexer.CreatePrimaryIndex("", "#primary", nil)
+
+	var buf bytes.Buffer
+	err := s.Snapshot().Write(&buf)
+	if err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+
+	snapshot, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %v", err)
+	}
+
+	b.Delete([]string{"o1", "o2"}, datastore.NULL_QUERY_CONTEXT)
+	s.CreateKeyspace("default", "customers")
+
+	err = s.Restore(snapshot)
+	if err != nil {
+		t.Fatalf("failed to restore snapshot: %v", err)
+	}
+
+	names, _ := s.namespaces["default"].KeyspaceNames()
+	if len(names) != 1 || names[0] != "orders" {
+		t.Errorf("expected only keyspace orders after restore, got %v", names)
+	}
+
+	span := &datastore.Span{Seek: value.Values{value.NewValue("b")}}
+	entries := doScan(t, index, span, datastore.UNBOUNDED)
+	if keys(entries) != "o1 o2" {
+		t.Errorf("expected restored index entries, got %s", keys(entries))
+	}
+
+	entries = doScan(t, b.indexer.primary, &datastore.Span{}, datastore.UNBOUNDED)
+	if keys(entries) != "o1 o2 o3 o4" {
+		t.Errorf("expected restored primary index entries, got %s", keys(entries))
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	s, b := newTestKeyspace(t, "mem:orders")
+
+	trigger := &datastore.Trigger{
+		Name:      "audit",
+		Events:    []string{datastore.TRIGGER_INSERT, datastore.TRIGGER_DELETE},
+		Statement: "INSERT INTO audit VALUES (UUID(), NEW)",
+		OnError:   datastore.TRIGGER_ON_ERROR_WARN,
+	}
+
+	err := b.CreateTrigger(trigger)
+	if err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+
+	err = b.CreateTrigger(trigger)
+	if err == nil || err.Code() != errors.NewTriggerAlreadyExistsError("").Code() {
+		t.Errorf("expected trigger exists error, got %v", err)
+	}
+
+	snapshot := s.Snapshot()
+
+	err = b.DropTrigger("audit")
+	if err != nil {
+		t.Fatalf("failed to drop trigger: %v", err)
+	}
+
+	err = b.DropTrigger("audit")
+	if err == nil || err.Code() != errors.NewTriggerNotFoundError("").Code() {
+		t.Errorf("expected trigger not found error, got %v", err)
+	}
+
+	triggers, _ := b.Triggers()
+	if len(triggers) != 0 {
+		t.Errorf("expected no triggers after drop, got %v", triggers)
+	}
+
+	err = s.Restore(snapshot)
+	if err != nil {
+		t.Fatalf("failed to restore snapshot: %v", err)
+	}
+
+	restored, _ := s.namespaces["default"].KeyspaceByName("orders")
+	triggers, _ = restored.(*keyspace).Triggers()
+	if len(triggers) != 1 || !triggers[0].HasEvent(datastore.TRIGGER_DELETE) ||
+		triggers[0].HasEvent(datastore.TRIGGER_UPDATE) {
+		t.Errorf("expected restored trigger, got %v", triggers)
+	}
+}
+
+func TestViews(t *testing.T) {
+	s, _ := newTestKeyspace(t, "mem:orders")
+	p := s.namespaces["default"]
+
+	view := &datastore.MaterializedView{
+		Name:      "totals",
+		Statement: "SELECT total FROM orders",
+		Refresh:   datastore.VIEW_REFRESH_MANUAL,
+	}
+
+	vks, err := p.CreateView(view)
+	if err != nil {
+		t.Fatalf("failed to create view: %v", err)
+	}
+
+	_, err = p.CreateView(view)
+	if err == nil || err.Code() != errors.NewViewAlreadyExistsError("").Code() {
+		t.Errorf("expected view exists error, got %v", err)
+	}
+
+	_, err = vks.Insert([]value.Pair{value.Pair{Name: "x", Value: value.NewValue(1)}}, datastore.NULL_QUERY_CONTEXT)
+	if err == nil || err.Code() != errors.NewViewReadOnlyError("").Code() {
+		t.Errorf("expected read-only error, got %v", err)
+	}
+
+	err = vks.ReplaceRows([]value.Pair{
+		value.Pair{Name: "1", Value: value.NewValue(map[string]interface{}{"total": 10})},
+		value.Pair{Name: "2", Value: value.NewValue(map[string]interface{}{"total": 20})},
+	})
+	if err == nil {
+		err = vks.ApplyRows([]value.Pair{
+			value.Pair{Name: "3", Value: value.NewValue(map[string]interface{}{"total": 30})},
+		}, []string{"1"})
+	}
+	if err != nil {
+		t.Fatalf("failed to write view rows: %v", err)
+	}
+
+	entries := doScan(t, vks.(*keyspace).indexer.primary, &datastore.Span{}, datastore.UNBOUNDED)
+	if keys(entries) != "2 3" {
+		t.Errorf("unexpected view rows %s", keys(entries))
+	}
+
+	snapshot := s.Snapshot()
+
+	err = p.DropView("orders")
+	if err == nil || err.Code() != errors.NewViewNotFoundError("").Code() {
+		t.Errorf("expected view not found error, got %v", err)
+	}
+
+	err = p.DropView("totals")
+	if err != nil {
+		t.Fatalf("failed to drop view: %v", err)
+	}
+
+	err = s.Restore(snapshot)
+	if err != nil {
+		t.Fatalf("failed to restore snapshot: %v", err)
+	}
+
+	restored, _ := p.KeyspaceByName("totals")
+	if restored == nil || restored.(*keyspace).View() == nil {
+		t.Fatalf("expected restored view, got %v", restored)
+	}
+
+	count, _ := restored.Count()
+	if count != 2 {
+		t.Errorf("expected 2 restored view rows, got %d", count)
+	}
+}
+
+func TestNamedViews(t *testing.T) {
+	s, _ := newTestKeyspace(t, "mem:orders")
+	p := s.namespaces["default"]
+
+	err := p.CreateNamedView(&datastore.NamedView{Name: "orders", Statement: "SELECT 1"})
+	if err == nil || err.Code() != errors.NewNamedViewAlreadyExistsError("").Code() {
+		t.Errorf("expected view exists error for a keyspace name, got %v", err)
+	}
+
+	err = p.CreateNamedView(&datastore.NamedView{Name: "large", Statement: "SELECT * FROM orders WHERE total > 15"})
+	if err != nil {
+		t.Fatalf("failed to create view: %v", err)
+	}
+
+	snapshot := s.Snapshot()
+
+	err = p.DropNamedView("large")
+	if err != nil {
+		t.Fatalf("failed to drop view: %v", err)
+	}
+
+	err = p.DropNamedView("large")
+	if err == nil || err.Code() != errors.NewNamedViewNotFoundError("").Code() {
+		t.Errorf("expected view not found error, got %v", err)
+	}
+
+	err = s.Restore(snapshot)
+	if err != nil {
+		t.Fatalf("failed to restore snapshot: %v", err)
+	}
+
+	views, _ := p.NamedViews()
+	if len(views) != 1 || views[0].Name != "large" {
+		t.Errorf("expected restored view, got %v", views)
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	s, b := newTestKeyspace(t, "mem:orders")
+
+	sch, er := schema.Compile(value.NewValue([]byte(`{"required": ["total"]}`)))
+	if er != nil {
+		t.Fatalf("failed to compile schema: %v", er)
+	}
+
+	b.SetSchema(sch)
+	snapshot := s.Snapshot()
+	b.SetSchema(nil)
+
+	err := s.Restore(snapshot)
+	if err != nil {
+		t.Fatalf("failed to restore snapshot: %v", err)
+	}
+
+	restored := s.namespaces["default"].keyspaces["orders"].Schema()
+	if restored == nil || len(restored.Validate(value.NewValue(map[string]interface{}{}))) != 1 {
+		t.Errorf("expected restored schema, got %v", restored)
+	}
+
+	snapshot.Namespaces["default"]["orders"].Schema = map[string]interface{}{"type": 1}
+	err = s.Restore(snapshot)
+	if err == nil || err.Code() != errors.NewSchemaError(nil, "").Code() {
+		t.Errorf("expected schema error, got %v", err)
+	}
+}
+
+func TestSequences(t *testing.T) {
+	s, _ := newTestKeyspace(t, "mem:orders")
+	p := s.namespaces["default"]
+
+	err := p.CreateSequence(&datastore.Sequence{Name: "ids", Start: 10, Increment: -2, Cache: 3})
+	if err != nil {
+		t.Fatalf("failed to create sequence: %v", err)
+	}
+
+	err = p.CreateSequence(&datastore.Sequence{Name: "ids", Start: 1, Increment: 1, Cache: 1})
+	if err == nil || err.Code() != errors.NewSequenceAlreadyExistsError("").Code() {
+		t.Errorf("expected sequence exists error, got %v", err)
+	}
+
+	for _, expected := range []int64{10, 8} {
+		next, err := p.NextSequenceValue("ids")
+		if err != nil || next != expected {
+			t.Errorf("expected next value %d, got %d, %v", expected, next, err)
+		}
+	}
+
+	// The position is read without handing out a value
+	for i := 0; i < 2; i++ {
+		position, err := p.SequencePosition("ids")
+		if err != nil || position != 6 {
+			t.Errorf("expected position 6, got %d, %v", position, err)
+		}
+	}
+
+	snapshot := s.Snapshot()
+
+	err = p.DropSequence("ids")
+	if err != nil {
+		t.Fatalf("failed to drop sequence: %v", err)
+	}
+
+	_, err = p.NextSequenceValue("ids")
+	if err == nil || err.Code() != errors.NewSequenceNotFoundError("").Code() {
+		t.Errorf("expected sequence not found error, got %v", err)
+	}
+
+	err = s.Restore(snapshot)
+	if err != nil {
+		t.Fatalf("failed to restore snapshot: %v", err)
+	}
+
+	next, err := p.NextSequenceValue("ids")
+	if err != nil || next != 6 {
+		t.Errorf("expected restored next value 6, got %d, %v", next, err)
+	}
+
+	// Batches are reserved before their first value is handed out
+	var reserved []int64
+	reserve := func(next int64) errors.Error {
+		reserved = append(reserved, next)
+		return nil
+	}
+
+	counter := datastore.NewSequenceCounter(&datastore.Sequence{Name: "batch", Start: 1, Increment: 1, Cache: 2}, 1)
+	for i := 0; i < 5; i++ {
+		counter.Next(reserve)
+	}
+	if len(reserved) != 3 || reserved[0] != 3 || reserved[1] != 5 || reserved[2] != 7 {
+		t.Errorf("expected reservations 3 5 7, got %v", reserved)
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	_, b := newTestKeyspace(t, "mem:orders")
+
+	_, err := b.indexer.CreatePrimaryIndex("", "#primary", nil)
+	if err != nil {
+		t.Fatalf("failed to create primary index: %v", err)
+	}
+
+	// A scan blocked on its consumer ends when the request stops
+	context := &stoppableContext{testingContext{t}, datastore.NewStoppableQueryContext()}
+	conn, _ := datastore.NewSizedIndexConnection(1, context)
+	done := make(chan bool)
+	go func() {
+		b.indexer.primary.ScanEntries("", 0, datastore.UNBOUNDED, nil, conn)
+		close(done)
+	}()
+
+	context.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("scan did not end when its request was stopped")
+	}
+
+	// Stopped operations do nothing
+	pairs, errs := b.Fetch([]string{"o1"}, context)
+	if len(pairs) != 0 || len(errs) != 1 || errs[0].Code() != 5260 {
+		t.Errorf("expected a stopped fetch, got %v and %v", pairs, errs)
+	}
+
+	inserted, err := b.Insert([]value.Pair{value.Pair{Name: "o9", Value: value.NewValue(9)}}, context)
+	if len(inserted) != 0 || err == nil || err.Code() != 5260 {
+		t.Errorf("expected a stopped insert, got %v and %v", inserted, err)
+	}
+
+	deleted, err := b.Delete([]string{"o1"}, context)
+	if len(deleted) != 0 || err == nil || err.Code() != 5260 {
+		t.Errorf("expected a stopped delete, got %v and %v", deleted, err)
+	}
+
+	if c, _ := b.Count(); c != 4 {
+		t.Errorf("expected 4 documents after stopped mutations, got %d", c)
+	}
+}
+
+type stoppableContext struct {
+	testingContext
+	*datastore.StoppableQueryContext
+}
+
+type testingContext struct {
+	t *testing.T
+}
+
+func (this *testingContext) Error(err errors.Error) {
+	this.t.Errorf("Scan error: %v", err)
+}
+
+func (this *testingContext) Warning(wrn errors.Error) {
+	this.t.Logf("scan warning: %v", wrn)
+}
+
+func (this *testingContext) Fatal(fatal errors.Error) {
+	this.t.Errorf("scan fatal: %v", fatal)
+}
+
+func doScan(t *testing.T, index datastore.Index, span *datastore.Span,
+	cons datastore.ScanConsistency) []*datastore.IndexEntry {
+	conn := datastore.NewIndexConnection(&testingContext{t})
+	go index.Scan("", span, false, 0, cons, nil, conn)
+
+	var rv []*datastore.IndexEntry
+	for entry := range conn.EntryChannel() {
+		rv = append(rv, entry)
+	}
+	return rv
+}
+
+func keys(entries []*datastore.IndexEntry) string {
+	var buf bytes.Buffer
+	for i, entry := range entries {
+		if i > 0 {
+			buf.WriteString(" ")
+		}
+		buf.WriteString(entry.PrimaryKey)
+	}
+	return buf.String()
+}
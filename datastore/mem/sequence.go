@@ -0,0 +1,101 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package mem
+
+import (
+	"sort"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+func (p *namespace) Sequences() ([]*datastore.Sequence, errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	counters := p.sortedSequences()
+	rv := make([]*datastore.Sequence, len(counters))
+	for i, counter := range counters {
+		rv[i] = counter.Sequence()
+	}
+
+	return rv, nil
+}
+
+func (p *namespace) sortedSequences() []*datastore.SequenceCounter {
+	names := make([]string, 0, len(p.sequences))
+	for name, _ := range p.sequences {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rv := make([]*datastore.SequenceCounter, len(names))
+	for i, name := range names {
+		rv[i] = p.sequences[name]
+	}
+
+	return rv
+}
+
+func (p *namespace) CreateSequence(sequence *datastore.Sequence) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.sequences[sequence.Name]; ok {
+		return errors.NewSequenceAlreadyExistsError(sequence.Name)
+	}
+
+	p.sequences[sequence.Name] = datastore.NewSequenceCounter(sequence, sequence.Start)
+	return nil
+}
+
+func (p *namespace) DropSequence(name string) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.sequences[name]; !ok {
+		return errors.NewSequenceNotFoundError(name)
+	}
+
+	delete(p.sequences, name)
+	return nil
+}
+
+/*
+NextSequenceValue does not reserve batches, since sequences are not
+persisted.
+*/
+func (p *namespace) NextSequenceValue(name string) (int64, errors.Error) {
+	p.RLock()
+	counter, ok := p.sequences[name]
+	p.RUnlock()
+
+	if !ok {
+		return 0, errors.NewSequenceNotFoundError(name)
+	}
+
+	return counter.Next(reserveNothing)
+}
+
+func (p *namespace) SequencePosition(name string) (int64, errors.Error) {
+	p.RLock()
+	counter, ok := p.sequences[name]
+	p.RUnlock()
+
+	if !ok {
+		return 0, errors.NewSequenceNotFoundError(name)
+	}
+
+	return counter.Position(), nil
+}
+
+func reserveNothing(next int64) errors.Error {
+	return nil
+}
@@ -0,0 +1,364 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package mem
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/expression/parser"
+	"github.com/couchbase/query/schema"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Snapshot is a copy of the documents, index definitions, triggers and schemas of a mem
+datastore, by namespace and keyspace, and of its named views and
+sequences by namespace. It can be restored between tests, and read from and
+written to JSON fixtures.
+*/
+type Snapshot struct {
+	Namespaces map[string]map[string]*KeyspaceSnapshot `json:"namespaces"`
+	Views      map[string][]*datastore.NamedView       `json:"views,omitempty"`
+	Sequences  map[string][]*SequenceSnapshot          `json:"sequences,omitempty"`
+}
+
+/*
+SequenceSnapshot is a sequence definition, with the next value of
+the sequence.
+*/
+type SequenceSnapshot struct {
+	datastore.Sequence
+	Next int64 `json:"next"`
+}
+
+type KeyspaceSnapshot struct {
+	Documents map[string]json.RawMessage  `json:"documents"`
+	Indexes   []*IndexDefinition          `json:"indexes,omitempty"`
+	Triggers  []*datastore.Trigger        `json:"triggers,omitempty"`
+	View      *datastore.MaterializedView `json:"view,omitempty"`
+	Schema    interface{}                 `json:"schema,omitempty"`
+}
+
+/*
+IndexDefinition is the definition of an index in a snapshot. Keys and
+Condition are N1QL expressions; array index keys are prefixed with
+ALL or DISTINCT, as in CREATE INDEX.
+*/
+type IndexDefinition struct {
+	Name      string   `json:"name"`
+	Primary   bool     `json:"primary,omitempty"`
+	Keys      []string `json:"keys,omitempty"`
+	Condition string   `json:"condition,omitempty"`
+	Deferred  bool     `json:"deferred,omitempty"`
+}
+
+func ReadSnapshot(r io.Reader) (*Snapshot, errors.Error) {
+	snapshot := &Snapshot{}
+	err := json.NewDecoder(r).Decode(snapshot)
+	if err != nil {
+		return nil, errors.NewOtherDatastoreError(err, "Invalid mem datastore snapshot.")
+	}
+	return snapshot, nil
+}
+
+func (this *Snapshot) Write(w io.Writer) errors.Error {
+	bytes, err := json.MarshalIndent(this, "", "    ")
+	if err == nil {
+		_, err = w.Write(append(bytes, '\n'))
+	}
+	if err != nil {
+		return errors.NewOtherDatastoreError(err, "Error writing mem datastore snapshot.")
+	}
+	return nil
+}
+
+/*
+Snapshot copies the documents, index definitions and triggers of the store.
+Pending mutations are included.
+*/
+func (s *Store) Snapshot() *Snapshot {
+	s.RLock()
+	defer s.RUnlock()
+
+	snapshot := &Snapshot{Namespaces: make(map[string]map[string]*KeyspaceSnapshot, len(s.namespaces))}
+	for pname, p := range s.namespaces {
+		p.RLock()
+		keyspaces := make(map[string]*KeyspaceSnapshot, len(p.keyspaces))
+		for name, b := range p.keyspaces {
+			keyspaces[name] = b.snapshot()
+		}
+		views := p.sortedViews()
+		counters := p.sortedSequences()
+		p.RUnlock()
+		snapshot.Namespaces[pname] = keyspaces
+		if len(views) > 0 {
+			if snapshot.Views == nil {
+				snapshot.Views = make(map[string][]*datastore.NamedView)
+			}
+			snapshot.Views[pname] = views
+		}
+		if len(counters) > 0 {
+			if snapshot.Sequences == nil {
+				snapshot.Sequences = make(map[string][]*SequenceSnapshot)
+			}
+			sequences := make([]*SequenceSnapshot, len(counters))
+			for i, counter := range counters {
+				sequences[i] = &SequenceSnapshot{*counter.Sequence(), counter.Position()}
+			}
+			snapshot.Sequences[pname] = sequences
+		}
+	}
+
+	return snapshot
+}
+
+/*
+Restore replaces the contents of the store with a snapshot. Existing
+keyspaces and indexes are reused, so that prepared statements remain
+valid; those missing from the snapshot are dropped.
+*/
+func (s *Store) Restore(snapshot *Snapshot) errors.Error {
+	schemas := make(map[*KeyspaceSnapshot]*schema.Schema)
+	for pname, keyspaces := range snapshot.Namespaces {
+		for name, ks := range keyspaces {
+			if ks == nil {
+				return errors.NewOtherDatastoreError(nil,
+					fmt.Sprintf("Missing snapshot of keyspace %s:%s.", pname, name))
+			}
+			for _, def := range ks.Indexes {
+				if _, err := def.parse(); err != nil {
+					return err
+				}
+			}
+			if ks.Schema != nil {
+				sch, err := schema.Compile(value.NewValue(ks.Schema))
+				if err != nil {
+					return errors.NewSchemaError(err, pname+":"+name)
+				}
+				schemas[ks] = sch
+			}
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for pname, _ := range s.namespaces {
+		if _, ok := snapshot.Namespaces[pname]; !ok && pname != "default" {
+			delete(s.namespaces, pname)
+		}
+	}
+
+	for pname, keyspaces := range snapshot.Namespaces {
+		p, ok := s.namespaces[pname]
+		if !ok {
+			p = newNamespace(s, pname)
+			s.namespaces[pname] = p
+		}
+
+		p.Lock()
+		for name, _ := range p.keyspaces {
+			if _, ok := keyspaces[name]; !ok {
+				delete(p.keyspaces, name)
+			}
+		}
+
+		for name, ks := range keyspaces {
+			b, ok := p.keyspaces[name]
+			if !ok {
+				b = newKeyspace(p, name)
+				p.keyspaces[name] = b
+			}
+
+			defs := make([]*indexDefinition, len(ks.Indexes))
+			for i, def := range ks.Indexes {
+				defs[i], _ = def.parse()
+			}
+
+			b.restore(ks, defs, schemas[ks])
+		}
+		p.Unlock()
+	}
+
+	if s.namespaces["default"] == nil {
+		s.namespaces["default"] = newNamespace(s, "default")
+	} else if _, ok := snapshot.Namespaces["default"]; !ok {
+		p := s.namespaces["default"]
+		p.Lock()
+		p.keyspaces = make(map[string]*keyspace)
+		p.Unlock()
+	}
+
+	for _, p := range s.namespaces {
+		p.Lock()
+		p.views = make(map[string]*datastore.NamedView)
+		for _, view := range snapshot.Views[p.name] {
+			p.views[view.Name] = view
+		}
+		p.sequences = make(map[string]*datastore.SequenceCounter)
+		for _, sequence := range snapshot.Sequences[p.name] {
+			definition := sequence.Sequence
+			p.sequences[sequence.Name] = datastore.NewSequenceCounter(&definition, sequence.Next)
+		}
+		p.Unlock()
+	}
+
+	return nil
+}
+
+func (b *keyspace) snapshot() *KeyspaceSnapshot {
+	b.RLock()
+	defer b.RUnlock()
+
+	rv := &KeyspaceSnapshot{Documents: make(map[string]json.RawMessage, len(b.docs))}
+	for key, doc := range b.docs {
+		rv.Documents[key] = json.RawMessage(doc)
+	}
+
+	names, _ := b.indexer.IndexNames()
+	for _, name := range names {
+		index := b.indexer.indexes[name]
+		def := &IndexDefinition{
+			Name:     index.name,
+			Primary:  index.primary,
+			Deferred: index.state == datastore.DEFERRED,
+		}
+		for _, key := range index.keys {
+			def.Keys = append(def.Keys, keyString(key))
+		}
+		if index.where != nil {
+			def.Condition = index.where.String()
+		}
+		rv.Indexes = append(rv.Indexes, def)
+	}
+
+	rv.Triggers = b.sortedTriggers()
+	rv.View = b.view
+	if b.schema != nil {
+		rv.Schema = b.schema.Source()
+	}
+	return rv
+}
+
+/*
+restore replaces the documents, indexes, triggers, view definition and schema of a keyspace.
+Indexes with the name of an existing index replace its definition.
+*/
+func (b *keyspace) restore(ks *KeyspaceSnapshot, defs []*indexDefinition, sch *schema.Schema) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.docs = make(map[string][]byte, len(ks.Documents))
+	for key, doc := range ks.Documents {
+		b.docs[key] = []byte(doc)
+	}
+	b.pending = nil
+	b.seqno++
+	b.indexed = b.seqno
+
+	b.triggers = make(map[string]*datastore.Trigger, len(ks.Triggers))
+	for _, trigger := range ks.Triggers {
+		b.triggers[trigger.Name] = trigger
+	}
+	b.view = ks.View
+	b.schema = sch
+
+	mi := b.indexer
+	indexes := make(map[string]*index, len(defs))
+	mi.primary = nil
+	for _, def := range defs {
+		idx, ok := mi.indexes[def.name]
+		if !ok {
+			idx = &index{indexer: mi, name: def.name}
+		}
+
+		idx.primary = def.primary
+		idx.keys = def.keys
+		idx.where = def.where
+		idx.root = nil
+		idx.entries = nil
+		idx.state = datastore.DEFERRED
+		if !def.deferred {
+			mi.build(idx)
+		}
+
+		indexes[def.name] = idx
+		if idx.primary {
+			mi.primary = idx
+		}
+	}
+	mi.indexes = indexes
+}
+
+// indexDefinition is a parsed IndexDefinition.
+type indexDefinition struct {
+	name     string
+	primary  bool
+	keys     expression.Expressions
+	where    expression.Expression
+	deferred bool
+}
+
+func (this *IndexDefinition) parse() (*indexDefinition, errors.Error) {
+	rv := &indexDefinition{
+		name:     this.Name,
+		primary:  this.Primary,
+		deferred: this.Deferred,
+	}
+
+	if this.Name == "" {
+		return nil, errors.NewOtherDatastoreError(nil, "Missing index name in mem datastore snapshot.")
+	}
+
+	if this.Primary != (len(this.Keys) == 0) {
+		return nil, errors.NewOtherDatastoreError(nil,
+			fmt.Sprintf("Index %s must be primary or have keys.", this.Name))
+	}
+
+	for _, key := range this.Keys {
+		expr, err := parser.Parse(key)
+		if err != nil {
+			return nil, errors.NewOtherDatastoreError(err,
+				fmt.Sprintf("Invalid key %s of index %s.", key, this.Name))
+		}
+		rv.keys = append(rv.keys, expr)
+	}
+
+	if this.Condition != "" {
+		expr, err := parser.Parse(this.Condition)
+		if err != nil {
+			return nil, errors.NewOtherDatastoreError(err,
+				fmt.Sprintf("Invalid condition of index %s.", this.Name))
+		}
+		rv.where = expr
+	}
+
+	return rv, nil
+}
+
+/*
+keyString returns the text of an index key, which can be parsed back.
+*/
+func keyString(key expression.Expression) string {
+	all, ok := key.(*expression.All)
+	if !ok {
+		return key.String()
+	}
+
+	if all.Distinct() {
+		return "DISTINCT " + all.Array().String()
+	}
+	return "ALL " + all.Array().String()
+}
@@ -0,0 +1,189 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package mem
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/couchbase/query/value"
+)
+
+/*
+indexEntry is an entry of an index tree: the values of the index
+keys of a document, and its primary key. Entries are ordered by key
+values, then by primary key.
+*/
+type indexEntry struct {
+	key value.Values
+	pk  string
+}
+
+func compareEntries(a, b *indexEntry) int {
+	c := compareKeys(a.key, b.key)
+	if c != 0 {
+		return c
+	}
+
+	switch {
+	case a.pk < b.pk:
+		return -1
+	case a.pk > b.pk:
+		return 1
+	}
+	return 0
+}
+
+/*
+compareKeys collates the leading values of key with bound, which
+may be shorter than key.
+*/
+func compareKeys(key, bound value.Values) int {
+	for i, b := range bound {
+		if i >= len(key) {
+			return -1
+		}
+
+		c := key[i].Collate(b)
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+/*
+A tree of treeNodes is an ordered set of index entries, implemented
+as a treap. Trees are persistent: insert and remove copy the nodes on the path
+they change and return a new root, so a scan can iterate over a root
+without holding a lock while the index is updated.
+*/
+type treeNode struct {
+	entry    *indexEntry
+	priority int32
+	left     *treeNode
+	right    *treeNode
+}
+
+var priorityLock sync.Mutex
+var priorities = rand.New(rand.NewSource(1))
+
+func newPriority() int32 {
+	priorityLock.Lock()
+	defer priorityLock.Unlock()
+	return priorities.Int31()
+}
+
+func treeInsert(n *treeNode, entry *indexEntry) *treeNode {
+	if n == nil {
+		return &treeNode{entry: entry, priority: newPriority()}
+	}
+
+	m := *n
+	c := compareEntries(entry, n.entry)
+	switch {
+	case c < 0:
+		m.left = treeInsert(n.left, entry)
+		if m.left.priority > m.priority {
+			return rotateRight(&m)
+		}
+	case c > 0:
+		m.right = treeInsert(n.right, entry)
+		if m.right.priority > m.priority {
+			return rotateLeft(&m)
+		}
+	default:
+		m.entry = entry
+	}
+
+	return &m
+}
+
+// The children rotated up are new copies, and may be modified.
+func rotateRight(n *treeNode) *treeNode {
+	l := n.left
+	n.left = l.right
+	l.right = n
+	return l
+}
+
+func rotateLeft(n *treeNode) *treeNode {
+	r := n.right
+	n.right = r.left
+	r.left = n
+	return r
+}
+
+func treeRemove(n *treeNode, entry *indexEntry) *treeNode {
+	if n == nil {
+		return nil
+	}
+
+	c := compareEntries(entry, n.entry)
+	switch {
+	case c < 0:
+		left := treeRemove(n.left, entry)
+		if left == n.left {
+			return n
+		}
+		m := *n
+		m.left = left
+		return &m
+	case c > 0:
+		right := treeRemove(n.right, entry)
+		if right == n.right {
+			return n
+		}
+		m := *n
+		m.right = right
+		return &m
+	}
+
+	return treeMerge(n.left, n.right)
+}
+
+func treeMerge(a, b *treeNode) *treeNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	if a.priority > b.priority {
+		m := *a
+		m.right = treeMerge(a.right, b)
+		return &m
+	}
+
+	m := *b
+	m.left = treeMerge(a, b.left)
+	return &m
+}
+
+/*
+treeAscend calls fn for the entries of the tree in order, starting
+with the first entry for which start returns true, until fn returns
+false. start must be false for a prefix of the entries and true for
+the rest. It returns false if iteration was stopped by fn.
+*/
+func treeAscend(n *treeNode, start func(*indexEntry) bool, fn func(*indexEntry) bool) bool {
+	if n == nil {
+		return true
+	}
+
+	if start(n.entry) {
+		if !treeAscend(n.left, start, fn) || !fn(n.entry) {
+			return false
+		}
+	}
+
+	return treeAscend(n.right, start, fn)
+}
@@ -0,0 +1,62 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package mem
+
+import (
+	"sort"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+func (b *keyspace) Triggers() ([]*datastore.Trigger, errors.Error) {
+	b.RLock()
+	defer b.RUnlock()
+	return b.sortedTriggers(), nil
+}
+
+func (b *keyspace) sortedTriggers() []*datastore.Trigger {
+	names := make([]string, 0, len(b.triggers))
+	for name, _ := range b.triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rv := make([]*datastore.Trigger, len(names))
+	for i, name := range names {
+		rv[i] = b.triggers[name]
+	}
+
+	return rv
+}
+
+func (b *keyspace) CreateTrigger(trigger *datastore.Trigger) errors.Error {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.triggers[trigger.Name]; ok {
+		return errors.NewTriggerAlreadyExistsError(trigger.Name)
+	}
+
+	b.triggers[trigger.Name] = trigger
+	return nil
+}
+
+func (b *keyspace) DropTrigger(name string) errors.Error {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.triggers[name]; !ok {
+		return errors.NewTriggerNotFoundError(name)
+	}
+
+	delete(b.triggers, name)
+	return nil
+}
@@ -0,0 +1,152 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package mem
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+/*
+CreateView creates the keyspace of a materialized view, with a
+primary index so that the view can be queried.
+*/
+func (p *namespace) CreateView(view *datastore.MaterializedView) (datastore.ViewKeyspace, errors.Error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.nameTaken(view.Name) {
+		return nil, errors.NewViewAlreadyExistsError(view.Name)
+	}
+
+	b := newKeyspace(p, view.Name)
+	b.view = view
+	b.indexer.primary, _ = b.indexer.create("#primary", nil, nil, nil)
+	p.keyspaces[view.Name] = b
+	return b, nil
+}
+
+func (p *namespace) DropView(name string) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	b, ok := p.keyspaces[name]
+	if !ok || b.view == nil {
+		return errors.NewViewNotFoundError(name)
+	}
+
+	delete(p.keyspaces, name)
+	return nil
+}
+
+func (b *keyspace) View() *datastore.MaterializedView {
+	return b.view
+}
+
+func (b *keyspace) ReplaceRows(rows []value.Pair) errors.Error {
+	b.Lock()
+	defer b.Unlock()
+
+	keys := make([]string, 0, len(b.docs))
+	for key, _ := range b.docs {
+		keys = append(keys, key)
+	}
+	b.delete(keys, datastore.NULL_QUERY_CONTEXT)
+	return b.write(rows)
+}
+
+func (b *keyspace) ApplyRows(upserts []value.Pair, deletes []string) errors.Error {
+	b.Lock()
+	defer b.Unlock()
+
+	b.delete(deletes, datastore.NULL_QUERY_CONTEXT)
+	return b.write(upserts)
+}
+
+// write upserts the rows of a view. The keyspace must be locked.
+func (b *keyspace) write(rows []value.Pair) errors.Error {
+	for _, row := range rows {
+		bytes, err := row.Value.MarshalJSON()
+		if err != nil {
+			return errors.NewOtherDatastoreError(err,
+				fmt.Sprintf("Error writing row %s of view %s.", row.Name, b.name))
+		}
+
+		b.docs[row.Name] = bytes
+		b.mutate(row.Name, bytes)
+	}
+
+	return nil
+}
+
+func (p *namespace) NamedViews() ([]*datastore.NamedView, errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.sortedViews(), nil
+}
+
+func (p *namespace) sortedViews() []*datastore.NamedView {
+	names := make([]string, 0, len(p.views))
+	for name, _ := range p.views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rv := make([]*datastore.NamedView, len(names))
+	for i, name := range names {
+		rv[i] = p.views[name]
+	}
+
+	return rv
+}
+
+func (p *namespace) NamedViewByName(name string) (*datastore.NamedView, errors.Error) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.views[name], nil
+}
+
+func (p *namespace) CreateNamedView(view *datastore.NamedView) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.nameTaken(view.Name) {
+		return errors.NewNamedViewAlreadyExistsError(view.Name)
+	}
+
+	p.views[view.Name] = view
+	return nil
+}
+
+func (p *namespace) DropNamedView(name string) errors.Error {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.views[name]; !ok {
+		return errors.NewNamedViewNotFoundError(name)
+	}
+
+	delete(p.views, name)
+	return nil
+}
+
+/*
+nameTaken returns true if name is the name of a keyspace or named view.
+The namespace must be locked.
+*/
+func (p *namespace) nameTaken(name string) bool {
+	_, keyspace := p.keyspaces[name]
+	_, view := p.views[name]
+	return keyspace || view
+}
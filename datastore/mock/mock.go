@@ -136,6 +136,7 @@ type keyspace struct {
 	name      string
 	nitems    int
 	mi        datastore.Indexer
+	seqnos    *datastore.Seqnos
 }
 
 func (b *keyspace) NamespaceId() string {
@@ -162,10 +163,15 @@ func (b *keyspace) Indexers() ([]datastore.Indexer, errors.Error) {
 	return []datastore.Indexer{b.mi}, nil
 }
 
-func (b *keyspace) Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) {
+func (b *keyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
 	var errs []errors.Error
 	rv := make([]value.AnnotatedPair, 0, len(keys))
 	for _, k := range keys {
+		if err := context.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
 		item, e := b.fetchOne(k)
 		if e != nil {
 			if errs == nil {
@@ -210,22 +216,22 @@ func genItem(i int, nitems int) (value.AnnotatedValue, errors.Error) {
 	return doc, nil
 }
 
-func (b *keyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *keyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewOtherNotImplementedError(nil, "for Mock datastore")
 }
 
-func (b *keyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+func (b *keyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewOtherNotImplementedError(nil, "for Mock datastore")
 }
 
-func (b *keyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *keyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewOtherNotImplementedError(nil, "for Mock datastore")
 }
 
-func (b *keyspace) Delete(deletes []string) ([]string, errors.Error) {
+func (b *keyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
 	// FIXME
 	return nil, errors.NewOtherNotImplementedError(nil, "for Mock datastore")
 }
@@ -233,6 +239,10 @@ func (b *keyspace) Delete(deletes []string) ([]string, errors.Error) {
 func (b *keyspace) Release() {
 }
 
+func (b *keyspace) MutationToken() timestamp.Entry {
+	return b.seqnos.Token()
+}
+
 type mockIndexer struct {
 	keyspace *keyspace
 	indexes  map[string]datastore.Index
@@ -353,7 +363,8 @@ func NewDatastore(path string) (datastore.Datastore, errors.Error) {
 	for i := 0; i < nnamespaces; i++ {
 		p := &namespace{store: s, name: "p" + strconv.Itoa(i), keyspaces: map[string]*keyspace{}, keyspaceNames: []string{}}
 		for j := 0; j < nkeyspaces; j++ {
-			b := &keyspace{namespace: p, name: "b" + strconv.Itoa(j), nitems: nitems}
+			b := &keyspace{namespace: p, name: "b" + strconv.Itoa(j), nitems: nitems,
+				seqnos: datastore.NewSeqnos()}
 
 			b.mi = newMockIndexer(b)
 			b.mi.CreatePrimaryIndex("", "#primary", nil)
@@ -429,6 +440,10 @@ func (pi *primaryIndex) Scan(requestId string, span *datastore.Span, distinct bo
 	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
 	defer close(conn.EntryChannel())
 
+	if !pi.keyspace.seqnos.Wait(cons, vector, conn) {
+		return
+	}
+
 	// For primary indexes, bounds must always be strings, so we
 	// can just enforce that directly
 	low, high := "", ""
@@ -479,7 +494,9 @@ func (pi *primaryIndex) Scan(requestId string, span *datastore.Span, distinct bo
 		}
 
 		entry := datastore.IndexEntry{PrimaryKey: id}
-		conn.EntryChannel() <- &entry
+		if !conn.Send(&entry) {
+			return
+		}
 	}
 }
 
@@ -487,12 +504,18 @@ func (pi *primaryIndex) ScanEntries(requestId string, limit int64, cons datastor
 	vector timestamp.Vector, conn *datastore.IndexConnection) {
 	defer close(conn.EntryChannel())
 
+	if !pi.keyspace.seqnos.Wait(cons, vector, conn) {
+		return
+	}
+
 	if limit == 0 {
 		limit = int64(pi.keyspace.nitems)
 	}
 
 	for i := 0; i < pi.keyspace.nitems && int64(i) < limit; i++ {
 		entry := datastore.IndexEntry{PrimaryKey: strconv.Itoa(i)}
-		conn.EntryChannel() <- &entry
+		if !conn.Send(&entry) {
+			return
+		}
 	}
 }
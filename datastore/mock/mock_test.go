@@ -12,9 +12,11 @@ package mock
 import (
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/timestamp"
 	"github.com/couchbase/query/value"
 )
 
@@ -109,7 +111,7 @@ func TestMock(t *testing.T) {
 	}
 
 	f := []string{"123"}
-	vs, errs := b.Fetch(f)
+	vs, errs := b.Fetch(f, datastore.NULL_QUERY_CONTEXT)
 	if errs != nil || len(vs) == 0 {
 		t.Fatalf("expected item 123")
 	}
@@ -130,12 +132,12 @@ func TestMock(t *testing.T) {
 		t.Fatalf("expected not-a-valid-path to err")
 	}
 
-	vs, errs = b.Fetch([]string{"not-an-item"})
+	vs, errs = b.Fetch([]string{"not-an-item"}, datastore.NULL_QUERY_CONTEXT)
 	if errs == nil || len(vs) > 0 {
 		t.Fatalf("expected not-an-item")
 	}
 
-	vs, errs = b.Fetch([]string{strconv.Itoa(DEFAULT_NUM_ITEMS)})
+	vs, errs = b.Fetch([]string{strconv.Itoa(DEFAULT_NUM_ITEMS)}, datastore.NULL_QUERY_CONTEXT)
 	if errs == nil || len(vs) > 0 {
 		t.Fatalf("expected not-an-item")
 	}
@@ -193,6 +195,129 @@ func TestMockIndex(t *testing.T) {
 	items, err = doIndexScan(t, b, span)
 }
 
+func TestMockScanConsistency(t *testing.T) {
+	s, err := NewDatastore("mock:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	p, _ := s.NamespaceById("p0")
+	b, _ := p.KeyspaceById("b0")
+	indexers, _ := b.Indexers()
+	pindexes, _ := indexers[0].PrimaryIndexes()
+	idx := pindexes[0]
+	token := b.(datastore.Sequenced).MutationToken()
+
+	context := &waitingContext{testingContext{t}, 20 * time.Millisecond, nil}
+	scan := func(cons datastore.ScanConsistency, seqno uint64) int {
+		vector := testVector{&testEntry{seqno, token.Guard()}}
+		conn := datastore.NewIndexConnection(context)
+		go idx.ScanEntries("", 10, cons, vector, conn)
+
+		n := 0
+		for _ = range conn.EntryChannel() {
+			n++
+		}
+		return n
+	}
+
+	if n := scan(datastore.SCAN_PLUS, 0); n != 10 {
+		t.Errorf("expected 10 items with scan_plus, got %d", n)
+	}
+
+	if n := scan(datastore.AT_PLUS, token.Value()); n != 10 {
+		t.Errorf("expected 10 items at the token, got %d", n)
+	}
+
+	// Mock keyspaces are never mutated
+	if n := scan(datastore.AT_PLUS, token.Value()+1); n != 0 || len(context.errs) != 1 {
+		t.Errorf("expected a scan wait timeout, got %d items and errors %v", n, context.errs)
+	}
+}
+
+func TestMockCancellation(t *testing.T) {
+	s, err := NewDatastore("mock:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	p, _ := s.NamespaceById("p0")
+	b, _ := p.KeyspaceById("b0")
+	indexers, _ := b.Indexers()
+	pindexes, _ := indexers[0].PrimaryIndexes()
+	idx := pindexes[0]
+
+	// Scans end when their consumer or their request stops
+	stops := []func(context *stoppableContext, conn *datastore.IndexConnection){
+		func(context *stoppableContext, conn *datastore.IndexConnection) {
+			conn.StopChannel() <- false
+		},
+		func(context *stoppableContext, conn *datastore.IndexConnection) {
+			context.Stop()
+		},
+	}
+
+	for i, stop := range stops {
+		context := newStoppableContext(t)
+		conn, _ := datastore.NewSizedIndexConnection(1, context)
+		done := make(chan bool)
+		go func() {
+			idx.ScanEntries("", 0, datastore.UNBOUNDED, nil, conn)
+			close(done)
+		}()
+
+		stop(context, conn)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("scan %d did not end when stopped", i)
+		}
+	}
+
+	context := newStoppableContext(t)
+	context.Stop()
+	vs, errs := b.Fetch([]string{"1", "2"}, context)
+	if len(vs) != 0 || len(errs) != 1 || errs[0].Code() != 5260 {
+		t.Errorf("expected a stopped fetch, got %v and %v", vs, errs)
+	}
+}
+
+type stoppableContext struct {
+	waitingContext
+	*datastore.StoppableQueryContext
+}
+
+func newStoppableContext(t *testing.T) *stoppableContext {
+	return &stoppableContext{waitingContext{testingContext{t}, time.Minute, nil}, datastore.NewStoppableQueryContext()}
+}
+
+type waitingContext struct {
+	testingContext
+	wait time.Duration
+	errs []errors.Error
+}
+
+func (this *waitingContext) Error(err errors.Error) {
+	this.errs = append(this.errs, err)
+}
+
+func (this *waitingContext) ScanWait() time.Duration {
+	return this.wait
+}
+
+type testEntry struct {
+	seqno uint64
+	guard string
+}
+
+func (this *testEntry) Position() uint32 { return 0 }
+func (this *testEntry) Guard() string    { return this.guard }
+func (this *testEntry) Value() uint64    { return this.seqno }
+
+type testVector []timestamp.Entry
+
+func (this testVector) Entries() []timestamp.Entry { return this }
+
 type testingContext struct {
 	t *testing.T
 }
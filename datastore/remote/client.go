@@ -0,0 +1,201 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+/*
+client runs statements on the query service of a remote engine.
+*/
+type client struct {
+	url      string // Query service endpoint
+	username string
+	password string
+	http     *http.Client
+}
+
+func newClient(base string) (*client, errors.Error) {
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.NewOtherDatastoreError(err, "Invalid remote engine url "+base)
+	}
+
+	c := &client{http: &http.Client{}}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+		u.User = nil
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/query/service"
+	}
+	c.url = u.String()
+	return c, nil
+}
+
+/*
+query runs a statement with positional arguments, and calls fn for
+each result until fn returns false. Results are decoded as they are
+received, so that large results are not held in memory.
+*/
+func (this *client) query(statement string, args []interface{}, cons datastore.ScanConsistency,
+	fn func(json.RawMessage) bool) errors.Error {
+	form := url.Values{}
+	form.Set("statement", statement)
+	if len(args) > 0 {
+		bytes, err := json.Marshal(args)
+		if err != nil {
+			return errors.NewOtherDatastoreError(err, "Invalid arguments for remote engine")
+		}
+		form.Set("args", string(bytes))
+	}
+
+	if cons == datastore.SCAN_PLUS || cons == datastore.AT_PLUS {
+		// Vectors of the local engine do not apply to the remote one
+		form.Set("scan_consistency", "request_plus")
+	}
+
+	req, err := http.NewRequest("POST", this.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.NewOtherDatastoreError(err, "Error creating remote engine request")
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if this.username != "" {
+		req.SetBasicAuth(this.username, this.password)
+	}
+
+	resp, err := this.http.Do(req)
+	if err != nil {
+		return errors.NewOtherDatastoreError(err, "Error connecting to remote engine "+this.url)
+	}
+	defer resp.Body.Close()
+
+	return decodeResponse(resp.Body, fn)
+}
+
+/*
+queryValues runs a statement and returns its results.
+*/
+func (this *client) queryValues(statement string, args ...interface{}) ([]json.RawMessage, errors.Error) {
+	var rv []json.RawMessage
+	err := this.query(statement, args, datastore.UNBOUNDED, func(result json.RawMessage) bool {
+		rv = append(rv, result)
+		return true
+	})
+	return rv, err
+}
+
+/*
+queryStrings runs a statement whose results are strings.
+*/
+func (this *client) queryStrings(statement string, args ...interface{}) ([]string, errors.Error) {
+	var rv []string
+	var er error
+	err := this.query(statement, args, datastore.UNBOUNDED, func(result json.RawMessage) bool {
+		var s string
+		er = json.Unmarshal(result, &s)
+		rv = append(rv, s)
+		return er == nil
+	})
+	if err == nil && er != nil {
+		err = errors.NewOtherDatastoreError(er, "Unexpected result from remote engine")
+	}
+	return rv, err
+}
+
+type remoteError struct {
+	Code int32  `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+/*
+decodeResponse reads a query service response, and returns its first
+error after the results.
+*/
+func decodeResponse(r io.Reader, fn func(json.RawMessage) bool) errors.Error {
+	decoder := json.NewDecoder(r)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+
+	var remoteErrors []remoteError
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return errors.NewOtherDatastoreError(err, "Invalid response from remote engine")
+		}
+
+		switch tok {
+		case "results":
+			if err := expectDelim(decoder, '['); err != nil {
+				return err
+			}
+
+			for decoder.More() {
+				var result json.RawMessage
+				if err := decoder.Decode(&result); err != nil {
+					return errors.NewOtherDatastoreError(err, "Invalid response from remote engine")
+				}
+				if !fn(result) {
+					return nil
+				}
+			}
+
+			if err := expectDelim(decoder, ']'); err != nil {
+				return err
+			}
+		case "errors":
+			if err := decoder.Decode(&remoteErrors); err != nil {
+				return errors.NewOtherDatastoreError(err, "Invalid response from remote engine")
+			}
+		default:
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return errors.NewOtherDatastoreError(err, "Invalid response from remote engine")
+			}
+		}
+	}
+
+	if len(remoteErrors) > 0 {
+		e := remoteErrors[0]
+		return errors.NewOtherDatastoreError(nil, fmt.Sprintf("Remote engine error %d: %s", e.Code, e.Msg))
+	}
+	return nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) errors.Error {
+	tok, err := decoder.Token()
+	if err == nil && tok != delim {
+		err = fmt.Errorf("expected %v, found %v", delim, tok)
+	}
+	if err != nil {
+		return errors.NewOtherDatastoreError(err, "Invalid response from remote engine")
+	}
+	return nil
+}
+
+/*
+identifier escapes a name for a statement.
+*/
+func identifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
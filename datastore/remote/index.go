@@ -0,0 +1,579 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/expression/parser"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+/*
+indexer lists and manages the remote indexes of a keyspace of one
+type. Indexes are listed from system:indexes of the remote engine on
+each call, so that they reflect DDL run on either engine.
+*/
+type indexer struct {
+	keyspace *keyspace
+	using    datastore.IndexType
+}
+
+func newIndexer(keyspace *keyspace, using datastore.IndexType) *indexer {
+	return &indexer{
+		keyspace: keyspace,
+		using:    using,
+	}
+}
+
+func (mi *indexer) KeyspaceId() string {
+	return mi.keyspace.Id()
+}
+
+func (mi *indexer) Name() datastore.IndexType {
+	return mi.using
+}
+
+// indexInfo is an entry of system:indexes.
+type indexInfo struct {
+	Name      string   `json:"name"`
+	IndexKey  []string `json:"index_key"`
+	Condition string   `json:"condition"`
+	IsPrimary bool     `json:"is_primary"`
+	State     string   `json:"state"`
+}
+
+/*
+load lists the remote indexes. Array indexes are not supported, since
+their spans cannot be expressed as predicates.
+*/
+func (mi *indexer) load() ([]*index, errors.Error) {
+	b := mi.keyspace
+	results, err := b.client().queryValues("SELECT RAW i FROM system:indexes AS i"+
+		" WHERE i.namespace_id = $1 AND i.keyspace_id = $2 AND i.`using` = $3 ORDER BY i.name",
+		b.namespace.name, b.name, string(mi.using))
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]*index, 0, len(results))
+	for _, result := range results {
+		var info indexInfo
+		if er := json.Unmarshal(result, &info); er != nil {
+			return nil, errors.NewOtherDatastoreError(er, "Unexpected result from remote engine")
+		}
+
+		idx, er := mi.newIndex(&info)
+		if er != nil {
+			logging.Debugf("Ignoring remote index %s: %v", info.Name, er)
+			continue
+		}
+		rv = append(rv, idx)
+	}
+
+	return rv, nil
+}
+
+func (mi *indexer) newIndex(info *indexInfo) (*index, error) {
+	idx := &index{
+		indexer: mi,
+		name:    info.Name,
+		primary: info.IsPrimary,
+		state:   datastore.IndexState(info.State),
+	}
+
+	for _, key := range info.IndexKey {
+		expr, err := parser.Parse(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if isArray, _ := expr.IsArrayIndexKey(); isArray {
+			return nil, fmt.Errorf("array index key %s", key)
+		}
+		idx.keys = append(idx.keys, expr)
+	}
+
+	if !idx.primary && len(idx.keys) == 0 {
+		return nil, fmt.Errorf("no index keys")
+	}
+
+	if info.Condition != "" {
+		cond, err := parser.Parse(info.Condition)
+		if err != nil {
+			return nil, err
+		}
+		idx.condition = cond
+	}
+
+	return idx, nil
+}
+
+func (mi *indexer) IndexIds() ([]string, errors.Error) {
+	return mi.IndexNames()
+}
+
+func (mi *indexer) IndexNames() ([]string, errors.Error) {
+	indexes, err := mi.load()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]string, len(indexes))
+	for i, idx := range indexes {
+		rv[i] = idx.name
+	}
+	return rv, nil
+}
+
+func (mi *indexer) IndexById(id string) (datastore.Index, errors.Error) {
+	return mi.IndexByName(id)
+}
+
+func (mi *indexer) IndexByName(name string) (datastore.Index, errors.Error) {
+	indexes, err := mi.load()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, idx := range indexes {
+		if idx.name == name {
+			return idx, nil
+		}
+	}
+	return nil, errors.NewOtherIdxNotFoundError(nil, name+" for remote datastore")
+}
+
+func (mi *indexer) PrimaryIndexes() ([]datastore.PrimaryIndex, errors.Error) {
+	indexes, err := mi.load()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]datastore.PrimaryIndex, 0, 1)
+	for _, idx := range indexes {
+		if idx.primary {
+			rv = append(rv, idx)
+		}
+	}
+	return rv, nil
+}
+
+func (mi *indexer) Indexes() ([]datastore.Index, errors.Error) {
+	indexes, err := mi.load()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]datastore.Index, len(indexes))
+	for i, idx := range indexes {
+		rv[i] = idx
+	}
+	return rv, nil
+}
+
+func (mi *indexer) CreatePrimaryIndex(requestId, name string, with value.Value) (
+	datastore.PrimaryIndex, errors.Error) {
+	statement := fmt.Sprintf("CREATE PRIMARY INDEX %s ON %s %s%s",
+		identifier(name), mi.keyspace.ref, mi.usingClause(), withClause(with))
+	_, err := mi.keyspace.client().queryValues(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := mi.IndexByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return idx.(*index), nil
+}
+
+func (mi *indexer) CreateIndex(requestId, name string, seekKey, rangeKey expression.Expressions,
+	where expression.Expression, with value.Value) (datastore.Index, errors.Error) {
+	keys := make([]string, len(rangeKey))
+	for i, key := range rangeKey {
+		keys[i] = keyString(key)
+	}
+
+	statement := fmt.Sprintf("CREATE INDEX %s ON %s(%s)", identifier(name), mi.keyspace.ref,
+		strings.Join(keys, ", "))
+	if where != nil {
+		statement += " WHERE " + where.String()
+	}
+	statement += " " + mi.usingClause() + withClause(with)
+
+	_, err := mi.keyspace.client().queryValues(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	return mi.IndexByName(name)
+}
+
+func (mi *indexer) BuildIndexes(requestId string, names ...string) errors.Error {
+	ids := make([]string, len(names))
+	for i, name := range names {
+		ids[i] = identifier(name)
+	}
+
+	statement := fmt.Sprintf("BUILD INDEX ON %s(%s) %s", mi.keyspace.ref,
+		strings.Join(ids, ", "), mi.usingClause())
+	_, err := mi.keyspace.client().queryValues(statement)
+	return err
+}
+
+func (mi *indexer) Refresh() errors.Error {
+	return nil
+}
+
+func (mi *indexer) SetLogLevel(level logging.Level) {
+	// No-op, the remote engine has its own logger
+}
+
+func (mi *indexer) usingClause() string {
+	if mi.using == datastore.DEFAULT {
+		return ""
+	}
+	return "USING " + strings.ToUpper(string(mi.using))
+}
+
+func withClause(with value.Value) string {
+	if with == nil {
+		return ""
+	}
+
+	bytes, _ := with.MarshalJSON()
+	return " WITH " + string(bytes)
+}
+
+/*
+keyString returns the text of an index key for CREATE INDEX.
+*/
+func keyString(key expression.Expression) string {
+	all, ok := key.(*expression.All)
+	if !ok {
+		return key.String()
+	}
+
+	if all.Distinct() {
+		return "DISTINCT " + all.Array().String()
+	}
+	return "ALL " + all.Array().String()
+}
+
+/*
+index is a remote index. Scans are forwarded as SELECT statements that
+use the index, with the span as a predicate on the index keys.
+*/
+type index struct {
+	indexer   *indexer
+	name      string
+	primary   bool
+	keys      expression.Expressions
+	condition expression.Expression
+	state     datastore.IndexState
+}
+
+func (idx *index) KeyspaceId() string {
+	return idx.indexer.KeyspaceId()
+}
+
+func (idx *index) Id() string {
+	return idx.Name()
+}
+
+func (idx *index) Name() string {
+	return idx.name
+}
+
+func (idx *index) Type() datastore.IndexType {
+	return idx.indexer.using
+}
+
+func (idx *index) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (idx *index) RangeKey() expression.Expressions {
+	return idx.keys
+}
+
+func (idx *index) Condition() expression.Expression {
+	return idx.condition
+}
+
+func (idx *index) IsPrimary() bool {
+	return idx.primary
+}
+
+func (idx *index) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return idx.state, "", nil
+}
+
+func (idx *index) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (idx *index) Drop(requestId string) errors.Error {
+	b := idx.indexer.keyspace
+	statement := fmt.Sprintf("DROP INDEX %s.%s %s", b.ref, identifier(idx.name), idx.indexer.usingClause())
+	_, err := b.client().queryValues(statement)
+	return err
+}
+
+func (idx *index) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer close(conn.EntryChannel())
+
+	statement, args, filter := idx.scanStatement(span, limit)
+
+	var n int64
+	var er error
+	err := idx.indexer.keyspace.client().query(statement, args, cons, func(result json.RawMessage) bool {
+		var entry *datastore.IndexEntry
+		entry, er = idx.entry(result)
+		if er != nil {
+			return false
+		}
+
+		if filter && !inRange(entry.EntryKey, &span.Range) {
+			return true
+		}
+
+		if limit > 0 && n >= limit {
+			return false
+		}
+		n++
+
+		if idx.primary {
+			entry.EntryKey = nil
+		}
+
+		return conn.Send(entry)
+	})
+
+	if err == nil && er != nil {
+		err = errors.NewOtherDatastoreError(er, "Unexpected result from remote engine")
+	}
+	if err != nil {
+		conn.Error(err)
+	}
+}
+
+func (idx *index) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	idx.Scan(requestId, &datastore.Span{}, false, limit, cons, vector, conn)
+}
+
+/*
+scanKeys returns the text of the index keys in statements. The key of
+a primary index is the document key.
+*/
+func (idx *index) scanKeys() []string {
+	alias := idx.indexer.keyspace.alias
+	if idx.primary {
+		return []string{"META(" + alias + ").id"}
+	}
+
+	rv := make([]string, len(idx.keys))
+	for i, key := range idx.keys {
+		rv[i] = "(" + key.String() + ")"
+	}
+	return rv
+}
+
+/*
+scanStatement returns the statement and arguments of a scan. Seeks and
+single-key ranges are pushed down with the limit. Other ranges are
+pushed down on their leading key, and filtered on all keys with
+filter true.
+*/
+func (idx *index) scanStatement(span *datastore.Span, limit int64) (
+	statement string, args []interface{}, filter bool) {
+	b := idx.indexer.keyspace
+	keys := idx.scanKeys()
+
+	var buf bytes.Buffer
+	buf.WriteString("SELECT META(")
+	buf.WriteString(b.alias)
+	buf.WriteString(").id AS id")
+	for i, key := range keys {
+		fmt.Fprintf(&buf, ", %s AS k%d", key, i)
+	}
+	fmt.Fprintf(&buf, " FROM %s USE INDEX (%s %s)", b.ref, identifier(idx.name), idx.indexer.usingClause())
+
+	arg := func(v value.Value) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var preds []string
+	if idx.condition != nil {
+		preds = append(preds, "("+idx.condition.String()+")")
+	}
+
+	if len(span.Seek) > 0 {
+		for i, v := range span.Seek {
+			if i >= len(keys) {
+				break
+			}
+
+			switch v.Type() {
+			case value.MISSING:
+				preds = append(preds, keys[i]+" IS MISSING")
+			case value.NULL:
+				preds = append(preds, keys[i]+" IS NULL")
+			default:
+				preds = append(preds, keys[i]+" = "+arg(v))
+			}
+		}
+	} else {
+		low, high, inclusion := span.Range.Low, span.Range.High, span.Range.Inclusion
+		filter = len(low) > 1 || len(high) > 1
+
+		// Documents whose leading key is MISSING are not indexed.
+		// Document keys are strings, and some primary indexes only
+		// accept string bounds.
+		pred := keys[0] + " IS NOT MISSING"
+		if idx.primary {
+			pred = keys[0] + ` >= ""`
+		}
+
+		if len(low) > 0 {
+			exclusive := inclusion&datastore.LOW == 0 && len(low) == 1
+			switch low[0].Type() {
+			case value.MISSING:
+			case value.NULL:
+				if exclusive && !idx.primary {
+					pred = keys[0] + " IS VALUED"
+				}
+			default:
+				op := " >= "
+				if exclusive {
+					op = " > "
+				}
+				pred = keys[0] + op + arg(low[0])
+			}
+		}
+
+		preds = append(preds, pred)
+
+		if len(high) > 0 {
+			switch high[0].Type() {
+			case value.MISSING, value.NULL:
+				filter = true
+			default:
+				op := " <= "
+				if inclusion&datastore.HIGH == 0 && len(high) == 1 {
+					op = " < "
+				}
+				preds = append(preds, keys[0]+op+arg(high[0]))
+			}
+		}
+	}
+
+	if len(preds) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(preds, " AND "))
+	}
+
+	buf.WriteString(" ORDER BY ")
+	buf.WriteString(strings.Join(keys, ", "))
+	if !idx.primary {
+		fmt.Fprintf(&buf, ", META(%s).id", b.alias)
+	}
+
+	if limit > 0 && limit <= _MAX_LIMIT && !filter {
+		fmt.Fprintf(&buf, " LIMIT %d", limit)
+	}
+
+	return buf.String(), args, filter
+}
+
+// Limits are numbers in statements, and larger ones would overflow
+const _MAX_LIMIT = 1 << 53
+
+/*
+entry decodes a scan result. Keys that are MISSING are omitted from
+the result.
+*/
+func (idx *index) entry(result json.RawMessage) (*datastore.IndexEntry, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(result, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &datastore.IndexEntry{}
+	err = json.Unmarshal(fields["id"], &entry.PrimaryKey)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(idx.keys)
+	if idx.primary {
+		n = 1
+	}
+
+	entry.EntryKey = make(value.Values, n)
+	for i := 0; i < n; i++ {
+		if raw, ok := fields[fmt.Sprintf("k%d", i)]; ok {
+			entry.EntryKey[i] = value.NewValue([]byte(raw))
+		} else {
+			entry.EntryKey[i] = value.NewMissingValue()
+		}
+	}
+
+	return entry, nil
+}
+
+/*
+inRange returns true if key is within rng, comparing the bounds with
+the leading keys.
+*/
+func inRange(key value.Values, rng *datastore.Range) bool {
+	if len(rng.Low) > 0 {
+		c := compareKeys(key, rng.Low)
+		if c < 0 || (c == 0 && rng.Inclusion&datastore.LOW == 0) {
+			return false
+		}
+	}
+
+	if len(rng.High) > 0 {
+		c := compareKeys(key, rng.High)
+		if c > 0 || (c == 0 && rng.Inclusion&datastore.HIGH == 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func compareKeys(key, bound value.Values) int {
+	for i, b := range bound {
+		if i >= len(key) {
+			return -1
+		}
+
+		if c := key[i].Collate(b); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,241 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package remote
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/couchbase/query/accounting"
+	acct_resolver "github.com/couchbase/query/accounting/resolver"
+	config_resolver "github.com/couchbase/query/clustering/resolver"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/file"
+	"github.com/couchbase/query/datastore/system"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+	log_resolver "github.com/couchbase/query/logging/resolver"
+	"github.com/couchbase/query/server"
+	"github.com/couchbase/query/server/http"
+	"github.com/couchbase/query/value"
+)
+
+func init() {
+	logger, _ := log_resolver.NewLogger("golog")
+	logging.SetLogger(logger)
+}
+
+/*
+startEngine starts a query engine over a dir: datastore with a
+contacts keyspace, and returns its url.
+*/
+func startEngine(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "remote")
+	if err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	contacts := filepath.Join(dir, "default", "contacts")
+	os.MkdirAll(contacts, 0755)
+	for name, doc := range map[string]string{
+		"dave":  `{"name": "dave", "age": 46}`,
+		"earl":  `{"name": "earl", "age": 46}`,
+		"fred":  `{"name": "fred", "age": 22}`,
+		"harry": `{"name": "harry", "age": 62}`,
+		"ian":   `{"name": "ian"}`,
+	} {
+		ioutil.WriteFile(filepath.Join(contacts, name+".json"), []byte(doc), 0644)
+	}
+
+	store, er := file.NewDatastore(dir)
+	if er != nil {
+		t.Fatalf("failed to create datastore: %v", er)
+	}
+
+	sys, er := system.NewDatastore(store)
+	if er != nil {
+		t.Fatalf("failed to create system datastore: %v", er)
+	}
+
+	configstore, _ := config_resolver.NewConfigstore("stub:")
+	acctstore, _ := acct_resolver.NewAcctstore("stub:")
+	accounting.RequestsInit(0, 8)
+
+	srv, er := server.NewServer(store, sys, configstore, acctstore, "default",
+		false, make(server.RequestChannel, 10), make(server.RequestChannel, 10),
+		4, 4, 0, 0, false, false, false, false)
+	if er != nil {
+		t.Fatalf("failed to create server: %v", er)
+	}
+	srv.SetKeepAlive(1 << 10)
+	srv.SetRequestSizeCap(server.MAX_REQUEST_SIZE)
+	datastore.SetDatastore(store)
+	datastore.SetSystemstore(sys)
+	go srv.Serve()
+	go srv.PlusServe()
+
+	endpoint := http.NewServiceEndpoint(srv, "", false, "", "", "", "", "")
+	ts := httptest.NewServer(endpoint)
+	return ts.URL, func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRemote(t *testing.T) {
+	url, stop := startEngine(t)
+	defer stop()
+
+	s, err := NewDatastore("n1ql:" + url)
+	if err != nil {
+		t.Fatalf("failed to create remote datastore: %v", err)
+	}
+
+	p, err := s.NamespaceByName("default")
+	if err != nil {
+		t.Fatalf("expected namespace default: %v", err)
+	}
+
+	names, err := p.KeyspaceNames()
+	if err != nil || len(names) != 1 || names[0] != "contacts" {
+		t.Fatalf("expected keyspace contacts, got %v %v", names, err)
+	}
+
+	b, err := p.KeyspaceByName("contacts")
+	if err != nil {
+		t.Fatalf("expected keyspace contacts: %v", err)
+	}
+
+	count, err := b.Count()
+	if err != nil || count != 5 {
+		t.Errorf("expected 5 documents, got %d %v", count, err)
+	}
+
+	pairs, errs := b.Fetch([]string{"dave", "nobody"}, datastore.NULL_QUERY_CONTEXT)
+	if len(errs) > 0 || len(pairs) != 1 || pairs[0].Name != "dave" {
+		t.Fatalf("expected to fetch dave only, got %v %v", pairs, errs)
+	}
+
+	age, _ := pairs[0].Value.Field("age")
+	if age.Actual() != float64(46) {
+		t.Errorf("unexpected document %v", pairs[0].Value)
+	}
+
+	indexer, _ := b.Indexer(datastore.DEFAULT)
+	primary, err := indexer.IndexByName("#primary")
+	if err != nil {
+		t.Fatalf("expected primary index: %v", err)
+	}
+
+	entries := doScan(t, primary, &datastore.Span{}, 0)
+	if len(entries) != 5 || entries[0].PrimaryKey != "dave" || entries[4].PrimaryKey != "ian" {
+		t.Errorf("unexpected primary scan %v", entries)
+	}
+
+	span := &datastore.Span{Range: datastore.Range{
+		Low:       value.Values{value.NewValue("earl")},
+		High:      value.Values{value.NewValue("harry")},
+		Inclusion: datastore.LOW,
+	}}
+	entries = doScan(t, primary, span, 1)
+	if len(entries) != 1 || entries[0].PrimaryKey != "earl" {
+		t.Errorf("unexpected primary range scan %v", entries)
+	}
+
+	_, err = b.Insert([]value.Pair{
+		value.Pair{Name: "jack", Value: value.NewValue(map[string]interface{}{"name": "jack"})},
+		value.Pair{Name: "dave", Value: value.NewValue(map[string]interface{}{"name": "dave"})},
+	}, datastore.NULL_QUERY_CONTEXT)
+	if err == nil {
+		t.Errorf("expected duplicate key error")
+	}
+
+	updated, err := b.Update([]value.Pair{
+		value.Pair{Name: "jack", Value: value.NewValue(map[string]interface{}{"name": "jack", "age": 5})},
+		value.Pair{Name: "kate", Value: value.NewValue(map[string]interface{}{"name": "kate"})},
+	}, datastore.NULL_QUERY_CONTEXT)
+	if err == nil || len(updated) != 1 {
+		t.Errorf("expected to update jack only, got %v %v", updated, err)
+	}
+
+	deleted, err := b.Delete([]string{"jack", "kate"}, datastore.NULL_QUERY_CONTEXT)
+	if err != nil || len(deleted) != 1 || deleted[0] != "jack" {
+		t.Errorf("expected to delete jack only, got %v %v", deleted, err)
+	}
+}
+
+func TestScanStatement(t *testing.T) {
+	b := newKeyspace(&namespace{name: "default"}, "contacts")
+	idx, er := b.indexers[datastore.GSI].newIndex(&indexInfo{
+		Name:     "ix_name_age",
+		IndexKey: []string{"`name`", "`age`"},
+	})
+	if er != nil {
+		t.Fatalf("failed to create index: %v", er)
+	}
+
+	span := &datastore.Span{Seek: value.Values{value.NewValue("dave"), value.NewNullValue()}}
+	statement, args, filter := idx.scanStatement(span, 10)
+	expected := "SELECT META(`contacts`).id AS id, (`name`) AS k0, (`age`) AS k1" +
+		" FROM `default`:`contacts` USE INDEX (`ix_name_age` USING GSI)" +
+		" WHERE (`name`) = $1 AND (`age`) IS NULL" +
+		" ORDER BY (`name`), (`age`), META(`contacts`).id LIMIT 10"
+	if statement != expected || len(args) != 1 || filter {
+		t.Errorf("unexpected seek statement %s %v", statement, args)
+	}
+
+	span = &datastore.Span{Range: datastore.Range{
+		Low:       value.Values{value.NewValue("dave"), value.NewValue(30)},
+		Inclusion: datastore.NEITHER,
+	}}
+	statement, args, filter = idx.scanStatement(span, 10)
+	expected = "SELECT META(`contacts`).id AS id, (`name`) AS k0, (`age`) AS k1" +
+		" FROM `default`:`contacts` USE INDEX (`ix_name_age` USING GSI)" +
+		" WHERE (`name`) >= $1" +
+		" ORDER BY (`name`), (`age`), META(`contacts`).id"
+	if statement != expected || len(args) != 1 || !filter {
+		t.Errorf("unexpected range statement %s %v", statement, args)
+	}
+
+	key := value.Values{value.NewValue("dave"), value.NewValue(30)}
+	if inRange(key, &span.Range) {
+		t.Errorf("expected %v not to be in range %v", key, span.Range)
+	}
+}
+
+type testingContext struct {
+	t *testing.T
+}
+
+func (this *testingContext) Error(err errors.Error) {
+	this.t.Errorf("Scan error: %v", err)
+}
+
+func (this *testingContext) Warning(wrn errors.Error) {
+	this.t.Logf("scan warning: %v", wrn)
+}
+
+func (this *testingContext) Fatal(fatal errors.Error) {
+	this.t.Errorf("scan fatal: %v", fatal)
+}
+
+func doScan(t *testing.T, index datastore.Index, span *datastore.Span, limit int64) []*datastore.IndexEntry {
+	conn := datastore.NewIndexConnection(&testingContext{t})
+	go index.Scan("", span, false, limit, datastore.UNBOUNDED, nil, conn)
+
+	var rv []*datastore.IndexEntry
+	for entry := range conn.EntryChannel() {
+		rv = append(rv, entry)
+	}
+	return rv
+}
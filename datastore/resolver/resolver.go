@@ -10,13 +10,17 @@
 package resolver
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/datastore/couchbase"
+	"github.com/couchbase/query/datastore/federated"
 	"github.com/couchbase/query/datastore/file"
+	"github.com/couchbase/query/datastore/mem"
 	"github.com/couchbase/query/datastore/mock"
+	"github.com/couchbase/query/datastore/remote"
 	"github.com/couchbase/query/errors"
 )
 
@@ -41,5 +45,50 @@ func NewDatastore(uri string) (datastore.Datastore, errors.Error) {
 		return mock.NewDatastore(uri)
 	}
 
+	if strings.HasPrefix(uri, "mem:") {
+		return mem.NewDatastore(uri)
+	}
+
+	if strings.HasPrefix(uri, "n1ql:") {
+		return remote.NewDatastore(uri)
+	}
+
+	if strings.HasPrefix(uri, "federated:") {
+		return newFederatedDatastore(uri[10:])
+	}
+
 	return nil, errors.NewError(nil, fmt.Sprintf("Invalid datastore uri: %s", uri))
 }
+
+/*
+newFederatedDatastore creates a federated datastore from a JSON object
+that maps namespaces to datastore uris, e.g.
+
+	{"prod": "http://cb:8091", "fixtures": "dir:/data"}
+
+Namespaces with the same uri share a datastore.
+*/
+func newFederatedDatastore(config string) (datastore.Datastore, errors.Error) {
+	var uris map[string]string
+	err := json.Unmarshal([]byte(config), &uris)
+	if err != nil {
+		return nil, errors.NewError(err, fmt.Sprintf("Invalid federated datastore configuration: %s", config))
+	}
+
+	byUri := make(map[string]datastore.Datastore, len(uris))
+	stores := make(map[string]datastore.Datastore, len(uris))
+	for namespace, uri := range uris {
+		store, ok := byUri[uri]
+		if !ok {
+			var err errors.Error
+			store, err = NewDatastore(uri)
+			if err != nil {
+				return nil, err
+			}
+			byUri[uri] = store
+		}
+		stores[namespace] = store
+	}
+
+	return federated.NewDatastore(stores)
+}
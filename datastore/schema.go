@@ -0,0 +1,26 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package datastore
+
+import (
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/schema"
+)
+
+/*
+SchemaKeyspace is implemented by keyspaces that can hold a JSON
+schema. Documents written by INSERT, UPSERT and UPDATE must be valid.
+*/
+type SchemaKeyspace interface {
+	Keyspace
+
+	Schema() *schema.Schema                       // nil if the keyspace has no schema
+	SetSchema(schema *schema.Schema) errors.Error // nil removes the schema
+}
@@ -0,0 +1,110 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package datastore
+
+import (
+	"sync"
+
+	"github.com/couchbase/query/errors"
+)
+
+// Defaults of sequence options
+const (
+	SEQUENCE_START     = 1
+	SEQUENCE_INCREMENT = 1
+	SEQUENCE_CACHE     = 20
+)
+
+/*
+Sequence is a named generator of integers, from Start by Increment.
+Values are reserved in batches of Cache values; the unused values of
+a batch are skipped after a restart.
+*/
+type Sequence struct {
+	Name      string `json:"name"`
+	Start     int64  `json:"start"`
+	Increment int64  `json:"increment"`
+	Cache     int64  `json:"cache"`
+}
+
+/*
+SequenceNamespace is implemented by namespaces that can store
+sequences.
+*/
+type SequenceNamespace interface {
+	Namespace
+
+	Sequences() ([]*Sequence, errors.Error)              // Sorted by name
+	CreateSequence(sequence *Sequence) errors.Error      // Fails if the sequence exists
+	DropSequence(name string) errors.Error               // Fails if the sequence does not exist
+	NextSequenceValue(name string) (int64, errors.Error) // Fails if the sequence does not exist
+	SequencePosition(name string) (int64, errors.Error)  // The next value, which is not handed out
+}
+
+/*
+SequenceCounter hands out the values of a sequence. Before the first
+value of a batch is handed out, the value that follows the batch is
+persisted by the reserve function of the store; after a restart, the
+counter resumes from the persisted value. Values are therefore never
+handed out twice, even after a crash.
+*/
+type SequenceCounter struct {
+	sync.Mutex
+	sequence  *Sequence
+	next      int64 // The next value
+	remaining int64 // The values of the batch not yet handed out
+}
+
+/*
+NewSequenceCounter returns a counter that resumes from next, which is
+the Start of a new sequence.
+*/
+func NewSequenceCounter(sequence *Sequence, next int64) *SequenceCounter {
+	return &SequenceCounter{
+		sequence: sequence,
+		next:     next,
+	}
+}
+
+func (this *SequenceCounter) Sequence() *Sequence {
+	return this.sequence
+}
+
+/*
+Position returns the value from which a restored counter resumes.
+*/
+func (this *SequenceCounter) Position() int64 {
+	this.Lock()
+	defer this.Unlock()
+	return this.next
+}
+
+func (this *SequenceCounter) Next(reserve func(next int64) errors.Error) (int64, errors.Error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.remaining <= 0 {
+		cache := this.sequence.Cache
+		if cache < 1 {
+			cache = 1
+		}
+
+		err := reserve(this.next + cache*this.sequence.Increment)
+		if err != nil {
+			return 0, err
+		}
+		this.remaining = cache
+	}
+
+	rv := this.next
+	this.next += this.sequence.Increment
+	this.remaining--
+	return rv, nil
+}
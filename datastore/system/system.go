@@ -27,6 +27,10 @@ const KEYSPACE_NAME_DUAL = "dual"
 const KEYSPACE_NAME_PREPAREDS = "prepareds"
 const KEYSPACE_NAME_REQUESTS = "completed_requests"
 const KEYSPACE_NAME_ACTIVE = "active_requests"
+const KEYSPACE_NAME_TRIGGERS = "triggers"
+const KEYSPACE_NAME_VIEWS = "views"
+const KEYSPACE_NAME_SEQUENCES = "sequences"
+const KEYSPACE_NAME_RESULT_CACHE = "result_cache"
 
 type store struct {
 	actualStore              datastore.Datastore
@@ -54,7 +54,7 @@ func (b *activeRequestsKeyspace) Indexers() ([]datastore.Indexer, errors.Error)
 	return []datastore.Indexer{b.indexer}, nil
 }
 
-func (b *activeRequestsKeyspace) Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) {
+func (b *activeRequestsKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
 	var errs []errors.Error
 	rv := make([]value.AnnotatedPair, 0, len(keys))
 
@@ -102,22 +102,22 @@ func (b *activeRequestsKeyspace) Fetch(keys []string) ([]value.AnnotatedPair, []
 	return rv, errs
 }
 
-func (b *activeRequestsKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *activeRequestsKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *activeRequestsKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+func (b *activeRequestsKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *activeRequestsKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *activeRequestsKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *activeRequestsKeyspace) Delete(deletes []string) ([]string, errors.Error) {
+func (b *activeRequestsKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
 	for i, name := range deletes {
 		done := server.ActiveRequestsDelete(name)
 
@@ -214,6 +214,8 @@ func (pi *activeRequestsIndex) ScanEntries(requestId string, limit int64, cons d
 
 	for _, name := range requestIds {
 		entry := datastore.IndexEntry{PrimaryKey: name}
-		conn.EntryChannel() <- &entry
+		if !conn.Send(&entry) {
+			return
+		}
 	}
 }
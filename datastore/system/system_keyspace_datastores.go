@@ -53,7 +53,7 @@ func (b *storeKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
 	return []datastore.Indexer{b.si}, nil
 }
 
-func (b *storeKeyspace) Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) {
+func (b *storeKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
 	var errs []errors.Error
 	rv := make([]value.AnnotatedPair, 0, len(keys))
 	for _, k := range keys {
@@ -92,22 +92,22 @@ func (b *storeKeyspace) fetchOne(key string) (value.AnnotatedValue, errors.Error
 	return nil, errors.NewSystemDatastoreError(nil, "Key Not Found "+key)
 }
 
-func (b *storeKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *storeKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *storeKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+func (b *storeKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *storeKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *storeKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *storeKeyspace) Delete(deletes []string) ([]string, errors.Error) {
+func (b *storeKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
@@ -190,7 +190,7 @@ func (pi *storeIndex) Scan(requestId string, span *datastore.Span, distinct bool
 
 	if strings.EqualFold(val, pi.keyspace.namespace.store.actualStore.Id()) {
 		entry := datastore.IndexEntry{PrimaryKey: pi.keyspace.namespace.store.actualStore.Id()}
-		conn.EntryChannel() <- &entry
+		conn.Send(&entry)
 	}
 }
 
@@ -199,5 +199,5 @@ func (pi *storeIndex) ScanEntries(requestId string, limit int64, cons datastore.
 	defer close(conn.EntryChannel())
 
 	entry := datastore.IndexEntry{PrimaryKey: pi.keyspace.namespace.store.actualStore.Id()}
-	conn.EntryChannel() <- &entry
+	conn.Send(&entry)
 }
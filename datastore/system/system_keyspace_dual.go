@@ -53,7 +53,7 @@ func (b *dualKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
 	return []datastore.Indexer{b.di}, nil
 }
 
-func (b *dualKeyspace) Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) {
+func (b *dualKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
 	var errs []errors.Error
 	rv := make([]value.AnnotatedPair, 0, len(keys))
 	for _, k := range keys {
@@ -85,19 +85,19 @@ func (b *dualKeyspace) fetchOne(key string) (value.AnnotatedValue, errors.Error)
 	return value.NewAnnotatedValue(nil), nil
 }
 
-func (b *dualKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *dualKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	return nil, errors.NewSystemDatastoreError(nil, "Mutations not allowed on system:dual.")
 }
 
-func (b *dualKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+func (b *dualKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	return nil, errors.NewSystemDatastoreError(nil, "Mutations not allowed on system:dual.")
 }
 
-func (b *dualKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *dualKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	return nil, errors.NewSystemDatastoreError(nil, "Mutations not allowed on system:dual.")
 }
 
-func (b *dualKeyspace) Delete(deletes []string) ([]string, errors.Error) {
+func (b *dualKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
 	return nil, errors.NewSystemDatastoreError(nil, "Mutations not allowed on system:dual.")
 }
 
@@ -179,7 +179,7 @@ func (pi *dualIndex) Scan(requestId string, span *datastore.Span, distinct bool,
 
 	if strings.EqualFold(val, KEYSPACE_NAME_DUAL) {
 		entry := datastore.IndexEntry{PrimaryKey: KEYSPACE_NAME_DUAL}
-		conn.EntryChannel() <- &entry
+		conn.Send(&entry)
 	}
 }
 
@@ -188,5 +188,5 @@ func (pi *dualIndex) ScanEntries(requestId string, limit int64, cons datastore.S
 	defer close(conn.EntryChannel())
 
 	entry := datastore.IndexEntry{PrimaryKey: KEYSPACE_NAME_DUAL}
-	conn.EntryChannel() <- &entry
+	conn.Send(&entry)
 }
@@ -97,7 +97,7 @@ func (b *indexKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
 	return []datastore.Indexer{b.indexer}, nil
 }
 
-func (b *indexKeyspace) Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) {
+func (b *indexKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
 	var errs []errors.Error
 	rv := make([]value.AnnotatedPair, 0, len(keys)*2)
 
@@ -210,22 +210,22 @@ func newIndexesKeyspace(p *namespace) (*indexKeyspace, errors.Error) {
 	return b, nil
 }
 
-func (b *indexKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *indexKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *indexKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+func (b *indexKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "Not yet implemented.")
 }
 
-func (b *indexKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *indexKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "Not yet implemented.")
 }
 
-func (b *indexKeyspace) Delete(deletes []string) ([]string, errors.Error) {
+func (b *indexKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "Not yet implemented.")
 }
@@ -332,6 +332,8 @@ func (pi *indexIndex) ScanEntries(requestId string, limit int64, cons datastore.
 
 	for k, _ := range keys {
 		entry := datastore.IndexEntry{PrimaryKey: k}
-		conn.EntryChannel() <- &entry
+		if !conn.Send(&entry) {
+			return
+		}
 	}
 }
@@ -82,7 +82,7 @@ func (b *keyspaceKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
 	return []datastore.Indexer{b.indexer}, nil
 }
 
-func (b *keyspaceKeyspace) Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) {
+func (b *keyspaceKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
 	var errs []errors.Error
 	rv := make([]value.AnnotatedPair, 0, len(keys))
 	for _, k := range keys {
@@ -133,22 +133,22 @@ func (b *keyspaceKeyspace) fetchOne(key string) (value.AnnotatedValue, errors.Er
 	return nil, err
 }
 
-func (b *keyspaceKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *keyspaceKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *keyspaceKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+func (b *keyspaceKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *keyspaceKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *keyspaceKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *keyspaceKeyspace) Delete(deletes []string) ([]string, errors.Error) {
+func (b *keyspaceKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
@@ -242,7 +242,7 @@ func (pi *keyspaceIndex) Scan(requestId string, span *datastore.Span, distinct b
 	keyspace, _ := namespace.KeyspaceById(ids[1])
 	if keyspace != nil {
 		entry := datastore.IndexEntry{PrimaryKey: fmt.Sprintf("%s/%s", namespace.Id(), keyspace.Id())}
-		conn.EntryChannel() <- &entry
+		conn.Send(&entry)
 	}
 }
 
@@ -273,7 +273,9 @@ func (pi *keyspaceIndex) ScanEntries(requestId string, limit int64, cons datasto
 							break
 						}
 						entry := datastore.IndexEntry{PrimaryKey: fmt.Sprintf("%s/%s", namespaceId, keyspaceId)}
-						conn.EntryChannel() <- &entry
+						if !conn.Send(&entry) {
+							return
+						}
 						numProduced++
 					}
 				}
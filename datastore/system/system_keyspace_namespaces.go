@@ -56,7 +56,7 @@ func (b *namespaceKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
 	return []datastore.Indexer{b.indexer}, nil
 }
 
-func (b *namespaceKeyspace) Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) {
+func (b *namespaceKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
 	var errs []errors.Error
 	rv := make([]value.AnnotatedPair, 0, len(keys))
 	for _, k := range keys {
@@ -98,22 +98,22 @@ func (b *namespaceKeyspace) fetchOne(key string) (value.AnnotatedValue, errors.E
 	return nil, errors.NewSystemDatastoreError(excp, "Key Not Found "+key)
 }
 
-func (b *namespaceKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *namespaceKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *namespaceKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+func (b *namespaceKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *namespaceKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *namespaceKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *namespaceKeyspace) Delete(deletes []string) ([]string, errors.Error) {
+func (b *namespaceKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
@@ -197,7 +197,7 @@ func (pi *namespaceIndex) Scan(requestId string, span *datastore.Span, distinct
 	namespace, _ := pi.keyspace.namespace.store.actualStore.NamespaceById(val)
 	if namespace != nil {
 		entry := datastore.IndexEntry{PrimaryKey: namespace.Id()}
-		conn.EntryChannel() <- &entry
+		conn.Send(&entry)
 	}
 }
 
@@ -213,7 +213,9 @@ func (pi *namespaceIndex) ScanEntries(requestId string, limit int64, cons datast
 			}
 
 			entry := datastore.IndexEntry{PrimaryKey: namespaceId}
-			conn.EntryChannel() <- &entry
+			if !conn.Send(&entry) {
+				return
+			}
 		}
 	}
 }
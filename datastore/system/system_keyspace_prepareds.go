@@ -53,7 +53,7 @@ func (b *preparedsKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
 	return []datastore.Indexer{b.indexer}, nil
 }
 
-func (b *preparedsKeyspace) Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) {
+func (b *preparedsKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
 	var errs []errors.Error
 	rv := make([]value.AnnotatedPair, 0, len(keys))
 
@@ -86,22 +86,22 @@ func (b *preparedsKeyspace) Fetch(keys []string) ([]value.AnnotatedPair, []error
 	return rv, errs
 }
 
-func (b *preparedsKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *preparedsKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *preparedsKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+func (b *preparedsKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *preparedsKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *preparedsKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *preparedsKeyspace) Delete(deletes []string) ([]string, errors.Error) {
+func (b *preparedsKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
 	for i, name := range deletes {
 		err := plan.DeletePrepared(name)
 		if err != nil {
@@ -188,6 +188,8 @@ func (pi *preparedsIndex) ScanEntries(requestId string, limit int64, cons datast
 
 	for _, name := range names {
 		entry := datastore.IndexEntry{PrimaryKey: name}
-		conn.EntryChannel() <- &entry
+		if !conn.Send(&entry) {
+			return
+		}
 	}
 }
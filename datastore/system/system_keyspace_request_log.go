@@ -51,7 +51,7 @@ func (b *requestLogKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
 	return []datastore.Indexer{b.indexer}, nil
 }
 
-func (b *requestLogKeyspace) Fetch(keys []string) ([]value.AnnotatedPair, []errors.Error) {
+func (b *requestLogKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
 	var errs []errors.Error
 	rv := make([]value.AnnotatedPair, 0, len(keys))
 
@@ -99,22 +99,22 @@ func (b *requestLogKeyspace) Fetch(keys []string) ([]value.AnnotatedPair, []erro
 	return rv, errs
 }
 
-func (b *requestLogKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *requestLogKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *requestLogKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+func (b *requestLogKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *requestLogKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+func (b *requestLogKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
 	// FIXME
 	return nil, errors.NewSystemNotImplementedError(nil, "")
 }
 
-func (b *requestLogKeyspace) Delete(deletes []string) ([]string, errors.Error) {
+func (b *requestLogKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
 	for i, name := range deletes {
 		err := accounting.RequestDelete(name)
 
@@ -199,8 +199,12 @@ func (pi *requestLogIndex) Scan(requestId string, span *datastore.Span, distinct
 func (pi *requestLogIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
 	vector timestamp.Vector, conn *datastore.IndexConnection) {
 	defer close(conn.EntryChannel())
+	stopped := false
 	accounting.RequestsForeach(func(id string, entry *accounting.RequestLogEntry) {
+		if stopped {
+			return
+		}
 		indexEntry := datastore.IndexEntry{PrimaryKey: id}
-		conn.EntryChannel() <- &indexEntry
+		stopped = !conn.Send(&indexEntry)
 	})
 }
@@ -0,0 +1,198 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"time"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/server"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+type resultCacheKeyspace struct {
+	namespace *namespace
+	name      string
+	indexer   datastore.Indexer
+}
+
+func (b *resultCacheKeyspace) Release() {
+}
+
+func (b *resultCacheKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *resultCacheKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *resultCacheKeyspace) Name() string {
+	return b.name
+}
+
+func (b *resultCacheKeyspace) Count() (int64, errors.Error) {
+	return int64(server.ResultCacheCount()), nil
+}
+
+func (b *resultCacheKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.indexer, nil
+}
+
+func (b *resultCacheKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *resultCacheKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
+	var errs []errors.Error
+	rv := make([]value.AnnotatedPair, 0, len(keys))
+
+	for _, key := range keys {
+		server.ResultCacheDo(key, func(entry *server.ResultCacheEntry) {
+			keyspaces := make([]interface{}, 0, len(entry.Keyspaces))
+			for keyspace, _ := range entry.Keyspaces {
+				keyspaces = append(keyspaces, keyspace)
+			}
+
+			item := value.NewAnnotatedValue(map[string]interface{}{
+				"id":          key,
+				"namespace":   entry.Namespace,
+				"statement":   entry.Statement,
+				"keyspaces":   keyspaces,
+				"resultCount": len(entry.Results),
+				"resultSize":  entry.Size,
+				"hits":        entry.Hits,
+				"created":     entry.Created.String(),
+				"lastUse":     entry.LastUse.String(),
+				"age":         time.Since(entry.Created).String(),
+			})
+			item.SetAttachment("meta", map[string]interface{}{
+				"id": key,
+			})
+			rv = append(rv, value.AnnotatedPair{
+				Name:  key,
+				Value: item,
+			})
+		})
+	}
+	return rv, errs
+}
+
+func (b *resultCacheKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *resultCacheKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *resultCacheKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+/*
+Deleting an entry evicts it from the result cache.
+*/
+func (b *resultCacheKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	for i, name := range deletes {
+		if !server.ResultCacheDelete(name) {
+			deleted := make([]string, i)
+			if i > 0 {
+				copy(deleted, deletes[0:i])
+			}
+			return deleted, errors.NewSystemStmtNotFoundError(nil, name)
+		}
+	}
+	return deletes, nil
+}
+
+func newResultCacheKeyspace(p *namespace) (*resultCacheKeyspace, errors.Error) {
+	b := new(resultCacheKeyspace)
+	b.namespace = p
+	b.name = KEYSPACE_NAME_RESULT_CACHE
+
+	primary := &resultCacheIndex{name: "#primary", keyspace: b}
+	b.indexer = newSystemIndexer(b, primary)
+
+	return b, nil
+}
+
+type resultCacheIndex struct {
+	name     string
+	keyspace *resultCacheKeyspace
+}
+
+func (pi *resultCacheIndex) KeyspaceId() string {
+	return pi.keyspace.Id()
+}
+
+func (pi *resultCacheIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *resultCacheIndex) Name() string {
+	return pi.name
+}
+
+func (pi *resultCacheIndex) Type() datastore.IndexType {
+	return datastore.DEFAULT
+}
+
+func (pi *resultCacheIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *resultCacheIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *resultCacheIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *resultCacheIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *resultCacheIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *resultCacheIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *resultCacheIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, "")
+}
+
+func (pi *resultCacheIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	pi.ScanEntries(requestId, limit, cons, vector, conn)
+}
+
+func (pi *resultCacheIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer close(conn.EntryChannel())
+
+	for i, id := range server.ResultCacheIds() {
+		if limit > 0 && int64(i) >= limit {
+			break
+		}
+		entry := datastore.IndexEntry{PrimaryKey: id}
+		if !conn.Send(&entry) {
+			return
+		}
+	}
+}
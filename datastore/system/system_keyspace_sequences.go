@@ -0,0 +1,276 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+type sequenceKeyspace struct {
+	namespace *namespace
+	name      string
+	indexer   datastore.Indexer
+}
+
+func (b *sequenceKeyspace) Release() {
+}
+
+func (b *sequenceKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *sequenceKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *sequenceKeyspace) Name() string {
+	return b.name
+}
+
+func (b *sequenceKeyspace) Count() (int64, errors.Error) {
+	count := int64(0)
+	err := b.forEach(func(namespace datastore.Namespace, sequence *datastore.Sequence) {
+		count++
+	})
+	if err != nil {
+		return 0, errors.NewSystemDatastoreError(err, "")
+	}
+	return count, nil
+}
+
+/*
+Call f for every sequence of every namespace in the actual datastore.
+Namespaces that do not support sequences are skipped.
+*/
+func (b *sequenceKeyspace) forEach(f func(datastore.Namespace, *datastore.Sequence)) errors.Error {
+	actualStore := b.namespace.store.actualStore
+	namespaceIds, err := actualStore.NamespaceIds()
+	if err != nil {
+		return err
+	}
+
+	for _, namespaceId := range namespaceIds {
+		namespace, err := actualStore.NamespaceById(namespaceId)
+		if err != nil {
+			return err
+		}
+
+		snamespace, ok := namespace.(datastore.SequenceNamespace)
+		if !ok {
+			continue
+		}
+
+		sequences, err := snamespace.Sequences()
+		if err != nil {
+			return err
+		}
+
+		for _, sequence := range sequences {
+			f(namespace, sequence)
+		}
+	}
+
+	return nil
+}
+
+func (b *sequenceKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.indexer, nil
+}
+
+func (b *sequenceKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *sequenceKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
+	var errs []errors.Error
+	rv := make([]value.AnnotatedPair, 0, len(keys))
+
+	for _, key := range keys {
+		item, err := b.fetchOne(key)
+		if err != nil {
+			if errs == nil {
+				errs = make([]errors.Error, 0, 1)
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		if item != nil {
+			rv = append(rv, value.AnnotatedPair{key, item})
+		}
+	}
+
+	return rv, errs
+}
+
+func (b *sequenceKeyspace) fetchOne(key string) (value.AnnotatedValue, errors.Error) {
+	ids := strings.SplitN(key, "/", 2)
+	if len(ids) != 2 {
+		return nil, nil
+	}
+
+	namespace, err := b.namespace.store.actualStore.NamespaceById(ids[0])
+	if err != nil {
+		return nil, err
+	}
+
+	snamespace, ok := namespace.(datastore.SequenceNamespace)
+	if !ok {
+		return nil, nil
+	}
+
+	sequences, err := snamespace.Sequences()
+	if err != nil {
+		return nil, err
+	}
+
+	var sequence *datastore.Sequence
+	for _, s := range sequences {
+		if s.Name == ids[1] {
+			sequence = s
+			break
+		}
+	}
+
+	if sequence == nil {
+		return nil, nil
+	}
+
+	// The sequence may have been dropped since it was listed
+	next, err := snamespace.SequencePosition(sequence.Name)
+	if err != nil {
+		return nil, nil
+	}
+
+	doc := value.NewAnnotatedValue(map[string]interface{}{
+		"name":         sequence.Name,
+		"namespace_id": namespace.Id(),
+		"datastore_id": b.namespace.store.actualStore.URL(),
+		"start":        sequence.Start,
+		"increment":    sequence.Increment,
+		"cache":        sequence.Cache,
+		"next_value":   next,
+	})
+
+	doc.SetAttachment("meta", map[string]interface{}{
+		"id": key,
+	})
+
+	return doc, nil
+}
+
+func newSequencesKeyspace(p *namespace) (*sequenceKeyspace, errors.Error) {
+	b := new(sequenceKeyspace)
+	b.namespace = p
+	b.name = KEYSPACE_NAME_SEQUENCES
+
+	primary := &sequenceIndex{name: "#primary", keyspace: b}
+	b.indexer = newSystemIndexer(b, primary)
+
+	return b, nil
+}
+
+func (b *sequenceKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *sequenceKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *sequenceKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *sequenceKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+type sequenceIndex struct {
+	name     string
+	keyspace *sequenceKeyspace
+}
+
+func (pi *sequenceIndex) KeyspaceId() string {
+	return pi.keyspace.Id()
+}
+
+func (pi *sequenceIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *sequenceIndex) Name() string {
+	return pi.name
+}
+
+func (pi *sequenceIndex) Type() datastore.IndexType {
+	return datastore.DEFAULT
+}
+
+func (pi *sequenceIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *sequenceIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *sequenceIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *sequenceIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *sequenceIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *sequenceIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *sequenceIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, "")
+}
+
+func (pi *sequenceIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	pi.ScanEntries(requestId, limit, cons, vector, conn)
+}
+
+func (pi *sequenceIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer close(conn.EntryChannel())
+
+	var numProduced int64 = 0
+	stopped := false
+	err := pi.keyspace.forEach(func(namespace datastore.Namespace, sequence *datastore.Sequence) {
+		if stopped || (limit > 0 && numProduced >= limit) {
+			return
+		}
+		key := fmt.Sprintf("%s/%s", namespace.Id(), sequence.Name)
+		entry := datastore.IndexEntry{PrimaryKey: key}
+		stopped = !conn.Send(&entry)
+		numProduced++
+	})
+	if err != nil {
+		conn.Error(errors.NewSystemDatastoreError(err, ""))
+	}
+}
@@ -0,0 +1,292 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+type triggerKeyspace struct {
+	namespace *namespace
+	name      string
+	indexer   datastore.Indexer
+}
+
+func (b *triggerKeyspace) Release() {
+}
+
+func (b *triggerKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *triggerKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *triggerKeyspace) Name() string {
+	return b.name
+}
+
+func (b *triggerKeyspace) Count() (int64, errors.Error) {
+	count := int64(0)
+	err := b.forEach(func(namespace datastore.Namespace, keyspace datastore.TriggerKeyspace,
+		trigger *datastore.Trigger) {
+		count++
+	})
+	if err != nil {
+		return 0, errors.NewSystemDatastoreError(err, "")
+	}
+	return count, nil
+}
+
+/*
+Call f for every trigger of every keyspace in the actual datastore.
+Keyspaces that do not support triggers are skipped.
+*/
+func (b *triggerKeyspace) forEach(f func(datastore.Namespace, datastore.TriggerKeyspace,
+	*datastore.Trigger)) errors.Error {
+	actualStore := b.namespace.store.actualStore
+	namespaceIds, err := actualStore.NamespaceIds()
+	if err != nil {
+		return err
+	}
+
+	for _, namespaceId := range namespaceIds {
+		namespace, err := actualStore.NamespaceById(namespaceId)
+		if err != nil {
+			return err
+		}
+
+		keyspaceIds, err := namespace.KeyspaceIds()
+		if err != nil {
+			return err
+		}
+
+		for _, keyspaceId := range keyspaceIds {
+			keyspace, err := namespace.KeyspaceById(keyspaceId)
+			if err != nil {
+				continue
+			}
+
+			tkeyspace, ok := keyspace.(datastore.TriggerKeyspace)
+			if !ok {
+				continue
+			}
+
+			triggers, err := tkeyspace.Triggers()
+			if err != nil {
+				return err
+			}
+
+			for _, trigger := range triggers {
+				f(namespace, tkeyspace, trigger)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *triggerKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.indexer, nil
+}
+
+func (b *triggerKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *triggerKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
+	var errs []errors.Error
+	rv := make([]value.AnnotatedPair, 0, len(keys))
+
+	for _, key := range keys {
+		item, err := b.fetchOne(key)
+		if err != nil {
+			if errs == nil {
+				errs = make([]errors.Error, 0, 1)
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		if item != nil {
+			rv = append(rv, value.AnnotatedPair{key, item})
+		}
+	}
+
+	return rv, errs
+}
+
+func (b *triggerKeyspace) fetchOne(key string) (value.AnnotatedValue, errors.Error) {
+	ids := strings.SplitN(key, "/", 3)
+	if len(ids) != 3 {
+		return nil, nil
+	}
+
+	namespace, err := b.namespace.store.actualStore.NamespaceById(ids[0])
+	if err != nil {
+		return nil, err
+	}
+
+	keyspace, err := namespace.KeyspaceById(ids[1])
+	if err != nil {
+		return nil, err
+	}
+
+	tkeyspace, ok := keyspace.(datastore.TriggerKeyspace)
+	if !ok {
+		return nil, nil
+	}
+
+	triggers, err := tkeyspace.Triggers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, trigger := range triggers {
+		if trigger.Name != ids[2] {
+			continue
+		}
+
+		events := make([]interface{}, len(trigger.Events))
+		for i, event := range trigger.Events {
+			events[i] = event
+		}
+
+		doc := value.NewAnnotatedValue(map[string]interface{}{
+			"name":         trigger.Name,
+			"keyspace_id":  keyspace.Id(),
+			"namespace_id": namespace.Id(),
+			"datastore_id": b.namespace.store.actualStore.URL(),
+			"events":       events,
+			"statement":    trigger.Statement,
+			"async":        trigger.Async,
+			"on_error":     trigger.OnError,
+		})
+
+		doc.SetAttachment("meta", map[string]interface{}{
+			"id": key,
+		})
+
+		return doc, nil
+	}
+
+	return nil, nil
+}
+
+func newTriggersKeyspace(p *namespace) (*triggerKeyspace, errors.Error) {
+	b := new(triggerKeyspace)
+	b.namespace = p
+	b.name = KEYSPACE_NAME_TRIGGERS
+
+	primary := &triggerIndex{name: "#primary", keyspace: b}
+	b.indexer = newSystemIndexer(b, primary)
+
+	return b, nil
+}
+
+func (b *triggerKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *triggerKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *triggerKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *triggerKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+type triggerIndex struct {
+	name     string
+	keyspace *triggerKeyspace
+}
+
+func (pi *triggerIndex) KeyspaceId() string {
+	return pi.keyspace.Id()
+}
+
+func (pi *triggerIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *triggerIndex) Name() string {
+	return pi.name
+}
+
+func (pi *triggerIndex) Type() datastore.IndexType {
+	return datastore.DEFAULT
+}
+
+func (pi *triggerIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *triggerIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *triggerIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *triggerIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *triggerIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *triggerIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *triggerIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, "")
+}
+
+func (pi *triggerIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	pi.ScanEntries(requestId, limit, cons, vector, conn)
+}
+
+func (pi *triggerIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer close(conn.EntryChannel())
+
+	var numProduced int64 = 0
+	stopped := false
+	err := pi.keyspace.forEach(func(namespace datastore.Namespace, keyspace datastore.TriggerKeyspace,
+		trigger *datastore.Trigger) {
+		if stopped || (limit > 0 && numProduced >= limit) {
+			return
+		}
+		key := fmt.Sprintf("%s/%s/%s", namespace.Id(), keyspace.Id(), trigger.Name)
+		entry := datastore.IndexEntry{PrimaryKey: key}
+		stopped = !conn.Send(&entry)
+		numProduced++
+	})
+	if err != nil {
+		conn.Error(errors.NewSystemDatastoreError(err, ""))
+	}
+}
@@ -0,0 +1,255 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+type viewKeyspace struct {
+	namespace *namespace
+	name      string
+	indexer   datastore.Indexer
+}
+
+func (b *viewKeyspace) Release() {
+}
+
+func (b *viewKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *viewKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *viewKeyspace) Name() string {
+	return b.name
+}
+
+func (b *viewKeyspace) Count() (int64, errors.Error) {
+	count := int64(0)
+	err := b.forEach(func(namespace datastore.Namespace, view *datastore.NamedView) {
+		count++
+	})
+	if err != nil {
+		return 0, errors.NewSystemDatastoreError(err, "")
+	}
+	return count, nil
+}
+
+/*
+Call f for every named view of every namespace in the actual
+datastore. Namespaces that do not support named views are skipped.
+*/
+func (b *viewKeyspace) forEach(f func(datastore.Namespace, *datastore.NamedView)) errors.Error {
+	actualStore := b.namespace.store.actualStore
+	namespaceIds, err := actualStore.NamespaceIds()
+	if err != nil {
+		return err
+	}
+
+	for _, namespaceId := range namespaceIds {
+		namespace, err := actualStore.NamespaceById(namespaceId)
+		if err != nil {
+			return err
+		}
+
+		vnamespace, ok := namespace.(datastore.NamedViewNamespace)
+		if !ok {
+			continue
+		}
+
+		views, err := vnamespace.NamedViews()
+		if err != nil {
+			return err
+		}
+
+		for _, view := range views {
+			f(namespace, view)
+		}
+	}
+
+	return nil
+}
+
+func (b *viewKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.indexer, nil
+}
+
+func (b *viewKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *viewKeyspace) Fetch(keys []string, context datastore.QueryContext) ([]value.AnnotatedPair, []errors.Error) {
+	var errs []errors.Error
+	rv := make([]value.AnnotatedPair, 0, len(keys))
+
+	for _, key := range keys {
+		item, err := b.fetchOne(key)
+		if err != nil {
+			if errs == nil {
+				errs = make([]errors.Error, 0, 1)
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		if item != nil {
+			rv = append(rv, value.AnnotatedPair{key, item})
+		}
+	}
+
+	return rv, errs
+}
+
+func (b *viewKeyspace) fetchOne(key string) (value.AnnotatedValue, errors.Error) {
+	ids := strings.SplitN(key, "/", 2)
+	if len(ids) != 2 {
+		return nil, nil
+	}
+
+	namespace, err := b.namespace.store.actualStore.NamespaceById(ids[0])
+	if err != nil {
+		return nil, err
+	}
+
+	vnamespace, ok := namespace.(datastore.NamedViewNamespace)
+	if !ok {
+		return nil, nil
+	}
+
+	view, err := vnamespace.NamedViewByName(ids[1])
+	if err != nil || view == nil {
+		return nil, err
+	}
+
+	doc := value.NewAnnotatedValue(map[string]interface{}{
+		"name":         view.Name,
+		"namespace_id": namespace.Id(),
+		"datastore_id": b.namespace.store.actualStore.URL(),
+		"statement":    view.Statement,
+	})
+
+	doc.SetAttachment("meta", map[string]interface{}{
+		"id": key,
+	})
+
+	return doc, nil
+}
+
+func newViewsKeyspace(p *namespace) (*viewKeyspace, errors.Error) {
+	b := new(viewKeyspace)
+	b.namespace = p
+	b.name = KEYSPACE_NAME_VIEWS
+
+	primary := &viewIndex{name: "#primary", keyspace: b}
+	b.indexer = newSystemIndexer(b, primary)
+
+	return b, nil
+}
+
+func (b *viewKeyspace) Insert(inserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *viewKeyspace) Update(updates []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *viewKeyspace) Upsert(upserts []value.Pair, context datastore.QueryContext) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *viewKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+type viewIndex struct {
+	name     string
+	keyspace *viewKeyspace
+}
+
+func (pi *viewIndex) KeyspaceId() string {
+	return pi.keyspace.Id()
+}
+
+func (pi *viewIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *viewIndex) Name() string {
+	return pi.name
+}
+
+func (pi *viewIndex) Type() datastore.IndexType {
+	return datastore.DEFAULT
+}
+
+func (pi *viewIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *viewIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *viewIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *viewIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *viewIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *viewIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *viewIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, "")
+}
+
+func (pi *viewIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	pi.ScanEntries(requestId, limit, cons, vector, conn)
+}
+
+func (pi *viewIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer close(conn.EntryChannel())
+
+	var numProduced int64 = 0
+	stopped := false
+	err := pi.keyspace.forEach(func(namespace datastore.Namespace, view *datastore.NamedView) {
+		if stopped || (limit > 0 && numProduced >= limit) {
+			return
+		}
+		key := fmt.Sprintf("%s/%s", namespace.Id(), view.Name)
+		entry := datastore.IndexEntry{PrimaryKey: key}
+		stopped = !conn.Send(&entry)
+		numProduced++
+	})
+	if err != nil {
+		conn.Error(errors.NewSystemDatastoreError(err, ""))
+	}
+}
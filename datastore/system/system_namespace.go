@@ -126,5 +126,29 @@ func (p *namespace) loadKeyspaces() (e errors.Error) {
 	}
 	p.keyspaces[actives.Name()] = actives
 
+	triggers, e := newTriggersKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[triggers.Name()] = triggers
+
+	views, e := newViewsKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[views.Name()] = views
+
+	sequences, e := newSequencesKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[sequences.Name()] = sequences
+
+	resultCache, e := newResultCacheKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[resultCache.Name()] = resultCache
+
 	return nil
 }
@@ -94,7 +94,7 @@ func TestSystem(t *testing.T) {
 	}
 
 	// Fetch on the keyspaces keyspace - expect to find a value for this key:
-	vals, errs := bb.Fetch([]string{"p0/b1"})
+	vals, errs := bb.Fetch([]string{"p0/b1"}, datastore.NULL_QUERY_CONTEXT)
 	if errs != nil {
 		t.Fatalf("errors in key fetch %v", errs)
 	}
@@ -104,7 +104,7 @@ func TestSystem(t *testing.T) {
 	}
 
 	// Fetch on the indexes keyspace - expect to find a value for this key:
-	vals, errs = ib.Fetch([]string{"p0/b1/#primary"})
+	vals, errs = ib.Fetch([]string{"p0/b1/#primary"}, datastore.NULL_QUERY_CONTEXT)
 	if errs != nil {
 		t.Fatalf("errors in key fetch %v", errs)
 	}
@@ -114,7 +114,7 @@ func TestSystem(t *testing.T) {
 	}
 
 	// Fetch on the keyspaces keyspace - expect to not find a value for this key:
-	vals, errs = bb.Fetch([]string{"p0/b5"})
+	vals, errs = bb.Fetch([]string{"p0/b5"}, datastore.NULL_QUERY_CONTEXT)
 	if errs == nil {
 		t.Fatalf("Expected not found error for key fetch on %s", "p0/b5")
 	}
@@ -0,0 +1,64 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package datastore
+
+import (
+	"github.com/couchbase/query/errors"
+)
+
+// Trigger events
+const (
+	TRIGGER_INSERT = "insert"
+	TRIGGER_UPDATE = "update"
+	TRIGGER_UPSERT = "upsert"
+	TRIGGER_DELETE = "delete"
+)
+
+// What a failed trigger statement does to the mutating request
+const (
+	TRIGGER_ON_ERROR_FAIL   = "fail"
+	TRIGGER_ON_ERROR_WARN   = "warn"
+	TRIGGER_ON_ERROR_IGNORE = "ignore"
+)
+
+/*
+Trigger is a statement that runs after each mutation of a keyspace by
+one of the events. NEW and OLD are bound to the document after and
+before the mutation. Synchronous triggers run in the mutating request;
+asynchronous triggers are queued and run after it.
+*/
+type Trigger struct {
+	Name      string   `json:"name"`
+	Events    []string `json:"events"`
+	Statement string   `json:"statement"`
+	Async     bool     `json:"async,omitempty"`
+	OnError   string   `json:"on_error,omitempty"`
+}
+
+func (this *Trigger) HasEvent(event string) bool {
+	for _, e := range this.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+TriggerKeyspace is implemented by keyspaces that persist triggers.
+*/
+type TriggerKeyspace interface {
+	Keyspace
+
+	Triggers() ([]*Trigger, errors.Error)        // Ordered by name
+	CreateTrigger(trigger *Trigger) errors.Error // Fails if the name exists
+	DropTrigger(name string) errors.Error        // Fails if the name does not exist
+}
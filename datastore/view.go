@@ -0,0 +1,80 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package datastore
+
+import (
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+// How a materialized view is refreshed
+const (
+	VIEW_REFRESH_INCREMENTAL = "incremental"
+	VIEW_REFRESH_MANUAL      = "manual"
+)
+
+/*
+MaterializedView stores the results of a SELECT statement in a
+read-only keyspace. Incremental views are kept up to date from the
+mutations of their source keyspace; manual views change only when
+they are refreshed.
+*/
+type MaterializedView struct {
+	Name      string `json:"name"`
+	Statement string `json:"statement"`
+	Refresh   string `json:"refresh"`
+}
+
+/*
+ViewNamespace is implemented by namespaces that can store materialized
+views.
+*/
+type ViewNamespace interface {
+	Namespace
+
+	CreateView(view *MaterializedView) (ViewKeyspace, errors.Error) // Fails if the keyspace exists
+	DropView(name string) errors.Error                              // Fails if it is not a view
+}
+
+/*
+ViewKeyspace is implemented by the keyspaces of stores that support
+materialized views; View returns nil for the other keyspaces. DML on
+a view fails; its documents are set by the refresh methods.
+*/
+type ViewKeyspace interface {
+	Keyspace
+
+	View() *MaterializedView
+	ReplaceRows(rows []value.Pair) errors.Error                    // Replaces all the documents
+	ApplyRows(upserts []value.Pair, deletes []string) errors.Error // Changes some of the documents
+}
+
+/*
+NamedView is a SELECT statement published under a name. Queries use
+it in FROM like a keyspace; its statement is expanded when they are
+planned, and it stores no documents.
+*/
+type NamedView struct {
+	Name      string `json:"name"`
+	Statement string `json:"statement"`
+}
+
+/*
+NamedViewNamespace is implemented by namespaces that can store named
+views. Named views share the names of the keyspaces of the namespace.
+*/
+type NamedViewNamespace interface {
+	Namespace
+
+	NamedViews() ([]*NamedView, errors.Error)               // Sorted by name
+	NamedViewByName(name string) (*NamedView, errors.Error) // Returns nil if there is no such view
+	CreateNamedView(view *NamedView) errors.Error           // Fails if the name is taken
+	DropNamedView(name string) errors.Error
+}
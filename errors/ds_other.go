@@ -11,6 +11,7 @@ package errors
 
 import (
 	"fmt"
+	"time"
 )
 
 func NewIndexScanSizeError(size int64) Error {
@@ -60,6 +61,17 @@ func NewOtherKeyNotFoundError(e error, msg string) Error {
 		InternalMsg: "Key not found " + msg, InternalCaller: CallerN(1)}
 }
 
+func NewOtherKeyExistsError(e error, msg string) Error {
+	return &err{level: EXCEPTION, ICode: 16008, IKey: "datastore.other.key_exists", ICause: e,
+		InternalMsg: "Duplicate key " + msg, InternalCaller: CallerN(1)}
+}
+
+func NewOtherScanWaitTimeoutError(seqno uint64, wait time.Duration) Error {
+	return &err{level: EXCEPTION, ICode: 16009, IKey: "datastore.other.scan_wait_timeout",
+		InternalMsg:    fmt.Sprintf("Index scan timed out after %v waiting for sequence number %d", wait, seqno),
+		InternalCaller: CallerN(1)}
+}
+
 func NewInferencerNotFoundError(e error, msg string) Error {
 	return &err{level: EXCEPTION, ICode: 16020, IKey: "datastore.other.inferencer_not_found", ICause: e,
 		InternalMsg: "Inferencer not found " + msg, InternalCaller: CallerN(1)}
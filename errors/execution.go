@@ -11,6 +11,7 @@ package errors
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/couchbase/query/value"
 )
@@ -118,3 +119,52 @@ func NewScanVectorTooManyScannedBuckets(buckets []string) Error {
 		InternalMsg: fmt.Sprintf("The scan_vector parameter should not be used for queries accessing more than one keyspace. "+
 			"Use scan_vectors instead. Keyspaces: %v", buckets), InternalCaller: CallerN(1)}
 }
+
+func NewTriggerError(e error, name string) Error {
+	return &err{level: EXCEPTION, ICode: 5200, IKey: "execution.trigger_error", ICause: e,
+		InternalMsg: fmt.Sprintf("Error executing trigger %s.", name), InternalCaller: CallerN(1)}
+}
+
+func NewTriggerDepthError(name string, depth int) Error {
+	return &err{level: EXCEPTION, ICode: 5210, IKey: "execution.trigger_depth",
+		InternalMsg:    fmt.Sprintf("Trigger %s exceeds the maximum trigger depth of %d.", name, depth),
+		InternalCaller: CallerN(1)}
+}
+
+func NewViewReadOnlyError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 5220, IKey: "execution.view_read_only",
+		InternalMsg: fmt.Sprintf("Materialized view %s is read-only.", name), InternalCaller: CallerN(1)}
+}
+
+func NewViewRefreshError(e error, name string) Error {
+	return &err{level: EXCEPTION, ICode: 5230, IKey: "execution.view_refresh_error", ICause: e,
+		InternalMsg: fmt.Sprintf("Error refreshing materialized view %s.", name), InternalCaller: CallerN(1)}
+}
+
+func NewSchemaViolationError(keyspace, key string, violations []string) Error {
+	msg := strings.Join(violations, " ")
+	if len(violations) > 3 {
+		msg = fmt.Sprintf("%s (and %d more)", strings.Join(violations[:3], " "), len(violations)-3)
+	}
+
+	return &err{level: EXCEPTION, ICode: 5240, IKey: "execution.schema_violation",
+		InternalMsg: fmt.Sprintf("Document %s does not match the schema of keyspace %s: %s",
+			key, keyspace, msg), InternalCaller: CallerN(1)}
+}
+
+func NewMergeMultipleMatchError(key string) Error {
+	return &err{level: EXCEPTION, ICode: 5250, IKey: "execution.merge_multiple_match",
+		InternalMsg:    fmt.Sprintf("Target document %s is matched by more than one MERGE source item.", key),
+		InternalCaller: CallerN(1)}
+}
+
+func NewExecutionStoppedError() Error {
+	return &err{level: EXCEPTION, ICode: 5260, IKey: "execution.stopped",
+		InternalMsg: "Request stopped before the operation completed.", InternalCaller: CallerN(1)}
+}
+
+func NewSequenceReadonlyError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 5270, IKey: "execution.sequence_read_only",
+		InternalMsg:    fmt.Sprintf("Sequence %s cannot be advanced by a read-only request.", name),
+		InternalCaller: CallerN(1)}
+}
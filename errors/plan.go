@@ -108,3 +108,133 @@ func NewIndexAlreadyExistsError(idx string) Error {
 		InternalMsg:    fmt.Sprintf("The index %s already exists.", idx),
 		InternalCaller: CallerN(1)}
 }
+
+func NewTriggerAlreadyExistsError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 4310, IKey: "plan.new_trigger_already_exists",
+		InternalMsg: fmt.Sprintf("The trigger %s already exists.", name), InternalCaller: CallerN(1)}
+}
+
+func NewTriggerNotFoundError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 4320, IKey: "plan.trigger_not_found",
+		InternalMsg: fmt.Sprintf("The trigger %s does not exist.", name), InternalCaller: CallerN(1)}
+}
+
+func NewTriggersNotSupportedError(keyspace string) Error {
+	return &err{level: EXCEPTION, ICode: 4330, IKey: "plan.triggers_not_supported",
+		InternalMsg: fmt.Sprintf("Keyspace %s does not support triggers.", keyspace), InternalCaller: CallerN(1)}
+}
+
+func NewTriggerOptionError(option string) Error {
+	return &err{level: EXCEPTION, ICode: 4340, IKey: "plan.trigger_option",
+		InternalMsg: fmt.Sprintf("Invalid trigger option %s.", option), InternalCaller: CallerN(1)}
+}
+
+func NewViewAlreadyExistsError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 4350, IKey: "plan.new_view_already_exists",
+		InternalMsg: fmt.Sprintf("The keyspace or materialized view %s already exists.", name), InternalCaller: CallerN(1)}
+}
+
+func NewViewNotFoundError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 4360, IKey: "plan.view_not_found",
+		InternalMsg: fmt.Sprintf("The materialized view %s does not exist.", name), InternalCaller: CallerN(1)}
+}
+
+func NewViewsNotSupportedError(namespace string) Error {
+	return &err{level: EXCEPTION, ICode: 4370, IKey: "plan.views_not_supported",
+		InternalMsg: fmt.Sprintf("Namespace %s does not support materialized views.", namespace), InternalCaller: CallerN(1)}
+}
+
+func NewViewOptionError(option string) Error {
+	return &err{level: EXCEPTION, ICode: 4380, IKey: "plan.view_option",
+		InternalMsg: fmt.Sprintf("Invalid materialized view option %s.", option), InternalCaller: CallerN(1)}
+}
+
+func NewViewNotIncrementalError(reason string) Error {
+	return &err{level: EXCEPTION, ICode: 4390, IKey: "plan.view_not_incremental",
+		InternalMsg: "Materialized view cannot be refreshed incrementally: " + reason, InternalCaller: CallerN(1)}
+}
+
+func NewNamedViewAlreadyExistsError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 4400, IKey: "plan.new_named_view_already_exists",
+		InternalMsg: fmt.Sprintf("The keyspace or view %s already exists.", name), InternalCaller: CallerN(1)}
+}
+
+func NewNamedViewNotFoundError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 4410, IKey: "plan.named_view_not_found",
+		InternalMsg: fmt.Sprintf("The view %s does not exist.", name), InternalCaller: CallerN(1)}
+}
+
+func NewNamedViewsNotSupportedError(namespace string) Error {
+	return &err{level: EXCEPTION, ICode: 4420, IKey: "plan.named_views_not_supported",
+		InternalMsg: fmt.Sprintf("Namespace %s does not support views.", namespace), InternalCaller: CallerN(1)}
+}
+
+func NewNamedViewError(e error, name string) Error {
+	return &err{level: EXCEPTION, ICode: 4430, IKey: "plan.named_view", ICause: e,
+		InternalMsg: fmt.Sprintf("Error expanding view %s.", name), InternalCaller: CallerN(1)}
+}
+
+func NewSequenceAlreadyExistsError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 4440, IKey: "plan.new_sequence_already_exists",
+		InternalMsg: fmt.Sprintf("The sequence %s already exists.", name), InternalCaller: CallerN(1)}
+}
+
+func NewSequenceNotFoundError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 4450, IKey: "plan.sequence_not_found",
+		InternalMsg: fmt.Sprintf("The sequence %s does not exist.", name), InternalCaller: CallerN(1)}
+}
+
+func NewSequencesNotSupportedError(namespace string) Error {
+	return &err{level: EXCEPTION, ICode: 4460, IKey: "plan.sequences_not_supported",
+		InternalMsg: fmt.Sprintf("Namespace %s does not support sequences.", namespace), InternalCaller: CallerN(1)}
+}
+
+func NewSequenceOptionError(option string) Error {
+	return &err{level: EXCEPTION, ICode: 4470, IKey: "plan.sequence_option",
+		InternalMsg: fmt.Sprintf("Invalid sequence option %s.", option), InternalCaller: CallerN(1)}
+}
+
+func NewSchemaError(e error, keyspace string) Error {
+	return &err{level: EXCEPTION, ICode: 4480, IKey: "plan.schema_error", ICause: e,
+		InternalMsg: fmt.Sprintf("Invalid schema for keyspace %s.", keyspace), InternalCaller: CallerN(1)}
+}
+
+func NewSchemasNotSupportedError(keyspace string) Error {
+	return &err{level: EXCEPTION, ICode: 4490, IKey: "plan.schemas_not_supported",
+		InternalMsg: fmt.Sprintf("Keyspace %s does not support schemas.", keyspace), InternalCaller: CallerN(1)}
+}
+
+func NewSchemaNotFoundError(keyspace string) Error {
+	return &err{level: EXCEPTION, ICode: 4500, IKey: "plan.schema_not_found",
+		InternalMsg: fmt.Sprintf("Keyspace %s has no schema.", keyspace), InternalCaller: CallerN(1)}
+}
+
+func NewAdviseError(msg string) Error {
+	return &err{level: EXCEPTION, ICode: 4510, IKey: "plan.advise_error",
+		InternalMsg: msg, InternalCaller: CallerN(1)}
+}
+
+func NewOptimHintWarning(hint, reason string) Error {
+	return &err{level: WARNING, ICode: 4520, IKey: "plan.optim_hint",
+		InternalMsg: fmt.Sprintf("Optimizer hint %s is not followed: %s.", hint, reason), InternalCaller: CallerN(1)}
+}
+
+func NewInvalidOptimHintWarning(hint, reason string) Error {
+	return &err{level: WARNING, ICode: 4530, IKey: "plan.invalid_optim_hint",
+		InternalMsg: fmt.Sprintf("Invalid optimizer hint %s: %s.", hint, reason), InternalCaller: CallerN(1)}
+}
+
+func NewPlanBaselineError(msg string) Error {
+	return &err{level: EXCEPTION, ICode: 4540, IKey: "plan.baseline_error",
+		InternalMsg: msg, InternalCaller: CallerN(1)}
+}
+
+func NewPlanBaselineNotFoundError(statement string) Error {
+	return &err{level: EXCEPTION, ICode: 4550, IKey: "plan.baseline_not_found",
+		InternalMsg: fmt.Sprintf("There is no plan baseline for %s", statement), InternalCaller: CallerN(1)}
+}
+
+func NewInvalidPlanBaselineWarning(reason string) Error {
+	return &err{level: WARNING, ICode: 4560, IKey: "plan.invalid_baseline",
+		InternalMsg: fmt.Sprintf("The plan baseline is not used: %s.", reason), InternalCaller: CallerN(1)}
+}
@@ -98,3 +98,14 @@ func NewServiceErrorDecodeNil() Error {
 	return &err{level: EXCEPTION, ICode: 1160, IKey: "service.io.request.type",
 		InternalMsg: "Failed to decode nil value.", InternalCaller: CallerN(1)}
 }
+
+func NewServiceErrorChangesPosition(keyspace string, seqno uint64) Error {
+	return &err{level: EXCEPTION, ICode: 1170, IKey: "service.changes.position",
+		InternalMsg: fmt.Sprintf("Changes to %s after sequence number %d are no longer available.",
+			keyspace, seqno), InternalCaller: CallerN(1)}
+}
+
+func NewServiceErrorChangesOverflow() Error {
+	return &err{level: EXCEPTION, ICode: 1180, IKey: "service.changes.overflow",
+		InternalMsg: "Change subscription fell too far behind and was closed.", InternalCaller: CallerN(1)}
+}
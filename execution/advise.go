@@ -0,0 +1,274 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"sort"
+	"time"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/planner"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Advise produces the index advice computed by the planner, one item
+per keyspace term of the advised statement.
+*/
+type Advise struct {
+	base
+	plan *plan.Advise
+}
+
+func NewAdvise(plan *plan.Advise) *Advise {
+	rv := &Advise{
+		base: newBase(),
+		plan: plan,
+	}
+
+	rv.output = rv
+	return rv
+}
+
+func (this *Advise) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitAdvise(this)
+}
+
+func (this *Advise) Copy() Operator {
+	return &Advise{this.base.copy(), this.plan}
+}
+
+func (this *Advise) RunOnce(context *Context, parent value.Value) {
+	this.once.Do(func() {
+		defer context.Recover()       // Recover from any panic
+		defer close(this.itemChannel) // Broadcast that I have stopped
+		defer this.notify()           // Notify that I have stopped
+
+		advice := this.plan.Advice()
+		for i := 0; advice != nil; i++ {
+			item, ok := advice.Index(i)
+			if !ok {
+				break
+			}
+
+			if !this.sendItem(value.NewAnnotatedValue(item)) {
+				break
+			}
+		}
+	})
+}
+
+/*
+Maximum number of sample queries reported per recommended index.
+*/
+const _ADVISE_QUERIES = 5
+
+/*
+AdviseRequests consumes completed requests, advises their SELECT,
+UPDATE and DELETE statements, and produces the recommended indexes
+with the number and total elapsed time of the requests that would
+benefit, most frequent first.
+*/
+type AdviseRequests struct {
+	base
+	plan    *plan.AdviseRequests
+	advice  map[adviseText]value.Value
+	indexes map[string]*adviseIndex
+}
+
+type adviseText struct {
+	text     string
+	prepared bool
+}
+
+type adviseIndex struct {
+	statement string
+	kind      string
+	keyspace  string
+	count     int
+	elapsed   time.Duration
+	queries   []interface{}
+}
+
+func NewAdviseRequests(plan *plan.AdviseRequests) *AdviseRequests {
+	rv := &AdviseRequests{
+		base: newBase(),
+		plan: plan,
+	}
+
+	rv.output = rv
+	return rv
+}
+
+func (this *AdviseRequests) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitAdviseRequests(this)
+}
+
+func (this *AdviseRequests) Copy() Operator {
+	return &AdviseRequests{this.base.copy(), this.plan, nil, nil}
+}
+
+func (this *AdviseRequests) RunOnce(context *Context, parent value.Value) {
+	this.runConsumer(this, context, parent)
+}
+
+func (this *AdviseRequests) beforeItems(context *Context, parent value.Value) bool {
+	this.advice = make(map[adviseText]value.Value, 64)
+	this.indexes = make(map[string]*adviseIndex, 64)
+	return true
+}
+
+func (this *AdviseRequests) processItem(item value.AnnotatedValue, context *Context) bool {
+	var key adviseText
+	if v, ok := item.Field("prepared_text"); ok && v.Type() == value.STRING {
+		key = adviseText{v.Actual().(string), true}
+	} else if v, ok := item.Field("statement"); ok && v.Type() == value.STRING {
+		key = adviseText{v.Actual().(string), false}
+	} else {
+		return true
+	}
+
+	var elapsed time.Duration
+	if v, ok := item.Field("elapsed_time"); ok && v.Type() == value.STRING {
+		elapsed, _ = time.ParseDuration(v.Actual().(string))
+	}
+
+	advice, ok := this.advice[key]
+	if !ok {
+		advice = adviseStatement(context, key.text, key.prepared)
+		this.advice[key] = advice
+	}
+
+	for i := 0; advice != nil; i++ {
+		term, ok := advice.Index(i)
+		if !ok {
+			break
+		}
+
+		keyspace, _ := term.Field("keyspace")
+		recs, _ := term.Field("recommendations")
+		for j := 0; recs != nil; j++ {
+			rec, ok := recs.Index(j)
+			if !ok {
+				break
+			}
+
+			stmt, _ := rec.Field("statement")
+			s, ok := stmt.Actual().(string)
+			if !ok {
+				continue
+			}
+
+			index, ok := this.indexes[s]
+			if !ok {
+				kind, _ := rec.Field("kind")
+				index = &adviseIndex{statement: s}
+				index.kind, _ = kind.Actual().(string)
+				index.keyspace, _ = keyspace.Actual().(string)
+				this.indexes[s] = index
+			}
+
+			index.count++
+			index.elapsed += elapsed
+			if len(index.queries) < _ADVISE_QUERIES && !hasQuery(index.queries, key.text) {
+				index.queries = append(index.queries, key.text)
+			}
+		}
+	}
+
+	return true
+}
+
+func (this *AdviseRequests) afterItems(context *Context) {
+	indexes := make(adviseIndexes, 0, len(this.indexes))
+	for _, index := range this.indexes {
+		indexes = append(indexes, index)
+	}
+
+	sort.Sort(indexes)
+
+	for _, index := range indexes {
+		item := value.NewAnnotatedValue(map[string]interface{}{
+			"statement":          index.statement,
+			"kind":               index.kind,
+			"keyspace":           index.keyspace,
+			"count":              index.count,
+			"total_elapsed_time": index.elapsed.String(),
+			"queries":            index.queries,
+		})
+
+		if !this.sendItem(item) {
+			return
+		}
+	}
+}
+
+/*
+adviseStatement returns the index advice for a statement text, or nil
+if the statement cannot be advised. The text of a prepared statement
+is the PREPARE statement.
+*/
+func adviseStatement(context *Context, text string, prepared bool) value.Value {
+	stmt, err := n1ql.ParseStatement(text)
+	if err != nil {
+		return nil
+	}
+
+	if prepare, ok := stmt.(*algebra.Prepare); ok && prepared {
+		stmt = prepare.Statement()
+	}
+
+	switch stmt.(type) {
+	case *algebra.Select, *algebra.Update, *algebra.Delete:
+	default:
+		return nil
+	}
+
+	advice, err := planner.Advise(stmt, context.datastore, context.systemstore, context.namespace)
+	if err != nil {
+		return nil
+	}
+
+	return advice
+}
+
+func hasQuery(queries []interface{}, text string) bool {
+	for _, query := range queries {
+		if query == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+type adviseIndexes []*adviseIndex
+
+func (this adviseIndexes) Len() int {
+	return len(this)
+}
+
+func (this adviseIndexes) Less(i, j int) bool {
+	if this[i].count != this[j].count {
+		return this[i].count > this[j].count
+	}
+
+	if this[i].elapsed != this[j].elapsed {
+		return this[i].elapsed > this[j].elapsed
+	}
+
+	return this[i].statement < this[j].statement
+}
+
+func (this adviseIndexes) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+}
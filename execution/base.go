@@ -24,6 +24,7 @@ import (
 type base struct {
 	itemChannel value.AnnotatedChannel
 	stopChannel StopChannel // Never closed
+	stopped     bool        // Set once the stop is received; later sends fail
 	input       Operator
 	output      Operator
 	stop        Operator
@@ -131,8 +132,13 @@ func (this *base) sendItem(item value.AnnotatedValue) bool {
 	}
 	defer addTime()
 
+	if this.stopped {
+		return false
+	}
+
 	select {
 	case <-this.stopChannel: // Never closed
+		this.stopped = true
 		return false
 	default:
 	}
@@ -141,6 +147,7 @@ func (this *base) sendItem(item value.AnnotatedValue) bool {
 	case this.output.ItemChannel() <- item:
 		return true
 	case <-this.stopChannel: // Never closed
+		this.stopped = true
 		return false
 	}
 }
@@ -176,6 +183,7 @@ func (this *base) runConsumer(cons consumer, context *Context, parent value.Valu
 			select {
 			case <-this.stopChannel: // Never closed
 				this.chanTime += time.Since(t)
+				this.stopped = true
 				break loop
 			default:
 			}
@@ -187,6 +195,7 @@ func (this *base) runConsumer(cons consumer, context *Context, parent value.Valu
 				}
 			case <-this.stopChannel: // Never closed
 				this.chanTime += time.Since(t)
+				this.stopped = true
 				break loop
 			}
 			this.chanTime += time.Since(t)
@@ -174,6 +174,14 @@ func (this *builder) VisitIndexNest(plan *plan.IndexNest) (interface{}, error) {
 	return NewIndexNest(plan), nil
 }
 
+func (this *builder) VisitSemiJoin(plan *plan.SemiJoin) (interface{}, error) {
+	return NewSemiJoin(plan), nil
+}
+
+func (this *builder) VisitGroupJoin(plan *plan.GroupJoin) (interface{}, error) {
+	return NewGroupJoin(plan), nil
+}
+
 func (this *builder) VisitUnnest(plan *plan.Unnest) (interface{}, error) {
 	return NewUnnest(plan), nil
 }
@@ -359,6 +367,16 @@ func (this *builder) VisitAuthorize(plan *plan.Authorize) (interface{}, error) {
 	return NewAuthorize(plan, child.(Operator)), nil
 }
 
+// OptimHints
+func (this *builder) VisitOptimHints(plan *plan.OptimHints) (interface{}, error) {
+	child, err := plan.Child().Accept(this)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewOptimHints(plan, child.(Operator)), nil
+}
+
 // Parallel
 func (this *builder) VisitParallel(plan *plan.Parallel) (interface{}, error) {
 	child, err := plan.Child().Accept(this)
@@ -431,6 +449,80 @@ func (this *builder) VisitBuildIndexes(plan *plan.BuildIndexes) (interface{}, er
 	return NewBuildIndexes(plan), nil
 }
 
+// CreateTrigger
+func (this *builder) VisitCreateTrigger(plan *plan.CreateTrigger) (interface{}, error) {
+	return NewCreateTrigger(plan), nil
+}
+
+// DropTrigger
+func (this *builder) VisitDropTrigger(plan *plan.DropTrigger) (interface{}, error) {
+	return NewDropTrigger(plan), nil
+}
+
+// CreateMaterializedView
+func (this *builder) VisitCreateMaterializedView(plan *plan.CreateMaterializedView) (interface{}, error) {
+	return NewCreateMaterializedView(plan), nil
+}
+
+// RefreshMaterializedView
+func (this *builder) VisitRefreshMaterializedView(plan *plan.RefreshMaterializedView) (interface{}, error) {
+	return NewRefreshMaterializedView(plan), nil
+}
+
+// DropMaterializedView
+func (this *builder) VisitDropMaterializedView(plan *plan.DropMaterializedView) (interface{}, error) {
+	return NewDropMaterializedView(plan), nil
+}
+
+// CreateView
+func (this *builder) VisitCreateView(plan *plan.CreateView) (interface{}, error) {
+	return NewCreateView(plan), nil
+}
+
+// DropView
+func (this *builder) VisitDropView(plan *plan.DropView) (interface{}, error) {
+	return NewDropView(plan), nil
+}
+
+// CreateSequence
+func (this *builder) VisitCreateSequence(plan *plan.CreateSequence) (interface{}, error) {
+	return NewCreateSequence(plan), nil
+}
+
+// DropSequence
+func (this *builder) VisitDropSequence(plan *plan.DropSequence) (interface{}, error) {
+	return NewDropSequence(plan), nil
+}
+
+// CreatePlanBaseline
+func (this *builder) VisitCreatePlanBaseline(plan *plan.CreatePlanBaseline) (interface{}, error) {
+	return NewCreatePlanBaseline(plan), nil
+}
+
+// DropPlanBaseline
+func (this *builder) VisitDropPlanBaseline(plan *plan.DropPlanBaseline) (interface{}, error) {
+	return NewDropPlanBaseline(plan), nil
+}
+
+// AlterKeyspace
+func (this *builder) VisitAlterKeyspace(plan *plan.AlterKeyspace) (interface{}, error) {
+	return NewAlterKeyspace(plan), nil
+}
+
+// Validate
+func (this *builder) VisitValidate(plan *plan.Validate) (interface{}, error) {
+	return NewValidate(plan), nil
+}
+
+// Advise
+func (this *builder) VisitAdvise(plan *plan.Advise) (interface{}, error) {
+	return NewAdvise(plan), nil
+}
+
+func (this *builder) VisitAdviseRequests(plan *plan.AdviseRequests) (interface{}, error) {
+	return NewAdviseRequests(plan), nil
+}
+
 // Prepare
 func (this *builder) VisitPrepare(plan *plan.Prepare) (interface{}, error) {
 	return NewPrepare(plan.Prepared()), nil
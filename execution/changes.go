@@ -0,0 +1,39 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"github.com/couchbase/query/changes"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/value"
+)
+
+// Publish the mutations of the Send operators to change subscribers,
+// unless the keyspace publishes its own.
+
+func publishPairs(keyspace datastore.Keyspace, op string, pairs []value.Pair) {
+	if changes.Publishes(keyspace) {
+		return
+	}
+
+	for _, pair := range pairs {
+		changes.Publish(keyspace.NamespaceId(), keyspace.Name(), op, pair.Name, pair.Value)
+	}
+}
+
+func publishDeletes(keyspace datastore.Keyspace, keys []string, docs map[string]value.Value) {
+	if changes.Publishes(keyspace) {
+		return
+	}
+
+	for _, key := range keys {
+		changes.Publish(keyspace.NamespaceId(), keyspace.Name(), changes.DELETE, key, docs[key])
+	}
+}
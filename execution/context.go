@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/changes"
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/logging"
@@ -57,6 +58,7 @@ type Output interface {
 	Warning(wrn errors.Error)
 	AddMutationCount(uint64)
 	MutationCount() uint64
+	AddMutationToken(keyspace string, token timestamp.Entry)
 	SortCount() uint64
 	SetSortCount(i uint64)
 	AddPhaseOperator(p Phases)
@@ -81,9 +83,13 @@ type Context struct {
 	credentials      datastore.Credentials
 	consistency      datastore.ScanConsistency
 	scanVectorSource timestamp.ScanVectorSource
+	scanWait         time.Duration
 	output           Output
 	subplans         *subqueryMap
 	subresults       *subqueryMap
+	triggerDepth     int       // Nesting of the trigger running in this context
+	done             chan bool // Closed when the request is stopped
+	stopOnce         *sync.Once
 	mutex            sync.RWMutex
 }
 
@@ -107,6 +113,8 @@ func NewContext(requestId string, datastore, systemstore datastore.Datastore,
 		output:           output,
 		subplans:         nil,
 		subresults:       nil,
+		done:             make(chan bool),
+		stopOnce:         &sync.Once{},
 	}
 
 	if rv.maxParallelism <= 0 || rv.maxParallelism > runtime.NumCPU() {
@@ -172,6 +180,35 @@ func (this *Context) ScanVectorSource() timestamp.ScanVectorSource {
 	return this.scanVectorSource
 }
 
+func (this *Context) ScanWait() time.Duration {
+	return this.scanWait
+}
+
+func (this *Context) SetScanWait(scanWait time.Duration) {
+	this.scanWait = scanWait
+}
+
+/*
+Stop cancels the datastore operations of the request. Operators that
+see Err after a datastore call end without reporting its error.
+*/
+func (this *Context) Stop() {
+	this.stopOnce.Do(func() { close(this.done) })
+}
+
+func (this *Context) Done() <-chan bool {
+	return this.done
+}
+
+func (this *Context) Err() errors.Error {
+	select {
+	case <-this.done:
+		return errors.NewExecutionStoppedError()
+	default:
+		return nil
+	}
+}
+
 func (this *Context) AddMutationCount(i uint64) {
 	this.output.AddMutationCount(i)
 }
@@ -180,6 +217,20 @@ func (this *Context) MutationCount() uint64 {
 	return this.output.MutationCount()
 }
 
+/*
+AddMutationToken records the token of the last mutation of keyspace,
+if the keyspace numbers its mutations.
+*/
+func (this *Context) AddMutationToken(keyspace datastore.Keyspace) {
+	sequenced, ok := keyspace.(datastore.Sequenced)
+	if !ok {
+		return
+	}
+
+	name := changes.KeyspaceName(keyspace.NamespaceId(), keyspace.Name())
+	this.output.AddMutationToken(name, sequenced.MutationToken())
+}
+
 func (this *Context) SetSortCount(i uint64) {
 	this.output.SetSortCount(i)
 }
@@ -241,7 +292,40 @@ func (this *Context) EvaluateSubquery(query *algebra.Select, parent value.Value)
 		subplans.set(query, subplan)
 	}
 
-	pipeline, err := Build(subplan.(plan.Operator), this)
+	results, err := this.evaluatePlan(subplan.(plan.Operator), parent)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache results
+	if !query.IsCorrelated() {
+		subresults.set(query, results)
+	}
+
+	return results, nil
+}
+
+/*
+SetSubqueryPlans supplies the subquery plans built with the plan of
+the statement, so that subqueries are not planned when evaluated.
+*/
+func (this *Context) SetSubqueryPlans(subqueries plan.SubqueryPlans) {
+	if len(subqueries) == 0 {
+		return
+	}
+
+	subplans := this.getSubplans()
+	for _, subquery := range subqueries {
+		subplans.set(subquery.Query(), subquery.Operator())
+	}
+}
+
+/*
+evaluatePlan runs a query plan to completion, and returns the array
+of its results.
+*/
+func (this *Context) evaluatePlan(subplan plan.Operator, parent value.Value) (value.Value, error) {
+	pipeline, err := Build(subplan, this)
 	if err != nil {
 		return nil, err
 	}
@@ -251,20 +335,19 @@ func (this *Context) EvaluateSubquery(query *algebra.Select, parent value.Value)
 	sequence := NewSequence(pipeline, collect)
 	sequence.RunOnce(this, parent)
 
-	// Await completion
-	ok = true
+	// Await completion, or stop the subquery with its request
+	stop := this.done
+	ok := true
 	for ok {
-		_, ok = <-collect.Output().ItemChannel()
-	}
-
-	results := collect.ValuesOnce()
-
-	// Cache results
-	if !planFound && !query.IsCorrelated() {
-		subresults.set(query, results)
+		select {
+		case _, ok = <-collect.Output().ItemChannel():
+		case <-stop:
+			notifyChildren(sequence)
+			stop = nil
+		}
 	}
 
-	return results, nil
+	return collect.ValuesOnce(), nil
 }
 
 func (this *Context) getSubplans() *subqueryMap {
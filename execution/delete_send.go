@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/couchbase/query/changes"
+	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/value"
@@ -93,6 +95,13 @@ func (this *SendDelete) flushBatch(context *Context) bool {
 	keys := _STRING_POOL.Get()
 	defer _STRING_POOL.Put(keys)
 
+	// Keep the deleted documents for change subscribers and triggers
+	var docs map[string]value.Value
+	if !changes.Publishes(this.plan.Keyspace()) ||
+		len(keyspaceTriggers(this.plan.Keyspace(), datastore.TRIGGER_DELETE)) > 0 {
+		docs = make(map[string]value.Value, len(this.batch))
+	}
+
 	for _, item := range this.batch {
 		dv, ok := item.Field(this.plan.Alias())
 		if !ok {
@@ -112,11 +121,14 @@ func (this *SendDelete) flushBatch(context *Context) bool {
 		}
 
 		keys = append(keys, key)
+		if docs != nil {
+			docs[key] = av
+		}
 	}
 
 	timer := time.Now()
 
-	deleted_keys, e := this.plan.Keyspace().Delete(keys)
+	deleted_keys, e := this.plan.Keyspace().Delete(keys, context)
 
 	t := time.Since(timer)
 	context.AddPhaseTime("delete", t)
@@ -124,11 +136,16 @@ func (this *SendDelete) flushBatch(context *Context) bool {
 
 	// Update mutation count with number of deleted docs:
 	context.AddMutationCount(uint64(len(deleted_keys)))
+	context.AddMutationToken(this.plan.Keyspace())
 
-	if e != nil {
+	// The error of a stopped request is not reported
+	if e != nil && context.Err() == nil {
 		context.Error(e)
 	}
 
+	publishDeletes(this.plan.Keyspace(), deleted_keys, docs)
+	runDeleteTriggers(context, this.plan.Keyspace(), deleted_keys, docs)
+
 	for _, item := range this.batch {
 		if !this.sendItem(item) {
 			return false
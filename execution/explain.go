@@ -44,7 +44,15 @@ func (this *Explain) RunOnce(context *Context, parent value.Value) {
 		defer close(this.itemChannel) // Broadcast that I have stopped
 		defer this.notify()           // Notify that I have stopped
 
-		bytes, err := this.plan.MarshalJSON()
+		var bytes []byte
+		var err error
+		explain, ok := this.plan.(*plan.Explain)
+		if ok && explain.Format() != "" {
+			bytes, err = plan.FormatExplain(explain)
+		} else {
+			bytes, err = this.plan.MarshalJSON()
+		}
+
 		if err != nil {
 			context.Fatal(errors.NewExplainError(err, "EXPLAIN: Error marshaling JSON."))
 			return
@@ -112,12 +112,16 @@ func (this *Fetch) flushBatch(context *Context) bool {
 	timer := time.Now()
 
 	// Fetch
-	pairs, errs := this.plan.Keyspace().Fetch(keys)
+	pairs, errs := this.plan.Keyspace().Fetch(keys, context)
 
 	t := time.Since(timer)
 	context.AddPhaseTime("fetch", t)
 	this.plan.AddTime(t)
 
+	if context.Err() != nil {
+		return false
+	}
+
 	fetchOk := true
 	for _, err := range errs {
 		context.Error(err)
@@ -142,6 +146,12 @@ func (this *Fetch) flushBatch(context *Context) bool {
 		}
 
 		item := batchMap[key]
+
+		// Carry the score of a full-text index scan
+		if smeta := item.GetAttachment("smeta"); smeta != nil {
+			fv.SetAttachment("smeta", smeta)
+		}
+
 		item.SetField(this.plan.Term().Alias(), fv)
 
 		if !this.sendItem(item) {
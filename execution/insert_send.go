@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/couchbase/query/changes"
+	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/value"
@@ -95,6 +97,7 @@ func (this *SendInsert) flushBatch(context *Context) bool {
 
 	keyExpr := this.plan.Key()
 	valExpr := this.plan.Value()
+	sch := keyspaceSchema(this.plan.Keyspace())
 	var key, val value.Value
 	var err error
 	var ok bool
@@ -144,17 +147,24 @@ func (this *SendInsert) flushBatch(context *Context) bool {
 			continue
 		}
 
+		if !validDocument(context, this.plan.Keyspace(), sch, dpair.Name, val) {
+			continue
+		}
+
 		dpair.Value = val
 		i++
 	}
 
 	dpairs = dpairs[0:i]
+	if len(dpairs) == 0 {
+		return true
+	}
 
 	timer := time.Now()
 
 	// Perform the actual INSERT
 	var er errors.Error
-	dpairs, er = this.plan.Keyspace().Insert(dpairs)
+	dpairs, er = this.plan.Keyspace().Insert(dpairs, context)
 
 	t := time.Since(timer)
 	context.AddPhaseTime("insert", t)
@@ -162,11 +172,16 @@ func (this *SendInsert) flushBatch(context *Context) bool {
 
 	// Update mutation count with number of inserted docs
 	context.AddMutationCount(uint64(len(dpairs)))
+	context.AddMutationToken(this.plan.Keyspace())
 
-	if er != nil {
+	// The error of a stopped request is not reported
+	if er != nil && context.Err() == nil {
 		context.Error(er)
 	}
 
+	publishPairs(this.plan.Keyspace(), changes.INSERT, dpairs)
+	runPairTriggers(context, this.plan.Keyspace(), datastore.TRIGGER_INSERT, dpairs, nil)
+
 	// Capture the inserted keys in case there is a RETURNING clause
 	for _, dp := range dpairs {
 		dv := value.NewAnnotatedValue(dp.Value)
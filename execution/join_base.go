@@ -76,7 +76,10 @@ func (this *joinBase) joinFetch(keyspace datastore.Keyspace, keyCount map[string
 		}
 	}
 
-	pairs, errs := keyspace.Fetch(fetchKeys)
+	pairs, errs := keyspace.Fetch(fetchKeys, context)
+	if context.Err() != nil {
+		return false
+	}
 
 	fetchOk := true
 	for _, err := range errs {
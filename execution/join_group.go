@@ -0,0 +1,84 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+type GroupJoin struct {
+	base
+	plan  *plan.GroupJoin
+	table *hashTable
+}
+
+func NewGroupJoin(plan *plan.GroupJoin) *GroupJoin {
+	rv := &GroupJoin{
+		base:  newBase(),
+		plan:  plan,
+		table: &hashTable{},
+	}
+
+	rv.output = rv
+	return rv
+}
+
+func (this *GroupJoin) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitGroupJoin(this)
+}
+
+func (this *GroupJoin) Copy() Operator {
+	return &GroupJoin{this.base.copy(), this.plan, this.table}
+}
+
+func (this *GroupJoin) RunOnce(context *Context, parent value.Value) {
+	this.runConsumer(this, context, parent)
+}
+
+func (this *GroupJoin) beforeItems(context *Context, parent value.Value) bool {
+	n := len(this.plan.Keys())
+	return this.table.load(this.plan.Inner(), context,
+		func(row value.Value) (string, value.Value, bool) {
+			keys, _ := row.Index(0)
+			key, ok := hashKey(keys, n)
+			if !ok {
+				return "", nil, false
+			}
+
+			// The subquery result is the array of its single row
+			result, _ := row.Index(1)
+			return key, value.NewValue([]interface{}{result}), true
+		})
+}
+
+func (this *GroupJoin) processItem(item value.AnnotatedValue, context *Context) bool {
+	keys, e := evaluateKeys(this.plan.Keys(), item, context)
+	if e != nil {
+		context.Error(errors.NewEvaluationError(e, "group join keys"))
+		return false
+	}
+
+	result := this.plan.Empty()
+	key, ok := hashKey(keys, len(this.plan.Keys()))
+	if ok {
+		if vals, found := this.table.entries[key]; found {
+			result = vals[0]
+		}
+	}
+
+	cv := value.NewScopeValue(make(map[string]interface{}, 1), item)
+	gv := value.NewAnnotatedValue(cv)
+	gv.SetAnnotations(item)
+	gv.SetField(this.plan.As(), result)
+
+	return this.sendItem(gv)
+}
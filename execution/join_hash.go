@@ -0,0 +1,102 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+/*
+hashTable holds the rows of the inner plan of a semi-join, a grouped
+join or a merge, by their keys. It is loaded once, by the first copy
+of the join to run, and shared by all the copies.
+*/
+type hashTable struct {
+	once    sync.Once
+	loaded  bool
+	entries map[string][]value.Value
+}
+
+/*
+load evaluates the inner plan, and adds each of its rows to the
+table, as the entry returned by entry. Rows without an entry are
+skipped, and rows with the same key are kept in order.
+*/
+func (this *hashTable) load(inner plan.Operator, context *Context,
+	entry func(row value.Value) (string, value.Value, bool)) bool {
+	this.once.Do(func() {
+		results, err := context.evaluatePlan(inner, nil)
+		if err != nil {
+			context.Error(errors.NewEvaluationError(err, "join subquery"))
+			return
+		}
+
+		rows, _ := results.Actual().([]interface{})
+		this.entries = make(map[string][]value.Value, len(rows))
+		for _, row := range rows {
+			key, val, ok := entry(value.NewValue(row))
+			if ok {
+				this.entries[key] = append(this.entries[key], val)
+			}
+		}
+
+		this.loaded = true
+	})
+
+	return this.loaded
+}
+
+/*
+hashKey returns the key of an array of values. Values compare equal
+if their keys are equal. There is no key if any value is MISSING or
+NULL, since such values are equal to nothing.
+*/
+func hashKey(row value.Value, n int) (string, bool) {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		v, _ := row.Index(i)
+		if v == nil || v.Type() <= value.NULL {
+			return "", false
+		}
+
+		b, err := v.MarshalJSON()
+		if err != nil {
+			return "", false
+		}
+
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+
+	return buf.String(), true
+}
+
+/*
+evaluateKeys evaluates the join keys of an item, as an array.
+*/
+func evaluateKeys(keys expression.Expressions, item value.AnnotatedValue,
+	context *Context) (value.Value, error) {
+	vals := make([]interface{}, len(keys))
+	for i, key := range keys {
+		v, err := key.Evaluate(item, context)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+
+	return value.NewValue(vals), nil
+}
@@ -0,0 +1,74 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+type SemiJoin struct {
+	base
+	plan  *plan.SemiJoin
+	table *hashTable
+}
+
+func NewSemiJoin(plan *plan.SemiJoin) *SemiJoin {
+	rv := &SemiJoin{
+		base:  newBase(),
+		plan:  plan,
+		table: &hashTable{},
+	}
+
+	rv.output = rv
+	return rv
+}
+
+func (this *SemiJoin) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitSemiJoin(this)
+}
+
+func (this *SemiJoin) Copy() Operator {
+	return &SemiJoin{this.base.copy(), this.plan, this.table}
+}
+
+func (this *SemiJoin) RunOnce(context *Context, parent value.Value) {
+	this.runConsumer(this, context, parent)
+}
+
+func (this *SemiJoin) beforeItems(context *Context, parent value.Value) bool {
+	n := len(this.plan.Keys())
+	return this.table.load(this.plan.Inner(), context,
+		func(row value.Value) (string, value.Value, bool) {
+			key, ok := hashKey(row, n)
+			return key, value.TRUE_VALUE, ok
+		})
+}
+
+func (this *SemiJoin) processItem(item value.AnnotatedValue, context *Context) bool {
+	keys, e := evaluateKeys(this.plan.Keys(), item, context)
+	if e != nil {
+		context.Error(errors.NewEvaluationError(e, "semi-join keys"))
+		return false
+	}
+
+	matched := false
+	key, ok := hashKey(keys, len(this.plan.Keys()))
+	if ok {
+		_, matched = this.table.entries[key]
+	}
+
+	if matched != this.plan.Anti() {
+		return this.sendItem(item)
+	} else {
+		return true
+	}
+}
@@ -0,0 +1,56 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+type AlterKeyspace struct {
+	base
+	plan *plan.AlterKeyspace
+}
+
+func NewAlterKeyspace(plan *plan.AlterKeyspace) *AlterKeyspace {
+	rv := &AlterKeyspace{
+		base: newBase(),
+		plan: plan,
+	}
+
+	rv.output = rv
+	return rv
+}
+
+func (this *AlterKeyspace) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitAlterKeyspace(this)
+}
+
+func (this *AlterKeyspace) Copy() Operator {
+	return &AlterKeyspace{this.base.copy(), this.plan}
+}
+
+func (this *AlterKeyspace) RunOnce(context *Context, parent value.Value) {
+	this.once.Do(func() {
+		defer context.Recover()       // Recover from any panic
+		defer close(this.itemChannel) // Broadcast that I have stopped
+		defer this.notify()           // Notify that I have stopped
+
+		if context.Readonly() {
+			return
+		}
+
+		// Actually alter keyspace
+		err := this.plan.Keyspace().SetSchema(this.plan.Schema())
+		if err != nil {
+			context.Error(err)
+		}
+	})
+}
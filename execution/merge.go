@@ -11,6 +11,7 @@ package execution
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/couchbase/query/errors"
@@ -25,6 +26,14 @@ type Merge struct {
 	delete       Operator
 	insert       Operator
 	childChannel StopChannel
+	table        *hashTable    // Target documents, for an ON condition
+	matched      *mergeMatches // Target documents already matched
+	pending      []mergeAction // Actions held until all source items are matched
+}
+
+type mergeAction struct {
+	op   Operator
+	item value.AnnotatedValue
 }
 
 func NewMerge(plan *plan.Merge, update, delete, insert Operator) *Merge {
@@ -35,6 +44,8 @@ func NewMerge(plan *plan.Merge, update, delete, insert Operator) *Merge {
 		delete:       delete,
 		insert:       insert,
 		childChannel: make(StopChannel, 3),
+		table:        &hashTable{},
+		matched:      newMergeMatches(),
 	}
 
 	rv.output = rv
@@ -53,6 +64,8 @@ func (this *Merge) Copy() Operator {
 		delete:       copyOperator(this.delete),
 		insert:       copyOperator(this.insert),
 		childChannel: make(StopChannel, 3),
+		table:        this.table,
+		matched:      this.matched,
 	}
 }
 
@@ -72,6 +85,11 @@ func (this *Merge) RunOnce(context *Context, parent value.Value) {
 			return
 		}
 
+		// Load the target documents before any is mutated
+		if this.plan.Inner() != nil && !this.loadTargets(context) {
+			return
+		}
+
 		go this.input.RunOnce(context, parent)
 
 		update, updateInput := this.wrapChild(this.update)
@@ -104,10 +122,12 @@ func (this *Merge) RunOnce(context *Context, parent value.Value) {
 
 		var item value.AnnotatedValue
 		ok := true
+		matched := true
 	loop:
 		for ok {
 			select {
 			case <-this.stopChannel: // Never closed
+				matched = false
 				break loop
 			default:
 			}
@@ -118,13 +138,25 @@ func (this *Merge) RunOnce(context *Context, parent value.Value) {
 				this.chanTime += time.Since(t)
 				if ok {
 					ok = this.processMatch(item, context, update, delete, insert)
+					matched = ok
 				}
 			case <-this.stopChannel: // Never closed
 				this.chanTime += time.Since(t)
+				matched = false
 				break loop
 			}
 		}
 
+		// Perform the held actions, unless a target document was matched twice
+		if matched {
+			for _, action := range this.pending {
+				if !this.mergeSendItem(action.op, action.item) {
+					break
+				}
+			}
+		}
+		this.pending = nil
+
 		// Close child input Channels, which will signal children
 		for _, input := range inputs {
 			input.Close()
@@ -168,6 +200,10 @@ func (this *Merge) mergeSendItem(op Operator, item value.AnnotatedValue) bool {
 
 func (this *Merge) processMatch(item value.AnnotatedValue,
 	context *Context, update, delete, insert Operator) bool {
+	if this.plan.Key() == nil {
+		return this.processJoin(item, context, update, delete, insert)
+	}
+
 	kv, e := this.plan.Key().Evaluate(item, context)
 	if e != nil {
 		context.Error(errors.NewEvaluationError(e, "MERGE key"))
@@ -185,10 +221,14 @@ func (this *Merge) processMatch(item value.AnnotatedValue,
 	timer := time.Now()
 
 	ok = true
-	bvs, errs := this.plan.Keyspace().Fetch([]string{k})
+	bvs, errs := this.plan.Keyspace().Fetch([]string{k}, context)
 
 	this.duration += time.Since(timer)
 
+	if context.Err() != nil {
+		return false
+	}
+
 	for _, err := range errs {
 		context.Error(err)
 		if err.IsFatal() {
@@ -222,6 +262,100 @@ func (this *Merge) processMatch(item value.AnnotatedValue,
 	return ok
 }
 
+func (this *Merge) loadTargets(context *Context) bool {
+	n := len(this.plan.Keys())
+	return this.table.load(this.plan.Inner(), context,
+		func(row value.Value) (string, value.Value, bool) {
+			keys, _ := row.Index(0)
+			key, ok := hashKey(keys, n)
+			return key, row, ok
+		})
+}
+
+/*
+processJoin matches a source item to each target document that
+satisfies the ON condition, and inserts it if there is none. A target
+document that is updated or deleted may be matched only once; such a
+merge runs in a single copy and holds its actions until all source
+items are matched, so that a second match fails the statement before
+any mutation.
+*/
+func (this *Merge) processJoin(item value.AnnotatedValue,
+	context *Context, update, delete, insert Operator) bool {
+	keys, e := evaluateKeys(this.plan.Keys(), item, context)
+	if e != nil {
+		context.Error(errors.NewEvaluationError(e, "MERGE keys"))
+		return false
+	}
+
+	var rows []value.Value
+	key, ok := hashKey(keys, len(this.plan.Keys()))
+	if ok {
+		rows = this.table.entries[key]
+	}
+
+	alias := this.plan.KeyspaceRef().Alias()
+	hold := update != nil || delete != nil
+	matched := false
+	for _, row := range rows {
+		id, _ := row.Index(1)
+		doc, _ := row.Index(2)
+		k, _ := id.Actual().(string)
+
+		// The target documents are shared by the copies of the merge
+		tv := value.NewAnnotatedValue(doc.Copy())
+		tv.SetAttachment("meta", map[string]interface{}{"id": k})
+		mv := item.Copy().(value.AnnotatedValue)
+		mv.SetField(alias, tv)
+
+		if this.plan.On() != nil {
+			cv, e := this.plan.On().Evaluate(mv, context)
+			if e != nil {
+				context.Error(errors.NewEvaluationError(e, "MERGE condition"))
+				return false
+			}
+
+			if !cv.Truth() {
+				continue
+			}
+		}
+
+		matched = true
+		if !hold {
+			break
+		}
+
+		if !this.matched.add(k) {
+			context.Error(errors.NewMergeMultipleMatchError(k))
+			return false
+		}
+
+		// Perform UPDATE and/or DELETE
+		if update != nil {
+			this.pending = append(this.pending, mergeAction{update, mv})
+		}
+
+		if delete != nil {
+			if update != nil {
+				mv = mv.CopyForUpdate().(value.AnnotatedValue)
+			}
+			this.pending = append(this.pending, mergeAction{delete, mv})
+		}
+	}
+
+	// Not matched; INSERT
+	if !matched && insert != nil {
+		if hold {
+			this.pending = append(this.pending, mergeAction{insert, item})
+			return true
+		}
+
+		return this.mergeSendItem(insert, item)
+	}
+
+	return true
+}
+
 func (this *Merge) wrapChild(op Operator) (Operator, *Channel) {
 	if op == nil {
 		return nil, nil
@@ -237,3 +371,31 @@ func (this *Merge) wrapChild(op Operator) (Operator, *Channel) {
 
 var _MERGE_OPERATOR_POOL = NewOperatorPool(3)
 var _MERGE_CHANNEL_POOL = NewChannelPool(3)
+
+/*
+mergeMatches is the set of target document keys matched so far, shared
+by the copies of a merge.
+*/
+type mergeMatches struct {
+	sync.Mutex
+	keys map[string]bool
+}
+
+func newMergeMatches() *mergeMatches {
+	return &mergeMatches{
+		keys: make(map[string]bool),
+	}
+}
+
+// add returns false if the key was matched already.
+func (this *mergeMatches) add(key string) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.keys[key] {
+		return false
+	}
+
+	this.keys[key] = true
+	return true
+}
@@ -0,0 +1,91 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+/*
+OptimHints warns of the optimizer hints that were not followed, and
+then runs its child.
+*/
+type OptimHints struct {
+	base
+	plan         *plan.OptimHints
+	child        Operator
+	childChannel StopChannel
+}
+
+func NewOptimHints(plan *plan.OptimHints, child Operator) *OptimHints {
+	rv := &OptimHints{
+		base:         newBase(),
+		plan:         plan,
+		child:        child,
+		childChannel: make(StopChannel, 1),
+	}
+
+	rv.output = rv
+	return rv
+}
+
+func (this *OptimHints) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitOptimHints(this)
+}
+
+func (this *OptimHints) Copy() Operator {
+	return &OptimHints{
+		base:         this.base.copy(),
+		plan:         this.plan,
+		child:        this.child.Copy(),
+		childChannel: make(StopChannel, 1),
+	}
+}
+
+func (this *OptimHints) RunOnce(context *Context, parent value.Value) {
+	this.once.Do(func() {
+		defer context.Recover()       // Recover from any panic
+		defer close(this.itemChannel) // Broadcast that I have stopped
+		defer this.notify()           // Notify that I have stopped
+
+		for _, hint := range this.plan.Hints() {
+			switch hint.Status {
+			case plan.OPTIM_HINT_NOT_FOLLOWED:
+				context.Warning(errors.NewOptimHintWarning(hint.Hint, hint.Reason))
+			case plan.OPTIM_HINT_INVALID:
+				context.Warning(errors.NewInvalidOptimHintWarning(hint.Hint, hint.Reason))
+			}
+		}
+
+		this.child.SetInput(this.input)
+		this.child.SetOutput(this.output)
+		this.child.SetStop(nil)
+		this.child.SetParent(this)
+
+		go this.child.RunOnce(context, parent)
+
+		for {
+			select {
+			case <-this.childChannel: // Never closed
+				// Wait for child
+				return
+			case <-this.stopChannel: // Never closed
+				this.notifyStop()
+				notifyChildren(this.child)
+			}
+		}
+	})
+}
+
+func (this *OptimHints) ChildChannel() StopChannel {
+	return this.childChannel
+}
@@ -0,0 +1,129 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+/*
+internalOutput is the output of a statement run by the engine itself,
+such as a trigger or a view refresh. It discards the results and
+keeps the errors, which are also passed to onError, if any. Warnings,
+mutation tokens and phase statistics go to the parent output, if any.
+*/
+type internalOutput struct {
+	sync.Mutex
+	parent    Output
+	onError   func(errors.Error)
+	errs      []errors.Error
+	mutations uint64
+	sortCount uint64
+}
+
+func newInternalOutput(parent Output, onError func(errors.Error)) *internalOutput {
+	return &internalOutput{
+		parent:  parent,
+		onError: onError,
+	}
+}
+
+func (this *internalOutput) Result(item value.Value) bool {
+	return true
+}
+
+func (this *internalOutput) CloseResults() {
+}
+
+func (this *internalOutput) Fatal(err errors.Error) {
+	this.Error(err)
+}
+
+func (this *internalOutput) Error(err errors.Error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.onError != nil {
+		this.onError(err)
+	}
+
+	this.errs = append(this.errs, err)
+}
+
+func (this *internalOutput) Warning(wrn errors.Error) {
+	if this.parent != nil {
+		this.parent.Warning(wrn)
+	}
+}
+
+func (this *internalOutput) AddMutationCount(i uint64) {
+	this.Lock()
+	this.mutations += i
+	this.Unlock()
+}
+
+func (this *internalOutput) MutationCount() uint64 {
+	this.Lock()
+	defer this.Unlock()
+	return this.mutations
+}
+
+func (this *internalOutput) AddMutationToken(keyspace string, token timestamp.Entry) {
+	if this.parent != nil {
+		this.parent.AddMutationToken(keyspace, token)
+	}
+}
+
+func (this *internalOutput) SortCount() uint64 {
+	return this.sortCount
+}
+
+func (this *internalOutput) SetSortCount(i uint64) {
+	this.sortCount = i
+}
+
+func (this *internalOutput) AddPhaseOperator(p Phases) {
+	if this.parent != nil {
+		this.parent.AddPhaseOperator(p)
+	}
+}
+
+func (this *internalOutput) AddPhaseCount(p Phases, c uint64) {
+	if this.parent != nil {
+		this.parent.AddPhaseCount(p, c)
+	}
+}
+
+func (this *internalOutput) FmtPhaseCounts() map[string]interface{} {
+	return nil
+}
+
+func (this *internalOutput) FmtPhaseOperators() map[string]interface{} {
+	return nil
+}
+
+func (this *internalOutput) AddPhaseTime(phase string, duration time.Duration) {
+	if this.parent != nil {
+		this.parent.AddPhaseTime(phase, duration)
+	}
+}
+
+func (this *internalOutput) PhaseTimes() map[string]time.Duration {
+	return nil
+}
+
+func (this *internalOutput) FmtPhaseTimes() map[string]interface{} {
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
 
@@ -61,6 +62,11 @@ func (this *IndexScan) RunOnce(context *Context, parent value.Value) {
 		children := _INDEX_SCAN_POOL.Get()
 		defer _INDEX_SCAN_POOL.Put(children)
 
+		if this.plan.Partitioned() {
+			this.scanPartitions(context, parent, children)
+			return
+		}
+
 		for i, span := range spans {
 			children = append(children, newSpanScan(this, span))
 			go children[i].RunOnce(context, parent)
@@ -86,6 +92,61 @@ func (this *IndexScan) RunOnce(context *Context, parent value.Value) {
 	})
 }
 
+/*
+scanPartitions scans at most max-parallelism partitions at a time. If
+the scan is ordered, the index scans of the partitions run ahead, but
+the partitions are returned one after another.
+*/
+func (this *IndexScan) scanPartitions(context *Context, parent value.Value, children []Operator) {
+	spans := this.plan.Spans()
+	scans := make([]*spanScan, len(spans))
+	for i, span := range spans {
+		scans[i] = newSpanScan(this, span)
+		children = append(children, scans[i])
+	}
+
+	ordered := this.plan.Ordered()
+	window := util.MaxInt(1, util.MinInt(len(scans), context.MaxParallelism()))
+	started, running, next := 0, 0, 0
+	stopped := false
+
+	start := func() {
+		for !stopped && started < len(scans) && started < next+window {
+			if ordered {
+				scans[started].open(context)
+			} else {
+				go scans[started].RunOnce(context, parent)
+				running++
+			}
+			started++
+		}
+
+		if ordered && !stopped && next < started && running == 0 {
+			go scans[next].RunOnce(context, parent)
+			running++
+		}
+	}
+
+	start()
+	for running > 0 {
+		select {
+		case <-this.childChannel: // Never closed
+			running--
+			next++
+			start()
+		case <-this.stopChannel: // Never closed
+			this.notifyStop()
+			notifyChildren(children...)
+			stopped = true
+		}
+	}
+
+	// Stop the index scans that were opened but not run
+	for _, scan := range scans[next:started] {
+		scan.close()
+	}
+}
+
 func (this *IndexScan) ChildChannel() StopChannel {
 	return this.childChannel
 }
@@ -94,6 +155,7 @@ type spanScan struct {
 	base
 	plan *plan.IndexScan
 	span *plan.Span
+	conn *datastore.IndexConnection
 }
 
 func newSpanScan(parent *IndexScan, span *plan.Span) *spanScan {
@@ -113,7 +175,27 @@ func (this *spanScan) Accept(visitor Visitor) (interface{}, error) {
 }
 
 func (this *spanScan) Copy() Operator {
-	return &spanScan{this.base.copy(), this.plan, this.span}
+	return &spanScan{this.base.copy(), this.plan, this.span, nil}
+}
+
+/*
+open starts the index scan of the span, so that its entries are
+fetched ahead of RunOnce.
+*/
+func (this *spanScan) open(context *Context) {
+	if this.conn == nil {
+		this.conn = datastore.NewIndexConnection(context)
+		go this.scan(context, this.conn)
+	}
+}
+
+/*
+close stops an index scan that was opened but not run.
+*/
+func (this *spanScan) close() {
+	if this.conn != nil {
+		notifyConn(this.conn.StopChannel())
+	}
 }
 
 func (this *spanScan) RunOnce(context *Context, parent value.Value) {
@@ -122,7 +204,8 @@ func (this *spanScan) RunOnce(context *Context, parent value.Value) {
 		defer close(this.itemChannel) // Broadcast that I have stopped
 		defer this.notify()           // Notify that I have stopped
 
-		conn := datastore.NewIndexConnection(context)
+		this.open(context)
+		conn := this.conn
 		defer notifyConn(conn.StopChannel()) // Notify index that I have stopped
 
 		timer := time.Now()
@@ -134,8 +217,6 @@ func (this *spanScan) RunOnce(context *Context, parent value.Value) {
 		}
 		defer addTime()
 
-		go this.scan(context, conn)
-
 		var entry *datastore.IndexEntry
 		ok := true
 		var docs uint64 = 0
@@ -147,6 +228,8 @@ func (this *spanScan) RunOnce(context *Context, parent value.Value) {
 		}
 		defer countDocs()
 
+		_, search := this.plan.Index().(datastore.SearchIndex)
+
 		for ok {
 			select {
 			case <-this.stopChannel:
@@ -164,6 +247,13 @@ func (this *spanScan) RunOnce(context *Context, parent value.Value) {
 					meta := map[string]interface{}{"id": entry.PrimaryKey}
 					av.SetAttachment("meta", meta)
 
+					// For SEARCH_SCORE()
+					if search && len(entry.EntryKey) > 0 {
+						av.SetAttachment("smeta", map[string]interface{}{
+							"score": entry.EntryKey[0].Actual(),
+						})
+					}
+
 					covers := this.plan.Covers()
 					if len(covers) > 0 {
 						for c, v := range this.plan.FilterCovers() {
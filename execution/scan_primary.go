@@ -10,6 +10,7 @@
 package execution
 
 import (
+	"fmt"
 	"math"
 	"time"
 
@@ -17,18 +18,21 @@ import (
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/logging"
 	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
 
 type PrimaryScan struct {
 	base
-	plan *plan.PrimaryScan
+	plan         *plan.PrimaryScan
+	childChannel StopChannel
 }
 
 func NewPrimaryScan(plan *plan.PrimaryScan) *PrimaryScan {
 	rv := &PrimaryScan{
-		base: newBase(),
-		plan: plan,
+		base:         newBase(),
+		plan:         plan,
+		childChannel: make(StopChannel, len(plan.Partitions())),
 	}
 
 	rv.output = rv
@@ -40,7 +44,11 @@ func (this *PrimaryScan) Accept(visitor Visitor) (interface{}, error) {
 }
 
 func (this *PrimaryScan) Copy() Operator {
-	return &PrimaryScan{this.base.copy(), this.plan}
+	return &PrimaryScan{
+		base:         this.base.copy(),
+		plan:         this.plan,
+		childChannel: make(StopChannel, len(this.plan.Partitions())),
+	}
 }
 
 func (this *PrimaryScan) RunOnce(context *Context, parent value.Value) {
@@ -50,10 +58,56 @@ func (this *PrimaryScan) RunOnce(context *Context, parent value.Value) {
 		defer close(this.itemChannel) // Broadcast that I have stopped
 		defer this.notify()           // Notify that I have stopped
 
-		this.scanPrimary(context, parent)
+		if len(this.plan.Partitions()) > 0 {
+			this.scanPartitions(context, parent)
+		} else {
+			this.scanPrimary(context, parent)
+		}
 	})
 }
 
+/*
+scanPartitions scans at most max-parallelism key ranges at a time.
+*/
+func (this *PrimaryScan) scanPartitions(context *Context, parent value.Value) {
+	partitions := this.plan.Partitions()
+	children := _INDEX_SCAN_POOL.Get()
+	defer _INDEX_SCAN_POOL.Put(children)
+
+	for _, span := range partitions {
+		children = append(children, newRangeScan(this, span))
+	}
+
+	window := util.MaxInt(1, util.MinInt(len(children), context.MaxParallelism()))
+	started, running := 0, 0
+	stopped := false
+
+	for ; started < window; started++ {
+		go children[started].RunOnce(context, parent)
+		running++
+	}
+
+	for running > 0 {
+		select {
+		case <-this.childChannel: // Never closed
+			running--
+			if !stopped && started < len(children) {
+				go children[started].RunOnce(context, parent)
+				started++
+				running++
+			}
+		case <-this.stopChannel: // Never closed
+			this.notifyStop()
+			notifyChildren(children...)
+			stopped = true
+		}
+	}
+}
+
+func (this *PrimaryScan) ChildChannel() StopChannel {
+	return this.childChannel
+}
+
 func (this *PrimaryScan) scanPrimary(context *Context, parent value.Value) {
 	conn := this.newIndexConnection(context)
 	defer notifyConn(conn.StopChannel()) // Notify index that I have stopped
@@ -228,3 +282,106 @@ func (this *PrimaryScan) newIndexConnection(context *Context) *datastore.IndexCo
 
 	return conn
 }
+
+/*
+rangeScan scans a key range of a partitioned primary scan.
+*/
+type rangeScan struct {
+	base
+	plan *plan.PrimaryScan
+	span *plan.Span
+}
+
+func newRangeScan(parent *PrimaryScan, span *plan.Span) *rangeScan {
+	rv := &rangeScan{
+		base: newRedirectBase(),
+		plan: parent.plan,
+		span: span,
+	}
+
+	rv.parent = parent
+	rv.output = parent.output
+	return rv
+}
+
+func (this *rangeScan) Accept(visitor Visitor) (interface{}, error) {
+	panic(fmt.Sprintf("Internal operator rangeScan visited by %v.", visitor))
+}
+
+func (this *rangeScan) Copy() Operator {
+	return &rangeScan{this.base.copy(), this.plan, this.span}
+}
+
+func (this *rangeScan) RunOnce(context *Context, parent value.Value) {
+	this.once.Do(func() {
+		defer context.Recover()       // Recover from any panic
+		defer close(this.itemChannel) // Broadcast that I have stopped
+		defer this.notify()           // Notify that I have stopped
+
+		conn := datastore.NewIndexConnection(context)
+		defer notifyConn(conn.StopChannel()) // Notify index that I have stopped
+
+		timer := time.Now()
+		addTime := func() {
+
+			t := time.Since(timer) - this.chanTime
+			context.AddPhaseTime("scan", t)
+			this.plan.AddTime(t)
+		}
+		defer addTime()
+
+		go this.scan(context, conn)
+
+		var entry *datastore.IndexEntry
+		ok := true
+		var docs uint64 = 0
+
+		defer func() {
+			if docs > 0 {
+				context.AddPhaseCount(PRIMARY_SCAN, docs)
+			}
+		}()
+
+		for ok {
+			select {
+			case <-this.stopChannel:
+				return
+			default:
+			}
+
+			select {
+			case entry, ok = <-conn.EntryChannel():
+				if ok {
+					cv := value.NewScopeValue(make(map[string]interface{}), parent)
+					av := value.NewAnnotatedValue(cv)
+					av.SetAttachment("meta", map[string]interface{}{"id": entry.PrimaryKey})
+					ok = this.sendItem(av)
+					docs++
+					if docs > _PHASE_UPDATE_COUNT {
+						context.AddPhaseCount(PRIMARY_SCAN, docs)
+						docs = 0
+					}
+				}
+
+			case <-this.stopChannel:
+				return
+			}
+		}
+	})
+}
+
+func (this *rangeScan) scan(context *Context, conn *datastore.IndexConnection) {
+	defer context.Recover() // Recover from any panic
+
+	dspan, err := evalSpan(this.span, context)
+	if err != nil {
+		context.Error(errors.NewEvaluationError(err, "span"))
+		close(conn.EntryChannel())
+		return
+	}
+
+	keyspace := this.plan.Keyspace()
+	scanVector := context.ScanVectorSource().ScanVector(keyspace.NamespaceId(), keyspace.Name())
+	this.plan.Index().Scan(context.RequestId(), dspan, false, math.MaxInt64,
+		context.ScanConsistency(), scanVector, conn)
+}
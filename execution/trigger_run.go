@@ -0,0 +1,291 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"sync"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/planner"
+	"github.com/couchbase/query/value"
+)
+
+// Maximum nesting of triggers run by the mutations of triggers
+const _MAX_TRIGGER_DEPTH = 16
+
+// Capacity of the queue of asynchronous triggers
+const _TRIGGER_QUEUE_CAP = 1024
+
+/*
+triggerRow is a mutated document. new is nil for deletes, and old is
+nil for inserts and upserts.
+*/
+type triggerRow struct {
+	key string
+	new value.Value
+	old value.Value
+}
+
+/*
+keyspaceTriggers returns the triggers of keyspace for event.
+*/
+func keyspaceTriggers(keyspace datastore.Keyspace, event string) []*datastore.Trigger {
+	tks, ok := keyspace.(datastore.TriggerKeyspace)
+	if !ok {
+		return nil
+	}
+
+	triggers, err := tks.Triggers()
+	if err != nil {
+		logging.Errorp("Loading triggers", logging.Pair{"keyspace", keyspace.Name()},
+			logging.Pair{"error", err})
+		return nil
+	}
+
+	var rv []*datastore.Trigger
+	for _, trigger := range triggers {
+		if trigger.HasEvent(event) {
+			rv = append(rv, trigger)
+		}
+	}
+
+	return rv
+}
+
+/*
+runTriggers runs triggers for each row. Synchronous triggers run in
+this request, and their errors are handled according to the on_error
+option of the trigger; asynchronous triggers are queued, and their
+errors are logged.
+*/
+func runTriggers(context *Context, keyspace datastore.Keyspace, triggers []*datastore.Trigger,
+	rows []triggerRow) {
+	if len(rows) == 0 {
+		return
+	}
+
+	depth := context.triggerDepth + 1
+	for _, trigger := range triggers {
+		if depth > _MAX_TRIGGER_DEPTH {
+			triggerFailed(context, trigger, errors.NewTriggerDepthError(trigger.Name, _MAX_TRIGGER_DEPTH))
+			continue
+		}
+
+		op, err := planTrigger(context, keyspace, trigger)
+		if err != nil {
+			triggerFailed(context, trigger, errors.NewTriggerError(err, trigger.Name))
+			continue
+		}
+
+		for _, row := range rows {
+			child := newTriggerContext(context, keyspace.NamespaceId(), depth, row)
+			if trigger.Async {
+				child.output = newInternalOutput(nil, asyncTriggerFailed(trigger))
+				queueTrigger(&triggerJob{trigger, op, child})
+				continue
+			}
+
+			// Synchronous triggers stop with the request; asynchronous
+			// ones run for mutations that are already committed
+			child.done, child.stopOnce = context.done, context.stopOnce
+			output := newInternalOutput(context.output, nil)
+			child.output = output
+			runTrigger(op, child)
+
+			if len(output.errs) > 0 {
+				triggerFailed(context, trigger, triggerError(output.errs[0], trigger.Name))
+				if trigger.OnError == datastore.TRIGGER_ON_ERROR_FAIL {
+					break
+				}
+			}
+		}
+	}
+}
+
+func planTrigger(context *Context, keyspace datastore.Keyspace, trigger *datastore.Trigger) (
+	plan.Operator, error) {
+	stmt, err := n1ql.ParseTriggerStatement(trigger.Statement)
+	if err != nil {
+		return nil, err
+	}
+
+	return planner.Build(stmt, context.datastore, context.systemstore, keyspace.NamespaceId(), true)
+}
+
+/*
+triggerError wraps an error of a trigger run. Errors of nested
+triggers are already wrapped, and are returned as they are.
+*/
+func triggerError(err errors.Error, name string) errors.Error {
+	switch err.Code() {
+	case 5200, 5210:
+		return err
+	default:
+		return errors.NewTriggerError(err, name)
+	}
+}
+
+/*
+asyncTriggerFailed returns the error handler of an asynchronous
+trigger, which has no request to report to.
+*/
+func asyncTriggerFailed(trigger *datastore.Trigger) func(errors.Error) {
+	return func(err errors.Error) {
+		triggerFailed(nil, trigger, err)
+	}
+}
+
+func triggerFailed(context *Context, trigger *datastore.Trigger, err errors.Error) {
+	if trigger.Async {
+		logging.Errorp("Asynchronous trigger", logging.Pair{"trigger", trigger.Name},
+			logging.Pair{"error", err})
+		return
+	}
+
+	switch trigger.OnError {
+	case datastore.TRIGGER_ON_ERROR_IGNORE:
+	case datastore.TRIGGER_ON_ERROR_WARN:
+		context.Warning(err)
+	default:
+		context.Error(err)
+	}
+}
+
+/*
+newTriggerContext returns the context of a trigger run for a mutation
+in context, with NEW and OLD bound to the documents of row. The
+context is not stopped with the request.
+*/
+func newTriggerContext(context *Context, namespace string, depth int, row triggerRow) *Context {
+	args := map[string]value.Value{
+		algebra.TRIGGER_NEW: documentValue(row.key, row.new),
+		algebra.TRIGGER_OLD: documentValue(row.key, row.old),
+	}
+
+	rv := NewContext(context.requestId, context.datastore, context.systemstore, namespace,
+		false, context.maxParallelism, args, nil, context.credentials, context.consistency,
+		context.scanVectorSource, nil)
+	rv.scanWait = context.scanWait
+	rv.triggerDepth = depth
+	return rv
+}
+
+/*
+documentValue returns doc with the meta data of key, without sharing
+the annotations of the mutated value.
+*/
+func documentValue(key string, doc value.Value) value.Value {
+	if doc == nil {
+		return value.NewMissingValue()
+	}
+
+	for {
+		av, ok := doc.(value.AnnotatedValue)
+		if !ok {
+			break
+		}
+		doc = av.GetValue()
+	}
+
+	rv := value.NewAnnotatedValue(doc)
+	rv.SetAttachment("meta", map[string]interface{}{"id": key})
+	return rv
+}
+
+/*
+runTrigger runs a trigger statement and waits for it to complete.
+*/
+func runTrigger(op plan.Operator, context *Context) {
+	defer context.Recover()
+
+	pipeline, err := Build(op, context)
+	if err != nil {
+		context.Error(errors.NewError(err, ""))
+		return
+	}
+
+	collect := NewCollect()
+	sequence := NewSequence(pipeline, collect)
+	sequence.RunOnce(context, nil)
+
+	ok := true
+	for ok {
+		_, ok = <-collect.Output().ItemChannel()
+	}
+}
+
+type triggerJob struct {
+	trigger *datastore.Trigger
+	op      plan.Operator
+	context *Context
+}
+
+var triggerQueue chan *triggerJob
+var triggerQueueOnce sync.Once
+
+/*
+queueTrigger queues an asynchronous trigger, waiting while the queue
+is full.
+*/
+func queueTrigger(job *triggerJob) {
+	triggerQueueOnce.Do(func() {
+		triggerQueue = make(chan *triggerJob, _TRIGGER_QUEUE_CAP)
+		go func() {
+			for job := range triggerQueue {
+				runTrigger(job.op, job.context)
+			}
+		}()
+	})
+
+	triggerQueue <- job
+}
+
+/*
+runPairTriggers runs the triggers for the inserted, updated or
+upserted pairs. olds holds the documents before an update, by key.
+*/
+func runPairTriggers(context *Context, keyspace datastore.Keyspace, event string,
+	pairs []value.Pair, olds map[string]value.Value) {
+	triggers := keyspaceTriggers(keyspace, event)
+	if len(triggers) == 0 {
+		return
+	}
+
+	rows := make([]triggerRow, len(pairs))
+	for i, pair := range pairs {
+		rows[i] = triggerRow{key: pair.Name, new: pair.Value, old: olds[pair.Name]}
+	}
+
+	runTriggers(context, keyspace, triggers, rows)
+}
+
+/*
+runDeleteTriggers runs the triggers for the deleted keys. olds holds
+the deleted documents, by key.
+*/
+func runDeleteTriggers(context *Context, keyspace datastore.Keyspace, keys []string,
+	olds map[string]value.Value) {
+	triggers := keyspaceTriggers(keyspace, datastore.TRIGGER_DELETE)
+	if len(triggers) == 0 {
+		return
+	}
+
+	rows := make([]triggerRow, len(keys))
+	for i, key := range keys {
+		rows[i] = triggerRow{key: key, old: olds[key]}
+	}
+
+	runTriggers(context, keyspace, triggers, rows)
+}
@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/couchbase/query/changes"
+	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/value"
@@ -94,7 +96,17 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 	pairs := _UPDATE_POOL.Get()
 	defer _UPDATE_POOL.Put(pairs)
 
-	for i, item := range this.batch {
+	// Keep the documents before the update for triggers
+	var olds map[string]value.Value
+	if len(keyspaceTriggers(this.plan.Keyspace(), datastore.TRIGGER_UPDATE)) > 0 {
+		olds = make(map[string]value.Value, len(this.batch))
+	}
+
+	// Documents that do not match the schema are not updated
+	sch := keyspaceSchema(this.plan.Keyspace())
+	i := 0
+
+	for _, item := range this.batch {
 		uv, ok := item.Field(this.plan.Alias())
 		if !ok {
 			context.Error(errors.NewUpdateAliasMissingError(this.plan.Alias()))
@@ -114,6 +126,9 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 
 		pairs = pairs[0 : i+1]
 		pairs[i].Name = key
+		if olds != nil {
+			olds[key] = av
+		}
 
 		clone := item.GetAttachment("clone")
 		switch clone := clone.(type) {
@@ -124,6 +139,10 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 				return false
 			}
 
+			if !validDocument(context, this.plan.Keyspace(), sch, key, cv) {
+				continue
+			}
+
 			cav := value.NewAnnotatedValue(cv)
 			cav.SetAnnotations(av)
 			pairs[i].Value = cav
@@ -133,11 +152,20 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 				"Invalid UPDATE value of type %T.", clone)))
 			return false
 		}
+
+		this.batch[i] = item
+		i++
+	}
+
+	pairs = pairs[0:i]
+	this.batch = this.batch[0:i]
+	if len(pairs) == 0 {
+		return true
 	}
 
 	timer := time.Now()
 
-	pairs, e := this.plan.Keyspace().Update(pairs)
+	pairs, e := this.plan.Keyspace().Update(pairs, context)
 
 	t := time.Since(timer)
 	context.AddPhaseTime("update", t)
@@ -145,11 +173,16 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 
 	// Update mutation count with number of updated docs
 	context.AddMutationCount(uint64(len(pairs)))
+	context.AddMutationToken(this.plan.Keyspace())
 
-	if e != nil {
+	// The error of a stopped request is not reported
+	if e != nil && context.Err() == nil {
 		context.Error(e)
 	}
 
+	publishPairs(this.plan.Keyspace(), changes.UPDATE, pairs)
+	runPairTriggers(context, this.plan.Keyspace(), datastore.TRIGGER_UPDATE, pairs, olds)
+
 	for _, item := range this.batch {
 		if !this.sendItem(item) {
 			return false
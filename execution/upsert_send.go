@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/couchbase/query/changes"
+	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/value"
@@ -65,6 +67,7 @@ func (this *SendUpsert) flushBatch(context *Context) bool {
 
 	keyExpr := this.plan.Key()
 	valExpr := this.plan.Value()
+	sch := keyspaceSchema(this.plan.Keyspace())
 	var key, val value.Value
 	var err error
 	var ok bool
@@ -114,17 +117,24 @@ func (this *SendUpsert) flushBatch(context *Context) bool {
 			continue
 		}
 
+		if !validDocument(context, this.plan.Keyspace(), sch, dpair.Name, val) {
+			continue
+		}
+
 		dpair.Value = val
 		i++
 	}
 
 	dpairs = dpairs[0:i]
+	if len(dpairs) == 0 {
+		return true
+	}
 
 	timer := time.Now()
 
 	// Perform the actual UPSERT
 	var er errors.Error
-	dpairs, er = this.plan.Keyspace().Upsert(dpairs)
+	dpairs, er = this.plan.Keyspace().Upsert(dpairs, context)
 
 	t := time.Since(timer)
 	context.AddPhaseTime("upsert", t)
@@ -132,11 +142,16 @@ func (this *SendUpsert) flushBatch(context *Context) bool {
 
 	// Update mutation count with number of upserted docs
 	context.AddMutationCount(uint64(len(dpairs)))
+	context.AddMutationToken(this.plan.Keyspace())
 
-	if er != nil {
+	// The error of a stopped request is not reported
+	if er != nil && context.Err() == nil {
 		context.Error(er)
 	}
 
+	publishPairs(this.plan.Keyspace(), changes.UPSERT, dpairs)
+	runPairTriggers(context, this.plan.Keyspace(), datastore.TRIGGER_UPSERT, dpairs, nil)
+
 	// Capture the upserted keys in case there is a RETURNING clause
 	for _, dp := range dpairs {
 		dv := value.NewAnnotatedValue(dp.Value)
@@ -0,0 +1,124 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/schema"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Validate consumes the documents of a keyspace, projected as id and
+doc, and produces the id and the violations of each document that
+does not match the schema of the keyspace.
+*/
+type Validate struct {
+	base
+	plan   *plan.Validate
+	schema *schema.Schema
+}
+
+func NewValidate(plan *plan.Validate) *Validate {
+	rv := &Validate{
+		base: newBase(),
+		plan: plan,
+	}
+
+	rv.output = rv
+	return rv
+}
+
+func (this *Validate) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitValidate(this)
+}
+
+func (this *Validate) Copy() Operator {
+	return &Validate{this.base.copy(), this.plan, nil}
+}
+
+func (this *Validate) RunOnce(context *Context, parent value.Value) {
+	this.runConsumer(this, context, parent)
+}
+
+func (this *Validate) beforeItems(context *Context, parent value.Value) bool {
+	keyspace := this.plan.Keyspace()
+	this.schema = keyspace.Schema()
+	if this.schema == nil {
+		context.Error(errors.NewSchemaNotFoundError(keyspace.NamespaceId() + ":" + keyspace.Name()))
+		return false
+	}
+
+	return true
+}
+
+func (this *Validate) processItem(item value.AnnotatedValue, context *Context) bool {
+	id, _ := item.Field("id")
+	doc, ok := item.Field("doc")
+	if !ok {
+		return true
+	}
+
+	violations := this.schema.Validate(doc)
+	if len(violations) == 0 {
+		return true
+	}
+
+	rv := make([]interface{}, len(violations))
+	for i, violation := range violations {
+		rv[i] = map[string]interface{}{
+			"path":    violation.Path,
+			"message": violation.Message,
+		}
+	}
+
+	return this.sendItem(value.NewAnnotatedValue(map[string]interface{}{
+		"id":         id,
+		"violations": rv,
+	}))
+}
+
+/*
+keyspaceSchema returns the schema of keyspace, or nil.
+*/
+func keyspaceSchema(keyspace datastore.Keyspace) *schema.Schema {
+	sks, ok := keyspace.(datastore.SchemaKeyspace)
+	if !ok {
+		return nil
+	}
+
+	return sks.Schema()
+}
+
+/*
+validDocument returns true if doc matches sch, which may be nil, and
+reports the violations of doc otherwise.
+*/
+func validDocument(context *Context, keyspace datastore.Keyspace, sch *schema.Schema,
+	key string, doc value.Value) bool {
+	if sch == nil {
+		return true
+	}
+
+	violations := sch.Validate(doc)
+	if len(violations) == 0 {
+		return true
+	}
+
+	msgs := make([]string, len(violations))
+	for i, violation := range violations {
+		msgs[i] = violation.String()
+	}
+
+	context.Error(errors.NewSchemaViolationError(keyspace.Name(), key, msgs))
+	return false
+}
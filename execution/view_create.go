@@ -0,0 +1,66 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+type CreateMaterializedView struct {
+	base
+	plan *plan.CreateMaterializedView
+}
+
+func NewCreateMaterializedView(plan *plan.CreateMaterializedView) *CreateMaterializedView {
+	rv := &CreateMaterializedView{
+		base: newBase(),
+		plan: plan,
+	}
+
+	rv.output = rv
+	return rv
+}
+
+func (this *CreateMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateMaterializedView(this)
+}
+
+func (this *CreateMaterializedView) Copy() Operator {
+	return &CreateMaterializedView{this.base.copy(), this.plan}
+}
+
+func (this *CreateMaterializedView) RunOnce(context *Context, parent value.Value) {
+	this.once.Do(func() {
+		defer context.Recover()       // Recover from any panic
+		defer close(this.itemChannel) // Broadcast that I have stopped
+		defer this.notify()           // Notify that I have stopped
+
+		if context.Readonly() {
+			return
+		}
+
+		// Actually create view
+		namespace := this.plan.Namespace()
+		keyspace, err := namespace.CreateView(this.plan.View())
+		if err != nil {
+			context.Error(err)
+			return
+		}
+
+		// Compute the view; it is dropped if that fails
+		err = refreshView(context, keyspace)
+		if err != nil {
+			context.Error(err)
+			stopView(keyspace)
+			namespace.DropView(keyspace.Name())
+		}
+	})
+}
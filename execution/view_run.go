@@ -0,0 +1,703 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/changes"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+/*
+refreshView recomputes all the documents of a view. A manual view
+stores the results of its query, keyed by position. An incremental
+view is recomputed from the documents of its keyspace, keyed by
+document for ungrouped queries and by group otherwise, and is then
+maintained from the mutations of the keyspace.
+*/
+func refreshView(context *Context, keyspace datastore.ViewKeyspace) errors.Error {
+	query, err := parseView(keyspace)
+	if err != nil {
+		return errors.NewViewRefreshError(err, keyspace.Name())
+	}
+
+	if keyspace.View().Refresh == datastore.VIEW_REFRESH_INCREMENTAL {
+		return startView(context.datastore, context.systemstore, keyspace, query)
+	}
+
+	results, err := evaluateView(context.datastore, context.systemstore, keyspace.NamespaceId(), query)
+	if err != nil {
+		return errors.NewViewRefreshError(err, keyspace.Name())
+	}
+
+	rows := make([]value.Pair, len(results))
+	for i, result := range results {
+		rows[i] = value.Pair{Name: strconv.Itoa(i + 1), Value: value.NewValue(result)}
+	}
+
+	return keyspace.ReplaceRows(rows)
+}
+
+/*
+MaintainViews starts the maintenance of the incremental views of the
+datastore, which are recomputed first. Errors are logged.
+*/
+func MaintainViews(store, systemstore datastore.Datastore) {
+	names, err := store.NamespaceNames()
+	if err != nil {
+		logging.Errorp("Loading materialized views", logging.Pair{"error", err})
+		return
+	}
+
+	for _, name := range names {
+		namespace, err := store.NamespaceByName(name)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := namespace.(datastore.ViewNamespace); !ok {
+			continue
+		}
+
+		keyspaceNames, err := namespace.KeyspaceNames()
+		if err != nil {
+			continue
+		}
+
+		for _, keyspaceName := range keyspaceNames {
+			keyspace, err := namespace.KeyspaceByName(keyspaceName)
+			if err != nil {
+				continue
+			}
+
+			vks, ok := keyspace.(datastore.ViewKeyspace)
+			if !ok || vks.View() == nil || vks.View().Refresh != datastore.VIEW_REFRESH_INCREMENTAL {
+				continue
+			}
+
+			query, er := parseView(vks)
+			if er == nil {
+				er = startView(store, systemstore, vks, query)
+			}
+
+			if er != nil {
+				logging.Errorp("Materialized view", logging.Pair{"view", keyspace.Name()},
+					logging.Pair{"error", er})
+			}
+		}
+	}
+}
+
+func parseView(keyspace datastore.ViewKeyspace) (*algebra.Select, error) {
+	stmt, err := n1ql.ParseStatement(keyspace.View().Statement)
+	if err != nil {
+		return nil, err
+	}
+
+	query, ok := stmt.(*algebra.Select)
+	if !ok {
+		return nil, errors.NewError(nil, "The query of a materialized view must be a SELECT.")
+	}
+
+	return query, nil
+}
+
+/*
+evaluateView runs query in namespace, and returns its results.
+*/
+func evaluateView(store, systemstore datastore.Datastore, namespace string,
+	query *algebra.Select) ([]interface{}, error) {
+	output := newInternalOutput(nil, nil)
+	context := newViewContext(store, systemstore, namespace, output)
+
+	rv, err := context.EvaluateSubquery(query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.errs) > 0 {
+		return nil, output.errs[0]
+	}
+
+	results, _ := rv.Actual().([]interface{})
+	return results, nil
+}
+
+/*
+newViewContext returns the context of view queries, which do not
+belong to a request.
+*/
+func newViewContext(store, systemstore datastore.Datastore, namespace string, output Output) *Context {
+	return NewContext("", store, systemstore, namespace, true, 0, nil, nil, nil,
+		datastore.SCAN_PLUS, &viewVectorSource{}, output)
+}
+
+type viewVectorSource struct {
+}
+
+func (this *viewVectorSource) Type() int32 {
+	return timestamp.NO_VECTORS
+}
+
+func (this *viewVectorSource) ScanVector(namespace_id string, keyspace_name string) timestamp.Vector {
+	return nil
+}
+
+/*
+The maintainers of the incremental views, by namespace:keyspace.
+*/
+var viewMaintainers = struct {
+	sync.Mutex
+	byName map[string]*viewMaintainer
+}{byName: make(map[string]*viewMaintainer)}
+
+/*
+startView recomputes an incremental view, and replaces its
+maintainer.
+*/
+func startView(store, systemstore datastore.Datastore, keyspace datastore.ViewKeyspace,
+	query *algebra.Select) errors.Error {
+	subselect, term, err := algebra.IncrementalView(query)
+	if err != nil {
+		return err
+	}
+
+	name := changes.KeyspaceName(keyspace.NamespaceId(), keyspace.Name())
+
+	viewMaintainers.Lock()
+	defer viewMaintainers.Unlock()
+
+	if old := viewMaintainers.byName[name]; old != nil {
+		old.close()
+		delete(viewMaintainers.byName, name)
+	}
+
+	this, err := newViewMaintainer(store, systemstore, keyspace, subselect, term)
+	if err != nil {
+		return err
+	}
+
+	err = this.load()
+	if err != nil {
+		return errors.NewViewRefreshError(err, keyspace.Name())
+	}
+
+	viewMaintainers.byName[name] = this
+	go this.run()
+	return nil
+}
+
+/*
+stopView stops the maintenance of a view, if any.
+*/
+func stopView(keyspace datastore.Keyspace) {
+	name := changes.KeyspaceName(keyspace.NamespaceId(), keyspace.Name())
+
+	viewMaintainers.Lock()
+	defer viewMaintainers.Unlock()
+
+	if this := viewMaintainers.byName[name]; this != nil {
+		this.close()
+		delete(viewMaintainers.byName, name)
+	}
+}
+
+/*
+viewMaintainer keeps an incremental view up to date from the change
+stream of its keyspace. For grouped views it keeps the documents that
+satisfy the WHERE clause, by group, to recompute the aggregates of the
+groups that change.
+*/
+type viewMaintainer struct {
+	keyspace     datastore.ViewKeyspace
+	subselect    *algebra.Subselect
+	alias        string
+	source       datastore.Keyspace
+	store        datastore.Datastore
+	systemstore  datastore.Datastore
+	context      *Context
+	grouped      bool
+	aggregates   []algebra.Aggregate
+	groups       map[string]map[string]value.AnnotatedValue // Documents by group and key
+	members      map[string]string                          // Groups by document key
+	subscription *changes.Subscription
+	stop         chan bool
+	done         chan bool
+}
+
+func newViewMaintainer(store, systemstore datastore.Datastore, keyspace datastore.ViewKeyspace,
+	subselect *algebra.Subselect, term *algebra.KeyspaceTerm) (*viewMaintainer, errors.Error) {
+	namespace := term.Namespace()
+	if namespace == "" {
+		namespace = keyspace.NamespaceId()
+	}
+
+	ns, err := store.NamespaceByName(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := ns.KeyspaceByName(term.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	rv := &viewMaintainer{
+		keyspace:    keyspace,
+		subselect:   subselect,
+		alias:       term.Alias(),
+		source:      source,
+		store:       store,
+		systemstore: systemstore,
+		aggregates:  viewAggregates(subselect),
+		stop:        make(chan bool),
+		done:        make(chan bool),
+	}
+
+	rv.grouped = subselect.Group() != nil || len(rv.aggregates) > 0
+	rv.context = newViewContext(store, systemstore, keyspace.NamespaceId(),
+		newInternalOutput(nil, rv.logError))
+	return rv, nil
+}
+
+/*
+load subscribes to the changes of the keyspace, and recomputes the
+view from the documents of the keyspace. Changes that are already
+reflected in the documents are applied again when they are read,
+without effect.
+*/
+func (this *viewMaintainer) load() errors.Error {
+	subscription, err := changes.DefaultBus().Subscribe([]string{
+		changes.KeyspaceName(this.source.NamespaceId(), this.source.Name()),
+	}, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("SELECT RAW [META(`s`).id, `s`] FROM `%s`:`%s` AS `s`",
+		this.source.NamespaceId(), this.source.Name())
+	stmt, er := n1ql.ParseStatement(text)
+	if er != nil {
+		subscription.Close()
+		return errors.NewError(er, "")
+	}
+
+	docs, er := evaluateView(this.store, this.systemstore, this.source.NamespaceId(), stmt.(*algebra.Select))
+	if er != nil {
+		subscription.Close()
+		return errors.NewError(er, "")
+	}
+
+	this.groups = make(map[string]map[string]value.AnnotatedValue)
+	this.members = make(map[string]string)
+	delta := newViewDelta()
+
+	for _, doc := range docs {
+		pair, ok := value.NewValue(doc).Actual().([]interface{})
+		if !ok || len(pair) != 2 {
+			continue
+		}
+
+		key, ok := value.NewValue(pair[0]).Actual().(string)
+		if ok {
+			this.set(key, value.NewValue(pair[1]), delta)
+		}
+	}
+
+	// Aggregates without GROUP BY always have a result
+	if this.grouped && this.subselect.Group() == nil {
+		delta.groups[this.groupKey(nil)] = true
+	}
+
+	this.finish(delta)
+	upserts, _ := delta.rows()
+
+	err = this.keyspace.ReplaceRows(upserts)
+	if err != nil {
+		subscription.Close()
+		return err
+	}
+
+	this.subscription = subscription
+	return nil
+}
+
+/*
+run applies the changes of the keyspace to the view until the view is
+stopped. If the subscription falls behind, the view is recomputed.
+*/
+func (this *viewMaintainer) run() {
+	defer close(this.done)
+
+	for {
+		select {
+		case <-this.stop:
+			this.subscription.Close()
+			return
+		case <-this.subscription.Ready():
+		}
+
+		events, err := this.subscription.Read()
+		if err != nil {
+			this.subscription.Close()
+			err = this.load()
+			if err != nil {
+				this.logError(err)
+				return
+			}
+			continue
+		}
+
+		delta := newViewDelta()
+		for _, event := range events {
+			if event.Op == changes.DELETE {
+				this.set(event.Key, nil, delta)
+			} else {
+				this.set(event.Key, event.Doc, delta)
+			}
+		}
+
+		this.finish(delta)
+		upserts, deletes := delta.rows()
+		if len(upserts) > 0 || len(deletes) > 0 {
+			err = this.keyspace.ApplyRows(upserts, deletes)
+			if err != nil {
+				this.logError(err)
+			}
+		}
+	}
+}
+
+/*
+close stops the maintainer and waits for it.
+*/
+func (this *viewMaintainer) close() {
+	close(this.stop)
+	<-this.done
+}
+
+func (this *viewMaintainer) logError(err errors.Error) {
+	logging.Errorp("Materialized view", logging.Pair{"view", this.keyspace.Name()},
+		logging.Pair{"error", err})
+}
+
+/*
+set records the document of key, or its deletion if doc is nil, in
+delta.
+*/
+func (this *viewMaintainer) set(key string, doc value.Value, delta *viewDelta) {
+	var item value.AnnotatedValue
+	if doc != nil {
+		item = value.NewAnnotatedValue(value.NewScopeValue(make(map[string]interface{}, 1), nil))
+		item.SetField(this.alias, documentValue(key, doc))
+
+		where := this.subselect.Where()
+		if where != nil {
+			result, err := where.Evaluate(item, this.context)
+			if err != nil {
+				this.logError(errors.NewEvaluationError(err, "filter"))
+				item = nil
+			} else if !result.Truth() {
+				item = nil
+			}
+		}
+	}
+
+	if !this.grouped {
+		if item == nil {
+			delta.remove(key)
+			return
+		}
+
+		row, err := projectView(this.subselect.Projection(), item, this.context)
+		if err != nil {
+			this.logError(errors.NewEvaluationError(err, "projection"))
+		}
+
+		if row == nil {
+			delta.remove(key)
+		} else {
+			delta.upsert(key, row)
+		}
+		return
+	}
+
+	if group, ok := this.members[key]; ok {
+		delete(this.groups[group], key)
+		delete(this.members, key)
+		delta.groups[group] = true
+	}
+
+	if item == nil {
+		return
+	}
+
+	var by expression.Expressions
+	if this.subselect.Group() != nil {
+		by = this.subselect.Group().By()
+	}
+
+	keys := make([]interface{}, len(by))
+	for i, expr := range by {
+		v, err := expr.Evaluate(item, this.context)
+		if err != nil {
+			this.logError(errors.NewEvaluationError(err, "GROUP key"))
+			return
+		}
+		keys[i] = v
+	}
+
+	group := this.groupKey(keys)
+	if this.groups[group] == nil {
+		this.groups[group] = make(map[string]value.AnnotatedValue)
+	}
+
+	this.groups[group][key] = item
+	this.members[key] = group
+	delta.groups[group] = true
+}
+
+/*
+groupKey returns the key of a group in the view: the group values, as
+a JSON array.
+*/
+func (this *viewMaintainer) groupKey(keys []interface{}) string {
+	if keys == nil {
+		keys = []interface{}{}
+	}
+
+	bytes, _ := value.NewValue(keys).MarshalJSON()
+	return string(bytes)
+}
+
+/*
+finish recomputes the groups that changed in delta.
+*/
+func (this *viewMaintainer) finish(delta *viewDelta) {
+	for group, _ := range delta.groups {
+		members := this.groups[group]
+		if len(members) == 0 && this.subselect.Group() != nil {
+			delete(this.groups, group)
+			delta.remove(group)
+			continue
+		}
+
+		keys := make([]string, 0, len(members))
+		for key, _ := range members {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var item value.AnnotatedValue
+		if len(keys) > 0 {
+			item = members[keys[0]].Copy().(value.AnnotatedValue)
+		} else {
+			item = value.NewAnnotatedValue(nil)
+		}
+
+		err := this.aggregate(item, members, keys)
+		if err != nil {
+			this.logError(errors.NewGroupUpdateError(err, "Error updating GROUP value."))
+			delta.remove(group)
+			continue
+		}
+
+		if this.subselect.Group() != nil && this.subselect.Group().Having() != nil {
+			result, err := this.subselect.Group().Having().Evaluate(item, this.context)
+			if err != nil || !result.Truth() {
+				delta.remove(group)
+				continue
+			}
+		}
+
+		row, err := projectView(this.subselect.Projection(), item, this.context)
+		if err != nil {
+			this.logError(errors.NewEvaluationError(err, "projection"))
+		}
+
+		if row == nil {
+			delta.remove(group)
+		} else {
+			delta.upsert(group, row)
+		}
+	}
+}
+
+/*
+aggregate sets the aggregates of a group on item, from the documents
+of members in the order of keys.
+*/
+func (this *viewMaintainer) aggregate(item value.AnnotatedValue,
+	members map[string]value.AnnotatedValue, keys []string) error {
+	aggregates := make(map[string]value.Value, len(this.aggregates))
+	item.SetAttachment("aggregates", aggregates)
+
+	for _, agg := range this.aggregates {
+		cumulative := agg.Default()
+		if len(keys) > 0 {
+			var err error
+			for _, key := range keys {
+				cumulative, err = agg.CumulateInitial(members[key], cumulative, this.context)
+				if err != nil {
+					return err
+				}
+			}
+
+			cumulative, err = agg.ComputeFinal(cumulative, this.context)
+			if err != nil {
+				return err
+			}
+		}
+
+		aggregates[agg.String()] = cumulative
+	}
+
+	return nil
+}
+
+/*
+projectView evaluates a projection for a view, as the project
+operators do. It returns nil if there is no result.
+*/
+func projectView(projection *algebra.Projection, item value.AnnotatedValue,
+	context *Context) (value.Value, error) {
+	terms := projection.Terms()
+
+	if projection.Raw() {
+		v, err := terms[0].Expression().Evaluate(item, context)
+		if err != nil || v.Type() == value.MISSING {
+			return nil, err
+		}
+		return v, nil
+	}
+
+	rv := value.NewValue(make(map[string]interface{}, len(terms)))
+	for _, term := range terms {
+		if term.Alias() != "" {
+			v, err := term.Expression().Evaluate(item, context)
+			if err != nil {
+				return nil, err
+			}
+
+			rv.SetField(term.Alias(), v)
+			continue
+		}
+
+		// Star
+		starval := item.GetValue()
+		if term.Expression() != nil {
+			var err error
+			starval, err = term.Expression().Evaluate(item, context)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		switch sa := starval.Actual().(type) {
+		case map[string]interface{}:
+			for k, v := range sa {
+				rv.SetField(k, v)
+			}
+		}
+	}
+
+	return rv, nil
+}
+
+/*
+viewAggregates returns the aggregates of the projection and HAVING
+clause of a view query.
+*/
+func viewAggregates(subselect *algebra.Subselect) []algebra.Aggregate {
+	exprs := make(expression.Expressions, 0, 8)
+	for _, term := range subselect.Projection().Terms() {
+		if term.Expression() != nil {
+			exprs = append(exprs, term.Expression())
+		}
+	}
+
+	if subselect.Group() != nil && subselect.Group().Having() != nil {
+		exprs = append(exprs, subselect.Group().Having())
+	}
+
+	aggs := make(map[string]algebra.Aggregate)
+	collectViewAggregates(aggs, exprs)
+
+	rv := make([]algebra.Aggregate, 0, len(aggs))
+	for _, agg := range aggs {
+		rv = append(rv, agg)
+	}
+
+	return rv
+}
+
+func collectViewAggregates(aggs map[string]algebra.Aggregate, exprs expression.Expressions) {
+	for _, expr := range exprs {
+		if agg, ok := expr.(algebra.Aggregate); ok {
+			aggs[agg.String()] = agg
+			continue
+		}
+
+		collectViewAggregates(aggs, expr.Children())
+	}
+}
+
+/*
+viewDelta is the change of a view from a batch of mutations: the rows
+to write and remove, and the groups to recompute.
+*/
+type viewDelta struct {
+	upserts map[string]value.Value
+	deletes map[string]bool
+	groups  map[string]bool
+}
+
+func newViewDelta() *viewDelta {
+	return &viewDelta{
+		upserts: make(map[string]value.Value),
+		deletes: make(map[string]bool),
+		groups:  make(map[string]bool),
+	}
+}
+
+func (this *viewDelta) upsert(key string, row value.Value) {
+	this.upserts[key] = row
+	delete(this.deletes, key)
+}
+
+func (this *viewDelta) remove(key string) {
+	this.deletes[key] = true
+	delete(this.upserts, key)
+}
+
+func (this *viewDelta) rows() ([]value.Pair, []string) {
+	upserts := make([]value.Pair, 0, len(this.upserts))
+	for key, row := range this.upserts {
+		upserts = append(upserts, value.Pair{Name: key, Value: row})
+	}
+
+	deletes := make([]string, 0, len(this.deletes))
+	for key, _ := range this.deletes {
+		deletes = append(deletes, key)
+	}
+
+	return upserts, deletes
+}
@@ -0,0 +1,366 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"github.com/couchbase/query/geo"
+	"github.com/couchbase/query/value"
+)
+
+///////////////////////////////////////////////////
+//
+// StBbox
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function ST_BBOX(geom [, distance]).
+It returns the bounding box [min_lon, min_lat, max_lon, max_lat] of
+the geometry, expanded by distance meters if given.
+*/
+type StBbox struct {
+	FunctionBase
+}
+
+func NewStBbox(operands ...Expression) Function {
+	rv := &StBbox{
+		*NewFunctionBase("st_bbox", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *StBbox) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *StBbox) Type() value.Type { return value.ARRAY }
+
+func (this *StBbox) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+func (this *StBbox) Apply(context Context, args ...value.Value) (value.Value, error) {
+	for _, arg := range args {
+		if arg.Type() == value.MISSING {
+			return value.MISSING_VALUE, nil
+		}
+	}
+
+	g, ok := geometryValue(args[0])
+	if !ok {
+		return value.NULL_VALUE, nil
+	}
+
+	bounds := g.Bounds()
+	if len(args) > 1 {
+		if args[1].Type() != value.NUMBER {
+			return value.NULL_VALUE, nil
+		}
+
+		bounds = bounds.Expand(args[1].Actual().(float64))
+	}
+
+	return value.NewValue(bounds.Array()), nil
+}
+
+/*
+Minimum input arguments required is 1.
+*/
+func (this *StBbox) MinArgs() int { return 1 }
+
+/*
+Maximum input arguments allowed is 2.
+*/
+func (this *StBbox) MaxArgs() int { return 2 }
+
+/*
+Factory method pattern.
+*/
+func (this *StBbox) Constructor() FunctionConstructor {
+	return NewStBbox
+}
+
+///////////////////////////////////////////////////
+//
+// StDistance
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function ST_DISTANCE(geom1, geom2). It
+returns the great-circle distance in meters between the nearest
+points of the two geometries, or 0 if they intersect.
+*/
+type StDistance struct {
+	BinaryFunctionBase
+}
+
+func NewStDistance(first, second Expression) Function {
+	rv := &StDistance{
+		*NewBinaryFunctionBase("st_distance", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *StDistance) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *StDistance) Type() value.Type { return value.NUMBER }
+
+func (this *StDistance) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+func (this *StDistance) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	}
+
+	g1, ok1 := geometryValue(first)
+	g2, ok2 := geometryValue(second)
+	if !ok1 || !ok2 {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(geo.Distance(g1, g2)), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *StDistance) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewStDistance(operands[0], operands[1])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// StIntersects
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function ST_INTERSECTS(geom1, geom2).
+It returns true if the geometries have at least one point in common.
+*/
+type StIntersects struct {
+	BinaryFunctionBase
+}
+
+func NewStIntersects(first, second Expression) Function {
+	rv := &StIntersects{
+		*NewBinaryFunctionBase("st_intersects", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *StIntersects) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *StIntersects) Type() value.Type { return value.BOOLEAN }
+
+func (this *StIntersects) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+/*
+If this expression is in the WHERE clause of a partial index, lists
+the Expressions that are implicitly covered.
+
+For boolean functions, simply list this expression.
+*/
+func (this *StIntersects) FilterCovers(covers map[string]value.Value) map[string]value.Value {
+	covers[this.String()] = value.TRUE_VALUE
+	return covers
+}
+
+func (this *StIntersects) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	}
+
+	g1, ok1 := geometryValue(first)
+	g2, ok2 := geometryValue(second)
+	if !ok1 || !ok2 {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(geo.Intersects(g1, g2)), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *StIntersects) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewStIntersects(operands[0], operands[1])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// StPoint
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function ST_POINT(lon, lat). It returns
+a GeoJSON point.
+*/
+type StPoint struct {
+	BinaryFunctionBase
+}
+
+func NewStPoint(first, second Expression) Function {
+	rv := &StPoint{
+		*NewBinaryFunctionBase("st_point", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *StPoint) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *StPoint) Type() value.Type { return value.OBJECT }
+
+func (this *StPoint) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+/*
+Returns NULL for non-numeric or out of range coordinates.
+*/
+func (this *StPoint) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if first.Type() != value.NUMBER || second.Type() != value.NUMBER {
+		return value.NULL_VALUE, nil
+	}
+
+	p := geo.Point{Lon: first.Actual().(float64), Lat: second.Actual().(float64)}
+	if !p.Valid() {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(geo.NewPoint(p.Lon, p.Lat).ToGeoJSON()), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *StPoint) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewStPoint(operands[0], operands[1])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// StWithin
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function ST_WITHIN(geom1, geom2). It
+returns true if geom1 lies entirely within geom2.
+*/
+type StWithin struct {
+	BinaryFunctionBase
+}
+
+func NewStWithin(first, second Expression) Function {
+	rv := &StWithin{
+		*NewBinaryFunctionBase("st_within", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *StWithin) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *StWithin) Type() value.Type { return value.BOOLEAN }
+
+func (this *StWithin) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+/*
+If this expression is in the WHERE clause of a partial index, lists
+the Expressions that are implicitly covered.
+
+For boolean functions, simply list this expression.
+*/
+func (this *StWithin) FilterCovers(covers map[string]value.Value) map[string]value.Value {
+	covers[this.String()] = value.TRUE_VALUE
+	return covers
+}
+
+func (this *StWithin) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	}
+
+	g1, ok1 := geometryValue(first)
+	g2, ok2 := geometryValue(second)
+	if !ok1 || !ok2 {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(geo.Within(g1, g2)), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *StWithin) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewStWithin(operands[0], operands[1])
+	}
+}
+
+func geometryValue(val value.Value) (*geo.Geometry, bool) {
+	switch val.Type() {
+	case value.OBJECT, value.ARRAY:
+		g, err := geo.Parse(val.Actual())
+		return g, err == nil && !g.Empty()
+	}
+
+	return nil, false
+}
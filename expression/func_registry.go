@@ -280,6 +280,17 @@ var _FUNCTIONS = map[string]Function{
 	"uuid":          &Uuid{},
 	"version":       &Version{},
 
+	// Search
+	"search":       &Search{},
+	"search_score": &SearchScore{},
+
+	// Geospatial
+	"st_bbox":       &StBbox{},
+	"st_distance":   &StDistance{},
+	"st_intersects": &StIntersects{},
+	"st_point":      &StPoint{},
+	"st_within":     &StWithin{},
+
 	// Type checking
 	"is_array":   &IsArray{},
 	"is_atom":    &IsAtom{},
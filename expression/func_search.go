@@ -0,0 +1,267 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"fmt"
+
+	"github.com/couchbase/query/search"
+	"github.com/couchbase/query/value"
+)
+
+///////////////////////////////////////////////////
+//
+// Search
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the search function SEARCH(keyspace, query [, options]).
+It returns true if the document bound to keyspace matches the full-text
+query string. Options is an object that may name the "analyzer" used to
+evaluate the query, and the "index" that the planner should use for it.
+When planned as a full-text index scan, only the indexed fields are
+searched.
+*/
+type Search struct {
+	FunctionBase
+}
+
+func NewSearch(operands ...Expression) Function {
+	rv := &Search{
+		*NewFunctionBase("search", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Search) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Search) Type() value.Type { return value.BOOLEAN }
+
+func (this *Search) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+/*
+SEARCH() is evaluated row by row, or pushed down to a full-text
+index by the planner; it is never an index key.
+*/
+func (this *Search) Indexable() bool {
+	return false
+}
+
+func (this *Search) Apply(context Context, args ...value.Value) (value.Value, error) {
+	doc := args[0]
+	qv := args[1]
+
+	if doc.Type() == value.MISSING || qv.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if qv.Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	analyzerName := ""
+	if len(args) > 2 {
+		options := args[2]
+		if options.Type() != value.OBJECT {
+			return value.NULL_VALUE, nil
+		}
+
+		name, ok := options.Field("analyzer")
+		if ok && name.Type() == value.STRING {
+			analyzerName = name.Actual().(string)
+		}
+	}
+
+	analyzer, ok := search.GetAnalyzer(analyzerName)
+	if !ok {
+		return nil, fmt.Errorf("Invalid search analyzer %s.", analyzerName)
+	}
+
+	query, err := search.ParseQuery(qv.Actual().(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return value.NewValue(search.Match(analyzer, query, doc.Actual())), nil
+}
+
+/*
+Minimum input arguments required is 2.
+*/
+func (this *Search) MinArgs() int { return 2 }
+
+/*
+Maximum input arguments allowed is 3.
+*/
+func (this *Search) MaxArgs() int { return 3 }
+
+/*
+Factory method pattern.
+*/
+func (this *Search) Constructor() FunctionConstructor {
+	return NewSearch
+}
+
+/*
+Returns the expression for the searched document.
+*/
+func (this *Search) Keyspace() Expression {
+	return this.operands[0]
+}
+
+/*
+Returns the expression for the query string.
+*/
+func (this *Search) Query() Expression {
+	return this.operands[1]
+}
+
+/*
+Returns the options expression, or nil.
+*/
+func (this *Search) Options() Expression {
+	if len(this.operands) > 2 {
+		return this.operands[2]
+	}
+
+	return nil
+}
+
+/*
+Returns the analyzer named in static options, or the default analyzer.
+*/
+func (this *Search) Analyzer() string {
+	name := this.option("analyzer")
+	if name == "" {
+		return search.DEFAULT_ANALYZER
+	}
+
+	return name
+}
+
+/*
+Returns the index named in static options, or the empty string.
+*/
+func (this *Search) IndexName() string {
+	return this.option("index")
+}
+
+func (this *Search) option(name string) string {
+	options := this.Options()
+	if options == nil {
+		return ""
+	}
+
+	ov := options.Value()
+	if ov == nil || ov.Type() != value.OBJECT {
+		return ""
+	}
+
+	v, ok := ov.Field(name)
+	if !ok || v.Type() != value.STRING {
+		return ""
+	}
+
+	return v.Actual().(string)
+}
+
+///////////////////////////////////////////////////
+//
+// SearchScore
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the search function SEARCH_SCORE([keyspace]). It
+returns the relevance score assigned by the full-text index scan that
+produced the document, or MISSING if the document was not produced by
+a full-text index scan.
+*/
+type SearchScore struct {
+	FunctionBase
+}
+
+func NewSearchScore(operands ...Expression) Function {
+	rv := &SearchScore{
+		*NewFunctionBase("search_score", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *SearchScore) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *SearchScore) Type() value.Type { return value.NUMBER }
+
+/*
+The score is attached to the document by the index scan as "smeta".
+Without an operand, the score attached to the current item is used.
+*/
+func (this *SearchScore) Evaluate(item value.Value, context Context) (value.Value, error) {
+	val := item
+
+	if len(this.operands) > 0 {
+		arg, err := this.operands[0].Evaluate(item, context)
+		if err != nil {
+			return nil, err
+		}
+
+		val = arg
+	}
+
+	switch val := val.(type) {
+	case value.AnnotatedValue:
+		smeta, ok := val.GetAttachment("smeta").(map[string]interface{})
+		if ok {
+			if score, ok := smeta["score"]; ok {
+				return value.NewValue(score), nil
+			}
+		}
+	}
+
+	return value.MISSING_VALUE, nil
+}
+
+func (this *SearchScore) Value() value.Value {
+	return nil
+}
+
+func (this *SearchScore) Static() Expression {
+	return nil
+}
+
+func (this *SearchScore) Indexable() bool {
+	return false
+}
+
+func (this *SearchScore) MinArgs() int { return 0 }
+
+func (this *SearchScore) MaxArgs() int { return 1 }
+
+/*
+Factory method pattern.
+*/
+func (this *SearchScore) Constructor() FunctionConstructor {
+	return NewSearchScore
+}
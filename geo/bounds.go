@@ -0,0 +1,130 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+Bounds is a bounding box. It is represented in JSON as the GeoJSON
+bbox array [min_lon, min_lat, max_lon, max_lat].
+*/
+type Bounds struct {
+	MinLon float64
+	MinLat float64
+	MaxLon float64
+	MaxLat float64
+}
+
+var WORLD = Bounds{-180.0, -90.0, 180.0, 90.0}
+
+func ParseBounds(val interface{}) (Bounds, error) {
+	a, ok := unwrap(val).([]interface{})
+	if !ok || len(a) != 4 {
+		return Bounds{}, fmt.Errorf("Invalid bounding box %v.", val)
+	}
+
+	return parseBounds(a)
+}
+
+func parseBounds(a []interface{}) (Bounds, error) {
+	var f [4]float64
+	for i, v := range a {
+		n, ok := toFloat(v)
+		if !ok {
+			return Bounds{}, fmt.Errorf("Invalid bounding box %v.", a)
+		}
+		f[i] = n
+	}
+
+	rv := Bounds{f[0], f[1], f[2], f[3]}
+	if !rv.Valid() {
+		return Bounds{}, fmt.Errorf("Invalid bounding box %v.", a)
+	}
+
+	return rv, nil
+}
+
+func (this Bounds) Valid() bool {
+	return Point{this.MinLon, this.MinLat}.Valid() &&
+		Point{this.MaxLon, this.MaxLat}.Valid() &&
+		this.MinLon <= this.MaxLon && this.MinLat <= this.MaxLat
+}
+
+/*
+Bounds returns the bounding box of the geometry.
+*/
+func (this *Geometry) Bounds() Bounds {
+	rv := Bounds{180.0, 90.0, -180.0, -90.0}
+	for _, p := range this.Vertices() {
+		rv.MinLon = math.Min(rv.MinLon, p.Lon)
+		rv.MinLat = math.Min(rv.MinLat, p.Lat)
+		rv.MaxLon = math.Max(rv.MaxLon, p.Lon)
+		rv.MaxLat = math.Max(rv.MaxLat, p.Lat)
+	}
+
+	return rv
+}
+
+/*
+Expand returns the bounding box of every position within distance
+meters of this bounding box. The result spans all longitudes when it
+would reach a pole or cross the antimeridian.
+*/
+func (this Bounds) Expand(distance float64) Bounds {
+	if distance <= 0 {
+		return this
+	}
+
+	dLat := degrees(distance / EARTH_RADIUS)
+	rv := Bounds{this.MinLon, this.MinLat - dLat, this.MaxLon, this.MaxLat + dLat}
+
+	if rv.MinLat <= -90.0 || rv.MaxLat >= 90.0 {
+		rv.MinLat = math.Max(rv.MinLat, -90.0)
+		rv.MaxLat = math.Min(rv.MaxLat, 90.0)
+		rv.MinLon, rv.MaxLon = -180.0, 180.0
+		return rv
+	}
+
+	maxLat := math.Max(math.Abs(rv.MinLat), math.Abs(rv.MaxLat))
+	dLon := dLat / math.Cos(radians(maxLat))
+	rv.MinLon -= dLon
+	rv.MaxLon += dLon
+
+	if rv.MinLon < -180.0 || rv.MaxLon > 180.0 {
+		rv.MinLon, rv.MaxLon = -180.0, 180.0
+	}
+
+	return rv
+}
+
+func (this Bounds) Intersects(other Bounds) bool {
+	return this.MinLon <= other.MaxLon && other.MinLon <= this.MaxLon &&
+		this.MinLat <= other.MaxLat && other.MinLat <= this.MaxLat
+}
+
+/*
+Ring returns the bounding box as a closed polygon ring.
+*/
+func (this Bounds) Ring() []Point {
+	return []Point{
+		Point{this.MinLon, this.MinLat},
+		Point{this.MaxLon, this.MinLat},
+		Point{this.MaxLon, this.MaxLat},
+		Point{this.MinLon, this.MaxLat},
+		Point{this.MinLon, this.MinLat},
+	}
+}
+
+func (this Bounds) Array() []interface{} {
+	return []interface{}{this.MinLon, this.MinLat, this.MaxLon, this.MaxLat}
+}
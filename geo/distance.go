@@ -0,0 +1,120 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package geo
+
+import (
+	"math"
+)
+
+// Mean radius of the Earth in meters
+const EARTH_RADIUS = 6371008.8
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180.0
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180.0 / math.Pi
+}
+
+/*
+Haversine returns the great-circle distance in meters between two
+points.
+*/
+func Haversine(p1, p2 Point) float64 {
+	return EARTH_RADIUS * angle(p1, p2)
+}
+
+// angle returns the central angle between two points, in radians.
+func angle(p1, p2 Point) float64 {
+	lat1 := radians(p1.Lat)
+	lat2 := radians(p2.Lat)
+	dLat := lat2 - lat1
+	dLon := radians(p2.Lon - p1.Lon)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * math.Atan2(math.Sqrt(a), math.Sqrt(math.Max(0, 1-a)))
+}
+
+// bearing returns the initial bearing from p1 to p2, in radians.
+func bearing(p1, p2 Point) float64 {
+	lat1 := radians(p1.Lat)
+	lat2 := radians(p2.Lat)
+	dLon := radians(p2.Lon - p1.Lon)
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Atan2(y, x)
+}
+
+/*
+segmentDistance returns the great-circle distance in meters from p to
+the nearest point of the great-circle arc from a to b.
+*/
+func segmentDistance(p, a, b Point) float64 {
+	if a == b {
+		return Haversine(p, a)
+	}
+
+	d13 := angle(a, p)
+	d12 := angle(a, b)
+	diff := bearing(a, p) - bearing(a, b)
+
+	// p is behind a
+	if math.Cos(diff) <= 0 {
+		return EARTH_RADIUS * d13
+	}
+
+	dxt := math.Asin(clamp(math.Sin(d13) * math.Sin(diff)))
+	dat := math.Acos(clamp(math.Cos(d13) / math.Cos(dxt)))
+
+	// p is beyond b
+	if dat >= d12 {
+		return Haversine(p, b)
+	}
+
+	return EARTH_RADIUS * math.Abs(dxt)
+}
+
+func clamp(x float64) float64 {
+	return math.Max(-1.0, math.Min(1.0, x))
+}
+
+/*
+Distance returns the great-circle distance in meters between the
+nearest points of two geometries, or 0 if they intersect.
+*/
+func Distance(g1, g2 *Geometry) float64 {
+	if Intersects(g1, g2) {
+		return 0.0
+	}
+
+	return math.Min(vertexDistance(g1, g2), vertexDistance(g2, g1))
+}
+
+// vertexDistance returns the least distance from a vertex of g1 to g2.
+func vertexDistance(g1, g2 *Geometry) float64 {
+	rv := math.Inf(1)
+	points := g2.Vertices()
+	segments := g2.Segments()
+
+	for _, p := range g1.Vertices() {
+		for _, q := range points {
+			rv = math.Min(rv, Haversine(p, q))
+		}
+
+		for _, s := range segments {
+			rv = math.Min(rv, segmentDistance(p, s[0], s[1]))
+		}
+	}
+
+	return rv
+}
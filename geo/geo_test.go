@@ -0,0 +1,119 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package geo
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+var london = Point{Lon: -0.1278, Lat: 51.5074}
+var paris = Point{Lon: 2.3522, Lat: 48.8566}
+
+func parseJSON(t *testing.T, s string) *Geometry {
+	var val interface{}
+	err := json.Unmarshal([]byte(s), &val)
+	if err != nil {
+		t.Fatalf("Invalid JSON %s: %v", s, err)
+	}
+
+	g, err := Parse(val)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing %s: %v", s, err)
+	}
+	return g
+}
+
+func TestHaversine(t *testing.T) {
+	d := Haversine(london, paris)
+	if math.Abs(d-343500) > 1500 {
+		t.Errorf("Expected about 343.5 km from London to Paris, got %v", d)
+	}
+
+	if Haversine(london, london) != 0 {
+		t.Errorf("Expected zero distance to self")
+	}
+}
+
+func TestGeohash(t *testing.T) {
+	hash := Geohash(Point{Lon: 10.40744, Lat: 57.64911}, 11)
+	if hash != "u4pruydqqvj" {
+		t.Errorf("Expected geohash u4pruydqqvj, got %s", hash)
+	}
+}
+
+func TestCover(t *testing.T) {
+	b := NewPoint(london.Lon, london.Lat).Bounds().Expand(1000)
+	cells := Cover(b, MAX_PRECISION, 64)
+	if len(cells) == 0 || len(cells) > 64 {
+		t.Fatalf("Expected 1 to 64 cells, got %d", len(cells))
+	}
+
+	hash := Geohash(london, MAX_PRECISION)
+	found := false
+	for _, cell := range cells {
+		if strings.HasPrefix(hash, cell) {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected cover %v to contain %s", cells, hash)
+	}
+}
+
+func TestParse(t *testing.T) {
+	g := parseJSON(t, `{"type": "Point", "coordinates": [2.3522, 48.8566]}`)
+	if len(g.Points) != 1 || g.Points[0] != paris {
+		t.Errorf("Unexpected GeoJSON point %v", g.Points)
+	}
+
+	g = parseJSON(t, `{"lat": 51.5074, "lon": -0.1278}`)
+	if len(g.Points) != 1 || g.Points[0] != london {
+		t.Errorf("Unexpected lat/lon point %v", g.Points)
+	}
+
+	g = parseJSON(t, `[-1, 50, 3, 52]`)
+	if len(g.Polygons) != 1 {
+		t.Errorf("Expected bounding box polygon, got %v", g)
+	}
+
+	var val interface{}
+	json.Unmarshal([]byte(`{"type": "Point", "coordinates": [200, 10]}`), &val)
+	if _, err := Parse(val); err == nil {
+		t.Errorf("Expected error for out of range longitude")
+	}
+}
+
+func TestRelate(t *testing.T) {
+	box := parseJSON(t, `[-1, 50, 3, 52]`)
+	ln := NewPoint(london.Lon, london.Lat)
+	pa := NewPoint(paris.Lon, paris.Lat)
+
+	if !Within(ln, box) || Within(pa, box) {
+		t.Errorf("Expected London and not Paris within %v", box.Bounds())
+	}
+
+	line := parseJSON(t, `{"type": "LineString", "coordinates": [[-2, 51], [4, 51]]}`)
+	if !Intersects(line, box) || Within(line, box) {
+		t.Errorf("Expected line to intersect but not be within box")
+	}
+
+	if Distance(ln, box) != 0 {
+		t.Errorf("Expected zero distance to containing box")
+	}
+
+	d := Distance(pa, ln)
+	if math.Abs(d-Haversine(london, paris)) > 1 {
+		t.Errorf("Expected point distance, got %v", d)
+	}
+}
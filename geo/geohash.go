@@ -0,0 +1,114 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package geo
+
+import (
+	"math"
+)
+
+const _BASE32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+const MAX_PRECISION = 12
+
+/*
+Geohash encodes a point as a geohash of the given precision, in
+characters. Each additional character divides the cell into 32.
+*/
+func Geohash(p Point, precision int) string {
+	minLon, maxLon := -180.0, 180.0
+	minLat, maxLat := -90.0, 90.0
+
+	rv := make([]byte, precision)
+	bit := 0
+	ch := 0
+	even := true
+
+	for i := 0; i < precision; {
+		if even {
+			mid := (minLon + maxLon) / 2
+			if p.Lon >= mid {
+				ch |= 1 << uint(4-bit)
+				minLon = mid
+			} else {
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if p.Lat >= mid {
+				ch |= 1 << uint(4-bit)
+				minLat = mid
+			} else {
+				maxLat = mid
+			}
+		}
+
+		even = !even
+		if bit < 4 {
+			bit++
+		} else {
+			rv[i] = _BASE32[ch]
+			i++
+			bit = 0
+			ch = 0
+		}
+	}
+
+	return string(rv)
+}
+
+// cellSize returns the width and height in degrees of geohash cells.
+func cellSize(precision int) (float64, float64) {
+	lonBits := (5*precision + 1) / 2
+	latBits := 5 * precision / 2
+	return 360.0 / math.Pow(2, float64(lonBits)), 180.0 / math.Pow(2, float64(latBits))
+}
+
+/*
+Cover returns geohash cells that together cover the bounding box,
+using the greatest precision up to maxPrecision that needs at most
+maxCells cells. If no precision qualifies, it returns the empty
+geohash, which covers the world.
+*/
+func Cover(b Bounds, maxPrecision, maxCells int) []string {
+	for precision := maxPrecision; precision > 0; precision-- {
+		w, h := cellSize(precision)
+		x0, x1 := cellRange(b.MinLon+180.0, b.MaxLon+180.0, w, 360.0)
+		y0, y1 := cellRange(b.MinLat+90.0, b.MaxLat+90.0, h, 180.0)
+
+		if (x1-x0+1)*(y1-y0+1) > maxCells {
+			continue
+		}
+
+		rv := make([]string, 0, (x1-x0+1)*(y1-y0+1))
+		for x := x0; x <= x1; x++ {
+			for y := y0; y <= y1; y++ {
+				center := Point{-180.0 + (float64(x)+0.5)*w, -90.0 + (float64(y)+0.5)*h}
+				rv = append(rv, Geohash(center, precision))
+			}
+		}
+
+		return rv
+	}
+
+	return []string{""}
+}
+
+func cellRange(low, high, size, extent float64) (int, int) {
+	last := int(extent/size) - 1
+	i0 := int(math.Floor(low / size))
+	i1 := int(math.Floor(high / size))
+	if i0 < 0 {
+		i0 = 0
+	}
+	if i1 > last {
+		i1 = last
+	}
+	return i0, i1
+}
@@ -0,0 +1,361 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+
+Package geo provides the geometry, great-circle distance and geohash
+support used by the geospatial functions and the spatial indexes.
+
+Coordinates are longitude and latitude in degrees, in that order, as
+in GeoJSON. Distances are great-circle distances in meters. Spatial
+relations (within, intersects) treat edges as straight lines in
+longitude and latitude, as GeoJSON does, and do not wrap around the
+antimeridian.
+
+*/
+package geo
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Point struct {
+	Lon float64
+	Lat float64
+}
+
+/*
+Geometry is a parsed GeoJSON geometry. Multi-geometries and geometry
+collections are flattened into their points, lines and polygons. The
+first ring of each polygon is its exterior; the others are holes.
+*/
+type Geometry struct {
+	Points   []Point
+	Lines    [][]Point
+	Polygons [][][]Point
+}
+
+func NewPoint(lon, lat float64) *Geometry {
+	return &Geometry{Points: []Point{Point{lon, lat}}}
+}
+
+/*
+Parse converts a JSON-like value, as returned by value.Value.Actual(),
+into a Geometry. Besides GeoJSON geometries and features, it accepts
+objects with "lat" and "lon" (or "lng") fields, [lon, lat] pairs, and
+[min_lon, min_lat, max_lon, max_lat] bounding boxes.
+*/
+func Parse(val interface{}) (*Geometry, error) {
+	rv := &Geometry{}
+	err := rv.add(unwrap(val))
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+/*
+unwrap replaces nested values that wrap their JSON representation,
+such as the elements of constructed arrays and objects, by that
+representation.
+*/
+func unwrap(val interface{}) interface{} {
+	if v, ok := val.(interface {
+		Actual() interface{}
+	}); ok {
+		val = v.Actual()
+	}
+
+	switch val := val.(type) {
+	case []interface{}:
+		rv := make([]interface{}, len(val))
+		for i, v := range val {
+			rv[i] = unwrap(v)
+		}
+		return rv
+	case map[string]interface{}:
+		rv := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			rv[k] = unwrap(v)
+		}
+		return rv
+	}
+
+	return val
+}
+
+func (this *Geometry) add(val interface{}) error {
+	switch val := val.(type) {
+	case []interface{}:
+		switch len(val) {
+		case 2:
+			p, err := parsePoint(val)
+			if err != nil {
+				return err
+			}
+			this.Points = append(this.Points, p)
+			return nil
+		case 4:
+			b, err := parseBounds(val)
+			if err != nil {
+				return err
+			}
+			this.Polygons = append(this.Polygons, [][]Point{b.Ring()})
+			return nil
+		}
+	case map[string]interface{}:
+		t, ok := val["type"].(string)
+		if !ok {
+			return this.addLatLon(val)
+		}
+
+		return this.addGeoJSON(t, val)
+	}
+
+	return fmt.Errorf("Invalid geometry %v.", val)
+}
+
+func (this *Geometry) addLatLon(val map[string]interface{}) error {
+	lat, ok1 := toFloat(val["lat"])
+	lon, ok2 := toFloat(val["lon"])
+	if !ok2 {
+		lon, ok2 = toFloat(val["lng"])
+	}
+
+	if !ok1 || !ok2 {
+		return fmt.Errorf("Invalid geometry %v.", val)
+	}
+
+	p := Point{lon, lat}
+	if !p.Valid() {
+		return fmt.Errorf("Invalid coordinates %v.", val)
+	}
+
+	this.Points = append(this.Points, p)
+	return nil
+}
+
+func (this *Geometry) addGeoJSON(t string, val map[string]interface{}) error {
+	switch strings.ToLower(t) {
+	case "feature":
+		return this.add(val["geometry"])
+	case "geometrycollection":
+		geometries, ok := val["geometries"].([]interface{})
+		if !ok {
+			return fmt.Errorf("Invalid geometry collection %v.", val)
+		}
+
+		for _, g := range geometries {
+			err := this.add(g)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	coords, ok := val["coordinates"].([]interface{})
+	if !ok {
+		return fmt.Errorf("Missing coordinates in geometry %v.", val)
+	}
+
+	switch strings.ToLower(t) {
+	case "point":
+		p, err := parsePoint(coords)
+		if err != nil {
+			return err
+		}
+		this.Points = append(this.Points, p)
+	case "multipoint":
+		points, err := parsePoints(coords, 1)
+		if err != nil {
+			return err
+		}
+		this.Points = append(this.Points, points...)
+	case "linestring":
+		line, err := parsePoints(coords, 2)
+		if err != nil {
+			return err
+		}
+		this.Lines = append(this.Lines, line)
+	case "multilinestring":
+		for _, c := range coords {
+			line, err := parsePoints(c, 2)
+			if err != nil {
+				return err
+			}
+			this.Lines = append(this.Lines, line)
+		}
+	case "polygon":
+		polygon, err := parsePolygon(coords)
+		if err != nil {
+			return err
+		}
+		this.Polygons = append(this.Polygons, polygon)
+	case "multipolygon":
+		for _, c := range coords {
+			rings, ok := c.([]interface{})
+			if !ok {
+				return fmt.Errorf("Invalid polygon %v.", c)
+			}
+
+			polygon, err := parsePolygon(rings)
+			if err != nil {
+				return err
+			}
+			this.Polygons = append(this.Polygons, polygon)
+		}
+	default:
+		return fmt.Errorf("Unsupported geometry type %s.", t)
+	}
+
+	return nil
+}
+
+func parsePoint(val interface{}) (Point, error) {
+	coords, ok := val.([]interface{})
+	if !ok || len(coords) < 2 {
+		return Point{}, fmt.Errorf("Invalid position %v.", val)
+	}
+
+	lon, ok1 := toFloat(coords[0])
+	lat, ok2 := toFloat(coords[1])
+	p := Point{lon, lat}
+	if !ok1 || !ok2 || !p.Valid() {
+		return Point{}, fmt.Errorf("Invalid position %v.", val)
+	}
+
+	return p, nil
+}
+
+func parsePoints(val interface{}, min int) ([]Point, error) {
+	coords, ok := val.([]interface{})
+	if !ok || len(coords) < min {
+		return nil, fmt.Errorf("Invalid positions %v.", val)
+	}
+
+	rv := make([]Point, len(coords))
+	for i, c := range coords {
+		p, err := parsePoint(c)
+		if err != nil {
+			return nil, err
+		}
+		rv[i] = p
+	}
+
+	return rv, nil
+}
+
+func parsePolygon(coords []interface{}) ([][]Point, error) {
+	if len(coords) == 0 {
+		return nil, fmt.Errorf("Empty polygon.")
+	}
+
+	rv := make([][]Point, len(coords))
+	for i, c := range coords {
+		ring, err := parsePoints(c, 3)
+		if err != nil {
+			return nil, err
+		}
+
+		// Close the ring if needed
+		if ring[0] != ring[len(ring)-1] {
+			ring = append(ring, ring[0])
+		}
+
+		rv[i] = ring
+	}
+
+	return rv, nil
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	switch val := val.(type) {
+	case float64:
+		return val, true
+	case int64:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	}
+
+	return 0, false
+}
+
+func (this Point) Valid() bool {
+	return this.Lon >= -180.0 && this.Lon <= 180.0 &&
+		this.Lat >= -90.0 && this.Lat <= 90.0
+}
+
+/*
+Vertices returns every position of the geometry.
+*/
+func (this *Geometry) Vertices() []Point {
+	rv := make([]Point, 0, len(this.Points)+4*(len(this.Lines)+len(this.Polygons)))
+	rv = append(rv, this.Points...)
+	for _, line := range this.Lines {
+		rv = append(rv, line...)
+	}
+
+	for _, polygon := range this.Polygons {
+		for _, ring := range polygon {
+			rv = append(rv, ring...)
+		}
+	}
+
+	return rv
+}
+
+/*
+Segments returns the edges of the lines and polygon rings of the
+geometry.
+*/
+func (this *Geometry) Segments() [][2]Point {
+	var rv [][2]Point
+	add := func(line []Point) {
+		for i := 1; i < len(line); i++ {
+			rv = append(rv, [2]Point{line[i-1], line[i]})
+		}
+	}
+
+	for _, line := range this.Lines {
+		add(line)
+	}
+
+	for _, polygon := range this.Polygons {
+		for _, ring := range polygon {
+			add(ring)
+		}
+	}
+
+	return rv
+}
+
+func (this *Geometry) Empty() bool {
+	return len(this.Points) == 0 && len(this.Lines) == 0 && len(this.Polygons) == 0
+}
+
+/*
+ToGeoJSON returns the GeoJSON representation of a single point
+geometry, or nil.
+*/
+func (this *Geometry) ToGeoJSON() map[string]interface{} {
+	if len(this.Points) != 1 || len(this.Lines) != 0 || len(this.Polygons) != 0 {
+		return nil
+	}
+
+	p := this.Points[0]
+	return map[string]interface{}{
+		"type":        "Point",
+		"coordinates": []interface{}{p.Lon, p.Lat},
+	}
+}
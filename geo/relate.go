@@ -0,0 +1,170 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package geo
+
+import (
+	"math"
+)
+
+/*
+Intersects returns true if the geometries have at least one point in
+common, including their boundaries.
+*/
+func Intersects(g1, g2 *Geometry) bool {
+	if !g1.Bounds().Intersects(g2.Bounds()) {
+		return false
+	}
+
+	for _, p := range g1.Vertices() {
+		if pointIntersects(p, g2) {
+			return true
+		}
+	}
+
+	for _, p := range g2.Vertices() {
+		if pointIntersects(p, g1) {
+			return true
+		}
+	}
+
+	segments := g2.Segments()
+	for _, s1 := range g1.Segments() {
+		for _, s2 := range segments {
+			if segmentsIntersect(s1[0], s1[1], s2[0], s2[1]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/*
+Within returns true if every point of g1 is in g2, including its
+boundary. Edges of g1 must not cross the boundary of g2.
+*/
+func Within(g1, g2 *Geometry) bool {
+	if g1.Empty() {
+		return false
+	}
+
+	for _, p := range g1.Vertices() {
+		if !pointIntersects(p, g2) {
+			return false
+		}
+	}
+
+	segments := g2.Segments()
+	for _, s1 := range g1.Segments() {
+		for _, s2 := range segments {
+			if segmentsCross(s1[0], s1[1], s2[0], s2[1]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// pointIntersects returns true if p is in or on g.
+func pointIntersects(p Point, g *Geometry) bool {
+	for _, q := range g.Points {
+		if p == q {
+			return true
+		}
+	}
+
+	for _, s := range g.Segments() {
+		if onSegment(p, s[0], s[1]) {
+			return true
+		}
+	}
+
+	for _, polygon := range g.Polygons {
+		if inPolygon(p, polygon) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inPolygon returns true if p is inside the exterior ring and outside the holes.
+func inPolygon(p Point, polygon [][]Point) bool {
+	if !inRing(p, polygon[0]) {
+		return false
+	}
+
+	for _, hole := range polygon[1:] {
+		if inRing(p, hole) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// inRing uses ray casting; points on edges are handled by onSegment.
+func inRing(p Point, ring []Point) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			in = !in
+		}
+	}
+
+	return in
+}
+
+// orientation returns the sign of the cross product (b-a) x (c-a).
+func orientation(a, b, c Point) int {
+	v := (b.Lon-a.Lon)*(c.Lat-a.Lat) - (b.Lat-a.Lat)*(c.Lon-a.Lon)
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// onSegment returns true if p lies on the segment from a to b.
+func onSegment(p, a, b Point) bool {
+	return orientation(a, b, p) == 0 &&
+		p.Lon >= math.Min(a.Lon, b.Lon) && p.Lon <= math.Max(a.Lon, b.Lon) &&
+		p.Lat >= math.Min(a.Lat, b.Lat) && p.Lat <= math.Max(a.Lat, b.Lat)
+}
+
+func segmentsIntersect(a, b, c, d Point) bool {
+	o1 := orientation(a, b, c)
+	o2 := orientation(a, b, d)
+	o3 := orientation(c, d, a)
+	o4 := orientation(c, d, b)
+
+	if o1 != o2 && o3 != o4 {
+		return true
+	}
+
+	return onSegment(c, a, b) || onSegment(d, a, b) ||
+		onSegment(a, c, d) || onSegment(b, c, d)
+}
+
+// segmentsCross returns true if the segments cross at a point interior to both.
+func segmentsCross(a, b, c, d Point) bool {
+	o1 := orientation(a, b, c)
+	o2 := orientation(a, b, d)
+	o3 := orientation(c, d, a)
+	o4 := orientation(c, d, b)
+
+	return o1*o2 < 0 && o3*o4 < 0
+}
@@ -20,6 +20,18 @@ import (
 )
 
 func ParseStatement(input string) (algebra.Statement, error) {
+	return parseStatement(input, false)
+}
+
+/*
+ParseTriggerStatement parses the statement of a trigger, in which the
+identifiers NEW and OLD are the documents after and before a mutation.
+*/
+func ParseTriggerStatement(input string) (algebra.Statement, error) {
+	return parseStatement(input, true)
+}
+
+func parseStatement(input string, trigger bool) (algebra.Statement, error) {
 	input = strings.TrimSpace(input)
 	reader := strings.NewReader(input)
 	lex := newLexer(NewLexer(reader))
@@ -33,6 +45,13 @@ func ParseStatement(input string) (algebra.Statement, error) {
 	} else if lex.stmt == nil {
 		return nil, fmt.Errorf("Input was not a statement.")
 	} else {
+		if trigger {
+			err := algebra.BindTriggerParameters(lex.stmt)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		err := lex.stmt.Formalize()
 		if err != nil {
 			return nil, err
@@ -82,6 +101,9 @@ type lexer struct {
 	expr        expression.Expression
 	parsingStmt bool
 	text        string
+	peeked      bool
+	peekToken   int
+	peekLval    yySymType
 }
 
 func newLexer(nex *Lexer) *lexer {
@@ -91,8 +113,48 @@ func newLexer(nex *Lexer) *lexer {
 	}
 }
 
+/*
+Optimizer hint comments are not passed to the parser. If they
+immediately follow a SELECT, UPDATE, DELETE or MERGE keyword, they are
+returned with that keyword, and are otherwise ordinary comments.
+*/
 func (this *lexer) Lex(lval *yySymType) int {
-	return this.nex.Lex(lval)
+	token := this.lex(lval)
+
+	switch token {
+	case SELECT, UPDATE, DELETE, MERGE:
+		var next yySymType
+		var hints algebra.OptimHints
+		nextToken := this.nex.Lex(&next)
+		for nextToken == OPTIM_HINTS {
+			hints = append(hints, parseOptimHints(next.s)...)
+			nextToken = this.nex.Lex(&next)
+		}
+
+		lval.optimHints = hints
+		this.peeked, this.peekToken, this.peekLval = true, nextToken, next
+	}
+
+	return token
+}
+
+/*
+Returns the next token that is not a hint comment, starting with the
+token read past a keyword's hints.
+*/
+func (this *lexer) lex(lval *yySymType) int {
+	if this.peeked {
+		this.peeked = false
+		*lval = this.peekLval
+		return this.peekToken
+	}
+
+	for {
+		token := this.nex.Lex(lval)
+		if token != OPTIM_HINTS {
+			return token
+		}
+	}
 }
 
 func (this *lexer) Remainder(offset int) string {
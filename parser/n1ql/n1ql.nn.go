@@ -31110,6 +31110,11 @@ func (yylex *Lexer) Lex(lval *yySymType) int {
 			continue
 		case 7:
 			{
+				if isOptimHints(yylex.Text()) {
+					lval.s = yylex.Text()
+					logToken(yylex.Text(), "OPTIM_HINTS (length=%d)", len(yylex.Text()))
+					return OPTIM_HINTS
+				}
 				logToken(yylex.Text(), "BLOCK_COMMENT (length=%d)", len(yylex.Text())) /* eat up block comment */
 			}
 			continue
@@ -31608,6 +31613,7 @@ func (yylex *Lexer) Lex(lval *yySymType) int {
 		case 90:
 			{
 				logToken(yylex.Text(), "FOR")
+				lval.tokOffset = curOffset
 				return FOR
 			}
 			continue
@@ -68,6 +68,8 @@ mergeUpdate      *algebra.MergeUpdate
 mergeDelete      *algebra.MergeDelete
 mergeInsert      *algebra.MergeInsert
 
+optimHints       algebra.OptimHints
+
 indexType        datastore.IndexType
 inferenceType    datastore.InferenceType
 val              value.Value
@@ -254,6 +256,9 @@ tokOffset	 int
 %token LBRACE RBRACE LBRACKET RBRACKET RBRACKET_ICASE
 %token COMMA COLON
 
+/* Optimizer hint comments, consumed by the lexer and never parsed */
+%token OPTIM_HINTS
+
 /* Precedence: lowest to highest */
 %left           ORDER
 %left           UNION INTERESECT EXCEPT
@@ -298,7 +303,7 @@ tokOffset	 int
 %type <binding>          binding
 %type <bindings>         bindings
 
-%type <s>                alias as_alias opt_as_alias variable opt_name
+%type <s>                alias as_alias opt_as_alias variable prepare_name
 
 %type <expr>             case_expr simple_or_searched_case simple_case searched_case opt_else
 %type <whenTerms>        when_thens
@@ -315,7 +320,7 @@ tokOffset	 int
 %type <expr>             paren_expr
 %type <subquery>         subquery_expr
 
-%type <fullselect>       fullselect
+%type <fullselect>       fullselect advise_select
 %type <subresult>        select_term select_terms
 %type <subselect>        subselect
 %type <subselect>        select_from
@@ -349,6 +354,13 @@ tokOffset	 int
 %type <statement>        infer infer_keyspace
 %type <statement>        insert upsert delete update merge
 %type <statement>        index_stmt create_index drop_index alter_index build_index
+%type <statement>        trigger_stmt create_trigger drop_trigger
+%type <statement>        view_stmt create_view refresh_view drop_view
+%type <statement>        create_named_view drop_named_view
+%type <statement>        sequence_stmt create_sequence drop_sequence
+%type <statement>        plan_baseline_stmt create_plan_baseline drop_plan_baseline
+%type <statement>        alter_keyspace validate advise
+%type <val>              opt_sequence_options sequence_options sequence_option
 
 %type <keyspaceRef>      keyspace_ref
 %type <pairs>            values values_list next_values
@@ -371,6 +383,8 @@ tokOffset	 int
 
 %type <s>                index_name opt_primary_name
 %type <ss>               index_names
+%type <ss>               trigger_events
+%type <s>                trigger_event
 %type <keyspaceRef>      named_keyspace_ref
 %type <exprs>            index_partition
 %type <indexType>        index_using opt_index_using
@@ -421,28 +435,48 @@ prepare
 execute
 |
 infer
+|
+validate
+|
+advise
 ;
 
 explain:
 EXPLAIN stmt
 {
-    $$ = algebra.NewExplain($2, yylex.(*lexer).Remainder($<tokOffset>1))
+    $$ = algebra.NewExplain($2, yylex.(*lexer).Remainder($<tokOffset>1), "")
+}
+|
+EXPLAIN IDENT IDENT stmt
+{
+    if strings.ToUpper($2) != "FORMAT" {
+	yylex.Error("Unexpected " + $2 + " after EXPLAIN.")
+    }
+    format := strings.ToLower($3)
+    switch format {
+    case algebra.EXPLAIN_FORMAT_JSON, algebra.EXPLAIN_FORMAT_TEXT, algebra.EXPLAIN_FORMAT_DOT:
+    default:
+	yylex.Error("Unknown EXPLAIN format " + $3 + "; expected TEXT, DOT or JSON.")
+    }
+    text := skipWords(yylex.(*lexer).Remainder($<tokOffset>1), 2)
+    $$ = algebra.NewExplain($4, text, format)
 }
 ;
 
 prepare:
-PREPARE opt_name stmt
+PREPARE stmt
+{
+    $$ = algebra.NewPrepare("", $2, yylex.(*lexer).getText())
+}
+|
+PREPARE prepare_name stmt
 {
     $$ = algebra.NewPrepare($2, $3, yylex.(*lexer).getText())
 }
 ;
 
-opt_name:
-/* empty */
-{
-    $$ = ""
-}
-|
+/* The name is not optional here, so that statements may begin with IDENT */
+prepare_name:
 IDENT from_or_as
 {
     $$ = $1
@@ -533,6 +567,16 @@ merge
 
 ddl_stmt:
 index_stmt
+|
+trigger_stmt
+|
+view_stmt
+|
+sequence_stmt
+|
+plan_baseline_stmt
+|
+alter_keyspace
 ;
 
 index_stmt:
@@ -657,6 +701,7 @@ from_select:
 from opt_let opt_where opt_group select_clause
 {
     $$ = algebra.NewSubselect($1, $2, $3, $4, $5)
+    $$.SetOptimHints($<optimHints>5)
 }
 ;
 
@@ -664,6 +709,7 @@ select_from:
 select_clause opt_from opt_let opt_where opt_group
 {
     $$ = algebra.NewSubselect($2, $3, $4, $5, $1)
+    $$.SetOptimHints($<optimHints>1)
 }
 ;
 
@@ -679,6 +725,7 @@ SELECT
 projection
 {
     $$ = $2
+    $<optimHints>$ = $<optimHints>1
 }
 ;
 
@@ -1396,6 +1443,7 @@ delete:
 DELETE FROM keyspace_ref opt_use opt_where opt_limit opt_returning
 {
     $$ = algebra.NewDelete($3, $4.Keys(), $4.Indexes(), $5, $6, $7)
+    $$.SetOptimHints($<optimHints>1)
 }
 ;
 
@@ -1410,16 +1458,19 @@ update:
 UPDATE keyspace_ref opt_use set unset opt_where opt_limit opt_returning
 {
     $$ = algebra.NewUpdate($2, $3.Keys(), $3.Indexes(), $4, $5, $6, $7, $8)
+    $$.SetOptimHints($<optimHints>1)
 }
 |
 UPDATE keyspace_ref opt_use set opt_where opt_limit opt_returning
 {
     $$ = algebra.NewUpdate($2, $3.Keys(), $3.Indexes(), $4, nil, $5, $6, $7)
+    $$.SetOptimHints($<optimHints>1)
 }
 |
 UPDATE keyspace_ref opt_use unset opt_where opt_limit opt_returning
 {
     $$ = algebra.NewUpdate($2, $3.Keys(), $3.Indexes(), nil, $4, $5, $6, $7)
+    $$.SetOptimHints($<optimHints>1)
 }
 ;
 
@@ -1566,13 +1617,29 @@ merge:
 MERGE INTO keyspace_ref USING keyspace_term ON key_expr merge_actions opt_limit opt_returning
 {
     source := algebra.NewMergeSourceFrom($5, "")
-    $$ = algebra.NewMerge($3, source, $7, $8, $9, $10)
+    $$ = algebra.NewMerge($3, source, $7, nil, $8, $9, $10)
+    $$.SetOptimHints($<optimHints>1)
 }
 |
 MERGE INTO keyspace_ref USING LPAREN fullselect RPAREN as_alias ON key_expr merge_actions opt_limit opt_returning
 {
     source := algebra.NewMergeSourceSelect($6, $8)
-    $$ = algebra.NewMerge($3, source, $10, $11, $12, $13)
+    $$ = algebra.NewMerge($3, source, $10, nil, $11, $12, $13)
+    $$.SetOptimHints($<optimHints>1)
+}
+|
+MERGE INTO keyspace_ref USING keyspace_term ON expr merge_actions opt_limit opt_returning
+{
+    source := algebra.NewMergeSourceFrom($5, "")
+    $$ = algebra.NewMerge($3, source, nil, $7, $8, $9, $10)
+    $$.SetOptimHints($<optimHints>1)
+}
+|
+MERGE INTO keyspace_ref USING LPAREN fullselect RPAREN as_alias ON expr merge_actions opt_limit opt_returning
+{
+    source := algebra.NewMergeSourceSelect($6, $8)
+    $$ = algebra.NewMerge($3, source, nil, $10, $11, $12, $13)
+    $$.SetOptimHints($<optimHints>1)
 }
 ;
 
@@ -1654,7 +1721,12 @@ opt_where
 merge_insert:
 expr opt_where
 {
-    $$ = algebra.NewMergeInsert($1, $2)
+    $$ = algebra.NewMergeInsert(nil, $1, $2)
+}
+|
+LPAREN KEY expr COMMA VALUE expr RPAREN opt_where
+{
+    $$ = algebra.NewMergeInsert($3, $6, $8)
 }
 ;
 
@@ -1733,6 +1805,18 @@ USING GSI
 {
     $$ = datastore.GSI
 }
+|
+USING IDENT
+{
+    switch datastore.IndexType(strings.ToLower($2)) {
+    case datastore.FTS:
+        $$ = datastore.FTS
+    case datastore.GEO:
+        $$ = datastore.GEO
+    default:
+        yylex.Error(fmt.Sprintf("Invalid index type %s.", $2))
+    }
+}
 ;
 
 opt_index_with:
@@ -1883,6 +1967,395 @@ index_names COMMA index_name
 ;
 
 
+/*************************************************
+ *
+ * CREATE TRIGGER
+ *
+ *************************************************/
+
+trigger_stmt:
+create_trigger
+|
+drop_trigger
+;
+
+create_trigger:
+CREATE TRIGGER IDENT ON named_keyspace_ref IDENT trigger_events opt_index_with AS stmt
+{
+    if strings.ToUpper($6) != "AFTER" {
+	yylex.Error("CREATE TRIGGER expects AFTER, not " + $6 + ".")
+    }
+    text := strings.TrimRight(yylex.(*lexer).Remainder($<tokOffset>9), " \t\n;")
+    $$ = algebra.NewCreateTrigger($3, $5, $7, $8, $10, text)
+}
+;
+
+trigger_events:
+trigger_event
+{
+    $$ = []string{$1}
+}
+|
+trigger_events OR trigger_event
+{
+    $$ = append($1, $3)
+}
+;
+
+trigger_event:
+INSERT
+{
+    $$ = datastore.TRIGGER_INSERT
+}
+|
+UPDATE
+{
+    $$ = datastore.TRIGGER_UPDATE
+}
+|
+UPSERT
+{
+    $$ = datastore.TRIGGER_UPSERT
+}
+|
+DELETE
+{
+    $$ = datastore.TRIGGER_DELETE
+}
+;
+
+/*************************************************
+ *
+ * DROP TRIGGER
+ *
+ *************************************************/
+
+drop_trigger:
+DROP TRIGGER named_keyspace_ref DOT IDENT
+{
+    $$ = algebra.NewDropTrigger($3, $5)
+}
+;
+
+
+/*************************************************
+ *
+ * CREATE MATERIALIZED VIEW
+ *
+ *************************************************/
+
+view_stmt:
+create_view
+|
+refresh_view
+|
+drop_view
+|
+create_named_view
+|
+drop_named_view
+;
+
+create_view:
+CREATE MATERIALIZED VIEW named_keyspace_ref opt_index_with AS fullselect
+{
+    text := strings.TrimRight(yylex.(*lexer).Remainder($<tokOffset>6), " \t\n;")
+    $$ = algebra.NewCreateMaterializedView($4, $5, $7, text)
+}
+;
+
+
+/*************************************************
+ *
+ * REFRESH MATERIALIZED VIEW
+ *
+ *************************************************/
+
+refresh_view:
+IDENT MATERIALIZED VIEW named_keyspace_ref
+{
+    if strings.ToUpper($1) != "REFRESH" {
+	yylex.Error("Unexpected " + $1 + " before MATERIALIZED VIEW.")
+    }
+    $$ = algebra.NewRefreshMaterializedView($4)
+}
+;
+
+
+/*************************************************
+ *
+ * DROP MATERIALIZED VIEW
+ *
+ *************************************************/
+
+drop_view:
+DROP MATERIALIZED VIEW named_keyspace_ref
+{
+    $$ = algebra.NewDropMaterializedView($4)
+}
+;
+
+
+/*************************************************
+ *
+ * CREATE VIEW
+ *
+ *************************************************/
+
+create_named_view:
+CREATE VIEW named_keyspace_ref AS fullselect
+{
+    text := strings.TrimRight(yylex.(*lexer).Remainder($<tokOffset>4), " \t\n;")
+    $$ = algebra.NewCreateView($3, $5, text)
+}
+;
+
+
+/*************************************************
+ *
+ * DROP VIEW
+ *
+ *************************************************/
+
+drop_named_view:
+DROP VIEW named_keyspace_ref
+{
+    $$ = algebra.NewDropView($3)
+}
+;
+
+
+/*************************************************
+ *
+ * CREATE SEQUENCE
+ *
+ *************************************************/
+
+sequence_stmt:
+create_sequence
+|
+drop_sequence
+;
+
+create_sequence:
+CREATE IDENT named_keyspace_ref opt_sequence_options
+{
+    if strings.ToUpper($2) != "SEQUENCE" {
+	yylex.Error("Unexpected " + $2 + " after CREATE.")
+    }
+    $$ = algebra.NewCreateSequence($3, $4)
+}
+;
+
+opt_sequence_options:
+/* empty */
+{
+    $$ = nil
+}
+|
+sequence_options
+;
+
+sequence_options:
+sequence_option
+|
+sequence_options sequence_option
+{
+    $$ = $1
+    for name, option := range $2.Fields() {
+	if _, ok := $$.Field(name); ok {
+	    yylex.Error("Duplicate sequence option " + strings.ToUpper(name) + ".")
+	}
+	$$.SetField(name, option)
+    }
+}
+;
+
+sequence_option:
+START WITH expr
+{
+    $$ = value.NewValue(map[string]interface{}{"start": $3.Value()})
+    if $3.Value() == nil {
+	yylex.Error("START WITH value must be static.")
+    }
+}
+|
+INCREMENT BY expr
+{
+    $$ = value.NewValue(map[string]interface{}{"increment": $3.Value()})
+    if $3.Value() == nil {
+	yylex.Error("INCREMENT BY value must be static.")
+    }
+}
+|
+DECREMENT BY expr
+{
+    $$ = value.NewValue(map[string]interface{}{"decrement": $3.Value()})
+    if $3.Value() == nil {
+	yylex.Error("DECREMENT BY value must be static.")
+    }
+}
+|
+IDENT expr
+{
+    $$ = value.NewValue(map[string]interface{}{"cache": $2.Value()})
+    if strings.ToUpper($1) != "CACHE" {
+	yylex.Error("Unexpected sequence option " + $1 + ".")
+    } else if $2.Value() == nil {
+	yylex.Error("CACHE value must be static.")
+    }
+}
+;
+
+
+/*************************************************
+ *
+ * DROP SEQUENCE
+ *
+ *************************************************/
+
+drop_sequence:
+DROP IDENT named_keyspace_ref
+{
+    if strings.ToUpper($2) != "SEQUENCE" {
+	yylex.Error("Unexpected " + $2 + " after DROP.")
+    }
+    $$ = algebra.NewDropSequence($3)
+}
+;
+
+
+/*************************************************
+ *
+ * CREATE PLAN BASELINE
+ * DROP PLAN BASELINE
+ *
+ *************************************************/
+
+plan_baseline_stmt:
+create_plan_baseline
+|
+drop_plan_baseline
+;
+
+create_plan_baseline:
+CREATE IDENT IDENT FOR stmt
+{
+    if strings.ToUpper($2) != "PLAN" || strings.ToUpper($3) != "BASELINE" {
+	yylex.Error("Unexpected " + $2 + " " + $3 + " after CREATE.")
+    }
+    text := strings.TrimRight(yylex.(*lexer).Remainder($<tokOffset>4), " \t\n;")
+    $$ = algebra.NewCreatePlanBaseline($5, text)
+}
+;
+
+drop_plan_baseline:
+DROP IDENT IDENT FOR stmt
+{
+    if strings.ToUpper($2) != "PLAN" || strings.ToUpper($3) != "BASELINE" {
+	yylex.Error("Unexpected " + $2 + " " + $3 + " after DROP.")
+    }
+    text := strings.TrimRight(yylex.(*lexer).Remainder($<tokOffset>4), " \t\n;")
+    $$ = algebra.NewDropPlanBaseline($5, text)
+}
+;
+
+
+/*************************************************
+ *
+ * ALTER KEYSPACE
+ *
+ *************************************************/
+
+alter_keyspace:
+ALTER KEYSPACE named_keyspace_ref SET SCHEMA expr
+{
+    $$ = algebra.NewAlterKeyspace($3, $6.Value())
+    if $6.Value() == nil {
+	yylex.Error("SCHEMA value must be static.")
+    }
+}
+|
+ALTER KEYSPACE named_keyspace_ref UNSET SCHEMA
+{
+    $$ = algebra.NewAlterKeyspace($3, nil)
+}
+;
+
+
+/*************************************************
+ *
+ * VALIDATE
+ *
+ *************************************************/
+
+validate:
+VALIDATE opt_keyspace named_keyspace_ref
+{
+    $$ = algebra.NewValidate($3)
+}
+;
+
+
+/*************************************************
+ *
+ * ADVISE
+ *
+ *************************************************/
+
+advise:
+IDENT advise_select
+{
+    if strings.ToUpper($1) != "ADVISE" {
+	yylex.Error("Unexpected " + $1 + " before SELECT.")
+    }
+    $$ = algebra.NewAdvise($2, nil)
+}
+|
+IDENT update
+{
+    if strings.ToUpper($1) != "ADVISE" {
+	yylex.Error("Unexpected " + $1 + " before UPDATE.")
+    }
+    $$ = algebra.NewAdvise($2, nil)
+}
+|
+IDENT delete
+{
+    if strings.ToUpper($1) != "ADVISE" {
+	yylex.Error("Unexpected " + $1 + " before DELETE.")
+    }
+    $$ = algebra.NewAdvise($2, nil)
+}
+|
+IDENT ON keyspace_ref
+{
+    if strings.ToUpper($1) != "ADVISE" {
+	yylex.Error("Unexpected " + $1 + " before ON.")
+    }
+    $$ = algebra.NewAdvise(nil, $3)
+}
+;
+
+/* Only SELECT-first queries, so that ADVISE does not clash with function calls and PREPARE names */
+advise_select:
+select_from opt_order_by
+{
+    $$ = algebra.NewSelect($1, $2, nil, nil) /* OFFSET precedes LIMIT */
+}
+|
+select_from opt_order_by limit opt_offset
+{
+    $$ = algebra.NewSelect($1, $2, $4, $3) /* OFFSET precedes LIMIT */
+}
+|
+select_from opt_order_by offset opt_limit
+{
+    $$ = algebra.NewSelect($1, $2, $3, $4) /* OFFSET precedes LIMIT */
+}
+;
+
+
 /*************************************************
  *
  * Path
@@ -2504,6 +2977,9 @@ function_name LPAREN opt_exprs RPAREN
     if !ok {
         f, ok = algebra.GetAggregate($1, false);
     }
+    if !ok {
+        f, ok = algebra.GetFunction($1);
+    }
 
     if ok {
         if len($3) < f.MinArgs() || len($3) > f.MaxArgs() {
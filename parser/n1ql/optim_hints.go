@@ -0,0 +1,140 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package n1ql
+
+import (
+	"strings"
+
+	"github.com/couchbase/query/algebra"
+)
+
+// Block comments beginning with /*+ carry optimizer hints.
+func isOptimHints(text string) bool {
+	return strings.HasPrefix(text, "/*+")
+}
+
+// Parse the hints of an optimizer hint comment, e.g.
+// /*+ INDEX(o idx1 idx2) USE_NL(c) PARALLEL(4) */. Hints that cannot
+// be parsed are returned as invalid hints, so that they are reported
+// rather than failing the statement.
+func parseOptimHints(text string) algebra.OptimHints {
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "/*+"), "*/")
+	hints := make(algebra.OptimHints, 0, 4)
+
+	s := &hintScanner{text: text}
+	for {
+		s.skipSpace()
+		if s.done() {
+			return hints
+		}
+
+		start := s.pos
+		name, ok := s.word()
+		if !ok {
+			break
+		}
+
+		var args []string
+		s.skipSpace()
+		if !s.done() && s.text[s.pos] == '(' {
+			s.pos++
+			args, ok = s.args()
+			if !ok {
+				s.pos = start
+				break
+			}
+		}
+
+		hints = append(hints, algebra.NewOptimHint(name, args))
+	}
+
+	rest := strings.TrimSpace(s.text[s.pos:])
+	return append(hints, algebra.NewInvalidOptimHint(rest, "syntax error"))
+}
+
+type hintScanner struct {
+	text string
+	pos  int
+}
+
+func (this *hintScanner) done() bool {
+	return this.pos >= len(this.text)
+}
+
+func (this *hintScanner) skipSpace() {
+	for !this.done() && strings.IndexByte(" \t\r\n", this.text[this.pos]) >= 0 {
+		this.pos++
+	}
+}
+
+// Scan an identifier, a number, or a back-quoted identifier.
+func (this *hintScanner) word() (string, bool) {
+	if this.done() {
+		return "", false
+	}
+
+	if this.text[this.pos] == '`' {
+		end := this.pos + 1
+		for end < len(this.text) {
+			if this.text[end] == '`' {
+				if end+1 < len(this.text) && this.text[end+1] == '`' {
+					end += 2
+					continue
+				}
+
+				word, err := UnmarshalBackQuoted(this.text[this.pos : end+1])
+				this.pos = end + 1
+				return word, err == nil && word != ""
+			}
+			end++
+		}
+
+		return "", false
+	}
+
+	start := this.pos
+	for !this.done() {
+		c := this.text[this.pos]
+		if c != '_' && c != '-' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			break
+		}
+		this.pos++
+	}
+
+	return this.text[start:this.pos], this.pos > start
+}
+
+// Scan the arguments of a hint, separated by spaces or commas, up to
+// the closing parenthesis.
+func (this *hintScanner) args() ([]string, bool) {
+	var args []string
+	for {
+		this.skipSpace()
+		if this.done() {
+			return nil, false
+		}
+
+		switch this.text[this.pos] {
+		case ')':
+			this.pos++
+			return args, true
+		case ',':
+			this.pos++
+			continue
+		}
+
+		arg, ok := this.word()
+		if !ok {
+			return nil, false
+		}
+
+		args = append(args, arg)
+	}
+}
@@ -66,3 +66,16 @@ func UnmarshalUnquoted(s string) (t string, e error) {
 
 	return t, e
 }
+
+// Skip the first n whitespace-separated words of s.
+func skipWords(s string, n int) string {
+	for i := 0; i < n; i++ {
+		s = strings.TrimLeft(s, " \t\r\n")
+		end := strings.IndexAny(s, " \t\r\n")
+		if end < 0 {
+			return ""
+		}
+		s = s[end:]
+	}
+	return strings.TrimLeft(s, " \t\r\n")
+}
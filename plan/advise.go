@@ -0,0 +1,95 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/value"
+)
+
+/*
+Advise returns the index advice computed by the planner for a
+statement, one item per keyspace term.
+*/
+type Advise struct {
+	readonly
+	advice value.Value
+}
+
+func NewAdvise(advice value.Value) *Advise {
+	return &Advise{
+		advice: advice,
+	}
+}
+
+func (this *Advise) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitAdvise(this)
+}
+
+func (this *Advise) New() Operator {
+	return &Advise{}
+}
+
+func (this *Advise) Advice() value.Value {
+	return this.advice
+}
+
+func (this *Advise) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "Advise"}
+	r["advice"] = this.advice
+	return json.Marshal(r)
+}
+
+func (this *Advise) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_      string          `json:"#operator"`
+		Advice json.RawMessage `json:"advice"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.advice = value.NewValue([]byte(_unmarshalled.Advice))
+	return nil
+}
+
+/*
+AdviseRequests consumes completed requests, projected as statement
+and elapsed_time, and returns the index advice for their statements
+aggregated by recommended index.
+*/
+type AdviseRequests struct {
+	readonly
+}
+
+func NewAdviseRequests() *AdviseRequests {
+	return &AdviseRequests{}
+}
+
+func (this *AdviseRequests) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitAdviseRequests(this)
+}
+
+func (this *AdviseRequests) New() Operator {
+	return &AdviseRequests{}
+}
+
+func (this *AdviseRequests) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "AdviseRequests"}
+	return json.Marshal(r)
+}
+
+func (this *AdviseRequests) UnmarshalJSON([]byte) error {
+	// NOP: AdviseRequests has no data structure
+	return nil
+}
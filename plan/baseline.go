@@ -0,0 +1,400 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	atomic "github.com/couchbase/go-couchbase/platform"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+/*
+Baseline pins the plan of a statement. Requests whose normalized
+statement text and namespace match the baseline use its plan instead
+of being planned, as long as the indexes referenced by the plan still
+exist. Baselines are persisted in the namespaces that support them,
+and reloaded when the query service starts; the plan of a reloaded
+baseline is decoded when it is first used.
+*/
+type Baseline struct {
+	mutex     sync.Mutex
+	namespace string
+	statement string
+	prepared  *Prepared // Nil until a reloaded baseline is used
+	encoded   []byte
+	indexes   []*baselineIndex
+	created   time.Time
+	uses      int32
+}
+
+type baselineIndex struct {
+	namespace string
+	keyspace  string
+	using     datastore.IndexType
+	name      string
+	id        string
+}
+
+type baselineKey struct {
+	namespace string
+	statement string
+}
+
+type baselineCache struct {
+	sync.RWMutex
+	baselines map[baselineKey]*Baseline
+}
+
+var baselines = &baselineCache{
+	baselines: make(map[baselineKey]*Baseline, 64),
+}
+
+/*
+NewBaseline captures the plan of a statement, and the indexes the plan
+references.
+*/
+func NewBaseline(namespace, statement string, prepared *Prepared) (*Baseline, errors.Error) {
+	encoded, err := json.Marshal(prepared)
+	if err != nil {
+		return nil, errors.NewPlanBaselineError(err.Error())
+	}
+
+	baseline, er := newBaseline(namespace, NormalizeStatement(statement), encoded, time.Now())
+	if er != nil {
+		return nil, er
+	}
+
+	baseline.prepared = prepared
+	return baseline, nil
+}
+
+func newBaseline(namespace, statement string, encoded []byte, created time.Time) (*Baseline, errors.Error) {
+	var body interface{}
+	err := json.Unmarshal(encoded, &body)
+	if err != nil {
+		return nil, errors.NewPlanBaselineError(err.Error())
+	}
+
+	return &Baseline{
+		namespace: namespace,
+		statement: statement,
+		encoded:   encoded,
+		indexes:   baselineIndexes(body, nil),
+		created:   created,
+	}, nil
+}
+
+func (this *Baseline) Namespace() string {
+	return this.namespace
+}
+
+func (this *Baseline) Statement() string {
+	return this.statement
+}
+
+/*
+Prepared returns the pinned plan, or a warning if an index referenced
+by the plan has been dropped, rebuilt or taken offline.
+*/
+func (this *Baseline) Prepared() (*Prepared, errors.Error) {
+	for _, index := range this.indexes {
+		reason := index.verify()
+		if reason != "" {
+			return nil, errors.NewInvalidPlanBaselineWarning(reason)
+		}
+	}
+
+	prepared, err := this.decode()
+	if err != nil {
+		return nil, err
+	}
+
+	atomic.AddInt32(&this.uses, 1)
+	return prepared, nil
+}
+
+/*
+decode returns the plan, decoding it if the baseline was reloaded.
+The indexes of the plan must have been verified.
+*/
+func (this *Baseline) decode() (*Prepared, errors.Error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.prepared == nil {
+		prepared := &Prepared{}
+		err := json.Unmarshal(this.encoded, prepared)
+		if err != nil {
+			return nil, errors.NewInvalidPlanBaselineWarning("the plan cannot be decoded: " + err.Error())
+		}
+
+		this.prepared = prepared
+	}
+
+	return this.prepared, nil
+}
+
+func (this *baselineIndex) verify() string {
+	keyspace, err := datastore.GetKeyspace(this.namespace, this.keyspace)
+	if err != nil {
+		return fmt.Sprintf("keyspace %s:%s not found", this.namespace, this.keyspace)
+	}
+
+	indexer, err := keyspace.Indexer(this.using)
+	if err != nil {
+		return fmt.Sprintf("indexer %s not found", this.using)
+	}
+
+	index, err := indexer.IndexByName(this.name)
+	if err != nil {
+		return fmt.Sprintf("index %s not found", this.name)
+	}
+
+	if this.id != "" && index.Id() != this.id {
+		return fmt.Sprintf("index %s was recreated", this.name)
+	}
+
+	state, _, err := index.State()
+	if err != nil || state != datastore.ONLINE {
+		return fmt.Sprintf("index %s is not online", this.name)
+	}
+
+	return ""
+}
+
+/*
+Collect the indexes named by the scan operators of a serialized plan.
+*/
+func baselineIndexes(body interface{}, indexes []*baselineIndex) []*baselineIndex {
+	switch body := body.(type) {
+	case map[string]interface{}:
+		name, _ := body["index"].(string)
+		namespace, _ := body["namespace"].(string)
+		keyspace, _ := body["keyspace"].(string)
+		if name != "" && namespace != "" && keyspace != "" {
+			using, _ := body["using"].(string)
+			id, _ := body["index_id"].(string)
+			indexes = append(indexes, &baselineIndex{
+				namespace: namespace,
+				keyspace:  keyspace,
+				using:     datastore.IndexType(using),
+				name:      name,
+				id:        id,
+			})
+		}
+
+		for _, v := range body {
+			indexes = baselineIndexes(v, indexes)
+		}
+	case []interface{}:
+		for _, v := range body {
+			indexes = baselineIndexes(v, indexes)
+		}
+	}
+
+	return indexes
+}
+
+/*
+NormalizeStatement collapses the white space outside of quotes and
+identifiers, and drops trailing semicolons, so that statements that
+differ only in layout share a baseline.
+*/
+func NormalizeStatement(text string) string {
+	var buf bytes.Buffer
+	var quote byte
+	space := false
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if quote != 0 {
+			buf.WriteByte(c)
+			if c == '\\' && quote == '"' && i+1 < len(text) {
+				i++
+				buf.WriteByte(text[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			space = true
+			continue
+		case '"', '\'', '`':
+			quote = c
+		}
+
+		if space && buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+
+		space = false
+		buf.WriteByte(c)
+	}
+
+	return strings.TrimRight(buf.String(), "; ")
+}
+
+/*
+AddBaseline pins the plan of a statement, replacing any previous
+baseline of the statement. The baseline is persisted if its namespace
+supports it.
+*/
+func AddBaseline(store datastore.Datastore, baseline *Baseline) errors.Error {
+	baselines.Lock()
+	defer baselines.Unlock()
+
+	namespace, err := baselineNamespace(store, baseline.namespace)
+	if err != nil {
+		return err
+	}
+
+	if namespace != nil {
+		err = namespace.SetPlanBaseline(&datastore.PlanBaseline{
+			Statement: baseline.statement,
+			Plan:      baseline.encoded,
+			Created:   baseline.created,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	baselines.baselines[baselineKey{baseline.namespace, baseline.statement}] = baseline
+	return nil
+}
+
+/*
+GetBaseline returns the baseline of a statement, or nil.
+*/
+func GetBaseline(namespace, statement string) *Baseline {
+	key := baselineKey{namespace, NormalizeStatement(statement)}
+	baselines.RLock()
+	defer baselines.RUnlock()
+	return baselines.baselines[key]
+}
+
+func DeleteBaseline(store datastore.Datastore, namespace, statement string) errors.Error {
+	key := baselineKey{namespace, NormalizeStatement(statement)}
+	baselines.Lock()
+	defer baselines.Unlock()
+	if _, ok := baselines.baselines[key]; !ok {
+		return errors.NewPlanBaselineNotFoundError(key.statement)
+	}
+
+	bnamespace, err := baselineNamespace(store, namespace)
+	if err != nil {
+		return err
+	}
+
+	if bnamespace != nil {
+		err = bnamespace.DropPlanBaseline(key.statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	delete(baselines.baselines, key)
+	return nil
+}
+
+/*
+LoadBaselines replaces the baselines of the namespaces of a datastore
+by the baselines persisted in them. It is called when the query
+service starts.
+*/
+func LoadBaselines(store datastore.Datastore) errors.Error {
+	namespaceIds, err := store.NamespaceIds()
+	if err != nil {
+		return err
+	}
+
+	baselines.Lock()
+	defer baselines.Unlock()
+
+	for _, namespaceId := range namespaceIds {
+		namespace, err := store.NamespaceById(namespaceId)
+		if err != nil {
+			return err
+		}
+
+		bnamespace, ok := namespace.(datastore.BaselineNamespace)
+		if !ok {
+			continue
+		}
+
+		persisted, err := bnamespace.PlanBaselines()
+		if err != nil {
+			return err
+		}
+
+		for key, _ := range baselines.baselines {
+			if key.namespace == namespace.Name() {
+				delete(baselines.baselines, key)
+			}
+		}
+
+		for _, pb := range persisted {
+			baseline, err := newBaseline(namespace.Name(), pb.Statement, pb.Plan, pb.Created)
+			if err != nil {
+				return err
+			}
+
+			baselines.baselines[baselineKey{baseline.namespace, baseline.statement}] = baseline
+		}
+	}
+
+	return nil
+}
+
+/*
+baselineNamespace returns the namespace of a datastore, or nil if the
+namespace does not persist baselines.
+*/
+func baselineNamespace(store datastore.Datastore, name string) (datastore.BaselineNamespace, errors.Error) {
+	namespace, err := store.NamespaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	bnamespace, _ := namespace.(datastore.BaselineNamespace)
+	return bnamespace, nil
+}
+
+func CountBaselines() int {
+	baselines.RLock()
+	defer baselines.RUnlock()
+	return len(baselines.baselines)
+}
+
+func SnapshotBaselines() []map[string]interface{} {
+	baselines.RLock()
+	defer baselines.RUnlock()
+	data := make([]map[string]interface{}, 0, len(baselines.baselines))
+	for _, b := range baselines.baselines {
+		data = append(data, map[string]interface{}{
+			"namespace": b.namespace,
+			"statement": b.statement,
+			"plan":      json.RawMessage(b.encoded),
+			"created":   b.created.String(),
+			"uses":      atomic.LoadInt32(&b.uses),
+		})
+	}
+	return data
+}
@@ -0,0 +1,77 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+)
+
+// Create plan baseline
+type CreatePlanBaseline struct {
+	readwrite
+	namespace string
+	statement string
+	prepared  *Prepared
+}
+
+func NewCreatePlanBaseline(namespace, statement string, prepared *Prepared) *CreatePlanBaseline {
+	return &CreatePlanBaseline{
+		namespace: namespace,
+		statement: statement,
+		prepared:  prepared,
+	}
+}
+
+func (this *CreatePlanBaseline) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreatePlanBaseline(this)
+}
+
+func (this *CreatePlanBaseline) New() Operator {
+	return &CreatePlanBaseline{}
+}
+
+func (this *CreatePlanBaseline) Namespace() string {
+	return this.namespace
+}
+
+func (this *CreatePlanBaseline) Statement() string {
+	return this.statement
+}
+
+func (this *CreatePlanBaseline) Prepared() *Prepared {
+	return this.prepared
+}
+
+func (this *CreatePlanBaseline) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "CreatePlanBaseline"}
+	r["namespace"] = this.namespace
+	r["statement"] = this.statement
+	r["plan"] = this.prepared
+	return json.Marshal(r)
+}
+
+func (this *CreatePlanBaseline) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_         string          `json:"#operator"`
+		Namespace string          `json:"namespace"`
+		Statement string          `json:"statement"`
+		Plan      json.RawMessage `json:"plan"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.namespace = _unmarshalled.Namespace
+	this.statement = _unmarshalled.Statement
+	this.prepared = &Prepared{}
+	return this.prepared.UnmarshalJSON(_unmarshalled.Plan)
+}
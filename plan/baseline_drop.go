@@ -0,0 +1,68 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+)
+
+// Drop plan baseline
+type DropPlanBaseline struct {
+	readwrite
+	namespace string
+	statement string
+}
+
+func NewDropPlanBaseline(namespace, statement string) *DropPlanBaseline {
+	return &DropPlanBaseline{
+		namespace: namespace,
+		statement: statement,
+	}
+}
+
+func (this *DropPlanBaseline) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDropPlanBaseline(this)
+}
+
+func (this *DropPlanBaseline) New() Operator {
+	return &DropPlanBaseline{}
+}
+
+func (this *DropPlanBaseline) Namespace() string {
+	return this.namespace
+}
+
+func (this *DropPlanBaseline) Statement() string {
+	return this.statement
+}
+
+func (this *DropPlanBaseline) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "DropPlanBaseline"}
+	r["namespace"] = this.namespace
+	r["statement"] = this.statement
+	return json.Marshal(r)
+}
+
+func (this *DropPlanBaseline) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_         string `json:"#operator"`
+		Namespace string `json:"namespace"`
+		Statement string `json:"statement"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.namespace = _unmarshalled.Namespace
+	this.statement = _unmarshalled.Statement
+	return nil
+}
@@ -0,0 +1,154 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mem"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+func TestNormalizeStatement(t *testing.T) {
+	tests := []struct {
+		text       string
+		normalized string
+	}{
+		{"SELECT * FROM orders", "SELECT * FROM orders"},
+		{"  SELECT *\n\tFROM   orders  ", "SELECT * FROM orders"},
+		{"SELECT * FROM orders;", "SELECT * FROM orders"},
+		{"SELECT * FROM orders ; ;", "SELECT * FROM orders"},
+		{`SELECT "a  b" FROM orders`, `SELECT "a  b" FROM orders`},
+		{`SELECT 'a  b',  "c \"  d" FROM orders`, `SELECT 'a  b', "c \"  d" FROM orders`},
+		{"SELECT `my  field` FROM  orders", "SELECT `my  field` FROM orders"},
+		{`SELECT "a;" FROM orders`, `SELECT "a;" FROM orders`},
+		{"SELECT * FROM Orders", "SELECT * FROM Orders"},
+	}
+
+	for _, test := range tests {
+		normalized := NormalizeStatement(test.text)
+		if normalized != test.normalized {
+			t.Errorf("Expected %q to be normalized to %q, got %q", test.text, test.normalized, normalized)
+		}
+	}
+}
+
+func newBaselineStore(t *testing.T) datastore.Indexer {
+	store, err := mem.NewStore("mem:orders")
+	if err != nil {
+		t.Fatalf("Error creating datastore: %v", err)
+	}
+
+	datastore.SetDatastore(store)
+	keyspace, err := datastore.GetKeyspace("default", "orders")
+	if err != nil {
+		t.Fatalf("Error getting keyspace: %v", err)
+	}
+
+	indexer, err := keyspace.Indexer(datastore.GSI)
+	if err != nil {
+		t.Fatalf("Error getting indexer: %v", err)
+	}
+
+	return indexer
+}
+
+func createBaselineIndex(t *testing.T, indexer datastore.Indexer, with value.Value) datastore.Index {
+	key := expression.NewField(expression.NewIdentifier("orders"), expression.NewFieldName("total", false))
+	index, err := indexer.CreateIndex("", "ix_total", nil, expression.Expressions{key}, nil, with)
+	if err != nil {
+		t.Fatalf("Error creating index: %v", err)
+	}
+
+	return index
+}
+
+/*
+The indexes of a baseline are collected from its serialized plan.
+*/
+func testBaselineIndexes(t *testing.T, id string) []*baselineIndex {
+	encoded := `{"#operator": "Sequence", "~children": [
+		{"#operator": "IndexScan", "index": "ix_total", "index_id": "` + id + `",
+		 "keyspace": "orders", "namespace": "default", "using": "gsi"},
+		{"#operator": "Fetch", "keyspace": "orders", "namespace": "default"}]}`
+
+	var body interface{}
+	err := json.Unmarshal([]byte(encoded), &body)
+	if err != nil {
+		t.Fatalf("Error decoding plan: %v", err)
+	}
+
+	indexes := baselineIndexes(body, nil)
+	if len(indexes) != 1 || indexes[0].name != "ix_total" || indexes[0].using != datastore.GSI {
+		t.Fatalf("Expected index ix_total, got %v", indexes)
+	}
+
+	return indexes
+}
+
+func TestBaselineVerify(t *testing.T) {
+	defer datastore.SetDatastore(nil)
+	indexer := newBaselineStore(t)
+	index := createBaselineIndex(t, indexer, nil)
+	indexes := testBaselineIndexes(t, index.Id())
+
+	baseline := &Baseline{indexes: indexes, prepared: &Prepared{}}
+	prepared, err := baseline.Prepared()
+	if err != nil || prepared == nil || baseline.uses != 1 {
+		t.Errorf("Expected the baseline to be used, got %v, error %v, %d uses", prepared, err, baseline.uses)
+	}
+
+	// An index of the same name with another id was recreated
+	stale := testBaselineIndexes(t, "stale")
+	if reason := stale[0].verify(); reason != "index ix_total was recreated" {
+		t.Errorf("Expected a recreated index, got %q", reason)
+	}
+
+	err = index.Drop("")
+	if err != nil {
+		t.Fatalf("Error dropping index: %v", err)
+	}
+
+	if reason := indexes[0].verify(); reason != "index ix_total not found" {
+		t.Errorf("Expected a dropped index, got %q", reason)
+	}
+
+	prepared, err = baseline.Prepared()
+	if prepared != nil || err == nil || err.Code() != errors.NewInvalidPlanBaselineWarning("").Code() {
+		t.Errorf("Expected a baseline warning, got %v, error %v", prepared, err)
+	}
+	if baseline.uses != 1 {
+		t.Errorf("Expected 1 use, got %d", baseline.uses)
+	}
+
+	// A deferred index is not online until it is built
+	index = createBaselineIndex(t, indexer, value.NewValue(map[string]interface{}{"defer_build": true}))
+	if reason := indexes[0].verify(); reason != "index ix_total is not online" {
+		t.Errorf("Expected an offline index, got %q", reason)
+	}
+
+	err = indexer.BuildIndexes("", "ix_total")
+	if err != nil {
+		t.Fatalf("Error building index: %v", err)
+	}
+
+	if reason := indexes[0].verify(); reason != "" {
+		t.Errorf("Expected a valid index, got %q", reason)
+	}
+
+	missing := &baselineIndex{namespace: "default", keyspace: "customers", using: datastore.GSI, name: "ix_total"}
+	if reason := missing.verify(); reason != "keyspace default:customers not found" {
+		t.Errorf("Expected a missing keyspace, got %q", reason)
+	}
+}
@@ -15,14 +15,21 @@ import (
 
 type Explain struct {
 	readonly
-	op   Operator
-	text string
+	op         Operator
+	text       string
+	optimHints OptimHintStatuses
+	format     string
+	subqueries SubqueryPlans
 }
 
-func NewExplain(op Operator, text string) *Explain {
+func NewExplain(op Operator, text string, optimHints OptimHintStatuses, format string,
+	subqueries SubqueryPlans) *Explain {
 	return &Explain{
-		op:   op,
-		text: text,
+		op:         op,
+		text:       text,
+		optimHints: optimHints,
+		format:     format,
+		subqueries: subqueries,
 	}
 }
 
@@ -38,17 +45,44 @@ func (this *Explain) Operator() Operator {
 	return this.op
 }
 
+func (this *Explain) Text() string {
+	return this.text
+}
+
+func (this *Explain) OptimHints() OptimHintStatuses {
+	return this.optimHints
+}
+
+func (this *Explain) Format() string {
+	return this.format
+}
+
+func (this *Explain) Subqueries() SubqueryPlans {
+	return this.subqueries
+}
+
 func (this *Explain) MarshalJSON() ([]byte, error) {
 	r := make(map[string]interface{}, 2)
 	r["plan"] = this.op
 	r["text"] = this.text
+	if len(this.subqueries) > 0 {
+		r["~subqueries"] = this.subqueries
+	}
+	if len(this.optimHints) > 0 {
+		r["optimizer_hints"] = this.optimHints
+	}
+	if this.format != "" {
+		r["format"] = this.format
+	}
 	return json.Marshal(r)
 }
 
 func (this *Explain) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
-		Op   json.RawMessage `json:"plan"`
-		Text string          `json:"text"`
+		Op         json.RawMessage   `json:"plan"`
+		Text       string            `json:"text"`
+		OptimHints OptimHintStatuses `json:"optimizer_hints"`
+		Format     string            `json:"format"`
 	}
 
 	var op_type struct {
@@ -66,6 +100,8 @@ func (this *Explain) UnmarshalJSON(body []byte) error {
 	}
 
 	this.text = _unmarshalled.Text
+	this.optimHints = _unmarshalled.OptimHints
+	this.format = _unmarshalled.Format
 
 	err = json.Unmarshal(_unmarshalled.Op, &op_type)
 	if err != nil {
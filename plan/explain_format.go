@@ -0,0 +1,969 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+FormatExplain renders the output of EXPLAIN in the requested format:
+an indented operator tree, a Graphviz DOT graph, or the plan JSON
+together with the estimated number of entries read by each scan.
+*/
+func FormatExplain(explain *Explain) ([]byte, error) {
+	o, err := explain.Operator().Accept(&explainFormatter{})
+	if err != nil {
+		return nil, err
+	}
+
+	// The subquery plans follow the plan of the statement
+	roots := []*explainNode{o.(*explainNode)}
+	for _, subquery := range explain.Subqueries() {
+		o, err = subquery.Operator().Accept(&explainFormatter{})
+		if err != nil {
+			return nil, err
+		}
+
+		roots = append(roots, newExplainNode("Subquery", o.(*explainNode)).
+			add("subquery", subquery.Query().String()).
+			add("correlated", outerString(subquery.Query().IsCorrelated())))
+	}
+
+	r := map[string]interface{}{
+		"text": explain.Text(),
+	}
+
+	if len(explain.OptimHints()) > 0 {
+		r["optimizer_hints"] = explain.OptimHints()
+	}
+
+	switch explain.Format() {
+	case algebra.EXPLAIN_FORMAT_TEXT:
+		var lines []interface{}
+		for _, root := range roots {
+			lines = root.lines(lines, 0)
+		}
+		r["plan"] = lines
+	case algebra.EXPLAIN_FORMAT_DOT:
+		var buf bytes.Buffer
+		buf.WriteString("digraph plan {\n")
+		buf.WriteString("  node [shape=box];\n")
+		next := 0
+		for _, root := range roots {
+			root.dot(&buf, &next)
+		}
+		buf.WriteString("}\n")
+		r["plan"] = buf.String()
+	default:
+		r["plan"] = explain.Operator()
+		if len(explain.Subqueries()) > 0 {
+			r["~subqueries"] = explain.Subqueries()
+		}
+		estimates := make([]interface{}, 0, 4)
+		for _, root := range roots {
+			estimates = root.estimates(estimates)
+		}
+		r["estimates"] = estimates
+	}
+
+	return json.Marshal(r)
+}
+
+/*
+explainNode is an operator of the plan tree, with the properties
+worth showing in EXPLAIN output.
+*/
+type explainNode struct {
+	operator   string
+	properties []explainProperty
+	estimate   int64 // Estimated number of entries scanned, or -1
+	children   []*explainNode
+}
+
+type explainProperty struct {
+	name  string
+	value string
+}
+
+func newExplainNode(operator string, children ...*explainNode) *explainNode {
+	return &explainNode{
+		operator: operator,
+		estimate: -1,
+		children: children,
+	}
+}
+
+/*
+Add a property, unless its value is empty.
+*/
+func (this *explainNode) add(name, value string) *explainNode {
+	if value != "" {
+		this.properties = append(this.properties, explainProperty{name, value})
+	}
+	return this
+}
+
+func (this *explainNode) property(name string) string {
+	for _, p := range this.properties {
+		if p.name == name {
+			return p.value
+		}
+	}
+	return ""
+}
+
+func (this *explainNode) label(separator string) string {
+	var buf bytes.Buffer
+	buf.WriteString(this.operator)
+	for _, p := range this.properties {
+		buf.WriteString(separator)
+		buf.WriteString(p.name)
+		buf.WriteString(": ")
+		buf.WriteString(p.value)
+	}
+	if this.estimate >= 0 {
+		buf.WriteString(separator)
+		buf.WriteString("estimate: ")
+		buf.WriteString(strconv.FormatInt(this.estimate, 10))
+	}
+	return buf.String()
+}
+
+func (this *explainNode) lines(lines []interface{}, depth int) []interface{} {
+	line := strings.Repeat("  ", depth) + this.operator
+	if len(this.properties) > 0 || this.estimate >= 0 {
+		label := this.label(", ")
+		line += " (" + label[len(this.operator)+2:] + ")"
+	}
+
+	lines = append(lines, line)
+	for _, child := range this.children {
+		lines = child.lines(lines, depth+1)
+	}
+	return lines
+}
+
+/*
+Write the node and its descendants as DOT statements, numbering the
+nodes from *next.
+*/
+func (this *explainNode) dot(buf *bytes.Buffer, next *int) int {
+	id := *next
+	*next++
+
+	fmt.Fprintf(buf, "  n%d [label=%s];\n", id, strconv.Quote(this.label("\n")))
+	for _, child := range this.children {
+		childId := child.dot(buf, next)
+		fmt.Fprintf(buf, "  n%d -> n%d;\n", id, childId)
+	}
+	return id
+}
+
+func (this *explainNode) estimates(estimates []interface{}) []interface{} {
+	if this.estimate >= 0 {
+		estimate := map[string]interface{}{
+			"#operator": this.operator,
+			"keyspace":  this.property("keyspace"),
+			"estimate":  this.estimate,
+		}
+		if index := this.property("index"); index != "" {
+			estimate["index"] = index
+		}
+		estimates = append(estimates, estimate)
+	}
+
+	for _, child := range this.children {
+		estimates = child.estimates(estimates)
+	}
+	return estimates
+}
+
+/*
+explainFormatter builds the explainNode tree of a plan.
+*/
+type explainFormatter struct {
+}
+
+func (this *explainFormatter) nodes(ops ...Operator) ([]*explainNode, error) {
+	nodes := make([]*explainNode, 0, len(ops))
+	for _, op := range ops {
+		if op == nil {
+			continue
+		}
+
+		node, err := op.Accept(this)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node.(*explainNode))
+	}
+	return nodes, nil
+}
+
+func (this *explainFormatter) parent(operator string, ops ...Operator) (*explainNode, error) {
+	children, err := this.nodes(ops...)
+	if err != nil {
+		return nil, err
+	}
+	return newExplainNode(operator, children...), nil
+}
+
+// Scan
+
+func (this *explainFormatter) VisitPrimaryScan(op *PrimaryScan) (interface{}, error) {
+	node := newExplainNode("PrimaryScan").
+		add("index", op.Index().Name()).
+		add("keyspace", termName(op.Term())).
+		add("partitions", spansString(op.Partitions())).
+		add("limit", exprString(op.Limit()))
+
+	if op.Limit() == nil {
+		spans := op.Partitions()
+		if len(spans) == 0 {
+			spans = Spans{&Span{}}
+		}
+		node.estimate = estimateSpans(op.Index(), spans)
+	}
+	return node, nil
+}
+
+func (this *explainFormatter) VisitParentScan(op *ParentScan) (interface{}, error) {
+	return newExplainNode("ParentScan"), nil
+}
+
+func (this *explainFormatter) VisitIndexScan(op *IndexScan) (interface{}, error) {
+	node := newExplainNode("IndexScan").
+		add("index", op.Index().Name()).
+		add("keyspace", termName(op.Term())).
+		add("spans", spansString(op.Spans())).
+		add("limit", exprString(op.Limit()))
+
+	if op.Covering() {
+		node.add("covers", coversString(op.Covers()))
+	}
+	if op.Partitioned() && op.Ordered() {
+		node.add("partitioned", "ordered")
+	} else if op.Partitioned() {
+		node.add("partitioned", "true")
+	}
+	if op.Limit() == nil {
+		node.estimate = estimateSpans(op.Index(), op.Spans())
+	}
+	return node, nil
+}
+
+func (this *explainFormatter) VisitKeyScan(op *KeyScan) (interface{}, error) {
+	node := newExplainNode("KeyScan").
+		add("keys", exprString(op.Keys()))
+
+	// Static keys are their own estimate
+	if keys := op.Keys().Value(); keys != nil {
+		switch keys.Type() {
+		case value.ARRAY:
+			node.estimate = int64(len(keys.Actual().([]interface{})))
+		case value.STRING:
+			node.estimate = 1
+		}
+	}
+	return node, nil
+}
+
+func (this *explainFormatter) VisitValueScan(op *ValueScan) (interface{}, error) {
+	return newExplainNode("ValueScan").
+		add("values", exprString(op.Values().Expression())), nil
+}
+
+func (this *explainFormatter) VisitDummyScan(op *DummyScan) (interface{}, error) {
+	return newExplainNode("DummyScan"), nil
+}
+
+func (this *explainFormatter) VisitCountScan(op *CountScan) (interface{}, error) {
+	return newExplainNode("CountScan").
+		add("keyspace", termName(op.Term())), nil
+}
+
+func (this *explainFormatter) VisitIndexCountScan(op *IndexCountScan) (interface{}, error) {
+	node := newExplainNode("IndexCountScan").
+		add("index", op.Index().Name()).
+		add("keyspace", termName(op.Term())).
+		add("spans", spansString(op.Spans()))
+
+	if op.Covering() {
+		node.add("covers", coversString(op.Covers()))
+	}
+	return node, nil
+}
+
+func (this *explainFormatter) VisitIntersectScan(op *IntersectScan) (interface{}, error) {
+	return this.parent("IntersectScan", op.Scans()...)
+}
+
+func (this *explainFormatter) VisitUnionScan(op *UnionScan) (interface{}, error) {
+	return this.parent("UnionScan", op.Scans()...)
+}
+
+func (this *explainFormatter) VisitDistinctScan(op *DistinctScan) (interface{}, error) {
+	return this.parent("DistinctScan", op.Scan())
+}
+
+// Fetch
+
+func (this *explainFormatter) VisitFetch(op *Fetch) (interface{}, error) {
+	return newExplainNode("Fetch").
+		add("keyspace", termName(op.Term())), nil
+}
+
+func (this *explainFormatter) VisitDummyFetch(op *DummyFetch) (interface{}, error) {
+	return newExplainNode("DummyFetch").
+		add("keyspace", termName(op.Term())), nil
+}
+
+// Join
+
+func (this *explainFormatter) VisitJoin(op *Join) (interface{}, error) {
+	return newExplainNode("Join").
+		add("keyspace", termName(op.Term())).
+		add("on_keys", exprString(op.Term().Keys())).
+		add("outer", outerString(op.Outer())), nil
+}
+
+func (this *explainFormatter) VisitIndexJoin(op *IndexJoin) (interface{}, error) {
+	return newExplainNode("IndexJoin").
+		add("index", op.Index().Name()).
+		add("keyspace", termName(op.Term())).
+		add("on_key", exprString(op.Term().Keys())).
+		add("for", op.For()).
+		add("outer", outerString(op.Outer())), nil
+}
+
+func (this *explainFormatter) VisitNest(op *Nest) (interface{}, error) {
+	return newExplainNode("Nest").
+		add("keyspace", termName(op.Term())).
+		add("on_keys", exprString(op.Term().Keys())).
+		add("outer", outerString(op.Outer())), nil
+}
+
+func (this *explainFormatter) VisitIndexNest(op *IndexNest) (interface{}, error) {
+	return newExplainNode("IndexNest").
+		add("index", op.Index().Name()).
+		add("keyspace", termName(op.Term())).
+		add("on_key", exprString(op.Term().Keys())).
+		add("for", op.For()).
+		add("outer", outerString(op.Outer())), nil
+}
+
+func (this *explainFormatter) VisitSemiJoin(op *SemiJoin) (interface{}, error) {
+	node, err := this.parent("SemiJoin", op.Inner())
+	if err != nil {
+		return nil, err
+	}
+	return node.
+		add("keys", exprsString(op.Keys())).
+		add("anti", outerString(op.Anti())), nil
+}
+
+func (this *explainFormatter) VisitGroupJoin(op *GroupJoin) (interface{}, error) {
+	node, err := this.parent("GroupJoin", op.Inner())
+	if err != nil {
+		return nil, err
+	}
+	return node.
+		add("keys", exprsString(op.Keys())).
+		add("as", op.As()), nil
+}
+
+func (this *explainFormatter) VisitUnnest(op *Unnest) (interface{}, error) {
+	return newExplainNode("Unnest").
+		add("expr", exprString(op.Term().Expression())).
+		add("as", op.Alias()).
+		add("outer", outerString(op.Term().Outer())), nil
+}
+
+// Let
+
+func (this *explainFormatter) VisitLet(op *Let) (interface{}, error) {
+	bindings := make([]string, len(op.Bindings()))
+	for i, b := range op.Bindings() {
+		bindings[i] = b.Variable() + " = " + exprString(b.Expression())
+	}
+	return newExplainNode("Let").
+		add("bindings", strings.Join(bindings, ", ")), nil
+}
+
+// Filter
+
+func (this *explainFormatter) VisitFilter(op *Filter) (interface{}, error) {
+	return newExplainNode("Filter").
+		add("condition", exprString(op.Condition())), nil
+}
+
+// Group
+
+func (this *explainFormatter) VisitInitialGroup(op *InitialGroup) (interface{}, error) {
+	return groupNode("InitialGroup", op.Keys(), op.Aggregates()), nil
+}
+
+func (this *explainFormatter) VisitIntermediateGroup(op *IntermediateGroup) (interface{}, error) {
+	return groupNode("IntermediateGroup", op.Keys(), op.Aggregates()), nil
+}
+
+func (this *explainFormatter) VisitFinalGroup(op *FinalGroup) (interface{}, error) {
+	return groupNode("FinalGroup", op.Keys(), op.Aggregates()), nil
+}
+
+// Project
+
+func (this *explainFormatter) VisitInitialProject(op *InitialProject) (interface{}, error) {
+	return projectNode("InitialProject", op.Projection(), op.Terms()), nil
+}
+
+func (this *explainFormatter) VisitFinalProject(op *FinalProject) (interface{}, error) {
+	return newExplainNode("FinalProject"), nil
+}
+
+func (this *explainFormatter) VisitIndexCountProject(op *IndexCountProject) (interface{}, error) {
+	return projectNode("IndexCountProject", op.Projection(), op.Terms()), nil
+}
+
+// Distinct
+
+func (this *explainFormatter) VisitDistinct(op *Distinct) (interface{}, error) {
+	return newExplainNode("Distinct"), nil
+}
+
+// Set operators
+
+func (this *explainFormatter) VisitUnionAll(op *UnionAll) (interface{}, error) {
+	return this.parent("UnionAll", op.Children()...)
+}
+
+func (this *explainFormatter) VisitIntersectAll(op *IntersectAll) (interface{}, error) {
+	return this.parent("IntersectAll", op.First(), op.Second())
+}
+
+func (this *explainFormatter) VisitExceptAll(op *ExceptAll) (interface{}, error) {
+	return this.parent("ExceptAll", op.First(), op.Second())
+}
+
+// Order
+
+func (this *explainFormatter) VisitOrder(op *Order) (interface{}, error) {
+	terms := make([]string, len(op.Terms()))
+	for i, term := range op.Terms() {
+		terms[i] = term.Expression().String()
+		if term.Descending() {
+			terms[i] += " DESC"
+		}
+	}
+
+	node := newExplainNode("Order").
+		add("sort_terms", strings.Join(terms, ", "))
+	if op.Offset() != nil {
+		node.add("offset", exprString(op.Offset().Expression()))
+	}
+	if op.Limit() != nil {
+		node.add("limit", exprString(op.Limit().Expression()))
+	}
+	return node, nil
+}
+
+// Offset
+
+func (this *explainFormatter) VisitOffset(op *Offset) (interface{}, error) {
+	return newExplainNode("Offset").
+		add("expr", exprString(op.Expression())), nil
+}
+
+func (this *explainFormatter) VisitLimit(op *Limit) (interface{}, error) {
+	return newExplainNode("Limit").
+		add("expr", exprString(op.Expression())), nil
+}
+
+// Insert
+
+func (this *explainFormatter) VisitSendInsert(op *SendInsert) (interface{}, error) {
+	return newExplainNode("SendInsert").
+		add("keyspace", keyspaceName(op.Keyspace())).
+		add("key", exprString(op.Key())).
+		add("value", exprString(op.Value())).
+		add("limit", exprString(op.Limit())), nil
+}
+
+// Upsert
+
+func (this *explainFormatter) VisitSendUpsert(op *SendUpsert) (interface{}, error) {
+	return newExplainNode("SendUpsert").
+		add("keyspace", keyspaceName(op.Keyspace())).
+		add("key", exprString(op.Key())).
+		add("value", exprString(op.Value())), nil
+}
+
+// Delete
+
+func (this *explainFormatter) VisitSendDelete(op *SendDelete) (interface{}, error) {
+	return newExplainNode("SendDelete").
+		add("keyspace", keyspaceName(op.Keyspace())).
+		add("limit", exprString(op.Limit())), nil
+}
+
+// Update
+
+func (this *explainFormatter) VisitClone(op *Clone) (interface{}, error) {
+	return newExplainNode("Clone"), nil
+}
+
+func (this *explainFormatter) VisitSet(op *Set) (interface{}, error) {
+	terms := make([]string, len(op.Node().Terms()))
+	for i, term := range op.Node().Terms() {
+		terms[i] = exprString(term.Path()) + " = " + exprString(term.Value())
+	}
+	return newExplainNode("Set").
+		add("set_terms", strings.Join(terms, ", ")), nil
+}
+
+func (this *explainFormatter) VisitUnset(op *Unset) (interface{}, error) {
+	terms := make([]string, len(op.Node().Terms()))
+	for i, term := range op.Node().Terms() {
+		terms[i] = exprString(term.Path())
+	}
+	return newExplainNode("Unset").
+		add("unset_terms", strings.Join(terms, ", ")), nil
+}
+
+func (this *explainFormatter) VisitSendUpdate(op *SendUpdate) (interface{}, error) {
+	return newExplainNode("SendUpdate").
+		add("keyspace", keyspaceName(op.Keyspace())).
+		add("limit", exprString(op.Limit())), nil
+}
+
+// Merge
+
+func (this *explainFormatter) VisitMerge(op *Merge) (interface{}, error) {
+	node, err := this.parent("Merge", op.Inner(), op.Update(), op.Delete(), op.Insert())
+	if err != nil {
+		return nil, err
+	}
+	return node.add("keyspace", keyspaceName(op.Keyspace())).
+		add("key", exprString(op.Key())).
+		add("keys", exprsString(op.Keys())).
+		add("on", exprString(op.On())), nil
+}
+
+// Alias
+
+func (this *explainFormatter) VisitAlias(op *Alias) (interface{}, error) {
+	return newExplainNode("Alias").
+		add("as", op.Alias()), nil
+}
+
+// Authorize
+
+func (this *explainFormatter) VisitAuthorize(op *Authorize) (interface{}, error) {
+	return this.parent("Authorize", op.Child())
+}
+
+// Optimizer hints
+
+func (this *explainFormatter) VisitOptimHints(op *OptimHints) (interface{}, error) {
+	return op.Child().Accept(this)
+}
+
+// Parallel
+
+func (this *explainFormatter) VisitParallel(op *Parallel) (interface{}, error) {
+	node, err := this.parent("Parallel", op.Child())
+	if err != nil {
+		return nil, err
+	}
+	if op.MaxParallelism() > 0 {
+		node.add("max_parallelism", strconv.Itoa(op.MaxParallelism()))
+	}
+	return node, nil
+}
+
+// Sequence
+
+func (this *explainFormatter) VisitSequence(op *Sequence) (interface{}, error) {
+	return this.parent("Sequence", op.Children()...)
+}
+
+// Discard
+
+func (this *explainFormatter) VisitDiscard(op *Discard) (interface{}, error) {
+	return newExplainNode("Discard"), nil
+}
+
+// Stream
+
+func (this *explainFormatter) VisitStream(op *Stream) (interface{}, error) {
+	return newExplainNode("Stream"), nil
+}
+
+// Collect
+
+func (this *explainFormatter) VisitCollect(op *Collect) (interface{}, error) {
+	return newExplainNode("Collect"), nil
+}
+
+// CreatePrimaryIndex
+
+func (this *explainFormatter) VisitCreatePrimaryIndex(op *CreatePrimaryIndex) (interface{}, error) {
+	return newExplainNode("CreatePrimaryIndex").
+		add("index", op.Node().Name()).
+		add("keyspace", keyspaceName(op.Keyspace())), nil
+}
+
+// CreateIndex
+
+func (this *explainFormatter) VisitCreateIndex(op *CreateIndex) (interface{}, error) {
+	return newExplainNode("CreateIndex").
+		add("index", op.Node().Name()).
+		add("keyspace", keyspaceName(op.Keyspace())).
+		add("keys", exprsString(op.Node().Keys())), nil
+}
+
+// DropIndex
+
+func (this *explainFormatter) VisitDropIndex(op *DropIndex) (interface{}, error) {
+	return newExplainNode("DropIndex").
+		add("index", op.Node().Name()), nil
+}
+
+// AlterIndex
+
+func (this *explainFormatter) VisitAlterIndex(op *AlterIndex) (interface{}, error) {
+	return newExplainNode("AlterIndex").
+		add("index", op.Node().Name()).
+		add("keyspace", keyspaceName(op.Keyspace())), nil
+}
+
+// BuildIndexes
+
+func (this *explainFormatter) VisitBuildIndexes(op *BuildIndexes) (interface{}, error) {
+	return newExplainNode("BuildIndexes").
+		add("indexes", strings.Join(op.Node().Names(), ", ")).
+		add("keyspace", keyspaceName(op.Keyspace())), nil
+}
+
+// Triggers
+
+func (this *explainFormatter) VisitCreateTrigger(op *CreateTrigger) (interface{}, error) {
+	return newExplainNode("CreateTrigger").
+		add("trigger", op.Trigger().Name).
+		add("keyspace", keyspaceName(op.Keyspace())), nil
+}
+
+func (this *explainFormatter) VisitDropTrigger(op *DropTrigger) (interface{}, error) {
+	return newExplainNode("DropTrigger").
+		add("trigger", op.Name()).
+		add("keyspace", keyspaceName(op.Keyspace())), nil
+}
+
+// Materialized views
+
+func (this *explainFormatter) VisitCreateMaterializedView(op *CreateMaterializedView) (interface{}, error) {
+	return newExplainNode("CreateMaterializedView").
+		add("view", op.View().Name), nil
+}
+
+func (this *explainFormatter) VisitRefreshMaterializedView(op *RefreshMaterializedView) (interface{}, error) {
+	return newExplainNode("RefreshMaterializedView").
+		add("keyspace", keyspaceName(op.Keyspace())), nil
+}
+
+func (this *explainFormatter) VisitDropMaterializedView(op *DropMaterializedView) (interface{}, error) {
+	return newExplainNode("DropMaterializedView").
+		add("keyspace", keyspaceName(op.Keyspace())), nil
+}
+
+// Named views
+
+func (this *explainFormatter) VisitCreateView(op *CreateView) (interface{}, error) {
+	return newExplainNode("CreateView").
+		add("view", op.View().Name), nil
+}
+
+func (this *explainFormatter) VisitDropView(op *DropView) (interface{}, error) {
+	return newExplainNode("DropView").
+		add("view", op.Name()), nil
+}
+
+// Sequences
+
+func (this *explainFormatter) VisitCreateSequence(op *CreateSequence) (interface{}, error) {
+	return newExplainNode("CreateSequence").
+		add("sequence", op.Sequence().Name), nil
+}
+
+func (this *explainFormatter) VisitDropSequence(op *DropSequence) (interface{}, error) {
+	return newExplainNode("DropSequence").
+		add("sequence", op.Name()), nil
+}
+
+// Plan baselines
+
+func (this *explainFormatter) VisitCreatePlanBaseline(op *CreatePlanBaseline) (interface{}, error) {
+	return newExplainNode("CreatePlanBaseline").
+		add("statement", op.Statement()), nil
+}
+
+func (this *explainFormatter) VisitDropPlanBaseline(op *DropPlanBaseline) (interface{}, error) {
+	return newExplainNode("DropPlanBaseline").
+		add("statement", op.Statement()), nil
+}
+
+// Schemas
+
+func (this *explainFormatter) VisitAlterKeyspace(op *AlterKeyspace) (interface{}, error) {
+	return newExplainNode("AlterKeyspace").
+		add("keyspace", keyspaceName(op.Keyspace())), nil
+}
+
+func (this *explainFormatter) VisitValidate(op *Validate) (interface{}, error) {
+	return newExplainNode("Validate").
+		add("keyspace", keyspaceName(op.Keyspace())), nil
+}
+
+// Advise
+
+func (this *explainFormatter) VisitAdvise(op *Advise) (interface{}, error) {
+	return newExplainNode("Advise"), nil
+}
+
+func (this *explainFormatter) VisitAdviseRequests(op *AdviseRequests) (interface{}, error) {
+	return newExplainNode("AdviseRequests"), nil
+}
+
+// Explain
+
+func (this *explainFormatter) VisitExplain(op *Explain) (interface{}, error) {
+	return this.parent("Explain", op.Operator())
+}
+
+// Prepare
+
+func (this *explainFormatter) VisitPrepare(op *Prepare) (interface{}, error) {
+	return newExplainNode("Prepare"), nil
+}
+
+// Infer
+
+func (this *explainFormatter) VisitInferKeyspace(op *InferKeyspace) (interface{}, error) {
+	return newExplainNode("InferKeyspace").
+		add("keyspace", keyspaceName(op.Keyspace())), nil
+}
+
+func groupNode(operator string, keys expression.Expressions, aggs algebra.Aggregates) *explainNode {
+	s := make([]string, len(aggs))
+	for i, agg := range aggs {
+		s[i] = exprString(agg)
+	}
+	return newExplainNode(operator).
+		add("group_keys", exprsString(keys)).
+		add("aggregates", strings.Join(s, ", "))
+}
+
+func projectNode(operator string, projection *algebra.Projection, terms ProjectTerms) *explainNode {
+	s := make([]string, len(terms))
+	for i, term := range terms {
+		result := term.Result()
+		switch {
+		case result.Star() && result.Expression() == nil:
+			s[i] = "*"
+		case result.Star():
+			s[i] = exprString(result.Expression()) + ".*"
+		default:
+			s[i] = exprString(result.Expression())
+		}
+		if result.As() != "" {
+			s[i] += " AS " + result.As()
+		}
+	}
+
+	node := newExplainNode(operator)
+	if projection != nil && projection.Distinct() {
+		node.add("distinct", "true")
+	}
+	if projection != nil && projection.Raw() {
+		node.add("raw", "true")
+	}
+	return node.add("result_terms", strings.Join(s, ", "))
+}
+
+func exprString(expr expression.Expression) string {
+	if expr == nil {
+		return ""
+	}
+	return expression.NewStringer().Visit(expr)
+}
+
+func exprsString(exprs expression.Expressions) string {
+	s := make([]string, len(exprs))
+	for i, expr := range exprs {
+		s[i] = exprString(expr)
+	}
+	return strings.Join(s, ", ")
+}
+
+func coversString(covers expression.Covers) string {
+	s := make([]string, len(covers))
+	for i, cover := range covers {
+		s[i] = exprString(cover)
+	}
+	return strings.Join(s, ", ")
+}
+
+func outerString(outer bool) string {
+	if outer {
+		return "true"
+	}
+	return ""
+}
+
+func termName(term *algebra.KeyspaceTerm) string {
+	if term == nil {
+		return ""
+	}
+
+	name := term.Namespace() + ":" + term.Keyspace()
+	if term.As() != "" && term.As() != term.Keyspace() {
+		name += " AS " + term.As()
+	}
+	return name
+}
+
+func keyspaceName(keyspace datastore.Keyspace) string {
+	if keyspace == nil {
+		return ""
+	}
+	return keyspace.NamespaceId() + ":" + keyspace.Name()
+}
+
+/*
+Render spans as key ranges, e.g. ["a" .. "m"), where a missing bound
+is shown as MIN or MAX.
+*/
+func spansString(spans Spans) string {
+	s := make([]string, 0, len(spans))
+	for _, span := range spans {
+		if span == nil {
+			continue
+		}
+
+		if len(span.Seek) > 0 {
+			s = append(s, "= "+keyString(span.Seek, ""))
+			continue
+		}
+
+		low, high := "(", ")"
+		if span.Range.Inclusion&datastore.LOW != 0 {
+			low = "["
+		}
+		if span.Range.Inclusion&datastore.HIGH != 0 {
+			high = "]"
+		}
+		s = append(s, low+keyString(span.Range.Low, "MIN")+" .. "+
+			keyString(span.Range.High, "MAX")+high)
+	}
+	return strings.Join(s, ", ")
+}
+
+func keyString(key expression.Expressions, missing string) string {
+	switch len(key) {
+	case 0:
+		return missing
+	case 1:
+		return exprString(key[0])
+	default:
+		return "(" + exprsString(key) + ")"
+	}
+}
+
+/*
+Estimate the number of index entries in spans from the index
+statistics. Returns -1 if a span is not static, or the index has no
+statistics.
+*/
+func estimateSpans(index datastore.Index, spans Spans) int64 {
+	total := int64(0)
+	for _, span := range spans {
+		dspan, ok := staticSpan(span)
+		if !ok {
+			return -1
+		}
+
+		stats, err := index.Statistics("", dspan)
+		if err != nil || stats == nil {
+			return -1
+		}
+
+		count, err := stats.Count()
+		if err != nil {
+			return -1
+		}
+		total += count
+	}
+	return total
+}
+
+func staticSpan(span *Span) (*datastore.Span, bool) {
+	seek, ok := staticKey(span.Seek)
+	if !ok {
+		return nil, false
+	}
+
+	low, ok := staticKey(span.Range.Low)
+	if !ok {
+		return nil, false
+	}
+
+	high, ok := staticKey(span.Range.High)
+	if !ok {
+		return nil, false
+	}
+
+	return &datastore.Span{
+		Seek: seek,
+		Range: datastore.Range{
+			Low:       low,
+			High:      high,
+			Inclusion: span.Range.Inclusion,
+		},
+	}, true
+}
+
+func staticKey(key expression.Expressions) (value.Values, bool) {
+	if len(key) == 0 {
+		return nil, true
+	}
+
+	values := make(value.Values, len(key))
+	for i, expr := range key {
+		if expr == nil {
+			return nil, false
+		}
+
+		values[i] = expr.Value()
+		if values[i] == nil {
+			return nil, false
+		}
+	}
+	return values, true
+}
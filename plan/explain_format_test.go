@@ -0,0 +1,208 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mem"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/expression/parser"
+	"github.com/couchbase/query/value"
+)
+
+func parseExpr(t *testing.T, text string) expression.Expression {
+	expr, err := parser.Parse(text)
+	if err != nil {
+		t.Fatalf("Error parsing %s: %v", text, err)
+	}
+
+	return expr
+}
+
+/*
+The plan of SELECT o.id FROM orders o WHERE o.total > 15 ORDER BY o.id
+LIMIT 2, with a Parallel nested in a Sequence nested in a Sequence.
+*/
+func newExplainPlan(t *testing.T) Operator {
+	store, err := mem.NewStore("mem:orders")
+	if err != nil {
+		t.Fatalf("Error creating datastore: %v", err)
+	}
+
+	namespace, err := store.NamespaceByName("default")
+	if err != nil {
+		t.Fatalf("Error getting namespace: %v", err)
+	}
+
+	keyspace, err := namespace.KeyspaceByName("orders")
+	if err != nil {
+		t.Fatalf("Error getting keyspace: %v", err)
+	}
+
+	_, err = keyspace.Insert([]value.Pair{
+		value.Pair{Name: "o1", Value: value.NewValue(map[string]interface{}{"id": "o1", "total": 10})},
+		value.Pair{Name: "o2", Value: value.NewValue(map[string]interface{}{"id": "o2", "total": 20})},
+		value.Pair{Name: "o3", Value: value.NewValue(map[string]interface{}{"id": "o3", "total": 30})},
+	}, datastore.NULL_QUERY_CONTEXT)
+	if err != nil {
+		t.Fatalf("Error inserting documents: %v", err)
+	}
+
+	indexer, err := keyspace.Indexer(datastore.GSI)
+	if err != nil {
+		t.Fatalf("Error getting indexer: %v", err)
+	}
+
+	primary, err := indexer.CreatePrimaryIndex("", "#primary", nil)
+	if err != nil {
+		t.Fatalf("Error creating primary index: %v", err)
+	}
+
+	term := algebra.NewKeyspaceTerm("default", "orders", "o", nil, nil)
+	projection := algebra.NewProjection(false, algebra.ResultTerms{
+		algebra.NewResultTerm(parseExpr(t, "o.id"), false, "id"),
+	})
+	order := algebra.NewOrder(algebra.SortTerms{algebra.NewSortTerm(parseExpr(t, "o.id"), false)})
+	limit := NewLimit(expression.NewConstant(2))
+
+	return NewSequence(
+		NewSequence(
+			NewPrimaryScan(primary, keyspace, term, nil),
+			NewParallel(
+				NewSequence(
+					NewFetch(keyspace, term),
+					NewFilter(parseExpr(t, "o.total > 15")),
+					NewInitialProject(projection),
+				),
+				4,
+			),
+		),
+		NewOrder(order, nil, limit),
+		limit,
+		NewFinalProject(),
+	)
+}
+
+func formatExplain(t *testing.T, format string) map[string]interface{} {
+	text := "SELECT o.id FROM orders o WHERE o.total > 15 ORDER BY o.id LIMIT 2"
+	bytes, err := FormatExplain(NewExplain(newExplainPlan(t), text, nil, format, nil))
+	if err != nil {
+		t.Fatalf("Error formatting %s: %v", format, err)
+	}
+
+	var r map[string]interface{}
+	err = json.Unmarshal(bytes, &r)
+	if err != nil {
+		t.Fatalf("Error decoding %s: %v", bytes, err)
+	}
+
+	if r["text"] != text {
+		t.Errorf("Expected text %s, got %v", text, r["text"])
+	}
+
+	return r
+}
+
+func TestFormatExplainText(t *testing.T) {
+	expected := []string{
+		"Sequence",
+		"  Sequence",
+		"    PrimaryScan (index: #primary, keyspace: default:orders AS o, estimate: 3)",
+		"    Parallel (max_parallelism: 4)",
+		"      Sequence",
+		"        Fetch (keyspace: default:orders AS o)",
+		"        Filter (condition: (15 < (`o`.`total`)))",
+		"        InitialProject (result_terms: (`o`.`id`) AS id)",
+		"  Order (sort_terms: (`o`.`id`), limit: 2)",
+		"  Limit (expr: 2)",
+		"  FinalProject",
+	}
+
+	r := formatExplain(t, algebra.EXPLAIN_FORMAT_TEXT)
+	lines, _ := r["plan"].([]interface{})
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %v", len(expected), r["plan"])
+	}
+
+	for i, line := range lines {
+		if line != expected[i] {
+			t.Errorf("Expected line %d to be %q, got %q", i, expected[i], line)
+		}
+	}
+}
+
+func TestFormatExplainDot(t *testing.T) {
+	expected := `digraph plan {
+  node [shape=box];
+  n0 [label="Sequence"];
+  n1 [label="Sequence"];
+  n2 [label="PrimaryScan\nindex: #primary\nkeyspace: default:orders AS o\nestimate: 3"];
+  n1 -> n2;
+  n3 [label="Parallel\nmax_parallelism: 4"];
+  n4 [label="Sequence"];
+  n5 [label="Fetch\nkeyspace: default:orders AS o"];
+  n4 -> n5;
+  n6 [label="Filter\ncondition: (15 < (` + "`o`.`total`" + `))"];
+  n4 -> n6;
+  n7 [label="InitialProject\nresult_terms: (` + "`o`.`id`" + `) AS id"];
+  n4 -> n7;
+  n3 -> n4;
+  n1 -> n3;
+  n0 -> n1;
+  n8 [label="Order\nsort_terms: (` + "`o`.`id`" + `)\nlimit: 2"];
+  n0 -> n8;
+  n9 [label="Limit\nexpr: 2"];
+  n0 -> n9;
+  n10 [label="FinalProject"];
+  n0 -> n10;
+}
+`
+
+	r := formatExplain(t, algebra.EXPLAIN_FORMAT_DOT)
+	if r["plan"] != expected {
+		t.Errorf("Expected\n%s\ngot\n%v", expected, r["plan"])
+	}
+}
+
+func TestFormatExplainJSON(t *testing.T) {
+	expected := `{
+  "plan": {"#operator": "Sequence", "~children": [
+    {"#operator": "Sequence", "~children": [
+      {"#operator": "PrimaryScan", "index": "#primary", "keyspace": "orders", "namespace": "default", "using": "gsi"},
+      {"#operator": "Parallel", "maxParallelism": 4, "~child":
+        {"#operator": "Sequence", "~children": [
+          {"#operator": "Fetch", "as": "o", "keyspace": "orders", "namespace": "default"},
+          {"#operator": "Filter", "condition": "(15 < (` + "`o`.`total`" + `))"},
+          {"#operator": "InitialProject", "result_terms": [{"as": "id", "expr": "(` + "`o`.`id`" + `)"}]}]}}]},
+    {"#operator": "Order", "limit": "2", "sort_terms": [{"expr": "(` + "`o`.`id`" + `)"}]},
+    {"#operator": "Limit", "expr": "2"},
+    {"#operator": "FinalProject"}]},
+  "estimates": [
+    {"#operator": "PrimaryScan", "estimate": 3, "index": "#primary", "keyspace": "default:orders AS o"}]
+}`
+
+	var e map[string]interface{}
+	err := json.Unmarshal([]byte(expected), &e)
+	if err != nil {
+		t.Fatalf("Error decoding expected output: %v", err)
+	}
+
+	r := formatExplain(t, algebra.EXPLAIN_FORMAT_JSON)
+	delete(r, "text")
+	if !reflect.DeepEqual(r, e) {
+		actual, _ := json.MarshalIndent(r, "", "  ")
+		t.Errorf("Expected\n%s\ngot\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,111 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+GroupJoin binds, as a variable of each item, the result of a scalar
+aggregate subquery that is correlated by equality. The inner plan is
+uncorrelated and grouped by the correlation keys; it is evaluated
+once, and each of its rows is the pair [keys, result]. Items whose
+keys match no group are bound to the result over no rows.
+*/
+type GroupJoin struct {
+	readonly
+	inner Operator
+	keys  expression.Expressions
+	as    string
+	empty value.Value
+}
+
+func NewGroupJoin(inner Operator, keys expression.Expressions, as string, empty value.Value) *GroupJoin {
+	return &GroupJoin{
+		inner: inner,
+		keys:  keys,
+		as:    as,
+		empty: empty,
+	}
+}
+
+func (this *GroupJoin) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitGroupJoin(this)
+}
+
+func (this *GroupJoin) New() Operator {
+	return &GroupJoin{}
+}
+
+func (this *GroupJoin) Inner() Operator {
+	return this.inner
+}
+
+func (this *GroupJoin) Keys() expression.Expressions {
+	return this.keys
+}
+
+func (this *GroupJoin) As() string {
+	return this.as
+}
+
+func (this *GroupJoin) Empty() value.Value {
+	return this.empty
+}
+
+func (this *GroupJoin) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "GroupJoin"}
+	keylist := make([]string, 0, len(this.keys))
+	for _, key := range this.keys {
+		keylist = append(keylist, expression.NewStringer().Visit(key))
+	}
+	r["keys"] = keylist
+	r["as"] = this.as
+	r["empty"] = this.empty
+	r["~inner"] = this.inner
+	return json.Marshal(r)
+}
+
+func (this *GroupJoin) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_     string          `json:"#operator"`
+		Keys  []string        `json:"keys"`
+		As    string          `json:"as"`
+		Empty json.RawMessage `json:"empty"`
+		Inner json.RawMessage `json:"~inner"`
+	}
+	var inner_type struct {
+		Operator string `json:"#operator"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.keys, err = parseKeys(_unmarshalled.Keys)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(_unmarshalled.Inner, &inner_type)
+	if err != nil {
+		return err
+	}
+
+	this.as = _unmarshalled.As
+	this.empty = value.NewValue([]byte(_unmarshalled.Empty))
+	this.inner, err = MakeOperator(inner_type.Operator, _unmarshalled.Inner)
+	return err
+}
@@ -0,0 +1,119 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/expression/parser"
+)
+
+/*
+SemiJoin keeps the items whose keys match a row of the inner plan,
+or, if anti, the items whose keys match none. The inner plan is
+uncorrelated; it is evaluated once, and each of its rows is the
+array of keys to match. It replaces EXISTS, NOT EXISTS and IN
+subqueries that are correlated by equality.
+*/
+type SemiJoin struct {
+	readonly
+	inner Operator
+	keys  expression.Expressions
+	anti  bool
+}
+
+func NewSemiJoin(inner Operator, keys expression.Expressions, anti bool) *SemiJoin {
+	return &SemiJoin{
+		inner: inner,
+		keys:  keys,
+		anti:  anti,
+	}
+}
+
+func (this *SemiJoin) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitSemiJoin(this)
+}
+
+func (this *SemiJoin) New() Operator {
+	return &SemiJoin{}
+}
+
+func (this *SemiJoin) Inner() Operator {
+	return this.inner
+}
+
+func (this *SemiJoin) Keys() expression.Expressions {
+	return this.keys
+}
+
+func (this *SemiJoin) Anti() bool {
+	return this.anti
+}
+
+func (this *SemiJoin) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "SemiJoin"}
+	keylist := make([]string, 0, len(this.keys))
+	for _, key := range this.keys {
+		keylist = append(keylist, expression.NewStringer().Visit(key))
+	}
+	r["keys"] = keylist
+
+	if this.anti {
+		r["anti"] = this.anti
+	}
+
+	r["~inner"] = this.inner
+	return json.Marshal(r)
+}
+
+func (this *SemiJoin) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_     string          `json:"#operator"`
+		Keys  []string        `json:"keys"`
+		Anti  bool            `json:"anti"`
+		Inner json.RawMessage `json:"~inner"`
+	}
+	var inner_type struct {
+		Operator string `json:"#operator"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.keys, err = parseKeys(_unmarshalled.Keys)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(_unmarshalled.Inner, &inner_type)
+	if err != nil {
+		return err
+	}
+
+	this.anti = _unmarshalled.Anti
+	this.inner, err = MakeOperator(inner_type.Operator, _unmarshalled.Inner)
+	return err
+}
+
+func parseKeys(keys []string) (expression.Expressions, error) {
+	exprs := make(expression.Expressions, len(keys))
+	for i, key := range keys {
+		expr, err := parser.Parse(key)
+		if err != nil {
+			return nil, err
+		}
+		exprs[i] = expr
+	}
+
+	return exprs, nil
+}
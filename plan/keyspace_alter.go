@@ -0,0 +1,104 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/schema"
+	"github.com/couchbase/query/value"
+)
+
+// Alter keyspace
+type AlterKeyspace struct {
+	readwrite
+	keyspace datastore.SchemaKeyspace
+	schema   *schema.Schema
+}
+
+func NewAlterKeyspace(keyspace datastore.SchemaKeyspace, schema *schema.Schema) *AlterKeyspace {
+	return &AlterKeyspace{
+		keyspace: keyspace,
+		schema:   schema,
+	}
+}
+
+func (this *AlterKeyspace) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitAlterKeyspace(this)
+}
+
+func (this *AlterKeyspace) New() Operator {
+	return &AlterKeyspace{}
+}
+
+func (this *AlterKeyspace) Keyspace() datastore.SchemaKeyspace {
+	return this.keyspace
+}
+
+/*
+Returns the schema, or nil to unset the schema.
+*/
+func (this *AlterKeyspace) Schema() *schema.Schema {
+	return this.schema
+}
+
+func (this *AlterKeyspace) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "AlterKeyspace"}
+	r["keyspace"] = this.keyspace.Name()
+	r["namespace"] = this.keyspace.NamespaceId()
+	if this.schema != nil {
+		r["schema"] = this.schema.Source()
+	}
+	return json.Marshal(r)
+}
+
+func (this *AlterKeyspace) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_      string          `json:"#operator"`
+		Keysp  string          `json:"keyspace"`
+		Namesp string          `json:"namespace"`
+		Schema json.RawMessage `json:"schema"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.keyspace, err = schemaKeyspace(_unmarshalled.Namesp, _unmarshalled.Keysp)
+	if err != nil {
+		return err
+	}
+
+	if len(_unmarshalled.Schema) > 0 {
+		this.schema, err = schema.Compile(value.NewValue([]byte(_unmarshalled.Schema)))
+		if err != nil {
+			return errors.NewSchemaError(err, this.keyspace.Name())
+		}
+	}
+
+	return nil
+}
+
+func schemaKeyspace(namespace, keyspace string) (datastore.SchemaKeyspace, error) {
+	ks, err := datastore.GetKeyspace(namespace, keyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	sks, ok := ks.(datastore.SchemaKeyspace)
+	if !ok {
+		return nil, errors.NewSchemasNotSupportedError(namespace + ":" + keyspace)
+	}
+
+	return sks, nil
+}
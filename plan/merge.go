@@ -18,11 +18,21 @@ import (
 	"github.com/couchbase/query/expression/parser"
 )
 
+/*
+Merge matches each source item to the target document of its key or,
+for an ON condition, to the rows of the inner plan. The inner plan is
+evaluated once; each of its rows is [keys, id, document], where keys
+are matched to the keys of the source item, and the rest of the ON
+condition is evaluated with the document bound to the target alias.
+*/
 type Merge struct {
 	readwrite
 	keyspace datastore.Keyspace
 	ref      *algebra.KeyspaceRef
 	key      expression.Expression
+	inner    Operator
+	keys     expression.Expressions
+	on       expression.Expression
 	update   Operator
 	delete   Operator
 	insert   Operator
@@ -40,6 +50,20 @@ func NewMerge(keyspace datastore.Keyspace, ref *algebra.KeyspaceRef,
 	}
 }
 
+func NewMergeOn(keyspace datastore.Keyspace, ref *algebra.KeyspaceRef, inner Operator,
+	keys expression.Expressions, on expression.Expression, update, delete, insert Operator) *Merge {
+	return &Merge{
+		keyspace: keyspace,
+		ref:      ref,
+		inner:    inner,
+		keys:     keys,
+		on:       on,
+		update:   update,
+		delete:   delete,
+		insert:   insert,
+	}
+}
+
 func (this *Merge) Accept(visitor Visitor) (interface{}, error) {
 	return visitor.VisitMerge(this)
 }
@@ -60,6 +84,18 @@ func (this *Merge) Key() expression.Expression {
 	return this.key
 }
 
+func (this *Merge) Inner() Operator {
+	return this.inner
+}
+
+func (this *Merge) Keys() expression.Expressions {
+	return this.keys
+}
+
+func (this *Merge) On() expression.Expression {
+	return this.on
+}
+
 func (this *Merge) Update() Operator {
 	return this.update
 }
@@ -76,7 +112,21 @@ func (this *Merge) MarshalJSON() ([]byte, error) {
 	r := map[string]interface{}{"#operator": "Merge"}
 	r["keyspace"] = this.keyspace.Name()
 	r["namespace"] = this.keyspace.NamespaceId()
-	r["key"] = expression.NewStringer().Visit(this.key)
+	if this.key != nil {
+		r["key"] = expression.NewStringer().Visit(this.key)
+	} else {
+		keylist := make([]string, 0, len(this.keys))
+		for _, key := range this.keys {
+			keylist = append(keylist, expression.NewStringer().Visit(key))
+		}
+		r["keys"] = keylist
+
+		if this.on != nil {
+			r["on"] = expression.NewStringer().Visit(this.on)
+		}
+
+		r["~inner"] = this.inner
+	}
 
 	if this.ref.As() != "" {
 		r["as"] = this.ref.As()
@@ -107,6 +157,9 @@ func (this *Merge) UnmarshalJSON(body []byte) error {
 		Names  string          `json:"namespace"`
 		As     string          `json:"as"`
 		Key    string          `json:"key"`
+		OnKeys []string        `json:"keys"`
+		On     string          `json:"on"`
+		Inner  json.RawMessage `json:"~inner"`
 		Update json.RawMessage `json:"update"`
 		Delete json.RawMessage `json:"delete"`
 		Insert json.RawMessage `json:"insert"`
@@ -131,10 +184,25 @@ func (this *Merge) UnmarshalJSON(body []byte) error {
 		}
 	}
 
+	if _unmarshalled.On != "" {
+		this.on, err = parser.Parse(_unmarshalled.On)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(_unmarshalled.Inner) > 0 {
+		this.keys, err = parseKeys(_unmarshalled.OnKeys)
+		if err != nil {
+			return err
+		}
+	}
+
 	ops := []json.RawMessage{
 		_unmarshalled.Update,
 		_unmarshalled.Delete,
 		_unmarshalled.Insert,
+		_unmarshalled.Inner,
 	}
 
 	for i, child := range ops {
@@ -158,6 +226,8 @@ func (this *Merge) UnmarshalJSON(body []byte) error {
 			this.delete, err = MakeOperator(op_type.Operator, child)
 		case 2:
 			this.insert, err = MakeOperator(op_type.Operator, child)
+		case 3:
+			this.inner, err = MakeOperator(op_type.Operator, child)
 		}
 
 		if err != nil {
@@ -0,0 +1,94 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+// Create view
+type CreateView struct {
+	readwrite
+	namespace datastore.NamedViewNamespace
+	view      *datastore.NamedView
+}
+
+func NewCreateView(namespace datastore.NamedViewNamespace, view *datastore.NamedView) *CreateView {
+	return &CreateView{
+		namespace: namespace,
+		view:      view,
+	}
+}
+
+func (this *CreateView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateView(this)
+}
+
+func (this *CreateView) New() Operator {
+	return &CreateView{}
+}
+
+func (this *CreateView) Namespace() datastore.NamedViewNamespace {
+	return this.namespace
+}
+
+func (this *CreateView) View() *datastore.NamedView {
+	return this.view
+}
+
+func (this *CreateView) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "CreateView"}
+	r["namespace"] = this.namespace.Name()
+	r["view"] = this.view
+	return json.Marshal(r)
+}
+
+func (this *CreateView) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_      string               `json:"#operator"`
+		Namesp string               `json:"namespace"`
+		View   *datastore.NamedView `json:"view"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.namespace, err = namedViewNamespace(_unmarshalled.Namesp)
+	if err != nil {
+		return err
+	}
+
+	this.view = _unmarshalled.View
+	return nil
+}
+
+func namedViewNamespace(namespace string) (datastore.NamedViewNamespace, error) {
+	store := datastore.GetDatastore()
+	if store == nil {
+		return nil, errors.NewError(nil, "Datastore not set.")
+	}
+
+	ns, err := store.NamespaceByName(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	vns, ok := ns.(datastore.NamedViewNamespace)
+	if !ok {
+		return nil, errors.NewNamedViewsNotSupportedError(namespace)
+	}
+
+	return vns, nil
+}
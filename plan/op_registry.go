@@ -61,6 +61,8 @@ var _OPERATORS = map[string]Operator{
 	"IndexJoin": &IndexJoin{},
 	"Nest":      &Nest{},
 	"IndexNest": &IndexNest{},
+	"SemiJoin":  &SemiJoin{},
+	"GroupJoin": &GroupJoin{},
 	"Unnest":    &Unnest{},
 
 	// Let + Letting
@@ -113,13 +115,14 @@ var _OPERATORS = map[string]Operator{
 	"Merge": &Merge{},
 
 	// Framework
-	"Alias":     &Alias{},
-	"Authorize": &Authorize{},
-	"Parallel":  &Parallel{},
-	"Sequence":  &Sequence{},
-	"Discard":   &Discard{},
-	"Stream":    &Stream{},
-	"Collect":   &Collect{},
+	"Alias":      &Alias{},
+	"Authorize":  &Authorize{},
+	"OptimHints": &OptimHints{},
+	"Parallel":   &Parallel{},
+	"Sequence":   &Sequence{},
+	"Discard":    &Discard{},
+	"Stream":     &Stream{},
+	"Collect":    &Collect{},
 
 	// Index DDL
 	"CreatePrimaryIndex": &CreatePrimaryIndex{},
@@ -128,6 +131,35 @@ var _OPERATORS = map[string]Operator{
 	"AlterIndex":         &AlterIndex{},
 	"BuildIndexes":       &BuildIndexes{},
 
+	// Trigger DDL
+	"CreateTrigger": &CreateTrigger{},
+	"DropTrigger":   &DropTrigger{},
+
+	// Materialized view DDL
+	"CreateMaterializedView":  &CreateMaterializedView{},
+	"RefreshMaterializedView": &RefreshMaterializedView{},
+	"DropMaterializedView":    &DropMaterializedView{},
+
+	// View DDL
+	"CreateView": &CreateView{},
+	"DropView":   &DropView{},
+
+	// Sequence DDL
+	"CreateSequence": &CreateSequence{},
+	"DropSequence":   &DropSequence{},
+
+	// Plan baselines
+	"CreatePlanBaseline": &CreatePlanBaseline{},
+	"DropPlanBaseline":   &DropPlanBaseline{},
+
+	// Schema
+	"AlterKeyspace": &AlterKeyspace{},
+	"Validate":      &Validate{},
+
+	// Advise
+	"Advise":         &Advise{},
+	"AdviseRequests": &AdviseRequests{},
+
 	// Explain
 	"Explain": &Explain{},
 
@@ -0,0 +1,102 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+)
+
+/*
+Status of an optimizer hint after planning.
+*/
+const (
+	OPTIM_HINT_FOLLOWED     = "followed"
+	OPTIM_HINT_NOT_FOLLOWED = "not_followed"
+	OPTIM_HINT_INVALID      = "invalid"
+)
+
+type OptimHintStatuses []*OptimHintStatus
+
+/*
+OptimHintStatus records whether the planner followed an optimizer
+hint, and if not, why.
+*/
+type OptimHintStatus struct {
+	Hint   string `json:"hint"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+/*
+OptimHints reports the optimizer hints that were not followed as
+warnings, and then runs its child.
+*/
+type OptimHints struct {
+	readonly
+	hints OptimHintStatuses
+	child Operator
+}
+
+func NewOptimHints(hints OptimHintStatuses, child Operator) *OptimHints {
+	return &OptimHints{
+		hints: hints,
+		child: child,
+	}
+}
+
+func (this *OptimHints) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitOptimHints(this)
+}
+
+func (this *OptimHints) New() Operator {
+	return &OptimHints{}
+}
+
+func (this *OptimHints) Hints() OptimHintStatuses {
+	return this.hints
+}
+
+func (this *OptimHints) Readonly() bool {
+	return this.child.Readonly()
+}
+
+func (this *OptimHints) Child() Operator {
+	return this.child
+}
+
+func (this *OptimHints) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "OptimHints"}
+	r["hints"] = this.hints
+	r["child"] = this.child
+	return json.Marshal(r)
+}
+
+func (this *OptimHints) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_     string            `json:"#operator"`
+		Hints OptimHintStatuses `json:"hints"`
+		Child json.RawMessage   `json:"child"`
+	}
+	var child_type struct {
+		Operator string `json:"#operator"`
+	}
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+	this.hints = _unmarshalled.Hints
+
+	err = json.Unmarshal(_unmarshalled.Child, &child_type)
+	if err != nil {
+		return err
+	}
+	this.child, err = MakeOperator(child_type.Operator, _unmarshalled.Child)
+	return err
+}
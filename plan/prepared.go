@@ -30,6 +30,8 @@ type Prepared struct {
 	name         string
 	encoded_plan string
 	text         string
+	subqueries   SubqueryPlans
+	volatile     bool
 }
 
 func NewPrepared(operator Operator, signature value.Value) *Prepared {
@@ -40,12 +42,15 @@ func NewPrepared(operator Operator, signature value.Value) *Prepared {
 }
 
 func (this *Prepared) MarshalJSON() ([]byte, error) {
-	r := make(map[string]interface{}, 5)
+	r := make(map[string]interface{}, 6)
 	r["operator"] = this.Operator
 	r["signature"] = this.signature
 	r["name"] = this.name
 	r["encoded_plan"] = this.encoded_plan
 	r["text"] = this.text
+	if this.volatile {
+		r["volatile"] = this.volatile
+	}
 
 	return json.Marshal(r)
 }
@@ -57,6 +62,7 @@ func (this *Prepared) UnmarshalJSON(body []byte) error {
 		Name        string          `json:"name"`
 		EncodedPlan string          `json:"encoded_plan"`
 		Text        string          `json:"text"`
+		Volatile    bool            `json:"volatile"`
 	}
 
 	var op_type struct {
@@ -77,6 +83,7 @@ func (this *Prepared) UnmarshalJSON(body []byte) error {
 	this.name = _unmarshalled.Name
 	this.encoded_plan = _unmarshalled.EncodedPlan
 	this.text = _unmarshalled.Text
+	this.volatile = _unmarshalled.Volatile
 	this.Operator, err = MakeOperator(op_type.Operator, _unmarshalled.Operator)
 
 	return err
@@ -102,6 +109,26 @@ func (this *Prepared) SetText(text string) {
 	this.text = text
 }
 
+func (this *Prepared) Subqueries() SubqueryPlans {
+	return this.subqueries
+}
+
+func (this *Prepared) SetSubqueries(subqueries SubqueryPlans) {
+	this.subqueries = subqueries
+}
+
+/*
+Returns true if the statement calls functions, such as NOW_STR() or
+NEXTVAL(), whose results differ between executions.
+*/
+func (this *Prepared) Volatile() bool {
+	return this.volatile
+}
+
+func (this *Prepared) SetVolatile(volatile bool) {
+	this.volatile = volatile
+}
+
 func (this *Prepared) EncodedPlan() string {
 	return this.encoded_plan
 }
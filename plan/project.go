@@ -128,7 +128,13 @@ func (this *InitialProject) UnmarshalJSON(body []byte) error {
 		}
 		terms[i] = algebra.NewResultTerm(expr, term_data.Star, term_data.As)
 	}
-	projection := algebra.NewProjection(_unmarshalled.Distinct, terms)
+	var projection *algebra.Projection
+	if _unmarshalled.Raw && len(terms) == 1 {
+		projection = algebra.NewRawProjection(_unmarshalled.Distinct, terms[0].Expression(), terms[0].As())
+	} else {
+		projection = algebra.NewProjection(_unmarshalled.Distinct, terms)
+	}
+
 	results := projection.Terms()
 	project_terms := make(ProjectTerms, len(results))
 
@@ -28,6 +28,8 @@ type IndexScan struct {
 	limit        expression.Expression
 	covers       expression.Covers
 	filterCovers map[*expression.Cover]value.Value
+	partitioned  bool
+	ordered      bool
 }
 
 func NewIndexScan(index datastore.Index, term *algebra.KeyspaceTerm, spans Spans,
@@ -44,6 +46,24 @@ func NewIndexScan(index datastore.Index, term *algebra.KeyspaceTerm, spans Spans
 	}
 }
 
+/*
+NewPartitionedIndexScan scans disjoint key ranges of a span
+concurrently. If ordered, the entries of each range are returned
+before those of the next, for plans that rely on index order.
+*/
+func NewPartitionedIndexScan(index datastore.Index, term *algebra.KeyspaceTerm, partitions Spans,
+	covers expression.Covers, filterCovers map[*expression.Cover]value.Value, ordered bool) *IndexScan {
+	return &IndexScan{
+		index:        index,
+		term:         term,
+		spans:        partitions,
+		covers:       covers,
+		filterCovers: filterCovers,
+		partitioned:  true,
+		ordered:      ordered,
+	}
+}
+
 func (this *IndexScan) Accept(visitor Visitor) (interface{}, error) {
 	return visitor.VisitIndexScan(this)
 }
@@ -80,6 +100,14 @@ func (this *IndexScan) FilterCovers() map[*expression.Cover]value.Value {
 	return this.filterCovers
 }
 
+func (this *IndexScan) Partitioned() bool {
+	return this.partitioned
+}
+
+func (this *IndexScan) Ordered() bool {
+	return this.ordered
+}
+
 func (this *IndexScan) Covering() bool {
 	return len(this.covers) > 0
 }
@@ -101,6 +129,14 @@ func (this *IndexScan) MarshalJSON() ([]byte, error) {
 		r["limit"] = expression.NewStringer().Visit(this.limit)
 	}
 
+	if this.partitioned {
+		r["partitioned"] = this.partitioned
+	}
+
+	if this.ordered {
+		r["ordered"] = this.ordered
+	}
+
 	if len(this.covers) > 0 {
 		r["covers"] = this.covers
 	}
@@ -132,6 +168,8 @@ func (this *IndexScan) UnmarshalJSON(body []byte) error {
 		Spans        Spans                      `json:"spans"`
 		Distinct     bool                       `json:"distinct"`
 		Limit        string                     `json:"limit"`
+		Partitioned  bool                       `json:"partitioned"`
+		Ordered      bool                       `json:"ordered"`
 		Covers       []string                   `json:"covers"`
 		FilterCovers map[string]json.RawMessage `json:"filter_covers"`
 	}
@@ -149,6 +187,8 @@ func (this *IndexScan) UnmarshalJSON(body []byte) error {
 	this.term = algebra.NewKeyspaceTerm(_unmarshalled.Namespace, _unmarshalled.Keyspace, "", nil, nil)
 	this.spans = _unmarshalled.Spans
 	this.distinct = _unmarshalled.Distinct
+	this.partitioned = _unmarshalled.Partitioned
+	this.ordered = _unmarshalled.Ordered
 
 	if _unmarshalled.Limit != "" {
 		this.limit, err = parser.Parse(_unmarshalled.Limit)
@@ -21,10 +21,11 @@ import (
 
 type PrimaryScan struct {
 	readonly
-	index    datastore.PrimaryIndex
-	keyspace datastore.Keyspace
-	term     *algebra.KeyspaceTerm
-	limit    expression.Expression
+	index      datastore.PrimaryIndex
+	keyspace   datastore.Keyspace
+	term       *algebra.KeyspaceTerm
+	limit      expression.Expression
+	partitions Spans
 }
 
 func NewPrimaryScan(index datastore.PrimaryIndex, keyspace datastore.Keyspace,
@@ -37,6 +38,20 @@ func NewPrimaryScan(index datastore.PrimaryIndex, keyspace datastore.Keyspace,
 	}
 }
 
+/*
+NewPartitionedPrimaryScan scans disjoint key ranges of the primary
+index concurrently.
+*/
+func NewPartitionedPrimaryScan(index datastore.PrimaryIndex, keyspace datastore.Keyspace,
+	term *algebra.KeyspaceTerm, partitions Spans) *PrimaryScan {
+	return &PrimaryScan{
+		index:      index,
+		keyspace:   keyspace,
+		term:       term,
+		partitions: partitions,
+	}
+}
+
 func (this *PrimaryScan) Accept(visitor Visitor) (interface{}, error) {
 	return visitor.VisitPrimaryScan(this)
 }
@@ -61,6 +76,10 @@ func (this *PrimaryScan) Limit() expression.Expression {
 	return this.limit
 }
 
+func (this *PrimaryScan) Partitions() Spans {
+	return this.partitions
+}
+
 func (this *PrimaryScan) MarshalJSON() ([]byte, error) {
 	r := map[string]interface{}{"#operator": "PrimaryScan"}
 	r["index"] = this.index.Name()
@@ -71,6 +90,9 @@ func (this *PrimaryScan) MarshalJSON() ([]byte, error) {
 	if this.limit != nil {
 		r["limit"] = expression.NewStringer().Visit(this.limit)
 	}
+	if len(this.partitions) > 0 {
+		r["partitions"] = this.partitions
+	}
 	if this.duration != 0 {
 		r["#time"] = this.duration.String()
 	}
@@ -86,6 +108,7 @@ func (this *PrimaryScan) UnmarshalJSON(body []byte) error {
 		Keys  string              `json:"keyspace"`
 		Using datastore.IndexType `json:"using"`
 		Limit string              `json:"limit"`
+		Parts Spans               `json:"partitions"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -100,6 +123,8 @@ func (this *PrimaryScan) UnmarshalJSON(body []byte) error {
 		}
 	}
 
+	this.partitions = _unmarshalled.Parts
+
 	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
 	if err != nil {
 		return err
@@ -0,0 +1,94 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+// Create sequence
+type CreateSequence struct {
+	readwrite
+	namespace datastore.SequenceNamespace
+	sequence  *datastore.Sequence
+}
+
+func NewCreateSequence(namespace datastore.SequenceNamespace, sequence *datastore.Sequence) *CreateSequence {
+	return &CreateSequence{
+		namespace: namespace,
+		sequence:  sequence,
+	}
+}
+
+func (this *CreateSequence) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateSequence(this)
+}
+
+func (this *CreateSequence) New() Operator {
+	return &CreateSequence{}
+}
+
+func (this *CreateSequence) Namespace() datastore.SequenceNamespace {
+	return this.namespace
+}
+
+func (this *CreateSequence) Sequence() *datastore.Sequence {
+	return this.sequence
+}
+
+func (this *CreateSequence) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "CreateSequence"}
+	r["namespace"] = this.namespace.Name()
+	r["sequence"] = this.sequence
+	return json.Marshal(r)
+}
+
+func (this *CreateSequence) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_        string              `json:"#operator"`
+		Namesp   string              `json:"namespace"`
+		Sequence *datastore.Sequence `json:"sequence"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.namespace, err = sequenceNamespace(_unmarshalled.Namesp)
+	if err != nil {
+		return err
+	}
+
+	this.sequence = _unmarshalled.Sequence
+	return nil
+}
+
+func sequenceNamespace(namespace string) (datastore.SequenceNamespace, error) {
+	store := datastore.GetDatastore()
+	if store == nil {
+		return nil, errors.NewError(nil, "Datastore not set.")
+	}
+
+	ns, err := store.NamespaceByName(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	sns, ok := ns.(datastore.SequenceNamespace)
+	if !ok {
+		return nil, errors.NewSequencesNotSupportedError(namespace)
+	}
+
+	return sns, nil
+}
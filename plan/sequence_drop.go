@@ -0,0 +1,70 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+)
+
+// Drop sequence
+type DropSequence struct {
+	readwrite
+	namespace datastore.SequenceNamespace
+	name      string
+}
+
+func NewDropSequence(namespace datastore.SequenceNamespace, name string) *DropSequence {
+	return &DropSequence{
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (this *DropSequence) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDropSequence(this)
+}
+
+func (this *DropSequence) New() Operator {
+	return &DropSequence{}
+}
+
+func (this *DropSequence) Namespace() datastore.SequenceNamespace {
+	return this.namespace
+}
+
+func (this *DropSequence) Name() string {
+	return this.name
+}
+
+func (this *DropSequence) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "DropSequence"}
+	r["namespace"] = this.namespace.Name()
+	r["name"] = this.name
+	return json.Marshal(r)
+}
+
+func (this *DropSequence) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_      string `json:"#operator"`
+		Namesp string `json:"namespace"`
+		Name   string `json:"name"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.namespace, err = sequenceNamespace(_unmarshalled.Namesp)
+	this.name = _unmarshalled.Name
+	return err
+}
@@ -0,0 +1,52 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/algebra"
+)
+
+/*
+SubqueryPlan is the plan of a subquery expression, built with the
+plan of its statement. Subquery plans are not encoded in prepared
+statements; the subqueries of decoded statements are planned when
+first evaluated.
+*/
+type SubqueryPlan struct {
+	query    *algebra.Select
+	operator Operator
+}
+
+type SubqueryPlans []*SubqueryPlan
+
+func NewSubqueryPlan(query *algebra.Select, operator Operator) *SubqueryPlan {
+	return &SubqueryPlan{
+		query:    query,
+		operator: operator,
+	}
+}
+
+func (this *SubqueryPlan) Query() *algebra.Select {
+	return this.query
+}
+
+func (this *SubqueryPlan) Operator() Operator {
+	return this.operator
+}
+
+func (this *SubqueryPlan) MarshalJSON() ([]byte, error) {
+	r := make(map[string]interface{}, 3)
+	r["subquery"] = this.query.String()
+	r["correlated"] = this.query.IsCorrelated()
+	r["plan"] = this.operator
+	return json.Marshal(r)
+}
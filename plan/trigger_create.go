@@ -0,0 +1,91 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+// Create trigger
+type CreateTrigger struct {
+	readwrite
+	keyspace datastore.TriggerKeyspace
+	trigger  *datastore.Trigger
+}
+
+func NewCreateTrigger(keyspace datastore.TriggerKeyspace, trigger *datastore.Trigger) *CreateTrigger {
+	return &CreateTrigger{
+		keyspace: keyspace,
+		trigger:  trigger,
+	}
+}
+
+func (this *CreateTrigger) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateTrigger(this)
+}
+
+func (this *CreateTrigger) New() Operator {
+	return &CreateTrigger{}
+}
+
+func (this *CreateTrigger) Keyspace() datastore.TriggerKeyspace {
+	return this.keyspace
+}
+
+func (this *CreateTrigger) Trigger() *datastore.Trigger {
+	return this.trigger
+}
+
+func (this *CreateTrigger) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "CreateTrigger"}
+	r["keyspace"] = this.keyspace.Name()
+	r["namespace"] = this.keyspace.NamespaceId()
+	r["trigger"] = this.trigger
+	return json.Marshal(r)
+}
+
+func (this *CreateTrigger) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_       string             `json:"#operator"`
+		Keysp   string             `json:"keyspace"`
+		Namesp  string             `json:"namespace"`
+		Trigger *datastore.Trigger `json:"trigger"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.keyspace, err = triggerKeyspace(_unmarshalled.Namesp, _unmarshalled.Keysp)
+	if err != nil {
+		return err
+	}
+
+	this.trigger = _unmarshalled.Trigger
+	return nil
+}
+
+func triggerKeyspace(namespace, keyspace string) (datastore.TriggerKeyspace, error) {
+	ks, err := datastore.GetKeyspace(namespace, keyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	tks, ok := ks.(datastore.TriggerKeyspace)
+	if !ok {
+		return nil, errors.NewTriggersNotSupportedError(namespace + ":" + keyspace)
+	}
+
+	return tks, nil
+}
@@ -0,0 +1,76 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+)
+
+// Drop trigger
+type DropTrigger struct {
+	readwrite
+	keyspace datastore.TriggerKeyspace
+	name     string
+}
+
+func NewDropTrigger(keyspace datastore.TriggerKeyspace, name string) *DropTrigger {
+	return &DropTrigger{
+		keyspace: keyspace,
+		name:     name,
+	}
+}
+
+func (this *DropTrigger) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDropTrigger(this)
+}
+
+func (this *DropTrigger) New() Operator {
+	return &DropTrigger{}
+}
+
+func (this *DropTrigger) Keyspace() datastore.TriggerKeyspace {
+	return this.keyspace
+}
+
+func (this *DropTrigger) Name() string {
+	return this.name
+}
+
+func (this *DropTrigger) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "DropTrigger"}
+	r["keyspace"] = this.keyspace.Name()
+	r["namespace"] = this.keyspace.NamespaceId()
+	r["name"] = this.name
+	return json.Marshal(r)
+}
+
+func (this *DropTrigger) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_      string `json:"#operator"`
+		Keysp  string `json:"keyspace"`
+		Namesp string `json:"namespace"`
+		Name   string `json:"name"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.keyspace, err = triggerKeyspace(_unmarshalled.Namesp, _unmarshalled.Keysp)
+	if err != nil {
+		return err
+	}
+
+	this.name = _unmarshalled.Name
+	return nil
+}
@@ -0,0 +1,66 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+)
+
+/*
+Validate checks the documents of a keyspace, projected as id and doc,
+against the schema of the keyspace.
+*/
+type Validate struct {
+	readonly
+	keyspace datastore.SchemaKeyspace
+}
+
+func NewValidate(keyspace datastore.SchemaKeyspace) *Validate {
+	return &Validate{
+		keyspace: keyspace,
+	}
+}
+
+func (this *Validate) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitValidate(this)
+}
+
+func (this *Validate) New() Operator {
+	return &Validate{}
+}
+
+func (this *Validate) Keyspace() datastore.SchemaKeyspace {
+	return this.keyspace
+}
+
+func (this *Validate) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "Validate"}
+	r["keyspace"] = this.keyspace.Name()
+	r["namespace"] = this.keyspace.NamespaceId()
+	return json.Marshal(r)
+}
+
+func (this *Validate) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_      string `json:"#operator"`
+		Keysp  string `json:"keyspace"`
+		Namesp string `json:"namespace"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.keyspace, err = schemaKeyspace(_unmarshalled.Namesp, _unmarshalled.Keysp)
+	return err
+}
@@ -0,0 +1,109 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+// Create materialized view
+type CreateMaterializedView struct {
+	readwrite
+	namespace datastore.ViewNamespace
+	view      *datastore.MaterializedView
+}
+
+func NewCreateMaterializedView(namespace datastore.ViewNamespace,
+	view *datastore.MaterializedView) *CreateMaterializedView {
+	return &CreateMaterializedView{
+		namespace: namespace,
+		view:      view,
+	}
+}
+
+func (this *CreateMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateMaterializedView(this)
+}
+
+func (this *CreateMaterializedView) New() Operator {
+	return &CreateMaterializedView{}
+}
+
+func (this *CreateMaterializedView) Namespace() datastore.ViewNamespace {
+	return this.namespace
+}
+
+func (this *CreateMaterializedView) View() *datastore.MaterializedView {
+	return this.view
+}
+
+func (this *CreateMaterializedView) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "CreateMaterializedView"}
+	r["namespace"] = this.namespace.Name()
+	r["view"] = this.view
+	return json.Marshal(r)
+}
+
+func (this *CreateMaterializedView) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_      string                      `json:"#operator"`
+		Namesp string                      `json:"namespace"`
+		View   *datastore.MaterializedView `json:"view"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.namespace, err = viewNamespace(_unmarshalled.Namesp)
+	if err != nil {
+		return err
+	}
+
+	this.view = _unmarshalled.View
+	return nil
+}
+
+func viewNamespace(namespace string) (datastore.ViewNamespace, error) {
+	store := datastore.GetDatastore()
+	if store == nil {
+		return nil, errors.NewError(nil, "Datastore not set.")
+	}
+
+	ns, err := store.NamespaceByName(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	vns, ok := ns.(datastore.ViewNamespace)
+	if !ok {
+		return nil, errors.NewViewsNotSupportedError(namespace)
+	}
+
+	return vns, nil
+}
+
+func viewKeyspace(namespace, keyspace string) (datastore.ViewKeyspace, error) {
+	ks, err := datastore.GetKeyspace(namespace, keyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	vks, ok := ks.(datastore.ViewKeyspace)
+	if !ok || vks.View() == nil {
+		return nil, errors.NewViewNotFoundError(namespace + ":" + keyspace)
+	}
+
+	return vks, nil
+}
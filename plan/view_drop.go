@@ -0,0 +1,75 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+)
+
+// Drop materialized view
+type DropMaterializedView struct {
+	readwrite
+	namespace datastore.ViewNamespace
+	keyspace  datastore.ViewKeyspace
+}
+
+func NewDropMaterializedView(namespace datastore.ViewNamespace,
+	keyspace datastore.ViewKeyspace) *DropMaterializedView {
+	return &DropMaterializedView{
+		namespace: namespace,
+		keyspace:  keyspace,
+	}
+}
+
+func (this *DropMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDropMaterializedView(this)
+}
+
+func (this *DropMaterializedView) New() Operator {
+	return &DropMaterializedView{}
+}
+
+func (this *DropMaterializedView) Namespace() datastore.ViewNamespace {
+	return this.namespace
+}
+
+func (this *DropMaterializedView) Keyspace() datastore.ViewKeyspace {
+	return this.keyspace
+}
+
+func (this *DropMaterializedView) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "DropMaterializedView"}
+	r["keyspace"] = this.keyspace.Name()
+	r["namespace"] = this.namespace.Name()
+	return json.Marshal(r)
+}
+
+func (this *DropMaterializedView) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_      string `json:"#operator"`
+		Keysp  string `json:"keyspace"`
+		Namesp string `json:"namespace"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.namespace, err = viewNamespace(_unmarshalled.Namesp)
+	if err != nil {
+		return err
+	}
+
+	this.keyspace, err = viewKeyspace(_unmarshalled.Namesp, _unmarshalled.Keysp)
+	return err
+}
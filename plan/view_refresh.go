@@ -0,0 +1,63 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+)
+
+// Refresh materialized view
+type RefreshMaterializedView struct {
+	readwrite
+	keyspace datastore.ViewKeyspace
+}
+
+func NewRefreshMaterializedView(keyspace datastore.ViewKeyspace) *RefreshMaterializedView {
+	return &RefreshMaterializedView{
+		keyspace: keyspace,
+	}
+}
+
+func (this *RefreshMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitRefreshMaterializedView(this)
+}
+
+func (this *RefreshMaterializedView) New() Operator {
+	return &RefreshMaterializedView{}
+}
+
+func (this *RefreshMaterializedView) Keyspace() datastore.ViewKeyspace {
+	return this.keyspace
+}
+
+func (this *RefreshMaterializedView) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"#operator": "RefreshMaterializedView"}
+	r["keyspace"] = this.keyspace.Name()
+	r["namespace"] = this.keyspace.NamespaceId()
+	return json.Marshal(r)
+}
+
+func (this *RefreshMaterializedView) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_      string `json:"#operator"`
+		Keysp  string `json:"keyspace"`
+		Namesp string `json:"namespace"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.keyspace, err = viewKeyspace(_unmarshalled.Namesp, _unmarshalled.Keysp)
+	return err
+}
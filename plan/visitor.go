@@ -32,6 +32,8 @@ type Visitor interface {
 	VisitIndexJoin(op *IndexJoin) (interface{}, error)
 	VisitNest(op *Nest) (interface{}, error)
 	VisitIndexNest(op *IndexNest) (interface{}, error)
+	VisitSemiJoin(op *SemiJoin) (interface{}, error)
+	VisitGroupJoin(op *GroupJoin) (interface{}, error)
 	VisitUnnest(op *Unnest) (interface{}, error)
 
 	// Let + Letting
@@ -86,6 +88,7 @@ type Visitor interface {
 	// Framework
 	VisitAlias(op *Alias) (interface{}, error)
 	VisitAuthorize(op *Authorize) (interface{}, error)
+	VisitOptimHints(op *OptimHints) (interface{}, error)
 	VisitParallel(op *Parallel) (interface{}, error)
 	VisitSequence(op *Sequence) (interface{}, error)
 	VisitDiscard(op *Discard) (interface{}, error)
@@ -99,6 +102,35 @@ type Visitor interface {
 	VisitAlterIndex(op *AlterIndex) (interface{}, error)
 	VisitBuildIndexes(op *BuildIndexes) (interface{}, error)
 
+	// Trigger DDL
+	VisitCreateTrigger(op *CreateTrigger) (interface{}, error)
+	VisitDropTrigger(op *DropTrigger) (interface{}, error)
+
+	// Materialized view DDL
+	VisitCreateMaterializedView(op *CreateMaterializedView) (interface{}, error)
+	VisitRefreshMaterializedView(op *RefreshMaterializedView) (interface{}, error)
+	VisitDropMaterializedView(op *DropMaterializedView) (interface{}, error)
+
+	// View DDL
+	VisitCreateView(op *CreateView) (interface{}, error)
+	VisitDropView(op *DropView) (interface{}, error)
+
+	// Sequence DDL
+	VisitCreateSequence(op *CreateSequence) (interface{}, error)
+	VisitDropSequence(op *DropSequence) (interface{}, error)
+
+	// Plan baselines
+	VisitCreatePlanBaseline(op *CreatePlanBaseline) (interface{}, error)
+	VisitDropPlanBaseline(op *DropPlanBaseline) (interface{}, error)
+
+	// Schema
+	VisitAlterKeyspace(op *AlterKeyspace) (interface{}, error)
+	VisitValidate(op *Validate) (interface{}, error)
+
+	// Advise
+	VisitAdvise(op *Advise) (interface{}, error)
+	VisitAdviseRequests(op *AdviseRequests) (interface{}, error)
+
 	// Explain
 	VisitExplain(op *Explain) (interface{}, error)
 
@@ -0,0 +1,749 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+)
+
+/*
+advisor collects the index advice for the keyspace terms of a
+statement while the statement is planned.
+*/
+type advisor struct {
+	terms []interface{}
+}
+
+/*
+Classes of advised index keys, in index key order.
+*/
+const (
+	_ADVISE_EQ = iota
+	_ADVISE_IN
+	_ADVISE_ARRAY
+	_ADVISE_RANGE
+)
+
+type adviceKey struct {
+	expr     expression.Expression
+	term     expression.Expression // The predicate term sarged by the key
+	class    int
+	constant bool // Equality to a constant, usable in a partial index condition
+}
+
+/*
+adviseScan records the index advice for a keyspace term, given the
+scan chosen by the planner or the error in choosing one. Planning
+continues past scan errors, such as a missing primary index, so that
+the remaining keyspace terms are advised.
+*/
+func (this *builder) adviseScan(keyspace datastore.Keyspace, node *algebra.KeyspaceTerm,
+	scan plan.Operator, err error) (plan.Operator, error) {
+	alias := node.Alias()
+	advice := map[string]interface{}{
+		"keyspace":        keyspace.NamespaceId() + ":" + keyspace.Name(),
+		"alias":           alias,
+		"indexes":         []interface{}{},
+		"recommendations": []interface{}{},
+	}
+	this.advisor.terms = append(this.advisor.terms, advice)
+
+	used := make(map[string]bool, 4)
+	covering := false
+	if err != nil {
+		advice["error"] = err.Error()
+		scan = _EMPTY_PLAN
+	} else {
+		advice["current_scan"], covering = describeScan(scan, used)
+	}
+
+	if node.Keys() != nil || keyspace.NamespaceId() == "#system" {
+		return scan, nil
+	}
+
+	pred := this.where
+	if pred != nil {
+		cpred := pred.Value()
+		if cpred != nil && cpred.Truth() {
+			pred = nil
+		}
+	}
+
+	if pred != nil {
+		pred = pred.Copy()
+		dnf := NewDNF(pred)
+		pred, err = dnf.Map(pred)
+		if err != nil {
+			return nil, err
+		}
+
+		advice["predicate"] = pred.String()
+	}
+
+	indexes := _INDEX_POOL.Get()
+	defer _INDEX_POOL.Put(indexes)
+	indexes, err = allIndexes(keyspace, nil, indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	id := expression.NewField(
+		expression.NewMeta(expression.NewIdentifier(alias)),
+		expression.NewFieldName("id", false))
+	formalizer := expression.NewSelfFormalizer(alias, nil)
+
+	var sargables, entries map[datastore.Index]*indexEntry
+	if pred != nil {
+		sargables, entries, err = sargableIndexes(indexes, pred, pred, expression.Expressions{id}, formalizer)
+		if err != nil {
+			return nil, err
+		}
+
+		sargables = minimalIndexes(sargables, false)
+	}
+
+	hints := make(map[string]bool, len(node.Indexes()))
+	usedHint := false
+	for _, hint := range node.Indexes() {
+		hints[hint.Name()] = true
+		usedHint = usedHint || used[hint.Name()]
+	}
+
+	primary := false
+	considered := make([]interface{}, 0, len(indexes))
+	for _, index := range indexes {
+		primary = primary || index.IsPrimary()
+
+		c := map[string]interface{}{
+			"name":  index.Name(),
+			"using": string(index.Type()),
+		}
+
+		if !index.IsPrimary() {
+			c["keys"] = index.RangeKey().String()
+		}
+
+		if cond := index.Condition(); cond != nil {
+			c["condition"] = cond.String()
+		}
+
+		if used[index.Name()] {
+			c["status"] = "used"
+			c["covering"] = covering
+		} else {
+			c["status"] = "rejected"
+			c["reason"] = rejection(index, pred, entries, sargables, hints, usedHint, covering)
+		}
+
+		considered = append(considered, c)
+	}
+
+	advice["indexes"] = considered
+
+	// No advice for covered queries and constant predicates
+	if covering || (pred != nil && pred.Value() != nil) {
+		return scan, nil
+	}
+
+	recs := make([]interface{}, 0, 4)
+	recommend := func(kind string, keys expression.Expressions, cond expression.Expression) {
+		if existingIndex(entries, keys, cond) {
+			return
+		}
+
+		recs = append(recs, map[string]interface{}{
+			"kind":      kind,
+			"statement": adviceStatement(keyspace, alias, keys, cond),
+		})
+	}
+
+	if pred != nil {
+		akeys := adviceKeys(pred, alias, alias)
+		keys := sargedKeys(pred, adviceExprs(akeys, nil))
+		if len(keys) > 0 {
+			ckeys := this.coverKeys(alias, id, keys, nil)
+			if len(ckeys) == len(keys) {
+				recommend("covering", keys, nil)
+			} else {
+				recommend("index", keys, nil)
+				if ckeys != nil {
+					recommend("covering", ckeys, nil)
+				}
+			}
+		}
+
+		// Partial index on the remaining keys, with the equalities to constants as condition
+		var consts, constKeys expression.Expressions
+		for _, akey := range akeys {
+			if akey.constant {
+				consts = append(consts, akey.term)
+				constKeys = append(constKeys, akey.expr)
+			}
+		}
+
+		pkeys := sargedKeys(pred, adviceExprs(akeys, consts))
+		if len(consts) > 0 && len(pkeys) > 0 {
+			var cond expression.Expression
+			if len(consts) == 1 {
+				cond = consts[0]
+			} else {
+				cond = expression.NewAnd(consts...)
+			}
+
+			if SubsetOf(pred, cond) {
+				ckeys := this.coverKeys(alias, id, pkeys, constKeys)
+				if ckeys != nil {
+					pkeys = ckeys
+				}
+
+				recommend("partial", pkeys, cond)
+			}
+		}
+	}
+
+	if len(recs) == 0 && !primary {
+		recs = append(recs, map[string]interface{}{
+			"kind": "primary",
+			"statement": fmt.Sprintf("CREATE PRIMARY INDEX ON `%s`:`%s`",
+				keyspace.NamespaceId(), keyspace.Name()),
+		})
+	}
+
+	advice["recommendations"] = recs
+	return scan, nil
+}
+
+/*
+describeScan returns the name of a scan operator and whether it
+covers the query, and marks the indexes used by the scan.
+*/
+func describeScan(scan plan.Operator, used map[string]bool) (string, bool) {
+	switch scan := scan.(type) {
+	case *plan.KeyScan:
+		return "KeyScan", false
+	case *plan.ValueScan:
+		return "ValueScan", false
+	case *plan.CountScan:
+		return "CountScan", true
+	case *plan.PrimaryScan:
+		used[scan.Index().Name()] = true
+		return "PrimaryScan", false
+	case *plan.IndexScan:
+		used[scan.Index().Name()] = true
+		return "IndexScan", scan.Covering()
+	case *plan.IndexCountScan:
+		used[scan.Index().Name()] = true
+		return "IndexCountScan", scan.Covering()
+	case *plan.DistinctScan:
+		return describeScan(scan.Scan(), used)
+	case *plan.IntersectScan:
+		for _, s := range scan.Scans() {
+			describeScan(s, used)
+		}
+		return "IntersectScan", false
+	case *plan.UnionScan:
+		for _, s := range scan.Scans() {
+			describeScan(s, used)
+		}
+		return "UnionScan", false
+	default:
+		return "", false
+	}
+}
+
+/*
+rejection explains why the planner did not use an index.
+*/
+func rejection(index datastore.Index, pred expression.Expression,
+	entries, minimals map[datastore.Index]*indexEntry, hints map[string]bool,
+	usedHint, covering bool) string {
+	switch index.(type) {
+	case datastore.SearchIndex, datastore.SpatialIndex:
+		return "full-text and geospatial indexes only serve their own predicates"
+	}
+
+	if usedHint && !hints[index.Name()] {
+		return "not listed in USE INDEX"
+	}
+
+	if pred == nil {
+		if index.IsPrimary() && covering {
+			return "the query is answered without an index scan"
+		} else if index.IsPrimary() {
+			return "another primary index was chosen"
+		}
+
+		return "the query has no predicate on the keyspace"
+	}
+
+	entry, ok := entries[index]
+	if !ok {
+		return "the index condition is not implied by the query predicate"
+	}
+
+	if index.IsPrimary() {
+		return "the primary index is only used when no secondary index qualifies"
+	}
+
+	if len(entry.sargKeys) == 0 {
+		return fmt.Sprintf("the leading index key %s is not constrained by the query predicate",
+			index.RangeKey()[0].String())
+	}
+
+	if _, ok := minimals[index]; !ok {
+		return "another index constrains more index keys"
+	}
+
+	if covering {
+		return "another index covers the query"
+	}
+
+	return "another index was chosen"
+}
+
+/*
+adviceKeys returns the candidate index keys of a predicate on name,
+which is the keyspace alias or an ANY variable, ordered by class.
+*/
+func adviceKeys(pred expression.Expression, name, alias string) []*adviceKey {
+	terms := expression.Expressions{pred}
+	if and, ok := pred.(*expression.And); ok {
+		terms = and.Operands()
+	}
+
+	keys := make([]*adviceKey, 0, len(terms))
+	for _, term := range terms {
+		var key *adviceKey
+		switch term := term.(type) {
+		case *expression.Or:
+			key = orKey(term, name, alias)
+		case *expression.Any:
+			key = arrayKey(term, term.Bindings(), term.Satisfies(), alias)
+		case *expression.AnyEvery:
+			key = arrayKey(term, term.Bindings(), term.Satisfies(), alias)
+		default:
+			key = termKey(term, name, alias)
+		}
+
+		if key != nil {
+			keys = append(keys, key)
+		}
+	}
+
+	rv := make([]*adviceKey, 0, len(keys))
+	for class := _ADVISE_EQ; class <= _ADVISE_RANGE; class++ {
+		for _, key := range keys {
+			if key.class == class && !hasAdviceKey(rv, key.expr) {
+				rv = append(rv, key)
+			}
+		}
+	}
+
+	return rv
+}
+
+/*
+termKey returns the operand of a predicate term that depends on name,
+if it is the only such operand and the term is sargable for it.
+*/
+func termKey(term expression.Expression, name, alias string) *adviceKey {
+	var key expression.Expression
+	for _, child := range term.Children() {
+		if !references(child, name) && !references(child, alias) {
+			continue
+		}
+
+		if key != nil || !references(child, name) {
+			return nil
+		}
+
+		key = child
+	}
+
+	if key == nil || !key.Indexable() {
+		return nil
+	}
+
+	if ident, ok := key.(*expression.Identifier); ok && ident.Identifier() == alias {
+		return nil
+	}
+
+	if SargableFor(term, expression.Expressions{key}) == 0 {
+		return nil
+	}
+
+	rv := &adviceKey{expr: key, term: term, class: _ADVISE_RANGE}
+	switch term := term.(type) {
+	case *expression.Eq:
+		rv.class = _ADVISE_EQ
+		rv.constant = name == alias && (term.First().Value() != nil || term.Second().Value() != nil)
+	case *expression.In:
+		rv.class = _ADVISE_IN
+	}
+
+	return rv
+}
+
+/*
+orKey returns a key of the first disjunct of an OR that is sargable
+for the whole OR.
+*/
+func orKey(or *expression.Or, name, alias string) *adviceKey {
+	for _, key := range adviceKeys(or.Operands()[0], name, alias) {
+		if key.class != _ADVISE_ARRAY &&
+			SargableFor(or, expression.Expressions{key.expr}) > 0 {
+			return &adviceKey{expr: key.expr, term: or, class: _ADVISE_RANGE}
+		}
+	}
+
+	return nil
+}
+
+/*
+arrayKey returns the array index key for an ANY predicate over an
+array of the keyspace.
+*/
+func arrayKey(term expression.Expression, bindings expression.Bindings,
+	satisfies expression.Expression, alias string) *adviceKey {
+	if len(bindings) != 1 || bindings[0].Descend() ||
+		!references(bindings[0].Expression(), alias) {
+		return nil
+	}
+
+	for _, key := range adviceKeys(satisfies, bindings[0].Variable(), alias) {
+		if key.class == _ADVISE_ARRAY {
+			continue
+		}
+
+		all := expression.NewAll(expression.NewArray(key.expr, bindings, nil), true)
+		if SargableFor(term, expression.Expressions{all}) > 0 {
+			return &adviceKey{expr: all, term: term, class: _ADVISE_ARRAY}
+		}
+	}
+
+	return nil
+}
+
+/*
+adviceExprs returns the advised keys, without the keys of the
+excluded terms and with at most one array key.
+*/
+func adviceExprs(keys []*adviceKey, exclude expression.Expressions) expression.Expressions {
+	rv := make(expression.Expressions, 0, len(keys))
+	array := false
+
+outer:
+	for _, key := range keys {
+		for _, term := range exclude {
+			if term == key.term {
+				continue outer
+			}
+		}
+
+		if key.class == _ADVISE_ARRAY {
+			if array {
+				continue
+			}
+
+			array = true
+		}
+
+		rv = append(rv, key.expr)
+	}
+
+	return rv
+}
+
+/*
+sargedKeys returns the leading keys that are sargable for the
+predicate and produce index spans.
+*/
+func sargedKeys(pred expression.Expression, keys expression.Expressions) expression.Expressions {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	keys = keys[0:SargableFor(pred, keys)]
+	if len(keys) == 0 {
+		return nil
+	}
+
+	spans, _, err := SargFor(pred, keys, len(keys))
+	if err != nil || len(spans) == 0 {
+		return nil
+	}
+
+	return keys
+}
+
+/*
+coverKeys returns the keys extended with the keyspace paths needed to
+cover the query, or nil if the query cannot be covered.
+*/
+func (this *builder) coverKeys(alias string, id expression.Expression,
+	keys, covered expression.Expressions) expression.Expressions {
+	if this.cover == nil {
+		return nil
+	}
+
+	exprs := this.cover.Expressions()
+	rv := make(expression.Expressions, len(keys), len(keys)+len(exprs))
+	copy(rv, keys)
+
+	covered = append(covered.Copy(), id)
+	covered = append(covered, keys...)
+
+	for _, expr := range exprs {
+		paths, ok := keyspacePaths(expr, alias, id, nil)
+		if !ok {
+			return nil
+		}
+
+		for _, path := range paths {
+			if !path.CoveredBy(alias, covered) {
+				rv = append(rv, path)
+				covered = append(covered, path)
+			}
+		}
+	}
+
+	for _, expr := range exprs {
+		if !expr.CoveredBy(alias, covered) {
+			return nil
+		}
+	}
+
+	return rv
+}
+
+/*
+keyspacePaths appends the keyspace paths of an expression, and
+returns false if the expression uses the whole document or its
+metadata.
+*/
+func keyspacePaths(expr expression.Expression, alias string, id expression.Expression,
+	paths expression.Expressions) (expression.Expressions, bool) {
+	if expr.EquivalentTo(id) {
+		return paths, true
+	}
+
+	switch expr := expr.(type) {
+	case *expression.Identifier:
+		return paths, expr.Identifier() != alias
+	case *expression.Field:
+		if keyspacePath(expr, alias) {
+			if !hasExpression(paths, expr) {
+				paths = append(paths, expr)
+			}
+
+			return paths, true
+		}
+	}
+
+	ok := true
+	for _, child := range expr.Children() {
+		paths, ok = keyspacePaths(child, alias, id, paths)
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return paths, true
+}
+
+func keyspacePath(expr expression.Expression, alias string) bool {
+	switch expr := expr.(type) {
+	case *expression.Field:
+		return keyspacePath(expr.First(), alias)
+	case *expression.Identifier:
+		return expr.Identifier() == alias
+	default:
+		return false
+	}
+}
+
+/*
+references returns true if the expression refers to the identifier.
+*/
+func references(expr expression.Expression, name string) bool {
+	if ident, ok := expr.(*expression.Identifier); ok {
+		return ident.Identifier() == name
+	}
+
+	for _, child := range expr.Children() {
+		if references(child, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasAdviceKey(keys []*adviceKey, expr expression.Expression) bool {
+	for _, key := range keys {
+		if equivalentKey(key.expr, expr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasExpression(exprs expression.Expressions, expr expression.Expression) bool {
+	for _, e := range exprs {
+		if e.EquivalentTo(expr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+existingIndex returns true if an index already leads with the keys
+and has the same condition.
+*/
+func existingIndex(entries map[datastore.Index]*indexEntry, keys expression.Expressions,
+	cond expression.Expression) bool {
+outer:
+	for index, entry := range entries {
+		if index.IsPrimary() || len(entry.keys) < len(keys) {
+			continue
+		}
+
+		if (cond == nil) != (entry.cond == nil) ||
+			(cond != nil && !cond.EquivalentTo(entry.cond)) {
+			continue
+		}
+
+		for i, key := range keys {
+			if !equivalentKey(key, entry.keys[i]) {
+				continue outer
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+/*
+equivalentKey compares index keys, including array index keys, which
+are otherwise only equivalent to their arrays.
+*/
+func equivalentKey(key1, key2 expression.Expression) bool {
+	all1, ok1 := key1.(*expression.All)
+	all2, ok2 := key2.(*expression.All)
+	if ok1 || ok2 {
+		return ok1 && ok2 && all1.Distinct() == all2.Distinct() &&
+			all1.Array().EquivalentTo(all2.Array())
+	}
+
+	return key1.EquivalentTo(key2)
+}
+
+/*
+adviceStatement returns the CREATE INDEX statement for the keys and
+condition, which are qualified by the keyspace alias.
+*/
+func adviceStatement(keyspace datastore.Keyspace, alias string,
+	keys expression.Expressions, cond expression.Expression) string {
+	unformalizer := newUnformalizer(alias)
+	names := make([]string, 0, len(keys)+1)
+	texts := make([]string, len(keys))
+
+	for i, key := range keys {
+		names = append(names, keyName(key))
+		key, _ = unformalizer.Map(key.Copy())
+		texts[i] = key.String()
+	}
+
+	where := ""
+	if cond != nil {
+		names = append(names, "partial")
+		cond, _ = unformalizer.Map(cond.Copy())
+		where = " WHERE " + cond.String()
+	}
+
+	return fmt.Sprintf("CREATE INDEX `adv_%s` ON `%s`:`%s`(%s)%s", strings.Join(names, "_"),
+		keyspace.NamespaceId(), keyspace.Name(), strings.Join(texts, ", "), where)
+}
+
+/*
+keyName returns a name for an index key, for naming advised indexes.
+*/
+func keyName(key expression.Expression) string {
+	switch key := key.(type) {
+	case *expression.Identifier:
+		return key.Identifier()
+	case *expression.Field:
+		return key.Second().Alias()
+	case *expression.All:
+		if array, ok := key.Array().(*expression.Array); ok {
+			return keyName(array.Bindings()[0].Expression()) + "_" + keyName(array.ValueMapping())
+		}
+	case expression.Function:
+		if len(key.Operands()) > 0 {
+			return strings.ToLower(key.Name()) + "_" + keyName(key.Operands()[0])
+		}
+
+		return strings.ToLower(key.Name())
+	}
+
+	return "expr"
+}
+
+/*
+unformalizer removes the keyspace alias from formalized expressions,
+as in the keys of CREATE INDEX.
+*/
+type unformalizer struct {
+	expression.MapperBase
+
+	alias string
+}
+
+func newUnformalizer(alias string) *unformalizer {
+	rv := &unformalizer{
+		alias: alias,
+	}
+
+	rv.SetMapper(rv)
+	return rv
+}
+
+func (this *unformalizer) VisitField(expr *expression.Field) (interface{}, error) {
+	ident, ok := expr.First().(*expression.Identifier)
+	if ok && ident.Identifier() == this.alias {
+		rv := expression.NewIdentifier(expr.Second().Alias())
+		rv.SetCaseInsensitive(expr.CaseInsensitive())
+		return rv, nil
+	}
+
+	return expr, expr.MapChildren(this)
+}
+
+func (this *unformalizer) VisitFunction(expr expression.Function) (interface{}, error) {
+	if meta, ok := expr.(*expression.Meta); ok && len(meta.Operands()) == 1 {
+		ident, ok := meta.Operands()[0].(*expression.Identifier)
+		if ok && ident.Identifier() == this.alias {
+			return expression.NewMeta(), nil
+		}
+	}
+
+	return expr, expr.MapChildren(this)
+}
@@ -20,29 +20,54 @@ import (
 
 func Build(stmt algebra.Statement, datastore, systemstore datastore.Datastore,
 	namespace string, subquery bool) (plan.Operator, error) {
+	op, _, err := build(stmt, datastore, systemstore, namespace, subquery)
+	return op, err
+}
+
+/*
+build also returns the plans of the subqueries of a statement, unless
+the statement is itself a subquery.
+*/
+func build(stmt algebra.Statement, datastore, systemstore datastore.Datastore,
+	namespace string, subquery bool) (plan.Operator, plan.SubqueryPlans, error) {
 	builder := newBuilder(datastore, systemstore, namespace, subquery)
 	o, err := stmt.Accept(builder)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	op := o.(plan.Operator)
 	_, is_prepared := o.(*plan.Prepared)
 
 	if !subquery && !is_prepared {
+		var subqueries plan.SubqueryPlans
+		if plansSubqueries(stmt) {
+			subqueries = builder.buildSubqueries(stmt.Expressions())
+		}
+
+		// The hints of the subqueries are reported with the statement
+		if optimHints := builder.optimHintStatuses(); len(optimHints) > 0 {
+			op = plan.NewOptimHints(optimHints, op)
+		}
+
 		privs, er := stmt.Privileges()
 		if er != nil {
-			return nil, er
+			return nil, nil, er
+		}
+
+		privs, err = builder.viewPrivileges(privs)
+		if err != nil {
+			return nil, nil, err
 		}
 
 		if len(privs) > 0 {
 			op = plan.NewAuthorize(privs, op)
 		}
 
-		return plan.NewSequence(op, plan.NewStream()), nil
+		return plan.NewSequence(op, plan.NewStream()), subqueries, nil
 	} else {
-		return op, nil
+		return op, nil, nil
 	}
 }
 
@@ -66,6 +91,11 @@ type builder struct {
 	cover           expression.HasExpressions
 	coveringScan    *plan.IndexScan
 	countScan       *plan.IndexCountScan
+	views           []string         // Named views being expanded, to detect cycles
+	advisor         *advisor         // Used by ADVISE to collect index advice
+	optimHints      *optimHints      // Hints of the SELECT, UPDATE, DELETE or MERGE being planned
+	optimHintBlocks *optimHintBlocks // Shared with the builders of subqueries
+	subqueries      *subqueries      // Shared with the builders of subqueries
 }
 
 func newBuilder(datastore, systemstore datastore.Datastore, namespace string, subquery bool) *builder {
@@ -75,6 +105,8 @@ func newBuilder(datastore, systemstore datastore.Datastore, namespace string, su
 		namespace:       namespace,
 		subquery:        subquery,
 		delayProjection: false,
+		optimHintBlocks: newOptimHintBlocks(),
+		subqueries:      newSubqueries(),
 	}
 }
 
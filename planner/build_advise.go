@@ -0,0 +1,105 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Advise plans a SELECT, UPDATE or DELETE statement and returns the
+index advice for its keyspace terms.
+*/
+func Advise(stmt algebra.Statement, datastore, systemstore datastore.Datastore,
+	namespace string) (value.Value, error) {
+	builder := newBuilder(datastore, systemstore, namespace, false)
+	return builder.advise(stmt)
+}
+
+func (this *builder) advise(stmt algebra.Statement) (value.Value, error) {
+	switch stmt.(type) {
+	case *algebra.Select, *algebra.Update, *algebra.Delete:
+	default:
+		return nil, errors.NewAdviseError("Only SELECT, UPDATE and DELETE statements can be advised.")
+	}
+
+	// The hints of the advised statement are not reported
+	prevAdvisor := this.advisor
+	prevOptimHintBlocks := this.optimHintBlocks
+	defer func() {
+		this.advisor = prevAdvisor
+		this.optimHintBlocks = prevOptimHintBlocks
+	}()
+
+	this.advisor = &advisor{}
+	this.optimHintBlocks = newOptimHintBlocks()
+	_, err := stmt.Accept(this)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.NewValue(this.advisor.terms), nil
+}
+
+/*
+VisitAdvise returns the index advice for the statement, or scans the
+completed requests for the AdviseRequests operator.
+*/
+func (this *builder) VisitAdvise(stmt *algebra.Advise) (interface{}, error) {
+	if stmt.Statement() != nil {
+		advice, err := this.advise(stmt.Statement())
+		if err != nil {
+			return nil, err
+		}
+
+		return plan.NewAdvise(advice), nil
+	}
+
+	ksref := stmt.Keyspace()
+	ksref.SetDefaultNamespace(this.namespace)
+	if strings.ToLower(ksref.Namespace()) != "#system" ||
+		strings.ToLower(ksref.Keyspace()) != "completed_requests" {
+		return nil, errors.NewAdviseError(fmt.Sprintf(
+			"Only system:completed_requests can be advised, not %s:%s.",
+			ksref.Namespace(), ksref.Keyspace()))
+	}
+
+	alias := ksref.Keyspace()
+	projection := algebra.NewProjection(false, algebra.ResultTerms{
+		algebra.NewResultTerm(expression.NewField(expression.NewIdentifier(alias),
+			expression.NewFieldName("Statement", false)), false, "statement"),
+		algebra.NewResultTerm(expression.NewField(expression.NewIdentifier(alias),
+			expression.NewFieldName("PreparedText", false)), false, "prepared_text"),
+		algebra.NewResultTerm(expression.NewField(expression.NewIdentifier(alias),
+			expression.NewFieldName("ElapsedTime", false)), false, "elapsed_time"),
+	})
+
+	term := algebra.NewKeyspaceTerm(ksref.Namespace(), ksref.Keyspace(), alias, nil, nil)
+	query := algebra.NewSelect(algebra.NewSubselect(term, nil, nil, nil, projection), nil, nil, nil)
+	err := query.Formalize()
+	if err != nil {
+		return nil, err
+	}
+
+	scan, err := query.Accept(this)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewSequence(scan.(plan.Operator), plan.NewAdviseRequests()), nil
+}
@@ -0,0 +1,52 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+)
+
+/*
+BuildBaseline plans a DML statement, whose plan is to be pinned by a
+plan baseline.
+*/
+func BuildBaseline(stmt algebra.Statement, datastore, systemstore datastore.Datastore,
+	namespace, text string) (*plan.Prepared, error) {
+	switch stmt.(type) {
+	case *algebra.Select, *algebra.Insert, *algebra.Upsert, *algebra.Update, *algebra.Delete, *algebra.Merge:
+	default:
+		return nil, errors.NewPlanBaselineError("Only SELECT, INSERT, UPSERT, UPDATE, DELETE and MERGE " +
+			"statements can have plan baselines.")
+	}
+
+	prepared, err := BuildPrepared(stmt, datastore, systemstore, namespace, false)
+	if err != nil {
+		return nil, err
+	}
+
+	prepared.SetText(text)
+	return prepared, nil
+}
+
+func (this *builder) VisitCreatePlanBaseline(stmt *algebra.CreatePlanBaseline) (interface{}, error) {
+	prepared, err := BuildBaseline(stmt.Statement(), this.datastore, this.systemstore, this.namespace, stmt.Text())
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewCreatePlanBaseline(this.namespace, stmt.Text(), prepared), nil
+}
+
+func (this *builder) VisitDropPlanBaseline(stmt *algebra.DropPlanBaseline) (interface{}, error) {
+	return plan.NewDropPlanBaseline(this.namespace, stmt.Text()), nil
+}
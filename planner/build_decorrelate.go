@@ -0,0 +1,397 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"strconv"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+/*
+decorrelate rewrites the subqueries of a subselect that are
+correlated by equality, so that each is evaluated once per query
+instead of once per item. EXISTS, NOT EXISTS and IN conjuncts of the
+WHERE clause become semi-joins and anti-joins, which are returned
+with the rest of the WHERE clause. Scalar aggregate subqueries in the
+WHERE clause, and in the projection if there is no grouping, become
+grouped joins, which bind the subquery results to variables.
+*/
+func (this *builder) decorrelate(node *algebra.Subselect, group *algebra.Group) (
+	where expression.Expression, groupJoins, semiJoins []plan.Operator, err error) {
+	where = node.Where()
+	bound := boundIdentifiers(node)
+
+	if where != nil {
+		terms := conjuncts(where)
+		rest := make(expression.Expressions, 0, len(terms))
+		for _, conjunct := range terms {
+			semiJoin := this.semiJoin(conjunct, bound)
+			if semiJoin != nil {
+				semiJoins = append(semiJoins, semiJoin)
+			} else {
+				rest = append(rest, conjunct)
+			}
+		}
+
+		if len(semiJoins) > 0 {
+			where = conjunction(rest)
+		}
+	}
+
+	mapper := newGroupJoinMapper(this, bound)
+	if where != nil {
+		where, err = mapper.Map(where)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	if group == nil {
+		err = node.Projection().MapExpressions(mapper)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return where, mapper.joins, semiJoins, nil
+}
+
+/*
+semiJoin returns the semi-join or anti-join that replaces an EXISTS,
+NOT EXISTS or IN condition on a subquery, or nil. NOT IN is not
+rewritten, since it is not false if the subquery returns NULL.
+*/
+func (this *builder) semiJoin(cond expression.Expression, bound map[string]bool) plan.Operator {
+	anti := false
+	if not, ok := cond.(*expression.Not); ok {
+		anti = true
+		cond = not.Operand()
+	}
+
+	var subquery *algebra.Subquery
+	var first expression.Expression
+	switch cond := cond.(type) {
+	case *expression.Exists:
+		subquery, _ = cond.Operand().(*algebra.Subquery)
+	case *expression.In:
+		if !anti {
+			subquery, _ = cond.Second().(*algebra.Subquery)
+			first = cond.First()
+		}
+	}
+
+	if subquery == nil {
+		return nil
+	}
+
+	query := subquery.Select()
+	c := correlate(query, bound)
+	if c == nil || !keepsRows(query) {
+		return nil
+	}
+
+	aggs, err := allAggregates(c.sub, nil)
+	if err != nil || len(aggs) > 0 {
+		return nil
+	}
+
+	innerKeys := c.inner
+	outerKeys := c.outer
+	if first != nil {
+		// The IN operand is matched to the raw projection
+		projection := c.sub.Projection()
+		if query.Limit() != nil || !projection.Raw() ||
+			!refersTo(first, bound, false) || !refersTo(projection.Terms()[0].Expression(), c.alias(), false) {
+			return nil
+		}
+
+		innerKeys = append(expression.Expressions{projection.Terms()[0].Expression()}, innerKeys...)
+		outerKeys = append(expression.Expressions{first}, outerKeys...)
+	} else if len(innerKeys) == 0 {
+		// Uncorrelated EXISTS is evaluated once already
+		return nil
+	}
+
+	projection := algebra.NewRawProjection(false, expression.NewArrayConstruct(innerKeys...), "")
+	sub := algebra.NewSubselect(c.term, nil, c.filter, nil, projection)
+	sub.SetOptimHints(c.sub.OptimHints())
+	inner, err := this.buildSubquery(algebra.NewSelect(sub, nil, nil, nil))
+	if err != nil {
+		return nil
+	}
+
+	this.subqueries.decorrelated[query] = true
+	return plan.NewSemiJoin(inner, outerKeys, anti)
+}
+
+/*
+groupJoin returns the grouped join that replaces a scalar aggregate
+subquery, such as (SELECT RAW COUNT(*) FROM ...), and the variable it
+binds, or nil.
+*/
+func (this *builder) groupJoin(subquery *algebra.Subquery, bound map[string]bool) (
+	plan.Operator, expression.Expression) {
+	query := subquery.Select()
+	if !query.IsCorrelated() {
+		return nil, nil
+	}
+
+	c := correlate(query, bound)
+	if c == nil || len(c.inner) == 0 || !keepsRows(query) {
+		return nil, nil
+	}
+
+	projection := c.sub.Projection()
+	if !projection.Raw() {
+		return nil, nil
+	}
+
+	agg, ok := projection.Terms()[0].Expression().(algebra.Aggregate)
+	if !ok || !refersTo(agg, c.alias(), true) {
+		return nil, nil
+	}
+
+	// Each row of the inner plan is [keys, aggregate]
+	keys := c.inner
+	pair := expression.NewArrayConstruct(expression.NewArrayConstruct(keys...), agg)
+	sub := algebra.NewSubselect(c.term, nil, c.filter, algebra.NewGroup(keys, nil, nil),
+		algebra.NewRawProjection(false, pair, ""))
+	sub.SetOptimHints(c.sub.OptimHints())
+	inner, err := this.buildSubquery(algebra.NewSelect(sub, nil, nil, nil))
+	if err != nil {
+		return nil, nil
+	}
+
+	this.subqueries.decorrelated[query] = true
+	this.subqueries.groupJoins++
+	as := "#subquery" + strconv.Itoa(this.subqueries.groupJoins)
+
+	// Without rows, the subquery returns the aggregate default
+	empty := value.NewValue([]interface{}{agg.Default()})
+	return plan.NewGroupJoin(inner, c.outer, as, empty), expression.NewIdentifier(as)
+}
+
+/*
+correlation is a subquery over a single keyspace, whose WHERE clause
+is split into equalities between inner and outer expressions, and a
+filter that refers to the keyspace only.
+*/
+type correlation struct {
+	sub    *algebra.Subselect
+	term   *algebra.KeyspaceTerm
+	inner  expression.Expressions
+	outer  expression.Expressions
+	filter expression.Expression
+}
+
+/*
+correlate returns the correlation of a subquery, or nil if the
+subquery refers to anything but its keyspace and the bound
+identifiers, or does not only filter the rows of its keyspace.
+*/
+func correlate(query *algebra.Select, bound map[string]bool) *correlation {
+	sub, ok := query.Subresult().(*algebra.Subselect)
+	if !ok || sub.Let() != nil || sub.Group() != nil || query.Offset() != nil {
+		return nil
+	}
+
+	term, ok := sub.From().(*algebra.KeyspaceTerm)
+	if !ok || term.Keys() != nil || bound[term.Alias()] {
+		return nil
+	}
+
+	rv := &correlation{
+		sub:  sub,
+		term: term,
+	}
+
+	if sub.Where() == nil {
+		return rv
+	}
+
+	terms := conjuncts(sub.Where())
+	alias := rv.alias()
+	filters := make(expression.Expressions, 0, len(terms))
+	for _, conjunct := range terms {
+		if refersTo(conjunct, alias, true) {
+			filters = append(filters, conjunct)
+			continue
+		}
+
+		eq, ok := conjunct.(*expression.Eq)
+		if !ok {
+			return nil
+		}
+
+		inner, outer := eq.First(), eq.Second()
+		if !refersTo(inner, alias, false) {
+			inner, outer = outer, inner
+		}
+
+		if !refersTo(inner, alias, false) || !refersTo(outer, bound, false) {
+			return nil
+		}
+
+		rv.inner = append(rv.inner, inner)
+		rv.outer = append(rv.outer, outer)
+	}
+
+	rv.filter = conjunction(filters)
+	return rv
+}
+
+func (this *correlation) alias() map[string]bool {
+	return map[string]bool{this.term.Alias(): true}
+}
+
+/*
+keepsRows is true unless the subquery has a LIMIT that is not a
+positive constant.
+*/
+func keepsRows(query *algebra.Select) bool {
+	if query.Limit() == nil {
+		return true
+	}
+
+	limit := query.Limit().Value()
+	if limit == nil || limit.Type() != value.NUMBER {
+		return false
+	}
+
+	return limit.Actual().(float64) >= 1
+}
+
+/*
+refersTo is true if an expression refers only to the identifiers, and
+to at least one of them unless constant is true. Expressions that
+contain subqueries refer to nothing.
+*/
+func refersTo(expr expression.Expression, identifiers map[string]bool, constant bool) bool {
+	ids := make(map[string]bool, 4)
+	if !collectIdentifiers(expr, ids) {
+		return false
+	}
+
+	for id, _ := range ids {
+		if !identifiers[id] {
+			return false
+		}
+	}
+
+	return constant || len(ids) > 0
+}
+
+func collectIdentifiers(expr expression.Expression, ids map[string]bool) bool {
+	switch expr := expr.(type) {
+	case nil:
+		return true
+	case *algebra.Subquery:
+		return false
+	case *expression.Identifier:
+		ids[expr.Identifier()] = true
+	}
+
+	for _, child := range expr.Children() {
+		if !collectIdentifiers(child, ids) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+boundIdentifiers returns the keyspace aliases and LET variables that
+are bound in each item of a subselect, before filtering.
+*/
+func boundIdentifiers(node *algebra.Subselect) map[string]bool {
+	bound := make(map[string]bool, 4)
+	term := node.From()
+	for term != nil {
+		bound[term.Alias()] = true
+		join, ok := term.(interface {
+			Left() algebra.FromTerm
+		})
+		if !ok {
+			break
+		}
+		term = join.Left()
+	}
+
+	for _, binding := range node.Let() {
+		bound[binding.Variable()] = true
+	}
+
+	return bound
+}
+
+/*
+conjuncts returns the terms of a condition, flattening nested ANDs.
+*/
+func conjuncts(cond expression.Expression) expression.Expressions {
+	and, ok := cond.(*expression.And)
+	if !ok {
+		return expression.Expressions{cond}
+	}
+
+	and, _ = flattenAnd(and)
+	return and.Operands()
+}
+
+func conjunction(exprs expression.Expressions) expression.Expression {
+	switch len(exprs) {
+	case 0:
+		return nil
+	case 1:
+		return exprs[0]
+	default:
+		return expression.NewAnd(exprs...)
+	}
+}
+
+/*
+groupJoinMapper replaces scalar aggregate subqueries by the variables
+of the grouped joins that evaluate them. Subqueries nested in other
+subqueries are left to the planning of the enclosing subqueries.
+*/
+type groupJoinMapper struct {
+	expression.MapperBase
+	builder *builder
+	bound   map[string]bool
+	joins   []plan.Operator
+}
+
+func newGroupJoinMapper(builder *builder, bound map[string]bool) *groupJoinMapper {
+	rv := &groupJoinMapper{
+		builder: builder,
+		bound:   bound,
+	}
+	rv.SetMapper(rv)
+	return rv
+}
+
+func (this *groupJoinMapper) VisitSubquery(expr expression.Subquery) (interface{}, error) {
+	subquery, ok := expr.(*algebra.Subquery)
+	if !ok {
+		return expr, nil
+	}
+
+	join, variable := this.builder.groupJoin(subquery, this.bound)
+	if join == nil {
+		return expr, nil
+	}
+
+	this.joins = append(this.joins, join)
+	return variable, nil
+}
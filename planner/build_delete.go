@@ -11,10 +11,12 @@ package planner
 
 import (
 	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/plan"
 )
 
 func (this *builder) VisitDelete(stmt *algebra.Delete) (interface{}, error) {
+	defer this.useOptimHints(stmt.OptimHints())()
 	this.cover = stmt
 	this.where = stmt.Where()
 
@@ -24,7 +26,15 @@ func (this *builder) VisitDelete(stmt *algebra.Delete) (interface{}, error) {
 		return nil, err
 	}
 
-	err = this.beginMutate(keyspace, ksref, stmt.Keys(), stmt.Indexes(), stmt.Limit(), stmt.Returning() != nil)
+	// Delete triggers need the deleted documents, which a covering
+	// scan does not return
+	triggers := hasTriggers(keyspace, datastore.TRIGGER_DELETE)
+	if triggers {
+		this.cover = nil
+	}
+
+	mustFetch := stmt.Returning() != nil || triggers
+	err = this.beginMutate(keyspace, ksref, stmt.Keys(), stmt.Indexes(), stmt.Limit(), mustFetch)
 	if err != nil {
 		return nil, err
 	}
@@ -41,10 +51,10 @@ func (this *builder) VisitDelete(stmt *algebra.Delete) (interface{}, error) {
 	if stmt.Limit() != nil {
 		seqChildren := make([]plan.Operator, 0, 3)
 		if len(subChildren) > 0 {
-			seqChildren = append(seqChildren, plan.NewParallel(plan.NewSequence(subChildren...), this.maxParallelism))
+			seqChildren = append(seqChildren, plan.NewParallel(plan.NewSequence(subChildren...), this.parallelism()))
 		}
 		seqChildren = append(seqChildren, plan.NewLimit(stmt.Limit()))
-		seqChildren = append(seqChildren, plan.NewParallel(plan.NewSequence(deleteSubChildren...), this.maxParallelism))
+		seqChildren = append(seqChildren, plan.NewParallel(plan.NewSequence(deleteSubChildren...), this.parallelism()))
 		this.children = append(this.children, plan.NewSequence(seqChildren...))
 	} else {
 		if len(subChildren) > 0 {
@@ -52,7 +62,7 @@ func (this *builder) VisitDelete(stmt *algebra.Delete) (interface{}, error) {
 		} else {
 			subChildren = deleteSubChildren
 		}
-		this.children = append(this.children, plan.NewParallel(plan.NewSequence(subChildren...), this.maxParallelism))
+		this.children = append(this.children, plan.NewParallel(plan.NewSequence(subChildren...), this.parallelism()))
 	}
 
 	if stmt.Returning() == nil {
@@ -20,5 +20,8 @@ func (this *builder) VisitExplain(stmt *algebra.Explain) (interface{}, error) {
 		return nil, err
 	}
 
-	return plan.NewExplain(op.(plan.Operator), stmt.Text()), nil
+	// The hints are echoed by EXPLAIN instead of reported as warnings
+	subqueries := this.buildSubqueries(stmt.Statement().Expressions())
+	optimHints := this.optimHintStatuses()
+	return plan.NewExplain(op.(plan.Operator), stmt.Text(), optimHints, stmt.Format(), subqueries), nil
 }
@@ -51,7 +51,7 @@ func (this *builder) VisitInsert(stmt *algebra.Insert) (interface{}, error) {
 		subChildren = append(subChildren, plan.NewDiscard())
 	}
 
-	parallel := plan.NewParallel(plan.NewSequence(subChildren...), this.maxParallelism)
+	parallel := plan.NewParallel(plan.NewSequence(subChildren...), this.parallelism())
 	children = append(children, parallel)
 	return plan.NewSequence(children...), nil
 }
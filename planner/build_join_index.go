@@ -86,7 +86,7 @@ func (this *builder) buildJoinScan(keyspace datastore.Keyspace, node *algebra.Ke
 
 func (this *builder) buildCoveringJoinScan(secondaries map[datastore.Index]*indexEntry,
 	node *algebra.KeyspaceTerm, op string) (datastore.Index, expression.Covers, error) {
-	if this.cover != nil {
+	if this.cover != nil && !this.noCover(node.Alias()) {
 		alias := node.Alias()
 		exprs := this.cover.Expressions()
 
@@ -13,10 +13,13 @@ import (
 	"fmt"
 
 	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/plan"
 )
 
 func (this *builder) VisitMerge(stmt *algebra.Merge) (interface{}, error) {
+	defer this.useOptimHints(stmt.OptimHints())()
 	children := make([]plan.Operator, 0, 8)
 	subChildren := make([]plan.Operator, 0, 8)
 	source := stmt.Source()
@@ -101,18 +104,39 @@ func (this *builder) VisitMerge(stmt *algebra.Merge) (interface{}, error) {
 			ops = append(ops, plan.NewFilter(act.Where()))
 		}
 
-		ops = append(ops, plan.NewSendInsert(keyspace, ksref.Alias(), stmt.Key(), act.Value(), stmt.Limit()))
+		key := stmt.Key()
+		if act.Key() != nil {
+			key = act.Key()
+		}
+
+		ops = append(ops, plan.NewSendInsert(keyspace, ksref.Alias(), key, act.Value(), stmt.Limit()))
 		insert = plan.NewSequence(ops...)
 	}
 
-	merge := plan.NewMerge(keyspace, ksref, stmt.Key(), update, delete, insert)
+	var merge *plan.Merge
+	if stmt.Key() != nil {
+		merge = plan.NewMerge(keyspace, ksref, stmt.Key(), update, delete, insert)
+	} else {
+		merge, err = this.buildMergeOn(stmt, keyspace, update, delete, insert)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	subChildren = append(subChildren, merge)
 
 	if stmt.Returning() != nil {
 		subChildren = append(subChildren, plan.NewInitialProject(stmt.Returning()), plan.NewFinalProject())
 	}
 
-	parallel := plan.NewParallel(plan.NewSequence(subChildren...), this.maxParallelism)
+	// A MERGE ON that updates or deletes checks every match before any
+	// mutation, which the copies of a parallel merge cannot do
+	parallelism := this.parallelism()
+	if stmt.Key() == nil && (update != nil || delete != nil) {
+		parallelism = 1
+	}
+
+	parallel := plan.NewParallel(plan.NewSequence(subChildren...), parallelism)
 	children = append(children, parallel)
 
 	if stmt.Limit() != nil {
@@ -125,3 +149,55 @@ func (this *builder) VisitMerge(stmt *algebra.Merge) (interface{}, error) {
 
 	return plan.NewSequence(children...), nil
 }
+
+/*
+buildMergeOn plans a MERGE with an ON condition as a join of the
+source items to the target documents. The equalities between target
+and source expressions become the join keys, and the conditions on
+the target alone filter the inner plan, which may use an index. The
+rest of the ON condition is evaluated for each pair of items.
+*/
+func (this *builder) buildMergeOn(stmt *algebra.Merge, keyspace datastore.Keyspace,
+	update, delete, insert plan.Operator) (*plan.Merge, error) {
+	ksref := stmt.KeyspaceRef()
+	target := map[string]bool{ksref.Alias(): true}
+	source := map[string]bool{stmt.Source().Alias(): true}
+
+	var innerKeys, outerKeys, filters, rest expression.Expressions
+	for _, conjunct := range conjuncts(stmt.On()) {
+		if refersTo(conjunct, target, true) {
+			filters = append(filters, conjunct)
+			continue
+		}
+
+		if eq, ok := conjunct.(*expression.Eq); ok {
+			inner, outer := eq.First(), eq.Second()
+			if !refersTo(inner, target, false) {
+				inner, outer = outer, inner
+			}
+
+			if refersTo(inner, target, false) && refersTo(outer, source, false) {
+				innerKeys = append(innerKeys, inner)
+				outerKeys = append(outerKeys, outer)
+				continue
+			}
+		}
+
+		rest = append(rest, conjunct)
+	}
+
+	// Each row of the inner plan is [keys, id, document]
+	alias := expression.NewIdentifier(ksref.Alias())
+	id := expression.NewField(expression.NewMeta(alias), expression.NewFieldName("id", false))
+	row := expression.NewArrayConstruct(expression.NewArrayConstruct(innerKeys...), id, alias)
+	term := algebra.NewKeyspaceTerm(ksref.Namespace(), ksref.Keyspace(), ksref.Alias(), nil, nil)
+	sub := algebra.NewSubselect(term, nil, conjunction(filters), nil, algebra.NewRawProjection(false, row, ""))
+	sub.SetOptimHints(stmt.OptimHints())
+	inner, err := this.buildSubquery(algebra.NewSelect(sub, nil, nil, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewMergeOn(keyspace, ksref, inner, outerKeys, conjunction(rest),
+		update, delete, insert), nil
+}
@@ -0,0 +1,352 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/plan"
+)
+
+func (this *builder) VisitCreateView(stmt *algebra.CreateView) (interface{}, error) {
+	ksref := stmt.Keyspace()
+	namespace, err := this.getNamedViewNamespace(ksref)
+	if err != nil {
+		return nil, err
+	}
+
+	name := ksref.Keyspace()
+	_, er := namespace.KeyspaceByName(name)
+	view, _ := namespace.NamedViewByName(name)
+	if er == nil || view != nil {
+		return nil, errors.NewNamedViewAlreadyExistsError(namespace.Name() + ":" + name)
+	}
+
+	// Check that the view query can be planned; it is expanded in
+	// the namespace of the view
+	_, err = Build(stmt.Query(), this.datastore, this.systemstore, namespace.Name(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewCreateView(namespace, &datastore.NamedView{
+		Name:      name,
+		Statement: stmt.Text(),
+	}), nil
+}
+
+func (this *builder) VisitDropView(stmt *algebra.DropView) (interface{}, error) {
+	ksref := stmt.Keyspace()
+	namespace, err := this.getNamedViewNamespace(ksref)
+	if err != nil {
+		return nil, err
+	}
+
+	view, err := namespace.NamedViewByName(ksref.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	if view == nil {
+		return nil, errors.NewNamedViewNotFoundError(namespace.Name() + ":" + ksref.Keyspace())
+	}
+
+	return plan.NewDropView(namespace, view.Name), nil
+}
+
+func (this *builder) getNamedViewNamespace(ksref *algebra.KeyspaceRef) (datastore.NamedViewNamespace, error) {
+	ns := ksref.Namespace()
+	if ns == "" {
+		ns = this.namespace
+	}
+
+	namespace, err := this.datastore.NamespaceByName(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	vns, ok := namespace.(datastore.NamedViewNamespace)
+	if !ok {
+		return nil, errors.NewNamedViewsNotSupportedError(ns)
+	}
+
+	return vns, nil
+}
+
+/*
+getNamedView returns the named view ns:name, or nil if there is no
+such view. Keyspaces take precedence over views.
+*/
+func (this *builder) getNamedView(ns, name string) *datastore.NamedView {
+	if ns == "" {
+		ns = this.namespace
+	}
+
+	if strings.ToLower(ns) == "#system" {
+		return nil
+	}
+
+	namespace, err := this.datastore.NamespaceByName(ns)
+	if err != nil {
+		return nil
+	}
+
+	vns, ok := namespace.(datastore.NamedViewNamespace)
+	if !ok {
+		return nil
+	}
+
+	if _, err = namespace.KeyspaceByName(name); err == nil {
+		return nil
+	}
+
+	view, _ := vns.NamedViewByName(name)
+	return view
+}
+
+func parseNamedView(view *datastore.NamedView, name string) (*algebra.Select, error) {
+	stmt, err := n1ql.ParseStatement(view.Statement)
+	if err != nil {
+		return nil, errors.NewNamedViewError(err, name)
+	}
+
+	query, ok := stmt.(*algebra.Select)
+	if !ok {
+		return nil, errors.NewNamedViewError(fmt.Errorf("The view is not a SELECT."), name)
+	}
+
+	return query, nil
+}
+
+/*
+visitNamedView plans a named view in FROM as a subquery term, with
+the predicates of the outer WHERE clause on the view pushed into the
+view query.
+*/
+func (this *builder) visitNamedView(node *algebra.KeyspaceTerm, view *datastore.NamedView) (interface{}, error) {
+	name := node.Namespace() + ":" + view.Name
+	if node.Keys() != nil {
+		return nil, errors.NewNamedViewError(fmt.Errorf("USE KEYS is not supported on views."), name)
+	}
+
+	for _, v := range this.views {
+		if v == name {
+			return nil, errors.NewNamedViewError(fmt.Errorf("The view refers to itself."), name)
+		}
+	}
+
+	query, err := parseNamedView(view, name)
+	if err != nil {
+		return nil, err
+	}
+
+	query = pushViewPredicates(query, node.Alias(), this.where)
+
+	// The view query is planned in the namespace of the view
+	prevNamespace := this.namespace
+	this.namespace = node.Namespace()
+	this.views = append(this.views, name)
+	defer func() {
+		this.namespace = prevNamespace
+		this.views = this.views[:len(this.views)-1]
+	}()
+
+	return this.VisitSubqueryTerm(algebra.NewSubqueryTerm(query, node.Alias()))
+}
+
+/*
+pushViewPredicates adds to the WHERE clause of a view query the
+conjuncts of the outer where that refer only to the view, as alias.
+References to the fields of the view are replaced by the projected
+expressions. Nothing is pushed into views that group, aggregate or
+limit their results, or that project *. The outer WHERE clause is
+still evaluated, so pushdown only reduces the rows of the view.
+*/
+func pushViewPredicates(query *algebra.Select, alias string, where expression.Expression) *algebra.Select {
+	sub, ok := query.Subresult().(*algebra.Subselect)
+	if !ok || where == nil || query.Limit() != nil || query.Offset() != nil || sub.Group() != nil {
+		return query
+	}
+
+	aggs, err := allAggregates(sub, nil)
+	if err != nil || len(aggs) > 0 {
+		return query
+	}
+
+	projection := sub.Projection()
+	mapper := newViewMapper(alias)
+	for _, term := range projection.Terms() {
+		if term.Star() {
+			return query
+		}
+
+		if projection.Raw() {
+			mapper.raw = term.Expression()
+		} else {
+			mapper.terms[term.Alias()] = term.Expression()
+		}
+	}
+
+	conjuncts := expression.Expressions{where}
+	if and, ok := where.(*expression.And); ok {
+		conjuncts = and.Operands()
+	}
+
+	var pushed expression.Expressions
+	for _, conjunct := range conjuncts {
+		if !conjunct.Indexable() {
+			continue
+		}
+
+		mapper.ok = true
+		expr, err := mapper.Map(conjunct.Copy())
+		if err == nil && mapper.ok {
+			pushed = append(pushed, expr)
+		}
+	}
+
+	if len(pushed) == 0 {
+		return query
+	}
+
+	if sub.Where() != nil {
+		pushed = append(expression.Expressions{sub.Where()}, pushed...)
+	}
+
+	var cond expression.Expression = expression.NewAnd(pushed...)
+	if len(pushed) == 1 {
+		cond = pushed[0]
+	}
+
+	hints := sub.OptimHints()
+	sub = algebra.NewSubselect(sub.From(), sub.Let(), cond, nil, projection)
+	sub.SetOptimHints(hints)
+	return algebra.NewSelect(sub, query.Order(), nil, nil)
+}
+
+/*
+viewMapper replaces the references to a view in an expression by the
+projection of the view. ok is set to false if the expression refers
+to anything else than the projected fields of the view.
+*/
+type viewMapper struct {
+	expression.MapperBase
+	alias string
+	terms map[string]expression.Expression
+	raw   expression.Expression
+	ok    bool
+}
+
+func newViewMapper(alias string) *viewMapper {
+	rv := &viewMapper{
+		alias: alias,
+		terms: make(map[string]expression.Expression),
+	}
+	rv.SetMapper(rv)
+	return rv
+}
+
+func (this *viewMapper) VisitField(expr *expression.Field) (interface{}, error) {
+	ident, ok := expr.First().(*expression.Identifier)
+	if !ok || ident.Identifier() != this.alias || this.raw != nil {
+		return expr, expr.MapChildren(this)
+	}
+
+	name, ok := expr.Second().(*expression.FieldName)
+	if !ok || name.CaseInsensitive() {
+		this.ok = false
+		return expr, nil
+	}
+
+	term, ok := this.terms[name.Alias()]
+	if !ok {
+		this.ok = false
+		return expr, nil
+	}
+
+	return term.Copy(), nil
+}
+
+func (this *viewMapper) VisitIdentifier(expr *expression.Identifier) (interface{}, error) {
+	if expr.Identifier() != this.alias || this.raw == nil {
+		this.ok = false
+		return expr, nil
+	}
+
+	return this.raw.Copy(), nil
+}
+
+func (this *viewMapper) VisitSubquery(expr expression.Subquery) (interface{}, error) {
+	this.ok = false
+	return expr, nil
+}
+
+/*
+viewPrivileges replaces the privileges on named views by those of the
+view queries, so that the users of a view are authorized on the
+keyspaces it reads.
+*/
+func (this *builder) viewPrivileges(privs datastore.Privileges) (datastore.Privileges, error) {
+	rv := datastore.NewPrivileges()
+	for name, priv := range privs {
+		ids := strings.SplitN(name, ":", 2)
+		var view *datastore.NamedView
+		if len(ids) == 2 && priv == datastore.PRIV_READ {
+			view = this.getNamedView(ids[0], ids[1])
+		}
+
+		if view == nil {
+			rv.Add(datastore.Privileges{name: priv})
+			continue
+		}
+
+		for _, v := range this.views {
+			if v == name {
+				return nil, errors.NewNamedViewError(fmt.Errorf("The view refers to itself."), name)
+			}
+		}
+
+		query, err := parseNamedView(view, name)
+		if err != nil {
+			return nil, err
+		}
+
+		qprivs, err := query.Privileges()
+		if err != nil {
+			return nil, err
+		}
+
+		// Keyspaces of the view query are in the namespace of the view
+		vprivs := datastore.NewPrivileges()
+		for qname, qpriv := range qprivs {
+			if strings.HasPrefix(qname, ":") {
+				qname = ids[0] + qname
+			}
+			vprivs[qname] = qpriv
+		}
+
+		this.views = append(this.views, name)
+		vprivs, err = this.viewPrivileges(vprivs)
+		this.views = this.views[:len(this.views)-1]
+		if err != nil {
+			return nil, err
+		}
+
+		rv.Add(vprivs)
+	}
+
+	return rv, nil
+}
@@ -12,16 +12,46 @@ package planner
 import (
 	"github.com/couchbase/query/algebra"
 	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/plan"
 )
 
 func BuildPrepared(stmt algebra.Statement, datastore, systemstore datastore.Datastore,
 	namespace string, subquery bool) (*plan.Prepared, error) {
-	operator, err := Build(stmt, datastore, systemstore, namespace, subquery)
+	// The signature and volatility precede planning, which may rewrite subqueries
+	signature := stmt.Signature()
+	volatile := volatileExpressions(stmt.Expressions())
+	operator, subqueries, err := build(stmt, datastore, systemstore, namespace, subquery)
 	if err != nil {
 		return nil, err
 	}
 
-	signature := stmt.Signature()
-	return plan.NewPrepared(operator, signature), nil
+	// EXECUTE runs the subquery plans of the executed statement
+	if executed, ok := operator.(*plan.Prepared); ok {
+		subqueries = executed.Subqueries()
+		volatile = executed.Volatile()
+	}
+
+	prepared := plan.NewPrepared(operator, signature)
+	prepared.SetSubqueries(subqueries)
+	prepared.SetVolatile(volatile)
+	return prepared, nil
+}
+
+/*
+Returns true if any of the expressions, including those of their
+subqueries, calls a volatile function.
+*/
+func volatileExpressions(exprs expression.Expressions) bool {
+	for _, expr := range exprs {
+		if fn, ok := expr.(expression.Function); ok && fn.Volatile() {
+			return true
+		}
+
+		if volatileExpressions(expr.Children()) {
+			return true
+		}
+	}
+
+	return false
 }
@@ -20,6 +20,26 @@ import (
 
 func (this *builder) selectScan(keyspace datastore.Keyspace, node *algebra.KeyspaceTerm,
 	limit expression.Expression) (op plan.Operator, err error) {
+	if this.advisor != nil {
+		defer func() {
+			op, err = this.adviseScan(keyspace, node, op, err)
+		}()
+	}
+
+	if this.optimHints != nil {
+		prevCover := this.cover
+		if this.noCover(node.Alias()) {
+			this.cover = nil
+		}
+
+		defer func() {
+			this.cover = prevCover
+			if err == nil {
+				this.scanOptimHints(node, op)
+			}
+		}()
+	}
+
 	keys := node.Keys()
 	if keys != nil {
 		this.resetOrderLimit()
@@ -51,11 +71,19 @@ func (this *builder) selectScan(keyspace datastore.Keyspace, node *algebra.Keysp
 
 func (this *builder) buildScan(keyspace datastore.Keyspace, node *algebra.KeyspaceTerm, limit expression.Expression) (
 	secondary plan.Operator, primary *plan.PrimaryScan, err error) {
+	indexRefs := node.Indexes()
+	if len(indexRefs) == 0 {
+		indexRefs, err = this.hintIndexRefs(keyspace, node)
+		if err != nil {
+			return
+		}
+	}
+
 	var hints []datastore.Index
-	if len(node.Indexes()) > 0 {
+	if len(indexRefs) > 0 {
 		hints = _HINT_POOL.Get()
 		defer _HINT_POOL.Put(hints)
-		hints, err = allHints(keyspace, node.Indexes(), hints)
+		hints, err = allHints(keyspace, indexRefs, hints)
 		if err != nil {
 			return
 		}
@@ -114,9 +142,19 @@ func (this *builder) buildPredicateScan(keyspace datastore.Keyspace, node *algeb
 		return
 	}
 
+	secondary, err = this.buildSearchScan(keyspace, node, pred, hints)
+	if secondary != nil || err != nil {
+		return
+	}
+
 	primaryKey := expression.Expressions{id}
 	formalizer := expression.NewSelfFormalizer(node.Alias(), nil)
 
+	secondary, err = this.buildSpatialScan(keyspace, node, pred, hints, formalizer)
+	if secondary != nil || err != nil {
+		return
+	}
+
 	if len(hints) > 0 {
 		secondary, primary, err = this.buildSubsetScan(
 			keyspace, node, id, pred, limit, hints, primaryKey, formalizer, true)
@@ -132,6 +170,8 @@ func (this *builder) buildPredicateScan(keyspace datastore.Keyspace, node *algeb
 		return
 	}
 
+	others = this.excludeHintIndexes(node, others)
+
 	return this.buildSubsetScan(keyspace, node, id, pred, limit, others, primaryKey, formalizer, false)
 }
 
@@ -144,7 +144,7 @@ outer:
 		this.maxParallelism = 1
 	}
 
-	scan := plan.NewIndexScan(index, node, entry.spans, false, limit, covers, filterCovers)
+	scan := this.buildIndexScan(index, node, entry, limit, covers, filterCovers, arrayIndex)
 	this.coveringScan = scan
 
 	if arrayIndex || (len(entry.spans) > 1 && (!entry.exactSpans || pred.MayOverlapSpans())) {
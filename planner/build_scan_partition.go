@@ -0,0 +1,197 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"runtime"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Minimum number of index entries, by the index statistics, in each
+partition of a scan.
+*/
+const _MIN_PARTITION_SIZE = 256
+
+/*
+buildIndexScan partitions the scan of a single span, if the index
+statistics allow it; the scan is ordered if the plan relies on index
+order. Scans with a LIMIT are not partitioned, since a single range
+usually satisfies them.
+*/
+func (this *builder) buildIndexScan(index datastore.Index, node *algebra.KeyspaceTerm,
+	entry *indexEntry, limit expression.Expression, covers expression.Covers,
+	filterCovers map[*expression.Cover]value.Value, arrayIndex bool) *plan.IndexScan {
+	if limit == nil && !arrayIndex && len(entry.spans) == 1 {
+		partitions := this.partitionSpan(index, entry.spans[0])
+		if partitions != nil {
+			return plan.NewPartitionedIndexScan(index, node, partitions,
+				covers, filterCovers, this.order != nil)
+		}
+	}
+
+	return plan.NewIndexScan(index, node, entry.spans, false, limit, covers, filterCovers)
+}
+
+/*
+scanPartitions returns the number of key ranges a scan may be
+partitioned into. It is bounded at run time by max-parallelism.
+*/
+func (this *builder) scanPartitions() int {
+	n := this.parallelHint()
+	if n > 0 {
+		return n
+	}
+
+	return runtime.NumCPU()
+}
+
+/*
+partitionSpan splits a span into disjoint, ascending key ranges, at
+values of the leading index key taken from the bins of the index
+statistics. It returns nil if the span is not a static range, or the
+index has no statistics, or too few entries to partition.
+*/
+func (this *builder) partitionSpan(index datastore.Index, span *plan.Span) plan.Spans {
+	n := this.scanPartitions()
+	if n <= 1 || len(span.Seek) > 0 {
+		return nil
+	}
+
+	low, ok := staticValues(span.Range.Low)
+	if !ok {
+		return nil
+	}
+
+	high, ok := staticValues(span.Range.High)
+	if !ok {
+		return nil
+	}
+
+	dspan := &datastore.Span{
+		Range: datastore.Range{
+			Low:       low,
+			High:      high,
+			Inclusion: span.Range.Inclusion,
+		},
+	}
+
+	stats, err := index.Statistics("", dspan)
+	if err != nil || stats == nil {
+		return nil
+	}
+
+	count, err := stats.Count()
+	if err != nil {
+		return nil
+	}
+
+	if int64(n) > count/_MIN_PARTITION_SIZE {
+		n = int(count / _MIN_PARTITION_SIZE)
+	}
+
+	bins, err := stats.Bins()
+	if err != nil || len(bins) < n {
+		n = len(bins)
+	}
+
+	if n <= 1 {
+		return nil
+	}
+
+	// Boundaries must be strictly ascending, and inside the span
+	var prev value.Value
+	if len(low) > 0 {
+		prev = low[0]
+	}
+
+	boundaries := make(value.Values, 0, n-1)
+	for j := 1; j < n; j++ {
+		min, err := bins[j*len(bins)/n].Min()
+		if err != nil || len(min) == 0 || min[0] == nil {
+			continue
+		}
+
+		b := min[0]
+		if prev != nil && b.Collate(prev) <= 0 {
+			continue
+		}
+
+		if len(high) > 0 && b.Collate(high[0]) >= 0 {
+			break
+		}
+
+		boundaries = append(boundaries, b)
+		prev = b
+	}
+
+	if len(boundaries) == 0 {
+		return nil
+	}
+
+	// Each boundary is the exclusive high of one range, and the
+	// inclusive low of the next
+	partitions := make(plan.Spans, 0, len(boundaries)+1)
+	rangeLow := expression.CopyExpressions(span.Range.Low)
+	inclusion := span.Range.Inclusion & datastore.LOW
+	for _, b := range boundaries {
+		bound := expression.Expressions{expression.NewConstant(b)}
+		partitions = append(partitions, &plan.Span{
+			Range: plan.Range{
+				Low:       rangeLow,
+				High:      bound,
+				Inclusion: inclusion,
+			},
+			Exact: span.Exact,
+		})
+
+		rangeLow = expression.CopyExpressions(bound)
+		inclusion = datastore.LOW
+	}
+
+	partitions = append(partitions, &plan.Span{
+		Range: plan.Range{
+			Low:       rangeLow,
+			High:      expression.CopyExpressions(span.Range.High),
+			Inclusion: inclusion | (span.Range.Inclusion & datastore.HIGH),
+		},
+		Exact: span.Exact,
+	})
+
+	return partitions
+}
+
+/*
+staticValues returns the values of span bounds that are constant.
+*/
+func staticValues(exprs expression.Expressions) (value.Values, bool) {
+	if exprs == nil {
+		return nil, true
+	}
+
+	values := make(value.Values, len(exprs))
+	for i, expr := range exprs {
+		if expr == nil {
+			return nil, false
+		}
+
+		values[i] = expr.Value()
+		if values[i] == nil {
+			return nil, false
+		}
+	}
+
+	return values, true
+}
@@ -26,6 +26,13 @@ func (this *builder) buildPrimaryScan(keyspace datastore.Keyspace, node *algebra
 		return nil, err
 	}
 
+	if limit == nil && keyspace.NamespaceId() != "#system" {
+		partitions := this.partitionSpan(primary, &plan.Span{})
+		if partitions != nil {
+			return plan.NewPartitionedPrimaryScan(primary, keyspace, node, partitions), nil
+		}
+	}
+
 	return plan.NewPrimaryScan(primary, keyspace, node, limit), nil
 }
 
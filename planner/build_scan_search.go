@@ -0,0 +1,132 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/search"
+)
+
+/*
+buildSearchScan uses a full-text index for a SEARCH() predicate on
+the keyspace, when the predicate must hold for every result. The
+predicate is still evaluated by the Filter that follows the Fetch.
+*/
+func (this *builder) buildSearchScan(keyspace datastore.Keyspace, node *algebra.KeyspaceTerm,
+	pred expression.Expression, hints []datastore.Index) (plan.Operator, error) {
+	s := searchPredicate(node.Alias(), pred)
+	if s == nil {
+		return nil, nil
+	}
+
+	var err error
+	indexes := hints
+	if len(indexes) == 0 {
+		indexes = _INDEX_POOL.Get()
+		defer _INDEX_POOL.Put(indexes)
+		indexes, err = allIndexes(keyspace, nil, indexes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	index := searchIndex(s, indexes)
+	if index == nil {
+		return nil, nil
+	}
+
+	// Entries are returned in order of relevance
+	this.resetOrderLimit()
+	this.resetCountMin()
+
+	spans := plan.Spans{&plan.Span{Seek: expression.Expressions{s.Query()}}}
+	return plan.NewIndexScan(index, node, spans, false, nil, nil, nil), nil
+}
+
+/*
+searchPredicate returns the SEARCH() term on alias that is either the
+whole predicate or one of its conjuncts.
+*/
+func searchPredicate(alias string, pred expression.Expression) *expression.Search {
+	terms := expression.Expressions{pred}
+	if and, ok := pred.(*expression.And); ok {
+		terms = and.Operands()
+	}
+
+	for _, term := range terms {
+		s, ok := term.(*expression.Search)
+		if !ok || s.Query().Static() == nil {
+			continue
+		}
+
+		ident, ok := s.Keyspace().(*expression.Identifier)
+		if ok && ident.Identifier() == alias {
+			return s
+		}
+	}
+
+	return nil
+}
+
+/*
+searchIndex returns the first full-text index that uses the analyzer
+of s, and covers the fields referenced by its query string.
+*/
+func searchIndex(s *expression.Search, indexes []datastore.Index) datastore.SearchIndex {
+	var fields []string
+	if qv := s.Query().Value(); qv != nil {
+		if text, ok := qv.Actual().(string); ok {
+			query, err := search.ParseQuery(text)
+			if err != nil {
+				return nil
+			}
+			fields = query.Fields()
+		}
+	}
+
+	name := s.IndexName()
+	analyzer := s.Analyzer()
+
+outer:
+	for _, index := range indexes {
+		si, ok := index.(datastore.SearchIndex)
+		if !ok || si.Analyzer() != analyzer || (name != "" && si.Name() != name) {
+			continue
+		}
+
+		// Without a constant query, only whole-document indexes qualify
+		if fields == nil && !wholeDocument(si) {
+			continue
+		}
+
+		for _, field := range fields {
+			if !search.CoversField(si.Fields(), field) {
+				continue outer
+			}
+		}
+
+		return si
+	}
+
+	return nil
+}
+
+func wholeDocument(index datastore.SearchIndex) bool {
+	for _, field := range index.Fields() {
+		if field == "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -89,7 +89,7 @@ func (this *builder) buildSecondaryScan(indexes map[datastore.Index]*indexEntry,
 			this.limit = nil
 		}
 
-		op = plan.NewIndexScan(index, node, entry.spans, false, limit, nil, nil)
+		op = this.buildIndexScan(index, node, entry, limit, nil, nil, arrayIndex)
 
 		if arrayIndex || (len(entry.spans) > 1 && (!entry.exactSpans || pred.MayOverlapSpans())) {
 			// Use DistinctScan to de-dup array index scans, multiple spans
@@ -114,6 +114,13 @@ func sargableIndexes(indexes []datastore.Index, pred, subset expression.Expressi
 	entries = make(map[datastore.Index]*indexEntry, len(indexes))
 
 	for _, index := range indexes {
+		// Full-text and geospatial indexes are only used by
+		// buildSearchScan and buildSpatialScan
+		switch index.(type) {
+		case datastore.SearchIndex, datastore.SpatialIndex:
+			continue
+		}
+
 		if index.IsPrimary() {
 			if primaryKey != nil {
 				keys = primaryKey
@@ -267,8 +274,11 @@ func (this *builder) useIndexOrder(entry *indexEntry, keys expression.Expression
 			return false
 		}
 
-		if isArray, _ := entry.keys[i].IsArrayIndexKey(); isArray {
-			return false
+		// Covering scans append the document key to the index keys
+		if i < len(entry.keys) {
+			if isArray, _ := entry.keys[i].IsArrayIndexKey(); isArray {
+				return false
+			}
 		}
 
 	loop:
@@ -0,0 +1,83 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+)
+
+/*
+buildSpatialScan uses a geospatial index for radius and bounding box
+predicates on its key. See sargGeo().
+*/
+func (this *builder) buildSpatialScan(keyspace datastore.Keyspace, node *algebra.KeyspaceTerm,
+	pred expression.Expression, hints []datastore.Index, formalizer *expression.Formalizer) (
+	plan.Operator, error) {
+	var err error
+	indexes := hints
+	if len(indexes) == 0 {
+		indexes = _INDEX_POOL.Get()
+		defer _INDEX_POOL.Put(indexes)
+		indexes, err = allIndexes(keyspace, nil, indexes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, index := range indexes {
+		if _, ok := index.(datastore.SpatialIndex); !ok || len(index.RangeKey()) != 1 {
+			continue
+		}
+
+		key, err := formalizer.Map(index.RangeKey()[0].Copy())
+		if err != nil {
+			return nil, err
+		}
+
+		cond := index.Condition()
+		if cond != nil {
+			cond, err = formalizer.Map(cond.Copy())
+			if err != nil {
+				return nil, err
+			}
+
+			dnf := NewDNF(cond)
+			cond, err = dnf.Map(cond)
+			if err != nil {
+				return nil, err
+			}
+
+			if !SubsetOf(pred, cond) {
+				continue
+			}
+		}
+
+		spans := sargGeo(pred, key)
+		if spans == nil {
+			continue
+		}
+
+		// Candidates are returned in key order
+		this.resetOrderLimit()
+		this.resetCountMin()
+
+		var scan plan.Operator = plan.NewIndexScan(index, node, spans, false, nil, nil, nil)
+		if len(spans) > 1 {
+			scan = plan.NewDistinctScan(scan)
+		}
+
+		return scan, nil
+	}
+
+	return nil, nil
+}
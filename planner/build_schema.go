@@ -0,0 +1,88 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/schema"
+)
+
+func (this *builder) VisitAlterKeyspace(stmt *algebra.AlterKeyspace) (interface{}, error) {
+	keyspace, err := this.getSchemaKeyspace(stmt.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	var sch *schema.Schema
+	if stmt.Schema() != nil {
+		sch, err = schema.Compile(stmt.Schema())
+		if err != nil {
+			return nil, errors.NewSchemaError(err, keyspace.Name())
+		}
+	}
+
+	return plan.NewAlterKeyspace(keyspace, sch), nil
+}
+
+/*
+VisitValidate scans the documents of the keyspace, projected as id
+and doc, for the Validate operator.
+*/
+func (this *builder) VisitValidate(stmt *algebra.Validate) (interface{}, error) {
+	ksref := stmt.Keyspace()
+	keyspace, err := this.getSchemaKeyspace(ksref)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyspace.Schema() == nil {
+		return nil, errors.NewSchemaNotFoundError(keyspace.NamespaceId() + ":" + keyspace.Name())
+	}
+
+	alias := keyspace.Name()
+	projection := algebra.NewProjection(false, algebra.ResultTerms{
+		algebra.NewResultTerm(expression.NewField(
+			expression.NewMeta(expression.NewIdentifier(alias)),
+			expression.NewFieldName("id", false)), false, "id"),
+		algebra.NewResultTerm(expression.NewIdentifier(alias), false, "doc"),
+	})
+
+	term := algebra.NewKeyspaceTerm(ksref.Namespace(), ksref.Keyspace(), alias, nil, nil)
+	query := algebra.NewSelect(algebra.NewSubselect(term, nil, nil, nil, projection), nil, nil, nil)
+	err = query.Formalize()
+	if err != nil {
+		return nil, err
+	}
+
+	scan, err := query.Accept(this)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewSequence(scan.(plan.Operator), plan.NewValidate(keyspace)), nil
+}
+
+func (this *builder) getSchemaKeyspace(ksref *algebra.KeyspaceRef) (datastore.SchemaKeyspace, error) {
+	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	sks, ok := keyspace.(datastore.SchemaKeyspace)
+	if !ok {
+		return nil, errors.NewSchemasNotSupportedError(keyspace.NamespaceId() + ":" + keyspace.Name())
+	}
+
+	return sks, nil
+}
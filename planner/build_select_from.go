@@ -54,7 +54,12 @@ func (this *builder) VisitKeyspaceTerm(node *algebra.KeyspaceTerm) (interface{},
 	node.SetDefaultNamespace(this.namespace)
 	keyspace, err := this.getTermKeyspace(node)
 	if err != nil {
-		return nil, err
+		view := this.getNamedView(node.Namespace(), node.Keyspace())
+		if view == nil {
+			return nil, err
+		}
+
+		return this.visitNamedView(node, view)
 	}
 
 	if this.subquery && this.correlated && node.Keys() == nil {
@@ -101,6 +106,7 @@ func (this *builder) VisitJoin(node *algebra.Join) (interface{}, error) {
 	}
 
 	right := node.Right()
+	this.joinOptimHints(right.Alias())
 	right.SetDefaultNamespace(this.namespace)
 	namespace, err := this.datastore.NamespaceByName(right.Namespace())
 	if err != nil {
@@ -114,7 +120,7 @@ func (this *builder) VisitJoin(node *algebra.Join) (interface{}, error) {
 
 	join := plan.NewJoin(keyspace, node)
 	if len(this.subChildren) > 0 {
-		parallel := plan.NewParallel(plan.NewSequence(this.subChildren...), this.maxParallelism)
+		parallel := plan.NewParallel(plan.NewSequence(this.subChildren...), this.parallelism())
 		this.children = append(this.children, parallel)
 		this.subChildren = make([]plan.Operator, 0, 16)
 	}
@@ -132,6 +138,7 @@ func (this *builder) VisitIndexJoin(node *algebra.IndexJoin) (interface{}, error
 	}
 
 	right := node.Right()
+	this.joinOptimHints(right.Alias())
 	right.SetDefaultNamespace(this.namespace)
 	namespace, err := this.datastore.NamespaceByName(right.Namespace())
 	if err != nil {
@@ -165,6 +172,7 @@ func (this *builder) VisitNest(node *algebra.Nest) (interface{}, error) {
 	}
 
 	right := node.Right()
+	this.joinOptimHints(right.Alias())
 	right.SetDefaultNamespace(this.namespace)
 	namespace, err := this.datastore.NamespaceByName(right.Namespace())
 	if err != nil {
@@ -177,7 +185,7 @@ func (this *builder) VisitNest(node *algebra.Nest) (interface{}, error) {
 	}
 
 	if len(this.subChildren) > 0 {
-		parallel := plan.NewParallel(plan.NewSequence(this.subChildren...), this.maxParallelism)
+		parallel := plan.NewParallel(plan.NewSequence(this.subChildren...), this.parallelism())
 		this.children = append(this.children, parallel)
 		this.subChildren = make([]plan.Operator, 0, 16)
 	}
@@ -199,6 +207,7 @@ func (this *builder) VisitIndexNest(node *algebra.IndexNest) (interface{}, error
 	}
 
 	right := node.Right()
+	this.joinOptimHints(right.Alias())
 	right.SetDefaultNamespace(this.namespace)
 	namespace, err := this.datastore.NamespaceByName(right.Namespace())
 	if err != nil {
@@ -230,7 +239,7 @@ func (this *builder) VisitUnnest(node *algebra.Unnest) (interface{}, error) {
 
 	unnest := plan.NewUnnest(node)
 	this.subChildren = append(this.subChildren, unnest)
-	parallel := plan.NewParallel(plan.NewSequence(this.subChildren...), this.maxParallelism)
+	parallel := plan.NewParallel(plan.NewSequence(this.subChildren...), this.parallelism())
 	this.children = append(this.children, parallel)
 	this.subChildren = make([]plan.Operator, 0, 16)
 	return nil, nil
@@ -251,6 +260,10 @@ func (this *builder) fastCount(node *algebra.Subselect) (bool, error) {
 	from.SetDefaultNamespace(this.namespace)
 	keyspace, err := this.getTermKeyspace(from)
 	if err != nil {
+		// Named views are expanded by VisitKeyspaceTerm
+		if this.getNamedView(from.Namespace(), from.Keyspace()) != nil {
+			return false, nil
+		}
 		return false, err
 	}
 
@@ -269,7 +282,14 @@ func (this *builder) fastCount(node *algebra.Subselect) (bool, error) {
 		}
 	}
 
-	scan := plan.NewCountScan(keyspace, from)
+	var scan plan.Operator = plan.NewCountScan(keyspace, from)
+	if this.advisor != nil {
+		scan, err = this.adviseScan(keyspace, from, scan, nil)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	this.children = append(this.children, scan)
 	return true, nil
 }
@@ -27,6 +27,7 @@ func (this *builder) VisitSubselect(node *algebra.Subselect) (interface{}, error
 	prevMinAgg := this.minAgg
 	prevCoveringScan := this.coveringScan
 	prevCountScan := this.countScan
+	restoreOptimHints := this.useOptimHints(node.OptimHints())
 
 	defer func() {
 		this.cover = prevCover
@@ -35,6 +36,7 @@ func (this *builder) VisitSubselect(node *algebra.Subselect) (interface{}, error
 		this.minAgg = prevMinAgg
 		this.coveringScan = prevCoveringScan
 		this.countScan = prevCountScan
+		restoreOptimHints()
 	}()
 
 	this.coveringScan = nil
@@ -144,14 +146,23 @@ func (this *builder) VisitSubselect(node *algebra.Subselect) (interface{}, error
 	}
 
 	if this.countScan == nil {
+		where, groupJoins, semiJoins, err := this.decorrelate(node, group)
+		if err != nil {
+			return nil, err
+		}
+
 		if node.Let() != nil {
 			this.subChildren = append(this.subChildren, plan.NewLet(node.Let()))
 		}
 
-		if node.Where() != nil {
-			this.subChildren = append(this.subChildren, plan.NewFilter(node.Where()))
+		this.subChildren = append(this.subChildren, groupJoins...)
+
+		if where != nil {
+			this.subChildren = append(this.subChildren, plan.NewFilter(where))
 		}
 
+		this.subChildren = append(this.subChildren, semiJoins...)
+
 		if group != nil {
 			this.visitGroup(group, aggs)
 		}
@@ -170,7 +181,7 @@ func (this *builder) VisitSubselect(node *algebra.Subselect) (interface{}, error
 		}
 
 		// Parallelize the subChildren
-		this.children = append(this.children, plan.NewParallel(plan.NewSequence(this.subChildren...), this.maxParallelism))
+		this.children = append(this.children, plan.NewParallel(plan.NewSequence(this.subChildren...), this.parallelism()))
 
 		// Final DISTINCT (serial)
 		if projection.Distinct() || this.distinct {
@@ -197,7 +208,7 @@ func (this *builder) visitGroup(group *algebra.Group, aggs map[string]algebra.Ag
 	}
 
 	this.subChildren = append(this.subChildren, plan.NewInitialGroup(group.By(), aggv))
-	this.children = append(this.children, plan.NewParallel(plan.NewSequence(this.subChildren...), this.maxParallelism))
+	this.children = append(this.children, plan.NewParallel(plan.NewSequence(this.subChildren...), this.parallelism()))
 	this.children = append(this.children, plan.NewIntermediateGroup(group.By(), aggv))
 	this.children = append(this.children, plan.NewFinalGroup(group.By(), aggv))
 	this.subChildren = make([]plan.Operator, 0, 8)
@@ -0,0 +1,59 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+)
+
+func (this *builder) VisitCreateSequence(stmt *algebra.CreateSequence) (interface{}, error) {
+	namespace, err := this.getSequenceNamespace(stmt.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	sequence, err := stmt.Sequence()
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewCreateSequence(namespace, sequence), nil
+}
+
+func (this *builder) VisitDropSequence(stmt *algebra.DropSequence) (interface{}, error) {
+	namespace, err := this.getSequenceNamespace(stmt.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewDropSequence(namespace, stmt.Keyspace().Keyspace()), nil
+}
+
+func (this *builder) getSequenceNamespace(ksref *algebra.KeyspaceRef) (datastore.SequenceNamespace, error) {
+	ns := ksref.Namespace()
+	if ns == "" {
+		ns = this.namespace
+	}
+
+	namespace, err := this.datastore.NamespaceByName(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	sns, ok := namespace.(datastore.SequenceNamespace)
+	if !ok {
+		return nil, errors.NewSequencesNotSupportedError(ns)
+	}
+
+	return sns, nil
+}
@@ -0,0 +1,110 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+)
+
+/*
+subqueries collects the plans of the subqueries of a statement. It is
+shared by the builders of the statement and of its subqueries.
+*/
+type subqueries struct {
+	plans        plan.SubqueryPlans
+	planned      map[*algebra.Select]bool
+	decorrelated map[*algebra.Select]bool // Rewritten into joins, and never evaluated
+	groupJoins   int                      // Used to name the variables of grouped joins
+}
+
+func newSubqueries() *subqueries {
+	return &subqueries{
+		planned:      make(map[*algebra.Select]bool),
+		decorrelated: make(map[*algebra.Select]bool),
+	}
+}
+
+/*
+buildSubqueries plans the subqueries of the expressions, and the
+subqueries nested in them, and returns all the subquery plans of the
+statement so far. A subquery that fails to plan is left to be planned
+when it is evaluated, which reports the error.
+*/
+func (this *builder) buildSubqueries(exprs expression.Expressions) plan.SubqueryPlans {
+	for _, query := range collectSubqueries(exprs, nil) {
+		if this.subqueries.planned[query] {
+			continue
+		}
+
+		this.subqueries.planned[query] = true
+		if this.subqueries.decorrelated[query] {
+			continue
+		}
+
+		op, err := this.buildSubquery(query)
+		if err != nil {
+			continue
+		}
+
+		this.subqueries.plans = append(this.subqueries.plans, plan.NewSubqueryPlan(query, op))
+		this.buildSubqueries(query.Expressions())
+	}
+
+	return this.subqueries.plans
+}
+
+func (this *builder) buildSubquery(query *algebra.Select) (plan.Operator, error) {
+	builder := newBuilder(this.datastore, this.systemstore, this.namespace, true)
+	builder.subqueries = this.subqueries
+	builder.optimHintBlocks = this.optimHintBlocks
+
+	op, err := query.Accept(builder)
+	if err != nil {
+		return nil, err
+	}
+
+	return op.(plan.Operator), nil
+}
+
+/*
+collectSubqueries appends the subqueries of the expressions, but not
+the subqueries nested in them.
+*/
+func collectSubqueries(exprs expression.Expressions, queries []*algebra.Select) []*algebra.Select {
+	for _, expr := range exprs {
+		if expr == nil {
+			continue
+		}
+
+		if subquery, ok := expr.(*algebra.Subquery); ok {
+			queries = append(queries, subquery.Select())
+		} else {
+			queries = collectSubqueries(expr.Children(), queries)
+		}
+	}
+
+	return queries
+}
+
+/*
+plansSubqueries is true for the statements that are executed with
+their subqueries, as opposed to being prepared or advised.
+*/
+func plansSubqueries(stmt algebra.Statement) bool {
+	switch stmt.(type) {
+	case *algebra.Select, *algebra.Insert, *algebra.Upsert, *algebra.Update, *algebra.Delete,
+		*algebra.Merge, *algebra.Explain:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,115 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+)
+
+func (this *builder) VisitCreateTrigger(stmt *algebra.CreateTrigger) (interface{}, error) {
+	ksref := stmt.Keyspace()
+	keyspace, err := this.getTriggerKeyspace(ksref)
+	if err != nil {
+		return nil, err
+	}
+
+	trigger, er := stmt.Trigger()
+	if er != nil {
+		return nil, er
+	}
+
+	existing, er := findTrigger(keyspace, stmt.Name())
+	if er != nil {
+		return nil, er
+	}
+
+	if existing != nil {
+		return nil, errors.NewTriggerAlreadyExistsError(stmt.Name())
+	}
+
+	// Check that the trigger statement can be planned; it runs in
+	// the namespace of the keyspace
+	_, err = Build(stmt.Statement(), this.datastore, this.systemstore, keyspace.NamespaceId(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewCreateTrigger(keyspace, trigger), nil
+}
+
+func (this *builder) VisitDropTrigger(stmt *algebra.DropTrigger) (interface{}, error) {
+	keyspace, err := this.getTriggerKeyspace(stmt.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	existing, er := findTrigger(keyspace, stmt.Name())
+	if er != nil {
+		return nil, er
+	}
+
+	if existing == nil {
+		return nil, errors.NewTriggerNotFoundError(stmt.Name())
+	}
+
+	return plan.NewDropTrigger(keyspace, stmt.Name()), nil
+}
+
+func (this *builder) getTriggerKeyspace(ksref *algebra.KeyspaceRef) (datastore.TriggerKeyspace, error) {
+	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	tks, ok := keyspace.(datastore.TriggerKeyspace)
+	if !ok {
+		return nil, errors.NewTriggersNotSupportedError(keyspace.NamespaceId() + ":" + keyspace.Name())
+	}
+
+	return tks, nil
+}
+
+func findTrigger(keyspace datastore.TriggerKeyspace, name string) (*datastore.Trigger, errors.Error) {
+	triggers, err := keyspace.Triggers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, trigger := range triggers {
+		if trigger.Name == name {
+			return trigger, nil
+		}
+	}
+
+	return nil, nil
+}
+
+/*
+Returns true if the keyspace has triggers for event. Errors reading
+the triggers are reported when the triggers are run.
+*/
+func hasTriggers(keyspace datastore.Keyspace, event string) bool {
+	tkeyspace, ok := keyspace.(datastore.TriggerKeyspace)
+	if !ok {
+		return false
+	}
+
+	triggers, _ := tkeyspace.Triggers()
+	for _, trigger := range triggers {
+		if trigger.HasEvent(event) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -15,6 +15,7 @@ import (
 )
 
 func (this *builder) VisitUpdate(stmt *algebra.Update) (interface{}, error) {
+	defer this.useOptimHints(stmt.OptimHints())()
 	this.where = stmt.Where()
 
 	ksref := stmt.KeyspaceRef()
@@ -48,13 +49,13 @@ func (this *builder) VisitUpdate(stmt *algebra.Update) (interface{}, error) {
 
 	if stmt.Limit() != nil {
 		seqChildren := make([]plan.Operator, 0, 3)
-		seqChildren = append(seqChildren, plan.NewParallel(plan.NewSequence(subChildren...), this.maxParallelism))
+		seqChildren = append(seqChildren, plan.NewParallel(plan.NewSequence(subChildren...), this.parallelism()))
 		seqChildren = append(seqChildren, plan.NewLimit(stmt.Limit()))
-		seqChildren = append(seqChildren, plan.NewParallel(plan.NewSequence(updateSubChildren...), this.maxParallelism))
+		seqChildren = append(seqChildren, plan.NewParallel(plan.NewSequence(updateSubChildren...), this.parallelism()))
 		this.children = append(this.children, plan.NewSequence(seqChildren...))
 	} else {
 		subChildren = append(subChildren, updateSubChildren...)
-		this.children = append(this.children, plan.NewParallel(plan.NewSequence(subChildren...), this.maxParallelism))
+		this.children = append(this.children, plan.NewParallel(plan.NewSequence(subChildren...), this.parallelism()))
 	}
 
 	if stmt.Returning() == nil {
@@ -51,7 +51,7 @@ func (this *builder) VisitUpsert(stmt *algebra.Upsert) (interface{}, error) {
 		subChildren = append(subChildren, plan.NewDiscard())
 	}
 
-	parallel := plan.NewParallel(plan.NewSequence(subChildren...), this.maxParallelism)
+	parallel := plan.NewParallel(plan.NewSequence(subChildren...), this.parallelism())
 	children = append(children, parallel)
 	return plan.NewSequence(children...), nil
 }
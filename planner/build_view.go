@@ -0,0 +1,100 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+)
+
+func (this *builder) VisitCreateMaterializedView(stmt *algebra.CreateMaterializedView) (interface{}, error) {
+	ksref := stmt.Keyspace()
+	namespace, err := this.getViewNamespace(ksref)
+	if err != nil {
+		return nil, err
+	}
+
+	view, er := stmt.View()
+	if er != nil {
+		return nil, er
+	}
+
+	_, er = namespace.KeyspaceByName(ksref.Keyspace())
+	if er == nil {
+		return nil, errors.NewViewAlreadyExistsError(namespace.Name() + ":" + ksref.Keyspace())
+	}
+
+	// Check that the view query can be planned; it runs in the
+	// namespace of the view
+	_, err = Build(stmt.Query(), this.datastore, this.systemstore, namespace.Name(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewCreateMaterializedView(namespace, view), nil
+}
+
+func (this *builder) VisitRefreshMaterializedView(stmt *algebra.RefreshMaterializedView) (interface{}, error) {
+	keyspace, err := this.getViewKeyspace(stmt.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewRefreshMaterializedView(keyspace), nil
+}
+
+func (this *builder) VisitDropMaterializedView(stmt *algebra.DropMaterializedView) (interface{}, error) {
+	namespace, err := this.getViewNamespace(stmt.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	keyspace, err := this.getViewKeyspace(stmt.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewDropMaterializedView(namespace, keyspace), nil
+}
+
+func (this *builder) getViewNamespace(ksref *algebra.KeyspaceRef) (datastore.ViewNamespace, error) {
+	ns := ksref.Namespace()
+	if ns == "" {
+		ns = this.namespace
+	}
+
+	namespace, err := this.datastore.NamespaceByName(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	vns, ok := namespace.(datastore.ViewNamespace)
+	if !ok {
+		return nil, errors.NewViewsNotSupportedError(ns)
+	}
+
+	return vns, nil
+}
+
+func (this *builder) getViewKeyspace(ksref *algebra.KeyspaceRef) (datastore.ViewKeyspace, error) {
+	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	vks, ok := keyspace.(datastore.ViewKeyspace)
+	if !ok || vks.View() == nil {
+		return nil, errors.NewViewNotFoundError(keyspace.NamespaceId() + ":" + keyspace.Name())
+	}
+
+	return vks, nil
+}
@@ -0,0 +1,348 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/plan"
+)
+
+/*
+optimHints tracks whether the optimizer hints of a SELECT, UPDATE,
+DELETE or MERGE are followed while it is planned. A hint is followed
+if it is followed for any keyspace term it applies to.
+*/
+type optimHints struct {
+	hints    algebra.OptimHints
+	statuses plan.OptimHintStatuses
+}
+
+/*
+optimHintBlocks are the hints of a statement and of its subqueries,
+in the order they are first planned. A block planned more than once,
+such as a decorrelated subquery, is reported once.
+*/
+type optimHintBlocks struct {
+	blocks []*optimHints
+}
+
+func newOptimHintBlocks() *optimHintBlocks {
+	return &optimHintBlocks{}
+}
+
+func (this *optimHintBlocks) block(hints algebra.OptimHints) *optimHints {
+	if len(hints) == 0 {
+		return nil
+	}
+
+	for _, block := range this.blocks {
+		if &block.hints[0] == &hints[0] {
+			return block
+		}
+	}
+
+	block := newOptimHints(hints)
+	this.blocks = append(this.blocks, block)
+	return block
+}
+
+/*
+useOptimHints plans with the hints of a SELECT, UPDATE, DELETE or
+MERGE, until the returned function restores the enclosing hints.
+*/
+func (this *builder) useOptimHints(hints algebra.OptimHints) func() {
+	prev := this.optimHints
+	this.optimHints = this.optimHintBlocks.block(hints)
+	return func() {
+		this.optimHints = prev
+	}
+}
+
+func newOptimHints(hints algebra.OptimHints) *optimHints {
+	statuses := make(plan.OptimHintStatuses, len(hints))
+	for i, hint := range hints {
+		statuses[i] = &plan.OptimHintStatus{Hint: hint.String()}
+		if hint.Invalid() != "" {
+			statuses[i].Status = plan.OPTIM_HINT_INVALID
+			statuses[i].Reason = hint.Invalid()
+		}
+	}
+
+	return &optimHints{
+		hints:    hints,
+		statuses: statuses,
+	}
+}
+
+/*
+optimHintStatuses returns the status of each hint once the statement
+and its subqueries are planned, and forgets the hints. Hints that
+never applied are not followed.
+*/
+func (this *builder) optimHintStatuses() plan.OptimHintStatuses {
+	var rv plan.OptimHintStatuses
+	for _, block := range this.optimHintBlocks.blocks {
+		rv = append(rv, block.finish()...)
+	}
+
+	this.optimHintBlocks.blocks = nil
+	return rv
+}
+
+func (this *optimHints) finish() plan.OptimHintStatuses {
+	for i, hint := range this.hints {
+		status := this.statuses[i]
+		if status.Status != "" {
+			continue
+		}
+
+		status.Status = plan.OPTIM_HINT_NOT_FOLLOWED
+		switch hint.Name() {
+		case algebra.OPTIM_USE_NL, algebra.OPTIM_USE_HASH:
+			status.Reason = fmt.Sprintf("there is no join on alias %s", hint.Alias())
+		case algebra.OPTIM_ORDERED:
+			status.Reason = "the statement has no joins"
+		case algebra.OPTIM_PARALLEL:
+			status.Reason = "the statement has no parallel operators"
+		default:
+			status.Reason = fmt.Sprintf("keyspace alias %s is not scanned", hint.Alias())
+		}
+	}
+
+	return this.statuses
+}
+
+func (this *optimHints) follow(i int) {
+	this.statuses[i].Status = plan.OPTIM_HINT_FOLLOWED
+	this.statuses[i].Reason = ""
+}
+
+func (this *optimHints) reject(i int, reason string) {
+	if this.statuses[i].Status != plan.OPTIM_HINT_FOLLOWED {
+		this.statuses[i].Status = plan.OPTIM_HINT_NOT_FOLLOWED
+		this.statuses[i].Reason = reason
+	}
+}
+
+/*
+Returns the index of each valid hint with the given name that applies
+to the alias. Hints without an alias apply to every alias.
+*/
+func (this *optimHints) find(name, alias string) []int {
+	var rv []int
+	for i, hint := range this.hints {
+		if hint.Name() == name && hint.Invalid() == "" &&
+			(hint.Alias() == "" || hint.Alias() == alias) {
+			rv = append(rv, i)
+		}
+	}
+
+	return rv
+}
+
+/*
+noCover returns true if NO_COVER applies to the alias.
+*/
+func (this *builder) noCover(alias string) bool {
+	if this.optimHints == nil {
+		return false
+	}
+
+	found := this.optimHints.find(algebra.OPTIM_NO_COVER, alias)
+	for _, i := range found {
+		this.optimHints.follow(i)
+	}
+
+	return len(found) > 0
+}
+
+/*
+parallelism returns the degree of parallelism given by PARALLEL, or
+the one chosen by the planner. Plans that rely on index order run
+serially after the scan.
+*/
+func (this *builder) parallelism() int {
+	if this.order == nil {
+		n := this.parallelHint()
+		if n > 0 {
+			return n
+		}
+	}
+
+	return this.maxParallelism
+}
+
+/*
+parallelHint returns the degree of parallelism given by PARALLEL, or 0.
+*/
+func (this *builder) parallelHint() int {
+	if this.optimHints != nil {
+		found := this.optimHints.find(algebra.OPTIM_PARALLEL, "")
+		if len(found) > 0 {
+			for _, i := range found {
+				this.optimHints.follow(i)
+			}
+
+			return this.optimHints.hints[found[len(found)-1]].Parallel()
+		}
+	}
+
+	return 0
+}
+
+/*
+Joins are always nested-loop joins, performed in the order of the FROM
+clause.
+*/
+func (this *builder) joinOptimHints(alias string) {
+	if this.optimHints == nil {
+		return
+	}
+
+	for _, i := range this.optimHints.find(algebra.OPTIM_ORDERED, "") {
+		this.optimHints.follow(i)
+	}
+
+	for _, i := range this.optimHints.find(algebra.OPTIM_USE_NL, alias) {
+		this.optimHints.follow(i)
+	}
+
+	for _, i := range this.optimHints.find(algebra.OPTIM_USE_HASH, alias) {
+		this.optimHints.reject(i, "hash joins are not supported")
+	}
+}
+
+/*
+hintIndexRefs returns the indexes named by INDEX for the keyspace
+term, if it has no USE INDEX clause.
+*/
+func (this *builder) hintIndexRefs(keyspace datastore.Keyspace, node *algebra.KeyspaceTerm) (
+	algebra.IndexRefs, error) {
+	if this.optimHints == nil || len(node.Indexes()) > 0 || node.Keys() != nil {
+		return nil, nil
+	}
+
+	indexers, err := keyspace.Indexers()
+	if err != nil {
+		return nil, err
+	}
+
+	var refs algebra.IndexRefs
+	for _, i := range this.optimHints.find(algebra.OPTIM_INDEX, node.Alias()) {
+		var missing []string
+
+	names:
+		for _, name := range this.optimHints.hints[i].Indexes() {
+			for _, indexer := range indexers {
+				_, er := indexer.IndexByName(name)
+				if er == nil {
+					refs = append(refs, algebra.NewIndexRef(name, indexer.Name()))
+					continue names
+				}
+			}
+
+			missing = append(missing, name)
+		}
+
+		if len(missing) > 0 {
+			this.optimHints.reject(i, fmt.Sprintf("index %s not found", strings.Join(missing, ", ")))
+		}
+	}
+
+	return refs, nil
+}
+
+/*
+excludeHintIndexes removes the indexes named by NO_INDEX from the
+candidate indexes of the keyspace term, or all secondary indexes if
+NO_INDEX names none.
+*/
+func (this *builder) excludeHintIndexes(node *algebra.KeyspaceTerm, indexes []datastore.Index) []datastore.Index {
+	if this.optimHints == nil || len(node.Indexes()) > 0 {
+		return indexes
+	}
+
+	for _, i := range this.optimHints.find(algebra.OPTIM_NO_INDEX, node.Alias()) {
+		names := this.optimHints.hints[i].Indexes()
+		n := 0
+		for _, index := range indexes {
+			if (len(names) > 0 && !hasName(names, index.Name())) ||
+				(len(names) == 0 && index.IsPrimary()) {
+				indexes[n] = index
+				n++
+			}
+		}
+
+		indexes = indexes[:n]
+	}
+
+	return indexes
+}
+
+/*
+Record whether the INDEX and NO_INDEX hints of the keyspace term were
+followed by its scan.
+*/
+func (this *builder) scanOptimHints(node *algebra.KeyspaceTerm, scan plan.Operator) {
+	if this.optimHints == nil {
+		return
+	}
+
+	alias := node.Alias()
+	used := make(map[string]bool, 4)
+	describeScan(scan, used)
+
+	for _, name := range []string{algebra.OPTIM_INDEX, algebra.OPTIM_NO_INDEX} {
+		for _, i := range this.optimHints.find(name, alias) {
+			if node.Keys() != nil {
+				this.optimHints.reject(i, "USE KEYS is given")
+				continue
+			}
+
+			if len(node.Indexes()) > 0 {
+				this.optimHints.reject(i, "USE INDEX is given")
+				continue
+			}
+
+			named := false
+			for _, index := range this.optimHints.hints[i].Indexes() {
+				if used[index] {
+					named = true
+					break
+				}
+			}
+
+			switch {
+			case name == algebra.OPTIM_INDEX && named, name == algebra.OPTIM_NO_INDEX && !named:
+				this.optimHints.follow(i)
+			case this.optimHints.statuses[i].Status != "":
+				// Keep the reason given earlier
+			case name == algebra.OPTIM_INDEX:
+				this.optimHints.reject(i, "no named index is applicable")
+			default:
+				this.optimHints.reject(i, "no other index is applicable")
+			}
+		}
+	}
+}
+
+func hasName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,100 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+)
+
+/*
+sargGeo returns the spans of a spatial index on key for pred. Each
+span seeks the bounding box of a region that contains every matching
+geometry; the index returns candidates, and the predicate is still
+evaluated by the Filter. The sargable predicates are:
+
+	ST_WITHIN(key, region)
+	ST_INTERSECTS(key, region), ST_INTERSECTS(region, key)
+	ST_DISTANCE(key, region) <= radius, and < radius
+
+and AND and OR of them. It returns nil if pred is not sargable.
+*/
+func sargGeo(pred, key expression.Expression) plan.Spans {
+	switch pred := pred.(type) {
+	case *expression.And:
+		// Any conjunct bounds the results
+		for _, op := range pred.Operands() {
+			if spans := sargGeo(op, key); spans != nil {
+				return spans
+			}
+		}
+	case *expression.Or:
+		// Every disjunct must be bounded
+		var spans plan.Spans
+		for _, op := range pred.Operands() {
+			s := sargGeo(op, key)
+			if s == nil {
+				return nil
+			}
+			spans = append(spans, s...)
+		}
+		return spans
+	case *expression.StWithin:
+		if pred.First().EquivalentTo(key) {
+			return geoSpans(pred.Second(), nil)
+		}
+	case *expression.StIntersects:
+		if pred.First().EquivalentTo(key) {
+			return geoSpans(pred.Second(), nil)
+		} else if pred.Second().EquivalentTo(key) {
+			return geoSpans(pred.First(), nil)
+		}
+	case *expression.LE:
+		return sargGeoRadius(pred.First(), pred.Second(), key)
+	case *expression.LT:
+		return sargGeoRadius(pred.First(), pred.Second(), key)
+	}
+
+	return nil
+}
+
+func sargGeoRadius(distance, radius, key expression.Expression) plan.Spans {
+	d, ok := distance.(*expression.StDistance)
+	if !ok || radius.Static() == nil {
+		return nil
+	}
+
+	if d.First().EquivalentTo(key) {
+		return geoSpans(d.Second(), radius)
+	} else if d.Second().EquivalentTo(key) {
+		return geoSpans(d.First(), radius)
+	}
+
+	return nil
+}
+
+/*
+geoSpans seeks the bounding box of region, expanded by radius if not
+nil. The region must not depend on the scanned keyspace.
+*/
+func geoSpans(region, radius expression.Expression) plan.Spans {
+	if region.Static() == nil {
+		return nil
+	}
+
+	var bbox expression.Expression
+	if radius == nil {
+		bbox = expression.NewStBbox(region)
+	} else {
+		bbox = expression.NewStBbox(region, radius)
+	}
+
+	return plan.Spans{&plan.Span{Seek: expression.Expressions{bbox}}}
+}
@@ -0,0 +1,556 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+
+Package schema validates JSON documents against JSON Schema (draft-7)
+schemas. Schemas and documents are values of the value package.
+
+All the validation keywords of draft-7 are supported. References must
+be local JSON pointers, such as "#/definitions/address"; remote
+references are rejected when the schema is compiled. The formats
+date-time, date, time, email, hostname, ipv4, ipv6, uri, uuid and
+regex are checked; other formats are ignored, as the specification
+allows.
+
+*/
+package schema
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/couchbase/query/value"
+)
+
+/*
+Schema is a compiled schema.
+*/
+type Schema struct {
+	source value.Value
+	root   *node
+	nodes  map[string]*node // By JSON pointer, to resolve references
+	refs   []*node          // Nodes with a reference to resolve
+}
+
+/*
+Violation is a failed check. Path is the JSON pointer of the
+offending part of the document.
+*/
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (this *Violation) String() string {
+	path := this.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return path + ": " + this.Message
+}
+
+/*
+Compile checks and compiles source, which must be a JSON Schema
+object or boolean.
+*/
+func Compile(source value.Value) (*Schema, error) {
+	rv := &Schema{
+		source: source,
+		nodes:  make(map[string]*node),
+	}
+
+	var err error
+	rv.root, err = rv.compile(source, "#")
+	if err != nil {
+		return nil, err
+	}
+
+	for len(rv.refs) > 0 {
+		n := rv.refs[0]
+		rv.refs = rv.refs[1:]
+
+		n.target, err = rv.resolve(n.ref)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rv, nil
+}
+
+/*
+Source returns the schema, as given to Compile.
+*/
+func (this *Schema) Source() value.Value {
+	return this.source
+}
+
+/*
+Validate returns the violations of the schema by doc, or nil if doc
+is valid.
+*/
+func (this *Schema) Validate(doc value.Value) []*Violation {
+	var rv []*Violation
+	this.root.validate(doc, "", &rv)
+	return rv
+}
+
+/*
+resolve returns the node of a local reference, compiling it if it is
+not a subschema that has already been compiled.
+*/
+func (this *Schema) resolve(ref string) (*node, error) {
+	if n, ok := this.nodes[ref]; ok {
+		return n, nil
+	}
+
+	if id, ok := this.source.Field("$id"); ok && id.Actual() == ref {
+		return this.root, nil
+	}
+
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("Unsupported $ref %s; only local references are supported.", ref)
+	}
+
+	pointer, err := url.QueryUnescape(ref[1:])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid $ref %s.", ref)
+	}
+
+	target := this.source
+	if pointer != "" {
+		if pointer[0] != '/' {
+			return nil, fmt.Errorf("Invalid $ref %s.", ref)
+		}
+
+		for _, token := range strings.Split(pointer[1:], "/") {
+			token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+
+			var ok bool
+			switch target.Type() {
+			case value.OBJECT:
+				target, ok = target.Field(token)
+			case value.ARRAY:
+				i, er := strconv.Atoi(token)
+				if er == nil {
+					target, ok = target.Index(i)
+				}
+			}
+
+			if !ok {
+				return nil, fmt.Errorf("Unresolved $ref %s.", ref)
+			}
+		}
+	}
+
+	return this.compile(target, ref)
+}
+
+/*
+node is a compiled schema or subschema. Absent keywords are nil.
+*/
+type node struct {
+	pointer string
+	always  *bool // Boolean schemas
+	ref     string
+	target  *node // Resolved reference
+
+	types    []string
+	enum     []value.Value
+	constant value.Value
+
+	multipleOf       *float64
+	maximum          *float64
+	exclusiveMaximum *float64
+	minimum          *float64
+	exclusiveMinimum *float64
+
+	maxLength *int
+	minLength *int
+	pattern   *regexp.Regexp
+	format    string
+
+	items           *node
+	tupleItems      []*node
+	additionalItems *node
+	maxItems        *int
+	minItems        *int
+	uniqueItems     bool
+	contains        *node
+
+	maxProperties        *int
+	minProperties        *int
+	required             []string
+	properties           map[string]*node
+	patternProperties    []*patternNode
+	additionalProperties *node
+	propertyNames        *node
+	dependentRequired    map[string][]string
+	dependentSchemas     map[string]*node
+
+	ifNode   *node
+	thenNode *node
+	elseNode *node
+	allOf    []*node
+	anyOf    []*node
+	oneOf    []*node
+	not      *node
+}
+
+type patternNode struct {
+	pattern *regexp.Regexp
+	node    *node
+}
+
+var _TYPES = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"number":  true,
+	"integer": true,
+	"string":  true,
+	"array":   true,
+	"object":  true,
+}
+
+func (this *Schema) compile(source value.Value, pointer string) (*node, error) {
+	if n, ok := this.nodes[pointer]; ok {
+		return n, nil
+	}
+
+	n := &node{pointer: pointer}
+	this.nodes[pointer] = n
+
+	switch source.Type() {
+	case value.BOOLEAN:
+		always := source.Truth()
+		n.always = &always
+		return n, nil
+	case value.OBJECT:
+	default:
+		return nil, fmt.Errorf("Invalid schema at %s: expected an object or a boolean.", pointer)
+	}
+
+	fields := source.Fields()
+
+	// In draft-7, $ref overrides the other keywords
+	if ref, ok := fields["$ref"]; ok {
+		s, ok := value.NewValue(ref).Actual().(string)
+		if !ok {
+			return nil, fmt.Errorf("Invalid $ref at %s.", pointer)
+		}
+
+		n.ref = s
+		this.refs = append(this.refs, n)
+		return n, nil
+	}
+
+	for _, name := range sortedNames(fields) {
+		keyword := value.NewValue(fields[name])
+		at := pointer + "/" + escape(name)
+
+		var err error
+		switch name {
+		case "type":
+			n.types, err = compileTypes(keyword, at)
+		case "enum":
+			if keyword.Type() != value.ARRAY {
+				err = keywordError(at, "an array")
+			} else {
+				n.enum = valueList(keyword)
+			}
+		case "const":
+			n.constant = keyword
+		case "multipleOf":
+			n.multipleOf, err = compileNumber(keyword, at)
+			if err == nil && *n.multipleOf <= 0 {
+				err = keywordError(at, "a positive number")
+			}
+		case "maximum":
+			n.maximum, err = compileNumber(keyword, at)
+		case "exclusiveMaximum":
+			n.exclusiveMaximum, err = compileNumber(keyword, at)
+		case "minimum":
+			n.minimum, err = compileNumber(keyword, at)
+		case "exclusiveMinimum":
+			n.exclusiveMinimum, err = compileNumber(keyword, at)
+		case "maxLength":
+			n.maxLength, err = compileCount(keyword, at)
+		case "minLength":
+			n.minLength, err = compileCount(keyword, at)
+		case "pattern":
+			n.pattern, err = compilePattern(keyword, at)
+		case "format":
+			s, ok := keyword.Actual().(string)
+			if !ok {
+				err = keywordError(at, "a string")
+			}
+			n.format = s
+		case "items":
+			if keyword.Type() == value.ARRAY {
+				n.tupleItems, err = this.compileList(keyword, at)
+			} else {
+				n.items, err = this.compile(keyword, at)
+			}
+		case "additionalItems":
+			n.additionalItems, err = this.compile(keyword, at)
+		case "maxItems":
+			n.maxItems, err = compileCount(keyword, at)
+		case "minItems":
+			n.minItems, err = compileCount(keyword, at)
+		case "uniqueItems":
+			b, ok := keyword.Actual().(bool)
+			if !ok {
+				err = keywordError(at, "a boolean")
+			}
+			n.uniqueItems = b
+		case "contains":
+			n.contains, err = this.compile(keyword, at)
+		case "maxProperties":
+			n.maxProperties, err = compileCount(keyword, at)
+		case "minProperties":
+			n.minProperties, err = compileCount(keyword, at)
+		case "required":
+			n.required, err = compileStrings(keyword, at)
+		case "properties":
+			n.properties, err = this.compileMap(keyword, at)
+		case "patternProperties":
+			if keyword.Type() != value.OBJECT {
+				err = keywordError(at, "an object")
+				break
+			}
+			properties := keyword.Fields()
+			for _, p := range sortedNames(properties) {
+				pn := &patternNode{}
+				pn.pattern, err = compilePattern(value.NewValue(p), at)
+				if err == nil {
+					pn.node, err = this.compile(value.NewValue(properties[p]), at+"/"+escape(p))
+				}
+				if err != nil {
+					break
+				}
+				n.patternProperties = append(n.patternProperties, pn)
+			}
+		case "additionalProperties":
+			n.additionalProperties, err = this.compile(keyword, at)
+		case "propertyNames":
+			n.propertyNames, err = this.compile(keyword, at)
+		case "dependencies":
+			if keyword.Type() != value.OBJECT {
+				err = keywordError(at, "an object")
+				break
+			}
+			n.dependentRequired = make(map[string][]string)
+			n.dependentSchemas = make(map[string]*node)
+			dependencies := keyword.Fields()
+			for _, p := range sortedNames(dependencies) {
+				dependency := value.NewValue(dependencies[p])
+				if dependency.Type() == value.ARRAY {
+					n.dependentRequired[p], err = compileStrings(dependency, at+"/"+escape(p))
+				} else {
+					n.dependentSchemas[p], err = this.compile(dependency, at+"/"+escape(p))
+				}
+				if err != nil {
+					break
+				}
+			}
+		case "if":
+			n.ifNode, err = this.compile(keyword, at)
+		case "then":
+			n.thenNode, err = this.compile(keyword, at)
+		case "else":
+			n.elseNode, err = this.compile(keyword, at)
+		case "allOf":
+			n.allOf, err = this.compileList(keyword, at)
+		case "anyOf":
+			n.anyOf, err = this.compileList(keyword, at)
+		case "oneOf":
+			n.oneOf, err = this.compileList(keyword, at)
+		case "not":
+			n.not, err = this.compile(keyword, at)
+		case "definitions":
+			// Compiled when referenced, but checked now
+			if keyword.Type() != value.OBJECT {
+				err = keywordError(at, "an object")
+				break
+			}
+			_, err = this.compileMap(keyword, at)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return n, nil
+}
+
+func (this *Schema) compileList(keyword value.Value, at string) ([]*node, error) {
+	if keyword.Type() != value.ARRAY {
+		return nil, keywordError(at, "an array of schemas")
+	}
+
+	list := valueList(keyword)
+	if len(list) == 0 {
+		return nil, keywordError(at, "a non-empty array of schemas")
+	}
+
+	rv := make([]*node, len(list))
+	for i, item := range list {
+		var err error
+		rv[i], err = this.compile(item, at+"/"+strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rv, nil
+}
+
+func (this *Schema) compileMap(keyword value.Value, at string) (map[string]*node, error) {
+	if keyword.Type() != value.OBJECT {
+		return nil, keywordError(at, "an object of schemas")
+	}
+
+	fields := keyword.Fields()
+	rv := make(map[string]*node, len(fields))
+	for _, name := range sortedNames(fields) {
+		var err error
+		rv[name], err = this.compile(value.NewValue(fields[name]), at+"/"+escape(name))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rv, nil
+}
+
+func compileTypes(keyword value.Value, at string) ([]string, error) {
+	var types []string
+	if keyword.Type() == value.STRING {
+		types = []string{keyword.Actual().(string)}
+	} else {
+		var err error
+		types, err = compileStrings(keyword, at)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, t := range types {
+		if !_TYPES[t] {
+			return nil, fmt.Errorf("Invalid schema at %s: unknown type %s.", at, t)
+		}
+	}
+
+	return types, nil
+}
+
+func compileNumber(keyword value.Value, at string) (*float64, error) {
+	if keyword.Type() != value.NUMBER {
+		return nil, keywordError(at, "a number")
+	}
+
+	n := number(keyword)
+	return &n, nil
+}
+
+func compileCount(keyword value.Value, at string) (*int, error) {
+	if keyword.Type() != value.NUMBER {
+		return nil, keywordError(at, "a non-negative integer")
+	}
+
+	n := number(keyword)
+	if n < 0 || n != math.Trunc(n) {
+		return nil, keywordError(at, "a non-negative integer")
+	}
+
+	i := int(n)
+	return &i, nil
+}
+
+func compilePattern(keyword value.Value, at string) (*regexp.Regexp, error) {
+	s, ok := keyword.Actual().(string)
+	if !ok {
+		return nil, keywordError(at, "a regular expression")
+	}
+
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid schema at %s: %v", at, err)
+	}
+
+	return re, nil
+}
+
+func compileStrings(keyword value.Value, at string) ([]string, error) {
+	if keyword.Type() != value.ARRAY {
+		return nil, keywordError(at, "an array of strings")
+	}
+
+	list := valueList(keyword)
+	rv := make([]string, len(list))
+	for i, item := range list {
+		s, ok := item.Actual().(string)
+		if !ok {
+			return nil, keywordError(at, "an array of strings")
+		}
+		rv[i] = s
+	}
+
+	return rv, nil
+}
+
+func keywordError(at, expected string) error {
+	return fmt.Errorf("Invalid schema at %s: expected %s.", at, expected)
+}
+
+func valueList(val value.Value) []value.Value {
+	actual, _ := val.Actual().([]interface{})
+	rv := make([]value.Value, len(actual))
+	for i, item := range actual {
+		rv[i] = value.NewValue(item)
+	}
+
+	return rv
+}
+
+func sortedNames(fields map[string]interface{}) []string {
+	rv := make([]string, 0, len(fields))
+	for name, _ := range fields {
+		rv = append(rv, name)
+	}
+
+	sort.Strings(rv)
+	return rv
+}
+
+/*
+escape escapes a JSON pointer token.
+*/
+func escape(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+func number(val value.Value) float64 {
+	switch n := val.Actual().(type) {
+	case float64:
+		return n
+	case int64:
+		return float64(n)
+	default:
+		return math.NaN()
+	}
+}
@@ -0,0 +1,125 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+func compile(t *testing.T, s string) *Schema {
+	schema, err := Compile(value.NewValue([]byte(s)))
+	if err != nil {
+		t.Fatalf("Unexpected error compiling %s: %v", s, err)
+	}
+	return schema
+}
+
+func TestCompile(t *testing.T) {
+	invalid := []string{
+		`1`,
+		`{"type": "text"}`,
+		`{"minLength": -1}`,
+		`{"pattern": "("}`,
+		`{"anyOf": []}`,
+		`{"properties": {"a": 1}}`,
+		`{"$ref": "#/definitions/missing"}`,
+		`{"$ref": "http://example.com/schema"}`,
+	}
+
+	for _, s := range invalid {
+		_, err := Compile(value.NewValue([]byte(s)))
+		if err == nil {
+			t.Errorf("Expected error compiling %s", s)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema := compile(t, `{
+		"type": "object",
+		"required": ["id", "name"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"name": {"type": "string", "minLength": 1, "maxLength": 10},
+			"email": {"type": "string", "format": "email"},
+			"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true},
+			"status": {"enum": ["new", "done"]},
+			"price": {"type": "number", "multipleOf": 0.01, "exclusiveMinimum": 0}
+		},
+		"additionalProperties": false,
+		"dependencies": {"price": ["currency"]},
+		"patternProperties": {"^x-": {}, "^currency$": {"const": "EUR"}}
+	}`)
+
+	docs := map[string]int{
+		`{"id": 1, "name": "a"}`:                                     0,
+		`{"id": 1.0, "name": "a", "x-note": [1]}`:                    0,
+		`{"id": 1, "name": "a", "price": 9.99, "currency": "EUR"}`:   0,
+		`{"id": 0, "name": ""}`:                                      2,
+		`{"id": 1.5}`:                                                2,
+		`{"id": 1, "name": "a", "other": true}`:                      1,
+		`{"id": 1, "name": "a", "tags": ["x", 1, "x"]}`:              2,
+		`{"id": 1, "name": "a", "status": "old", "email": "nobody"}`: 2,
+		`{"id": 1, "name": "a", "price": 0.001}`:                     2,
+		`{"id": 1, "name": "a", "price": 1, "currency": "USD"}`:      1,
+		`[1, 2]`: 1,
+		`{"id": 1, "name": "abcdefghijk", "email": "a@b.c", "status": null}`: 2,
+	}
+
+	for doc, expected := range docs {
+		violations := schema.Validate(value.NewValue([]byte(doc)))
+		if len(violations) != expected {
+			t.Errorf("Expected %d violations for %s, got %v", expected, doc, violations)
+		}
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	schema := compile(t, `{
+		"definitions": {
+			"node": {
+				"type": "object",
+				"properties": {
+					"value": {"oneOf": [{"type": "integer"}, {"type": "string", "format": "date"}]},
+					"children": {"type": "array", "items": {"$ref": "#/definitions/node"}}
+				},
+				"if": {"required": ["children"]},
+				"then": {"properties": {"children": {"minItems": 1}}},
+				"else": {"required": ["value"]},
+				"not": {"required": ["deleted"]}
+			}
+		},
+		"$ref": "#/definitions/node"
+	}`)
+
+	docs := map[string]int{
+		`{"value": 1}`: 0,
+		`{"value": "2016-01-31", "children": [{"value": 2}]}`: 0,
+		`{"children": [{"children": [{"value": 1.5}]}]}`:      1,
+		`{"children": []}`:                                         1,
+		`{"value": "2016-13-01"}`:                                  1,
+		`{"value": 1, "deleted": true}`:                            1,
+		`{"children": [{"value": 1}, {"value": 2, "deleted": 1}]}`: 1,
+	}
+
+	for doc, expected := range docs {
+		violations := schema.Validate(value.NewValue([]byte(doc)))
+		if len(violations) != expected {
+			t.Errorf("Expected %d violations for %s, got %v", expected, doc, violations)
+		}
+	}
+
+	violations := schema.Validate(value.NewValue([]byte(`{"children": [{"value": true}]}`)))
+	if len(violations) != 1 || violations[0].String() != "/children/0/value: Value matches 0 of the schemas of oneOf, instead of one." {
+		t.Errorf("Unexpected violations %v", violations)
+	}
+}
@@ -0,0 +1,386 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/couchbase/query/value"
+)
+
+func (this *node) validate(val value.Value, path string, violations *[]*Violation) {
+	if this.always != nil {
+		if !*this.always {
+			violate(violations, path, "Not allowed by the schema.")
+		}
+		return
+	}
+
+	if this.target != nil {
+		this.target.validate(val, path, violations)
+		return
+	}
+
+	if this.types != nil && !this.hasType(val) {
+		violate(violations, path, "Expected type %s, found %s.",
+			strings.Join(this.types, " or "), typeName(val))
+	}
+
+	if this.enum != nil {
+		found := false
+		for _, item := range this.enum {
+			if equal(val, item) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			violate(violations, path, "Value is not one of the allowed values.")
+		}
+	}
+
+	if this.constant != nil && !equal(val, this.constant) {
+		violate(violations, path, "Expected the value %s.", this.constant.String())
+	}
+
+	switch val.Type() {
+	case value.NUMBER:
+		this.validateNumber(number(val), path, violations)
+	case value.STRING:
+		this.validateString(val.Actual().(string), path, violations)
+	case value.ARRAY:
+		this.validateArray(valueList(val), path, violations)
+	case value.OBJECT:
+		this.validateObject(val, path, violations)
+	}
+
+	if this.ifNode != nil {
+		if this.ifNode.valid(val) {
+			if this.thenNode != nil {
+				this.thenNode.validate(val, path, violations)
+			}
+		} else if this.elseNode != nil {
+			this.elseNode.validate(val, path, violations)
+		}
+	}
+
+	for _, n := range this.allOf {
+		n.validate(val, path, violations)
+	}
+
+	if this.anyOf != nil {
+		found := false
+		for _, n := range this.anyOf {
+			if n.valid(val) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			violate(violations, path, "Value does not match any of the schemas of anyOf.")
+		}
+	}
+
+	if this.oneOf != nil {
+		matches := 0
+		for _, n := range this.oneOf {
+			if n.valid(val) {
+				matches++
+			}
+		}
+
+		if matches != 1 {
+			violate(violations, path, "Value matches %d of the schemas of oneOf, instead of one.", matches)
+		}
+	}
+
+	if this.not != nil && this.not.valid(val) {
+		violate(violations, path, "Value matches the schema of not.")
+	}
+}
+
+/*
+valid returns true if val is valid, without reporting violations.
+*/
+func (this *node) valid(val value.Value) bool {
+	var violations []*Violation
+	this.validate(val, "", &violations)
+	return len(violations) == 0
+}
+
+func (this *node) hasType(val value.Value) bool {
+	name := typeName(val)
+	for _, t := range this.types {
+		if t == name || (t == "number" && name == "integer") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (this *node) validateNumber(n float64, path string, violations *[]*Violation) {
+	if this.multipleOf != nil {
+		q := n / *this.multipleOf
+		if math.IsInf(q, 0) || math.Abs(q-math.Floor(q+0.5)) > 1e-9 {
+			violate(violations, path, "%v is not a multiple of %v.", n, *this.multipleOf)
+		}
+	}
+
+	if this.maximum != nil && n > *this.maximum {
+		violate(violations, path, "%v is greater than the maximum %v.", n, *this.maximum)
+	}
+
+	if this.exclusiveMaximum != nil && n >= *this.exclusiveMaximum {
+		violate(violations, path, "%v is not less than the exclusive maximum %v.", n, *this.exclusiveMaximum)
+	}
+
+	if this.minimum != nil && n < *this.minimum {
+		violate(violations, path, "%v is less than the minimum %v.", n, *this.minimum)
+	}
+
+	if this.exclusiveMinimum != nil && n <= *this.exclusiveMinimum {
+		violate(violations, path, "%v is not greater than the exclusive minimum %v.", n, *this.exclusiveMinimum)
+	}
+}
+
+func (this *node) validateString(s string, path string, violations *[]*Violation) {
+	length := utf8.RuneCountInString(s)
+
+	if this.maxLength != nil && length > *this.maxLength {
+		violate(violations, path, "String is longer than %d characters.", *this.maxLength)
+	}
+
+	if this.minLength != nil && length < *this.minLength {
+		violate(violations, path, "String is shorter than %d characters.", *this.minLength)
+	}
+
+	if this.pattern != nil && !this.pattern.MatchString(s) {
+		violate(violations, path, "String does not match the pattern %s.", this.pattern.String())
+	}
+
+	if this.format != "" {
+		check, ok := _FORMATS[this.format]
+		if ok && !check(s) {
+			violate(violations, path, "String is not a valid %s.", this.format)
+		}
+	}
+}
+
+func (this *node) validateArray(items []value.Value, path string, violations *[]*Violation) {
+	if this.maxItems != nil && len(items) > *this.maxItems {
+		violate(violations, path, "Array has more than %d items.", *this.maxItems)
+	}
+
+	if this.minItems != nil && len(items) < *this.minItems {
+		violate(violations, path, "Array has fewer than %d items.", *this.minItems)
+	}
+
+	if this.uniqueItems {
+	unique:
+		for i := 1; i < len(items); i++ {
+			for j := 0; j < i; j++ {
+				if equal(items[i], items[j]) {
+					violate(violations, path, "Array items %d and %d are equal.", j, i)
+					break unique
+				}
+			}
+		}
+	}
+
+	if this.items != nil {
+		for i, item := range items {
+			this.items.validate(item, path+"/"+strconv.Itoa(i), violations)
+		}
+	} else if this.tupleItems != nil {
+		for i, item := range items {
+			if i < len(this.tupleItems) {
+				this.tupleItems[i].validate(item, path+"/"+strconv.Itoa(i), violations)
+			} else if this.additionalItems != nil {
+				this.additionalItems.validate(item, path+"/"+strconv.Itoa(i), violations)
+			}
+		}
+	}
+
+	if this.contains != nil {
+		found := false
+		for _, item := range items {
+			if this.contains.valid(item) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			violate(violations, path, "Array does not contain an item that matches the schema of contains.")
+		}
+	}
+}
+
+func (this *node) validateObject(val value.Value, path string, violations *[]*Violation) {
+	fields := val.Fields()
+
+	if this.maxProperties != nil && len(fields) > *this.maxProperties {
+		violate(violations, path, "Object has more than %d properties.", *this.maxProperties)
+	}
+
+	if this.minProperties != nil && len(fields) < *this.minProperties {
+		violate(violations, path, "Object has fewer than %d properties.", *this.minProperties)
+	}
+
+	for _, name := range this.required {
+		if _, ok := fields[name]; !ok {
+			violate(violations, path, "Missing required property %s.", name)
+		}
+	}
+
+	for _, name := range sortedNames(fields) {
+		field := value.NewValue(fields[name])
+		at := path + "/" + escape(name)
+
+		if this.propertyNames != nil && !this.propertyNames.valid(value.NewValue(name)) {
+			violate(violations, at, "Property name %s does not match the schema of propertyNames.", name)
+		}
+
+		matched := false
+		if n, ok := this.properties[name]; ok {
+			n.validate(field, at, violations)
+			matched = true
+		}
+
+		for _, pn := range this.patternProperties {
+			if pn.pattern.MatchString(name) {
+				pn.node.validate(field, at, violations)
+				matched = true
+			}
+		}
+
+		if !matched && this.additionalProperties != nil {
+			if this.additionalProperties.always != nil && !*this.additionalProperties.always {
+				violate(violations, at, "Additional property %s is not allowed.", name)
+			} else {
+				this.additionalProperties.validate(field, at, violations)
+			}
+		}
+
+		for _, dependency := range this.dependentRequired[name] {
+			if _, ok := fields[dependency]; !ok {
+				violate(violations, path, "Property %s requires property %s.", name, dependency)
+			}
+		}
+
+		if n, ok := this.dependentSchemas[name]; ok {
+			n.validate(val, path, violations)
+		}
+	}
+}
+
+func violate(violations *[]*Violation, path, format string, args ...interface{}) {
+	*violations = append(*violations, &Violation{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+/*
+typeName returns the JSON Schema type of val. Numbers without a
+fractional part are integers.
+*/
+func typeName(val value.Value) string {
+	switch val.Type() {
+	case value.NULL:
+		return "null"
+	case value.BOOLEAN:
+		return "boolean"
+	case value.NUMBER:
+		n := number(val)
+		if n == math.Trunc(n) && !math.IsInf(n, 0) {
+			return "integer"
+		}
+		return "number"
+	case value.STRING:
+		return "string"
+	case value.ARRAY:
+		return "array"
+	case value.OBJECT:
+		return "object"
+	default:
+		return val.Type().String()
+	}
+}
+
+/*
+equal compares JSON values; numbers are equal if their values are.
+*/
+func equal(a, b value.Value) bool {
+	ta, tb := a.Type(), b.Type()
+	if ta != tb {
+		return false
+	}
+
+	if ta == value.NULL {
+		return true
+	}
+
+	return a.Collate(b) == 0
+}
+
+var _FORMATS = map[string]func(string) bool{
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339Nano, strings.ToUpper(s))
+		return err == nil
+	},
+	"date": func(s string) bool {
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	},
+	"time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339Nano, "2006-01-02T"+strings.ToUpper(s))
+		return err == nil
+	},
+	"email": func(s string) bool {
+		return _EMAIL.MatchString(s)
+	},
+	"hostname": func(s string) bool {
+		return len(s) <= 253 && _HOSTNAME.MatchString(s)
+	},
+	"ipv4": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	},
+	"ipv6": func(s string) bool {
+		return net.ParseIP(s) != nil && strings.Contains(s, ":")
+	},
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	},
+	"uuid": func(s string) bool {
+		return _UUID.MatchString(s)
+	},
+	"regex": func(s string) bool {
+		_, err := regexp.Compile(s)
+		return err == nil
+	},
+}
+
+var _EMAIL = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+var _HOSTNAME = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+var _UUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
@@ -0,0 +1,182 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+
+Package search provides the text analysis, query string parsing and
+inverted index used by the in-process full-text search indexes and by
+the SEARCH() function.
+
+*/
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Analyzer names
+const (
+	KEYWORD  = "keyword"  // whole text as a single term
+	SIMPLE   = "simple"   // standard tokenizer, lowercase
+	STANDARD = "standard" // standard tokenizer, lowercase, stop words
+	ENGLISH  = "english"  // standard tokenizer, lowercase, stop words, stemming
+)
+
+const DEFAULT_ANALYZER = ENGLISH
+
+/*
+Token is a single analyzed term and its position within the
+analyzed text.
+*/
+type Token struct {
+	Term     string
+	Position int
+}
+
+type Tokens []*Token
+
+/*
+Analyzer converts text into the terms that are stored in, and looked
+up from, an inverted index.
+*/
+type Analyzer interface {
+	Name() string
+	Analyze(text string) Tokens
+}
+
+/*
+TokenFilter transforms a single term. Returning the empty string
+removes the term; positions are preserved so that phrases do not
+match across removed terms.
+*/
+type TokenFilter func(term string) string
+
+func GetAnalyzer(name string) (Analyzer, bool) {
+	if name == "" {
+		name = DEFAULT_ANALYZER
+	}
+
+	rv, ok := _ANALYZERS[strings.ToLower(name)]
+	return rv, ok
+}
+
+var _ANALYZERS = map[string]Analyzer{
+	KEYWORD:  &keywordAnalyzer{},
+	SIMPLE:   newChainAnalyzer(SIMPLE, lowercaseFilter),
+	STANDARD: newChainAnalyzer(STANDARD, lowercaseFilter, stopFilter),
+	ENGLISH:  newChainAnalyzer(ENGLISH, lowercaseFilter, stopFilter, Stem),
+}
+
+type keywordAnalyzer struct {
+}
+
+func (this *keywordAnalyzer) Name() string {
+	return KEYWORD
+}
+
+func (this *keywordAnalyzer) Analyze(text string) Tokens {
+	if text == "" {
+		return nil
+	}
+
+	return Tokens{&Token{Term: text, Position: 0}}
+}
+
+/*
+chainAnalyzer runs the standard tokenizer followed by a chain of
+token filters.
+*/
+type chainAnalyzer struct {
+	name    string
+	filters []TokenFilter
+}
+
+func newChainAnalyzer(name string, filters ...TokenFilter) Analyzer {
+	return &chainAnalyzer{
+		name:    name,
+		filters: filters,
+	}
+}
+
+func (this *chainAnalyzer) Name() string {
+	return this.name
+}
+
+func (this *chainAnalyzer) Analyze(text string) Tokens {
+	terms := Tokenize(text)
+	rv := make(Tokens, 0, len(terms))
+
+outer:
+	for pos, term := range terms {
+		for _, filter := range this.filters {
+			term = filter(term)
+			if term == "" {
+				continue outer
+			}
+		}
+
+		rv = append(rv, &Token{Term: term, Position: pos})
+	}
+
+	return rv
+}
+
+/*
+Tokenize implements the standard tokenizer. It splits text on any
+rune that is not a letter or a digit; apostrophes inside a word are
+kept, so that possessives can be handled by the stemmer.
+*/
+func Tokenize(text string) []string {
+	rv := make([]string, 0, 16)
+	runes := []rune(text)
+	start := -1
+
+	for i, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) ||
+			(r == '\'' && start >= 0 && i+1 < len(runes) && unicode.IsLetter(runes[i+1])) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		if start >= 0 {
+			rv = append(rv, string(runes[start:i]))
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		rv = append(rv, string(runes[start:]))
+	}
+
+	return rv
+}
+
+func lowercaseFilter(term string) string {
+	return strings.ToLower(term)
+}
+
+func stopFilter(term string) string {
+	if _STOP_WORDS[term] {
+		return ""
+	}
+
+	return term
+}
+
+var _STOP_WORDS = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "no": true, "not": true, "of": true,
+	"on": true, "or": true, "such": true, "that": true, "the": true, "their": true,
+	"then": true, "there": true, "these": true, "they": true, "this": true, "to": true,
+	"was": true, "will": true, "with": true,
+}
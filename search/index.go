@@ -0,0 +1,426 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BM25 parameters
+const (
+	_K1 = 1.2
+	_B  = 0.75
+)
+
+// Position gap between the values of a multi-valued field, so that
+// phrases do not match across values.
+const _POSITION_GAP = 100
+
+/*
+Fields maps document paths, e.g. "name" or "address.city", to the text
+found at that path. Arrays contribute one value per element to the
+path of the array.
+*/
+type Fields map[string][]string
+
+/*
+Add collects the text of val, which is a JSON-like value as returned
+by value.Value.Actual(), under the path prefix.
+*/
+func (this Fields) Add(prefix string, val interface{}) {
+	switch val := val.(type) {
+	case string:
+		this[prefix] = append(this[prefix], val)
+	case float64:
+		this[prefix] = append(this[prefix], strconv.FormatFloat(val, 'f', -1, 64))
+	case int64:
+		this[prefix] = append(this[prefix], strconv.FormatInt(val, 10))
+	case bool:
+		this[prefix] = append(this[prefix], strconv.FormatBool(val))
+	case []interface{}:
+		for _, v := range val {
+			this.Add(prefix, v)
+		}
+	case map[string]interface{}:
+		for n, v := range val {
+			if prefix != "" {
+				n = prefix + "." + n
+			}
+			this.Add(n, v)
+		}
+	}
+}
+
+/*
+Hit is a matching document and its relevance score.
+*/
+type Hit struct {
+	Id    string
+	Score float64
+}
+
+type Hits []*Hit
+
+/*
+Index is an in-memory inverted index. It is safe for concurrent use.
+*/
+type Index struct {
+	sync.RWMutex
+	analyzer Analyzer
+	lengths  map[string]map[string]int              // doc -> field -> number of terms
+	postings map[string]map[string]map[string][]int // field -> term -> doc -> positions
+	totals   map[string]int                         // field -> total number of terms
+}
+
+func NewIndex(analyzer Analyzer) *Index {
+	return &Index{
+		analyzer: analyzer,
+		lengths:  make(map[string]map[string]int),
+		postings: make(map[string]map[string]map[string][]int),
+		totals:   make(map[string]int),
+	}
+}
+
+func (this *Index) Analyzer() Analyzer {
+	return this.analyzer
+}
+
+func (this *Index) Count() int {
+	this.RLock()
+	defer this.RUnlock()
+	return len(this.lengths)
+}
+
+/*
+Add indexes the fields of a document, replacing any previous
+version of the document.
+*/
+func (this *Index) Add(id string, fields Fields) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.remove(id)
+
+	lengths := make(map[string]int, len(fields))
+	for field, texts := range fields {
+		terms := this.postings[field]
+		if terms == nil {
+			terms = make(map[string]map[string][]int)
+			this.postings[field] = terms
+		}
+
+		offset := 0
+		n := 0
+		for _, text := range texts {
+			tokens := this.analyzer.Analyze(text)
+			last := 0
+			for _, token := range tokens {
+				docs := terms[token.Term]
+				if docs == nil {
+					docs = make(map[string][]int)
+					terms[token.Term] = docs
+				}
+
+				docs[id] = append(docs[id], offset+token.Position)
+				last = token.Position
+			}
+
+			n += len(tokens)
+			offset += last + _POSITION_GAP
+		}
+
+		if n > 0 {
+			lengths[field] = n
+			this.totals[field] += n
+		}
+	}
+
+	this.lengths[id] = lengths
+}
+
+func (this *Index) Remove(id string) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.remove(id)
+}
+
+func (this *Index) remove(id string) {
+	lengths, ok := this.lengths[id]
+	if !ok {
+		return
+	}
+
+	for field, n := range lengths {
+		this.totals[field] -= n
+		terms := this.postings[field]
+		for term, docs := range terms {
+			if _, ok := docs[id]; ok {
+				delete(docs, id)
+				if len(docs) == 0 {
+					delete(terms, term)
+				}
+			}
+		}
+	}
+
+	delete(this.lengths, id)
+}
+
+/*
+Search returns the documents matching the query, in descending order
+of score.
+*/
+func (this *Index) Search(query *Query) Hits {
+	this.RLock()
+	defer this.RUnlock()
+
+	var required map[string]bool
+	scores := make(map[string]float64, 64)
+	excluded := make(map[string]bool)
+	haveMust := false
+
+	for _, clause := range query.clauses {
+		matches := this.matchClause(clause)
+		switch clause.Occur {
+		case MUST_NOT:
+			for id, _ := range matches {
+				excluded[id] = true
+			}
+			continue
+		case MUST:
+			if haveMust {
+				for id, _ := range required {
+					if _, ok := matches[id]; !ok {
+						delete(required, id)
+					}
+				}
+			} else {
+				haveMust = true
+				required = make(map[string]bool, len(matches))
+				for id, _ := range matches {
+					required[id] = true
+				}
+			}
+		}
+
+		for id, score := range matches {
+			scores[id] += score
+		}
+	}
+
+	rv := make(Hits, 0, len(scores))
+	for id, score := range scores {
+		if excluded[id] || (haveMust && !required[id]) {
+			continue
+		}
+
+		rv = append(rv, &Hit{Id: id, Score: score})
+	}
+
+	sort.Sort(rv)
+	return rv
+}
+
+/*
+matchClause returns the score of each document matching the clause.
+*/
+func (this *Index) matchClause(clause *Clause) map[string]float64 {
+	// A clause that is not a phrase may still analyze to several
+	// tokens, e.g. "e-mail"; these are matched as a phrase
+	tokens := this.analyzer.Analyze(clause.Text)
+
+	rv := make(map[string]float64)
+	if len(tokens) == 0 {
+		return rv
+	}
+
+	for field, terms := range this.postings {
+		if clause.Field != "" && field != clause.Field &&
+			!strings.HasPrefix(field, clause.Field+".") {
+			continue
+		}
+
+		for id, score := range this.matchField(field, terms, tokens) {
+			rv[id] += score
+		}
+	}
+
+	return rv
+}
+
+/*
+matchField scores the documents containing all the tokens, in
+sequence, in field.
+*/
+func (this *Index) matchField(field string, terms map[string]map[string][]int, tokens Tokens) map[string]float64 {
+	first := terms[tokens[0].Term]
+	if len(first) == 0 {
+		return nil
+	}
+
+	rv := make(map[string]float64, len(first))
+
+outer:
+	for id, positions := range first {
+		score := 0.0
+		for _, token := range tokens {
+			docs := terms[token.Term]
+			if _, ok := docs[id]; !ok {
+				continue outer
+			}
+
+			score += this.bm25(field, id, len(docs[id]), len(docs))
+		}
+
+		if len(tokens) > 1 && !this.phraseMatch(terms, id, positions, tokens) {
+			continue
+		}
+
+		rv[id] = score
+	}
+
+	return rv
+}
+
+func (this *Index) phraseMatch(terms map[string]map[string][]int, id string,
+	positions []int, tokens Tokens) bool {
+outer:
+	for _, start := range positions {
+		for _, token := range tokens[1:] {
+			want := start + token.Position - tokens[0].Position
+			if !containsInt(terms[token.Term][id], want) {
+				continue outer
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (this *Index) bm25(field, id string, tf, df int) float64 {
+	n := float64(len(this.lengths))
+	idf := math.Log(1.0 + (n-float64(df)+0.5)/(float64(df)+0.5))
+
+	dl := float64(this.lengths[id][field])
+	avgdl := float64(this.totals[field]) / n
+	norm := 1.0
+	if avgdl > 0 {
+		norm = 1.0 - _B + _B*dl/avgdl
+	}
+
+	f := float64(tf)
+	return idf * (f * (_K1 + 1.0)) / (f + _K1*norm)
+}
+
+func containsInt(a []int, v int) bool {
+	for _, i := range a {
+		if i == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Match evaluates the query against a single document, without an
+index.
+*/
+func Match(analyzer Analyzer, query *Query, doc interface{}) bool {
+	fields := make(Fields)
+	fields.Add("", doc)
+
+	index := NewIndex(analyzer)
+	index.Add("", fields)
+	return len(index.Search(query)) > 0
+}
+
+func (this *Index) MarshalJSON() ([]byte, error) {
+	this.RLock()
+	defer this.RUnlock()
+
+	r := map[string]interface{}{
+		"analyzer": this.analyzer.Name(),
+		"lengths":  this.lengths,
+		"postings": this.postings,
+	}
+
+	return json.Marshal(r)
+}
+
+func (this *Index) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		Analyzer string                                 `json:"analyzer"`
+		Lengths  map[string]map[string]int              `json:"lengths"`
+		Postings map[string]map[string]map[string][]int `json:"postings"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	analyzer, ok := GetAnalyzer(_unmarshalled.Analyzer)
+	if !ok {
+		return fmt.Errorf("Unknown search analyzer %s.", _unmarshalled.Analyzer)
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.analyzer = analyzer
+	this.lengths = _unmarshalled.Lengths
+	this.postings = _unmarshalled.Postings
+	this.totals = make(map[string]int)
+
+	if this.lengths == nil {
+		this.lengths = make(map[string]map[string]int)
+	}
+
+	if this.postings == nil {
+		this.postings = make(map[string]map[string]map[string][]int)
+	}
+
+	for _, lengths := range this.lengths {
+		for field, n := range lengths {
+			this.totals[field] += n
+		}
+	}
+
+	return nil
+}
+
+/*
+Implement sort.Interface; higher scores first, then by id.
+*/
+func (this Hits) Len() int {
+	return len(this)
+}
+
+func (this Hits) Less(i, j int) bool {
+	if this[i].Score != this[j].Score {
+		return this[i].Score > this[j].Score
+	}
+
+	return this[i].Id < this[j].Id
+}
+
+func (this Hits) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+}
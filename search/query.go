@@ -0,0 +1,175 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type Occur int
+
+const (
+	SHOULD   Occur = iota // optional; contributes to the score
+	MUST                  // required
+	MUST_NOT              // excluded
+)
+
+/*
+Clause is a single term or phrase of a query string, optionally
+restricted to a field.
+*/
+type Clause struct {
+	Occur  Occur
+	Field  string
+	Text   string
+	Phrase bool
+}
+
+/*
+Query is a parsed query string. The syntax is a whitespace separated
+list of clauses:
+
+	term            matches term in any field
+	"some phrase"   matches the terms in sequence
+	field:term      matches term in field, or in any field below it
+	+clause         the clause is required
+	-clause         the clause must not match
+
+A document matches if it matches every required clause, no excluded
+clause, and, when there are no required clauses, at least one of the
+optional clauses.
+*/
+type Query struct {
+	text    string
+	clauses []*Clause
+}
+
+func ParseQuery(text string) (*Query, error) {
+	rv := &Query{text: text}
+	runes := []rune(text)
+	i := 0
+
+	for {
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+
+		if i >= len(runes) {
+			break
+		}
+
+		clause := &Clause{Occur: SHOULD}
+		switch runes[i] {
+		case '+':
+			clause.Occur = MUST
+			i++
+		case '-':
+			clause.Occur = MUST_NOT
+			i++
+		}
+
+		// Optional field prefix
+		start := i
+		for i < len(runes) && runes[i] != ':' && runes[i] != '"' && !unicode.IsSpace(runes[i]) {
+			i++
+		}
+
+		if i < len(runes) && runes[i] == ':' {
+			clause.Field = string(runes[start:i])
+			if clause.Field == "" {
+				return nil, fmt.Errorf("Missing field name before ':' in search query %s.", text)
+			}
+			i++
+		} else {
+			i = start
+		}
+
+		if i < len(runes) && runes[i] == '"' {
+			i++
+			start = i
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+
+			if i >= len(runes) {
+				return nil, fmt.Errorf("Unterminated phrase in search query %s.", text)
+			}
+
+			clause.Text = string(runes[start:i])
+			clause.Phrase = true
+			i++
+		} else {
+			start = i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+
+			clause.Text = string(runes[start:i])
+		}
+
+		if clause.Text == "" {
+			return nil, fmt.Errorf("Empty clause in search query %s.", text)
+		}
+
+		rv.clauses = append(rv.clauses, clause)
+	}
+
+	if len(rv.clauses) == 0 {
+		return nil, fmt.Errorf("Empty search query.")
+	}
+
+	return rv, nil
+}
+
+func (this *Query) String() string {
+	return this.text
+}
+
+func (this *Query) Clauses() []*Clause {
+	return this.clauses
+}
+
+/*
+Fields returns the distinct field names referenced by the query. The
+empty string denotes clauses that are not restricted to a field.
+*/
+func (this *Query) Fields() []string {
+	seen := make(map[string]bool, len(this.clauses))
+	rv := make([]string, 0, len(this.clauses))
+	for _, clause := range this.clauses {
+		if !seen[clause.Field] {
+			seen[clause.Field] = true
+			rv = append(rv, clause.Field)
+		}
+	}
+
+	return rv
+}
+
+/*
+CoversField returns true if a query on field can be answered from an
+index on fields. The empty field, which matches any field, is always
+covered.
+*/
+func CoversField(fields []string, field string) bool {
+	if field == "" {
+		return true
+	}
+
+	for _, f := range fields {
+		if f == "" || f == field || strings.HasPrefix(field, f+".") {
+			return true
+		}
+	}
+
+	return false
+}
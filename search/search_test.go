@@ -0,0 +1,180 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package search
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	words := map[string]string{
+		"caresses":    "caress",
+		"ponies":      "poni",
+		"cats":        "cat",
+		"feed":        "feed",
+		"agreed":      "agre",
+		"running":     "run",
+		"hopping":     "hop",
+		"happy":       "happi",
+		"relational":  "relat",
+		"conditional": "condit",
+		"generalize":  "gener",
+		"hopeful":     "hope",
+		"goodness":    "good",
+		"adjustment":  "adjust",
+		"controlling": "control",
+		"john's":      "john",
+		"go":          "go",
+	}
+
+	for word, expected := range words {
+		stem := Stem(word)
+		if stem != expected {
+			t.Errorf("Expected stem %s for %s, got %s", expected, word, stem)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	query, err := ParseQuery(`+name:john -"new york" city`)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	clauses := query.Clauses()
+	if len(clauses) != 3 {
+		t.Fatalf("Expected 3 clauses, got %d", len(clauses))
+	}
+
+	if clauses[0].Occur != MUST || clauses[0].Field != "name" || clauses[0].Text != "john" {
+		t.Errorf("Unexpected first clause %v", clauses[0])
+	}
+
+	if clauses[1].Occur != MUST_NOT || !clauses[1].Phrase || clauses[1].Text != "new york" {
+		t.Errorf("Unexpected second clause %v", clauses[1])
+	}
+
+	if clauses[2].Occur != SHOULD || clauses[2].Field != "" || clauses[2].Text != "city" {
+		t.Errorf("Unexpected third clause %v", clauses[2])
+	}
+
+	for _, text := range []string{"", "  ", `"unterminated`, ":term"} {
+		_, err = ParseQuery(text)
+		if err == nil {
+			t.Errorf("Expected error for query %q", text)
+		}
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	analyzer, _ := GetAnalyzer("")
+	index := NewIndex(analyzer)
+
+	docs := map[string]interface{}{
+		"a": map[string]interface{}{"title": "The quick brown fox", "tags": []interface{}{"animals", "fox"}},
+		"b": map[string]interface{}{"title": "Foxes running quickly", "address": map[string]interface{}{"city": "New York"}},
+		"c": map[string]interface{}{"title": "A lazy dog", "address": map[string]interface{}{"city": "York"}},
+	}
+
+	for id, doc := range docs {
+		fields := make(Fields)
+		fields.Add("", doc)
+		index.Add(id, fields)
+	}
+
+	checkHits(t, index, "fox", "a", "b")
+	checkHits(t, index, "title:foxes", "a", "b")
+	checkHits(t, index, `"quick brown"`, "a")
+	checkHits(t, index, `"brown quick"`)
+	checkHits(t, index, "address:york", "b", "c")
+	checkHits(t, index, "address.city:new", "b")
+	checkHits(t, index, "+york -new", "c")
+	checkHits(t, index, "tags:animals", "a")
+
+	// Most relevant first
+	checkHits(t, index, "fox dog", "a", "b", "c")
+	hits := index.Search(mustParse(t, "fox"))
+	if hits[0].Score < hits[1].Score {
+		t.Errorf("Expected hits in descending order of score, got %v, %v", hits[0], hits[1])
+	}
+
+	index.Remove("a")
+	checkHits(t, index, "fox", "b")
+
+	// Round trip
+	bytes, err := json.Marshal(index)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	var restored Index
+	err = json.Unmarshal(bytes, &restored)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if restored.Count() != 2 {
+		t.Errorf("Expected 2 documents, got %d", restored.Count())
+	}
+
+	checkHits(t, &restored, "fox", "b")
+	checkHits(t, &restored, "york", "b", "c")
+}
+
+func TestMatch(t *testing.T) {
+	doc := map[string]interface{}{"name": "Dave Smith", "age": 46.0}
+	english, _ := GetAnalyzer(ENGLISH)
+	keyword, _ := GetAnalyzer(KEYWORD)
+
+	if !Match(english, mustParse(t, "name:dave"), doc) {
+		t.Errorf("Expected match on name:dave")
+	}
+
+	if !Match(english, mustParse(t, "age:46"), doc) {
+		t.Errorf("Expected match on age:46")
+	}
+
+	if Match(keyword, mustParse(t, "dave"), doc) {
+		t.Errorf("Expected no keyword match on dave")
+	}
+
+	if !Match(keyword, mustParse(t, `"Dave Smith"`), doc) {
+		t.Errorf("Expected keyword match on Dave Smith")
+	}
+}
+
+func mustParse(t *testing.T, text string) *Query {
+	query, err := ParseQuery(text)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing %s: %v", text, err)
+	}
+
+	return query
+}
+
+func checkHits(t *testing.T, index *Index, text string, ids ...string) {
+	hits := index.Search(mustParse(t, text))
+	if len(hits) != len(ids) {
+		t.Errorf("Expected %d hits for %s, got %d", len(ids), text, len(hits))
+		return
+	}
+
+	found := make(map[string]bool, len(hits))
+	for _, hit := range hits {
+		found[hit.Id] = true
+	}
+
+	for _, id := range ids {
+		if !found[id] {
+			t.Errorf("Expected hit %s for %s", id, text)
+		}
+	}
+}
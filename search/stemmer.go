@@ -0,0 +1,323 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package search
+
+import (
+	"strings"
+)
+
+/*
+Stem reduces an English word to its stem using the Porter stemming
+algorithm. Words that are not plain lowercase ASCII are returned
+unchanged.
+*/
+func Stem(term string) string {
+	term = strings.TrimSuffix(term, "'s")
+	if len(term) <= 2 {
+		return term
+	}
+
+	for i := 0; i < len(term); i++ {
+		if term[i] < 'a' || term[i] > 'z' {
+			return term
+		}
+	}
+
+	s := &stemmer{b: []byte(term), k: len(term) - 1}
+	s.step1ab()
+	if s.k > 0 {
+		s.step1c()
+		s.step2()
+		s.step3()
+		s.step4()
+		s.step5()
+	}
+
+	return string(s.b[0 : s.k+1])
+}
+
+/*
+stemmer holds the word being stemmed in b[0..k]; j is a general
+offset used while matching suffixes.
+*/
+type stemmer struct {
+	b []byte
+	k int
+	j int
+}
+
+// cons is true if b[i] is a consonant.
+func (this *stemmer) cons(i int) bool {
+	switch this.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		if i == 0 {
+			return true
+		}
+		return !this.cons(i - 1)
+	default:
+		return true
+	}
+}
+
+// m measures the number of consonant sequences between 0 and j.
+func (this *stemmer) m() int {
+	n := 0
+	i := 0
+	for {
+		if i > this.j {
+			return n
+		}
+		if !this.cons(i) {
+			break
+		}
+		i++
+	}
+
+	i++
+	for {
+		for {
+			if i > this.j {
+				return n
+			}
+			if this.cons(i) {
+				break
+			}
+			i++
+		}
+
+		i++
+		n++
+		for {
+			if i > this.j {
+				return n
+			}
+			if !this.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+	}
+}
+
+// vowelInStem is true if b[0..j] contains a vowel.
+func (this *stemmer) vowelInStem() bool {
+	for i := 0; i <= this.j; i++ {
+		if !this.cons(i) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// doublec is true if b[j-1..j] is a double consonant.
+func (this *stemmer) doublec(j int) bool {
+	if j < 1 || this.b[j] != this.b[j-1] {
+		return false
+	}
+
+	return this.cons(j)
+}
+
+/*
+cvc is true if b[i-2..i] is consonant-vowel-consonant and the second
+consonant is not w, x or y. This is used when restoring an e at the
+end of a short word, e.g. cav(e), lov(e), hop(e).
+*/
+func (this *stemmer) cvc(i int) bool {
+	if i < 2 || !this.cons(i) || this.cons(i-1) || !this.cons(i-2) {
+		return false
+	}
+
+	switch this.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+
+	return true
+}
+
+// ends is true if b[0..k] ends with s; it then sets j to the stem end.
+func (this *stemmer) ends(s string) bool {
+	l := len(s)
+	if l > this.k+1 {
+		return false
+	}
+
+	if string(this.b[this.k-l+1:this.k+1]) != s {
+		return false
+	}
+
+	this.j = this.k - l
+	return true
+}
+
+// setTo replaces b[j+1..k] with s.
+func (this *stemmer) setTo(s string) {
+	this.b = append(this.b[0:this.j+1], s...)
+	this.k = this.j + len(s)
+}
+
+func (this *stemmer) r(s string) {
+	if this.m() > 0 {
+		this.setTo(s)
+	}
+}
+
+// replace applies the first rule whose suffix matches.
+func (this *stemmer) replace(rules []string) {
+	for i := 0; i < len(rules); i += 2 {
+		if this.ends(rules[i]) {
+			this.r(rules[i+1])
+			return
+		}
+	}
+}
+
+// step1ab removes plurals and -ed or -ing.
+func (this *stemmer) step1ab() {
+	if this.b[this.k] == 's' {
+		if this.ends("sses") {
+			this.k -= 2
+		} else if this.ends("ies") {
+			this.setTo("i")
+		} else if this.b[this.k-1] != 's' {
+			this.k--
+		}
+	}
+
+	if this.ends("eed") {
+		if this.m() > 0 {
+			this.k--
+		}
+	} else if (this.ends("ed") || this.ends("ing")) && this.vowelInStem() {
+		this.k = this.j
+		if this.ends("at") {
+			this.setTo("ate")
+		} else if this.ends("bl") {
+			this.setTo("ble")
+		} else if this.ends("iz") {
+			this.setTo("ize")
+		} else if this.doublec(this.k) {
+			this.k--
+			switch this.b[this.k] {
+			case 'l', 's', 'z':
+				this.k++
+			}
+		} else if this.j = this.k; this.m() == 1 && this.cvc(this.k) {
+			this.setTo("e")
+		}
+	}
+}
+
+// step1c turns terminal y to i when there is another vowel in the stem.
+func (this *stemmer) step1c() {
+	if this.ends("y") && this.vowelInStem() {
+		this.b[this.k] = 'i'
+	}
+}
+
+// step2 maps double suffixes to single ones.
+func (this *stemmer) step2() {
+	if this.k < 1 {
+		return
+	}
+
+	rules, ok := _STEP2[this.b[this.k-1]]
+	if ok {
+		this.replace(rules)
+	}
+}
+
+var _STEP2 = map[byte][]string{
+	'a': {"ational", "ate", "tional", "tion"},
+	'c': {"enci", "ence", "anci", "ance"},
+	'e': {"izer", "ize"},
+	'l': {"bli", "ble", "alli", "al", "entli", "ent", "eli", "e", "ousli", "ous"},
+	'o': {"ization", "ize", "ation", "ate", "ator", "ate"},
+	's': {"alism", "al", "iveness", "ive", "fulness", "ful", "ousness", "ous"},
+	't': {"aliti", "al", "iviti", "ive", "biliti", "ble"},
+	'g': {"logi", "log"},
+}
+
+// step3 deals with -ic-, -full, -ness etc.
+func (this *stemmer) step3() {
+	rules, ok := _STEP3[this.b[this.k]]
+	if ok {
+		this.replace(rules)
+	}
+}
+
+var _STEP3 = map[byte][]string{
+	'e': {"icate", "ic", "ative", "", "alize", "al"},
+	'i': {"iciti", "ic"},
+	'l': {"ical", "ic", "ful", ""},
+	's': {"ness", ""},
+}
+
+// step4 takes off -ant, -ence etc., in context <c>vcvc<v>.
+func (this *stemmer) step4() {
+	if this.k < 1 {
+		return
+	}
+
+	matched := false
+	switch this.b[this.k-1] {
+	case 'o':
+		if this.ends("ion") && this.j >= 0 && (this.b[this.j] == 's' || this.b[this.j] == 't') {
+			matched = true
+		} else {
+			matched = this.ends("ou")
+		}
+	default:
+		for _, suffix := range _STEP4[this.b[this.k-1]] {
+			if this.ends(suffix) {
+				matched = true
+				break
+			}
+		}
+	}
+
+	if matched && this.m() > 1 {
+		this.k = this.j
+	}
+}
+
+var _STEP4 = map[byte][]string{
+	'a': {"al"},
+	'c': {"ance", "ence"},
+	'e': {"er"},
+	'i': {"ic"},
+	'l': {"able", "ible"},
+	'n': {"ant", "ement", "ment", "ent"},
+	's': {"ism"},
+	't': {"ate", "iti"},
+	'u': {"ous"},
+	'v': {"ive"},
+	'z': {"ize"},
+}
+
+// step5 removes a final -e if m() > 1, and changes -ll to -l if m() > 1.
+func (this *stemmer) step5() {
+	this.j = this.k
+	if this.b[this.k] == 'e' {
+		a := this.m()
+		if a > 1 || (a == 1 && !this.cvc(this.k-1)) {
+			this.k--
+		}
+	}
+
+	if this.b[this.k] == 'l' && this.doublec(this.k) && this.m() > 1 {
+		this.k--
+	}
+}
@@ -0,0 +1,266 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package server_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/couchbase/query/accounting"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mem"
+	"github.com/couchbase/query/server"
+)
+
+func newAdviseServer(t *testing.T) *server.Server {
+	store, er := mem.NewStore("mem:orders")
+	if er != nil {
+		t.Fatalf("Error creating datastore: %v", er)
+	}
+
+	srvr := startTestServer(t, store)
+	setup := []string{
+		"CREATE PRIMARY INDEX ON orders",
+		"CREATE INDEX ix_total ON orders(total)",
+		"CREATE INDEX ix_cover ON orders(status, total)",
+		`CREATE INDEX ix_new ON orders(total) WHERE status = "new"`,
+		"CREATE INDEX ix_items ON orders(DISTINCT ARRAY i.sku FOR i IN items END)",
+	}
+	for _, statement := range setup {
+		_, err := runTestRequest(srvr, statement, datastore.UNBOUNDED)
+		if err != nil {
+			t.Fatalf("Error running %s: %v", statement, err)
+		}
+	}
+
+	return srvr
+}
+
+const (
+	_PRIMARY_REJECTED = "the primary index is only used when no secondary index qualifies"
+	_STATUS_REJECTED  = "the leading index key `status` is not constrained by the query predicate"
+	_TOTAL_REJECTED   = "the leading index key `total` is not constrained by the query predicate"
+	_ITEMS_REJECTED   = "the leading index key (distinct (array (`i`.`sku`) for `i` in `items` end)) " +
+		"is not constrained by the query predicate"
+	_NEW_REJECTED = "the index condition is not implied by the query predicate"
+)
+
+func TestAdvise(t *testing.T) {
+	srvr := newAdviseServer(t)
+
+	tests := []struct {
+		statement       string
+		scan            string
+		indexes         map[string]string // Used, used and covering, or the reason for the rejection
+		recommendations []string
+	}{
+		// Plain secondary index
+		{
+			statement: "SELECT id FROM orders WHERE total > 15",
+			scan:      "IndexScan",
+			indexes: map[string]string{
+				"#primary": _PRIMARY_REJECTED,
+				"ix_total": "used",
+				"ix_cover": _STATUS_REJECTED,
+				"ix_new":   _NEW_REJECTED,
+				"ix_items": _ITEMS_REJECTED,
+			},
+			recommendations: []string{
+				"CREATE INDEX `adv_total_id` ON `default`:`orders`(`total`, `id`)",
+			},
+		},
+		// Covering index, which needs no advice
+		{
+			statement: `SELECT total FROM orders WHERE status = "shipped" AND total > 15`,
+			scan:      "IndexScan",
+			indexes: map[string]string{
+				"#primary": _PRIMARY_REJECTED,
+				"ix_total": "another index constrains more index keys",
+				"ix_cover": "covering",
+				"ix_new":   _NEW_REJECTED,
+				"ix_items": _ITEMS_REJECTED,
+			},
+			recommendations: []string{},
+		},
+		// Partial index, whose condition is implied by the predicate
+		{
+			statement: `SELECT id FROM orders WHERE status = "new" AND total > 15`,
+			scan:      "IntersectScan",
+			indexes: map[string]string{
+				"#primary": _PRIMARY_REJECTED,
+				"ix_total": "another index constrains more index keys",
+				"ix_cover": "used",
+				"ix_new":   "used",
+				"ix_items": _ITEMS_REJECTED,
+			},
+			recommendations: []string{
+				"CREATE INDEX `adv_status_total_id` ON `default`:`orders`(`status`, `total`, `id`)",
+				"CREATE INDEX `adv_total_id_partial` ON `default`:`orders`(`total`, `id`) WHERE (`status` = \"new\")",
+			},
+		},
+		// Array index for an ANY predicate, which is not advised again
+		{
+			statement: `SELECT id FROM orders WHERE ANY i IN items SATISFIES i.sku = "a" END`,
+			scan:      "IndexScan",
+			indexes: map[string]string{
+				"#primary": _PRIMARY_REJECTED,
+				"ix_total": _TOTAL_REJECTED,
+				"ix_cover": _STATUS_REJECTED,
+				"ix_new":   _NEW_REJECTED,
+				"ix_items": "used",
+			},
+			recommendations: []string{
+				"CREATE INDEX `adv_items_sku_items_id` ON `default`:`orders`" +
+					"((distinct (array (`i`.`sku`) for `i` in `items` end)), `items`, `id`)",
+			},
+		},
+		{
+			statement: `SELECT id FROM orders WHERE ANY i IN items SATISFIES i.qty = 2 END`,
+			scan:      "PrimaryScan",
+			indexes: map[string]string{
+				"#primary": "used",
+				"ix_total": _TOTAL_REJECTED,
+				"ix_cover": _STATUS_REJECTED,
+				"ix_new":   _NEW_REJECTED,
+				"ix_items": _ITEMS_REJECTED,
+			},
+			recommendations: []string{
+				"CREATE INDEX `adv_items_qty` ON `default`:`orders`" +
+					"((distinct (array (`i`.`qty`) for `i` in `items` end)))",
+				"CREATE INDEX `adv_items_qty_items_id` ON `default`:`orders`" +
+					"((distinct (array (`i`.`qty`) for `i` in `items` end)), `items`, `id`)",
+			},
+		},
+		// Indexes excluded by USE INDEX
+		{
+			statement: `SELECT id FROM orders USE INDEX (ix_cover) WHERE status = "new" AND total > 15`,
+			scan:      "IndexScan",
+			indexes: map[string]string{
+				"#primary": "not listed in USE INDEX",
+				"ix_total": "not listed in USE INDEX",
+				"ix_cover": "used",
+				"ix_new":   "not listed in USE INDEX",
+				"ix_items": "not listed in USE INDEX",
+			},
+			recommendations: []string{
+				"CREATE INDEX `adv_status_total_id` ON `default`:`orders`(`status`, `total`, `id`)",
+				"CREATE INDEX `adv_total_id_partial` ON `default`:`orders`(`total`, `id`) WHERE (`status` = \"new\")",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := runTestRequest(srvr, "ADVISE "+test.statement, datastore.UNBOUNDED)
+		if err != nil || len(results) != 1 {
+			t.Errorf("Expected advice for %s, got %v, error %v", test.statement, results, err)
+			continue
+		}
+
+		advice := results[0].(map[string]interface{})
+		if advice["keyspace"] != "default:orders" || advice["current_scan"] != test.scan {
+			t.Errorf("Expected %s of default:orders for %s, got %v of %v", test.scan, test.statement,
+				advice["current_scan"], advice["keyspace"])
+		}
+
+		indexes := make(map[string]string, len(test.indexes))
+		for _, index := range advice["indexes"].([]interface{}) {
+			index := index.(map[string]interface{})
+			status, _ := index["status"].(string)
+			if status == "rejected" {
+				status, _ = index["reason"].(string)
+			} else if index["covering"] == true {
+				status = "covering"
+			}
+			indexes[index["name"].(string)] = status
+		}
+		if !reflect.DeepEqual(indexes, test.indexes) {
+			t.Errorf("Expected indexes %v for %s, got %v", test.indexes, test.statement, indexes)
+		}
+
+		recommendations := make([]string, 0, len(test.recommendations))
+		for _, rec := range advice["recommendations"].([]interface{}) {
+			recommendations = append(recommendations, rec.(map[string]interface{})["statement"].(string))
+		}
+		if !reflect.DeepEqual(recommendations, test.recommendations) {
+			t.Errorf("Expected recommendations %v for %s, got %v", test.recommendations, test.statement,
+				recommendations)
+		}
+	}
+
+	_, err := runTestRequest(srvr, "ADVISE INSERT INTO orders VALUES (\"o1\", {})", datastore.UNBOUNDED)
+	if err == nil {
+		t.Errorf("Expected an error advising INSERT")
+	}
+}
+
+func TestAdviseCompletedRequests(t *testing.T) {
+	srvr := newAdviseServer(t)
+	accounting.RequestsInit(0, 8)
+
+	completed := []struct {
+		statement string
+		elapsed   time.Duration
+	}{
+		{`SELECT id FROM orders WHERE customer = "c1"`, time.Second},
+		{`SELECT id FROM orders WHERE customer = "c1"`, time.Second},
+		{`SELECT id FROM orders WHERE customer = "c2"`, 2 * time.Second},
+		{"SELECT id FROM orders WHERE total > 15", time.Second},
+		{`INSERT INTO orders (KEY, VALUE) VALUES ("o9", {})`, time.Second},
+		{"SELECT id FROM orders WHERE", time.Second},
+	}
+	for i, request := range completed {
+		accounting.LogRequest(request.elapsed, request.elapsed, 0, 0, 0, request.statement,
+			nil, nil, nil, nil, "completed", strconv.Itoa(i), "", "unbounded")
+	}
+
+	// Most frequent first, and statements that cannot be advised are skipped
+	customers := []interface{}{
+		`SELECT id FROM orders WHERE customer = "c1"`,
+		`SELECT id FROM orders WHERE customer = "c2"`,
+	}
+	expected := []interface{}{
+		map[string]interface{}{
+			"statement":          "CREATE INDEX `adv_customer_id` ON `default`:`orders`(`customer`, `id`)",
+			"kind":               "covering",
+			"keyspace":           "default:orders",
+			"count":              float64(3),
+			"total_elapsed_time": "4s",
+			"queries":            customers,
+		},
+		map[string]interface{}{
+			"statement":          "CREATE INDEX `adv_customer` ON `default`:`orders`(`customer`)",
+			"kind":               "index",
+			"keyspace":           "default:orders",
+			"count":              float64(3),
+			"total_elapsed_time": "4s",
+			"queries":            customers,
+		},
+		map[string]interface{}{
+			"statement":          "CREATE INDEX `adv_total_id` ON `default`:`orders`(`total`, `id`)",
+			"kind":               "covering",
+			"keyspace":           "default:orders",
+			"count":              float64(1),
+			"total_elapsed_time": "1s",
+			"queries":            []interface{}{"SELECT id FROM orders WHERE total > 15"},
+		},
+	}
+
+	results, err := runTestRequest(srvr, "ADVISE ON system:completed_requests", datastore.UNBOUNDED)
+	if err != nil || !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v, got %v, error %v", expected, results, err)
+	}
+
+	_, err = runTestRequest(srvr, "ADVISE ON orders", datastore.UNBOUNDED)
+	if err == nil {
+		t.Errorf("Expected an error advising a keyspace other than system:completed_requests")
+	}
+}
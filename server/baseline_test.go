@@ -0,0 +1,154 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package server_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/file"
+	"github.com/couchbase/query/datastore/mem"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+)
+
+func baselineUses(statement string) int32 {
+	for _, baseline := range plan.SnapshotBaselines() {
+		if baseline["statement"] == statement {
+			return baseline["uses"].(int32)
+		}
+	}
+	return -1
+}
+
+func TestPlanBaselineFallback(t *testing.T) {
+	store, er := mem.NewStore("mem:orders")
+	if er != nil {
+		t.Fatalf("Error creating datastore: %v", er)
+	}
+
+	// Baselines verify their indexes against the global datastore
+	datastore.SetDatastore(store)
+	defer datastore.SetDatastore(nil)
+	srvr := startTestServer(t, store)
+
+	setup := []string{
+		"CREATE PRIMARY INDEX ON orders",
+		"CREATE INDEX ix_total ON orders(total)",
+		`INSERT INTO orders (KEY, VALUE) VALUES ("o1", {"id": "o1", "total": 10})`,
+		`INSERT INTO orders (KEY, VALUE) VALUES ("o2", {"id": "o2", "total": 20})`,
+		`INSERT INTO orders (KEY, VALUE) VALUES ("o3", {"id": "o3", "total": 30})`,
+	}
+	for _, statement := range setup {
+		_, err := runTestRequest(srvr, statement, datastore.UNBOUNDED)
+		if err != nil {
+			t.Fatalf("Error running %s: %v", statement, err)
+		}
+	}
+
+	query := "SELECT id FROM orders WHERE total > 15 ORDER BY id"
+	_, err := runTestRequest(srvr, "CREATE PLAN BASELINE FOR "+query, datastore.UNBOUNDED)
+	if err != nil {
+		t.Fatalf("Error creating baseline: %v", err)
+	}
+	defer plan.DeleteBaseline(store, "default", query)
+
+	// Statements differing only in whitespace share the baseline
+	expected := []interface{}{
+		map[string]interface{}{"id": "o2"},
+		map[string]interface{}{"id": "o3"},
+	}
+	results, warnings, err := runTestRequestWarnings(srvr, "SELECT id  FROM orders\n WHERE total > 15 ORDER BY id;",
+		datastore.UNBOUNDED)
+	if err != nil || len(warnings) != 0 || !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v, got %v, warnings %v, error %v", expected, results, warnings, err)
+	}
+	if uses := baselineUses(query); uses != 1 {
+		t.Errorf("Expected 1 use of the baseline, got %d", uses)
+	}
+
+	// Once its index is dropped, the baseline is ignored with a warning and the statement is replanned
+	_, err = runTestRequest(srvr, "DROP INDEX orders.ix_total", datastore.UNBOUNDED)
+	if err != nil {
+		t.Fatalf("Error dropping index: %v", err)
+	}
+
+	results, warnings, err = runTestRequestWarnings(srvr, query, datastore.UNBOUNDED)
+	if err != nil || !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v, got %v, error %v", expected, results, err)
+	}
+	code := errors.NewInvalidPlanBaselineWarning("").Code()
+	if len(warnings) != 1 || warnings[0].Code() != code {
+		t.Errorf("Expected warning %d, got %v", code, warnings)
+	}
+	if uses := baselineUses(query); uses != 1 {
+		t.Errorf("Expected 1 use of the baseline, got %d", uses)
+	}
+}
+
+func TestPlanBaselineRestart(t *testing.T) {
+	srvr, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+	datastore.SetDatastore(srvr.Datastore())
+	defer datastore.SetDatastore(nil)
+
+	query := "SELECT id FROM orders WHERE qty > 1"
+	_, err := runTestRequest(srvr, "CREATE PLAN BASELINE FOR "+query, datastore.UNBOUNDED)
+	if err != nil {
+		t.Fatalf("Error creating baseline: %v", err)
+	}
+
+	expected := []interface{}{
+		map[string]interface{}{"id": "o2"},
+	}
+	results, err := runTestRequest(srvr, query, datastore.UNBOUNDED)
+	if err != nil || !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v, got %v, error %v", expected, results, err)
+	}
+	if uses := baselineUses(query); uses != 1 {
+		t.Errorf("Expected 1 use of the baseline, got %d", uses)
+	}
+
+	// A server started on the same files reloads the baseline, and decodes its plan when it is used
+	store, er := file.NewDatastore(dir)
+	if er != nil {
+		t.Fatalf("Error creating datastore: %v", er)
+	}
+	datastore.SetDatastore(store)
+	srvr = startTestServer(t, store)
+
+	if uses := baselineUses(query); uses != 0 {
+		t.Errorf("Expected a reloaded baseline with no uses, got %d", uses)
+	}
+	results, warnings, err := runTestRequestWarnings(srvr, query, datastore.UNBOUNDED)
+	if err != nil || len(warnings) != 0 || !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v, got %v, warnings %v, error %v", expected, results, warnings, err)
+	}
+	if uses := baselineUses(query); uses != 1 {
+		t.Errorf("Expected 1 use of the baseline, got %d", uses)
+	}
+
+	// A dropped baseline is not reloaded
+	_, err = runTestRequest(srvr, "DROP PLAN BASELINE FOR "+query, datastore.UNBOUNDED)
+	if err != nil {
+		t.Fatalf("Error dropping baseline: %v", err)
+	}
+
+	store, er = file.NewDatastore(dir)
+	if er != nil {
+		t.Fatalf("Error creating datastore: %v", er)
+	}
+	startTestServer(t, store)
+	if uses := baselineUses(query); uses != -1 {
+		t.Errorf("Expected no baseline after a restart, got one with %d uses", uses)
+	}
+}
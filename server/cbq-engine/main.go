@@ -35,7 +35,7 @@ import (
 	"github.com/couchbase/query/util"
 )
 
-var DATASTORE = flag.String("datastore", "", "Datastore address (http://URL or dir:PATH or mock:)")
+var DATASTORE = flag.String("datastore", "", "Datastore address (http://URL or dir:PATH or mock: or mem: or n1ql:URL or federated:JSON)")
 var CONFIGSTORE = flag.String("configstore", "stub:", "Configuration store address (http://URL or stub:)")
 var ACCTSTORE = flag.String("acctstore", "gometrics:", "Accounting store address (http://URL or stub:)")
 var NAMESPACE = flag.String("namespace", "default", "Default namespace")
@@ -74,6 +74,11 @@ var MEM_PROFILE = flag.String("memprofile", "", "write memory profile to this fi
 var COMPLETED_THRESHOLD = flag.Int("completed-threshold", 1000, "cache completed query lasting longer than this many milliseconds")
 var COMPLETED_LIMIT = flag.Int("completed-limit", 4000, "maximum number of completed requests")
 
+// Result cache
+var RESULT_CACHE_LIMIT = flag.Int("result-cache-limit", 0, "Maximum number of cached statement results; use zero to disable")
+var RESULT_CACHE_MEMORY = flag.Int("result-cache-memory", 64*1024*1024, "Maximum size in bytes of cached results; use zero or negative value to disable")
+var RESULT_CACHE_TTL = flag.Duration("result-cache-ttl", 60*time.Second, "Time to live of cached results, e.g. 5s; use zero or negative value to disable")
+
 func main() {
 	HideConsole(true)
 	defer HideConsole(false)
@@ -153,6 +158,9 @@ func main() {
 	// Start the completed requests log
 	accounting.RequestsInit(*COMPLETED_THRESHOLD, *COMPLETED_LIMIT)
 
+	// Start the result cache
+	server.ResultCacheInit(*RESULT_CACHE_LIMIT, *RESULT_CACHE_MEMORY, *RESULT_CACHE_TTL)
+
 	numProcs := runtime.GOMAXPROCS(0)
 	channel := make(server.RequestChannel, *REQUEST_CAP*numProcs)
 	plusChannel := make(server.RequestChannel, *REQUEST_CAP*numProcs)
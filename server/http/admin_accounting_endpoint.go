@@ -28,6 +28,7 @@ const (
 	accountingPrefix = adminPrefix + "/stats"
 	vitalsPrefix     = adminPrefix + "/vitals"
 	preparedsPrefix  = adminPrefix + "/prepareds"
+	baselinesPrefix  = adminPrefix + "/baselines"
 	requestsPrefix   = adminPrefix + "/active_requests"
 	completedsPrefix = adminPrefix + "/completed_requests"
 	indexesPrefix    = adminPrefix + "/indexes"
@@ -58,6 +59,9 @@ func (this *HttpEndpoint) registerAccountingHandlers() {
 	preparedsHandler := func(w http.ResponseWriter, req *http.Request) {
 		this.wrapAPI(w, req, doPrepareds)
 	}
+	baselinesHandler := func(w http.ResponseWriter, req *http.Request) {
+		this.wrapAPI(w, req, doBaselines)
+	}
 	requestsHandler := func(w http.ResponseWriter, req *http.Request) {
 		this.wrapAPI(w, req, doActiveRequests)
 	}
@@ -91,6 +95,7 @@ func (this *HttpEndpoint) registerAccountingHandlers() {
 		vitalsPrefix:                          {handler: vitalsHandler, methods: []string{"GET"}},
 		preparedsPrefix:                       {handler: preparedsHandler, methods: []string{"GET"}},
 		preparedsPrefix + "/{name}":           {handler: preparedHandler, methods: []string{"GET", "DELETE"}},
+		baselinesPrefix:                       {handler: baselinesHandler, methods: []string{"GET", "POST", "DELETE"}},
 		requestsPrefix:                        {handler: requestsHandler, methods: []string{"GET"}},
 		requestsPrefix + "/{request}":         {handler: requestHandler, methods: []string{"GET", "DELETE"}},
 		completedsPrefix:                      {handler: completedsHandler, methods: []string{"GET"}},
@@ -221,6 +226,27 @@ func doPrepareds(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Reques
 	}
 }
 
+func doBaselines(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request) (interface{}, errors.Error) {
+	switch req.Method {
+	case "GET":
+		return plan.SnapshotBaselines(), nil
+	case "POST":
+		err := endpoint.server.CreatePlanBaseline(req.FormValue("statement"), req.FormValue("namespace"))
+		if err != nil {
+			return nil, err
+		}
+		return true, nil
+	case "DELETE":
+		err := endpoint.server.DropPlanBaseline(req.FormValue("statement"), req.FormValue("namespace"))
+		if err != nil {
+			return nil, err
+		}
+		return true, nil
+	default:
+		return nil, nil
+	}
+}
+
 func doActiveRequest(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request) (interface{}, errors.Error) {
 	vars := mux.Vars(req)
 	requestId := vars["request"]
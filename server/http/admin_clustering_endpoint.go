@@ -326,6 +326,9 @@ const (
 	_CMPTHRESHOLD    = "completed-threshold"
 	_CMPLIMIT        = "completed-limit"
 	_PRETTY          = "pretty"
+	_RCLIMIT         = "result-cache-limit"
+	_RCMEMORY        = "result-cache-memory"
+	_RCTTL           = "result-cache-ttl"
 )
 
 type checker func(interface{}) bool
@@ -370,6 +373,9 @@ var _CHECKERS = map[string]checker{
 	_CMPTHRESHOLD:    checkNumber,
 	_CMPLIMIT:        checkNumber,
 	_PRETTY:          checkBool,
+	_RCLIMIT:         checkNumber,
+	_RCMEMORY:        checkNumber,
+	_RCTTL:           checkNumber,
 }
 
 type setter func(*server.Server, interface{})
@@ -435,6 +441,18 @@ var _SETTERS = map[string]setter{
 		value, _ := o.(bool)
 		s.SetPretty(value)
 	},
+	_RCLIMIT: func(s *server.Server, o interface{}) {
+		value, _ := o.(float64)
+		server.ResultCacheSetLimit(int(value))
+	},
+	_RCMEMORY: func(s *server.Server, o interface{}) {
+		value, _ := o.(float64)
+		server.ResultCacheSetMemory(int(value))
+	},
+	_RCTTL: func(s *server.Server, o interface{}) {
+		value, _ := o.(float64)
+		server.ResultCacheSetTTL(time.Duration(value))
+	},
 }
 
 func doSettings(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request) (interface{}, errors.Error) {
@@ -493,6 +511,9 @@ func fillSettings(settings map[string]interface{}, srvr *server.Server) map[stri
 	settings[_CMPTHRESHOLD] = accounting.RequestsThreshold()
 	settings[_CMPLIMIT] = accounting.RequestsLimit()
 	settings[_PRETTY] = srvr.Pretty()
+	settings[_RCLIMIT] = server.ResultCacheLimit()
+	settings[_RCMEMORY] = server.ResultCacheMemory()
+	settings[_RCTTL] = server.ResultCacheTTL()
 	return settings
 }
 
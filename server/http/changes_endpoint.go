@@ -0,0 +1,232 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/couchbase/query/changes"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/expression/parser"
+)
+
+const (
+	changesPrefix = "/query/changes"
+)
+
+var _CHANGES_KEEPALIVE = 30 * time.Second
+
+func (this *HttpEndpoint) registerChangesHandlers() {
+	this.mux.HandleFunc(changesPrefix, this.serveChanges).Methods("GET")
+}
+
+/*
+serveChanges streams the mutations of keyspaces made through this
+engine. Parameters:
+
+	keyspaces	comma separated keyspaces, optionally namespace qualified
+	filter		N1QL expression on the document; META().id is the key
+	since		comma separated ns:ks=seqno positions to resume after
+	format		json (one event per line, the default) or sse
+
+For server-sent events, the id of each event holds the positions, and
+the Last-Event-ID header resumes from them.
+*/
+func (this *HttpEndpoint) serveChanges(w http.ResponseWriter, req *http.Request) {
+	sse := req.FormValue("format") == "sse" ||
+		(req.FormValue("format") == "" && strings.Contains(req.Header.Get("Accept"), "text/event-stream"))
+	if format := req.FormValue("format"); format != "" && format != "sse" && format != "json" {
+		writeChangesError(w, http.StatusBadRequest, errors.NewServiceErrorUnrecognizedValue("format", format))
+		return
+	}
+
+	keyspaces, err := this.changesKeyspaces(req.FormValue("keyspaces"))
+	if err != nil {
+		writeChangesError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	creds, err := getCredentials(&urlArgs{req: req}, req.Header["Authorization"])
+	if err != nil {
+		writeChangesError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	privs := make(datastore.Privileges, len(keyspaces))
+	for _, name := range keyspaces {
+		privs[name] = datastore.PRIV_READ
+	}
+
+	err = this.server.Datastore().Authorize(privs, creds)
+	if err != nil {
+		writeChangesError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var filter expression.Expression
+	if f := req.FormValue("filter"); f != "" {
+		var e error
+		filter, e = parser.Parse(f)
+		if e != nil {
+			writeChangesError(w, http.StatusBadRequest, errors.NewServiceErrorBadValue(e, "filter"))
+			return
+		}
+	}
+
+	since := req.FormValue("since")
+	if id := req.Header.Get("Last-Event-ID"); sse && id != "" {
+		since = id
+	}
+
+	positions, e := changes.ParsePositions(since)
+	if e != nil {
+		writeChangesError(w, http.StatusBadRequest, errors.NewServiceErrorBadValue(e, "since"))
+		return
+	}
+
+	sub, err := changes.DefaultBus().Subscribe(keyspaces, filter, positions)
+	if err != nil {
+		writeChangesError(w, http.StatusGone, err)
+		return
+	}
+	defer sub.Close()
+
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	flush()
+
+	var closeNotify <-chan bool
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		closeNotify = notifier.CloseNotify()
+	}
+
+	keepalive := time.NewTicker(_CHANGES_KEEPALIVE)
+	defer keepalive.Stop()
+
+	for {
+		positions = sub.Positions()
+		events, err := sub.Read()
+		for _, event := range events {
+			positions[event.Keyspace] = event.Seqno
+			if writeChange(w, sse, event, positions) != nil {
+				return
+			}
+		}
+
+		if err != nil {
+			buf, _ := json.Marshal(map[string]interface{}{"error": err})
+			if sse {
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", buf)
+			} else {
+				fmt.Fprintf(w, "%s\n", buf)
+			}
+			flush()
+			return
+		}
+
+		if len(events) > 0 {
+			flush()
+		}
+
+		select {
+		case <-sub.Ready():
+		case <-keepalive.C:
+			if sse {
+				_, e = w.Write([]byte(": keepalive\n\n"))
+			} else {
+				_, e = w.Write([]byte("\n"))
+			}
+			if e != nil {
+				return
+			}
+			flush()
+		case <-closeNotify:
+			return
+		}
+	}
+}
+
+func (this *HttpEndpoint) changesKeyspaces(s string) ([]string, errors.Error) {
+	if s == "" {
+		return nil, errors.NewServiceErrorMissingValue("keyspaces")
+	}
+
+	names := strings.Split(s, ",")
+	keyspaces := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		namespace := this.server.Namespace()
+		if i := strings.Index(name, ":"); i >= 0 {
+			namespace, name = name[:i], name[i+1:]
+		}
+
+		ns, err := this.server.Datastore().NamespaceByName(namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = ns.KeyspaceByName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		keyspaces = append(keyspaces, changes.KeyspaceName(namespace, name))
+	}
+
+	return keyspaces, nil
+}
+
+func writeChange(w http.ResponseWriter, sse bool, event *changes.Event, positions map[string]uint64) error {
+	buf, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if !sse {
+		_, err = w.Write(append(buf, '\n'))
+		return err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "id: %s\nevent: %s\ndata: %s\n\n", changes.FormatPositions(positions), event.Op, buf)
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+func writeChangesError(w http.ResponseWriter, status int, err errors.Error) {
+	buf, er := json.Marshal(err)
+	if er != nil {
+		http.Error(w, er.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf)
+}
@@ -184,6 +184,7 @@ func (this *HttpEndpoint) registerHandlers(staticPath string) {
 
 	this.registerClusterHandlers()
 	this.registerAccountingHandlers()
+	this.registerChangesHandlers()
 	this.registerStaticHandlers(staticPath)
 }
 
@@ -106,6 +106,7 @@ func (this *httpRequest) Failed(srvr *server.Server) {
 	this.writeClientContextID(prefix)
 	this.writeErrors(prefix, indent)
 	this.writeWarnings(prefix, indent)
+	this.writeMutationTokens(prefix, indent)
 	this.writeState("", prefix)
 	this.writeMetrics(srvr.Metrics(), prefix, indent)
 	this.writeString("\n}\n")
@@ -275,6 +276,7 @@ func (this *httpRequest) writeSuffix(metrics bool, state server.State, prefix, i
 	return this.writeString("\n") && this.writeString(prefix) && this.writeString("]") &&
 		this.writeErrors(prefix, indent) &&
 		this.writeWarnings(prefix, indent) &&
+		this.writeMutationTokens(prefix, indent) &&
 		this.writeState(state, prefix) &&
 		this.writeMetrics(metrics, prefix, indent) &&
 		this.writeString("\n}\n")
@@ -372,6 +374,41 @@ loop:
 	return this.writeString("]")
 }
 
+/*
+writeMutationTokens writes the last mutation token of each keyspace
+the request mutated, as a scan vector that can be passed back in
+scan_vectors.
+*/
+func (this *httpRequest) writeMutationTokens(prefix, indent string) bool {
+	tokens := this.MutationTokens()
+	if len(tokens) == 0 {
+		return true
+	}
+
+	m := make(map[string]interface{}, len(tokens))
+	for keyspace, token := range tokens {
+		position := strconv.FormatUint(uint64(token.Position()), 10)
+		m[keyspace] = map[string]interface{}{
+			position: []interface{}{token.Value(), token.Guard()},
+		}
+	}
+
+	var er error
+	var bytes []byte
+
+	if prefix == "" && indent == "" {
+		bytes, er = json.Marshal(m)
+	} else {
+		bytes, er = json.MarshalIndent(m, prefix, indent)
+	}
+	if er != nil {
+		return false
+	}
+
+	return this.writeString(",\n") && this.writeString(prefix) &&
+		this.writeString("\"mutationTokens\": ") && this.writeString(string(bytes))
+}
+
 func (this *httpRequest) writeError(err errors.Error, count int, prefix, indent string) bool {
 
 	newPrefix := prefix + indent
@@ -60,6 +60,7 @@ type Request interface {
 	Pretty() value.Tristate
 	ScanConsistency() datastore.ScanConsistency
 	ScanVectorSource() timestamp.ScanVectorSource
+	ScanWait() time.Duration
 	RequestTime() time.Time
 	ServiceTime() time.Time
 	Output() execution.Output
@@ -164,6 +165,7 @@ type BaseRequest struct {
 	stopResult     chan bool // stop consuming results
 	stopExecute    chan bool // stop executing request
 	timings        plan.Operator
+	mutationTokens map[string]timestamp.Entry // by keyspace
 }
 
 type requestIDImpl struct {
@@ -314,6 +316,13 @@ func (this *BaseRequest) ScanVectorSource() timestamp.ScanVectorSource {
 	return this.consistency.ScanVectorSource()
 }
 
+func (this *BaseRequest) ScanWait() time.Duration {
+	if this.consistency == nil {
+		return 0
+	}
+	return this.consistency.ScanWait()
+}
+
 func (this *BaseRequest) RequestTime() time.Time {
 	return this.requestTime
 }
@@ -418,6 +427,34 @@ func (this *BaseRequest) MutationCount() uint64 {
 	return atomic.LoadUint64(&this.mutationCount)
 }
 
+/*
+AddMutationToken keeps the latest mutation token of each keyspace.
+*/
+func (this *BaseRequest) AddMutationToken(keyspace string, token timestamp.Entry) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.mutationTokens == nil {
+		this.mutationTokens = make(map[string]timestamp.Entry)
+	}
+
+	last, ok := this.mutationTokens[keyspace]
+	if !ok || last.Guard() != token.Guard() || last.Value() < token.Value() {
+		this.mutationTokens[keyspace] = token
+	}
+}
+
+func (this *BaseRequest) MutationTokens() map[string]timestamp.Entry {
+	this.RLock()
+	defer this.RUnlock()
+
+	rv := make(map[string]timestamp.Entry, len(this.mutationTokens))
+	for keyspace, token := range this.mutationTokens {
+		rv[keyspace] = token
+	}
+	return rv
+}
+
 func (this *BaseRequest) SetSortCount(i uint64) {
 	atomic.StoreUint64(&this.sortCount, i)
 }
@@ -437,13 +474,14 @@ func (this *BaseRequest) AddPhaseOperator(p execution.Phases) {
 func (this *BaseRequest) FmtPhaseCounts() map[string]interface{} {
 	var p map[string]interface{} = nil
 
-	for k, d := range this.phaseStats {
-		if d.count > 0 {
+	for k := range this.phaseStats {
+		count := atomic.LoadUint64(&this.phaseStats[k].count)
+		if count > 0 {
 			if p == nil {
 				p = make(map[string]interface{},
 					execution.PHASES)
 			}
-			p[execution.Phases(k).String()] = d.count
+			p[execution.Phases(k).String()] = count
 		}
 	}
 	return p
@@ -452,13 +490,14 @@ func (this *BaseRequest) FmtPhaseCounts() map[string]interface{} {
 func (this *BaseRequest) FmtPhaseOperators() map[string]interface{} {
 	var p map[string]interface{} = nil
 
-	for k, d := range this.phaseStats {
-		if d.operators > 0 {
+	for k := range this.phaseStats {
+		operators := atomic.LoadUint64(&this.phaseStats[k].operators)
+		if operators > 0 {
 			if p == nil {
 				p = make(map[string]interface{},
 					execution.PHASES)
 			}
-			p[execution.Phases(k).String()] = d.operators
+			p[execution.Phases(k).String()] = operators
 		}
 	}
 	return p
@@ -0,0 +1,511 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/accounting"
+	"github.com/couchbase/query/changes"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/execution"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/value"
+)
+
+/*
+The result cache keeps the results of read-only statements, so that
+identical requests, such as the queries of a dashboard repeated every
+few seconds, are answered without running the statement again.
+
+Entries are keyed by namespace, statement or prepared name, and the
+argument values of the request. Each entry records the change sequence
+numbers of the keyspaces referenced by its plan when the statement
+ran; any mutation of those keyspaces through the engine makes the
+entry stale. Entries also expire after the TTL, which bounds the
+staleness of mutations made outside the engine.
+
+The cache is bounded by number of entries and by the total size of the
+cached results, and evicts the least recently used entries. A limit of
+zero entries disables it.
+*/
+
+type ResultCacheEntry struct {
+	Id        string
+	Namespace string
+	Statement string
+	Keyspaces map[string]uint64
+	Results   [][]byte
+	SortCount uint64
+	Size      int
+	Created   time.Time
+	LastUse   time.Time
+	Hits      int64
+
+	key        string
+	privileges datastore.Privileges
+	elem       *list.Element
+}
+
+type cachedResults struct {
+	sync.Mutex
+	limit   int
+	memory  int
+	ttl     time.Duration
+	size    int
+	entries map[string]*ResultCacheEntry // by key
+	ids     map[string]*ResultCacheEntry // by id
+	lru     *list.List
+}
+
+var resultCache = newResultCache(0, 0, 0)
+
+func newResultCache(limit, memory int, ttl time.Duration) *cachedResults {
+	return &cachedResults{
+		limit:   limit,
+		memory:  memory,
+		ttl:     ttl,
+		entries: make(map[string]*ResultCacheEntry),
+		ids:     make(map[string]*ResultCacheEntry),
+		lru:     list.New(),
+	}
+}
+
+/*
+ResultCacheInit empties the cache and sets its limits. The cache is
+reset in place, since requests that are still running may add their
+results to it.
+*/
+func ResultCacheInit(limit, memory int, ttl time.Duration) {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	resultCache.limit = limit
+	resultCache.memory = memory
+	resultCache.ttl = ttl
+	resultCache.size = 0
+	resultCache.entries = make(map[string]*ResultCacheEntry)
+	resultCache.ids = make(map[string]*ResultCacheEntry)
+	resultCache.lru = list.New()
+}
+
+func ResultCacheLimit() int {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	return resultCache.limit
+}
+
+func ResultCacheSetLimit(limit int) {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	resultCache.limit = limit
+	resultCache.shrink()
+}
+
+func ResultCacheMemory() int {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	return resultCache.memory
+}
+
+func ResultCacheSetMemory(memory int) {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	resultCache.memory = memory
+	resultCache.shrink()
+}
+
+func ResultCacheTTL() time.Duration {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	return resultCache.ttl
+}
+
+func ResultCacheSetTTL(ttl time.Duration) {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	resultCache.ttl = ttl
+}
+
+func ResultCacheCount() int {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	return len(resultCache.entries)
+}
+
+func ResultCacheIds() []string {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	ids := make([]string, 0, len(resultCache.ids))
+	for id, _ := range resultCache.ids {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+/*
+Call f for every entry. f must not call back into the cache.
+*/
+func ResultCacheForEach(f func(string, *ResultCacheEntry)) {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	for id, entry := range resultCache.ids {
+		f(id, entry)
+	}
+}
+
+func ResultCacheDo(id string, f func(*ResultCacheEntry)) {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	entry := resultCache.ids[id]
+	if entry != nil {
+		f(entry)
+	}
+}
+
+func ResultCacheDelete(id string) bool {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	entry := resultCache.ids[id]
+	if entry == nil {
+		return false
+	}
+	resultCache.remove(entry)
+	return true
+}
+
+/*
+Returns the entry for key, if it is neither expired nor stale.
+Expired and stale entries are dropped.
+*/
+func (this *cachedResults) get(key string) *ResultCacheEntry {
+	this.Lock()
+	defer this.Unlock()
+
+	entry := this.entries[key]
+	if entry == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if this.ttl > 0 && now.Sub(entry.Created) > this.ttl {
+		this.remove(entry)
+		return nil
+	}
+
+	bus := changes.DefaultBus()
+	for keyspace, seqno := range entry.Keyspaces {
+		if bus.Seqno(keyspace) != seqno {
+			this.remove(entry)
+			return nil
+		}
+	}
+
+	entry.Hits++
+	entry.LastUse = now
+	this.lru.MoveToFront(entry.elem)
+	return entry
+}
+
+func (this *cachedResults) add(entry *ResultCacheEntry) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.limit <= 0 || (this.memory > 0 && entry.Size > this.memory) {
+		return
+	}
+
+	old := this.entries[entry.key]
+	if old != nil {
+		this.remove(old)
+	}
+
+	entry.elem = this.lru.PushFront(entry)
+	this.entries[entry.key] = entry
+	this.ids[entry.Id] = entry
+	this.size += entry.Size
+	this.shrink()
+}
+
+func (this *cachedResults) remove(entry *ResultCacheEntry) {
+	this.lru.Remove(entry.elem)
+	delete(this.entries, entry.key)
+	delete(this.ids, entry.Id)
+	this.size -= entry.Size
+}
+
+// Evict least recently used entries until within limits
+func (this *cachedResults) shrink() {
+	for this.lru.Len() > 0 && (len(this.entries) > this.limit ||
+		(this.memory > 0 && this.size > this.memory)) {
+		this.remove(this.lru.Back().Value.(*ResultCacheEntry))
+	}
+}
+
+/*
+Returns the cache key of the request, and whether its results may be
+cached at all: the request must be read-only, not require consistency
+with prior mutations, not call volatile functions, and not involve
+system keyspaces or plans, whose results are not tracked by the
+keyspace changes.
+*/
+func resultCacheKey(request Request, namespace string, prepared *plan.Prepared) (string, bool) {
+	if ResultCacheLimit() <= 0 || !prepared.Readonly() || prepared.Volatile() ||
+		request.ScanConsistency() != datastore.UNBOUNDED {
+		return "", false
+	}
+
+	privs, ok := cacheablePlan(prepared.Operator)
+	if !ok {
+		return "", false
+	}
+
+	for keyspace, _ := range privs {
+		if strings.HasPrefix(keyspace, "#system:") {
+			return "", false
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(namespace)
+	buf.WriteByte(0)
+	if prepared.Name() != "" {
+		buf.WriteString(prepared.Name())
+		buf.WriteByte(0)
+		buf.WriteString(prepared.Text())
+	} else {
+		buf.WriteString(request.Statement())
+	}
+	buf.WriteByte(0)
+
+	args, err := json.Marshal(request.NamedArgs())
+	if err != nil {
+		return "", false
+	}
+	buf.Write(args)
+	buf.WriteByte(0)
+
+	args, err = json.Marshal(request.PositionalArgs())
+	if err != nil {
+		return "", false
+	}
+	buf.Write(args)
+
+	return buf.String(), true
+}
+
+/*
+Returns the privileges of a statement plan, and false for statements
+that describe plans or indexes rather than return data.
+*/
+func cacheablePlan(op plan.Operator) (datastore.Privileges, bool) {
+	switch op := op.(type) {
+	case *plan.Sequence:
+		children := op.Children()
+		if len(children) == 0 {
+			return nil, true
+		}
+		return cacheablePlan(children[0])
+	case *plan.Authorize:
+		_, ok := cacheablePlan(op.Child())
+		return op.Privileges(), ok
+	case *plan.OptimHints:
+		return cacheablePlan(op.Child())
+	case *plan.Explain, *plan.Prepare, *plan.Advise:
+		return nil, false
+	default:
+		return nil, true
+	}
+}
+
+/*
+Answers the request from the cache, if possible. The privileges of the
+cached statement are checked against the credentials of the request.
+*/
+func (this *Server) serveCachedResult(request Request, prepared *plan.Prepared, key string) bool {
+	entry := resultCache.get(key)
+	if entry == nil {
+		this.recordResultCache(false)
+		return false
+	}
+
+	this.recordResultCache(true)
+
+	if len(entry.privileges) > 0 {
+		err := this.datastore.Authorize(entry.privileges, request.Credentials())
+		if err != nil {
+			request.Fail(err)
+			request.Failed(this)
+			return true
+		}
+	}
+
+	go request.Execute(this, prepared.Signature(), make(chan bool, 1))
+
+	output := request.Output()
+	output.SetSortCount(entry.SortCount)
+	for _, result := range entry.Results {
+		if !output.Result(value.NewValue(result)) {
+			break
+		}
+	}
+	output.CloseResults()
+	return true
+}
+
+func (this *Server) recordResultCache(hit bool) {
+	if this.acctstore == nil {
+		return
+	}
+
+	name := accounting.RESULT_CACHE_MISSES
+	if hit {
+		name = accounting.RESULT_CACHE_HITS
+	}
+
+	reg := this.acctstore.MetricRegistry()
+	if reg != nil {
+		reg.Counter(name).Inc(1)
+	}
+}
+
+/*
+resultCapture forwards the results of a request to its output and
+keeps a copy of them, to be added to the cache if the request
+completes without errors or warnings.
+*/
+type resultCapture struct {
+	execution.Output
+	sync.Mutex
+	entry  *ResultCacheEntry
+	limit  int
+	failed bool
+	closed bool
+}
+
+/*
+Starts capturing the output of a request. The sequence numbers of the
+referenced keyspaces are recorded before the statement runs, so that
+mutations made while it runs invalidate the entry.
+*/
+func newResultCapture(output execution.Output, request Request, namespace string,
+	prepared *plan.Prepared, key string) *resultCapture {
+	privs, _ := cacheablePlan(prepared.Operator)
+
+	bus := changes.DefaultBus()
+	keyspaces := make(map[string]uint64, len(privs))
+	for keyspace, _ := range privs {
+		keyspaces[keyspace] = bus.Seqno(keyspace)
+	}
+
+	statement := request.Statement()
+	if statement == "" {
+		statement = prepared.Text()
+	}
+
+	return &resultCapture{
+		Output: output,
+		entry: &ResultCacheEntry{
+			Namespace:  namespace,
+			Statement:  statement,
+			Keyspaces:  keyspaces,
+			key:        key,
+			privileges: privs,
+		},
+		limit: ResultCacheMemory(),
+	}
+}
+
+func (this *resultCapture) Result(item value.Value) bool {
+	this.Lock()
+	if !this.failed {
+		bytes, err := item.MarshalJSON()
+		if err != nil || (this.limit > 0 && this.entry.Size+len(bytes) > this.limit) {
+			this.failed = true
+			this.entry.Results = nil
+		} else {
+			this.entry.Results = append(this.entry.Results, bytes)
+			this.entry.Size += len(bytes)
+		}
+	}
+	this.Unlock()
+
+	if !this.Output.Result(item) {
+		this.fail()
+		return false
+	}
+	return true
+}
+
+func (this *resultCapture) CloseResults() {
+	this.Lock()
+	this.closed = true
+	this.Unlock()
+	this.Output.CloseResults()
+}
+
+func (this *resultCapture) Fatal(err errors.Error) {
+	this.fail()
+	this.Output.Fatal(err)
+}
+
+func (this *resultCapture) Error(err errors.Error) {
+	this.fail()
+	this.Output.Error(err)
+}
+
+func (this *resultCapture) Warning(wrn errors.Error) {
+	this.fail()
+	this.Output.Warning(wrn)
+}
+
+func (this *resultCapture) fail() {
+	this.Lock()
+	this.failed = true
+	this.entry.Results = nil
+	this.Unlock()
+}
+
+/*
+Adds the captured results to the cache, if the request ran to
+completion.
+*/
+func (this *resultCapture) done(request Request) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.failed || !this.closed {
+		return
+	}
+
+	switch request.State() {
+	case RUNNING, SUCCESS, COMPLETED:
+	default:
+		return
+	}
+
+	id, err := util.UUID()
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	this.entry.Id = id
+	this.entry.SortCount = this.Output.SortCount()
+	this.entry.Created = now
+	this.entry.LastUse = now
+	resultCache.add(this.entry)
+}
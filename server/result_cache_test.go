@@ -0,0 +1,391 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package server_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/couchbase/query/accounting"
+	acct_stub "github.com/couchbase/query/accounting/stub"
+	config_stub "github.com/couchbase/query/clustering/stub"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/file"
+	"github.com/couchbase/query/datastore/system"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/execution"
+	"github.com/couchbase/query/logging"
+	log_resolver "github.com/couchbase/query/logging/resolver"
+	"github.com/couchbase/query/server"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+func init() {
+	logger, _ := log_resolver.NewLogger("golog")
+	logging.SetLogger(logger)
+}
+
+type testRequest struct {
+	*server.BaseRequest
+	results  []interface{}
+	err      errors.Error
+	warnings []errors.Error
+	done     chan bool
+}
+
+func (this *testRequest) Output() execution.Output {
+	return this
+}
+
+func (this *testRequest) Fail(err errors.Error) {
+	this.err = err
+	this.Stop(server.FATAL)
+}
+
+func (this *testRequest) Failed(srvr *server.Server) {
+	close(this.done)
+}
+
+func (this *testRequest) Expire(state server.State, timeout time.Duration) {
+	this.Stop(state)
+}
+
+func (this *testRequest) Execute(srvr *server.Server, signature value.Value, stopNotify chan bool) {
+	defer close(this.done)
+
+	this.NotifyStop(stopNotify)
+	for item := range this.Results() {
+		var result interface{}
+		bytes, _ := item.MarshalJSON()
+		json.Unmarshal(bytes, &result)
+		this.results = append(this.results, result)
+	}
+
+	select {
+	case err := <-this.Errors():
+		this.err = err
+	default:
+	}
+
+	for len(this.Warnings()) > 0 {
+		this.warnings = append(this.warnings, <-this.Warnings())
+	}
+
+	this.SetState(server.COMPLETED)
+}
+
+/*
+Counts the metrics of the server; the other accounting is stubbed.
+*/
+type testAcctstore struct {
+	acct_stub.AccountingStoreStub
+	registry *testRegistry
+}
+
+func (this *testAcctstore) MetricRegistry() accounting.MetricRegistry {
+	return this.registry
+}
+
+type testRegistry struct {
+	acct_stub.MetricRegistryStub
+	sync.Mutex
+	counters map[string]*testCounter
+}
+
+func (this *testRegistry) Counter(name string) accounting.Counter {
+	this.Lock()
+	defer this.Unlock()
+	counter := this.counters[name]
+	if counter == nil {
+		counter = &testCounter{}
+		this.counters[name] = counter
+	}
+	return counter
+}
+
+type testCounter struct {
+	acct_stub.CounterStub
+	count int64
+}
+
+func (this *testCounter) Inc(amount int64) {
+	atomic.AddInt64(&this.count, amount)
+}
+
+func (this *testCounter) Count() int64 {
+	return atomic.LoadInt64(&this.count)
+}
+
+type testScanConfig struct {
+	consistency datastore.ScanConsistency
+}
+
+func (this *testScanConfig) ScanConsistency() datastore.ScanConsistency {
+	return this.consistency
+}
+
+func (this *testScanConfig) ScanWait() time.Duration {
+	return 0
+}
+
+func (this *testScanConfig) ScanVectorSource() timestamp.ScanVectorSource {
+	return &testVectorSource{}
+}
+
+type testVectorSource struct {
+}
+
+func (this *testVectorSource) ScanVector(namespace_id string, keyspace_name string) timestamp.Vector {
+	return nil
+}
+
+func (this *testVectorSource) Type() int32 {
+	return timestamp.NO_VECTORS
+}
+
+func newTestServer(t *testing.T) (*server.Server, string) {
+	dir, err := ioutil.TempDir("", "result_cache")
+	if err != nil {
+		t.Fatalf("Error creating directory: %v", err)
+	}
+
+	docs := map[string]string{
+		"orders/o1.json":   `{"id": "o1", "qty": 1}`,
+		"orders/o2.json":   `{"id": "o2", "qty": 2}`,
+		"products/p1.json": `{"id": "p1", "name": "pen"}`,
+	}
+	for name, doc := range docs {
+		path := filepath.Join(dir, "default", name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(doc), 0644); err != nil {
+			t.Fatalf("Error writing document: %v", err)
+		}
+	}
+
+	store, er := file.NewDatastore(dir)
+	if er != nil {
+		t.Fatalf("Error creating datastore: %v", er)
+	}
+
+	return startTestServer(t, store), dir
+}
+
+func startTestServer(t *testing.T, store datastore.Datastore) *server.Server {
+	sys, er := system.NewDatastore(store)
+	if er != nil {
+		t.Fatalf("Error creating system datastore: %v", er)
+	}
+
+	config, _ := config_stub.NewConfigurationStore()
+	acctstore := &testAcctstore{registry: &testRegistry{counters: make(map[string]*testCounter)}}
+	srvr, er := server.NewServer(store, sys, config, acctstore, "default", false,
+		make(server.RequestChannel, 1), make(server.RequestChannel, 1), 1, 1, 1, 0, false, false, false, false)
+	if er != nil {
+		t.Fatalf("Error creating server: %v", er)
+	}
+
+	go srvr.Serve()
+	return srvr
+}
+
+func runTestRequest(srvr *server.Server, statement string, consistency datastore.ScanConsistency) ([]interface{}, errors.Error) {
+	results, _, err := runTestRequestWarnings(srvr, statement, consistency)
+	return results, err
+}
+
+func runTestRequestWarnings(srvr *server.Server, statement string, consistency datastore.ScanConsistency) (
+	[]interface{}, []errors.Error, errors.Error) {
+	base := server.NewBaseRequest(statement, nil, nil, nil, "default", 1, value.NONE, value.NONE,
+		value.TRUE, value.FALSE, &testScanConfig{consistency}, "", nil)
+	request := &testRequest{
+		BaseRequest: base,
+		done:        make(chan bool),
+	}
+
+	srvr.Channel() <- request
+	<-request.done
+	return request.results, request.warnings, request.err
+}
+
+func resultCacheCounts(srvr *server.Server) (int64, int64) {
+	reg := srvr.AccountingStore().MetricRegistry()
+	return reg.Counter(accounting.RESULT_CACHE_HITS).Count(),
+		reg.Counter(accounting.RESULT_CACHE_MISSES).Count()
+}
+
+func TestResultCacheInvalidation(t *testing.T) {
+	srvr, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+	server.ResultCacheInit(10, 0, time.Minute)
+	defer server.ResultCacheInit(0, 0, 0)
+
+	query := "SELECT id, qty FROM orders ORDER BY id"
+	first, err := runTestRequest(srvr, query, datastore.UNBOUNDED)
+	if err != nil || len(first) != 2 {
+		t.Fatalf("Expected 2 results, got %v, error %v", first, err)
+	}
+	if server.ResultCacheCount() != 1 {
+		t.Errorf("Expected 1 cached result, got %d", server.ResultCacheCount())
+	}
+
+	second, err := runTestRequest(srvr, query, datastore.UNBOUNDED)
+	if err != nil || !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected cached results %v, got %v, error %v", first, second, err)
+	}
+	if hits, misses := resultCacheCounts(srvr); hits != 1 || misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d and %d", hits, misses)
+	}
+
+	// A mutation of another keyspace does not invalidate the entry
+	_, err = runTestRequest(srvr, `UPDATE products SET name = "ink"`, datastore.UNBOUNDED)
+	if err != nil {
+		t.Fatalf("Error updating products: %v", err)
+	}
+	runTestRequest(srvr, query, datastore.UNBOUNDED)
+	if hits, misses := resultCacheCounts(srvr); hits != 2 || misses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %d and %d", hits, misses)
+	}
+
+	_, err = runTestRequest(srvr, `UPDATE orders SET qty = 5 WHERE id = "o1"`, datastore.UNBOUNDED)
+	if err != nil {
+		t.Fatalf("Error updating orders: %v", err)
+	}
+
+	third, err := runTestRequest(srvr, query, datastore.UNBOUNDED)
+	if err != nil || len(third) != 2 {
+		t.Fatalf("Expected 2 results, got %v, error %v", third, err)
+	}
+	if hits, misses := resultCacheCounts(srvr); hits != 2 || misses != 2 {
+		t.Errorf("Expected 2 hits and 2 misses, got %d and %d", hits, misses)
+	}
+	qty := third[0].(map[string]interface{})["qty"]
+	if qty != float64(5) {
+		t.Errorf("Expected updated quantity 5, got %v", qty)
+	}
+}
+
+func TestResultCacheExpiry(t *testing.T) {
+	srvr, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+	server.ResultCacheInit(10, 0, 50*time.Millisecond)
+	defer server.ResultCacheInit(0, 0, 0)
+
+	query := "SELECT id FROM orders"
+	runTestRequest(srvr, query, datastore.UNBOUNDED)
+	runTestRequest(srvr, query, datastore.UNBOUNDED)
+	if hits, misses := resultCacheCounts(srvr); hits != 1 || misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d and %d", hits, misses)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	runTestRequest(srvr, query, datastore.UNBOUNDED)
+	if hits, misses := resultCacheCounts(srvr); hits != 1 || misses != 2 {
+		t.Errorf("Expected 1 hit and 2 misses after expiry, got %d and %d", hits, misses)
+	}
+}
+
+func TestResultCacheEviction(t *testing.T) {
+	srvr, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+	server.ResultCacheInit(2, 0, time.Minute)
+	defer server.ResultCacheInit(0, 0, 0)
+
+	runTestRequest(srvr, "SELECT id FROM orders", datastore.UNBOUNDED)
+	runTestRequest(srvr, "SELECT qty FROM orders", datastore.UNBOUNDED)
+	runTestRequest(srvr, "SELECT name FROM products", datastore.UNBOUNDED)
+	if server.ResultCacheCount() != 2 {
+		t.Errorf("Expected 2 cached results, got %d", server.ResultCacheCount())
+	}
+
+	// The least recently used entry was evicted
+	runTestRequest(srvr, "SELECT id FROM orders", datastore.UNBOUNDED)
+	if hits, misses := resultCacheCounts(srvr); hits != 0 || misses != 4 {
+		t.Errorf("Expected 0 hits and 4 misses, got %d and %d", hits, misses)
+	}
+
+	runTestRequest(srvr, "SELECT id FROM orders", datastore.UNBOUNDED)
+	if hits, misses := resultCacheCounts(srvr); hits != 1 || misses != 4 {
+		t.Errorf("Expected 1 hit and 4 misses, got %d and %d", hits, misses)
+	}
+}
+
+func TestResultCacheMemory(t *testing.T) {
+	srvr, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	// Each result set is 22 bytes, and a single one fits
+	server.ResultCacheInit(10, 40, time.Minute)
+	defer server.ResultCacheInit(0, 0, 0)
+
+	runTestRequest(srvr, "SELECT id FROM orders", datastore.UNBOUNDED)
+	runTestRequest(srvr, "SELECT id FROM orders WHERE qty > 0", datastore.UNBOUNDED)
+	if server.ResultCacheCount() != 1 {
+		t.Errorf("Expected 1 cached result, got %d", server.ResultCacheCount())
+	}
+
+	server.ResultCacheForEach(func(id string, entry *server.ResultCacheEntry) {
+		if entry.Statement != "SELECT id FROM orders WHERE qty > 0" || entry.Size != 22 {
+			t.Errorf("Expected the last result of 22 bytes to be cached, got %s of %d bytes",
+				entry.Statement, entry.Size)
+		}
+	})
+
+	// Results larger than the cache are never added
+	server.ResultCacheSetMemory(30)
+	runTestRequest(srvr, "SELECT id, qty FROM orders", datastore.UNBOUNDED)
+	if server.ResultCacheCount() != 1 {
+		t.Errorf("Expected the oversize result not to be cached")
+	}
+}
+
+func TestResultCacheBypass(t *testing.T) {
+	srvr, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+	server.ResultCacheInit(10, 0, time.Minute)
+	defer server.ResultCacheInit(0, 0, 0)
+
+	bypassed := []string{
+		`INSERT INTO products (KEY, VALUE) VALUES ("p2", {"id": "p2"})`,
+		"SELECT name FROM system:keyspaces",
+		"EXPLAIN SELECT id FROM orders",
+		"PREPARE SELECT id FROM orders",
+		"ADVISE SELECT id FROM orders WHERE qty > 1",
+		"SELECT NOW_STR() AS now, id FROM orders",
+		"SELECT RANDOM() AS r",
+		"SELECT (SELECT RAW UUID() FROM orders) AS ids",
+	}
+	for _, statement := range bypassed {
+		_, err := runTestRequest(srvr, statement, datastore.UNBOUNDED)
+		if err != nil {
+			t.Errorf("Error running %s: %v", statement, err)
+		}
+		if server.ResultCacheCount() != 0 {
+			t.Errorf("Expected %s not to be cached", statement)
+			server.ResultCacheInit(10, 0, time.Minute)
+		}
+	}
+
+	runTestRequest(srvr, "SELECT id FROM orders", datastore.SCAN_PLUS)
+	if server.ResultCacheCount() != 0 {
+		t.Errorf("Expected request_plus request not to be cached")
+	}
+
+	if hits, misses := resultCacheCounts(srvr); hits != 0 || misses != 0 {
+		t.Errorf("Expected no cache lookups, got %d hits and %d misses", hits, misses)
+	}
+}
@@ -102,6 +102,16 @@ func NewServer(store datastore.Datastore, sys datastore.Datastore, config cluste
 	//	}
 	//
 	//	rv.systemstore = sys
+
+	// Resume the maintenance of incremental materialized views
+	go execution.MaintainViews(store, sys)
+
+	// Reload the plan baselines persisted in the datastore
+	err := plan.LoadBaselines(store)
+	if err != nil {
+		return nil, err
+	}
+
 	return rv, nil
 }
 
@@ -113,6 +123,10 @@ func (this *Server) Systemstore() datastore.Datastore {
 	return this.systemstore
 }
 
+func (this *Server) Namespace() string {
+	return this.namespace
+}
+
 func (this *Server) ConfigurationStore() clustering.ConfigurationStore {
 	return this.configstore
 }
@@ -410,8 +424,8 @@ func (this *Server) serviceRequest(request Request) {
 		request.Fail(err)
 	}
 
-	if (this.readonly || value.ToBool(request.Readonly())) &&
-		(prepared != nil && !prepared.Readonly()) {
+	readonly := this.readonly || value.ToBool(request.Readonly())
+	if readonly && (prepared != nil && !prepared.Readonly()) {
 		request.Fail(errors.NewServiceErrorReadonly("The server or request is read-only" +
 			" and cannot accept this write statement."))
 	}
@@ -426,9 +440,24 @@ func (this *Server) serviceRequest(request Request) {
 		maxParallelism = this.MaxParallelism()
 	}
 
+	// Answer from the result cache, or capture the results for it
+	output := request.Output()
+	key, cacheable := resultCacheKey(request, namespace, prepared)
+	var capture *resultCapture
+	if cacheable {
+		if this.serveCachedResult(request, prepared, key) {
+			return
+		}
+
+		capture = newResultCapture(output, request, namespace, prepared, key)
+		output = capture
+	}
+
 	context := execution.NewContext(request.Id().String(), this.datastore, this.systemstore, namespace,
-		this.readonly, maxParallelism, request.NamedArgs(), request.PositionalArgs(),
-		request.Credentials(), request.ScanConsistency(), request.ScanVectorSource(), request.Output())
+		readonly, maxParallelism, request.NamedArgs(), request.PositionalArgs(),
+		request.Credentials(), request.ScanConsistency(), request.ScanVectorSource(), output)
+	context.SetSubqueryPlans(prepared.Subqueries())
+	context.SetScanWait(request.ScanWait())
 
 	build := time.Now()
 	operator, er := execution.Build(prepared, context)
@@ -456,19 +485,93 @@ func (this *Server) serviceRequest(request Request) {
 		defer timer.Stop()
 	}
 
-	go request.Execute(this, prepared.Signature(), operator.StopChannel())
+	stopNotify := make(chan bool, 1)
+	finished := make(chan bool)
+	defer close(finished)
+	go stopExecution(stopNotify, finished, context, operator)
+
+	go request.Execute(this, prepared.Signature(), stopNotify)
 
 	run := time.Now()
 	operator.RunOnce(context, nil)
 
+	if capture != nil {
+		capture.done(request)
+	}
+
 	if logging.LogLevel() >= logging.TRACE {
 		request.Output().AddPhaseTime("run", time.Since(run))
 		logPhases(request)
 	}
 }
 
+/*
+Returns the plan pinned by the baseline of the request statement, if
+any. A baseline that references missing indexes is not used, and the
+request is warned.
+*/
+func (this *Server) getBaseline(request Request, namespace string) *plan.Prepared {
+	if request.Statement() == "" {
+		return nil
+	}
+
+	baseline := plan.GetBaseline(namespace, request.Statement())
+	if baseline == nil {
+		return nil
+	}
+
+	prepared, err := baseline.Prepared()
+	if err != nil {
+		request.Output().Warning(err)
+		return nil
+	}
+
+	return prepared
+}
+
+/*
+CreatePlanBaseline pins the current plan of a statement.
+*/
+func (this *Server) CreatePlanBaseline(statement, namespace string) errors.Error {
+	if namespace == "" {
+		namespace = this.namespace
+	}
+
+	stmt, err := n1ql.ParseStatement(statement)
+	if err != nil {
+		return errors.NewParseSyntaxError(err, "")
+	}
+
+	prepared, err := planner.BuildBaseline(stmt, this.datastore, this.systemstore, namespace, statement)
+	if err != nil {
+		return errors.NewPlanError(err, "")
+	}
+
+	baseline, er := plan.NewBaseline(namespace, statement, prepared)
+	if er != nil {
+		return er
+	}
+
+	return plan.AddBaseline(this.datastore, baseline)
+}
+
+/*
+DropPlanBaseline unpins the plan of a statement.
+*/
+func (this *Server) DropPlanBaseline(statement, namespace string) errors.Error {
+	if namespace == "" {
+		namespace = this.namespace
+	}
+
+	return plan.DeleteBaseline(this.datastore, namespace, statement)
+}
+
 func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepared, errors.Error) {
 	prepared := request.Prepared()
+	if prepared == nil {
+		prepared = this.getBaseline(request, namespace)
+	}
+
 	if prepared == nil {
 		parse := time.Now()
 		stmt, err := n1ql.ParseStatement(request.Statement())
@@ -522,6 +625,19 @@ func logExplain(prepared *plan.Prepared) {
 	logging.Tracep("Explain ", logging.Pair{"explain", string(explain)})
 }
 
+/*
+stopExecution forwards the stop of a request, by a timeout or by the
+client, to its operators and to the datastore operations they wait on.
+*/
+func stopExecution(stopNotify, finished chan bool, context *execution.Context, operator execution.Operator) {
+	select {
+	case <-stopNotify:
+		context.Stop()
+		sendStop(operator.StopChannel())
+	case <-finished:
+	}
+}
+
 func logPhases(request Request) {
 	phaseTimes := request.Output().PhaseTimes()
 	if len(phaseTimes) == 0 {
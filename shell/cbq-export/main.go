@@ -0,0 +1,157 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+
+cbq-export writes the documents of a keyspace, or the results of a
+query, to a JSON, JSON lines or CSV file. Keyspaces are exported from
+a datastore URI or through a running query engine; queries require
+an engine.
+
+	cbq-export -datastore dir:./data -keyspace contacts -file contacts.jsonl -key-field _id
+	cbq-export -engine http://localhost:8093 -query 'SELECT name, age FROM contacts' -file ages.csv
+
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/couchbase/query/shell/transfer"
+)
+
+var DATASTORE = flag.String("datastore", "", "Datastore address (dir:PATH or mock:), instead of -engine")
+var ENGINE = flag.String("engine", "", "Query engine or cluster URL, e.g. http://localhost:8093")
+var USER = flag.String("user", "", "Username for the query engine")
+var PASSWORD = flag.String("password", "", "Password for the query engine")
+var NAMESPACE = flag.String("namespace", "default", "Namespace of the keyspace")
+var KEYSPACE = flag.String("keyspace", "", "Keyspace to export")
+var QUERY = flag.String("query", "", "Query whose results are exported, instead of -keyspace; requires -engine")
+var FILE = flag.String("file", "", "Output file, or - for the standard output")
+var FORMAT = flag.String("format", "", "Output format: json, jsonl or csv; default from the file extension")
+var FIELDS = flag.String("fields", "", "Comma-separated CSV columns; default is the fields of the first document")
+var KEY_FIELD = flag.String("key-field", "", "Field to which the document key is added, for keyspace exports")
+var BATCH = flag.Int("batch", 1000, "Number of documents per fetch and checkpoint")
+var RESUME = flag.Bool("resume", false, "Resume an interrupted export from its checkpoint")
+var CHECKPOINT = flag.String("checkpoint", "", "Checkpoint file; default is the output file with .checkpoint appended")
+var PROGRESS = flag.Duration("progress", 5*time.Second, "Interval of progress reports; zero disables them")
+
+func main() {
+	flag.Parse()
+
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cbq-export: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if *FILE == "" || (*KEYSPACE == "") == (*QUERY == "") {
+		return fmt.Errorf("-file and exactly one of -keyspace and -query are required.")
+	}
+
+	if (*DATASTORE == "") == (*ENGINE == "") {
+		return fmt.Errorf("Exactly one of -datastore and -engine is required.")
+	}
+
+	if *QUERY != "" && *ENGINE == "" {
+		return fmt.Errorf("-query requires -engine.")
+	}
+
+	format := transfer.FormatOf(*FILE)
+	if *FORMAT != "" {
+		var err error
+		format, err = transfer.ParseFormat(*FORMAT)
+		if err != nil {
+			return err
+		}
+	}
+
+	var fields []string
+	if *FIELDS != "" {
+		fields = strings.Split(*FIELDS, ",")
+	}
+
+	checkpoint := transfer.NewCheckpoint("")
+	if *FILE != "-" {
+		path := *CHECKPOINT
+		if path == "" {
+			path = *FILE + ".checkpoint"
+		}
+
+		var err error
+		if *RESUME {
+			checkpoint, err = transfer.LoadCheckpoint(path)
+			if err != nil {
+				return err
+			}
+		} else {
+			checkpoint = transfer.NewCheckpoint(path)
+		}
+	} else if *RESUME {
+		return fmt.Errorf("Exports to the standard output cannot be resumed.")
+	}
+
+	if fields == nil {
+		fields = checkpoint.Fields
+	}
+
+	var source transfer.Source
+	var err error
+	if *DATASTORE != "" {
+		source, err = transfer.NewDatastoreSource(*DATASTORE, *NAMESPACE, *KEYSPACE,
+			checkpoint.LastKey, *BATCH)
+		if err != nil {
+			return err
+		}
+	} else {
+		engine, err := transfer.NewEngine(*ENGINE, *USER, *PASSWORD)
+		if err != nil {
+			return err
+		}
+		defer engine.Close()
+
+		if *QUERY != "" {
+			source, err = transfer.NewEngineQuerySource(engine, *QUERY, checkpoint.Records)
+			if err != nil {
+				return err
+			}
+		} else {
+			source = transfer.NewEngineKeyspaceSource(engine, *NAMESPACE, *KEYSPACE,
+				checkpoint.LastKey, *BATCH)
+		}
+	}
+	defer source.Close()
+
+	output, err := transfer.OpenOutput(*FILE, checkpoint)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	writer, err := transfer.NewWriter(output, format, fields, output.Appending())
+	if err != nil {
+		return err
+	}
+
+	progress := transfer.NewProgress(os.Stderr, "Exported", *PROGRESS)
+	_, err = transfer.Export(source, writer, output, transfer.ExportOptions{
+		KeyField:   *KEY_FIELD,
+		Batch:      *BATCH,
+		Checkpoint: checkpoint,
+		Progress:   progress,
+	})
+	progress.Done()
+	return err
+}
@@ -0,0 +1,2 @@
+echo go build
+go build
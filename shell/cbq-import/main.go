@@ -0,0 +1,137 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+
+cbq-import loads documents from a JSON, JSON lines or CSV file into a
+keyspace, either of a datastore URI or through a running query engine.
+
+	cbq-import -datastore dir:./data -keyspace contacts -file contacts.csv -key 'name'
+	cbq-import -engine http://localhost:8093 -keyspace orders -file orders.jsonl -resume
+
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/couchbase/query/shell/transfer"
+)
+
+var DATASTORE = flag.String("datastore", "", "Datastore address (dir:PATH or mock:), instead of -engine")
+var ENGINE = flag.String("engine", "", "Query engine or cluster URL, e.g. http://localhost:8093")
+var USER = flag.String("user", "", "Username for the query engine")
+var PASSWORD = flag.String("password", "", "Password for the query engine")
+var NAMESPACE = flag.String("namespace", "default", "Namespace of the keyspace")
+var KEYSPACE = flag.String("keyspace", "", "Keyspace to import into")
+var FILE = flag.String("file", "", "Input file, or - for the standard input")
+var FORMAT = flag.String("format", "", "Input format: json, jsonl or csv; default from the file extension")
+var KEY = flag.String("key", transfer.DEFAULT_KEY, "Expression that generates the key of each document")
+var BATCH = flag.Int("batch", 100, "Number of documents per write")
+var INSERT = flag.Bool("insert", false, "Use INSERT instead of UPSERT; existing keys are an error")
+var INFER_TYPES = flag.Bool("infer-types", true, "Convert CSV numbers, booleans and nulls")
+var RESUME = flag.Bool("resume", false, "Resume an interrupted import from its checkpoint")
+var CHECKPOINT = flag.String("checkpoint", "", "Checkpoint file; default is the input file with .checkpoint appended")
+var PROGRESS = flag.Duration("progress", 5*time.Second, "Interval of progress reports; zero disables them")
+
+func main() {
+	flag.Parse()
+
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cbq-import: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if *KEYSPACE == "" || *FILE == "" {
+		return fmt.Errorf("-keyspace and -file are required.")
+	}
+
+	if (*DATASTORE == "") == (*ENGINE == "") {
+		return fmt.Errorf("Exactly one of -datastore and -engine is required.")
+	}
+
+	format := transfer.FormatOf(*FILE)
+	if *FORMAT != "" {
+		var err error
+		format, err = transfer.ParseFormat(*FORMAT)
+		if err != nil {
+			return err
+		}
+	}
+
+	key, err := transfer.ParseKey(*KEY)
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	checkpoint := transfer.NewCheckpoint("")
+	if *FILE != "-" {
+		file, err := os.Open(*FILE)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+
+		path := *CHECKPOINT
+		if path == "" {
+			path = *FILE + ".checkpoint"
+		}
+
+		if *RESUME {
+			checkpoint, err = transfer.LoadCheckpoint(path)
+			if err != nil {
+				return err
+			}
+		} else {
+			checkpoint = transfer.NewCheckpoint(path)
+		}
+	} else if *RESUME {
+		return fmt.Errorf("Imports from the standard input cannot be resumed.")
+	}
+
+	reader, err := transfer.NewReader(input, format, *INFER_TYPES)
+	if err != nil {
+		return err
+	}
+
+	var target transfer.Target
+	if *DATASTORE != "" {
+		target, err = transfer.NewDatastoreTarget(*DATASTORE, *NAMESPACE, *KEYSPACE, *INSERT)
+		if err != nil {
+			return err
+		}
+	} else {
+		engine, err := transfer.NewEngine(*ENGINE, *USER, *PASSWORD)
+		if err != nil {
+			return err
+		}
+		defer engine.Close()
+		target = transfer.NewEngineTarget(engine, *NAMESPACE, *KEYSPACE, *INSERT)
+	}
+	defer target.Close()
+
+	progress := transfer.NewProgress(os.Stderr, "Imported", *PROGRESS)
+	_, err = transfer.Import(reader, target, transfer.ImportOptions{
+		Key:        key,
+		Batch:      *BATCH,
+		Checkpoint: checkpoint,
+		Progress:   progress,
+	})
+	progress.Done()
+	return err
+}
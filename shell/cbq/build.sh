@@ -1,2 +1,4 @@
+echo go run mkkeywords.go
+go run mkkeywords.go
 echo go build
 go build
@@ -192,6 +192,11 @@ func ExecN1QLStmt(line string, dBn1ql n1ql.N1qlDB, w io.Writer) (int, string) {
 	if rows != nil {
 		// We have output. That is what we want. We can ignore the error, even if there is one.
 
+		if command.OUTPUT_FORMAT != command.JSON_FORMAT {
+			// Display the results in the chosen format
+			return WriteResponse(w, rows, command.OUTPUT_FORMAT)
+		}
+
 		_, werr := io.Copy(w, rows)
 
 		// For any captured write error
@@ -70,6 +70,19 @@ var (
 	SKIPVERIFY = false
 	//Batch flag is used to send queries to the Asterix backend.
 	BATCH = "off"
+	//Format used to display query results.
+	OUTPUT_FORMAT = JSON_FORMAT
+)
+
+/* Values of the output_format option. JSON displays the response
+   of the query service as is; the other formats display its results
+   followed by a summary.
+*/
+const (
+	JSON_FORMAT  = "json"
+	JSONL_FORMAT = "jsonl"
+	CSV_FORMAT   = "csv"
+	TABLE_FORMAT = "table"
 )
 
 /* Value to store sorted list of keys for shell commands */
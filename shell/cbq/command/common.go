@@ -28,8 +28,9 @@ var (
 	NamedParam map[string]*Stack = map[string]*Stack{}
 	UserDefSV  map[string]*Stack = map[string]*Stack{}
 	PreDefSV   map[string]*Stack = map[string]*Stack{
-		"histfile": Stack_Helper(),
-		"batch":    Stack_Helper(),
+		"histfile":      Stack_Helper(),
+		"batch":         Stack_Helper(),
+		"output_format": Stack_Helper(),
 		//"autoconfig": Stack_Helper(),
 	}
 )
@@ -63,6 +64,13 @@ func init() {
 
 	}
 
+	err_code, err_str = PushValue_Helper(false, PreDefSV, "output_format", OUTPUT_FORMAT)
+	if err_code != 0 {
+		s_err := HandleError(err_code, err_str)
+		PrintError(s_err)
+
+	}
+
 	/*err_code, err_str = PushValue_Helper(false, PreDefSV, "autoconfig", "false")
 	if err_code != 0 {
 		s_err := HandleError(err_code, err_str)
@@ -326,6 +334,8 @@ func PushOrSet(args []string, pushvalue bool) (int, string) {
 	// Check what kind of parameter needs to be set or pushed
 	// depending on the pushvalue boolean value.
 
+	args[0] = ShellOption(args[0])
+
 	if strings.HasPrefix(args[0], "-$") {
 
 		// For Named Parameters
@@ -456,6 +466,12 @@ func PushOrSet(args []string, pushvalue bool) (int, string) {
 			}
 		} else if vble == "batch" {
 			BATCH = args_str
+		} else if vble == "output_format" {
+			format, ok := ParseOutputFormat(args_str)
+			if !ok {
+				return errors.OUTPUT_FORMAT, args_str
+			}
+			OUTPUT_FORMAT = format
 		}
 
 		err_code, err_str := PushValue_Helper(pushvalue, PreDefSV, vble, args_str)
@@ -467,6 +483,28 @@ func PushOrSet(args []string, pushvalue bool) (int, string) {
 	return 0, ""
 }
 
+/* Shell options that are not query parameters can also be given
+   with a leading -, the way they are given on the command line.
+   For example : \SET -output_format table
+*/
+func ShellOption(name string) string {
+	if strings.ToLower(name) == "-output_format" {
+		return "output_format"
+	}
+	return name
+}
+
+/* Validate a value of the output_format option.
+ */
+func ParseOutputFormat(format string) (string, bool) {
+	format = strings.ToLower(handleStrings(strings.TrimSpace(format)))
+	switch format {
+	case JSON_FORMAT, JSONL_FORMAT, CSV_FORMAT, TABLE_FORMAT:
+		return format, true
+	}
+	return "", false
+}
+
 func printDesc(cmdname string) (int, string) {
 
 	switch cmdname {
@@ -102,6 +102,8 @@ func HandleError(err int, msg string) errors.Error {
 		return errors.NewShellErrorNoSuchAlias(msg)
 	case errors.BATCH_MODE:
 		return errors.NewShellErrorBatchMode("")
+	case errors.OUTPUT_FORMAT:
+		return errors.NewShellErrorOutputFormat(msg)
 
 	//Generic Errors
 	case errors.OPERATION_TIMEOUT:
@@ -14,6 +14,8 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+
+	"github.com/couchbase/query/errors"
 )
 
 /*
@@ -243,3 +245,27 @@ func TestUnset(t *testing.T) {
 		t.Error(HandleError(errCode, errStr))
 	}
 }
+
+func TestOutputFormat(t *testing.T) {
+	set := COMMAND_LIST["\\set"]
+	unset := COMMAND_LIST["\\unset"]
+
+	errCode, errStr := set.ExecCommand([]string{"-output_format", "table"})
+	if errCode != 0 {
+		t.Error(HandleError(errCode, errStr))
+	} else if OUTPUT_FORMAT != TABLE_FORMAT {
+		t.Errorf("Expected output format table, got %s", OUTPUT_FORMAT)
+	}
+
+	errCode, _ = set.ExecCommand([]string{"output_format", "xml"})
+	if errCode != errors.OUTPUT_FORMAT || OUTPUT_FORMAT != TABLE_FORMAT {
+		t.Errorf("Expected invalid output format error, got %d", errCode)
+	}
+
+	errCode, errStr = unset.ExecCommand([]string{"output_format"})
+	if errCode != 0 {
+		t.Error(HandleError(errCode, errStr))
+	} else if OUTPUT_FORMAT != JSON_FORMAT {
+		t.Errorf("Expected output format json, got %s", OUTPUT_FORMAT)
+	}
+}
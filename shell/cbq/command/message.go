@@ -37,6 +37,7 @@ const (
 	ULOG        = " File to log commands into. \n\t For example : -logfile temp.txt"
 	USSLVERIFY  = " Skip verification of Certificates. "
 	UBATCH      = " Batch mode for sending queries to Asterix. Values : on/off"
+	UFORMAT     = " Format of query results. Values : json/jsonl/csv/table"
 
 	//Shorthand message for flags
 	SHORTHAND = " Shorthand for "
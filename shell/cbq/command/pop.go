@@ -69,6 +69,8 @@ func (this *Pop) ExecCommand(args []string) (int, string) {
 	} else {
 		//Check what kind of parameter needs to be popped
 
+		args[0] = ShellOption(args[0])
+
 		if strings.HasPrefix(args[0], "-$") {
 			// For Named Parameters
 			vble := args[0]
@@ -163,6 +165,25 @@ func (this *Pop) ExecCommand(args []string) (int, string) {
 				}
 				BATCH = nval
 
+			} else if vble == "output_format" {
+				st_val, ok := PreDefSV["output_format"]
+				if ok {
+					newval, err_code, err_str := st_val.Top()
+					if err_code != 0 {
+						return err_code, err_str
+					}
+					nval = ValToStr(newval)
+					nval = handleStrings(nval)
+				} else {
+					err_code, err_str := PushValue_Helper(false, PreDefSV, "output_format", JSON_FORMAT)
+					if err_code != 0 {
+						return err_code, err_str
+
+					}
+					nval = JSON_FORMAT
+				}
+				OUTPUT_FORMAT, _ = ParseOutputFormat(nval)
+
 			} else if vble == "histfile" {
 				//Predefined variables are only allowed to be specifically
 				//popped
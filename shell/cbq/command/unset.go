@@ -50,6 +50,8 @@ func (this *Unset) ExecCommand(args []string) (int, string) {
 
 	} else {
 		//Check what kind of parameter needs to be Unset.
+		args[0] = ShellOption(args[0])
+
 		// For query parameters
 		if strings.HasPrefix(args[0], "-$") {
 			// For Named Parameters
@@ -113,6 +115,16 @@ func (this *Unset) ExecCommand(args []string) (int, string) {
 
 			}
 
+			if vble == "output_format" {
+				err_code, err_str = PushValue_Helper(false, PreDefSV, "output_format", JSON_FORMAT)
+				if err_code != 0 {
+					return err_code, err_str
+
+				}
+				OUTPUT_FORMAT = JSON_FORMAT
+
+			}
+
 			//Print the path to histfile
 			err_code, err_str = printPath(HISTFILE)
 			if err_code != 0 {
@@ -0,0 +1,603 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/couchbase/godbc/n1ql"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/shell/cbq/command"
+	"github.com/peterh/liner"
+)
+
+//go:generate go run mkkeywords.go
+
+/* Metadata fetched for completion is refreshed after this interval. */
+const COMPLETION_TTL = 5 * time.Minute
+
+/* Number of documents sampled by INFER for field name completion. */
+const COMPLETION_SAMPLE = 100
+
+/*
+A CompletionQuery runs a statement against the query service and
+returns its results. It is replaced in tests.
+*/
+type CompletionQuery func(stmt string) ([]interface{}, error)
+
+/*
+Completer implements context-aware tab completion for the
+interactive shell. Depending on the position of the cursor in the
+statement it suggests shell commands, keyspace names, index names,
+field names of the keyspaces in the statement, or N1QL keywords.
+Metadata is fetched from the query service on demand and cached.
+*/
+type Completer struct {
+	sync.Mutex
+	query     CompletionQuery
+	pending   string
+	server    string
+	fetched   time.Time
+	keyspaces []string
+	indexes   map[string][]string
+	fields    map[string][]string
+}
+
+func NewCompleter(query CompletionQuery) *Completer {
+	rv := &Completer{
+		query: query,
+	}
+	rv.reset()
+	return rv
+}
+
+/*
+The completer used by the interactive shell.
+*/
+var shellCompleter = NewCompleter(engineQuery)
+
+/*
+Register installs the completer on a liner. Candidates are listed
+when tab is pressed twice.
+*/
+func (this *Completer) Register(state *liner.State) {
+	state.SetWordCompleter(this.Complete)
+	state.SetTabCompletionStyle(liner.TabPrints)
+}
+
+/*
+SetPending records the lines of a multi-line statement entered
+before the current prompt, so that they provide context.
+*/
+func (this *Completer) SetPending(lines []string) {
+	this.Lock()
+	defer this.Unlock()
+	this.pending = strings.Join(lines, " ")
+}
+
+/*
+Complete is a liner.WordCompleter. It returns the text before the
+word under the cursor, the candidates for that word, and the text
+after it.
+*/
+func (this *Completer) Complete(line string, pos int) (head string, completions []string, tail string) {
+	this.Lock()
+	defer this.Unlock()
+
+	if pos > len(line) {
+		pos = len(line)
+	}
+
+	start := pos
+	for start > 0 && isWordByte(line[start-1]) {
+		start--
+	}
+
+	head, word, tail := line[:start], line[start:pos], line[pos:]
+
+	// Shell commands and aliases
+	if strings.HasPrefix(word, "\\") && strings.TrimSpace(this.pending) == "" &&
+		strings.TrimSpace(head) == "" {
+		return head, this.completeCommand(word), tail
+	}
+
+	before := this.pending + " " + head
+	tokens, inString := tokenize(before)
+	if inString {
+		return head, nil, tail
+	}
+
+	statement := this.pending + " " + line
+	return head, this.completeWord(word, tokens, statement), tail
+}
+
+func (this *Completer) completeCommand(word string) []string {
+	candidates := make([]string, 0, len(command.COMMAND_LIST)+len(command.AliasCommand))
+	for name, _ := range command.COMMAND_LIST {
+		candidates = append(candidates, name)
+	}
+	for name, _ := range command.AliasCommand {
+		candidates = append(candidates, "\\\\"+name)
+	}
+
+	return matchFold(word, candidates)
+}
+
+func (this *Completer) completeWord(word string, tokens []string, statement string) []string {
+	prev := token(tokens, 1)
+	prev2 := token(tokens, 2)
+
+	switch {
+	case prev == "INDEX" && prev2 == "DROP":
+		return this.completeIndex(word, nil, true)
+	case inUseIndex(tokens):
+		return this.completeIndex(word, statementKeyspaces(statement), false)
+	case prev == "FROM" || prev == "JOIN" || prev == "NEST" || prev == "INTO" ||
+		prev == "UPDATE" || prev == "INFER" || prev == "KEYSPACE" ||
+		(prev == "ON" && token(tokens, 0) == "CREATE") ||
+		(prev == "USING" && token(tokens, 0) == "MERGE"):
+		return this.completeKeyspace(word)
+	}
+
+	if strings.Index(word, ".") > 0 {
+		return this.completePath(word, statement)
+	}
+
+	completions := matchFold(word, N1QL_KEYWORDS)
+	if expectsExpression(prev) {
+		names := []string{}
+		for _, keyspace := range statementKeyspaces(statement) {
+			names = append(names, this.getFields(keyspace.name)...)
+		}
+		completions = append(matchName(word, names), completions...)
+	}
+
+	return completions
+}
+
+/*
+completePath completes alias.field paths, resolving the alias from
+the FROM and JOIN clauses of the statement.
+*/
+func (this *Completer) completePath(word string, statement string) []string {
+	word = strings.Replace(word, "`", "", -1)
+	alias := word[:strings.IndexByte(word, '.')]
+	path := word[len(alias)+1:]
+
+	for _, keyspace := range statementKeyspaces(statement) {
+		if keyspace.alias != alias {
+			continue
+		}
+
+		completions := []string{}
+		for _, field := range this.getFields(keyspace.name) {
+			if strings.HasPrefix(field, path) {
+				completions = append(completions, alias+"."+quoteName(field))
+			}
+		}
+		return completions
+	}
+
+	return nil
+}
+
+func (this *Completer) completeKeyspace(word string) []string {
+	return matchName(word, this.getKeyspaces())
+}
+
+/*
+completeIndex completes index names, limited to the keyspaces of the
+statement if any. DROP INDEX requires keyspace.index names.
+*/
+func (this *Completer) completeIndex(word string, keyspaces []keyspaceRef, qualified bool) []string {
+	this.refresh()
+
+	names := []string{}
+	for keyspace, indexes := range this.indexes {
+		if len(keyspaces) > 0 && !containsKeyspace(keyspaces, keyspace) {
+			continue
+		}
+
+		for _, index := range indexes {
+			if qualified {
+				names = append(names, quoteName(keyspace)+"."+quoteName(index))
+			} else {
+				names = append(names, index)
+			}
+		}
+	}
+
+	return matchName(word, names)
+}
+
+func (this *Completer) reset() {
+	this.fetched = time.Time{}
+	this.keyspaces = nil
+	this.indexes = make(map[string][]string)
+	this.fields = make(map[string][]string)
+}
+
+/*
+refresh fetches the keyspace and index names if the cache has
+expired or the shell has connected to a different server.
+*/
+func (this *Completer) refresh() {
+	if this.server != serverFlag {
+		this.server = serverFlag
+		this.reset()
+	}
+
+	if !this.fetched.IsZero() && time.Since(this.fetched) < COMPLETION_TTL {
+		return
+	}
+
+	this.reset()
+	this.fetched = time.Now()
+
+	results, err := this.query("SELECT namespace_id, name FROM system:keyspaces")
+	if err != nil {
+		return
+	}
+
+	for _, result := range results {
+		name, _ := getString(result, "name")
+		namespace, _ := getString(result, "namespace_id")
+		if name == "" {
+			continue
+		}
+
+		this.keyspaces = append(this.keyspaces, name)
+		if namespace != "" {
+			this.keyspaces = append(this.keyspaces, namespace+":"+name)
+		}
+	}
+
+	results, err = this.query("SELECT keyspace_id, name FROM system:indexes")
+	if err != nil {
+		return
+	}
+
+	for _, result := range results {
+		name, _ := getString(result, "name")
+		keyspace, _ := getString(result, "keyspace_id")
+		if name != "" && keyspace != "" {
+			this.indexes[keyspace] = append(this.indexes[keyspace], name)
+		}
+	}
+}
+
+func (this *Completer) getKeyspaces() []string {
+	this.refresh()
+	return this.keyspaces
+}
+
+/*
+getFields returns the field paths of a keyspace, inferred from a
+sample of its documents.
+*/
+func (this *Completer) getFields(keyspace string) []string {
+	this.refresh()
+
+	fields, ok := this.fields[keyspace]
+	if ok {
+		return fields
+	}
+
+	fields = []string{}
+	stmt := "INFER " + escapeKeyspace(keyspace) + " WITH {\"sample_size\": " +
+		strconv.Itoa(COMPLETION_SAMPLE) + "}"
+	results, err := this.query(stmt)
+	if err == nil {
+		set := make(map[string]bool)
+		collectFields(results, "", set)
+		for field, _ := range set {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+	}
+
+	// Failures are cached too, e.g. where INFER is not supported
+	this.fields[keyspace] = fields
+	return fields
+}
+
+/*
+collectFields adds the property paths of INFER flavors to set.
+*/
+func collectFields(val interface{}, prefix string, set map[string]bool) {
+	switch val := val.(type) {
+	case []interface{}:
+		for _, v := range val {
+			collectFields(v, prefix, set)
+		}
+	case map[string]interface{}:
+		properties, ok := val["properties"].(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		for name, property := range properties {
+			set[prefix+name] = true
+			collectFields(property, prefix+name+".", set)
+		}
+	}
+}
+
+/*
+engineQuery runs a completion query against the connected query
+service.
+*/
+func engineQuery(stmt string) ([]interface{}, error) {
+	if noQueryService {
+		return nil, errors.NewShellErrorNoConnection("")
+	}
+
+	db, err := n1ql.OpenExtended(serverFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryRaw(stmt)
+	if rows == nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var response struct {
+		Results []interface{} `json:"results"`
+	}
+
+	err = json.NewDecoder(rows).Decode(&response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Results, nil
+}
+
+type keyspaceRef struct {
+	name  string
+	alias string
+}
+
+var _FROM_TERM = regexp.MustCompile(
+	"(?i)\\b(?:FROM|JOIN|NEST|UPDATE|INTO)\\s+((?:\\w+:)?(?:`[^`]+`|\\w+))(?:\\s+(?:AS\\s+)?(\\w+))?")
+
+/*
+statementKeyspaces returns the keyspaces named in the FROM, JOIN,
+NEST, UPDATE and INTO clauses of the statement, with their aliases.
+*/
+func statementKeyspaces(statement string) []keyspaceRef {
+	rv := []keyspaceRef{}
+	for _, match := range _FROM_TERM.FindAllStringSubmatch(statement, -1) {
+		name := strings.Replace(match[1], "`", "", -1)
+		if i := strings.IndexByte(name, ':'); i >= 0 {
+			name = name[i+1:]
+		}
+
+		alias := match[2]
+		if alias == "" || isKeyword(alias) {
+			alias = name
+		}
+
+		rv = append(rv, keyspaceRef{name: name, alias: alias})
+	}
+
+	return rv
+}
+
+func containsKeyspace(keyspaces []keyspaceRef, name string) bool {
+	for _, keyspace := range keyspaces {
+		if keyspace.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+inUseIndex returns true if the cursor is inside a USE INDEX (...)
+hint.
+*/
+func inUseIndex(tokens []string) bool {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tokens[i] {
+		case ")":
+			return false
+		case "(":
+			return i >= 2 && tokens[i-1] == "INDEX" && tokens[i-2] == "USE"
+		case ",":
+		default:
+			if isKeyword(tokens[i]) {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+/*
+expectsExpression returns true if an expression, and so possibly a
+field name, can follow the given token.
+*/
+func expectsExpression(prev string) bool {
+	switch prev {
+	case "SELECT", "WHERE", "BY", "AND", "OR", "NOT", "SET", "HAVING", "RAW",
+		"ELEMENT", "VALUE", "DISTINCT", "ON", "KEYS", "WHEN", "THEN", "ELSE",
+		"UNNEST", ",", "(", "=", "<", ">", "!", "+", "-", "*", "/", "%":
+		return true
+	}
+	return false
+}
+
+/*
+tokenize splits text into words and punctuation, with words
+upper-cased and string literals dropped. It also reports whether
+text ends inside a string literal.
+*/
+func tokenize(text string) ([]string, bool) {
+	tokens := []string{}
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(text) && text[j] != c {
+				if text[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(text) {
+				return tokens, true
+			}
+			i = j + 1
+		case isWordByte(c):
+			j := i
+			for j < len(text) && isWordByte(text[j]) {
+				j++
+			}
+			tokens = append(tokens, strings.ToUpper(text[i:j]))
+			i = j
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, false
+}
+
+/*
+token returns the nth token from the end, counting from 1, or the
+first token if n is 0.
+*/
+func token(tokens []string, n int) string {
+	if n == 0 {
+		if len(tokens) == 0 {
+			return ""
+		}
+		return tokens[0]
+	}
+	if n > len(tokens) {
+		return ""
+	}
+	return tokens[len(tokens)-n]
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '.' || c == ':' || c == '`' || c == '\\' || c == '$' ||
+		c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+var _KEYWORDS map[string]bool
+
+func isKeyword(word string) bool {
+	if _KEYWORDS == nil {
+		_KEYWORDS = make(map[string]bool, len(N1QL_KEYWORDS))
+		for _, keyword := range N1QL_KEYWORDS {
+			_KEYWORDS[keyword] = true
+		}
+	}
+	return _KEYWORDS[strings.ToUpper(word)]
+}
+
+/*
+matchFold returns the sorted candidates that start with word,
+ignoring case, in the case of word.
+*/
+func matchFold(word string, candidates []string) []string {
+	lower := word != "" && word == strings.ToLower(word)
+	upper := word != strings.ToLower(word) && word == strings.ToUpper(word)
+	prefix := strings.ToLower(word)
+
+	rv := []string{}
+	for _, candidate := range candidates {
+		if !strings.HasPrefix(strings.ToLower(candidate), prefix) {
+			continue
+		}
+
+		switch {
+		case lower:
+			rv = append(rv, strings.ToLower(candidate))
+		case upper:
+			rv = append(rv, strings.ToUpper(candidate))
+		default:
+			rv = append(rv, candidate)
+		}
+	}
+
+	sort.Strings(rv)
+	return rv
+}
+
+/*
+matchName returns the sorted and de-duplicated names that start
+with word, escaping names that are not identifiers.
+*/
+func matchName(word string, names []string) []string {
+	prefix := strings.Replace(word, "`", "", -1)
+
+	seen := make(map[string]bool, len(names))
+	rv := []string{}
+	for _, name := range names {
+		if seen[name] || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		seen[name] = true
+		rv = append(rv, quoteName(name))
+	}
+
+	sort.Strings(rv)
+	return rv
+}
+
+var _IDENTIFIER = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_$]*$")
+
+/*
+quoteName escapes each part of a dotted or namespaced name that is
+not a plain identifier.
+*/
+func quoteName(name string) string {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		return name[:i+1] + quoteName(name[i+1:])
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if !_IDENTIFIER.MatchString(part) {
+			parts[i] = "`" + part + "`"
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func escapeKeyspace(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func getString(val interface{}, field string) (string, bool) {
+	object, ok := val.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+
+	s, ok := object[field].(string)
+	return s, ok
+}
@@ -0,0 +1,105 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var completionResults = map[string]string{
+	"SELECT namespace_id, name FROM system:keyspaces": `[
+		{"namespace_id": "default", "name": "customer"},
+		{"namespace_id": "default", "name": "orders"},
+		{"namespace_id": "default", "name": "travel-sample"}]`,
+	"SELECT keyspace_id, name FROM system:indexes": `[
+		{"keyspace_id": "customer", "name": "ix_name"},
+		{"keyspace_id": "customer", "name": "ix_city"},
+		{"keyspace_id": "orders", "name": "ix_total"}]`,
+	"INFER `customer`": `[[{"properties": {
+		"name": {"type": "string"},
+		"address": {"type": "object", "properties": {
+			"city": {"type": "string"}}}}}]]`,
+}
+
+func testCompleter(t *testing.T) *Completer {
+	return NewCompleter(func(stmt string) ([]interface{}, error) {
+		for prefix, results := range completionResults {
+			if strings.HasPrefix(stmt, prefix) {
+				var rv []interface{}
+				err := json.Unmarshal([]byte(results), &rv)
+				if err != nil {
+					t.Fatalf("Invalid results for %s: %v", prefix, err)
+				}
+				return rv, nil
+			}
+		}
+		return nil, nil
+	})
+}
+
+func checkCompletion(t *testing.T, c *Completer, line string, expected ...string) {
+	_, completions, _ := c.Complete(line, len(line))
+	if len(expected) == 0 {
+		expected = []string{}
+	}
+	if completions == nil {
+		completions = []string{}
+	}
+	if !reflect.DeepEqual(completions, expected) {
+		t.Errorf("Completing %q: expected %v, got %v", line, expected, completions)
+	}
+}
+
+func TestCompleteCommand(t *testing.T) {
+	c := testCompleter(t)
+	checkCompletion(t, c, "\\con", "\\connect")
+	checkCompletion(t, c, "\\\\server", "\\\\serverversion")
+}
+
+func TestCompleteKeyword(t *testing.T) {
+	c := testCompleter(t)
+	checkCompletion(t, c, "sele", "select")
+	checkCompletion(t, c, "SELE", "SELECT")
+	checkCompletion(t, c, "SELECT * FROM customer ORD", "ORDER")
+	checkCompletion(t, c, "SELECT \"sele")
+}
+
+func TestCompleteKeyspace(t *testing.T) {
+	c := testCompleter(t)
+	checkCompletion(t, c, "SELECT * FROM cu", "customer")
+	checkCompletion(t, c, "SELECT * FROM default:o", "default:orders")
+	checkCompletion(t, c, "INSERT INTO tr", "`travel-sample`")
+	checkCompletion(t, c, "CREATE INDEX ix ON or", "orders")
+}
+
+func TestCompleteIndex(t *testing.T) {
+	c := testCompleter(t)
+	checkCompletion(t, c, "SELECT * FROM customer USE INDEX (ix_", "ix_city", "ix_name")
+	checkCompletion(t, c, "DROP INDEX o", "orders.ix_total")
+}
+
+func TestCompleteField(t *testing.T) {
+	c := testCompleter(t)
+	line := "SELECT c.ad FROM customer c"
+	head, completions, tail := c.Complete(line, len("SELECT c.ad"))
+	if head != "SELECT " || tail != " FROM customer c" ||
+		!reflect.DeepEqual(completions, []string{"c.address", "c.address.city"}) {
+		t.Errorf("Unexpected completion %q %v %q", head, completions, tail)
+	}
+
+	checkCompletion(t, c, "SELECT * FROM customer WHERE na", "name", "namespace")
+
+	// Earlier lines of the statement provide the keyspace
+	c.SetPending([]string{"SELECT cust.name", "FROM customer AS cust"})
+	checkCompletion(t, c, "WHERE cust.address.c", "cust.address.city")
+}
@@ -75,6 +75,7 @@ func HandleInteractiveMode(prompt string) {
 	/* Create a new liner */
 	var liner = liner.NewLiner()
 	liner.SetMultiLineMode(true)
+	shellCompleter.Register(liner)
 	defer liner.Close()
 
 	/* Load history from Home directory
@@ -146,6 +147,8 @@ func HandleInteractiveMode(prompt string) {
 	// End handling the options
 
 	for {
+		// Earlier lines of a multi-line query are context for completion
+		shellCompleter.SetPending(inputLine)
 		line, err := liner.Prompt(fullPrompt)
 		if err != nil {
 			break
@@ -0,0 +1,102 @@
+// +build ignore
+
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+
+mkkeywords generates keywords.go from the token list of the N1QL
+grammar, so that the shell can complete keywords without importing
+the parser. Run it from this directory with
+
+	go run mkkeywords.go
+
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const GRAMMAR = "../../parser/n1ql/n1ql.y"
+const OUTPUT = "keywords.go"
+
+// Keywords are declared one per line; punctuation and literal
+// tokens share a line, and NOT_A_TOKEN contains underscores.
+var keywordToken = regexp.MustCompile(`^%token\s+([A-Z]+)\s*$`)
+
+func main() {
+	file, err := os.Open(GRAMMAR)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	keywords := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		match := keywordToken.FindStringSubmatch(scanner.Text())
+		if match != nil {
+			keywords = append(keywords, match[1])
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	sort.Strings(keywords)
+
+	var buf bytes.Buffer
+	header, err := ioutil.ReadFile("mkkeywords.go")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Reuse the license header of this file
+	lines := strings.SplitAfter(string(header), "\n")
+	for _, line := range lines[2:] {
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		buf.WriteString(line)
+	}
+
+	buf.WriteString("\n// Code generated by mkkeywords.go from " + GRAMMAR + ". DO NOT EDIT.\n\n")
+	buf.WriteString("package main\n\n")
+	buf.WriteString("/* N1QL keywords, for tab completion. */\n")
+	buf.WriteString("var N1QL_KEYWORDS = []string{\n")
+	for _, keyword := range keywords {
+		buf.WriteString("\t\"" + keyword + "\",\n")
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = ioutil.WriteFile(OUTPUT, src, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
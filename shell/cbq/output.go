@@ -0,0 +1,490 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/shell/cbq/command"
+	"github.com/couchbase/query/shell/transfer"
+)
+
+/* Results are displayed in pages of this many rows. Table columns
+   are sized for each page, so that large results are displayed as
+   they arrive.
+*/
+const PAGE_SIZE = 100
+
+/* Longer table cells are truncated. */
+const MAX_COLUMN_WIDTH = 40
+
+/* Name of the column of results that are not objects. */
+const VALUE_COLUMN = "$1"
+
+/* WriteResponse parses a query service response as it is read from
+   r, and writes its results to w in the given output format. The
+   status, metrics, errors and warnings of the response follow the
+   results in a summary.
+*/
+func WriteResponse(w io.Writer, r io.Reader, format string) (int, string) {
+	buf := bufio.NewWriter(w)
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	err := expectDelim(dec, '{')
+	if err != nil {
+		return errors.JSON_UNMARSHAL, err.Error()
+	}
+
+	var summary responseSummary
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return errors.JSON_UNMARSHAL, err.Error()
+		}
+
+		switch tok {
+		case "results":
+			err_code, err_str := writeResults(dec, newRowWriter(buf, format))
+			if err_code != 0 {
+				return err_code, err_str
+			}
+		case "errors":
+			err = dec.Decode(&summary.errors)
+		case "warnings":
+			err = dec.Decode(&summary.warnings)
+		case "status":
+			err = dec.Decode(&summary.status)
+		case "metrics":
+			summary.metrics, err = decodeFields(dec)
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+
+		if err != nil {
+			return errors.JSON_UNMARSHAL, err.Error()
+		}
+	}
+
+	summary.write(buf)
+	err = buf.Flush()
+	if err != nil {
+		return errors.WRITER_OUTPUT, err.Error()
+	}
+	return 0, ""
+}
+
+func writeResults(dec *json.Decoder, rw rowWriter) (int, string) {
+	err := expectDelim(dec, '[')
+	if err != nil {
+		return errors.JSON_UNMARSHAL, err.Error()
+	}
+
+	for dec.More() {
+		var raw json.RawMessage
+		err = dec.Decode(&raw)
+		if err != nil {
+			return errors.JSON_UNMARSHAL, err.Error()
+		}
+
+		err = rw.Write(raw)
+		if err != nil {
+			return errors.WRITER_OUTPUT, err.Error()
+		}
+	}
+
+	err = expectDelim(dec, ']')
+	if err != nil {
+		return errors.JSON_UNMARSHAL, err.Error()
+	}
+
+	err = rw.Close()
+	if err != nil {
+		return errors.WRITER_OUTPUT, err.Error()
+	}
+	return 0, ""
+}
+
+type responseSummary struct {
+	status   string
+	metrics  []string
+	errors   []map[string]interface{}
+	warnings []map[string]interface{}
+}
+
+func (this *responseSummary) write(w *bufio.Writer) {
+	w.WriteString("\n")
+	if this.status != "" {
+		w.WriteString(" status: " + this.status + "\n")
+	}
+	if len(this.metrics) > 0 {
+		w.WriteString(" metrics: " + strings.Join(this.metrics, ", ") + "\n")
+	}
+
+	for _, e := range this.warnings {
+		w.WriteString(" warning " + formatMessage(e) + "\n")
+	}
+
+	for _, e := range this.errors {
+		w.WriteString(command.GetfgRed() + " error " + formatMessage(e) + command.Getreset() + "\n")
+	}
+}
+
+func formatMessage(e map[string]interface{}) string {
+	return fmt.Sprintf("%v: %v", e["code"], e["msg"])
+}
+
+/* A rowWriter writes the results of a response. */
+type rowWriter interface {
+	Write(row json.RawMessage) error
+	Close() error
+}
+
+func newRowWriter(w *bufio.Writer, format string) rowWriter {
+	switch format {
+	case command.JSONL_FORMAT:
+		return &jsonlWriter{writer: w}
+	case command.CSV_FORMAT:
+		return &csvWriter{writer: w}
+	default:
+		return &tableWriter{writer: w}
+	}
+}
+
+/* jsonlWriter writes each result on a line. */
+type jsonlWriter struct {
+	writer *bufio.Writer
+	count  int
+}
+
+func (this *jsonlWriter) Write(row json.RawMessage) error {
+	var b bytes.Buffer
+	err := json.Compact(&b, row)
+	if err != nil {
+		return err
+	}
+
+	b.WriteByte('\n')
+	_, err = this.writer.Write(b.Bytes())
+	if err != nil {
+		return err
+	}
+
+	this.count++
+	if this.count%PAGE_SIZE == 0 {
+		return this.writer.Flush()
+	}
+	return nil
+}
+
+func (this *jsonlWriter) Close() error {
+	return this.writer.Flush()
+}
+
+/* csvWriter writes results with a column for each field path of
+   the first result, e.g. address.city.
+*/
+type csvWriter struct {
+	writer *bufio.Writer
+	csv    transfer.Writer
+	count  int
+}
+
+func (this *csvWriter) Write(row json.RawMessage) error {
+	doc, err := decodeRow(row)
+	if err != nil {
+		return err
+	}
+
+	if this.csv == nil {
+		this.csv, err = transfer.NewWriter(this.writer, transfer.CSV, rowFields(row), false)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = this.csv.Write(doc)
+	if err != nil {
+		return err
+	}
+
+	this.count++
+	if this.count%PAGE_SIZE == 0 {
+		return this.flush()
+	}
+	return nil
+}
+
+func (this *csvWriter) flush() error {
+	err := this.csv.Flush()
+	if err != nil {
+		return err
+	}
+	return this.writer.Flush()
+}
+
+func (this *csvWriter) Close() error {
+	if this.csv == nil {
+		return this.writer.Flush()
+	}
+	return this.flush()
+}
+
+/* tableWriter writes a table of the field paths of the results for
+   each page of results.
+*/
+type tableWriter struct {
+	writer  *bufio.Writer
+	columns []string
+	seen    map[string]bool
+	rows    []interface{}
+}
+
+func (this *tableWriter) Write(row json.RawMessage) error {
+	doc, err := decodeRow(row)
+	if err != nil {
+		return err
+	}
+
+	if this.seen == nil {
+		this.seen = make(map[string]bool)
+	}
+
+	for _, field := range rowFields(row) {
+		if !this.seen[field] {
+			this.seen[field] = true
+			this.columns = append(this.columns, field)
+		}
+	}
+
+	this.rows = append(this.rows, doc)
+	if len(this.rows) >= PAGE_SIZE {
+		return this.flush()
+	}
+	return nil
+}
+
+func (this *tableWriter) Close() error {
+	if len(this.rows) > 0 {
+		return this.flush()
+	}
+	return this.writer.Flush()
+}
+
+func (this *tableWriter) flush() error {
+	header := make([]string, len(this.columns))
+	widths := make([]int, len(this.columns))
+	for i, column := range this.columns {
+		header[i] = truncate(column)
+		widths[i] = utf8.RuneCountInString(header[i])
+	}
+
+	cells := make([][]string, len(this.rows))
+	numeric := make([][]bool, len(this.rows))
+	for r, row := range this.rows {
+		cells[r] = make([]string, len(this.columns))
+		numeric[r] = make([]bool, len(this.columns))
+		for i, column := range this.columns {
+			val, ok := transfer.GetPath(row, strings.Split(column, "."))
+			if !ok {
+				continue
+			}
+
+			_, numeric[r][i] = val.(json.Number)
+			cells[r][i] = truncate(escapeCell(transfer.FormatField(val)))
+			if n := utf8.RuneCountInString(cells[r][i]); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	separator := "+"
+	for _, width := range widths {
+		separator += strings.Repeat("-", width+2) + "+"
+	}
+	separator += "\n"
+
+	this.writer.WriteString(separator)
+	this.writeLine(header, nil, widths)
+	this.writer.WriteString(separator)
+	for r, _ := range cells {
+		this.writeLine(cells[r], numeric[r], widths)
+	}
+	this.writer.WriteString(separator)
+
+	this.columns = nil
+	this.seen = nil
+	this.rows = nil
+	return this.writer.Flush()
+}
+
+func (this *tableWriter) writeLine(cells []string, numeric []bool, widths []int) {
+	this.writer.WriteString("|")
+	for i, cell := range cells {
+		pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		if numeric != nil && numeric[i] {
+			this.writer.WriteString(" " + pad + cell + " |")
+		} else {
+			this.writer.WriteString(" " + cell + pad + " |")
+		}
+	}
+	this.writer.WriteString("\n")
+}
+
+/* Keep each row on a single line. */
+var cellEscaper = strings.NewReplacer("\n", "\\n", "\r", "\\r", "\t", " ")
+
+func escapeCell(cell string) string {
+	return cellEscaper.Replace(cell)
+}
+
+func truncate(cell string) string {
+	if utf8.RuneCountInString(cell) <= MAX_COLUMN_WIDTH {
+		return cell
+	}
+
+	runes := []rune(cell)
+	return string(runes[:MAX_COLUMN_WIDTH-3]) + "..."
+}
+
+/* decodeRow decodes a result, wrapping results that are not objects
+   in an object.
+*/
+func decodeRow(row json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(row))
+	dec.UseNumber()
+
+	var doc interface{}
+	err := dec.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := doc.(map[string]interface{}); !ok {
+		doc = map[string]interface{}{VALUE_COLUMN: doc}
+	}
+	return doc, nil
+}
+
+/* rowFields returns the paths of the scalar and array fields of a
+   result, in the order of the result, e.g. the order of the
+   projection.
+*/
+func rowFields(row json.RawMessage) []string {
+	dec := json.NewDecoder(bytes.NewReader(row))
+	dec.UseNumber()
+
+	fields := []string{}
+	var walk func(prefix string) error
+	walk = func(prefix string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			empty := true
+			for dec.More() {
+				name, err := dec.Token()
+				if err != nil {
+					return err
+				}
+
+				path := name.(string)
+				if prefix != "" {
+					path = prefix + "." + path
+				}
+
+				empty = false
+				err = walk(path)
+				if err != nil {
+					return err
+				}
+			}
+
+			if empty && prefix != "" {
+				fields = append(fields, prefix)
+			}
+			_, err = dec.Token()
+			return err
+		case json.Delim('['):
+			for dec.More() {
+				var skip json.RawMessage
+				err = dec.Decode(&skip)
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err = dec.Token()
+			if err != nil {
+				return err
+			}
+		}
+
+		if prefix == "" {
+			prefix = VALUE_COLUMN
+		}
+		fields = append(fields, prefix)
+		return nil
+	}
+
+	walk("")
+	return fields
+}
+
+/* decodeFields decodes an object as a list of name: value strings,
+   in the order of the object.
+*/
+func decodeFields(dec *json.Decoder) ([]string, error) {
+	err := expectDelim(dec, '{')
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []string{}
+	for dec.More() {
+		name, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var val interface{}
+		err = dec.Decode(&val)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, fmt.Sprintf("%v: %s", name, transfer.FormatField(val)))
+	}
+
+	return fields, expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok != delim {
+		return fmt.Errorf("Expected %v in query response, found %v", delim, tok)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/couchbase/query/shell/cbq/command"
+)
+
+const testResponse = `{
+    "requestID": "7c1b6b2b",
+    "signature": {"*": "*"},
+    "results": [
+        {"name": "Ann", "age": 32, "address": {"city": "Paris"}},
+        {"name": "Bob\nSmith", "age": 7, "tags": ["a", "b"]},
+        "scalar"
+    ],
+    "errors": [{"code": 5010, "msg": "Error evaluating projection."}],
+    "status": "errors",
+    "metrics": {"elapsedTime": "2.1ms", "resultCount": 3}
+}`
+
+func writeTestResponse(t *testing.T, format string) string {
+	command.SetDispVal("", "")
+	defer command.SetDispVal("\x1b[0m", "\x1b[31m")
+
+	var b bytes.Buffer
+	err_code, err_str := WriteResponse(&b, strings.NewReader(testResponse), format)
+	if err_code != 0 {
+		t.Fatalf("Error writing %s response: %v", format, command.HandleError(err_code, err_str))
+	}
+	return b.String()
+}
+
+const testSummary = `
+ status: errors
+ metrics: elapsedTime: 2.1ms, resultCount: 3
+ error 5010: Error evaluating projection.
+`
+
+func TestWriteJSONL(t *testing.T) {
+	expected := `{"name":"Ann","age":32,"address":{"city":"Paris"}}
+{"name":"Bob\nSmith","age":7,"tags":["a","b"]}
+"scalar"
+` + testSummary
+
+	if s := writeTestResponse(t, command.JSONL_FORMAT); s != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, s)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	expected := `name,age,address.city
+Ann,32,Paris
+"Bob
+Smith",7,
+,,
+` + testSummary
+
+	if s := writeTestResponse(t, command.CSV_FORMAT); s != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, s)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	expected := `+------------+-----+--------------+-----------+--------+
+| name       | age | address.city | tags      | $1     |
++------------+-----+--------------+-----------+--------+
+| Ann        |  32 | Paris        |           |        |
+| Bob\nSmith |   7 |              | ["a","b"] |        |
+|            |     |              |           | scalar |
++------------+-----+--------------+-----------+--------+
+` + testSummary
+
+	if s := writeTestResponse(t, command.TABLE_FORMAT); s != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, s)
+	}
+
+	long := strings.Repeat("x", MAX_COLUMN_WIDTH+10)
+	if s := truncate(long); len(s) != MAX_COLUMN_WIDTH || !strings.HasSuffix(s, "...") {
+		t.Errorf("Expected truncation to %d characters, got %s", MAX_COLUMN_WIDTH, s)
+	}
+}
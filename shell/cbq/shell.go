@@ -283,6 +283,14 @@ func init() {
 	flag.StringVar(&batchFlag, "b", defaultval, command.NewShorthandMsg("-batch"))
 }
 
+/*
+   Option        : -output_format
+   Args          : json/jsonl/csv/table
+   Format used to display query results.
+*/
+
+var formatFlag = flag.String("output_format", command.JSON_FORMAT, command.UFORMAT)
+
 var (
 	SERVICE_URL  string
 	DISCONNECT   bool
@@ -505,6 +513,15 @@ func main() {
 		command.BATCH = batchFlag
 	}
 
+	// Set the output format
+	if *formatFlag != command.JSON_FORMAT {
+		err_code, err_str := command.PushOrSet([]string{"output_format", *formatFlag}, true)
+		if err_code != 0 {
+			s_err := command.HandleError(err_code, err_str)
+			command.PrintError(s_err)
+		}
+	}
+
 	// Handle the inputFlag and ScriptFlag options in HandleInteractiveMode.
 	// This is so as to add these to the history.
 
@@ -0,0 +1,91 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transfer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+/*
+Checkpoint records the progress of an import or export, so that an
+interrupted transfer can be resumed. It is saved after each batch,
+and removed when the transfer completes.
+*/
+type Checkpoint struct {
+	Records int64    `json:"records"`            // Documents transferred
+	Offset  int64    `json:"offset,omitempty"`   // Size of the output of an export
+	LastKey string   `json:"last_key,omitempty"` // Last key exported from a keyspace
+	Fields  []string `json:"fields,omitempty"`   // CSV columns of an export
+
+	path string
+}
+
+/*
+LoadCheckpoint returns the checkpoint saved at path, or an empty
+checkpoint if there is none.
+*/
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	rv := &Checkpoint{path: path}
+
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rv, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(bytes, rv)
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func NewCheckpoint(path string) *Checkpoint {
+	return &Checkpoint{path: path}
+}
+
+/*
+Save writes the checkpoint to a temporary file which is then renamed,
+so that a crash does not leave a truncated checkpoint.
+*/
+func (this *Checkpoint) Save() error {
+	if this.path == "" {
+		return nil
+	}
+
+	bytes, err := json.Marshal(this)
+	if err != nil {
+		return err
+	}
+
+	tmp := this.path + ".tmp"
+	err = ioutil.WriteFile(tmp, bytes, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, this.path)
+}
+
+func (this *Checkpoint) Remove() error {
+	if this.path == "" {
+		return nil
+	}
+
+	err := os.Remove(this.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/godbc/n1ql"
+)
+
+/*
+Engine runs statements on a query engine or cluster, through the
+same driver as the cbq shell.
+*/
+type Engine struct {
+	db n1ql.N1qlDB
+}
+
+func NewEngine(url, user, password string) (*Engine, error) {
+	if user != "" {
+		n1ql.SetUsernamePassword(user, password)
+	}
+
+	db, err := n1ql.OpenExtended(url)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Engine{db: db}, nil
+}
+
+type engineResponse struct {
+	Results []json.RawMessage `json:"results"`
+	Errors  []struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	} `json:"errors"`
+}
+
+/*
+Query runs a statement with positional arguments, and returns its
+results.
+*/
+func (this *Engine) Query(statement string, args ...interface{}) ([]json.RawMessage, error) {
+	body, err := this.db.QueryRaw(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var response engineResponse
+	err = json.NewDecoder(body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid response from query engine: %v", err)
+	}
+
+	if len(response.Errors) > 0 {
+		msgs := make([]string, len(response.Errors))
+		for i, e := range response.Errors {
+			msgs[i] = fmt.Sprintf("%d %s", e.Code, e.Msg)
+		}
+		return nil, fmt.Errorf("Query engine error: %s", strings.Join(msgs, "; "))
+	}
+
+	return response.Results, nil
+}
+
+func (this *Engine) Close() error {
+	return this.db.Close()
+}
+
+// keyspaceRef returns the escaped namespace:keyspace of a statement.
+func keyspaceRef(namespace, keyspace string) string {
+	escape := func(s string) string {
+		return "`" + strings.Replace(s, "`", "``", -1) + "`"
+	}
+
+	if namespace == "" {
+		return escape(keyspace)
+	}
+	return escape(namespace) + ":" + escape(keyspace)
+}
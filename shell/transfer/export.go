@@ -0,0 +1,180 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type ExportOptions struct {
+	KeyField   string      // Adds the key of each document as this field, if set
+	Batch      int         // Documents per checkpoint
+	Checkpoint *Checkpoint // Resumes an interrupted export; may be nil
+	Progress   *Progress   // May be nil
+}
+
+/*
+Output is the output file of an export. It truncates the file of a
+resumed export to its size at the last checkpoint, so that the
+documents written after it are not duplicated.
+*/
+type Output struct {
+	file      *os.File
+	appending bool
+}
+
+/*
+OpenOutput opens the output file, or the standard output if path is
+"-". Exports to the standard output cannot be resumed.
+*/
+func OpenOutput(path string, checkpoint *Checkpoint) (*Output, error) {
+	if path == "-" {
+		return &Output{file: os.Stdout}, nil
+	}
+
+	if checkpoint == nil || checkpoint.Records == 0 {
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		return &Output{file: file}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot resume export: %v", err)
+	}
+
+	err = file.Truncate(checkpoint.Offset)
+	if err == nil {
+		_, err = file.Seek(checkpoint.Offset, os.SEEK_SET)
+	}
+
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Output{file: file, appending: true}, nil
+}
+
+func (this *Output) Write(p []byte) (int, error) {
+	return this.file.Write(p)
+}
+
+// Appending is true when resuming an export.
+func (this *Output) Appending() bool {
+	return this.appending
+}
+
+func (this *Output) offset() (int64, error) {
+	if this.file == os.Stdout {
+		return 0, nil
+	}
+
+	return this.file.Seek(0, os.SEEK_CUR)
+}
+
+func (this *Output) Close() error {
+	if this.file == os.Stdout {
+		return nil
+	}
+
+	return this.file.Close()
+}
+
+/*
+Export writes all the documents of source, saving the checkpoint
+after each batch. It returns the number of documents exported.
+*/
+func Export(source Source, writer Writer, output *Output, options ExportOptions) (int64, error) {
+	batch := options.Batch
+	if batch <= 0 {
+		batch = 1
+	}
+
+	checkpoint := options.Checkpoint
+	if checkpoint == nil {
+		checkpoint = NewCheckpoint("")
+	}
+
+	exported := int64(0)
+	pending := 0
+
+	save := func() error {
+		err := writer.Flush()
+		if err != nil {
+			return err
+		}
+
+		if output != nil {
+			checkpoint.Offset, err = output.offset()
+			if err != nil {
+				return err
+			}
+		}
+
+		if fw, ok := writer.(interface {
+			Fields() []string
+		}); ok {
+			checkpoint.Fields = fw.Fields()
+		}
+
+		options.Progress.Add(pending)
+		pending = 0
+		return checkpoint.Save()
+	}
+
+	for {
+		key, doc, err := source.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return exported, fmt.Errorf("Error reading document %d: %v",
+				checkpoint.Records+1, err)
+		}
+
+		if options.KeyField != "" {
+			if object, ok := doc.(map[string]interface{}); ok {
+				object[options.KeyField] = key
+			}
+		}
+
+		err = writer.Write(doc)
+		if err != nil {
+			return exported, fmt.Errorf("Error writing document %d: %v",
+				checkpoint.Records+1, err)
+		}
+
+		exported++
+		pending++
+		checkpoint.Records++
+		if key != "" {
+			checkpoint.LastKey = key
+		}
+
+		if pending >= batch {
+			err = save()
+			if err != nil {
+				return exported, err
+			}
+		}
+	}
+
+	options.Progress.Add(pending)
+	err := writer.Close()
+	if err != nil {
+		return exported, err
+	}
+
+	return exported, checkpoint.Remove()
+}
@@ -0,0 +1,61 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+
+Package transfer provides bulk import and export of documents, for
+the cbq-import and cbq-export tools. Documents are read from and
+written to JSON, JSON lines and CSV files, and loaded into or read
+from a datastore URI (see datastore/resolver) or a running query
+engine.
+
+*/
+package transfer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type Format string
+
+const (
+	JSON  Format = "json"  // A JSON array of documents, or a sequence of documents
+	JSONL Format = "jsonl" // One JSON document per line
+	CSV   Format = "csv"   // Comma-separated values with a header row
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "json":
+		return JSON, nil
+	case "jsonl", "ndjson", "lines":
+		return JSONL, nil
+	case "csv":
+		return CSV, nil
+	}
+
+	return "", fmt.Errorf("Unknown format %s; use json, jsonl or csv.", s)
+}
+
+/*
+FormatOf returns the format of a file from its extension. Files
+with other extensions are JSON.
+*/
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return JSONL
+	case ".csv":
+		return CSV
+	}
+
+	return JSON
+}
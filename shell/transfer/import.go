@@ -0,0 +1,151 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/expression/parser"
+	"github.com/couchbase/query/value"
+)
+
+// Default key of imported documents
+const DEFAULT_KEY = "UUID()"
+
+type ImportOptions struct {
+	Key        expression.Expression // Generates the key of each document
+	Batch      int                   // Documents per write
+	Checkpoint *Checkpoint           // Resumes an interrupted import; may be nil
+	Progress   *Progress             // May be nil
+}
+
+/*
+ParseKey parses a key generation expression, which is evaluated on
+each document, e.g. type || "::" || TOSTRING(id).
+*/
+func ParseKey(s string) (expression.Expression, error) {
+	if s == "" {
+		s = DEFAULT_KEY
+	}
+
+	expr, err := parser.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid key expression %s: %v", s, err)
+	}
+
+	return expr, nil
+}
+
+/*
+Import reads all the documents of reader and writes them to target
+in batches, saving the checkpoint after each batch. The documents
+of a resumed import that were written before are skipped. It returns
+the number of documents imported.
+*/
+func Import(reader Reader, target Target, options ImportOptions) (int64, error) {
+	batch := options.Batch
+	if batch <= 0 {
+		batch = 1
+	}
+
+	checkpoint := options.Checkpoint
+	if checkpoint == nil {
+		checkpoint = NewCheckpoint("")
+	}
+
+	// Skip documents imported before the interruption
+	record := int64(0)
+	for ; record < checkpoint.Records; record++ {
+		_, err := reader.Next()
+		if err == io.EOF {
+			return 0, nil
+		} else if err != nil {
+			return 0, fmt.Errorf("Error reading document %d: %v", record+1, err)
+		}
+	}
+
+	context := expression.NewIndexContext()
+	pairs := make([]value.Pair, 0, batch)
+	imported := int64(0)
+
+	flush := func() error {
+		if len(pairs) == 0 {
+			return nil
+		}
+
+		err := target.Write(pairs)
+		if err != nil {
+			return fmt.Errorf("Error writing documents %d to %d: %v",
+				checkpoint.Records+1, checkpoint.Records+int64(len(pairs)), err)
+		}
+
+		checkpoint.Records += int64(len(pairs))
+		imported += int64(len(pairs))
+		options.Progress.Add(len(pairs))
+		pairs = pairs[:0]
+		return checkpoint.Save()
+	}
+
+	for {
+		doc, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return imported, fmt.Errorf("Error reading document %d: %v", record+1, err)
+		}
+
+		record++
+		val := value.NewValue(doc)
+		key, err := generateKey(options.Key, val, context)
+		if err != nil {
+			return imported, fmt.Errorf("Error generating key of document %d: %v", record, err)
+		}
+
+		pairs = append(pairs, value.Pair{Name: key, Value: val})
+		if len(pairs) >= batch {
+			err = flush()
+			if err != nil {
+				return imported, err
+			}
+		}
+	}
+
+	err := flush()
+	if err != nil {
+		return imported, err
+	}
+
+	return imported, checkpoint.Remove()
+}
+
+func generateKey(expr expression.Expression, doc value.Value, context expression.Context) (string, error) {
+	if expr == nil {
+		return "", fmt.Errorf("Missing key expression.")
+	}
+
+	key, err := expr.Evaluate(value.NewAnnotatedValue(doc), context)
+	if err != nil {
+		return "", err
+	}
+
+	switch k := key.Actual().(type) {
+	case string:
+		if k != "" {
+			return k, nil
+		}
+	case float64:
+		return strconv.FormatFloat(k, 'f', -1, 64), nil
+	}
+
+	return "", fmt.Errorf("Key %v is not a non-empty string or a number.", key)
+}
@@ -0,0 +1,82 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+/*
+Progress reports the number of documents transferred, at most once
+per interval, and a summary when the transfer is done.
+*/
+type Progress struct {
+	writer   io.Writer
+	verb     string
+	interval time.Duration
+	start    time.Time
+	last     time.Time
+	count    int64
+}
+
+/*
+NewProgress returns a Progress that writes to w. A nil writer or a
+zero interval disables the periodic reports.
+*/
+func NewProgress(w io.Writer, verb string, interval time.Duration) *Progress {
+	now := time.Now()
+	return &Progress{
+		writer:   w,
+		verb:     verb,
+		interval: interval,
+		start:    now,
+		last:     now,
+	}
+}
+
+func (this *Progress) Add(n int) {
+	if this == nil {
+		return
+	}
+
+	this.count += int64(n)
+	if this.writer == nil || this.interval <= 0 {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(this.last) >= this.interval {
+		this.last = now
+		this.report(now)
+	}
+}
+
+func (this *Progress) Count() int64 {
+	return this.count
+}
+
+func (this *Progress) Done() {
+	if this != nil && this.writer != nil {
+		this.report(time.Now())
+	}
+}
+
+func (this *Progress) report(now time.Time) {
+	elapsed := now.Sub(this.start)
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(this.count) / elapsed.Seconds()
+	}
+
+	fmt.Fprintf(this.writer, "%s %d documents in %v (%.1f/s)\n",
+		this.verb, this.count, elapsed-elapsed%time.Millisecond, rate)
+}
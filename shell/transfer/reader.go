@@ -0,0 +1,242 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+Reader reads documents from an input file. Next returns io.EOF
+after the last document.
+*/
+type Reader interface {
+	Next() (interface{}, error)
+}
+
+/*
+NewReader returns a Reader of the given format. JSON lines are read
+as a sequence of JSON documents. CSV values are converted to
+numbers, booleans and nulls when inferTypes is set, and columns with
+dotted names such as "address.city" are read into nested objects.
+*/
+func NewReader(r io.Reader, format Format, inferTypes bool) (Reader, error) {
+	switch format {
+	case JSON, JSONL:
+		return newJSONReader(r), nil
+	case CSV:
+		return newCSVReader(r, inferTypes)
+	}
+
+	return nil, fmt.Errorf("Unknown format %s.", format)
+}
+
+/*
+jsonReader reads a top-level array of documents, or a sequence of
+documents such as JSON lines. Arrays are streamed element by element.
+*/
+type jsonReader struct {
+	reader  *bufio.Reader
+	decoder *json.Decoder
+	array   bool
+}
+
+func newJSONReader(r io.Reader) *jsonReader {
+	return &jsonReader{reader: bufio.NewReader(r)}
+}
+
+func (this *jsonReader) Next() (interface{}, error) {
+	if this.decoder == nil {
+		array, err := startsArray(this.reader)
+		if err != nil {
+			return nil, err
+		}
+
+		this.decoder = json.NewDecoder(this.reader)
+		this.array = array
+		if array {
+			// Consume the opening bracket
+			_, err = this.decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if this.array && !this.decoder.More() {
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	err := this.decoder.Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeDocument(raw)
+}
+
+// startsArray skips leading white space, and peeks at the next byte.
+func startsArray(reader *bufio.Reader) (bool, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err != nil {
+			return false, err
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			reader.ReadByte()
+		default:
+			return b[0] == '[', nil
+		}
+	}
+}
+
+/*
+decodeDocument decodes a document, keeping integers as int64 so
+that they are not rounded.
+*/
+func decodeDocument(raw []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var doc interface{}
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertNumbers(doc), nil
+}
+
+func convertNumbers(val interface{}) interface{} {
+	switch val := val.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case []interface{}:
+		for i, v := range val {
+			val[i] = convertNumbers(v)
+		}
+	case map[string]interface{}:
+		for k, v := range val {
+			val[k] = convertNumbers(v)
+		}
+	}
+
+	return val
+}
+
+type csvReader struct {
+	reader     *csv.Reader
+	header     [][]string
+	inferTypes bool
+}
+
+func newCSVReader(r io.Reader, inferTypes bool) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	names, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("Missing CSV header row.")
+	} else if err != nil {
+		return nil, err
+	}
+
+	header := make([][]string, len(names))
+	for i, name := range names {
+		header[i] = strings.Split(strings.TrimSpace(name), ".")
+	}
+
+	return &csvReader{
+		reader:     reader,
+		header:     header,
+		inferTypes: inferTypes,
+	}, nil
+}
+
+func (this *csvReader) Next() (interface{}, error) {
+	record, err := this.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := make(map[string]interface{}, len(record))
+	for i, field := range record {
+		if i >= len(this.header) || field == "" {
+			continue
+		}
+
+		var val interface{} = field
+		if this.inferTypes {
+			val = inferType(field)
+		}
+
+		setPath(doc, this.header[i], val)
+	}
+
+	return doc, nil
+}
+
+/*
+inferType converts CSV values that look like numbers, booleans,
+null, or JSON arrays and objects (as written by the CSV Writer).
+*/
+func inferType(field string) interface{} {
+	switch field {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+
+	if i, err := strconv.ParseInt(field, 10, 64); err == nil {
+		return i
+	}
+
+	if f, err := strconv.ParseFloat(field, 64); err == nil {
+		return f
+	}
+
+	if field[0] == '[' || field[0] == '{' {
+		if doc, err := decodeDocument([]byte(field)); err == nil {
+			return doc
+		}
+	}
+
+	return field
+}
+
+func setPath(doc map[string]interface{}, path []string, val interface{}) {
+	for _, name := range path[:len(path)-1] {
+		child, ok := doc[name].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			doc[name] = child
+		}
+		doc = child
+	}
+
+	doc[path[len(path)-1]] = val
+}
@@ -0,0 +1,263 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Source provides the documents of an export. Next returns io.EOF
+after the last document. Keyspace sources return documents in key
+order, and resume after the last key exported; query sources return
+an empty key, and are resumed by skipping the documents already
+exported.
+*/
+type Source interface {
+	Next() (key string, doc interface{}, err error)
+	Close()
+}
+
+type datastoreSource struct {
+	keyspace datastore.Keyspace
+	keys     []string
+	batch    int
+	docs     []value.AnnotatedPair
+}
+
+/*
+NewDatastoreSource exports a keyspace of a datastore URI, using its
+primary index.
+*/
+func NewDatastoreSource(uri, namespace, keyspace, after string, batch int) (Source, error) {
+	ks, err := openKeyspace(uri, namespace, keyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := scanKeys(ks)
+	if err != nil {
+		ks.Release()
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	start := sort.SearchStrings(keys, after)
+	if start < len(keys) && after != "" && keys[start] == after {
+		start++
+	}
+
+	return &datastoreSource{
+		keyspace: ks,
+		keys:     keys[start:],
+		batch:    batch,
+	}, nil
+}
+
+func scanKeys(keyspace datastore.Keyspace) ([]string, error) {
+	indexer, err := keyspace.Indexer(datastore.DEFAULT)
+	if err != nil {
+		return nil, err
+	}
+
+	primaries, err := indexer.PrimaryIndexes()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(primaries) == 0 {
+		return nil, fmt.Errorf("Keyspace %s has no primary index.", keyspace.Name())
+	}
+
+	context := &scanContext{}
+	conn := datastore.NewIndexConnection(context)
+	go primaries[0].ScanEntries("cbq-export", math.MaxInt64, datastore.UNBOUNDED, nil, conn)
+
+	var keys []string
+	for entry := range conn.EntryChannel() {
+		keys = append(keys, entry.PrimaryKey)
+	}
+
+	if context.err != nil {
+		return nil, context.err
+	}
+
+	return keys, nil
+}
+
+func (this *datastoreSource) Next() (string, interface{}, error) {
+	for len(this.docs) == 0 {
+		if len(this.keys) == 0 {
+			return "", nil, io.EOF
+		}
+
+		n := this.batch
+		if n <= 0 || n > len(this.keys) {
+			n = len(this.keys)
+		}
+
+		docs, errs := this.keyspace.Fetch(this.keys[:n], datastore.NULL_QUERY_CONTEXT)
+		if len(errs) > 0 {
+			return "", nil, errs[0]
+		}
+
+		// Keep key order; deleted documents are skipped
+		byKey := make(map[string]value.AnnotatedPair, len(docs))
+		for _, doc := range docs {
+			byKey[doc.Name] = doc
+		}
+
+		for _, key := range this.keys[:n] {
+			if doc, ok := byKey[key]; ok {
+				this.docs = append(this.docs, doc)
+			}
+		}
+
+		this.keys = this.keys[n:]
+	}
+
+	doc := this.docs[0]
+	this.docs = this.docs[1:]
+	return doc.Name, doc.Value.Actual(), nil
+}
+
+func (this *datastoreSource) Close() {
+	this.keyspace.Release()
+}
+
+// scanContext collects the errors of a primary index scan.
+type scanContext struct {
+	err errors.Error
+}
+
+func (this *scanContext) Fatal(err errors.Error) {
+	if this.err == nil {
+		this.err = err
+	}
+}
+
+func (this *scanContext) Error(err errors.Error) {
+	this.Fatal(err)
+}
+
+func (this *scanContext) Warning(wrn errors.Error) {
+}
+
+type engineKeyspaceSource struct {
+	engine   *Engine
+	keyspace string
+	after    string
+	batch    int
+	docs     []json.RawMessage
+	done     bool
+}
+
+/*
+NewEngineKeyspaceSource exports a keyspace through a query engine,
+one page of keys at a time, which requires a primary index.
+*/
+func NewEngineKeyspaceSource(engine *Engine, namespace, keyspace, after string, batch int) Source {
+	if batch <= 0 {
+		batch = 1000
+	}
+
+	return &engineKeyspaceSource{
+		engine:   engine,
+		keyspace: keyspaceRef(namespace, keyspace),
+		after:    after,
+		batch:    batch,
+	}
+}
+
+type keyedDocument struct {
+	Id  string          `json:"id"`
+	Doc json.RawMessage `json:"doc"`
+}
+
+func (this *engineKeyspaceSource) Next() (string, interface{}, error) {
+	if len(this.docs) == 0 {
+		if this.done {
+			return "", nil, io.EOF
+		}
+
+		statement := fmt.Sprintf("SELECT META(d).id AS id, d AS doc FROM %s d "+
+			"WHERE META(d).id > $1 ORDER BY META(d).id LIMIT %d", this.keyspace, this.batch)
+		docs, err := this.engine.Query(statement, this.after)
+		if err != nil {
+			return "", nil, err
+		}
+
+		this.docs = docs
+		this.done = len(docs) < this.batch
+		if len(docs) == 0 {
+			return "", nil, io.EOF
+		}
+	}
+
+	raw := this.docs[0]
+	this.docs = this.docs[1:]
+
+	var doc keyedDocument
+	err := json.Unmarshal(raw, &doc)
+	if err != nil {
+		return "", nil, err
+	}
+
+	this.after = doc.Id
+	val, err := decodeDocument(doc.Doc)
+	return doc.Id, val, err
+}
+
+func (this *engineKeyspaceSource) Close() {
+}
+
+type engineQuerySource struct {
+	docs []json.RawMessage
+}
+
+/*
+NewEngineQuerySource exports the results of a query, skipping the
+first skip results of a resumed export. The query must return its
+results in a stable order to be resumed.
+*/
+func NewEngineQuerySource(engine *Engine, query string, skip int64) (Source, error) {
+	docs, err := engine.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if skip > int64(len(docs)) {
+		skip = int64(len(docs))
+	}
+
+	return &engineQuerySource{docs: docs[skip:]}, nil
+}
+
+func (this *engineQuerySource) Next() (string, interface{}, error) {
+	if len(this.docs) == 0 {
+		return "", nil, io.EOF
+	}
+
+	raw := this.docs[0]
+	this.docs = this.docs[1:]
+	doc, err := decodeDocument(raw)
+	return "", doc, err
+}
+
+func (this *engineQuerySource) Close() {
+}
@@ -0,0 +1,125 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transfer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/resolver"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Target receives the documents of an import, one batch at a time.
+Batches are written with UPSERT, or with INSERT if insert is set,
+in which case existing keys are an error.
+*/
+type Target interface {
+	Write(pairs []value.Pair) error
+	Close()
+}
+
+type datastoreTarget struct {
+	keyspace datastore.Keyspace
+	insert   bool
+}
+
+/*
+NewDatastoreTarget imports into a keyspace of a datastore URI, such
+as dir:PATH.
+*/
+func NewDatastoreTarget(uri, namespace, keyspace string, insert bool) (Target, error) {
+	ks, err := openKeyspace(uri, namespace, keyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &datastoreTarget{keyspace: ks, insert: insert}, nil
+}
+
+func openKeyspace(uri, namespace, keyspace string) (datastore.Keyspace, error) {
+	store, err := resolver.NewDatastore(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := store.NamespaceByName(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	ks, err := ns.KeyspaceByName(keyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+func (this *datastoreTarget) Write(pairs []value.Pair) error {
+	var err error
+	if this.insert {
+		_, err = this.keyspace.Insert(pairs, datastore.NULL_QUERY_CONTEXT)
+	} else {
+		_, err = this.keyspace.Upsert(pairs, datastore.NULL_QUERY_CONTEXT)
+	}
+
+	return err
+}
+
+func (this *datastoreTarget) Close() {
+	this.keyspace.Release()
+}
+
+type engineTarget struct {
+	engine   *Engine
+	keyspace string
+	insert   bool
+}
+
+/*
+NewEngineTarget imports into a keyspace through a query engine, with
+one INSERT or UPSERT statement per batch.
+*/
+func NewEngineTarget(engine *Engine, namespace, keyspace string, insert bool) Target {
+	return &engineTarget{
+		engine:   engine,
+		keyspace: keyspaceRef(namespace, keyspace),
+		insert:   insert,
+	}
+}
+
+func (this *engineTarget) Write(pairs []value.Pair) error {
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	verb := "UPSERT"
+	if this.insert {
+		verb = "INSERT"
+	}
+
+	values := make([]string, len(pairs))
+	args := make([]interface{}, 0, 2*len(pairs))
+	for i, pair := range pairs {
+		values[i] = fmt.Sprintf("($%d, $%d)", 2*i+1, 2*i+2)
+		args = append(args, pair.Name, pair.Value)
+	}
+
+	statement := fmt.Sprintf("%s INTO %s (KEY, VALUE) VALUES %s",
+		verb, this.keyspace, strings.Join(values, ", "))
+	_, err := this.engine.Query(statement, args...)
+	return err
+}
+
+func (this *engineTarget) Close() {
+}
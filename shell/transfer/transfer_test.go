@@ -0,0 +1,168 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transfer
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, reader Reader) []interface{} {
+	var docs []interface{}
+	for {
+		doc, err := reader.Next()
+		if err == io.EOF {
+			return docs
+		} else if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		docs = append(docs, doc)
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	expected := []interface{}{
+		map[string]interface{}{"id": int64(1), "name": "a"},
+		map[string]interface{}{"id": int64(2), "price": 2.5},
+	}
+
+	inputs := []string{
+		`[{"id": 1, "name": "a"}, {"id": 2, "price": 2.5}]`,
+		"{\"id\": 1, \"name\": \"a\"}\n{\"id\": 2, \"price\": 2.5}\n",
+	}
+
+	for _, input := range inputs {
+		reader, err := NewReader(strings.NewReader(input), JSON, false)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		docs := readAll(t, reader)
+		if !reflect.DeepEqual(docs, expected) {
+			t.Errorf("Expected %v, got %v for %s", expected, docs, input)
+		}
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	docs := []interface{}{
+		map[string]interface{}{
+			"name":    "dave",
+			"age":     int64(46),
+			"address": map[string]interface{}{"city": "Mountain View"},
+			"hobbies": []interface{}{"golf", "surfing"},
+		},
+		map[string]interface{}{
+			"name":    "earl, jr",
+			"age":     int64(46),
+			"address": map[string]interface{}{"city": "Palo Alto"},
+			"hobbies": []interface{}{"surfing"},
+		},
+	}
+
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, CSV, nil, false)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for _, doc := range docs {
+		if err = writer.Write(doc); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+	writer.Close()
+
+	if !strings.HasPrefix(buf.String(), "address.city,age,hobbies,name\n") {
+		t.Errorf("Unexpected CSV header in %s", buf.String())
+	}
+
+	reader, err := NewReader(&buf, CSV, true)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	read := readAll(t, reader)
+	if !reflect.DeepEqual(read, docs) {
+		t.Errorf("Expected %v, got %v", docs, read)
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transfer")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = os.MkdirAll(filepath.Join(dir, "default", "people"), 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	uri := "dir:" + dir
+	input := "{\"id\": 1, \"name\": \"a\"}\n{\"id\": 2, \"name\": \"b\"}\n{\"id\": 3, \"name\": \"c\"}\n"
+
+	// Resume after the first document
+	checkpoint := NewCheckpoint(filepath.Join(dir, "import.checkpoint"))
+	checkpoint.Records = 1
+
+	key, _ := ParseKey(`"p" || TOSTRING(id)`)
+	reader, _ := NewReader(strings.NewReader(input), JSONL, false)
+	target, err := NewDatastoreTarget(uri, "default", "people", false)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	n, err := Import(reader, target, ImportOptions{Key: key, Batch: 2, Checkpoint: checkpoint})
+	target.Close()
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 documents imported, got %d, %v", n, err)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "import.checkpoint")); !os.IsNotExist(err) {
+		t.Errorf("Expected checkpoint to be removed")
+	}
+
+	// Export after the first key
+	output := filepath.Join(dir, "people.json")
+	checkpoint = NewCheckpoint(output + ".checkpoint")
+	checkpoint.LastKey = "p2"
+
+	source, err := NewDatastoreSource(uri, "default", "people", checkpoint.LastKey, 10)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer source.Close()
+
+	out, err := OpenOutput(output, checkpoint)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	writer, _ := NewWriter(out, JSON, nil, out.Appending())
+	n, err = Export(source, writer, out, ExportOptions{KeyField: "_id", Batch: 1, Checkpoint: checkpoint})
+	out.Close()
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 document exported, got %d, %v", n, err)
+	}
+
+	bytes, _ := ioutil.ReadFile(output)
+	expected := "[\n{\"_id\":\"p3\",\"id\":3,\"name\":\"c\"}\n]\n"
+	if string(bytes) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(bytes))
+	}
+}
@@ -0,0 +1,225 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Writer writes documents to an output file. Flush writes buffered
+documents, e.g. before saving a checkpoint, and Close terminates the
+output.
+*/
+type Writer interface {
+	Write(doc interface{}) error
+	Flush() error
+	Close() error
+}
+
+/*
+NewWriter returns a Writer of the given format. CSV columns are the
+given fields, with dotted names for nested fields; if there are none,
+they are the fields of the first document. When appending to the
+output of an interrupted export, the JSON array or the CSV header is
+not started again.
+*/
+func NewWriter(w io.Writer, format Format, fields []string, appending bool) (Writer, error) {
+	buf := bufio.NewWriter(w)
+
+	switch format {
+	case JSON:
+		return &jsonWriter{writer: buf, array: true, started: appending}, nil
+	case JSONL:
+		return &jsonWriter{writer: buf}, nil
+	case CSV:
+		return &csvWriter{buf: buf, writer: csv.NewWriter(buf), fields: fields,
+			started: appending}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown format %s.", format)
+}
+
+type jsonWriter struct {
+	writer  *bufio.Writer
+	array   bool
+	started bool
+}
+
+func (this *jsonWriter) Write(doc interface{}) error {
+	bytes, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	if this.array {
+		if this.started {
+			this.writer.WriteString(",\n")
+		} else {
+			this.writer.WriteString("[\n")
+		}
+	}
+
+	this.started = true
+	this.writer.Write(bytes)
+	if !this.array {
+		this.writer.WriteByte('\n')
+	}
+
+	return nil
+}
+
+func (this *jsonWriter) Flush() error {
+	return this.writer.Flush()
+}
+
+func (this *jsonWriter) Close() error {
+	if this.array {
+		if this.started {
+			this.writer.WriteString(_JSON_END)
+		} else {
+			this.writer.WriteString("[]\n")
+		}
+	}
+
+	return this.writer.Flush()
+}
+
+// Terminates JSON arrays; removed when appending.
+const _JSON_END = "\n]\n"
+
+type csvWriter struct {
+	buf     *bufio.Writer
+	writer  *csv.Writer
+	fields  []string
+	started bool
+}
+
+func (this *csvWriter) Write(doc interface{}) error {
+	if this.fields == nil {
+		this.fields = flatFields(doc)
+	}
+
+	if !this.started {
+		this.started = true
+		err := this.writer.Write(this.fields)
+		if err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(this.fields))
+	for i, field := range this.fields {
+		val, ok := GetPath(doc, strings.Split(field, "."))
+		if ok {
+			record[i] = FormatField(val)
+		}
+	}
+
+	return this.writer.Write(record)
+}
+
+func (this *csvWriter) Flush() error {
+	this.writer.Flush()
+	err := this.writer.Error()
+	if err != nil {
+		return err
+	}
+
+	return this.buf.Flush()
+}
+
+func (this *csvWriter) Close() error {
+	return this.Flush()
+}
+
+// Fields returns the CSV columns, once the first document is written.
+func (this *csvWriter) Fields() []string {
+	return this.fields
+}
+
+/*
+flatFields returns the sorted paths of the scalar and array fields of
+a document, e.g. "address.city".
+*/
+func flatFields(doc interface{}) []string {
+	var fields []string
+
+	var walk func(prefix string, val interface{})
+	walk = func(prefix string, val interface{}) {
+		object, ok := val.(map[string]interface{})
+		if !ok || len(object) == 0 {
+			if prefix != "" {
+				fields = append(fields, prefix)
+			}
+			return
+		}
+
+		for name, v := range object {
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			walk(name, v)
+		}
+	}
+
+	walk("", doc)
+	sort.Strings(fields)
+	return fields
+}
+
+// GetPath returns the field of a document at a path of names.
+func GetPath(doc interface{}, path []string) (interface{}, bool) {
+	for _, name := range path {
+		object, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		doc, ok = object[name]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return doc, true
+}
+
+/*
+FormatField formats a CSV value. Arrays and objects are written as
+JSON, which the CSV Reader converts back.
+*/
+func FormatField(val interface{}) string {
+	switch val := val.(type) {
+	case nil:
+		return "null"
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	}
+
+	bytes, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return string(bytes)
+}
@@ -63,6 +63,7 @@ func (this *MockQuery) Execute(srvr *server.Server, signature value.Value, stopN
 
 	this.NotifyStop(stopNotify)
 	this.writeResults()
+	this.writeErrors()
 	close(this.response.done)
 }
 
@@ -113,6 +114,22 @@ func (this *MockQuery) writeResults() bool {
 	return false
 }
 
+// keeps the first execution error, and all the warnings
+func (this *MockQuery) writeErrors() {
+	for {
+		select {
+		case err := <-this.Errors():
+			if this.response.err == nil {
+				this.response.err = err
+			}
+		case wrn := <-this.Warnings():
+			this.response.warnings = append(this.response.warnings, wrn)
+		default:
+			return
+		}
+	}
+}
+
 func (this *MockQuery) writeResult(item value.Value) bool {
 	bytes, err := json.Marshal(item)
 	if err != nil {
@@ -195,9 +212,14 @@ func Run(mockServer *MockServer, p bool, q string) ([]interface{}, []errors.Erro
 }
 
 func Start(site, pool string) *MockServer {
+	return StartDir("./json")
+}
+
+// starts a server on the file datastore in dir
+func StartDir(dir string) *MockServer {
 
 	mockServer := &MockServer{}
-	datastore, err := resolver.NewDatastore("dir:./json")
+	datastore, err := resolver.NewDatastore("dir:" + dir)
 	if err != nil {
 		logging.Errorp(err.Error())
 		os.Exit(1)
@@ -13,9 +13,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/dustin/go-jsonpointer"
 )
@@ -73,6 +75,67 @@ func TestAllCaseFiles(t *testing.T) {
 	}
 }
 
+// Each directory of test_cases has the case files of a feature, and
+// the json datastore they run on. The statements may modify the
+// datastore, so they run on a copy of it.
+func TestCaseDirectories(t *testing.T) {
+	dirs, err := filepath.Glob("test_cases/*")
+	if err != nil {
+		t.Errorf("glob failed: %v", err)
+	}
+	for _, dir := range dirs {
+		testCaseDirectory(t, dir)
+	}
+}
+
+func testCaseDirectory(t *testing.T, dir string) {
+	tmp, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Errorf("TempDir failed: %v", err)
+		return
+	}
+	defer os.RemoveAll(tmp)
+
+	err = copyDir(filepath.Join(dir, "json"), tmp)
+	if err != nil {
+		t.Errorf("copying the datastore of %v failed: %v", dir, err)
+		return
+	}
+
+	qc := StartDir(tmp)
+	matches, err := filepath.Glob(filepath.Join(dir, "case_*.json"))
+	if err != nil {
+		t.Errorf("glob failed: %v", err)
+	}
+	for _, m := range matches {
+		testCaseFile(t, m, qc)
+	}
+}
+
+func copyDir(from, to string) error {
+	return filepath.Walk(from, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(to, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, b, 0644)
+	})
+}
+
 func testCaseFile(t *testing.T, fname string, qc *MockServer) {
 	t.Logf("testCaseFile: %v\n", fname)
 	b, err := ioutil.ReadFile(fname)
@@ -117,7 +180,18 @@ func testCaseFile(t *testing.T, fname string, qc *MockServer) {
 		}
 		statements := v.(string)
 		t.Logf("  %d: %v\n", i, statements)
-		resultsActual, _, errActual := Run(qc, pretty, statements)
+		resultsActual, warningsActual, errActual := Run(qc, pretty, statements)
+
+		// Effects of asynchronous work, such as asynchronous triggers,
+		// are awaited by repeating the statements until the results match
+		v, ok = c["eventually"]
+		if ok && v.(bool) {
+			expected, _ := c["results"].([]interface{})
+			for n := 0; n < 50 && errActual == nil && !reflect.DeepEqual(resultsActual, expected); n++ {
+				time.Sleep(100 * time.Millisecond)
+				resultsActual, warningsActual, errActual = Run(qc, pretty, statements)
+			}
+		}
 
 		v, ok = c["postStatements"]
 		if ok {
@@ -132,7 +206,7 @@ func testCaseFile(t *testing.T, fname string, qc *MockServer) {
 		if ok {
 			matchStatements := v.(string)
 			resultsMatch, _, errMatch := Run(qc, pretty, matchStatements)
-			if !reflect.DeepEqual(errActual, errActual) {
+			if !reflect.DeepEqual(errActual, errMatch) {
 				t.Errorf("errors don't match, actual: %#v, expected: %#v"+
 					", for case file: %v, index: %v",
 					errActual, errMatch, fname, i)
@@ -152,6 +226,11 @@ func testCaseFile(t *testing.T, fname string, qc *MockServer) {
 				return
 			}
 			// TODO: Check that the actual err matches the expected err.
+			v, ok = c["errorCode"]
+			if ok && int32(v.(float64)) != errActual.Code() {
+				t.Errorf("expected error code %v, got err: %v, statements: %v"+
+					", for case file: %v, index: %v", v, errActual, statements, fname, i)
+			}
 			continue
 		}
 		if errExpected != "" {
@@ -160,6 +239,18 @@ func testCaseFile(t *testing.T, fname string, qc *MockServer) {
 			return
 		}
 
+		v, ok = c["warningCodes"]
+		if ok {
+			codes := make([]interface{}, len(warningsActual))
+			for j, wrn := range warningsActual {
+				codes[j] = float64(wrn.Code())
+			}
+			if !reflect.DeepEqual(codes, v) {
+				t.Errorf("expected warning codes %v, got warnings: %v, statements: %v"+
+					", for case file: %v, index: %v", v, warningsActual, statements, fname, i)
+			}
+		}
+
 		v, ok = c["results"]
 		if ok {
 			resultsExpected := v.([]interface{})
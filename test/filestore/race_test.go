@@ -0,0 +1,32 @@
+//  Copyright (c) 2016 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+// +build race
+
+package test
+
+import (
+	"testing"
+)
+
+/*
+Case directories whose statements share documents between operators
+running in parallel, run on their own with the race detector:
+
+	go test -race -run TestRaceCaseDirectories ./test/filestore
+*/
+var _RACE_CASE_DIRS = []string{
+	"test_cases/merge_on",
+}
+
+func TestRaceCaseDirectories(t *testing.T) {
+	for _, dir := range _RACE_CASE_DIRS {
+		testCaseDirectory(t, dir)
+	}
+}